@@ -14,10 +14,19 @@ const (
 	HEAACv1 = 5
 	// HEAACv2 - HE-AAC version 2 with SBR and PS
 	HEAACv2 = 29
+
+	// syncExtensionTypeSBR - syncExtensionType for backward-compatible explicit SBR signaling
+	syncExtensionTypeSBR = 0x2b7
+	// syncExtensionTypePS - syncExtensionType for backward-compatible explicit PS signaling
+	syncExtensionTypePS = 0x548
 )
 
 // AudioSpecificConfig according to ISO/IEC 14496-3
 // Syntax specified in Table 1.15
+//
+// ObjectType AAClc with SBRPresentFlag set corresponds to backward-compatible explicit
+// signaling (ISO/IEC 14496-3 1.6.5.2) where SBR and PS are signaled by sync extensions
+// after the GASpecificConfig. ObjectType HEAACv1 and HEAACv2 give hierarchical signaling.
 type AudioSpecificConfig struct {
 	ObjectType           byte
 	ChannelConfiguration byte // Defined in Table 1.19
@@ -111,11 +120,43 @@ func DecodeAudioSpecificConfig(r io.Reader) (*AudioSpecificConfig, error) {
 	}
 	//GASpecificConfig()
 	_ = br.Read(3) //GASpecificConfig
+	if asc.ObjectType == AAClc {
+		readSyncExtension(br, asc)
+	}
 	// Done (there may be trailing bits)
 	return asc, nil
 }
 
+// readSyncExtension - read backward-compatible explicit SBR and PS signaling if present
+func readSyncExtension(br *bits.Reader, asc *AudioSpecificConfig) {
+	if br.Read(11) != syncExtensionTypeSBR || br.AccError() != nil {
+		return
+	}
+	if br.Read(5) != HEAACv1 { // extensionAudioObjectType
+		return
+	}
+	sbrPresentFlag := br.ReadFlag()
+	if br.AccError() != nil || !sbrPresentFlag {
+		return
+	}
+	extFrequency, ok := getFrequency(br)
+	if !ok {
+		return
+	}
+	asc.SBRPresentFlag = true
+	asc.ExtensionFrequency = extFrequency
+	if br.Read(11) != syncExtensionTypePS {
+		return
+	}
+	psPresentFlag := br.ReadFlag()
+	if br.AccError() == nil {
+		asc.PSPresentFlag = psPresentFlag
+	}
+}
+
 // Encode - write AudioSpecificConfig to w for AAC-LC and HE-AAC
+// For AAClc with SBRPresentFlag set, explicit backward-compatible SBR signaling
+// with ExtensionFrequency is written, followed by PS signaling if PSPresentFlag is set.
 func (a *AudioSpecificConfig) Encode(w io.Writer) error {
 	switch a.ObjectType {
 	case AAClc, HEAACv1, HEAACv2:
@@ -123,32 +164,44 @@ func (a *AudioSpecificConfig) Encode(w io.Writer) error {
 	default:
 		return fmt.Errorf("audioObjectType %d not supported", a.ObjectType)
 	}
+	if a.ObjectType == AAClc && a.PSPresentFlag && !a.SBRPresentFlag {
+		return fmt.Errorf("explicit PS signaling requires SBRPresentFlag")
+	}
 	bw := bits.NewWriter(w)
 	bw.Write(uint(a.ObjectType), 5)
-	samplingIndex, ok := ReverseFrequencies[a.SamplingFrequency]
-	if ok {
-		bw.Write(uint(samplingIndex), 4)
-	} else {
-		bw.Write(0x0f, 4)
-		bw.Write(uint(a.SamplingFrequency), 24)
-	}
+	writeFrequency(bw, a.SamplingFrequency)
 	bw.Write(uint(a.ChannelConfiguration), 4)
 	switch a.ObjectType {
 	case HEAACv1, HEAACv2:
-		samplingIndex, ok := ReverseFrequencies[a.ExtensionFrequency]
-		if ok {
-			bw.Write(uint(samplingIndex), 4)
-		} else {
-			bw.Write(0x0f, 4)
-			bw.Write(uint(a.ExtensionFrequency), 24)
-		}
+		writeFrequency(bw, a.ExtensionFrequency)
 		bw.Write(AAClc, 5) // base audioObjectType
 	}
 	bw.Write(0x00, 3) // GASpecificConfig
+	if a.ObjectType == AAClc && a.SBRPresentFlag {
+		bw.Write(syncExtensionTypeSBR, 11)
+		bw.Write(HEAACv1, 5) // extensionAudioObjectType
+		bw.Write(1, 1)       // sbrPresentFlag
+		writeFrequency(bw, a.ExtensionFrequency)
+		if a.PSPresentFlag {
+			bw.Write(syncExtensionTypePS, 11)
+			bw.Write(1, 1) // psPresentFlag
+		}
+	}
 	bw.Flush()
 	return bw.AccError()
 }
 
+// writeFrequency - either as 4-bit index or 24-bit value
+func writeFrequency(bw *bits.Writer, frequency int) {
+	samplingIndex, ok := ReverseFrequencies[frequency]
+	if ok {
+		bw.Write(uint(samplingIndex), 4)
+	} else {
+		bw.Write(0x0f, 4)
+		bw.Write(uint(frequency), 24)
+	}
+}
+
 // getFrequency - either from 4-bit index or 24-bit value
 func getFrequency(br *bits.Reader) (frequency int, ok bool) {
 	frequencyIndex := br.Read(4)
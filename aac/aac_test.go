@@ -35,6 +35,22 @@ func TestAudioSpecificConfigEncodeDecode(t *testing.T) {
 			SBRPresentFlag:       true,
 			PSPresentFlag:        true,
 		},
+		{
+			ObjectType:           AAClc,
+			ChannelConfiguration: 2,
+			SamplingFrequency:    24000,
+			ExtensionFrequency:   48000,
+			SBRPresentFlag:       true,
+			PSPresentFlag:        false,
+		},
+		{
+			ObjectType:           AAClc,
+			ChannelConfiguration: 1,
+			SamplingFrequency:    24000,
+			ExtensionFrequency:   48000,
+			SBRPresentFlag:       true,
+			PSPresentFlag:        true,
+		},
 	}
 
 	for _, asc := range testCases {
@@ -105,3 +121,27 @@ func TestVariousInputs(t *testing.T) {
 		}
 	})
 }
+
+func TestExplicitSBRSignaling(t *testing.T) {
+	asc := AudioSpecificConfig{
+		ObjectType:           AAClc,
+		ChannelConfiguration: 1,
+		SamplingFrequency:    24000,
+		ExtensionFrequency:   48000,
+		SBRPresentFlag:       true,
+		PSPresentFlag:        true,
+	}
+	buf := &bytes.Buffer{}
+	if err := asc.Encode(buf); err != nil {
+		t.Fatal(err)
+	}
+	// AAC-LC 24kHz mono, sync extension 0x2b7 with SBR 48kHz, sync extension 0x548 with PS
+	wanted := "130856e59d4880"
+	if got := hex.EncodeToString(buf.Bytes()); got != wanted {
+		t.Errorf("got ASC %s instead of %s", got, wanted)
+	}
+	asc.SBRPresentFlag = false
+	if err := asc.Encode(&bytes.Buffer{}); err == nil {
+		t.Error("no error for PS without SBR")
+	}
+}
@@ -1,4 +1,7 @@
 /*
 Package av1 decodes (parses) and encodes (writes) AV1 CodecConfigurationRecord.
+
+It also provides parsing of OBUs (Open Bitstream Units) and the sequence header OBU,
+as well as validation and fixing of obu_size fields in samples.
 */
 package av1
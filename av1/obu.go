@@ -0,0 +1,205 @@
+package av1
+
+import (
+	"errors"
+	"fmt"
+)
+
+// OBUType - AV1 Open Bitstream Unit type
+type OBUType byte
+
+// OBU types as specified in AV1 Bitstream & Decoding Process Specification Section 6.2.2
+const (
+	OBU_SEQUENCE_HEADER        = OBUType(1)
+	OBU_TEMPORAL_DELIMITER     = OBUType(2)
+	OBU_FRAME_HEADER           = OBUType(3)
+	OBU_TILE_GROUP             = OBUType(4)
+	OBU_METADATA               = OBUType(5)
+	OBU_FRAME                  = OBUType(6)
+	OBU_REDUNDANT_FRAME_HEADER = OBUType(7)
+	OBU_TILE_LIST              = OBUType(8)
+	OBU_PADDING                = OBUType(15)
+)
+
+// ErrOBUForbiddenBit is returned if the obu_forbidden_bit is set
+var ErrOBUForbiddenBit = errors.New("AV1 OBU forbidden bit set")
+
+// ErrOBUNoSizeField is returned by ValidateSample if an OBU has no obu_size field
+var ErrOBUNoSizeField = errors.New("AV1 OBU without size field")
+
+func (o OBUType) String() string {
+	switch o {
+	case OBU_SEQUENCE_HEADER:
+		return "OBU_SEQUENCE_HEADER"
+	case OBU_TEMPORAL_DELIMITER:
+		return "OBU_TEMPORAL_DELIMITER"
+	case OBU_FRAME_HEADER:
+		return "OBU_FRAME_HEADER"
+	case OBU_TILE_GROUP:
+		return "OBU_TILE_GROUP"
+	case OBU_METADATA:
+		return "OBU_METADATA"
+	case OBU_FRAME:
+		return "OBU_FRAME"
+	case OBU_REDUNDANT_FRAME_HEADER:
+		return "OBU_REDUNDANT_FRAME_HEADER"
+	case OBU_TILE_LIST:
+		return "OBU_TILE_LIST"
+	case OBU_PADDING:
+		return "OBU_PADDING"
+	default:
+		return fmt.Sprintf("OBU_RESERVED_%d", byte(o))
+	}
+}
+
+// OBU - one Open Bitstream Unit with parsed header
+type OBU struct {
+	Type         OBUType
+	HasExtension bool
+	HasSize      bool
+	TemporalID   byte
+	SpatialID    byte
+	Data         []byte // The complete OBU including header
+	Payload      []byte // The OBU payload after header and size field
+}
+
+// Size - total size of OBU in bytes
+func (o *OBU) Size() int {
+	return len(o.Data)
+}
+
+// ParseOBUs splits data, for example a temporal unit (sample), into OBUs.
+// An OBU without obu_size field extends to the end of data.
+func ParseOBUs(data []byte) ([]OBU, error) {
+	var obus []OBU
+	pos := 0
+	for pos < len(data) {
+		obu, err := parseOBU(data[pos:])
+		if err != nil {
+			return nil, fmt.Errorf("OBU at pos %d: %w", pos, err)
+		}
+		obus = append(obus, obu)
+		pos += obu.Size()
+	}
+	return obus, nil
+}
+
+// parseOBU parses the first OBU in data.
+func parseOBU(data []byte) (OBU, error) {
+	obu := OBU{}
+	if len(data) < 1 {
+		return obu, fmt.Errorf("no data")
+	}
+	hdr := data[0]
+	if hdr&0x80 != 0 {
+		return obu, ErrOBUForbiddenBit
+	}
+	obu.Type = OBUType((hdr >> 3) & 0x0f)
+	obu.HasExtension = hdr&0x04 != 0
+	obu.HasSize = hdr&0x02 != 0
+	pos := 1
+	if obu.HasExtension {
+		if len(data) < 2 {
+			return obu, fmt.Errorf("no data for extension header")
+		}
+		obu.TemporalID = data[1] >> 5
+		obu.SpatialID = (data[1] >> 3) & 0x03
+		pos++
+	}
+	payloadSize := len(data) - pos
+	if obu.HasSize {
+		size, n, err := readLeb128(data[pos:])
+		if err != nil {
+			return obu, err
+		}
+		pos += n
+		if size > uint64(len(data)-pos) {
+			return obu, fmt.Errorf("obu_size %d larger than remaining %d bytes", size, len(data)-pos)
+		}
+		payloadSize = int(size)
+	}
+	obu.Data = data[:pos+payloadSize]
+	obu.Payload = data[pos : pos+payloadSize]
+	return obu, nil
+}
+
+// readLeb128 reads an unsigned leb128 value and returns the value and the number of bytes read.
+func readLeb128(data []byte) (value uint64, n int, err error) {
+	for i := 0; i < 8; i++ {
+		if i >= len(data) {
+			return 0, 0, fmt.Errorf("leb128 beyond data")
+		}
+		b := data[i]
+		value |= uint64(b&0x7f) << (7 * i)
+		if b&0x80 == 0 {
+			return value, i + 1, nil
+		}
+	}
+	return 0, 0, fmt.Errorf("leb128 longer than 8 bytes")
+}
+
+// ValidateSample checks that sample is a sequence of OBUs that all have obu_has_size_field set.
+// ISO/IEC AV1 in ISOBMFF allows the last OBU of a sample to lack the size field, but such
+// samples are not handled by all players, so they result in ErrOBUNoSizeField here.
+func ValidateSample(sample []byte) error {
+	if len(sample) == 0 {
+		return fmt.Errorf("empty sample")
+	}
+	obus, err := ParseOBUs(sample)
+	if err != nil {
+		return err
+	}
+	for i, obu := range obus {
+		if !obu.HasSize {
+			return fmt.Errorf("OBU %d (%s): %w", i+1, obu.Type, ErrOBUNoSizeField)
+		}
+	}
+	return nil
+}
+
+// EnsureSizeFields returns sample with obu_size fields added to OBUs that lack them.
+// sample is returned unchanged if all OBUs already have size fields.
+func EnsureSizeFields(sample []byte) ([]byte, error) {
+	obus, err := ParseOBUs(sample)
+	if err != nil {
+		return nil, err
+	}
+	allHaveSize := true
+	for _, obu := range obus {
+		if !obu.HasSize {
+			allHaveSize = false
+			break
+		}
+	}
+	if allHaveSize {
+		return sample, nil
+	}
+	out := make([]byte, 0, len(sample)+8)
+	for _, obu := range obus {
+		if obu.HasSize {
+			out = append(out, obu.Data...)
+			continue
+		}
+		hdrLen := 1
+		if obu.HasExtension {
+			hdrLen = 2
+		}
+		out = append(out, obu.Data[0]|0x02)
+		out = append(out, obu.Data[1:hdrLen]...)
+		out = appendLeb128(out, uint64(len(obu.Payload)))
+		out = append(out, obu.Payload...)
+	}
+	return out, nil
+}
+
+// appendLeb128 appends value as unsigned leb128 with the minimal number of bytes.
+func appendLeb128(data []byte, value uint64) []byte {
+	for {
+		b := byte(value & 0x7f)
+		value >>= 7
+		if value == 0 {
+			return append(data, b)
+		}
+		data = append(data, b|0x80)
+	}
+}
@@ -0,0 +1,84 @@
+package av1
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+func TestParseOBUs(t *testing.T) {
+	seqHdr, _ := hex.DecodeString(configOBUs)
+	// Temporal delimiter, sequence header, and padding OBU without obu_size field
+	data := append([]byte{0x12, 0x00}, seqHdr...)
+	data = append(data, 0x78, 0xaa, 0xbb)
+
+	obus, err := ParseOBUs(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantedTypes := []OBUType{OBU_TEMPORAL_DELIMITER, OBU_SEQUENCE_HEADER, OBU_PADDING}
+	wantedSizes := []int{2, 13, 3}
+	if len(obus) != len(wantedTypes) {
+		t.Fatalf("got %d OBUs instead of %d", len(obus), len(wantedTypes))
+	}
+	for i, obu := range obus {
+		if obu.Type != wantedTypes[i] {
+			t.Errorf("OBU %d: got type %s instead of %s", i, obu.Type, wantedTypes[i])
+		}
+		if obu.Size() != wantedSizes[i] {
+			t.Errorf("OBU %d: got size %d instead of %d", i, obu.Size(), wantedSizes[i])
+		}
+	}
+	if len(obus[1].Payload) != 11 {
+		t.Errorf("got sequence header payload size %d instead of 11", len(obus[1].Payload))
+	}
+
+	if _, err := ParseOBUs([]byte{0x0a, 0x0b, 0x00}); err == nil {
+		t.Error("expected error for too short OBU")
+	}
+	if _, err := ParseOBUs([]byte{0x92, 0x00}); !errors.Is(err, ErrOBUForbiddenBit) {
+		t.Errorf("expected ErrOBUForbiddenBit, got %v", err)
+	}
+}
+
+func TestEnsureSizeFields(t *testing.T) {
+	seqHdr, _ := hex.DecodeString(configOBUs)
+	// Temporal delimiter, sequence header, and padding OBU without obu_size field
+	sample := append([]byte{0x12, 0x00}, seqHdr...)
+	sample = append(sample, 0x78, 0xaa, 0xbb)
+	if err := ValidateSample(sample); !errors.Is(err, ErrOBUNoSizeField) {
+		t.Errorf("expected ErrOBUNoSizeField, got %v", err)
+	}
+	fixed, err := EnsureSizeFields(sample)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ValidateSample(fixed); err != nil {
+		t.Error(err)
+	}
+	wanted := append(append([]byte{0x12, 0x00}, seqHdr...), 0x7a, 0x02, 0xaa, 0xbb)
+	if !bytes.Equal(fixed, wanted) {
+		t.Errorf("got %x instead of %x", fixed, wanted)
+	}
+	again, err := EnsureSizeFields(fixed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(again, fixed) {
+		t.Errorf("sample with size fields changed")
+	}
+
+	// Padding OBU with extension header and 200-byte payload needing a 2-byte leb128 size
+	ext := append([]byte{0x7c, 0x08}, make([]byte, 200)...)
+	fixed, err = EnsureSizeFields(ext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(fixed[:4], []byte{0x7e, 0x08, 0xc8, 0x01}) || len(fixed) != 204 {
+		t.Errorf("got header %x and length %d", fixed[:4], len(fixed))
+	}
+	if err := ValidateSample(nil); err == nil {
+		t.Error("expected error for empty sample")
+	}
+}
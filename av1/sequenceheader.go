@@ -0,0 +1,319 @@
+package av1
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/Eyevinn/mp4ff/bits"
+)
+
+// Default color values when color_description_present_flag is 0
+const (
+	CP_BT_709      = 1
+	CP_UNSPECIFIED = 2
+	TC_UNSPECIFIED = 2
+	TC_SRGB        = 13
+	MC_IDENTITY    = 0
+	MC_UNSPECIFIED = 2
+)
+
+// selectScreenContentTools is the value SELECT_SCREEN_CONTENT_TOOLS (and SELECT_INTEGER_MV)
+const selectScreenContentTools = 2
+
+// SequenceHeader - AV1 sequence header OBU payload.
+// Specified in AV1 Bitstream & Decoding Process Specification Section 5.5.
+type SequenceHeader struct {
+	SeqProfile                  byte
+	StillPicture                bool
+	ReducedStillPictureHeader   bool
+	TimingInfoPresentFlag       bool
+	TimingInfo                  *TimingInfo
+	DecoderModelInfoPresentFlag bool
+	DecoderModelInfo            *DecoderModelInfo
+	InitialDisplayDelayPresent  bool
+	OperatingPoints             []OperatingPoint
+	FrameWidthBitsMinus1        byte
+	FrameHeightBitsMinus1       byte
+	MaxFrameWidthMinus1         uint32
+	MaxFrameHeightMinus1        uint32
+	FrameIDNumbersPresentFlag   bool
+	DeltaFrameIDLengthMinus2    byte
+	AdditionalFrameIDLengthM1   byte
+	Use128x128Superblock        bool
+	EnableFilterIntra           bool
+	EnableIntraEdgeFilter       bool
+	EnableInterintraCompound    bool
+	EnableMaskedCompound        bool
+	EnableWarpedMotion          bool
+	EnableDualFilter            bool
+	EnableOrderHint             bool
+	EnableJntComp               bool
+	EnableRefFrameMvs           bool
+	SeqChooseScreenContentTools bool
+	SeqForceScreenContentTools  byte
+	SeqForceIntegerMv           byte
+	OrderHintBitsMinus1         byte
+	EnableSuperres              bool
+	EnableCdef                  bool
+	EnableRestoration           bool
+	ColorConfig                 ColorConfig
+	FilmGrainParamsPresent      bool
+}
+
+// TimingInfo - timing_info()
+type TimingInfo struct {
+	NumUnitsInDisplayTick    uint32
+	TimeScale                uint32
+	EqualPictureInterval     bool
+	NumTicksPerPictureMinus1 uint32
+}
+
+// DecoderModelInfo - decoder_model_info()
+type DecoderModelInfo struct {
+	BufferDelayLengthMinus1           byte
+	NumUnitsInDecodingTick            uint32
+	BufferRemovalTimeLengthMinus1     byte
+	FramePresentationTimeLengthMinus1 byte
+}
+
+// OperatingPoint - operating point parameters in sequence header
+type OperatingPoint struct {
+	IDC                        uint16
+	SeqLevelIdx                byte
+	SeqTier                    byte
+	DecoderModelPresent        bool
+	DecoderBufferDelay         uint32
+	EncoderBufferDelay         uint32
+	LowDelayModeFlag           bool
+	InitialDisplayDelayPresent bool
+	InitialDisplayDelayMinus1  byte
+}
+
+// ColorConfig - color_config()
+type ColorConfig struct {
+	BitDepth                byte
+	MonoChrome              bool
+	ColorDescriptionPresent bool
+	ColorPrimaries          byte
+	TransferCharacteristics byte
+	MatrixCoefficients      byte
+	ColorRange              bool
+	SubsamplingX            byte
+	SubsamplingY            byte
+	ChromaSamplePosition    byte
+	SeparateUVDeltaQ        bool
+}
+
+// Width - max frame width in pixels
+func (s *SequenceHeader) Width() uint32 {
+	return s.MaxFrameWidthMinus1 + 1
+}
+
+// Height - max frame height in pixels
+func (s *SequenceHeader) Height() uint32 {
+	return s.MaxFrameHeightMinus1 + 1
+}
+
+// ParseSequenceHeader parses a complete sequence header OBU including the OBU header.
+func ParseSequenceHeader(obu []byte) (*SequenceHeader, error) {
+	o, err := parseOBU(obu)
+	if err != nil {
+		return nil, err
+	}
+	if o.Type != OBU_SEQUENCE_HEADER {
+		return nil, fmt.Errorf("OBU type %s is not sequence header", o.Type)
+	}
+	return ParseSequenceHeaderPayload(o.Payload)
+}
+
+// ParseSequenceHeaderPayload parses a sequence_header_obu() payload without OBU header.
+func ParseSequenceHeaderPayload(payload []byte) (*SequenceHeader, error) {
+	r := bits.NewReader(bytes.NewReader(payload))
+	sh := &SequenceHeader{}
+	sh.SeqProfile = byte(r.Read(3))
+	if sh.SeqProfile > 2 {
+		return nil, fmt.Errorf("seq_profile %d not supported", sh.SeqProfile)
+	}
+	sh.StillPicture = r.ReadFlag()
+	sh.ReducedStillPictureHeader = r.ReadFlag()
+	if sh.ReducedStillPictureHeader {
+		sh.OperatingPoints = []OperatingPoint{{SeqLevelIdx: byte(r.Read(5))}}
+	} else {
+		sh.TimingInfoPresentFlag = r.ReadFlag()
+		if sh.TimingInfoPresentFlag {
+			ti := &TimingInfo{}
+			ti.NumUnitsInDisplayTick = uint32(r.Read(32))
+			ti.TimeScale = uint32(r.Read(32))
+			ti.EqualPictureInterval = r.ReadFlag()
+			if ti.EqualPictureInterval {
+				ti.NumTicksPerPictureMinus1 = readUvlc(r)
+			}
+			sh.TimingInfo = ti
+			sh.DecoderModelInfoPresentFlag = r.ReadFlag()
+			if sh.DecoderModelInfoPresentFlag {
+				dmi := &DecoderModelInfo{}
+				dmi.BufferDelayLengthMinus1 = byte(r.Read(5))
+				dmi.NumUnitsInDecodingTick = uint32(r.Read(32))
+				dmi.BufferRemovalTimeLengthMinus1 = byte(r.Read(5))
+				dmi.FramePresentationTimeLengthMinus1 = byte(r.Read(5))
+				sh.DecoderModelInfo = dmi
+			}
+		}
+		sh.InitialDisplayDelayPresent = r.ReadFlag()
+		operatingPointsCntMinus1 := int(r.Read(5))
+		for i := 0; i <= operatingPointsCntMinus1; i++ {
+			op := OperatingPoint{}
+			op.IDC = uint16(r.Read(12))
+			op.SeqLevelIdx = byte(r.Read(5))
+			if op.SeqLevelIdx > 7 {
+				op.SeqTier = byte(r.Read(1))
+			}
+			if sh.DecoderModelInfoPresentFlag {
+				op.DecoderModelPresent = r.ReadFlag()
+				if op.DecoderModelPresent {
+					n := int(sh.DecoderModelInfo.BufferDelayLengthMinus1) + 1
+					op.DecoderBufferDelay = uint32(r.Read(n))
+					op.EncoderBufferDelay = uint32(r.Read(n))
+					op.LowDelayModeFlag = r.ReadFlag()
+				}
+			}
+			if sh.InitialDisplayDelayPresent {
+				op.InitialDisplayDelayPresent = r.ReadFlag()
+				if op.InitialDisplayDelayPresent {
+					op.InitialDisplayDelayMinus1 = byte(r.Read(4))
+				}
+			}
+			sh.OperatingPoints = append(sh.OperatingPoints, op)
+		}
+	}
+	sh.FrameWidthBitsMinus1 = byte(r.Read(4))
+	sh.FrameHeightBitsMinus1 = byte(r.Read(4))
+	sh.MaxFrameWidthMinus1 = uint32(r.Read(int(sh.FrameWidthBitsMinus1) + 1))
+	sh.MaxFrameHeightMinus1 = uint32(r.Read(int(sh.FrameHeightBitsMinus1) + 1))
+	if !sh.ReducedStillPictureHeader {
+		sh.FrameIDNumbersPresentFlag = r.ReadFlag()
+	}
+	if sh.FrameIDNumbersPresentFlag {
+		sh.DeltaFrameIDLengthMinus2 = byte(r.Read(4))
+		sh.AdditionalFrameIDLengthM1 = byte(r.Read(3))
+	}
+	sh.Use128x128Superblock = r.ReadFlag()
+	sh.EnableFilterIntra = r.ReadFlag()
+	sh.EnableIntraEdgeFilter = r.ReadFlag()
+	if sh.ReducedStillPictureHeader {
+		sh.SeqForceScreenContentTools = selectScreenContentTools
+		sh.SeqForceIntegerMv = selectScreenContentTools
+	} else {
+		sh.EnableInterintraCompound = r.ReadFlag()
+		sh.EnableMaskedCompound = r.ReadFlag()
+		sh.EnableWarpedMotion = r.ReadFlag()
+		sh.EnableDualFilter = r.ReadFlag()
+		sh.EnableOrderHint = r.ReadFlag()
+		if sh.EnableOrderHint {
+			sh.EnableJntComp = r.ReadFlag()
+			sh.EnableRefFrameMvs = r.ReadFlag()
+		}
+		sh.SeqChooseScreenContentTools = r.ReadFlag()
+		if sh.SeqChooseScreenContentTools {
+			sh.SeqForceScreenContentTools = selectScreenContentTools
+		} else {
+			sh.SeqForceScreenContentTools = byte(r.Read(1))
+		}
+		if sh.SeqForceScreenContentTools > 0 {
+			seqChooseIntegerMv := r.ReadFlag()
+			if seqChooseIntegerMv {
+				sh.SeqForceIntegerMv = selectScreenContentTools
+			} else {
+				sh.SeqForceIntegerMv = byte(r.Read(1))
+			}
+		} else {
+			sh.SeqForceIntegerMv = selectScreenContentTools
+		}
+		if sh.EnableOrderHint {
+			sh.OrderHintBitsMinus1 = byte(r.Read(3))
+		}
+	}
+	sh.EnableSuperres = r.ReadFlag()
+	sh.EnableCdef = r.ReadFlag()
+	sh.EnableRestoration = r.ReadFlag()
+	sh.ColorConfig = parseColorConfig(r, sh.SeqProfile)
+	sh.FilmGrainParamsPresent = r.ReadFlag()
+	if r.AccError() != nil {
+		return nil, fmt.Errorf("sequence header: %w", r.AccError())
+	}
+	return sh, nil
+}
+
+// parseColorConfig - parse color_config() as specified in Section 5.5.2
+func parseColorConfig(r *bits.Reader, seqProfile byte) ColorConfig {
+	cc := ColorConfig{BitDepth: 8}
+	highBitdepth := r.ReadFlag()
+	if seqProfile == 2 && highBitdepth {
+		if r.ReadFlag() {
+			cc.BitDepth = 12
+		} else {
+			cc.BitDepth = 10
+		}
+	} else if highBitdepth {
+		cc.BitDepth = 10
+	}
+	if seqProfile != 1 {
+		cc.MonoChrome = r.ReadFlag()
+	}
+	cc.ColorDescriptionPresent = r.ReadFlag()
+	if cc.ColorDescriptionPresent {
+		cc.ColorPrimaries = byte(r.Read(8))
+		cc.TransferCharacteristics = byte(r.Read(8))
+		cc.MatrixCoefficients = byte(r.Read(8))
+	} else {
+		cc.ColorPrimaries = CP_UNSPECIFIED
+		cc.TransferCharacteristics = TC_UNSPECIFIED
+		cc.MatrixCoefficients = MC_UNSPECIFIED
+	}
+	switch {
+	case cc.MonoChrome:
+		cc.ColorRange = r.ReadFlag()
+		cc.SubsamplingX, cc.SubsamplingY = 1, 1
+		return cc
+	case cc.ColorPrimaries == CP_BT_709 && cc.TransferCharacteristics == TC_SRGB &&
+		cc.MatrixCoefficients == MC_IDENTITY:
+		cc.ColorRange = true
+	default:
+		cc.ColorRange = r.ReadFlag()
+		switch seqProfile {
+		case 0:
+			cc.SubsamplingX, cc.SubsamplingY = 1, 1
+		case 1:
+		default:
+			if cc.BitDepth == 12 {
+				cc.SubsamplingX = byte(r.Read(1))
+				if cc.SubsamplingX == 1 {
+					cc.SubsamplingY = byte(r.Read(1))
+				}
+			} else {
+				cc.SubsamplingX = 1
+			}
+		}
+		if cc.SubsamplingX == 1 && cc.SubsamplingY == 1 {
+			cc.ChromaSamplePosition = byte(r.Read(2))
+		}
+	}
+	cc.SeparateUVDeltaQ = r.ReadFlag()
+	return cc
+}
+
+// readUvlc - read variable length unsigned integer uvlc() as specified in Section 4.10.3
+func readUvlc(r *bits.Reader) uint32 {
+	leadingZeros := 0
+	for !r.ReadFlag() {
+		if r.AccError() != nil {
+			return 0
+		}
+		leadingZeros++
+	}
+	if leadingZeros >= 32 {
+		return (1 << 32) - 1
+	}
+	value := uint32(r.Read(leadingZeros))
+	return value + (1 << leadingZeros) - 1
+}
@@ -0,0 +1,37 @@
+package av1
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+func TestParseSequenceHeader(t *testing.T) {
+	data, _ := hex.DecodeString(configOBUs)
+	sh, err := ParseSequenceHeader(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sh.SeqProfile != 0 {
+		t.Errorf("got seq_profile %d instead of 0", sh.SeqProfile)
+	}
+	if len(sh.OperatingPoints) != 1 || sh.OperatingPoints[0].SeqLevelIdx != 9 {
+		t.Errorf("got operating points %+v instead of one with level 9", sh.OperatingPoints)
+	}
+	if sh.Width() != 1920 || sh.Height() != 1080 {
+		t.Errorf("got resolution %dx%d instead of 1920x1080", sh.Width(), sh.Height())
+	}
+	cc := sh.ColorConfig
+	if cc.BitDepth != 10 || cc.MonoChrome || cc.SubsamplingX != 1 || cc.SubsamplingY != 1 {
+		t.Errorf("got unexpected color config %+v", cc)
+	}
+	if cc.ColorPrimaries != CP_UNSPECIFIED || cc.ColorRange {
+		t.Errorf("got unexpected color description %+v", cc)
+	}
+
+	if _, err := ParseSequenceHeader([]byte{0x12, 0x00}); err == nil {
+		t.Error("expected error for temporal delimiter OBU")
+	}
+	if _, err := ParseSequenceHeader(data[:6]); err == nil {
+		t.Error("expected error for truncated sequence header")
+	}
+}
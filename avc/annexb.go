@@ -4,6 +4,7 @@ package avc
 
 import (
 	"encoding/binary"
+	"fmt"
 	"math/bits"
 	"unsafe"
 )
@@ -178,6 +179,69 @@ func ConvertSampleToByteStream(sample []byte) []byte {
 	return sample
 }
 
+// AnnexBToAVCC converts an Annex B byte stream to NALUs with length fields of nalLengthSize bytes,
+// as used in MP4 samples. nalLengthSize must be 1, 2, or 4. Trailing zero bytes of the NALUs are removed.
+// The output is a new slice. This function is codec agnostic.
+func AnnexBToAVCC(data []byte, nalLengthSize int) ([]byte, error) {
+	if err := checkNalLengthSize(nalLengthSize); err != nil {
+		return nil, err
+	}
+	nalus := ExtractNalusFromByteStream(data)
+	if nalus == nil {
+		return nil, fmt.Errorf("no start code found")
+	}
+	size := 0
+	for _, nalu := range nalus {
+		size += nalLengthSize + len(nalu)
+	}
+	out := make([]byte, 0, size)
+	for _, nalu := range nalus {
+		if nalLengthSize < 4 && len(nalu) >= 1<<(8*nalLengthSize) {
+			return nil, fmt.Errorf("NALU length %d does not fit in %d bytes", len(nalu), nalLengthSize)
+		}
+		out = appendNaluLength(out, len(nalu), nalLengthSize)
+		out = append(out, nalu...)
+	}
+	return out, nil
+}
+
+// AVCCToAnnexB converts NALUs with length fields of nalLengthSize bytes, as in MP4 samples,
+// to an Annex B byte stream with 4-byte start codes. nalLengthSize must be 1, 2, or 4.
+// The output is a new slice. This function is codec agnostic.
+func AVCCToAnnexB(data []byte, nalLengthSize int) ([]byte, error) {
+	out := make([]byte, 0, len(data)+len(data)/8)
+	var err error
+	NalusSeq(data, nalLengthSize)(func(nalu []byte, e error) bool {
+		if e != nil {
+			err = e
+			return false
+		}
+		out = append(out, 0, 0, 0, 1)
+		out = append(out, nalu...)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func checkNalLengthSize(nalLengthSize int) error {
+	switch nalLengthSize {
+	case 1, 2, 4:
+		return nil
+	default:
+		return fmt.Errorf("NAL length size %d not 1, 2, or 4", nalLengthSize)
+	}
+}
+
+func appendNaluLength(out []byte, naluLength, nalLengthSize int) []byte {
+	for i := nalLengthSize - 1; i >= 0; i-- {
+		out = append(out, byte(naluLength>>(8*i)))
+	}
+	return out
+}
+
 // GetParameterSetsFromByteStream copies AVC SPS and PPS nalus from bytestream (Annex B)
 func GetParameterSetsFromByteStream(data []byte) (spss, ppss [][]byte) {
 	n := len(data)
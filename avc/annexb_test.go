@@ -257,3 +257,47 @@ func BenchmarkByteStreamToNaluSample(b *testing.B) {
 		_ = ConvertByteStreamToNaluSample(data)
 	}
 }
+
+func TestAnnexBToAVCC(t *testing.T) {
+	annexB := []byte{0, 0, 0, 1, 0x67, 0x42, 0, 0, 1, 0x68, 0xce, 0, 0, 0, 0, 1, 0x65, 0x88, 0x84}
+	nalus := [][]byte{{0x67, 0x42}, {0x68, 0xce}, {0x65, 0x88, 0x84}}
+	for _, nalLengthSize := range []int{1, 2, 4} {
+		var wanted []byte
+		for _, nalu := range nalus {
+			wanted = append(wanted, make([]byte, nalLengthSize-1)...)
+			wanted = append(wanted, byte(len(nalu)))
+			wanted = append(wanted, nalu...)
+		}
+		got, err := AnnexBToAVCC(annexB, nalLengthSize)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, wanted) {
+			t.Errorf("length size %d: got %x instead of %x", nalLengthSize, got, wanted)
+		}
+		back, err := AVCCToAnnexB(got, nalLengthSize)
+		if err != nil {
+			t.Fatal(err)
+		}
+		gotNalus := ExtractNalusFromByteStream(back)
+		if diff := deep.Equal(gotNalus, nalus); diff != nil {
+			t.Errorf("length size %d: %v", nalLengthSize, diff)
+		}
+		if !bytes.HasPrefix(back, []byte{0, 0, 0, 1}) || len(back) != len(got)+len(nalus)*(4-nalLengthSize) {
+			t.Errorf("length size %d: bad Annex B output %x", nalLengthSize, back)
+		}
+	}
+	if _, err := AnnexBToAVCC(annexB, 3); err == nil {
+		t.Error("no error for NAL length size 3")
+	}
+	if _, err := AnnexBToAVCC([]byte{0x67, 0x42}, 4); err == nil {
+		t.Error("no error for missing start code")
+	}
+	long := append([]byte{0, 0, 1}, bytes.Repeat([]byte{0x65}, 256)...)
+	if _, err := AnnexBToAVCC(long, 1); err == nil {
+		t.Error("no error for NALU too long for length field")
+	}
+	if _, err := AVCCToAnnexB([]byte{0, 0, 0, 5, 0x65}, 4); err == nil {
+		t.Error("no error for NALU beyond end of data")
+	}
+}
@@ -24,3 +24,38 @@ func GetNalusFromSample(sample []byte) ([][]byte, error) {
 	}
 	return naluList, nil
 }
+
+// NalusSeq returns an iterator over the NALUs of a sample with length fields of lengthSize bytes.
+// lengthSize must be 1, 2, or 4. The NALUs are sub-slices of sample with capacity limited to their
+// length, so no data is copied. They should be treated as read-only.
+// The returned function has the signature of iter.Seq2[[]byte, error], so it can be used
+// with range-over-func in Go 1.23 and later. Iteration stops after the first error.
+func NalusSeq(sample []byte, lengthSize int) func(yield func([]byte, error) bool) {
+	return func(yield func([]byte, error) bool) {
+		if err := checkNalLengthSize(lengthSize); err != nil {
+			yield(nil, err)
+			return
+		}
+		pos := 0
+		for pos < len(sample) {
+			if pos+lengthSize > len(sample) {
+				yield(nil, fmt.Errorf("incomplete NALU length field at pos %d", pos))
+				return
+			}
+			naluLength := 0
+			for _, b := range sample[pos : pos+lengthSize] {
+				naluLength = naluLength<<8 | int(b)
+			}
+			pos += lengthSize
+			if naluLength > len(sample)-pos {
+				yield(nil, fmt.Errorf("NALU length %d at pos %d beyond end of sample", naluLength, pos-lengthSize))
+				return
+			}
+			end := pos + naluLength
+			if !yield(sample[pos:end:end], nil) {
+				return
+			}
+			pos = end
+		}
+	}
+}
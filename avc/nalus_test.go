@@ -0,0 +1,48 @@
+package avc
+
+import (
+	"testing"
+
+	"github.com/go-test/deep"
+)
+
+func TestNalusSeq(t *testing.T) {
+	sample := []byte{0, 0, 0, 2, 0x09, 0xf0, 0, 0, 0, 3, 0x65, 0x88, 0x84}
+	collect := func(sample []byte, lengthSize int, maxNr int) ([][]byte, error) {
+		var nalus [][]byte
+		var err error
+		NalusSeq(sample, lengthSize)(func(nalu []byte, e error) bool {
+			if e != nil {
+				err = e
+				return false
+			}
+			nalus = append(nalus, nalu)
+			return len(nalus) < maxNr
+		})
+		return nalus, err
+	}
+	nalus, err := collect(sample, 4, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := deep.Equal(nalus, [][]byte{{0x09, 0xf0}, {0x65, 0x88, 0x84}}); diff != nil {
+		t.Error(diff)
+	}
+	if &nalus[1][0] != &sample[10] || cap(nalus[0]) != 2 {
+		t.Error("NALU is not a capacity-limited sub-slice of sample")
+	}
+	nalus, err = collect(sample, 4, 1)
+	if err != nil || len(nalus) != 1 {
+		t.Errorf("iteration did not stop after first NALU: %d NALUs, err=%v", len(nalus), err)
+	}
+	nalus, err = collect([]byte{2, 0x09, 0xf0, 1, 0x0c}, 1, 10)
+	if err != nil || len(nalus) != 2 {
+		t.Errorf("got %d NALUs with length size 1, err=%v", len(nalus), err)
+	}
+	if _, err = collect(sample[:11], 4, 10); err == nil {
+		t.Error("no error for truncated NALU")
+	}
+	if _, err = collect(sample, 3, 10); err == nil {
+		t.Error("no error for length size 3")
+	}
+}
@@ -13,6 +13,8 @@ var (
 )
 
 // ParseSEINalu - parse SEI NAL unit (incl header) and return messages given SPS.
+// The HRD and pic_struct_present_flag values in the SPS VUI are used to parse
+// buffering_period and pic_timing messages.
 // Returns sei.ErrRbspTrailingBitsMissing if the NALU is missing the trailing bits.
 func ParseSEINalu(nalu []byte, sps *SPS) ([]sei.SEIMessage, error) {
 	if GetNaluType(nalu[0]) != NALU_SEI {
@@ -48,7 +50,9 @@ func ParseSEINalu(nalu []byte, sps *SPS) ([]sei.SEIMessage, error) {
 				}
 				timeOffsetLen = byte(hrdParams.TimeOffsetLength)
 			}
-			seiMsg, err = sei.DecodePicTimingAvcSEIHRD(&seiData, cbpDbpDelay, timeOffsetLen)
+			seiMsg, err = sei.DecodePicTimingAvcSEIPictStruct(&seiData, cbpDbpDelay, timeOffsetLen, sps.PicStructPresent())
+		case seiData.Type() == sei.SEIBufferingPeriodType && sps != nil && sps.VUI != nil:
+			seiMsg, err = sei.DecodeBufferingPeriodAvcSEI(&seiData, fillAVCBufferingPeriodParams(sps))
 		default:
 			seiMsg, err = sei.DecodeSEIMessage(&seiData, sei.AVC)
 		}
@@ -62,3 +66,18 @@ func ParseSEINalu(nalu []byte, sps *SPS) ([]sei.SEIMessage, error) {
 	}
 	return seiMsgs, nil
 }
+
+func fillAVCBufferingPeriodParams(sps *SPS) sei.AVCBufferingPeriodParams {
+	bpp := sei.AVCBufferingPeriodParams{}
+	if hrd := sps.VUI.NalHrdParameters; sps.VUI.NalHrdParametersPresentFlag && hrd != nil {
+		bpp.NalHrdBpPresentFlag = true
+		bpp.NalCpbCntMinus1 = uint8(hrd.CpbCountMinus1)
+		bpp.NalInitialCpbRemovalDelayLengthMinus1 = uint8(hrd.InitialCpbRemovalDelayLengthMinus1)
+	}
+	if hrd := sps.VUI.VclHrdParameters; sps.VUI.VclHrdParametersPresentFlag && hrd != nil {
+		bpp.VclHrdBpPresentFlag = true
+		bpp.VclCpbCntMinus1 = uint8(hrd.CpbCountMinus1)
+		bpp.VclInitialCpbRemovalDelayLengthMinus1 = uint8(hrd.InitialCpbRemovalDelayLengthMinus1)
+	}
+	return bpp
+}
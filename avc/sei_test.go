@@ -64,3 +64,35 @@ func TestSEIParsing(t *testing.T) {
 		})
 	}
 }
+
+func TestSEIBufferingPeriodParsing(t *testing.T) {
+	spsBytes, err := hex.DecodeString("6764002aac2cac0780227e5c04f000003e90001d4c0e6a000337ec001bcef5ef80f8442370")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sps, err := avc.ParseSPSNALUnit(spsBytes, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// buffering_period with NAL HRD initial_cpb_removal_delay 90000 and offset 1000
+	seiBytes, err := hex.DecodeString("0600078015f9000007d080")
+	if err != nil {
+		t.Fatal(err)
+	}
+	msgs, err := avc.ParseSEINalu(seiBytes, sps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != 1 {
+		t.Fatalf("expected 1 message, got %d", len(msgs))
+	}
+	bp, ok := msgs[0].(*sei.BufferingPeriodAvcSEI)
+	if !ok {
+		t.Fatalf("expected BufferingPeriodAvcSEI, got %T", msgs[0])
+	}
+	wanted := []sei.InitialCpbRemoval{{InitialCpbRemovalDelay: 90000, InitialCpbRemovalDelayOffset: 1000}}
+	if bp.SeqParameterSetID != 0 || len(bp.VclInitialCpbRemovals) != 0 ||
+		len(bp.NalInitialCpbRemovals) != 1 || bp.NalInitialCpbRemovals[0] != wanted[0] {
+		t.Errorf("got %s", bp)
+	}
+}
@@ -58,6 +58,8 @@ type ScalingList []int
 
 // VUIParameters - extra parameters according to 14496-10, E.1
 type VUIParameters struct {
+	AspectRatioInfoPresentFlag         bool
+	AspectRatioIDC                     uint
 	SampleAspectRatioWidth             uint
 	SampleAspectRatioHeight            uint
 	OverscanInfoPresentFlag            bool
@@ -261,6 +263,40 @@ func (s *SPS) PicStructPresent() bool {
 	return s.VUI.PicStructPresentFlag
 }
 
+// FrameRate returns the frame rate as time_scale / (2 * num_units_in_tick) according to Section E.2.1.
+// ok is false if there is no timing info.
+func (s *SPS) FrameRate() (numerator, denominator uint, ok bool) {
+	if s.VUI == nil || !s.VUI.TimingInfoPresentFlag || s.VUI.NumUnitsInTick == 0 {
+		return 0, 0, false
+	}
+	return s.VUI.TimeScale, 2 * s.VUI.NumUnitsInTick, true
+}
+
+// ColourDescription returns colour_primaries, transfer_characteristics, and matrix_coefficients.
+// If not present, the inferred value 2 (unspecified) is returned according to Section E.2.1.
+func (v *VUIParameters) ColourDescription() (colourPrimaries, transferCharacteristics, matrixCoefficients uint) {
+	if !v.ColourDescriptionFlag {
+		return 2, 2, 2
+	}
+	return v.ColourPrimaries, v.TransferCharacteristics, v.MatrixCoefficients
+}
+
+// BitRate returns the bit rate in bits/s for schedSelIdx according to Section E.2.2.
+func (h *HrdParameters) BitRate(schedSelIdx int) uint {
+	if schedSelIdx < 0 || schedSelIdx >= len(h.CpbEntries) {
+		return 0
+	}
+	return (h.CpbEntries[schedSelIdx].BitRateValueMinus1 + 1) << (6 + h.BitRateScale)
+}
+
+// CpbSize returns the CPB size in bits for schedSelIdx according to Section E.2.2.
+func (h *HrdParameters) CpbSize(schedSelIdx int) uint {
+	if schedSelIdx < 0 || schedSelIdx >= len(h.CpbEntries) {
+		return 0
+	}
+	return (h.CpbEntries[schedSelIdx].CpbSizeValueMinus1 + 1) << (4 + h.CpbSizeScale)
+}
+
 // ChromaArrayType as defined in Section 7.4.2.1.1 under separate_colour_plane_flag
 func (s *SPS) ChromaArrayType() byte {
 	if !s.SeparateColourPlaneFlag {
@@ -274,9 +310,10 @@ func (s *SPS) ChromaArrayType() byte {
 func parseVUI(reader *bits.EBSPReader, parseVUIBeyondAspectRatio bool) *VUIParameters {
 	vui := &VUIParameters{}
 	var err error
-	aspectRatioInfoPresentFlag := reader.ReadFlag()
-	if aspectRatioInfoPresentFlag {
+	vui.AspectRatioInfoPresentFlag = reader.ReadFlag()
+	if vui.AspectRatioInfoPresentFlag {
 		aspectRatioIDC := reader.Read(8)
+		vui.AspectRatioIDC = aspectRatioIDC
 		if aspectRatioIDC == ExtendedSAR {
 			vui.SampleAspectRatioWidth = reader.Read(16)
 			vui.SampleAspectRatioHeight = reader.Read(16)
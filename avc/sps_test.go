@@ -46,6 +46,8 @@ func TestSPSParser1(t *testing.T) {
 		Width:                           1280,
 		Height:                          720,
 		VUI: &VUIParameters{
+			AspectRatioInfoPresentFlag:  true,
+			AspectRatioIDC:              1,
 			SampleAspectRatioWidth:      1,
 			SampleAspectRatioHeight:     1,
 			VideoSignalTypePresentFlag:  true,
@@ -180,6 +182,8 @@ func TestSPSParser3(t *testing.T) {
 		Width:                           1280,
 		Height:                          720,
 		VUI: &VUIParameters{
+			AspectRatioInfoPresentFlag:  true,
+			AspectRatioIDC:              1,
 			SampleAspectRatioWidth:      1,
 			SampleAspectRatioHeight:     1,
 			TimingInfoPresentFlag:       true,
@@ -230,3 +234,38 @@ func TestCodecString(t *testing.T) {
 		t.Errorf("expected codec: %q, got %q", expected, codec)
 	}
 }
+
+func TestSPSFrameRateAndHrd(t *testing.T) {
+	byteData, _ := hex.DecodeString(sps1nalu)
+	sps, err := ParseSPSNALUnit(byteData, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	num, den, ok := sps.FrameRate()
+	if !ok || num != 100 || den != 2 {
+		t.Errorf("got frame rate %d/%d (%t) instead of 100/2", num, den, ok)
+	}
+	cp, tc, mc := sps.VUI.ColourDescription()
+	if cp != 2 || tc != 2 || mc != 2 {
+		t.Errorf("got colour description %d/%d/%d instead of 2/2/2", cp, tc, mc)
+	}
+	hrd := sps.VUI.NalHrdParameters
+	if got := hrd.BitRate(0); got != 34375<<7 {
+		t.Errorf("got bit rate %d instead of %d", got, 34375<<7)
+	}
+	if got := hrd.CpbSize(0); got != 34375<<7 {
+		t.Errorf("got cpb size %d instead of %d", got, 34375<<7)
+	}
+	if got := hrd.BitRate(1); got != 0 {
+		t.Errorf("got bit rate %d for non-existing entry", got)
+	}
+
+	byteData, _ = hex.DecodeString(sps2nalu)
+	sps, err = ParseSPSNALUnit(byteData, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if num, den, _ = sps.FrameRate(); num != 60 || den != 2 {
+		t.Errorf("got frame rate %d/%d instead of 60/2", num, den)
+	}
+}
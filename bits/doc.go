@@ -13,5 +13,6 @@ used in MPEG video standards from AVC (H.264) and forward. The main types are:
   - [ByteWriter] writes byte-based structures to an underlying [io.Writer] with accumulated error
   - [FixedSliceReader] reads various byte-based structures from a fixed slice with accumulated error
   - [FixedSliceWriter] writes various byte-based structures to a fixed slice with accumulated error
+  - [PooledSliceWriter] is a [FixedSliceWriter] with a buffer from a pool for reduced allocations
 */
 package bits
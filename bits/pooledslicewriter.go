@@ -0,0 +1,34 @@
+package bits
+
+import "sync"
+
+var sliceWriterPool = sync.Pool{
+	New: func() interface{} {
+		return &PooledSliceWriter{}
+	},
+}
+
+// PooledSliceWriter - FixedSliceWriter with a buffer from a pool of reusable buffers.
+// Call Release when the written bytes are no longer needed to return the buffer to the pool.
+type PooledSliceWriter struct {
+	FixedSliceWriter
+}
+
+// NewPooledSliceWriter returns a writer with a fixed size from a pool of buffers.
+// A buffer of a previously released writer is reused if it is large enough.
+func NewPooledSliceWriter(size int) *PooledSliceWriter {
+	sw := sliceWriterPool.Get().(*PooledSliceWriter)
+	buf := sw.buf
+	if cap(buf) < size {
+		buf = make([]byte, size)
+	}
+	sw.FixedSliceWriter = FixedSliceWriter{buf: buf[:size]}
+	return sw
+}
+
+// Release returns the writer and its buffer to the pool.
+// Neither the writer nor the slice returned by Bytes may be used afterwards.
+func (sw *PooledSliceWriter) Release() {
+	sw.FixedSliceWriter = FixedSliceWriter{buf: sw.buf[:0]}
+	sliceWriterPool.Put(sw)
+}
@@ -0,0 +1,34 @@
+package bits_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/Eyevinn/mp4ff/bits"
+)
+
+func TestPooledSliceWriter(t *testing.T) {
+	var sw bits.SliceWriter = bits.NewPooledSliceWriter(6)
+	sw.WriteUint16(0x0102)
+	sw.WriteUint32(0x03040506)
+	if !bytes.Equal(sw.Bytes(), []byte{1, 2, 3, 4, 5, 6}) {
+		t.Errorf("got %x", sw.Bytes())
+	}
+	sw.WriteUint8(7)
+	if sw.AccError() != bits.ErrSliceWrite {
+		t.Errorf("expected overflow error, got %v", sw.AccError())
+	}
+	sw.(*bits.PooledSliceWriter).Release()
+
+	for _, size := range []int{4, 64} {
+		psw := bits.NewPooledSliceWriter(size)
+		if psw.Len() != 0 || psw.Capacity() != size || psw.AccError() != nil {
+			t.Errorf("size %d: got len %d capacity %d err %v", size, psw.Len(), psw.Capacity(), psw.AccError())
+		}
+		psw.WriteZeroBytes(size)
+		if !bytes.Equal(psw.Bytes(), make([]byte, size)) {
+			t.Errorf("size %d: got %x instead of zeros", size, psw.Bytes())
+		}
+		psw.Release()
+	}
+}
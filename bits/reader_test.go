@@ -92,6 +92,20 @@ func TestAccErrReader(t *testing.T) {
 		}
 	})
 
+	t.Run("Read exp-Golomb", func(t *testing.T) {
+		input := []byte{0xa6, 0x42} // 1 010 011 00100 0010
+		r := bits.NewReader(bytes.NewReader(input))
+		for _, want := range []uint{0, 1, 2, 3} {
+			if got := r.ReadExpGolomb(); got != want {
+				t.Errorf("ReadExpGolomb()=%d, want=%d", got, want)
+			}
+		}
+		_ = r.ReadExpGolomb()
+		if err := r.AccError(); err != io.EOF {
+			t.Errorf("wanted error %v, got %v", io.EOF, err)
+		}
+	})
+
 	t.Run("Read flags", func(t *testing.T) {
 		input := []byte{0xe5} // 1110 0101
 		rd := bytes.NewReader(input)
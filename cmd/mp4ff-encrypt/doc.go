@@ -5,6 +5,12 @@ For a pure media segment, an init segment with encryption information is needed.
 For video, only AVC with avc1 and HEVC with hvc1 sample entries are currently supported.
 For audio, all supported audio codecs should work.
 
+With -trackconfig, a combined file is encrypted with one configuration per track, given
+as a JSON file mapping trackID to scheme, kid, key, iv, and optional pattern, like
+{"1": {"scheme": "cenc", "kid": "<hex>", "key": "<hex>", "iv": "<hex>"}}.
+Tracks not in the configuration are left in the clear.
+The -key, -iv, -kid, -scheme, -pattern, and -init options are then not used.
+
 Usage of mp4ff-encrypt:
 
 mp4ff-encrypt [options] infile outfile
@@ -19,10 +25,14 @@ options:
 	      Required: key (32 hex or 24 base64 chars)
 	-kid string
 	      key id (32 hex or 24 base64 chars). Required if initFilePath empty
+	-pattern string
+	      cbcs video crypt:skip pattern in 16-byte blocks (default "1:9")
 	-pssh string
 	      file with one or more pssh box(es) in binary format. Will be added at end of moov box
 	-scheme string
 	      cenc or cbcs. Required if initFilePath empty (default "cenc")
+	-trackconfig string
+	      JSON file with per-track encryption config mapping trackID to scheme, kid, key, iv, and pattern
 	-version
 	      Get mp4ff version
 */
@@ -2,6 +2,7 @@ package main
 
 import (
 	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
@@ -22,6 +23,12 @@ For a pure media segment, an init segment with encryption information is needed.
 For video, only AVC with avc1 and HEVC with hvc1 sample entries are currently supported.
 For audio, all supported audio codecs should work.
 
+With -trackconfig, a combined file is encrypted with one configuration per track, given
+as a JSON file mapping trackID to scheme, kid, key, iv, and optional pattern, like
+{"1": {"scheme": "cenc", "kid": "<hex>", "key": "<hex>", "iv": "<hex>"}}.
+Tracks not in the configuration are left in the clear.
+The -key, -iv, -kid, -scheme, -pattern, and -init options are then not used.
+
 Usage of %s:
 `
 
@@ -31,7 +38,9 @@ type options struct {
 	keyStr   string
 	ivHex    string
 	scheme   string
+	pattern  string
 	psshFile string
+	trackCfg string
 	version  bool
 }
 
@@ -49,7 +58,9 @@ func parseOptions(fs *flag.FlagSet, args []string) (*options, error) {
 	fs.StringVar(&opts.keyStr, "key", "", "Required: key (32 hex or 24 base64 chars)")
 	fs.StringVar(&opts.ivHex, "iv", "", "Required: iv (16 or 32 hex chars)")
 	fs.StringVar(&opts.scheme, "scheme", "cenc", "cenc or cbcs. Required if initFilePath empty")
+	fs.StringVar(&opts.pattern, "pattern", "1:9", "cbcs video crypt:skip pattern in 16-byte blocks")
 	fs.StringVar(&opts.psshFile, "pssh", "", "file with one or more pssh box(es) in binary format. Will be added at end of moov box")
+	fs.StringVar(&opts.trackCfg, "trackconfig", "", "JSON file with per-track encryption config mapping trackID to scheme, kid, key, iv, and pattern")
 	fs.BoolVar(&opts.version, "version", false, "Get mp4ff version")
 
 	err := fs.Parse(args[1:])
@@ -87,7 +98,13 @@ func run(args []string) error {
 	var inFilePath = fs.Arg(0)
 	var outFilePath = fs.Arg(1)
 
-	if opts.keyStr == "" || opts.ivHex == "" {
+	var perTrack map[uint32]mp4.TrackEncConfig
+	if opts.trackCfg != "" {
+		perTrack, err = readTrackConfig(opts.trackCfg)
+		if err != nil {
+			return err
+		}
+	} else if opts.keyStr == "" || opts.ivHex == "" {
 		fs.Usage()
 		return fmt.Errorf("need both key and iv")
 	}
@@ -129,15 +146,38 @@ func run(args []string) error {
 		}
 	}
 
-	err = encryptFile(ifh, ofh, initSeg, opts.scheme, opts.kidStr, opts.keyStr, opts.ivHex, psshData)
+	if perTrack != nil {
+		err = encryptFilePerTrack(ifh, ofh, perTrack, psshData)
+		if err != nil {
+			return fmt.Errorf("encryptFilePerTrack: %w", err)
+		}
+		return nil
+	}
+
+	pattern, err := parsePattern(opts.pattern)
+	if err != nil {
+		return err
+	}
+
+	err = encryptFile(ifh, ofh, initSeg, opts.scheme, pattern, opts.kidStr, opts.keyStr, opts.ivHex, psshData)
 	if err != nil {
 		return fmt.Errorf("encryptFile: %w", err)
 	}
 	return nil
 }
 
+// parsePattern parses a cbcs pattern on the form crypt:skip.
+func parsePattern(pattern string) (mp4.CryptPattern, error) {
+	var crypt, skip byte
+	n, err := fmt.Sscanf(pattern, "%d:%d", &crypt, &skip)
+	if err != nil || n != 2 {
+		return mp4.CryptPattern{}, fmt.Errorf("bad pattern %q, should be crypt:skip", pattern)
+	}
+	return mp4.CryptPattern{CryptByteBlock: crypt, SkipByteBlock: skip}, nil
+}
+
 func encryptFile(ifh io.Reader, ofh io.Writer, initSeg *mp4.InitSegment,
-	scheme, kidStr, keyStr, ivHex string, psshData []byte) error {
+	scheme string, pattern mp4.CryptPattern, kidStr, keyStr, ivHex string, psshData []byte) error {
 
 	if len(ivHex) != 32 && len(ivHex) != 16 {
 		return fmt.Errorf("hex iv must have length 16 or 32 chars; %d", len(ivHex))
@@ -178,7 +218,7 @@ func encryptFile(ifh io.Reader, ofh io.Writer, initSeg *mp4.InitSegment,
 		if err != nil {
 			return fmt.Errorf("pssh boxes from data: %w", err)
 		}
-		ipd, err = mp4.InitProtect(inFile.Init, key, iv, scheme, kidUUID, psshBoxes)
+		ipd, err = mp4.InitProtectWithPattern(inFile.Init, key, iv, scheme, kidUUID, psshBoxes, pattern)
 		if err != nil {
 			return fmt.Errorf("init protect: %w", err)
 		}
@@ -203,3 +243,82 @@ func encryptFile(ifh io.Reader, ofh io.Writer, initSeg *mp4.InitSegment,
 	}
 	return inFile.Encode(ofh)
 }
+
+// trackConfig is the JSON encryption configuration for one track.
+type trackConfig struct {
+	Scheme  string `json:"scheme"`
+	KID     string `json:"kid"`
+	Key     string `json:"key"`
+	IV      string `json:"iv"`
+	Pattern string `json:"pattern,omitempty"`
+}
+
+// readTrackConfig reads a JSON file mapping trackID to trackConfig.
+func readTrackConfig(path string) (map[uint32]mp4.TrackEncConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read track config: %w", err)
+	}
+	var cfgs map[uint32]trackConfig
+	if err := json.Unmarshal(data, &cfgs); err != nil {
+		return nil, fmt.Errorf("could not parse track config: %w", err)
+	}
+	if len(cfgs) == 0 {
+		return nil, fmt.Errorf("no tracks in track config")
+	}
+	perTrack := make(map[uint32]mp4.TrackEncConfig, len(cfgs))
+	for trackID, cfg := range cfgs {
+		if cfg.Scheme != "cenc" && cfg.Scheme != "cbcs" {
+			return nil, fmt.Errorf("track %d: scheme must be cenc or cbcs: %s", trackID, cfg.Scheme)
+		}
+		key, err := mp4.UnpackKey(cfg.Key)
+		if err != nil {
+			return nil, fmt.Errorf("track %d: invalid key %s: %w", trackID, cfg.Key, err)
+		}
+		kid, err := mp4.UnpackKey(cfg.KID)
+		if err != nil {
+			return nil, fmt.Errorf("track %d: invalid key ID %s: %w", trackID, cfg.KID, err)
+		}
+		kidUUID, _ := mp4.NewUUIDFromString(hex.EncodeToString(kid))
+		if len(cfg.IV) != 32 && len(cfg.IV) != 16 {
+			return nil, fmt.Errorf("track %d: hex iv must have length 16 or 32 chars; %d", trackID, len(cfg.IV))
+		}
+		iv, err := hex.DecodeString(cfg.IV)
+		if err != nil {
+			return nil, fmt.Errorf("track %d: invalid iv %s", trackID, cfg.IV)
+		}
+		tec := mp4.TrackEncConfig{Scheme: cfg.Scheme, KID: kidUUID, Key: key, IV: iv}
+		if cfg.Pattern != "" {
+			pattern, err := parsePattern(cfg.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("track %d: %w", trackID, err)
+			}
+			tec.Pattern = &pattern
+		}
+		perTrack[trackID] = tec
+	}
+	return perTrack, nil
+}
+
+// encryptFilePerTrack encrypts a combined file with one configuration per track.
+// The pssh boxes are added at the end of the moov box.
+func encryptFilePerTrack(ifh io.Reader, ofh io.Writer, perTrack map[uint32]mp4.TrackEncConfig, psshData []byte) error {
+	inFile, err := mp4.DecodeFile(ifh)
+	if err != nil {
+		return fmt.Errorf("decode file: %w", err)
+	}
+	if inFile.Init == nil {
+		return fmt.Errorf("per-track encryption needs a file with init segment")
+	}
+	psshBoxes, err := mp4.PsshBoxesFromBytes(psshData)
+	if err != nil {
+		return fmt.Errorf("pssh boxes from data: %w", err)
+	}
+	if err := mp4.EncryptFile(inFile, perTrack); err != nil {
+		return fmt.Errorf("encrypt file: %w", err)
+	}
+	for _, pssh := range psshBoxes {
+		inFile.Init.Moov.AddChild(pssh)
+	}
+	return inFile.Encode(ofh)
+}
@@ -21,6 +21,24 @@ func TestOptionCases(t *testing.T) {
 	if err != nil {
 		t.Fatalf("error making combined segment: %v", err)
 	}
+	trackCfg := path.Join(tmpDir, "trackcfg.json")
+	err = os.WriteFile(trackCfg, []byte(`{"2": {"scheme": "cbcs", "kid": "`+kid+`", "key": "`+key+
+		`", "iv": "`+iv+`", "pattern": "2:8"}}`), 0644)
+	if err != nil {
+		t.Fatalf("error writing track config: %v", err)
+	}
+	unknownTrackCfg := path.Join(tmpDir, "unknowntrackcfg.json")
+	err = os.WriteFile(unknownTrackCfg, []byte(`{"3": {"scheme": "cenc", "kid": "`+kid+`", "key": "`+key+
+		`", "iv": "`+iv+`"}}`), 0644)
+	if err != nil {
+		t.Fatalf("error writing track config: %v", err)
+	}
+	badSchemeTrackCfg := path.Join(tmpDir, "badschemetrackcfg.json")
+	err = os.WriteFile(badSchemeTrackCfg, []byte(`{"2": {"scheme": "cens", "kid": "`+kid+`", "key": "`+key+
+		`", "iv": "`+iv+`"}}`), 0644)
+	if err != nil {
+		t.Fatalf("error writing track config: %v", err)
+	}
 
 	cases := []struct {
 		desc string
@@ -63,6 +81,9 @@ func TestOptionCases(t *testing.T) {
 		{desc: "bad scheme ",
 			args: []string{appName, "-key", key, "-iv", iv, "-kid", kid, "-scheme", "badScheme", inSeg, outFile},
 			err:  true},
+		{desc: "bad pattern ",
+			args: []string{appName, "-key", key, "-iv", iv, "-kid", kid, "-scheme", "cbcs", "-pattern", "1-9", inSeg, outFile},
+			err:  true},
 		{desc: "bad inFile ",
 			args: []string{appName, "-key", key, "-iv", iv, "-kid", kid, "main.go", outFile},
 			err:  true},
@@ -81,6 +102,27 @@ func TestOptionCases(t *testing.T) {
 		{desc: "successful combined file",
 			args: []string{appName, "-key", key, "-iv", iv, "-kid", kid, "-pssh", pssh, combFile, outFile},
 			err:  false},
+		{desc: "successful combined file cbcs with pattern",
+			args: []string{appName, "-key", key, "-iv", iv, "-kid", kid, "-scheme", "cbcs", "-pattern", "2:8", combFile, outFile},
+			err:  false},
+		{desc: "non-existing trackconfig",
+			args: []string{appName, "-trackconfig", "trackcfg.json", combFile, outFile},
+			err:  true},
+		{desc: "bad trackconfig",
+			args: []string{appName, "-trackconfig", "main.go", combFile, outFile},
+			err:  true},
+		{desc: "trackconfig with unknown track",
+			args: []string{appName, "-trackconfig", unknownTrackCfg, combFile, outFile},
+			err:  true},
+		{desc: "trackconfig with bad scheme",
+			args: []string{appName, "-trackconfig", badSchemeTrackCfg, combFile, outFile},
+			err:  true},
+		{desc: "trackconfig with segFile",
+			args: []string{appName, "-trackconfig", trackCfg, inSeg, outFile},
+			err:  true},
+		{desc: "successful combined file with trackconfig",
+			args: []string{appName, "-trackconfig", trackCfg, "-pssh", pssh, combFile, outFile},
+			err:  false},
 		{desc: "version", args: []string{appName, "-version"}, err: false},
 		{desc: "help", args: []string{appName, "-h"}, err: false},
 	}
@@ -7,8 +7,12 @@ mp4ff-info prints the box tree of input mp4 (ISOBMFF) file.
 
 	options:
 
+		-json
+			Output box tree as JSON with type, size, offset, and fields
 		-l string
-			level of details, e.g. all:1 or trun:1,subs:1
+			level of details, e.g. all:1 or trun:1,subs:1, or sample range trun:1-100
+		-sei
+			Print decoded SEI messages of each AVC and HEVC video sample after the box tree
 		-version
 			Get mp4ff version
 */
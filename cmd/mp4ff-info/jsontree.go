@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/Eyevinn/mp4ff/mp4"
+)
+
+// jsonBox is the JSON representation of a box with the fields written by its Info method.
+// Repeated field names are collected into arrays. Info lines that are not "name: value"
+// fields are kept as details.
+type jsonBox struct {
+	Type     string                 `json:"type"`
+	Size     uint64                 `json:"size"`
+	Offset   uint64                 `json:"offset"`
+	Version  *int                   `json:"version,omitempty"`
+	Flags    string                 `json:"flags,omitempty"`
+	Fields   map[string]interface{} `json:"fields,omitempty"`
+	Details  []string               `json:"details,omitempty"`
+	Children []*jsonBox             `json:"children,omitempty"`
+}
+
+type childrenGetter interface {
+	GetChildren() []mp4.Box
+}
+
+var versionFlagsRe = regexp.MustCompile(`version=(\d+) flags=([0-9a-f]+)`)
+
+// writeJSON writes the box tree of the top-level boxes as a JSON array.
+func writeJSON(w io.Writer, boxes []mp4.Box, specificBoxLevels string) error {
+	tree := make([]*jsonBox, 0, len(boxes))
+	var offset uint64
+	for _, b := range boxes {
+		jb, err := makeJSONBox(b, offset, specificBoxLevels)
+		if err != nil {
+			return err
+		}
+		tree = append(tree, jb)
+		offset += b.Size()
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(tree)
+}
+
+// makeJSONBox converts box and its children recursively. offset is the box start position.
+func makeJSONBox(b mp4.Box, offset uint64, specificBoxLevels string) (*jsonBox, error) {
+	jb := &jsonBox{Type: b.Type(), Size: b.Size(), Offset: offset}
+	err := jb.parseInfo(b, specificBoxLevels)
+	if err != nil {
+		return nil, fmt.Errorf("info for %s box: %w", b.Type(), err)
+	}
+	cg, ok := b.(childrenGetter)
+	if !ok {
+		return jb, nil
+	}
+	children := cg.GetChildren()
+	var childrenSize uint64
+	for _, c := range children {
+		childrenSize += c.Size()
+	}
+	// Children come after the header and any other box fields
+	childOffset := offset + b.Size() - childrenSize
+	for _, c := range children {
+		jc, err := makeJSONBox(c, childOffset, specificBoxLevels)
+		if err != nil {
+			return nil, err
+		}
+		jb.Children = append(jb.Children, jc)
+		childOffset += c.Size()
+	}
+	return jb, nil
+}
+
+// parseInfo extracts version, flags, and field lines from the Info output of the box itself.
+// The lines of child boxes, which start with the first "[" line after the header, are skipped.
+func (jb *jsonBox) parseInfo(b mp4.Box, specificBoxLevels string) error {
+	buf := bytes.Buffer{}
+	err := b.Info(&buf, specificBoxLevels, "", "  ")
+	if err != nil {
+		return err
+	}
+	scanner := bufio.NewScanner(&buf)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	first := true
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if first {
+			first = false
+			if m := versionFlagsRe.FindStringSubmatch(line); m != nil {
+				version, _ := strconv.Atoi(m[1])
+				jb.Version = &version
+				jb.Flags = m[2]
+			}
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			break // First child box
+		}
+		jb.addInfoLine(line)
+	}
+	return scanner.Err()
+}
+
+// addInfoLine adds a " - name: value" line as field and other lines as details.
+func (jb *jsonBox) addInfoLine(line string) {
+	if strings.HasPrefix(line, "- ") {
+		nameValue := line[2:]
+		if idx := strings.Index(nameValue, ": "); idx > 0 {
+			name, value := nameValue[:idx], nameValue[idx+2:]
+			if jb.Fields == nil {
+				jb.Fields = make(map[string]interface{})
+			}
+			switch prev := jb.Fields[name].(type) {
+			case nil:
+				jb.Fields[name] = value
+			case string:
+				jb.Fields[name] = []string{prev, value}
+			case []string:
+				jb.Fields[name] = append(prev, value)
+			}
+			return
+		}
+	}
+	jb.Details = append(jb.Details, line)
+}
@@ -22,6 +22,8 @@ Usage of %s:
 
 type options struct {
 	levels  string
+	json    bool
+	sei     bool
 	version bool
 }
 
@@ -34,7 +36,9 @@ func parseOptions(fs *flag.FlagSet, args []string) (*options, error) {
 
 	opts := options{}
 
-	fs.StringVar(&opts.levels, "l", "", "level of details, e.g. all:1 or trun:1,subs:1")
+	fs.StringVar(&opts.levels, "l", "", "level of details, e.g. all:1 or trun:1,subs:1, or sample range trun:1-100")
+	fs.BoolVar(&opts.json, "json", false, "Output box tree as JSON with type, size, offset, and fields")
+	fs.BoolVar(&opts.sei, "sei", false, "Print decoded SEI messages of each AVC and HEVC video sample after the box tree")
 	fs.BoolVar(&opts.version, "version", false, "Get mp4ff version")
 
 	err := fs.Parse(args[1:])
@@ -75,13 +79,30 @@ func run(args []string, w io.Writer) error {
 		return fmt.Errorf("could not open input file: %w", err)
 	}
 	defer ifd.Close()
-	parsedMp4, err := mp4.DecodeFile(ifd, mp4.WithDecodeMode(mp4.DecModeLazyMdat))
+	decMode := mp4.DecModeLazyMdat
+	if opts.sei {
+		decMode = mp4.DecModeNormal // Sample data is needed
+	}
+	parsedMp4, err := mp4.DecodeFile(ifd, mp4.WithDecodeMode(decMode))
 	if err != nil {
 		return fmt.Errorf("could not parse input file: %w", err)
 	}
+	if opts.json {
+		err = writeJSON(w, parsedMp4.Children, opts.levels)
+		if err != nil {
+			return fmt.Errorf("could not write JSON: %w", err)
+		}
+		return nil
+	}
 	err = parsedMp4.Info(w, opts.levels, "", "  ")
 	if err != nil {
 		return fmt.Errorf("could not print info: %w", err)
 	}
+	if opts.sei {
+		err = writeSEI(w, parsedMp4)
+		if err != nil {
+			return fmt.Errorf("could not print SEI messages: %w", err)
+		}
+	}
 	return nil
 }
@@ -1,8 +1,11 @@
 package main
 
 import (
+	"bytes"
+	"encoding/json"
 	"io"
 	"os"
+	"strings"
 	"testing"
 )
 
@@ -20,6 +23,7 @@ func TestOptions(t *testing.T) {
 		{desc: "bad writer", args: []string{appName, "../../mp4/testdata/init.mp4"}, w: &badWriter{}, err: true},
 		{desc: "good file", args: []string{appName, "../../mp4/testdata/init.mp4"}, w: os.Stdout, err: false},
 		{desc: "good with details", args: []string{appName, "-l", "all:1", "../../mp4/testdata/init.mp4"}, w: os.Stdout, err: false},
+		{desc: "json", args: []string{appName, "-json", "../../mp4/testdata/init.mp4"}, w: io.Discard, err: false},
 		{desc: "version", args: []string{appName, "-version"}, w: os.Stdout, err: false},
 		{desc: "help", args: []string{appName, "-h"}, w: os.Stdout, err: false},
 	}
@@ -41,3 +45,54 @@ type badWriter struct{}
 func (w *badWriter) Write(p []byte) (n int, err error) {
 	return 0, os.ErrClosed
 }
+
+func TestJSONOutput(t *testing.T) {
+	buf := bytes.Buffer{}
+	err := run([]string{appName, "-json", "../../mp4/testdata/1.m4s"}, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var boxes []*jsonBox
+	err = json.Unmarshal(buf.Bytes(), &boxes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gotTypes := make([]string, 0, len(boxes))
+	for _, b := range boxes {
+		gotTypes = append(gotTypes, b.Type)
+	}
+	if strings.Join(gotTypes, ",") != "styp,moof,mdat" {
+		t.Fatalf("got top-level boxes %v", gotTypes)
+	}
+	moof := boxes[1]
+	if moof.Offset != boxes[0].Size {
+		t.Errorf("moof offset %d instead of %d", moof.Offset, boxes[0].Size)
+	}
+	mfhd := moof.Children[0]
+	if mfhd.Type != "mfhd" || mfhd.Offset != moof.Offset+8 {
+		t.Errorf("got %s at offset %d", mfhd.Type, mfhd.Offset)
+	}
+	if mfhd.Version == nil || *mfhd.Version != 0 {
+		t.Error("mfhd version not set")
+	}
+	if _, ok := mfhd.Fields["sequenceNumber"]; !ok {
+		t.Errorf("mfhd sequence number missing in fields %v", mfhd.Fields)
+	}
+}
+
+func TestSEIOutput(t *testing.T) {
+	for _, inFile := range []string{"../mp4ff-nallister/testdata/h264.mp4", "../../mp4/testdata/prog_8s_dec_dashinit.mp4"} {
+		buf := bytes.Buffer{}
+		err := run([]string{appName, "-sei", inFile}, &buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out := buf.String()
+		if !strings.Contains(out, "SEI messages of track") {
+			t.Errorf("%s: no SEI section in output", inFile)
+		}
+		if !strings.Contains(out, "  Sample 1, pts=") || !strings.Contains(out, "* SEI type 5") {
+			t.Errorf("%s: no SEI message for sample 1", inFile)
+		}
+	}
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/Eyevinn/mp4ff/avc"
+	"github.com/Eyevinn/mp4ff/hevc"
+	"github.com/Eyevinn/mp4ff/mp4"
+	"github.com/Eyevinn/mp4ff/sei"
+)
+
+// seiParser parses the SEI NAL units of samples of an AVC or HEVC track.
+// SPS NAL units in the samples replace the SPS from the sample description.
+type seiParser struct {
+	codec      string
+	lengthSize int // NAL unit length size in bytes
+	avcSPS     *avc.SPS
+	hevcSPS    *hevc.SPS
+}
+
+// newSEIParser returns a parser for the track, or false if the track is not AVC or HEVC video.
+func newSEIParser(trak *mp4.TrakBox) (*seiParser, bool, error) {
+	stsd := trak.Mdia.Minf.Stbl.Stsd
+	switch {
+	case stsd.AvcX != nil:
+		p := seiParser{codec: "avc", lengthSize: 4} // avcC is only decoded with 4-byte lengths
+		if stsd.AvcX.AvcC != nil && len(stsd.AvcX.AvcC.SPSnalus) > 0 {
+			sps, err := avc.ParseSPSNALUnit(stsd.AvcX.AvcC.SPSnalus[0], true)
+			if err != nil {
+				return nil, false, fmt.Errorf("parsing SPS: %w", err)
+			}
+			p.avcSPS = sps
+		}
+		return &p, true, nil
+	case stsd.HvcX != nil:
+		p := seiParser{codec: "hevc", lengthSize: 4}
+		if stsd.HvcX.HvcC != nil {
+			p.lengthSize = int(stsd.HvcX.HvcC.LengthSizeMinusOne) + 1
+			spss := stsd.HvcX.HvcC.GetNalusForType(hevc.NALU_SPS)
+			if len(spss) > 0 {
+				sps, err := hevc.ParseSPSNALUnit(spss[0])
+				if err != nil {
+					return nil, false, fmt.Errorf("parsing SPS: %w", err)
+				}
+				p.hevcSPS = sps
+			}
+		}
+		return &p, true, nil
+	default:
+		return nil, false, nil
+	}
+}
+
+// parseSample returns the SEI messages of a sample with NAL unit lengths of p.lengthSize bytes.
+func (p *seiParser) parseSample(sample []byte) ([]sei.SEIMessage, error) {
+	var msgs []sei.SEIMessage
+	var err error
+	avc.NalusSeq(sample, p.lengthSize)(func(nalu []byte, naluErr error) bool {
+		if naluErr != nil {
+			err = naluErr
+			return false
+		}
+		var naluMsgs []sei.SEIMessage
+		naluMsgs, err = p.parseNalu(nalu)
+		msgs = append(msgs, naluMsgs...)
+		return err == nil
+	})
+	return msgs, err
+}
+
+// parseNalu returns the SEI messages of a NAL unit, and updates the SPS if it is an SPS NAL unit.
+func (p *seiParser) parseNalu(nalu []byte) ([]sei.SEIMessage, error) {
+	if len(nalu) == 0 {
+		return nil, nil
+	}
+	var msgs []sei.SEIMessage
+	var err error
+	switch p.codec {
+	case "avc":
+		switch avc.GetNaluType(nalu[0]) {
+		case avc.NALU_SPS:
+			p.avcSPS, err = avc.ParseSPSNALUnit(nalu, true)
+			if err != nil {
+				return nil, fmt.Errorf("parsing SPS: %w", err)
+			}
+		case avc.NALU_SEI:
+			msgs, err = avc.ParseSEINalu(nalu, p.avcSPS)
+		}
+	case "hevc":
+		switch hevc.GetNaluType(nalu[0]) {
+		case hevc.NALU_SPS:
+			p.hevcSPS, err = hevc.ParseSPSNALUnit(nalu)
+			if err != nil {
+				return nil, fmt.Errorf("parsing SPS: %w", err)
+			}
+		case hevc.NALU_SEI_PREFIX, hevc.NALU_SEI_SUFFIX:
+			msgs, err = hevc.ParseSEINalu(nalu, p.hevcSPS)
+		}
+	}
+	if err == sei.ErrRbspTrailingBitsMissing {
+		err = nil
+	}
+	return msgs, err
+}
+
+// writeSEI writes the SEI messages of each sample of the AVC and HEVC video tracks of f.
+func writeSEI(w io.Writer, f *mp4.File) error {
+	var moov *mp4.MoovBox
+	switch {
+	case f.Moov != nil:
+		moov = f.Moov
+	case f.Init != nil:
+		moov = f.Init.Moov
+	default:
+		return fmt.Errorf("no moov box")
+	}
+	for _, trak := range moov.Traks {
+		p, ok, err := newSEIParser(trak)
+		if err != nil {
+			return fmt.Errorf("track %d: %w", trak.Tkhd.TrackID, err)
+		}
+		if !ok {
+			continue
+		}
+		trackID := trak.Tkhd.TrackID
+		_, err = fmt.Fprintf(w, "SEI messages of track %d (%s):\n", trackID, p.codec)
+		if err != nil {
+			return err
+		}
+		writeSampleSEI := func(nr int, pts uint64, data []byte) error {
+			msgs, err := p.parseSample(data)
+			if err != nil {
+				_, err = fmt.Fprintf(w, "  Sample %d, pts=%d: SEI error %q\n", nr, pts, err)
+				return err
+			}
+			if len(msgs) == 0 {
+				return nil
+			}
+			_, err = fmt.Fprintf(w, "  Sample %d, pts=%d:\n", nr, pts)
+			if err != nil {
+				return err
+			}
+			for _, msg := range msgs {
+				_, err = fmt.Fprintf(w, "    * %s\n", msg.String())
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		if f.IsFragmented() {
+			nr := 0
+			var iterErr error
+			f.Samples(trackID)(func(s mp4.FullSample, err error) bool {
+				if err != nil {
+					iterErr = err
+					return false
+				}
+				nr++
+				iterErr = writeSampleSEI(nr, s.PresentationTime(), s.Data)
+				return iterErr == nil
+			})
+			if iterErr != nil {
+				return iterErr
+			}
+			continue
+		}
+		if err := writeProgressiveSEI(f, trak, writeSampleSEI); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeProgressiveSEI calls writeSampleSEI for each sample of trak in a progressive file.
+func writeProgressiveSEI(f *mp4.File, trak *mp4.TrakBox, writeSampleSEI func(nr int, pts uint64, data []byte) error) error {
+	stbl := trak.Mdia.Minf.Stbl
+	if (stbl.Stsz == nil && stbl.Stz2 == nil) || stbl.Stts == nil || f.Mdat == nil {
+		return fmt.Errorf("track %d: missing sample table boxes or mdat", trak.Tkhd.TrackID)
+	}
+	sizes := trak.SampleSizes()
+	durations := trak.SampleDurations()
+	if len(durations) != len(sizes) {
+		return fmt.Errorf("track %d: %d sample durations but %d sample sizes", trak.Tkhd.TrackID,
+			len(durations), len(sizes))
+	}
+	var decTime uint64
+	for i, size := range sizes {
+		nr := uint32(i + 1)
+		offset, _, err := trak.SampleRange(nr)
+		if err != nil {
+			return fmt.Errorf("track %d: %w", trak.Tkhd.TrackID, err)
+		}
+		data, err := f.Mdat.ReadData(int64(offset), int64(size), nil)
+		if err != nil {
+			return err
+		}
+		pts := decTime
+		if stbl.Ctts != nil {
+			pts = uint64(int64(pts) + int64(stbl.Ctts.GetCompositionTimeOffset(nr)))
+		}
+		if err := writeSampleSEI(int(nr), pts, data); err != nil {
+			return err
+		}
+		decTime += uint64(durations[i])
+	}
+	return nil
+}
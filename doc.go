@@ -52,9 +52,11 @@ The top-level packages in the mp4ff module are
  4. [sei] provides support for handling  Supplementary Enhancement Information (SEI) such as timestamps
     for AVC and HEVC video.
  5. [av1] provides basic support for AV1 video packaging
- 6. [aac] provides support for AAC audio. This includes handling ADTS headers which is common
+ 6. [vvc] provides basic support for VVC (aka H.266) video packaging
+ 7. [emsg] provides parsing of ID3 and SCTE-35 message data in DASH inband event messages (emsg)
+ 8. [aac] provides support for AAC audio. This includes handling ADTS headers which is common
     for AAC inside MPEG-2 TS streams.
- 7. [bits] provides bit-wise and byte-wise readers and writers used by the other packages.
+ 9. [bits] provides bit-wise and byte-wise readers and writers used by the other packages.
 
 # Specifications
 
@@ -67,6 +69,8 @@ in the code.
 [hevc]: https://pkg.go.dev/github.com/Eyevinn/mp4ff/hevc
 [sei]: https://pkg.go.dev/github.com/Eyevinn/mp4ff/sei
 [av1]: https://pkg.go.dev/github.com/Eyevinn/mp4ff/av1
+[vvc]: https://pkg.go.dev/github.com/Eyevinn/mp4ff/vvc
+[emsg]: https://pkg.go.dev/github.com/Eyevinn/mp4ff/emsg
 [aac]: https://pkg.go.dev/github.com/Eyevinn/mp4ff/aac
 [bits]: https://pkg.go.dev/github.com/Eyevinn/mp4ff/bits
 [initcreator]: https://pkg.go.dev/github.com/Eyevinn/mp4ff/examples/initcreator
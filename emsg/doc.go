@@ -0,0 +1,7 @@
+/*
+Package emsg provides parsing of the message_data payload of DASH inband event messages (emsg boxes).
+Supported schemes are ID3 timed metadata as specified by AOM in "Carriage of ID3 Timed Metadata in
+the Common Media Application Format (CMAF)" and SCTE-35 binary splice information as specified in
+ANSI/SCTE 214-1 and ANSI/SCTE 35 2022.
+*/
+package emsg
@@ -0,0 +1,158 @@
+package emsg
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/Eyevinn/mp4ff/bits"
+)
+
+// SchemeIDURIID3 is the scheme_id_uri for ID3 timed metadata in emsg boxes.
+const SchemeIDURIID3 = "https://aomedia.org/emsg/ID3"
+
+const id3HeaderSize = 10
+
+// id3ExtHeaderMinSizeV24 is the size of a v2.4 extended header with no flags set.
+const id3ExtHeaderMinSizeV24 = 6
+
+// ErrNotID3 is returned if data does not start with an ID3v2 header.
+var ErrNotID3 = errors.New("not an ID3v2 tag")
+
+// ID3Tag - ID3v2 tag with header information and frames
+// Specified in ID3 tag version 2.4.0 - Main Structure (and 2.3.0)
+type ID3Tag struct {
+	MajorVersion byte
+	Revision     byte
+	Flags        byte
+	Frames       []ID3Frame
+}
+
+// ID3Frame - ID3v2 frame with four-character ID and raw data
+type ID3Frame struct {
+	ID    string
+	Flags uint16
+	Data  []byte
+}
+
+// ParseID3 parses an ID3v2.3 or v2.4 tag including its frames.
+// Frame data is not unsynchronized, but kept as in the tag.
+func ParseID3(data []byte) (*ID3Tag, error) {
+	if len(data) < id3HeaderSize || string(data[:3]) != "ID3" {
+		return nil, ErrNotID3
+	}
+	sr := bits.NewFixedSliceReader(data)
+	sr.SkipBytes(3)
+	tag := &ID3Tag{
+		MajorVersion: sr.ReadUint8(),
+		Revision:     sr.ReadUint8(),
+		Flags:        sr.ReadUint8(),
+	}
+	if tag.MajorVersion != 3 && tag.MajorVersion != 4 {
+		return nil, fmt.Errorf("ID3v2.%d not supported", tag.MajorVersion)
+	}
+	tagSize, err := syncSafeSize(sr.ReadBytes(4))
+	if err != nil {
+		return nil, err
+	}
+	if id3HeaderSize+tagSize > len(data) {
+		return nil, fmt.Errorf("ID3 tag size %d beyond data size %d", tagSize, len(data)-id3HeaderSize)
+	}
+	end := id3HeaderSize + tagSize
+	if tag.Flags&0x40 != 0 { // Extended header
+		var extSize int
+		if tag.MajorVersion == 4 {
+			extSize, err = syncSafeSize(sr.ReadBytes(4))
+			if err != nil {
+				return nil, err
+			}
+			if extSize < id3ExtHeaderMinSizeV24 {
+				return nil, fmt.Errorf("ID3 extended header size %d less than %d", extSize, id3ExtHeaderMinSizeV24)
+			}
+			extSize -= 4 // Size includes itself in v2.4
+		} else {
+			extSize = int(sr.ReadUint32())
+		}
+		if sr.GetPos()+extSize > end {
+			return nil, fmt.Errorf("ID3 extended header size %d beyond tag size %d", extSize, tagSize)
+		}
+		sr.SkipBytes(extSize)
+	}
+	for sr.GetPos()+id3HeaderSize <= end && sr.AccError() == nil {
+		id := sr.ReadFixedLengthString(4)
+		if id[0] == 0 {
+			break // Padding
+		}
+		var frameSize int
+		if tag.MajorVersion == 4 {
+			frameSize, err = syncSafeSize(sr.ReadBytes(4))
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			frameSize = int(sr.ReadUint32())
+		}
+		flags := sr.ReadUint16()
+		if sr.GetPos()+frameSize > end {
+			return nil, fmt.Errorf("ID3 frame %s size %d beyond tag size", id, frameSize)
+		}
+		tag.Frames = append(tag.Frames, ID3Frame{
+			ID:    id,
+			Flags: flags,
+			Data:  sr.ReadBytes(frameSize),
+		})
+	}
+	return tag, sr.AccError()
+}
+
+// syncSafeSize decodes a 4-byte syncsafe integer with 7 bits per byte.
+func syncSafeSize(b []byte) (int, error) {
+	if len(b) != 4 {
+		return 0, ErrNotID3
+	}
+	size := 0
+	for _, c := range b {
+		if c&0x80 != 0 {
+			return 0, fmt.Errorf("bad syncsafe integer %x", b)
+		}
+		size = size<<7 | int(c)
+	}
+	return size, nil
+}
+
+// Text returns the text of a text information frame (ID starting with T).
+// ISO-8859-1 and UTF-8 encodings are returned without conversion. Returns false for other frames.
+func (f ID3Frame) Text() (string, bool) {
+	if len(f.ID) != 4 || f.ID[0] != 'T' || f.ID == "TXXX" || len(f.Data) == 0 {
+		return "", false
+	}
+	switch f.Data[0] {
+	case 0, 3: // ISO-8859-1, UTF-8
+		return string(bytes.TrimRight(f.Data[1:], "\x00")), true
+	default:
+		return "", false
+	}
+}
+
+// Private returns owner identifier and private data for a PRIV frame. Returns false for other frames.
+func (f ID3Frame) Private() (owner string, data []byte, ok bool) {
+	if f.ID != "PRIV" {
+		return "", nil, false
+	}
+	idx := bytes.IndexByte(f.Data, 0)
+	if idx < 0 {
+		return "", nil, false
+	}
+	return string(f.Data[:idx]), f.Data[idx+1:], true
+}
+
+// String - one-line description of ID3 frame
+func (f ID3Frame) String() string {
+	if txt, ok := f.Text(); ok {
+		return fmt.Sprintf("%s: %q", f.ID, txt)
+	}
+	if owner, data, ok := f.Private(); ok {
+		return fmt.Sprintf("%s: owner=%q size=%d", f.ID, owner, len(data))
+	}
+	return fmt.Sprintf("%s: size=%d", f.ID, len(f.Data))
+}
@@ -0,0 +1,56 @@
+package emsg_test
+
+import (
+	"testing"
+
+	"github.com/Eyevinn/mp4ff/emsg"
+	"github.com/go-test/deep"
+)
+
+func TestParseID3(t *testing.T) {
+	// ID3v2.4 tag with one TIT2 and one PRIV frame followed by padding
+	tit2 := []byte{'T', 'I', 'T', '2', 0, 0, 0, 6, 0, 0, 3, 'H', 'e', 'l', 'l', 'o'}
+	priv := []byte{'P', 'R', 'I', 'V', 0, 0, 0, 6, 0, 0, 'o', 'w', 'n', 0, 0x01, 0x02}
+	padding := []byte{0, 0, 0, 0}
+	body := append(append(append([]byte{}, tit2...), priv...), padding...)
+	data := append([]byte{'I', 'D', '3', 4, 0, 0, 0, 0, 0, byte(len(body))}, body...)
+
+	tag, err := emsg.ParseID3(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &emsg.ID3Tag{
+		MajorVersion: 4,
+		Frames: []emsg.ID3Frame{
+			{ID: "TIT2", Data: []byte{3, 'H', 'e', 'l', 'l', 'o'}},
+			{ID: "PRIV", Data: []byte{'o', 'w', 'n', 0, 0x01, 0x02}},
+		},
+	}
+	if diff := deep.Equal(tag, expected); diff != nil {
+		t.Error(diff)
+	}
+	if txt, ok := tag.Frames[0].Text(); !ok || txt != "Hello" {
+		t.Errorf("got text %q", txt)
+	}
+	owner, privData, ok := tag.Frames[1].Private()
+	if !ok || owner != "own" || len(privData) != 2 {
+		t.Errorf("got owner %q and data %x", owner, privData)
+	}
+	for _, extSize := range []byte{2, 6, 100} {
+		extData := append([]byte{'I', 'D', '3', 4, 0, 0x40, 0, 0, 0, byte(6 + len(body))}, 0, 0, 0, extSize, 1, 0)
+		extData = append(extData, body...)
+		extTag, err := emsg.ParseID3(extData)
+		switch {
+		case extSize == 6 && err != nil:
+			t.Errorf("extended header size 6: %v", err)
+		case extSize == 6 && len(extTag.Frames) != 2:
+			t.Errorf("got %d frames after extended header", len(extTag.Frames))
+		case extSize != 6 && err == nil:
+			t.Errorf("no error for extended header size %d", extSize)
+		}
+	}
+	_, err = emsg.ParseID3([]byte("ID2"))
+	if err != emsg.ErrNotID3 {
+		t.Errorf("expected ErrNotID3, got %v", err)
+	}
+}
@@ -0,0 +1,241 @@
+package emsg
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/Eyevinn/mp4ff/bits"
+)
+
+// SchemeIDURISCTE35 is the scheme_id_uri for binary SCTE-35 splice_info_section in emsg boxes.
+const SchemeIDURISCTE35 = "urn:scte:scte35:2013:bin"
+
+// SCTE-35 splice command types as specified in ANSI/SCTE 35 Table 7
+const (
+	SpliceNullCmd           = 0x00
+	SpliceScheduleCmd       = 0x04
+	SpliceInsertCmd         = 0x05
+	TimeSignalCmd           = 0x06
+	BandwidthReservationCmd = 0x07
+	PrivateCmd              = 0xff
+)
+
+const scte35TableID = 0xfc
+
+// SpliceInfoSection - SCTE-35 splice_info_section
+// Specified in ANSI/SCTE 35 2022 Section 9.6.
+// Only splice_insert and time_signal commands are parsed. Other commands are available as raw data.
+type SpliceInfoSection struct {
+	SAPType             byte
+	ProtocolVersion     byte
+	EncryptedPacket     bool
+	EncryptionAlgorithm byte
+	PTSAdjustment       uint64
+	CWIndex             byte
+	Tier                uint16
+	SpliceCommandType   byte
+	SpliceInsert        *SpliceInsert
+	TimeSignal          *SpliceTime
+	SpliceCommandData   []byte // Raw command data if not parsed
+	Descriptors         []SpliceDescriptor
+	CRC32               uint32
+}
+
+// SpliceTime - splice_time()
+type SpliceTime struct {
+	TimeSpecified bool
+	PTSTime       uint64
+}
+
+// BreakDuration - break_duration()
+type BreakDuration struct {
+	AutoReturn bool
+	Duration   uint64
+}
+
+// SpliceComponent - component in a splice_insert without program_splice_flag
+type SpliceComponent struct {
+	ComponentTag byte
+	SpliceTime   *SpliceTime
+}
+
+// SpliceInsert - splice_insert() command
+type SpliceInsert struct {
+	SpliceEventID              uint32
+	SpliceEventCancelIndicator bool
+	OutOfNetworkIndicator      bool
+	ProgramSpliceFlag          bool
+	DurationFlag               bool
+	SpliceImmediateFlag        bool
+	SpliceTime                 *SpliceTime
+	Components                 []SpliceComponent
+	BreakDuration              *BreakDuration
+	UniqueProgramID            uint16
+	AvailNum                   byte
+	AvailsExpected             byte
+}
+
+// SpliceDescriptor - splice_descriptor() with tag, identifier and raw data
+type SpliceDescriptor struct {
+	Tag        byte
+	Identifier uint32
+	Data       []byte
+}
+
+// ParseSCTE35 parses a binary SCTE-35 splice_info_section.
+// The CRC is not verified and encrypted sections are only parsed up to the command.
+func ParseSCTE35(data []byte) (*SpliceInfoSection, error) {
+	if len(data) < 3 {
+		return nil, fmt.Errorf("SCTE-35 section too short: %d bytes", len(data))
+	}
+	r := bits.NewReader(bytes.NewReader(data))
+	tableID := r.Read(8)
+	if tableID != scte35TableID {
+		return nil, fmt.Errorf("SCTE-35 table_id %#02x instead of 0xfc", tableID)
+	}
+	_ = r.Read(2) // section_syntax_indicator and private_indicator
+	s := &SpliceInfoSection{}
+	s.SAPType = byte(r.Read(2))
+	sectionLength := int(r.Read(12))
+	if 3+sectionLength > len(data) {
+		return nil, fmt.Errorf("SCTE-35 section_length %d beyond data size", sectionLength)
+	}
+	s.ProtocolVersion = byte(r.Read(8))
+	s.EncryptedPacket = r.ReadFlag()
+	s.EncryptionAlgorithm = byte(r.Read(6))
+	s.PTSAdjustment = uint64(r.Read(33))
+	s.CWIndex = byte(r.Read(8))
+	s.Tier = uint16(r.Read(12))
+	spliceCommandLength := int(r.Read(12))
+	s.SpliceCommandType = byte(r.Read(8))
+	if r.AccError() != nil {
+		return nil, r.AccError()
+	}
+	if s.EncryptedPacket {
+		s.SpliceCommandData = data[r.NrBytesRead():]
+		return s, nil
+	}
+	cmdStart := r.NrBytesRead()
+	switch s.SpliceCommandType {
+	case SpliceInsertCmd:
+		s.SpliceInsert = parseSpliceInsert(r)
+	case TimeSignalCmd:
+		s.TimeSignal = parseSpliceTime(r)
+	case SpliceNullCmd:
+	default:
+		if spliceCommandLength == 0xfff {
+			return nil, fmt.Errorf("SCTE-35 command %d with unspecified length not supported", s.SpliceCommandType)
+		}
+		cmdEnd := cmdStart + spliceCommandLength
+		if cmdEnd > len(data) {
+			return nil, fmt.Errorf("SCTE-35 splice_command_length %d beyond data size", spliceCommandLength)
+		}
+		s.SpliceCommandData = make([]byte, spliceCommandLength)
+		for i := range s.SpliceCommandData {
+			s.SpliceCommandData[i] = byte(r.Read(8))
+		}
+	}
+	if r.AccError() != nil {
+		return nil, r.AccError()
+	}
+	if spliceCommandLength != 0xfff && r.NrBytesRead()-cmdStart != spliceCommandLength {
+		return nil, fmt.Errorf("SCTE-35 command length %d but %d bytes parsed", spliceCommandLength, r.NrBytesRead()-cmdStart)
+	}
+	descriptorLoopLength := int(r.Read(16))
+	descEnd := r.NrBytesRead() + descriptorLoopLength
+	for r.NrBytesRead() < descEnd && r.AccError() == nil {
+		tag := byte(r.Read(8))
+		length := int(r.Read(8))
+		if length < 4 || r.NrBytesRead()+length > descEnd {
+			return nil, fmt.Errorf("SCTE-35 bad splice descriptor length %d", length)
+		}
+		d := SpliceDescriptor{
+			Tag:        tag,
+			Identifier: uint32(r.Read(32)),
+			Data:       make([]byte, length-4),
+		}
+		for i := range d.Data {
+			d.Data[i] = byte(r.Read(8))
+		}
+		s.Descriptors = append(s.Descriptors, d)
+	}
+	s.CRC32 = uint32(r.Read(32))
+	return s, r.AccError()
+}
+
+func parseSpliceTime(r *bits.Reader) *SpliceTime {
+	st := &SpliceTime{}
+	st.TimeSpecified = r.ReadFlag()
+	if st.TimeSpecified {
+		_ = r.Read(6)
+		st.PTSTime = uint64(r.Read(33))
+	} else {
+		_ = r.Read(7)
+	}
+	return st
+}
+
+func parseSpliceInsert(r *bits.Reader) *SpliceInsert {
+	si := &SpliceInsert{}
+	si.SpliceEventID = uint32(r.Read(32))
+	si.SpliceEventCancelIndicator = r.ReadFlag()
+	_ = r.Read(7)
+	if si.SpliceEventCancelIndicator {
+		return si
+	}
+	si.OutOfNetworkIndicator = r.ReadFlag()
+	si.ProgramSpliceFlag = r.ReadFlag()
+	si.DurationFlag = r.ReadFlag()
+	si.SpliceImmediateFlag = r.ReadFlag()
+	_ = r.Read(4)
+	if si.ProgramSpliceFlag && !si.SpliceImmediateFlag {
+		si.SpliceTime = parseSpliceTime(r)
+	}
+	if !si.ProgramSpliceFlag {
+		componentCount := int(r.Read(8))
+		for i := 0; i < componentCount; i++ {
+			c := SpliceComponent{ComponentTag: byte(r.Read(8))}
+			if !si.SpliceImmediateFlag {
+				c.SpliceTime = parseSpliceTime(r)
+			}
+			si.Components = append(si.Components, c)
+		}
+	}
+	if si.DurationFlag {
+		bd := &BreakDuration{}
+		bd.AutoReturn = r.ReadFlag()
+		_ = r.Read(6)
+		bd.Duration = uint64(r.Read(33))
+		si.BreakDuration = bd
+	}
+	si.UniqueProgramID = uint16(r.Read(16))
+	si.AvailNum = byte(r.Read(8))
+	si.AvailsExpected = byte(r.Read(8))
+	return si
+}
+
+// String - one-line description of splice_info_section
+func (s *SpliceInfoSection) String() string {
+	msg := fmt.Sprintf("SCTE-35 cmdType=%d ptsAdjustment=%d", s.SpliceCommandType, s.PTSAdjustment)
+	switch {
+	case s.SpliceInsert != nil:
+		si := s.SpliceInsert
+		msg += fmt.Sprintf(" splice_insert eventID=%d cancel=%t outOfNetwork=%t immediate=%t",
+			si.SpliceEventID, si.SpliceEventCancelIndicator, si.OutOfNetworkIndicator, si.SpliceImmediateFlag)
+		if si.SpliceTime != nil && si.SpliceTime.TimeSpecified {
+			msg += fmt.Sprintf(" ptsTime=%d", si.SpliceTime.PTSTime)
+		}
+		if si.BreakDuration != nil {
+			msg += fmt.Sprintf(" duration=%d autoReturn=%t", si.BreakDuration.Duration, si.BreakDuration.AutoReturn)
+		}
+	case s.TimeSignal != nil:
+		msg += " time_signal"
+		if s.TimeSignal.TimeSpecified {
+			msg += fmt.Sprintf(" ptsTime=%d", s.TimeSignal.PTSTime)
+		}
+	}
+	for _, d := range s.Descriptors {
+		msg += fmt.Sprintf(" descriptor(tag=%d size=%d)", d.Tag, len(d.Data))
+	}
+	return msg
+}
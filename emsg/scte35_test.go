@@ -0,0 +1,69 @@
+package emsg_test
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/Eyevinn/mp4ff/emsg"
+	"github.com/go-test/deep"
+)
+
+func TestParseSCTE35(t *testing.T) {
+	// Examples from ANSI/SCTE 35 2022 Section 14
+	testCases := []struct {
+		name     string
+		b64      string
+		expected *emsg.SpliceInfoSection
+	}{
+		{"splice_insert", "/DAvAAAAAAAA///wFAVIAACPf+/+c2nALv4AUsz1AAAAAAAKAAhDVUVJAAABNWLbowo=",
+			&emsg.SpliceInfoSection{
+				SAPType:           3,
+				CWIndex:           0xff,
+				Tier:              0xfff,
+				SpliceCommandType: emsg.SpliceInsertCmd,
+				SpliceInsert: &emsg.SpliceInsert{
+					SpliceEventID:         0x4800008f,
+					OutOfNetworkIndicator: true,
+					ProgramSpliceFlag:     true,
+					DurationFlag:          true,
+					SpliceTime:            &emsg.SpliceTime{TimeSpecified: true, PTSTime: 0x07369c02e},
+					BreakDuration:         &emsg.BreakDuration{AutoReturn: true, Duration: 0x00052ccf5},
+				},
+				Descriptors: []emsg.SpliceDescriptor{
+					{Tag: 0, Identifier: 0x43554549, Data: []byte{0x00, 0x00, 0x01, 0x35}},
+				},
+				CRC32: 0x62dba30a,
+			}},
+		{"time_signal", "/DA0AAAAAAAA///wBQb+cr0AUAAeAhxDVUVJSAAAjn/PAAGlmbAICAAAAAAsoKGKNAIAmsnRfg==",
+			&emsg.SpliceInfoSection{
+				SAPType:           3,
+				CWIndex:           0xff,
+				Tier:              0xfff,
+				SpliceCommandType: emsg.TimeSignalCmd,
+				TimeSignal:        &emsg.SpliceTime{TimeSpecified: true, PTSTime: 0x072bd0050},
+				Descriptors: []emsg.SpliceDescriptor{
+					{Tag: 2, Identifier: 0x43554549, Data: []byte{0x48, 0x00, 0x00, 0x8e, 0x7f, 0xcf, 0x00, 0x01,
+						0xa5, 0x99, 0xb0, 0x08, 0x08, 0x00, 0x00, 0x00, 0x00, 0x2c, 0xa0, 0xa1, 0x8a, 0x34, 0x02, 0x00}},
+				},
+				CRC32: 0x9ac9d17e,
+			}},
+	}
+	for _, tc := range testCases {
+		data, err := base64.StdEncoding.DecodeString(tc.b64)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sis, err := emsg.ParseSCTE35(data)
+		if err != nil {
+			t.Errorf("%s: %s", tc.name, err)
+			continue
+		}
+		if diff := deep.Equal(sis, tc.expected); diff != nil {
+			t.Errorf("%s: %v", tc.name, diff)
+		}
+	}
+	_, err := emsg.ParseSCTE35([]byte{0xfd, 0x00, 0x00})
+	if err == nil {
+		t.Error("expected error for bad table_id")
+	}
+}
@@ -1,5 +1,19 @@
 package hevc
 
+import "github.com/Eyevinn/mp4ff/avc"
+
+// AnnexBToHVCC converts an Annex B byte stream to NALUs with length fields of nalLengthSize bytes,
+// as used in MP4 samples. nalLengthSize must be 1, 2, or 4. See avc.AnnexBToAVCC.
+func AnnexBToHVCC(data []byte, nalLengthSize int) ([]byte, error) {
+	return avc.AnnexBToAVCC(data, nalLengthSize)
+}
+
+// HVCCToAnnexB converts NALUs with length fields of nalLengthSize bytes, as in MP4 samples,
+// to an Annex B byte stream with 4-byte start codes. See avc.AVCCToAnnexB.
+func HVCCToAnnexB(data []byte, nalLengthSize int) ([]byte, error) {
+	return avc.AVCCToAnnexB(data, nalLengthSize)
+}
+
 // GetParameterSetsFromByteStream gets SPS and PPS nalus from bytestream
 func GetParameterSetsFromByteStream(data []byte) (vpss [][]byte, spss [][]byte, ppss [][]byte) {
 	n := len(data)
@@ -1,6 +1,7 @@
 package hevc
 
 import (
+	"bytes"
 	"testing"
 
 	"github.com/go-test/deep"
@@ -96,3 +97,23 @@ func TestExtractNalusOfTypeFromByteStream(t *testing.T) {
 		}
 	}
 }
+
+func TestAnnexBToHVCC(t *testing.T) {
+	annexB := []byte{0, 0, 1, 0x40, 0x01, 0x0c, 0, 0, 0, 1, 0x26, 0x01, 0xaf}
+	wanted := []byte{0, 3, 0x40, 0x01, 0x0c, 0, 3, 0x26, 0x01, 0xaf}
+	got, err := AnnexBToHVCC(annexB, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, wanted) {
+		t.Errorf("got %x instead of %x", got, wanted)
+	}
+	back, err := HVCCToAnnexB(got, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantedBack := []byte{0, 0, 0, 1, 0x40, 0x01, 0x0c, 0, 0, 0, 1, 0x26, 0x01, 0xaf}
+	if !bytes.Equal(back, wantedBack) {
+		t.Errorf("got %x instead of %x", back, wantedBack)
+	}
+}
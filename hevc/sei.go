@@ -53,6 +53,18 @@ func ParseSEINalu(nalu []byte, sps *SPS) ([]sei.SEIMessage, error) {
 	return seiMsgs, nil
 }
 
+// CreateSEINalu creates a prefix SEI NAL unit (incl header) with the messages in EBSP format.
+// The result can be prepended to the NAL units of a sample.
+func CreateSEINalu(msgs []sei.SEIMessage) ([]byte, error) {
+	buf := bytes.Buffer{}
+	buf.Write([]byte{byte(NALU_SEI_PREFIX) << 1, 0x01}) // nuh_layer_id = 0, nuh_temporal_id_plus1 = 1
+	err := sei.WriteSEIMessages(&buf, msgs)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
 func fillHEVCPicTimingParams(sps *SPS) sei.HEVCPicTimingParams {
 	hpt := sei.HEVCPicTimingParams{}
 	if sps.VUI == nil {
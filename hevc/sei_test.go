@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/Eyevinn/mp4ff/sei"
+	"github.com/go-test/deep"
 )
 
 func TestSEIParsing(t *testing.T) {
@@ -67,3 +68,29 @@ func TestSEIParsing(t *testing.T) {
 		})
 	}
 }
+
+func TestCreateSEINalu(t *testing.T) {
+	// HDR10 with BT.2020 primaries (G, B, R) and D65 white point.
+	// The minimum luminance 0.0001 cd/m2 results in 00 00 00 01 which needs emulation prevention.
+	mdcv := sei.NewMasteringDisplayColourVolume([3]uint16{8500, 6550, 35400}, [3]uint16{39850, 2300, 14600},
+		15635, 16450, 10000000, 1)
+	clli := sei.NewContentLightLevelInfo(1000, 400)
+	nalu, err := CreateSEINalu([]sei.SEIMessage{mdcv, clli})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if GetNaluType(nalu[0]) != NALU_SEI_PREFIX {
+		t.Errorf("got NALU type %s instead of %s", GetNaluType(nalu[0]), NALU_SEI_PREFIX)
+	}
+	wantedHex := "4e01891821349baa199608fc8a4839083d134042009896800000030001900403e8019080"
+	if got := hex.EncodeToString(nalu); got != wantedHex {
+		t.Errorf("got %s instead of %s", got, wantedHex)
+	}
+	msgs, err := ParseSEINalu(nalu, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := deep.Equal(msgs, []sei.SEIMessage{mdcv, clli}); diff != nil {
+		t.Error(diff)
+	}
+}
@@ -480,6 +480,15 @@ func ParseSPSNALUnit(data []byte) (*SPS, error) {
 	return sps, nil
 }
 
+// FrameRate returns the frame rate as vui_time_scale / vui_num_units_in_tick.
+// ok is false if there is no VUI timing info.
+func (s *SPS) FrameRate() (numerator, denominator uint, ok bool) {
+	if s.VUI == nil || !s.VUI.TimingInfoPresentFlag || s.VUI.NumUnitsInTick == 0 {
+		return 0, 0, false
+	}
+	return s.VUI.TimeScale, s.VUI.NumUnitsInTick, true
+}
+
 // ImageSize - calculated width and height using ConformanceWindow
 func (s *SPS) ImageSize() (width, height uint32) {
 	encWidth, encHeight := s.PicWidthInLumaSamples, s.PicHeightInLumaSamples
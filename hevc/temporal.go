@@ -0,0 +1,53 @@
+package hevc
+
+import (
+	"encoding/binary"
+
+	"github.com/Eyevinn/mp4ff/avc"
+)
+
+// GetTemporalID - extract TemporalId (nuh_temporal_id_plus1 - 1) from second byte of NALU Header
+func GetTemporalID(naluHeaderEnd byte) uint8 {
+	tidPlus1 := naluHeaderEnd & 0x07
+	if tidPlus1 == 0 { // Not allowed, but avoid wrap-around
+		return 0
+	}
+	return tidPlus1 - 1
+}
+
+// FilterByTemporalID returns the sample without the NALUs with TemporalId larger than maxTid,
+// e.g. to keep only the base temporal sub-layers for a lower frame rate.
+// The NALUs have length fields of lengthSize bytes (1, 2, or 4). The sample is returned
+// unchanged if no NALU is dropped or if it cannot be parsed. If all NALUs are dropped,
+// the result is empty.
+func FilterByTemporalID(sample []byte, maxTid uint8, lengthSize int) []byte {
+	var out []byte
+	dropped, ok := false, true
+	avc.NalusSeq(sample, lengthSize)(func(nalu []byte, err error) bool {
+		if err != nil {
+			ok = false
+			return false
+		}
+		if len(nalu) >= 2 && GetTemporalID(nalu[1]) > maxTid {
+			dropped = true
+			return true
+		}
+		out = appendLengthField(out, len(nalu), lengthSize)
+		out = append(out, nalu...)
+		return true
+	})
+	if !ok || !dropped {
+		return sample
+	}
+	if out == nil {
+		return []byte{}
+	}
+	return out
+}
+
+// appendLengthField appends a NALU length field of lengthSize bytes.
+func appendLengthField(b []byte, length, lengthSize int) []byte {
+	var buf [4]byte
+	binary.BigEndian.PutUint32(buf[:], uint32(length))
+	return append(b, buf[4-lengthSize:]...)
+}
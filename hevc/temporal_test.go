@@ -0,0 +1,36 @@
+package hevc
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestFilterByTemporalID(t *testing.T) {
+	aud := []byte{byte(NALU_AUD) << 1, 0x02, 0x50}       // TemporalId 1
+	slice := []byte{byte(NALU_TRAIL_N) << 1, 0x02, 0xaa} // TemporalId 1
+	base := []byte{byte(NALU_TRAIL_R) << 1, 0x01, 0xbb}  // TemporalId 0
+	for _, lengthSize := range []int{1, 2, 4} {
+		mkSample := func(nalus ...[]byte) []byte {
+			var out []byte
+			for _, n := range nalus {
+				out = appendLengthField(out, len(n), lengthSize)
+				out = append(out, n...)
+			}
+			return out
+		}
+		sample := mkSample(aud, slice, base)
+		if got := FilterByTemporalID(sample, 1, lengthSize); !bytes.Equal(got, sample) {
+			t.Errorf("lengthSize %d: sample changed for maxTid 1", lengthSize)
+		}
+		if got, want := FilterByTemporalID(sample, 0, lengthSize), mkSample(base); !bytes.Equal(got, want) {
+			t.Errorf("lengthSize %d: got %v instead of %v", lengthSize, got, want)
+		}
+		if got := FilterByTemporalID(mkSample(aud, slice), 0, lengthSize); got == nil || len(got) != 0 {
+			t.Errorf("lengthSize %d: got %v instead of empty sample", lengthSize, got)
+		}
+	}
+	bad := []byte{0, 0, 0, 9, 1}
+	if got := FilterByTemporalID(bad, 0, 4); !bytes.Equal(got, bad) {
+		t.Errorf("bad sample changed")
+	}
+}
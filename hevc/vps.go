@@ -0,0 +1,262 @@
+package hevc
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/Eyevinn/mp4ff/bits"
+)
+
+// VPS - HEVC VPS parameters
+// ISO/IEC 23008-2 Sec. 7.3.2.1
+type VPS struct {
+	VpsID                           byte
+	BaseLayerInternalFlag           bool
+	BaseLayerAvailableFlag          bool
+	MaxLayersMinus1                 byte
+	MaxSubLayersMinus1              byte
+	TemporalIDNestingFlag           bool
+	ProfileTierLevel                ProfileTierLevel
+	SubLayerOrderingInfoPresentFlag bool
+	SubLayeringOrderingInfos        []SubLayerOrderingInfo
+	MaxLayerID                      byte
+	NumLayerSetsMinus1              uint
+	// LayerIDIncludedFlags[i-1][j] is layer_id_included_flag[i][j] for layer sets i >= 1
+	LayerIDIncludedFlags        [][]bool
+	TimingInfoPresentFlag       bool
+	NumUnitsInTick              uint32
+	TimeScale                   uint32
+	PocProportionalToTimingFlag bool
+	NumTicksPocDiffOneMinus1    uint
+	NumHrdParameters            uint
+	HrdLayerSetIdx              []uint
+	CprmsPresentFlags           []bool
+	HrdParameters               []*HrdParameters
+	ExtensionFlag               bool
+	Extension                   *VPSExtension
+}
+
+// VPSExtension - the start of the multi-layer VPS extension
+// ISO/IEC 23008-2 Sec. F.7.3.2.1.1.
+// Parsing stops after the direct dependency flags, so later syntax elements are not available.
+type VPSExtension struct {
+	// BaseLayerProfileTierLevel is only present if MaxLayersMinus1 > 0 and BaseLayerInternalFlag is set
+	BaseLayerProfileTierLevel *ProfileTierLevel
+	SplittingFlag             bool
+	ScalabilityMaskFlags      uint16 // scalability_mask_flag[0] is the most significant bit
+	DimensionIDLenMinus1      []byte
+	NuhLayerIDPresentFlag     bool
+	// LayerIDInNuh and DimensionID have one entry per layer, with inferred values if not present
+	LayerIDInNuh []byte
+	DimensionID  [][]byte
+	ViewIDLen    byte
+	ViewIDVal    []uint16
+	// DirectDependencyFlags[i][j] is direct_dependency_flag[i][j] for j < i
+	DirectDependencyFlags [][]bool
+}
+
+// ScalabilityMaskFlag returns scalability_mask_flag[idx]
+func (e *VPSExtension) ScalabilityMaskFlag(idx int) bool {
+	return e.ScalabilityMaskFlags&(1<<(15-idx)) != 0
+}
+
+// ParseVPSNALUnit parses VPS NAL unit starting with NAL unit header
+func ParseVPSNALUnit(data []byte) (*VPS, error) {
+	vps := &VPS{}
+
+	rd := bytes.NewReader(data)
+	r := bits.NewEBSPReader(rd)
+	// Note! First two bytes are NALU Header
+
+	naluHdrBits := r.Read(16)
+	naluType := GetNaluType(byte(naluHdrBits >> 8))
+	if naluType != NALU_VPS {
+		return nil, fmt.Errorf("NALU type is %s not VPS", naluType)
+	}
+	vps.VpsID = byte(r.Read(4))
+	vps.BaseLayerInternalFlag = r.ReadFlag()
+	vps.BaseLayerAvailableFlag = r.ReadFlag()
+	vps.MaxLayersMinus1 = byte(r.Read(6))
+	vps.MaxSubLayersMinus1 = byte(r.Read(3))
+	vps.TemporalIDNestingFlag = r.ReadFlag()
+	_ = r.Read(16) // vps_reserved_0xffff_16bits
+	vps.ProfileTierLevel = parseProfileTierLevel(r, true, vps.MaxSubLayersMinus1)
+	vps.SubLayerOrderingInfoPresentFlag = r.ReadFlag()
+	startValue := vps.MaxSubLayersMinus1
+	if vps.SubLayerOrderingInfoPresentFlag {
+		startValue = 0
+	}
+	for i := startValue; i <= vps.MaxSubLayersMinus1; i++ {
+		vps.SubLayeringOrderingInfos = append(
+			vps.SubLayeringOrderingInfos,
+			SubLayerOrderingInfo{
+				MaxDecPicBufferingMinus1: byte(r.ReadExpGolomb()),
+				MaxNumReorderPics:        byte(r.ReadExpGolomb()),
+				MaxLatencyIncreasePlus1:  byte(r.ReadExpGolomb()),
+			})
+	}
+	vps.MaxLayerID = byte(r.Read(6))
+	// value shall be in the range of 0 to 1023, inclusive
+	vps.NumLayerSetsMinus1 = r.ReadExpGolomb()
+	if r.AccError() != nil {
+		return nil, r.AccError()
+	}
+	if vps.NumLayerSetsMinus1 > 1023 {
+		return nil, fmt.Errorf("vps_num_layer_sets_minus1 %d > 1023", vps.NumLayerSetsMinus1)
+	}
+	for i := uint(1); i <= vps.NumLayerSetsMinus1; i++ {
+		flags := make([]bool, int(vps.MaxLayerID)+1)
+		for j := range flags {
+			flags[j] = r.ReadFlag()
+		}
+		vps.LayerIDIncludedFlags = append(vps.LayerIDIncludedFlags, flags)
+	}
+	vps.TimingInfoPresentFlag = r.ReadFlag()
+	if vps.TimingInfoPresentFlag {
+		vps.NumUnitsInTick = uint32(r.Read(32))
+		vps.TimeScale = uint32(r.Read(32))
+		vps.PocProportionalToTimingFlag = r.ReadFlag()
+		if vps.PocProportionalToTimingFlag {
+			vps.NumTicksPocDiffOneMinus1 = r.ReadExpGolomb()
+		}
+		vps.NumHrdParameters = r.ReadExpGolomb()
+		if r.AccError() != nil {
+			return nil, r.AccError()
+		}
+		if vps.NumHrdParameters > vps.NumLayerSetsMinus1+1 {
+			return nil, fmt.Errorf("vps_num_hrd_parameters %d > %d", vps.NumHrdParameters, vps.NumLayerSetsMinus1+1)
+		}
+		for i := uint(0); i < vps.NumHrdParameters; i++ {
+			vps.HrdLayerSetIdx = append(vps.HrdLayerSetIdx, r.ReadExpGolomb())
+			cprmsPresentFlag := true // Inferred to be 1 for i = 0
+			if i > 0 {
+				cprmsPresentFlag = r.ReadFlag()
+			}
+			vps.CprmsPresentFlags = append(vps.CprmsPresentFlags, cprmsPresentFlag)
+			vps.HrdParameters = append(vps.HrdParameters,
+				parseHrdParameters(r, cprmsPresentFlag, vps.MaxSubLayersMinus1))
+		}
+	}
+	vps.ExtensionFlag = r.ReadFlag()
+	if r.AccError() != nil {
+		return nil, r.AccError()
+	}
+	if vps.ExtensionFlag {
+		for r.NrBitsRead()%8 != 0 {
+			_ = r.ReadFlag() // vps_extension_alignment_bit_equal_to_one
+		}
+		ext, err := parseVPSExtension(r, vps)
+		if err != nil {
+			return nil, fmt.Errorf("vps extension: %w", err)
+		}
+		vps.Extension = ext
+	}
+	return vps, nil
+}
+
+// parseVPSExtension parses the start of vps_extension() in ISO/IEC 23008-2 Sec. F.7.3.2.1.1
+// with the derived variables from Sec. F.7.4.3.1.1.
+func parseVPSExtension(r *bits.EBSPReader, vps *VPS) (*VPSExtension, error) {
+	ext := &VPSExtension{}
+	maxLayersMinus1 := int(vps.MaxLayersMinus1)
+	if maxLayersMinus1 > 62 {
+		maxLayersMinus1 = 62 // MaxLayersMinus1 = Min(62, vps_max_layers_minus1)
+	}
+	if maxLayersMinus1 > 0 && vps.BaseLayerInternalFlag {
+		ptl := parseProfileTierLevel(r, false, vps.MaxSubLayersMinus1)
+		ext.BaseLayerProfileTierLevel = &ptl
+	}
+	ext.SplittingFlag = r.ReadFlag()
+	ext.ScalabilityMaskFlags = uint16(r.Read(16))
+	numScalabilityTypes := 0
+	for i := 0; i < 16; i++ {
+		if ext.ScalabilityMaskFlag(i) {
+			numScalabilityTypes++
+		}
+	}
+	ext.DimensionIDLenMinus1 = make([]byte, numScalabilityTypes)
+	nrSignalled := numScalabilityTypes
+	if ext.SplittingFlag && nrSignalled > 0 {
+		nrSignalled--
+	}
+	dimBitOffset := make([]int, numScalabilityTypes+1)
+	for j := 0; j < nrSignalled; j++ {
+		ext.DimensionIDLenMinus1[j] = byte(r.Read(3))
+		dimBitOffset[j+1] = dimBitOffset[j] + int(ext.DimensionIDLenMinus1[j]) + 1
+	}
+	if ext.SplittingFlag && numScalabilityTypes > 0 {
+		last := numScalabilityTypes - 1
+		if dimBitOffset[last] > 6 {
+			return nil, fmt.Errorf("dimension id lengths %d bits > 6", dimBitOffset[last])
+		}
+		if dimBitOffset[last] < 6 {
+			ext.DimensionIDLenMinus1[last] = byte(5 - dimBitOffset[last])
+		}
+		dimBitOffset[numScalabilityTypes] = 6
+	}
+	ext.NuhLayerIDPresentFlag = r.ReadFlag()
+	ext.LayerIDInNuh = make([]byte, maxLayersMinus1+1)
+	ext.DimensionID = make([][]byte, maxLayersMinus1+1)
+	ext.DimensionID[0] = make([]byte, numScalabilityTypes)
+	for i := 1; i <= maxLayersMinus1; i++ {
+		ext.LayerIDInNuh[i] = byte(i)
+		if ext.NuhLayerIDPresentFlag {
+			ext.LayerIDInNuh[i] = byte(r.Read(6))
+		}
+		ext.DimensionID[i] = make([]byte, numScalabilityTypes)
+		for j := 0; j < numScalabilityTypes; j++ {
+			if ext.SplittingFlag {
+				mask := byte(1<<dimBitOffset[j+1]) - 1
+				ext.DimensionID[i][j] = (ext.LayerIDInNuh[i] & mask) >> dimBitOffset[j]
+			} else {
+				ext.DimensionID[i][j] = byte(r.Read(int(ext.DimensionIDLenMinus1[j]) + 1))
+			}
+		}
+	}
+	ext.ViewIDLen = byte(r.Read(4))
+	if ext.ViewIDLen > 0 {
+		numViews := ext.numViews()
+		ext.ViewIDVal = make([]uint16, numViews)
+		for i := range ext.ViewIDVal {
+			ext.ViewIDVal[i] = uint16(r.Read(int(ext.ViewIDLen)))
+		}
+	}
+	ext.DirectDependencyFlags = make([][]bool, maxLayersMinus1+1)
+	for i := 1; i <= maxLayersMinus1; i++ {
+		ext.DirectDependencyFlags[i] = make([]bool, i)
+		for j := 0; j < i; j++ {
+			ext.DirectDependencyFlags[i][j] = r.ReadFlag()
+		}
+	}
+	return ext, r.AccError()
+}
+
+// viewOrderIdx returns ScalabilityId[layerIdx][1], which is the view order index
+func (e *VPSExtension) viewOrderIdx(layerIdx int) byte {
+	if !e.ScalabilityMaskFlag(1) {
+		return 0
+	}
+	j := 0
+	if e.ScalabilityMaskFlag(0) {
+		j = 1
+	}
+	return e.DimensionID[layerIdx][j]
+}
+
+// numViews returns NumViews as derived in Sec. F.7.4.3.1.1
+func (e *VPSExtension) numViews() int {
+	numViews := 1
+	for i := 1; i < len(e.LayerIDInNuh); i++ {
+		isNew := true
+		for j := 0; j < i; j++ {
+			if e.viewOrderIdx(i) == e.viewOrderIdx(j) {
+				isNew = false
+				break
+			}
+		}
+		if isNew {
+			numViews++
+		}
+	}
+	return numViews
+}
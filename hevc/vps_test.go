@@ -0,0 +1,149 @@
+package hevc
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/Eyevinn/mp4ff/bits"
+	"github.com/go-test/deep"
+)
+
+const vpsNalu = "40010c01ffff01600000030090000003000003001e959809"
+
+func TestVPSParser(t *testing.T) {
+	byteData, _ := hex.DecodeString(vpsNalu)
+	wanted := VPS{
+		VpsID:                  0,
+		BaseLayerInternalFlag:  true,
+		BaseLayerAvailableFlag: true,
+		MaxLayersMinus1:        0,
+		MaxSubLayersMinus1:     0,
+		TemporalIDNestingFlag:  true,
+		ProfileTierLevel: ProfileTierLevel{
+			GeneralProfileIDC:                1,
+			GeneralProfileCompatibilityFlags: 0x60000000,
+			GeneralConstraintIndicatorFlags:  0x900000000000,
+			GeneralProgressiveSourceFlag:     true,
+			GeneralFrameOnlyConstraintFlag:   true,
+			GeneralLevelIDC:                  30,
+		},
+		SubLayerOrderingInfoPresentFlag: true,
+		SubLayeringOrderingInfos: []SubLayerOrderingInfo{
+			{MaxDecPicBufferingMinus1: 4, MaxNumReorderPics: 2, MaxLatencyIncreasePlus1: 5},
+		},
+	}
+	got, err := ParseVPSNALUnit(byteData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := deep.Equal(*got, wanted); diff != nil {
+		t.Error(diff)
+	}
+	if _, err := ParseVPSNALUnit([]byte{0x42, 0x01}); err == nil {
+		t.Error("no error for SPS NAL unit")
+	}
+}
+
+func TestVPSExtensionParser(t *testing.T) {
+	// Two-layer spatial scalability VPS with sub-layers and a layer set with both layers
+	buf := bytes.Buffer{}
+	w := bits.NewEBSPWriter(&buf)
+	w.Write(uint(NALU_VPS)<<9|1, 16)
+	w.Write(0, 4)    // vps_video_parameter_set_id
+	w.Write(0b11, 2) // base layer internal and available
+	w.Write(1, 6)    // vps_max_layers_minus1
+	w.Write(1, 3)    // vps_max_sub_layers_minus1
+	w.Write(1, 1)    // vps_temporal_id_nesting_flag
+	w.Write(0xffff, 16)
+	w.Write(0x01, 8) // profile space, tier, profile idc
+	w.Write(0x60000000, 32)
+	w.Write(0x9000, 16)
+	w.Write(0, 32)
+	w.Write(93, 8)   // general_level_idc
+	w.Write(0b11, 2) // sub_layer_profile_present_flag, sub_layer_level_present_flag
+	w.Write(0, 14)   // reserved_zero_2bits
+	w.Write(0x01, 8) // sub-layer profile space, tier, profile idc
+	w.Write(0x60000000, 32)
+	w.Write(0x9000, 16)
+	w.Write(0, 32)
+	w.Write(90, 8) // sub_layer_level_idc
+	w.Write(1, 1)  // vps_sub_layer_ordering_info_present_flag
+	for i := 0; i < 2; i++ {
+		w.WriteExpGolomb(uint(i + 1))
+		w.WriteExpGolomb(0)
+		w.WriteExpGolomb(0)
+	}
+	w.Write(1, 6)       // vps_max_layer_id
+	w.WriteExpGolomb(1) // vps_num_layer_sets_minus1
+	w.Write(0b11, 2)    // layer_id_included_flag[1][0..1]
+	w.Write(0, 1)       // vps_timing_info_present_flag
+	w.Write(1, 1)       // vps_extension_flag
+	for w.NrBitsInBuffer() != 0 {
+		w.Write(1, 1) // vps_extension_alignment_bit_equal_to_one
+	}
+	w.Write(93, 8)   // base layer profile_tier_level with only level
+	w.Write(0b11, 2) // sub-layer flags
+	w.Write(0, 14)
+	w.Write(0x01, 8) // sub-layer profile space, tier, profile idc
+	w.Write(0x60000000, 32)
+	w.Write(0x9000, 16)
+	w.Write(0, 32)
+	w.Write(90, 8)      // sub_layer_level_idc
+	w.Write(0, 1)       // splitting_flag
+	w.Write(0x2000, 16) // scalability_mask_flag[2] (spatial or quality scalability)
+	w.Write(0, 3)       // dimension_id_len_minus1[0]
+	w.Write(1, 1)       // vps_nuh_layer_id_present_flag
+	w.Write(1, 6)       // layer_id_in_nuh[1]
+	w.Write(1, 1)       // dimension_id[1][0]
+	w.Write(0, 4)       // view_id_len
+	w.Write(1, 1)       // direct_dependency_flag[1][0]
+	w.WriteRbspTrailingBits()
+
+	got, err := ParseVPSNALUnit(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.MaxLayersMinus1 != 1 || got.MaxSubLayersMinus1 != 1 {
+		t.Errorf("got max layers minus1 %d and max sub layers minus1 %d", got.MaxLayersMinus1, got.MaxSubLayersMinus1)
+	}
+	ptl := got.ProfileTierLevel
+	wantedOrdering := []SubLayerOrderingInfo{{MaxDecPicBufferingMinus1: 1}, {MaxDecPicBufferingMinus1: 2}}
+	if diff := deep.Equal(got.SubLayeringOrderingInfos, wantedOrdering); diff != nil {
+		t.Error(diff)
+	}
+	if diff := deep.Equal(got.LayerIDIncludedFlags, [][]bool{{true, true}}); diff != nil {
+		t.Error(diff)
+	}
+	wantedSubLayer := SubLayer{
+		ProfilePresentFlag:        true,
+		LevelPresentFlag:          true,
+		ProfileIDC:                1,
+		ProfileCompatibilityFlags: 0x60000000,
+		ProgressiveSourceFlag:     true,
+		FrameOnlyConstraintFlag:   true,
+		ConstraintFlags:           0x900000000000,
+		LayerIDC:                  90,
+	}
+	if diff := deep.Equal(ptl.SubLayers, []SubLayer{wantedSubLayer}); diff != nil {
+		t.Error(diff)
+	}
+	wantedExt := &VPSExtension{
+		BaseLayerProfileTierLevel: &ProfileTierLevel{
+			GeneralLevelIDC: 93,
+			SubLayers:       []SubLayer{wantedSubLayer},
+		},
+		ScalabilityMaskFlags:  0x2000,
+		DimensionIDLenMinus1:  []byte{0},
+		NuhLayerIDPresentFlag: true,
+		LayerIDInNuh:          []byte{0, 1},
+		DimensionID:           [][]byte{{0}, {1}},
+		DirectDependencyFlags: [][]bool{nil, {true}},
+	}
+	if diff := deep.Equal(got.Extension, wantedExt); diff != nil {
+		t.Error(diff)
+	}
+	if !got.Extension.ScalabilityMaskFlag(2) || got.Extension.ScalabilityMaskFlag(1) {
+		t.Error("wrong scalability mask flags")
+	}
+}
@@ -0,0 +1,192 @@
+package mp4
+
+import (
+	"fmt"
+	"math"
+)
+
+// AppendSamples appends samples to the track trackID of a progressive file with the mdat in memory.
+// The sample data is added at the end of the mdat box as one new chunk, and the sample tables
+// stts, stsz, stsc, stco/co64, ctts, stss, and sdtp are extended incrementally, so that tables for
+// earlier samples are kept. Decode times follow from the sample durations, so the DecodeTime
+// of the samples is not used. The durations in mdhd, tkhd (if there is no edit list), and mvhd
+// are updated. Edit lists and cslg boxes are not changed.
+//
+// The order of the top-level boxes is kept. If moov is before mdat, the growth of moov is
+// compensated for with FixChunkOffsets. To get a file with moov first, call FastStart when done.
+func (f *File) AppendSamples(trackID uint32, samples []FullSample) error {
+	if f.isFragmented {
+		return fmt.Errorf("only available for progressive files")
+	}
+	if f.Moov == nil || f.Mdat == nil {
+		return fmt.Errorf("moov or mdat box missing")
+	}
+	if f.Mdat.IsLazy() {
+		return fmt.Errorf("cannot append to lazy mdat")
+	}
+	var trak *TrakBox
+	for _, t := range f.Moov.Traks {
+		if t.Tkhd.TrackID == trackID {
+			trak = t
+			break
+		}
+	}
+	if trak == nil {
+		return fmt.Errorf("no track with trackID %d", trackID)
+	}
+	if len(samples) == 0 {
+		return nil
+	}
+	stbl := trak.Mdia.Minf.Stbl
+	if stbl.Stts == nil || stbl.Stsz == nil || stbl.Stsc == nil || (stbl.Stco == nil && stbl.Co64 == nil) {
+		return fmt.Errorf("track %d: missing sample table boxes", trackID)
+	}
+	nrPrevSamples := stbl.Stsz.SampleNumber
+	// Check everything before modifying the file
+	for _, s := range samples {
+		if len(s.Data) != int(s.Size) {
+			return fmt.Errorf("sample data length %d differs from size %d", len(s.Data), s.Size)
+		}
+	}
+	var nrPrevChunks int
+	if stbl.Stco != nil {
+		nrPrevChunks = len(stbl.Stco.ChunkOffset)
+	} else {
+		nrPrevChunks = len(stbl.Co64.ChunkOffset)
+	}
+	if len(stbl.Stsc.Entries) == 0 && nrPrevChunks > 0 {
+		return fmt.Errorf("track %d: no stsc entries for %d chunks", trackID, nrPrevChunks)
+	}
+
+	chunkOffset := f.Mdat.PayloadAbsoluteOffset() + f.Mdat.DataLength()
+	for _, s := range samples {
+		if len(f.Mdat.DataParts) > 0 {
+			f.Mdat.AddSampleDataPart(s.Data)
+		} else {
+			f.Mdat.AddSampleData(s.Data)
+		}
+	}
+
+	// Chunk offset and sample-to-chunk
+	if stbl.Stco != nil && chunkOffset > math.MaxUint32 {
+		stbl.promoteStcoToCo64()
+	}
+	var nrChunks int
+	if stbl.Stco != nil {
+		stbl.Stco.ChunkOffset = append(stbl.Stco.ChunkOffset, uint32(chunkOffset))
+		nrChunks = len(stbl.Stco.ChunkOffset)
+	} else {
+		stbl.Co64.ChunkOffset = append(stbl.Co64.ChunkOffset, chunkOffset)
+		nrChunks = len(stbl.Co64.ChunkOffset)
+	}
+	var sampleDescriptionID uint32 = 1
+	if nrChunks > 1 {
+		sampleDescriptionID = stbl.Stsc.GetSampleDescriptionID(nrChunks - 1)
+	}
+	nrEntries := len(stbl.Stsc.Entries)
+	if nrEntries == 0 || stbl.Stsc.Entries[nrEntries-1].SamplesPerChunk != uint32(len(samples)) {
+		err := stbl.Stsc.AddEntry(uint32(nrChunks), uint32(len(samples)), sampleDescriptionID)
+		if err != nil {
+			return err
+		}
+	}
+
+	var totDur uint64
+	for i, s := range samples {
+		sampleNr := nrPrevSamples + uint32(i) + 1
+		totDur += uint64(s.Dur)
+		appendStts(stbl.Stts, s.Dur)
+		appendStsz(stbl.Stsz, s.Size)
+		if stbl.Ctts != nil || s.CompositionTimeOffset != 0 {
+			if stbl.Ctts == nil {
+				stbl.AddChild(&CttsBox{})
+				appendCtts(stbl.Ctts, sampleNr-1, 0)
+			}
+			appendCtts(stbl.Ctts, 1, s.CompositionTimeOffset)
+		}
+		sf := DecodeSampleFlags(s.Flags)
+		if stbl.Stss != nil || sf.SampleIsNonSync {
+			if stbl.Stss == nil {
+				stss := &StssBox{SampleNumber: make([]uint32, 0, sampleNr)}
+				for nr := uint32(1); nr < sampleNr; nr++ {
+					stss.SampleNumber = append(stss.SampleNumber, nr)
+				}
+				stbl.AddChild(stss)
+			}
+			if !sf.SampleIsNonSync {
+				stbl.Stss.SampleNumber = append(stbl.Stss.SampleNumber, sampleNr)
+			}
+		}
+		if stbl.Sdtp != nil {
+			stbl.Sdtp.Entries = append(stbl.Sdtp.Entries, NewSdtpEntry(sf.IsLeading, sf.SampleDependsOn,
+				sf.SampleIsDependedOn, sf.SampleHasRedundancy))
+		}
+	}
+
+	// Durations
+	mdhd := trak.Mdia.Mdhd
+	mdhd.SetDuration(mdhd.Duration + totDur)
+	mvhd := f.Moov.Mvhd
+	if trak.Edts == nil && mdhd.Timescale != 0 {
+		trak.Tkhd.SetDuration(mdhd.Duration * uint64(mvhd.Timescale) / uint64(mdhd.Timescale))
+	}
+	var movieDur uint64
+	for _, t := range f.Moov.Traks {
+		if t.Tkhd.Duration > movieDur {
+			movieDur = t.Tkhd.Duration
+		}
+	}
+	mvhd.SetDuration(movieDur)
+
+	return f.FixChunkOffsets()
+}
+
+// appendStts appends a sample with duration dur to stts.
+func appendStts(stts *SttsBox, dur uint32) {
+	n := len(stts.SampleCount)
+	if n > 0 && stts.SampleTimeDelta[n-1] == dur {
+		stts.SampleCount[n-1]++
+		return
+	}
+	stts.SampleCount = append(stts.SampleCount, 1)
+	stts.SampleTimeDelta = append(stts.SampleTimeDelta, dur)
+}
+
+// appendStsz appends a sample of size to stsz. A uniform size is expanded to a table if needed.
+func appendStsz(stsz *StszBox, size uint32) {
+	switch {
+	case stsz.SampleNumber == 0 && len(stsz.SampleSize) == 0 && size != 0:
+		stsz.SampleUniformSize = size
+	case stsz.SampleUniformSize != 0 && stsz.SampleUniformSize == size:
+	case stsz.SampleUniformSize != 0:
+		stsz.SampleSize = make([]uint32, stsz.SampleNumber, stsz.SampleNumber+1)
+		for i := range stsz.SampleSize {
+			stsz.SampleSize[i] = stsz.SampleUniformSize
+		}
+		stsz.SampleUniformSize = 0
+		fallthrough
+	default:
+		stsz.SampleSize = append(stsz.SampleSize, size)
+	}
+	stsz.SampleNumber++
+}
+
+// appendCtts appends count samples with composition time offset to ctts.
+func appendCtts(ctts *CttsBox, count uint32, offset int32) {
+	if count == 0 {
+		return
+	}
+	if offset < 0 {
+		ctts.Version = 1
+	}
+	n := len(ctts.SampleOffset)
+	if n > 0 && ctts.SampleOffset[n-1] == offset {
+		ctts.EndSampleNr[n] += count
+		return
+	}
+	if len(ctts.EndSampleNr) == 0 {
+		ctts.EndSampleNr = append(ctts.EndSampleNr, 0)
+	}
+	ctts.SampleOffset = append(ctts.SampleOffset, offset)
+	ctts.EndSampleNr = append(ctts.EndSampleNr, ctts.EndSampleNr[n]+count)
+}
@@ -0,0 +1,135 @@
+package mp4
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestAppendSamples(t *testing.T) {
+	for _, moovLast := range []bool{false, true} {
+		f, err := ReadMP4File("testdata/prog_8s.mp4")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if moovLast {
+			f.Children = []Box{f.Ftyp, f.Mdat, f.Moov}
+			if err := f.FixChunkOffsets(); err != nil {
+				t.Fatal(err)
+			}
+		}
+		type trackState struct {
+			nrSamples uint32
+			mdhdDur   uint64
+			appended  []FullSample
+		}
+		states := make(map[uint32]*trackState)
+		for _, trak := range f.Moov.Traks {
+			stbl := trak.Mdia.Minf.Stbl
+			nrSamples := stbl.Stsz.SampleNumber
+			samples, err := f.ReadSamples(nil, trak, 1, 10)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// Copy data since AppendSamples may reallocate the mdat data
+			for i := range samples {
+				samples[i].Data = append([]byte(nil), samples[i].Data...)
+			}
+			states[trak.Tkhd.TrackID] = &trackState{nrSamples, trak.Mdia.Mdhd.Duration, samples}
+		}
+		for trackID, st := range states {
+			if err := f.AppendSamples(trackID, st.appended); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := f.AppendSamples(100, states[1].appended); err == nil {
+			t.Error("no error for non-existing track")
+		}
+		badSamples := append([]FullSample{}, states[1].appended...)
+		badSamples[1].Size++
+		mdatSize := f.Mdat.Size()
+		if err := f.AppendSamples(1, badSamples); err == nil {
+			t.Error("no error for sample size not matching data")
+		}
+		if f.Mdat.Size() != mdatSize || f.Moov.Traks[0].Mdia.Minf.Stbl.Stsz.SampleNumber != states[1].nrSamples+10 {
+			t.Error("file modified despite error")
+		}
+		check := func(desc string, f *File) {
+			t.Helper()
+			buf := bytes.Buffer{}
+			if err := f.Encode(&buf); err != nil {
+				t.Fatal(err)
+			}
+			decF, err := DecodeFile(&buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, trak := range decF.Moov.Traks {
+				st := states[trak.Tkhd.TrackID]
+				nrSamples := trak.Mdia.Minf.Stbl.Stsz.SampleNumber
+				if nrSamples != st.nrSamples+10 {
+					t.Fatalf("%s: track %d has %d samples instead of %d", desc, trak.Tkhd.TrackID, nrSamples, st.nrSamples+10)
+				}
+				var addedDur uint64
+				for _, s := range st.appended {
+					addedDur += uint64(s.Dur)
+				}
+				if dur := trak.Mdia.Mdhd.Duration; dur != st.mdhdDur+addedDur {
+					t.Errorf("%s: track %d mdhd duration %d instead of %d", desc, trak.Tkhd.TrackID, dur, st.mdhdDur+addedDur)
+				}
+				samples, err := decF.ReadSamples(nil, trak, st.nrSamples+1, nrSamples)
+				if err != nil {
+					t.Fatal(err)
+				}
+				for i, s := range samples {
+					if !bytes.Equal(s.Data, st.appended[i].Data) || s.Sample != st.appended[i].Sample {
+						t.Errorf("%s: track %d appended sample %d differs", desc, trak.Tkhd.TrackID, i+1)
+					}
+				}
+				first, err := decF.ReadSamples(nil, trak, 1, 1)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(first[0].Data, st.appended[0].Data) {
+					t.Errorf("%s: track %d first sample changed", desc, trak.Tkhd.TrackID)
+				}
+			}
+		}
+		check("after append", f)
+		if err := f.FastStart(); err != nil {
+			t.Fatal(err)
+		}
+		if f.Children[1] != f.Moov {
+			t.Errorf("moov not directly after ftyp")
+		}
+		check("after FastStart", f)
+	}
+}
+
+func TestAppendStszAndCtts(t *testing.T) {
+	stsz := &StszBox{}
+	for _, size := range []uint32{10, 10, 12} {
+		appendStsz(stsz, size)
+	}
+	if stsz.SampleNumber != 3 || stsz.SampleUniformSize != 0 || len(stsz.SampleSize) != 3 ||
+		stsz.GetSampleSize(2) != 10 || stsz.GetSampleSize(3) != 12 {
+		t.Errorf("unexpected stsz %+v", stsz)
+	}
+	// Zero-size samples must be added to the table, since uniform size 0 means no uniform size
+	for _, sizes := range [][]uint32{{0, 10, 0}, {10, 0, 0}} {
+		stsz = &StszBox{}
+		for _, size := range sizes {
+			appendStsz(stsz, size)
+		}
+		if stsz.SampleNumber != 3 || len(stsz.SampleSize) != 3 {
+			t.Errorf("unexpected stsz %+v for sizes %v", stsz, sizes)
+		}
+	}
+	ctts := &CttsBox{}
+	appendCtts(ctts, 2, 0)
+	appendCtts(ctts, 1, 0)
+	appendCtts(ctts, 1, -512)
+	if ctts.NrSampleCount() != 2 || ctts.SampleCount(0) != 3 || ctts.Version != 1 ||
+		ctts.GetCompositionTimeOffset(4) != -512 {
+		t.Errorf("unexpected ctts %+v", ctts)
+	}
+}
@@ -18,6 +18,9 @@ type AudioSampleEntryBox struct {
 	Esds               *EsdsBox
 	Dac3               *Dac3Box
 	Dec3               *Dec3Box
+	Dac4               *Dac4Box
+	Dops               *DopsBox
+	Dfla               *DflaBox
 	Btrt               *BtrtBox
 	Sinf               *SinfBox
 	Children           []Box
@@ -61,6 +64,12 @@ func (a *AudioSampleEntryBox) AddChild(child Box) {
 		a.Dac3 = child.(*Dac3Box)
 	case "dec3":
 		a.Dec3 = child.(*Dec3Box)
+	case "dac4":
+		a.Dac4 = child.(*Dac4Box)
+	case "dOps":
+		a.Dops = child.(*DopsBox)
+	case "dfLa":
+		a.Dfla = child.(*DflaBox)
 	case "btrt":
 		a.Btrt = child.(*BtrtBox)
 	case "sinf":
@@ -98,7 +107,7 @@ func DecodeAudioSampleEntry(hdr BoxHeader, startPos uint64, r io.Reader) (Box, e
 
 	pos := startPos + nrAudioSampleBytesBeforeChildren // Size of all previous data
 	for {
-		box, err := DecodeBox(pos, restReader)
+		box, err := decodeChildBox(pos, restReader)
 		if err == io.EOF {
 			break
 		} else if err != nil {
@@ -72,3 +72,38 @@ func BenchmarkEncodeFileSW(b *testing.B) {
 		})
 	}
 }
+
+func BenchmarkEncodeFragment(b *testing.B) {
+	raw, err := os.ReadFile("testdata/1.m4s")
+	if err != nil {
+		b.Fatal(err)
+	}
+	decFile, err := DecodeFile(bytes.NewBuffer(raw))
+	if err != nil {
+		b.Fatal(err)
+	}
+	frag := decFile.Segments[0].Fragments[0]
+	b.Run("Encode", func(b *testing.B) {
+		b.ReportAllocs()
+		buf := bytes.Buffer{}
+		for i := 0; i < b.N; i++ {
+			buf.Reset()
+			_ = frag.Encode(&buf)
+		}
+	})
+	b.Run("EncodeSW", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			sw := bits.NewFixedSliceWriter(int(frag.Size()))
+			_ = frag.EncodeSW(sw)
+		}
+	})
+	b.Run("EncodeSWPooled", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			sw := bits.NewPooledSliceWriter(int(frag.Size()))
+			_ = frag.EncodeSW(sw)
+			sw.Release()
+		}
+	})
+}
@@ -1,7 +1,9 @@
 package mp4
 
 import (
+	"bytes"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
 
@@ -24,6 +26,7 @@ func init() {
 		"\xa9too": DecodeGenericContainerBox,
 		"\xa9cpy": DecodeGenericContainerBox,
 		"ac-3":    DecodeAudioSampleEntry,
+		"ac-4":    DecodeAudioSampleEntry,
 		"alou":    DecodeAlou,
 		"av01":    DecodeVisualSampleEntry,
 		"av1C":    DecodeAv1C,
@@ -33,7 +36,9 @@ func init() {
 		"btrt":    DecodeBtrt,
 		"cdat":    DecodeCdat,
 		"cdsc":    DecodeTrefType,
+		"chap":    DecodeTrefType,
 		"clap":    DecodeClap,
+		"clef":    DecodeTrackApertureDimensions,
 		"co64":    DecodeCo64,
 		"CoLL":    DecodeCoLL,
 		"colr":    DecodeColr,
@@ -41,12 +46,22 @@ func init() {
 		"ctim":    DecodeCtim,
 		"ctts":    DecodeCtts,
 		"dac3":    DecodeDac3,
+		"dac4":    DecodeDac4,
 		"data":    DecodeData,
 		"dec3":    DecodeDec3,
 		"desc":    DecodeGenericContainerBox,
+		"dfLa":    DecodeDfla,
 		"dinf":    DecodeDinf,
+		"dOps":    DecodeDops,
 		"dpnd":    DecodeTrefType,
 		"dref":    DecodeDref,
+		"dva1":    DecodeVisualSampleEntry,
+		"dvav":    DecodeVisualSampleEntry,
+		"dvcC":    DecodeDoViConfiguration,
+		"dvh1":    DecodeVisualSampleEntry,
+		"dvhe":    DecodeVisualSampleEntry,
+		"dvvC":    DecodeDoViConfiguration,
+		"dvwC":    DecodeDoViConfiguration,
 		"ec-3":    DecodeAudioSampleEntry,
 		"edts":    DecodeEdts,
 		"elng":    DecodeElng,
@@ -56,11 +71,14 @@ func init() {
 		"emsg":    DecodeEmsg,
 		"enca":    DecodeAudioSampleEntry,
 		"encv":    DecodeVisualSampleEntry,
+		"enof":    DecodeTrackApertureDimensions,
 		"esds":    DecodeEsds,
 		"evte":    DecodeEvte,
+		"fLaC":    DecodeAudioSampleEntry,
 		"font":    DecodeTrefType,
 		"free":    DecodeFree,
 		"frma":    DecodeFrma,
+		"ftab":    DecodeFtab,
 		"ftyp":    DecodeFtyp,
 		"hdlr":    DecodeHdlr,
 		"hev1":    DecodeVisualSampleEntry,
@@ -68,10 +86,19 @@ func init() {
 		"hint":    DecodeTrefType,
 		"hvc1":    DecodeVisualSampleEntry,
 		"hvcC":    DecodeHvcC,
+		"idat":    DecodeIdat,
 		"iden":    DecodeIden,
+		"iinf":    DecodeIinf,
+		"iloc":    DecodeIloc,
 		"ilst":    DecodeIlst,
+		"infe":    DecodeInfe,
 		"iods":    DecodeUnknown,
+		"ipco":    DecodeIpco,
 		"ipir":    DecodeTrefType,
+		"ipma":    DecodeIpma,
+		"iprp":    DecodeIprp,
+		"iref":    DecodeIref,
+		"ispe":    DecodeIspe,
 		"kind":    DecodeKind,
 		"leva":    DecodeLeva,
 		"ludt":    DecodeLudt,
@@ -92,9 +119,12 @@ func init() {
 		"mvex":    DecodeMvex,
 		"mvhd":    DecodeMvhd,
 		"nmhd":    DecodeNmhd,
+		"Opus":    DecodeAudioSampleEntry,
 		"pasp":    DecodePasp,
 		"payl":    DecodePayl,
+		"pitm":    DecodePitm,
 		"prft":    DecodePrft,
+		"prof":    DecodeTrackApertureDimensions,
 		"pssh":    DecodePssh,
 		"saio":    DecodeSaio,
 		"saiz":    DecodeSaiz,
@@ -122,9 +152,11 @@ func init() {
 		"sttg":    DecodeSttg,
 		"stts":    DecodeStts,
 		"styp":    DecodeStyp,
+		"stz2":    DecodeStz2,
 		"subs":    DecodeSubs,
 		"subt":    DecodeTrefType,
 		"sync":    DecodeTrefType,
+		"tapt":    DecodeTapt,
 		"tenc":    DecodeTenc,
 		"tfdt":    DecodeTfdt,
 		"tfhd":    DecodeTfhd,
@@ -137,6 +169,7 @@ func init() {
 		"trep":    DecodeTrep,
 		"trex":    DecodeTrex,
 		"trun":    DecodeTrun,
+		"tx3g":    DecodeTx3g,
 		"udta":    DecodeUdta,
 		"url ":    DecodeURLBox,
 		"uuid":    DecodeUUIDBox,
@@ -152,6 +185,9 @@ func init() {
 		"vttc":    DecodeVttc,
 		"vttC":    DecodeVttC,
 		"vtte":    DecodeVtte,
+		"vvc1":    DecodeVisualSampleEntry,
+		"vvcC":    DecodeVvcC,
+		"vvi1":    DecodeVisualSampleEntry,
 		"wvtt":    DecodeWvtt,
 	}
 }
@@ -172,6 +208,54 @@ func SetBoxDecoder(boxType string, dec BoxDecoder, decSR BoxDecoderSR) {
 	decodersSR[boxType] = decSR
 }
 
+// RegisterBoxDecoder registers a decoder for boxType, for example to decode a proprietary box
+// into a custom type instead of an UnknownBox. Any built-in decoder for boxType is replaced.
+// The decoder is used both by DecodeBox and DecodeBoxSR. In the latter case, the box body
+// is read from the SliceReader and provided as an io.Reader. Use SetBoxDecoder to provide
+// a SliceReader decoder as well.
+//
+// This is a global change, so use with care.
+func RegisterBoxDecoder(boxType string, dec BoxDecoder) error {
+	if len(boxType) != 4 {
+		return fmt.Errorf("box type %q is not 4 characters", boxType)
+	}
+	if dec == nil {
+		return fmt.Errorf("nil decoder for box type %q", boxType)
+	}
+	decoders[boxType] = dec
+	decodersSR[boxType] = func(hdr BoxHeader, startPos uint64, sr bits.SliceReader) (Box, error) {
+		data := sr.ReadBytes(hdr.payloadLen())
+		if err := sr.AccError(); err != nil {
+			return nil, err
+		}
+		return dec(hdr, startPos, bytes.NewReader(data))
+	}
+	return nil
+}
+
+// RegisterBoxDecoderSR registers a SliceReader decoder for boxType, like RegisterBoxDecoder.
+// The decoder is used both by DecodeBoxSR and DecodeBox. In the latter case, the box body
+// is read into a FixedSliceReader.
+//
+// This is a global change, so use with care.
+func RegisterBoxDecoderSR(boxType string, decSR BoxDecoderSR) error {
+	if len(boxType) != 4 {
+		return fmt.Errorf("box type %q is not 4 characters", boxType)
+	}
+	if decSR == nil {
+		return fmt.Errorf("nil decoder for box type %q", boxType)
+	}
+	decodersSR[boxType] = decSR
+	decoders[boxType] = func(hdr BoxHeader, startPos uint64, r io.Reader) (Box, error) {
+		data, err := readBoxBody(r, hdr)
+		if err != nil {
+			return nil, err
+		}
+		return decSR(hdr, startPos, bits.NewFixedSliceReader(data))
+	}
+	return nil
+}
+
 // BoxHeader - 8 or 16 bytes depending on size
 type BoxHeader struct {
 	Name   string
@@ -183,8 +267,76 @@ func (b BoxHeader) payloadLen() int {
 	return int(b.Size) - b.Hdrlen
 }
 
-// DecodeHeader decodes a box header (size + box type + possible largeSize)
+// DecodeHeader decodes a box header (size + box type + possible largeSize).
+// Size 0, meaning that the box extends to the end of the file, is only supported if r is
+// an io.Seeker. The returned size is then the actual size up to the end of r.
 func DecodeHeader(r io.Reader) (BoxHeader, error) {
+	hdr, err := decodeHeader(r)
+	if err != nil || hdr.Size != 0 {
+		return hdr, err
+	}
+	s, ok := r.(io.Seeker)
+	if !ok {
+		return BoxHeader{}, fmt.Errorf("size 0, meaning to end of file, needs io.Seeker, but got %T", r)
+	}
+	return setSizeToEnd(hdr, s)
+}
+
+// errMaxBoxSize is returned if a box with size 0 is larger than the max box size
+var errMaxBoxSize = errors.New("larger than max box size")
+
+// decodeHeaderToEnd decodes a box header like DecodeHeader, but size 0 is also supported if r is
+// not an io.Seeker. In that case, the rest of r is read into memory, but at most maxSize bytes
+// including the header, unless maxSize is 0. The box body must be read from the returned reader.
+func decodeHeaderToEnd(r io.Reader, maxSize uint64) (BoxHeader, io.Reader, error) {
+	hdr, err := decodeHeader(r)
+	if err != nil || hdr.Size != 0 {
+		return hdr, r, err
+	}
+	if s, ok := r.(io.Seeker); ok {
+		hdr, err = setSizeToEnd(hdr, s)
+		return hdr, r, err
+	}
+	lr := r
+	maxBodySize := uint64(0)
+	if maxSize > 0 {
+		if maxSize <= uint64(hdr.Hdrlen) {
+			return BoxHeader{}, r, fmt.Errorf("%s box with size 0: %w %d", hdr.Name, errMaxBoxSize, maxSize)
+		}
+		maxBodySize = maxSize - uint64(hdr.Hdrlen)
+		lr = io.LimitReader(r, int64(maxBodySize)+1)
+	}
+	data, err := io.ReadAll(lr)
+	if err != nil {
+		return BoxHeader{}, r, err
+	}
+	if maxSize > 0 && uint64(len(data)) > maxBodySize {
+		return BoxHeader{}, r, fmt.Errorf("%s box with size 0: %w %d", hdr.Name, errMaxBoxSize, maxSize)
+	}
+	hdr.Size = uint64(hdr.Hdrlen) + uint64(len(data))
+	return hdr, bytes.NewReader(data), nil
+}
+
+// setSizeToEnd sets the size of a box with size 0 given the remaining bytes of s.
+func setSizeToEnd(hdr BoxHeader, s io.Seeker) (BoxHeader, error) {
+	cur, err := s.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return BoxHeader{}, err
+	}
+	end, err := s.Seek(0, io.SeekEnd)
+	if err != nil {
+		return BoxHeader{}, err
+	}
+	_, err = s.Seek(cur, io.SeekStart)
+	if err != nil {
+		return BoxHeader{}, err
+	}
+	hdr.Size = uint64(hdr.Hdrlen) + uint64(end-cur)
+	return hdr, nil
+}
+
+// decodeHeader decodes a box header. Size 0 is returned as is.
+func decodeHeader(r io.Reader) (BoxHeader, error) {
 	buf := make([]byte, boxHeaderSize)
 	_, err := io.ReadFull(r, buf)
 	if err != nil {
@@ -200,10 +352,8 @@ func DecodeHeader(r io.Reader) (BoxHeader, error) {
 		}
 		size = binary.BigEndian.Uint64(buf)
 		headerLen += largeSizeLen
-	} else if size == 0 {
-		return BoxHeader{}, fmt.Errorf("Size 0, meaning to end of file, not supported")
 	}
-	if uint64(headerLen) > size {
+	if size != 0 && uint64(headerLen) > size {
 		return BoxHeader{}, fmt.Errorf("box header size %d exceeds box size %d", headerLen, size)
 	}
 	return BoxHeader{string(buf[4:8]), size, headerLen}, nil
@@ -284,6 +434,8 @@ type Box interface {
 	// Info - write box details
 	//   spedificBoxLevels is a comma-separated list box:level or all:level where level >= 0.
 	//   Higher levels give more details. 0 is default
+	//   For boxes with per-sample details, like trun and senc, a 1-based inclusive sample range
+	//   can be given instead of or after the level, e.g. trun:1-100 or trun:2:1-100.
 	//   indent is indent at this box level.
 	//   indentStep is how much to indent at each level
 	Info(w io.Writer, specificBoxLevels, indent, indentStep string) error
@@ -294,6 +446,8 @@ type Informer interface {
 	// Info - write details via Info method
 	//   spedificBoxLevels is a comma-separated list box:level or all:level where level >= 0.
 	//   Higher levels give more details. 0 is default
+	//   For boxes with per-sample details, like trun and senc, a 1-based inclusive sample range
+	//   can be given instead of or after the level, e.g. trun:1-100 or trun:2:1-100.
 	//   indent is indent at this box level.
 	//   indentStep is how much to indent at each level
 	Info(w io.Writer, specificBoxLevels, indent, indentStep string) error
@@ -303,14 +457,32 @@ type Informer interface {
 type BoxDecoder func(hdr BoxHeader, startPos uint64, r io.Reader) (Box, error)
 
 // DecodeBox decodes a box
+// A box with size 0 extends to the end of r. If r is not an io.Seeker, such a box is read into memory
+// and may be at most DefaultMaxBoxSize bytes.
 func DecodeBox(startPos uint64, r io.Reader) (Box, error) {
-	var err error
-	var b Box
+	h, br, err := decodeHeaderToEnd(r, DefaultMaxBoxSize)
+	if err != nil {
+		return nil, err
+	}
+	return decodeBoxBody(h, startPos, br)
+}
 
-	h, err := DecodeHeader(r)
+// decodeChildBox decodes a box inside a parent box. Size 0 is only allowed for top-level boxes.
+func decodeChildBox(startPos uint64, r io.Reader) (Box, error) {
+	h, err := decodeHeader(r)
 	if err != nil {
 		return nil, err
 	}
+	if h.Size == 0 {
+		return nil, fmt.Errorf("%s box with size 0 inside parent box", h.Name)
+	}
+	return decodeBoxBody(h, startPos, r)
+}
+
+// decodeBoxBody decodes the box body after the header h has been read from r
+func decodeBoxBody(h BoxHeader, startPos uint64, r io.Reader) (Box, error) {
+	var err error
+	var b Box
 
 	d, ok := decoders[h.Name]
 
@@ -328,13 +500,17 @@ func DecodeBox(startPos uint64, r io.Reader) (Box, error) {
 
 // DecodeBoxLazyMdat decodes a box but doesn't read mdat into memory
 func DecodeBoxLazyMdat(startPos uint64, r io.ReadSeeker) (Box, error) {
-	var err error
-	var b Box
-
 	h, err := DecodeHeader(r)
 	if err != nil {
 		return nil, err
 	}
+	return decodeBoxBodyLazyMdat(h, startPos, r)
+}
+
+// decodeBoxBodyLazyMdat decodes the box body after the header h has been read from r
+func decodeBoxBodyLazyMdat(h BoxHeader, startPos uint64, r io.ReadSeeker) (Box, error) {
+	var err error
+	var b Box
 
 	d, ok := decoders[h.Name]
 
@@ -3,6 +3,7 @@ package mp4
 import (
 	"bytes"
 	"encoding/hex"
+	"io"
 	"testing"
 
 	"github.com/Eyevinn/mp4ff/bits"
@@ -61,3 +62,90 @@ func TestFixed16and32(t *testing.T) {
 		t.Errorf("Fixed32(65536) should be 1.0, not %s", f32.String())
 	}
 }
+
+// testXyzBox is a proprietary box used to test RegisterBoxDecoder
+type testXyzBox struct {
+	Value uint32
+}
+
+func (b *testXyzBox) Type() string { return "xyzb" }
+func (b *testXyzBox) Size() uint64 { return boxHeaderSize + 4 }
+func (b *testXyzBox) Encode(w io.Writer) error {
+	sw := bits.NewFixedSliceWriter(int(b.Size()))
+	if err := b.EncodeSW(sw); err != nil {
+		return err
+	}
+	_, err := w.Write(sw.Bytes())
+	return err
+}
+func (b *testXyzBox) EncodeSW(sw bits.SliceWriter) error {
+	if err := EncodeHeaderSW(b, sw); err != nil {
+		return err
+	}
+	sw.WriteUint32(b.Value)
+	return sw.AccError()
+}
+func (b *testXyzBox) Info(w io.Writer, specificBoxLevels, indent, indentStep string) error {
+	return nil
+}
+
+func decodeTestXyzSR(hdr BoxHeader, startPos uint64, sr bits.SliceReader) (Box, error) {
+	b := &testXyzBox{Value: sr.ReadUint32()}
+	return b, sr.AccError()
+}
+
+func TestRegisterBoxDecoder(t *testing.T) {
+	udta := &UdtaBox{}
+	udta.AddChild(&testXyzBox{Value: 42})
+	data := encodeBox(t, udta)
+
+	checkDecoded := func(t *testing.T, wantCustom bool) {
+		t.Helper()
+		decs := []func() (Box, error){
+			func() (Box, error) { return DecodeBox(0, bytes.NewReader(data)) },
+			func() (Box, error) { return DecodeBoxSR(0, bits.NewFixedSliceReader(data)) },
+		}
+		for _, dec := range decs {
+			box, err := dec()
+			if err != nil {
+				t.Fatal(err)
+			}
+			child := box.(*UdtaBox).Children[0]
+			xyz, isCustom := child.(*testXyzBox)
+			if isCustom != wantCustom {
+				t.Fatalf("got %T for xyzb box", child)
+			}
+			if isCustom && xyz.Value != 42 {
+				t.Errorf("got value %d instead of 42", xyz.Value)
+			}
+		}
+	}
+
+	checkDecoded(t, false)
+	err := RegisterBoxDecoder("xyzb", func(hdr BoxHeader, startPos uint64, r io.Reader) (Box, error) {
+		data, err := readBoxBody(r, hdr)
+		if err != nil {
+			return nil, err
+		}
+		return decodeTestXyzSR(hdr, startPos, bits.NewFixedSliceReader(data))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkDecoded(t, true)
+	RemoveBoxDecoder("xyzb")
+	checkDecoded(t, false)
+	if err := RegisterBoxDecoderSR("xyzb", decodeTestXyzSR); err != nil {
+		t.Fatal(err)
+	}
+	checkDecoded(t, true)
+	RemoveBoxDecoder("xyzb")
+	checkDecoded(t, false)
+
+	if err := RegisterBoxDecoder("xyz", nil); err == nil {
+		t.Error("no error for 3-character box type")
+	}
+	if err := RegisterBoxDecoderSR("xyzb", nil); err == nil {
+		t.Error("no error for nil decoder")
+	}
+}
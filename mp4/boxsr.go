@@ -15,6 +15,7 @@ func init() {
 		"\xa9nam": DecodeGenericContainerBoxSR,
 		"\xa9too": DecodeGenericContainerBoxSR,
 		"ac-3":    DecodeAudioSampleEntrySR,
+		"ac-4":    DecodeAudioSampleEntrySR,
 		"alou":    DecodeAlouBoxSR,
 		"av01":    DecodeVisualSampleEntrySR,
 		"av1C":    DecodeAv1CSR,
@@ -24,7 +25,9 @@ func init() {
 		"btrt":    DecodeBtrtSR,
 		"cdat":    DecodeCdatSR,
 		"cdsc":    DecodeTrefTypeSR,
+		"chap":    DecodeTrefTypeSR,
 		"clap":    DecodeClapSR,
+		"clef":    DecodeTrackApertureDimensionsSR,
 		"co64":    DecodeCo64SR,
 		"CoLL":    DecodeCoLLSR,
 		"colr":    DecodeColrSR,
@@ -32,12 +35,22 @@ func init() {
 		"ctim":    DecodeCtimSR,
 		"ctts":    DecodeCttsSR,
 		"dac3":    DecodeDac3SR,
+		"dac4":    DecodeDac4SR,
 		"data":    DecodeDataSR,
 		"dec3":    DecodeDec3SR,
 		"desc":    DecodeGenericContainerBoxSR,
+		"dfLa":    DecodeDflaSR,
 		"dinf":    DecodeDinfSR,
+		"dOps":    DecodeDopsSR,
 		"dpnd":    DecodeTrefTypeSR,
 		"dref":    DecodeDrefSR,
+		"dva1":    DecodeVisualSampleEntrySR,
+		"dvav":    DecodeVisualSampleEntrySR,
+		"dvcC":    DecodeDoViConfigurationSR,
+		"dvh1":    DecodeVisualSampleEntrySR,
+		"dvhe":    DecodeVisualSampleEntrySR,
+		"dvvC":    DecodeDoViConfigurationSR,
+		"dvwC":    DecodeDoViConfigurationSR,
 		"ec-3":    DecodeAudioSampleEntrySR,
 		"edts":    DecodeEdtsSR,
 		"elng":    DecodeElngSR,
@@ -47,11 +60,14 @@ func init() {
 		"emsg":    DecodeEmsgSR,
 		"enca":    DecodeAudioSampleEntrySR,
 		"encv":    DecodeVisualSampleEntrySR,
+		"enof":    DecodeTrackApertureDimensionsSR,
 		"esds":    DecodeEsdsSR,
 		"evte":    DecodeEvteSR,
+		"fLaC":    DecodeAudioSampleEntrySR,
 		"font":    DecodeTrefTypeSR,
 		"free":    DecodeFreeSR,
 		"frma":    DecodeFrmaSR,
+		"ftab":    DecodeFtabSR,
 		"ftyp":    DecodeFtypSR,
 		"hdlr":    DecodeHdlrSR,
 		"hev1":    DecodeVisualSampleEntrySR,
@@ -59,10 +75,19 @@ func init() {
 		"hint":    DecodeTrefTypeSR,
 		"hvc1":    DecodeVisualSampleEntrySR,
 		"hvcC":    DecodeHvcCSR,
+		"idat":    DecodeIdatSR,
 		"iden":    DecodeIdenSR,
+		"iinf":    DecodeIinfSR,
+		"iloc":    DecodeIlocSR,
 		"ilst":    DecodeIlstSR,
+		"infe":    DecodeInfeSR,
 		"iods":    DecodeUnknownSR,
+		"ipco":    DecodeIpcoSR,
 		"ipir":    DecodeTrefTypeSR,
+		"ipma":    DecodeIpmaSR,
+		"iprp":    DecodeIprpSR,
+		"iref":    DecodeIrefSR,
+		"ispe":    DecodeIspeSR,
 		"kind":    DecodeKindSR,
 		"leva":    DecodeLevaSR,
 		"ludt":    DecodeLudtSR,
@@ -83,9 +108,12 @@ func init() {
 		"mvex":    DecodeMvexSR,
 		"mvhd":    DecodeMvhdSR,
 		"nmhd":    DecodeNmhdSR,
+		"Opus":    DecodeAudioSampleEntrySR,
 		"pasp":    DecodePaspSR,
 		"payl":    DecodePaylSR,
+		"pitm":    DecodePitmSR,
 		"prft":    DecodePrftSR,
+		"prof":    DecodeTrackApertureDimensionsSR,
 		"pssh":    DecodePsshSR,
 		"saio":    DecodeSaioSR,
 		"saiz":    DecodeSaizSR,
@@ -113,9 +141,11 @@ func init() {
 		"sttg":    DecodeSttgSR,
 		"stts":    DecodeSttsSR,
 		"styp":    DecodeStypSR,
+		"stz2":    DecodeStz2SR,
 		"subs":    DecodeSubsSR,
 		"subt":    DecodeTrefTypeSR,
 		"sync":    DecodeTrefTypeSR,
+		"tapt":    DecodeTaptSR,
 		"tenc":    DecodeTencSR,
 		"tfdt":    DecodeTfdtSR,
 		"tfhd":    DecodeTfhdSR,
@@ -128,6 +158,7 @@ func init() {
 		"trep":    DecodeTrepSR,
 		"trex":    DecodeTrexSR,
 		"trun":    DecodeTrunSR,
+		"tx3g":    DecodeTx3gSR,
 		"udta":    DecodeUdtaSR,
 		"url ":    DecodeURLBoxSR,
 		"uuid":    DecodeUUIDBoxSR,
@@ -143,6 +174,9 @@ func init() {
 		"vttc":    DecodeVttcSR,
 		"vttC":    DecodeVttCSR,
 		"vtte":    DecodeVtteSR,
+		"vvc1":    DecodeVisualSampleEntrySR,
+		"vvcC":    DecodeVvcCSR,
+		"vvi1":    DecodeVisualSampleEntrySR,
 		"wvtt":    DecodeWvttSR,
 	}
 }
@@ -183,7 +217,8 @@ func DecodeBoxSR(startPos uint64, sr bits.SliceReader) (Box, error) {
 	return b, nil
 }
 
-// DecodeHeaderSR - decode a box header (size + box type + possible largeSize) from sr
+// DecodeHeaderSR - decode a box header (size + box type + possible largeSize) from sr.
+// Size 0, meaning that the box extends to the end of the file, results in the size up to the end of sr.
 func DecodeHeaderSR(sr bits.SliceReader) (BoxHeader, error) {
 	size := uint64(sr.ReadUint32())
 	boxType := sr.ReadFixedLengthString(4)
@@ -191,8 +226,8 @@ func DecodeHeaderSR(sr bits.SliceReader) (BoxHeader, error) {
 	if size == 1 {
 		size = sr.ReadUint64()
 		headerLen += largeSizeLen
-	} else if size == 0 {
-		return BoxHeader{}, fmt.Errorf("Size 0, meaning to end of file, not supported")
+	} else if size == 0 { // box extends to end of file
+		size = uint64(headerLen + sr.NrRemainingBytes())
 	}
 	if uint64(headerLen) > size {
 		return BoxHeader{}, fmt.Errorf("box header size %d exceeds box size %d", headerLen, size)
@@ -215,6 +250,8 @@ func DecodeFileSR(sr bits.SliceReader, options ...Option) (*File, error) {
 		return nil, fmt.Errorf("no support for lazy mdat in DecodeFileSR")
 	}
 
+	prevSkipped := false
+
 LoopBoxes:
 	for {
 		var box Box
@@ -223,11 +260,22 @@ LoopBoxes:
 			break LoopBoxes
 		}
 
-		box, err = DecodeBoxSR(boxStartPos, sr)
+		if f.boxFilter != nil {
+			box, err = f.decodeOrSkipBoxSR(boxStartPos, sr, prevSkipped)
+		} else {
+			box, err = DecodeBoxSR(boxStartPos, sr)
+		}
 		if err != nil {
 			return nil, err
 		}
 		boxType, boxSize := box.Type(), box.Size()
+		prevSkipped = isSkippedBox(box)
+		if prevSkipped {
+			f.Children = append(f.Children, box)
+			lastBoxType = boxType
+			boxStartPos += boxSize
+			continue LoopBoxes
+		}
 		switch boxType {
 		case "mdat":
 			if f.isFragmented {
@@ -274,3 +322,21 @@ LoopBoxes:
 	}
 	return f, nil
 }
+
+// decodeOrSkipBoxSR decodes a box from sr, or skips its payload if it is not in the box filter.
+func (f *File) decodeOrSkipBoxSR(startPos uint64, sr bits.SliceReader, prevSkipped bool) (Box, error) {
+	pos := sr.GetPos()
+	hdr, err := DecodeHeaderSR(sr)
+	if err != nil {
+		return nil, err
+	}
+	if f.skipBox(hdr, prevSkipped) {
+		if int(hdr.Size)-hdr.Hdrlen > sr.NrRemainingBytes() {
+			return nil, fmt.Errorf("skip %s box: size %d beyond end of data", hdr.Name, hdr.Size)
+		}
+		sr.SkipBytes(int(hdr.Size) - hdr.Hdrlen)
+		return newSkippedBox(hdr), sr.AccError()
+	}
+	sr.SetPos(pos)
+	return DecodeBoxSR(startPos, sr)
+}
@@ -0,0 +1,85 @@
+package mp4
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/Eyevinn/mp4ff/avc"
+	"github.com/Eyevinn/mp4ff/hevc"
+	"github.com/Eyevinn/mp4ff/sei"
+)
+
+// CaptionData - CEA-608/708 cc_data triples from one sample with presentation time in mdhd timescale
+type CaptionData struct {
+	PresentationTime uint64
+	CCData           []sei.CCData
+}
+
+// ExtractCaptions - extract CEA-608/708 caption data carried in SEI NAL units of the fragment samples.
+// The result is sorted in presentation order. Samples without caption data are not included.
+// Only 4-byte NALU lengths are supported.
+func (f *Fragment) ExtractCaptions(trex *TrexBox, codec sei.Codec) ([]CaptionData, error) {
+	samples, err := f.GetFullSamples(trex)
+	if err != nil {
+		return nil, err
+	}
+	var captions []CaptionData
+	for i := range samples {
+		ccData, err := extractSampleCCData(samples[i].Data, codec)
+		if err != nil {
+			return nil, fmt.Errorf("sample %d: %w", i+1, err)
+		}
+		if len(ccData) == 0 {
+			continue
+		}
+		captions = append(captions, CaptionData{
+			PresentationTime: samples[i].PresentationTime(),
+			CCData:           ccData,
+		})
+	}
+	sort.SliceStable(captions, func(i, j int) bool {
+		return captions[i].PresentationTime < captions[j].PresentationTime
+	})
+	return captions, nil
+}
+
+// extractSampleCCData - get all cc_data triples from SEI NAL units in a sample
+func extractSampleCCData(sample []byte, codec sei.Codec) ([]sei.CCData, error) {
+	nalus, err := avc.GetNalusFromSample(sample)
+	if err != nil {
+		return nil, err
+	}
+	var ccData []sei.CCData
+	for _, nalu := range nalus {
+		if len(nalu) == 0 {
+			continue
+		}
+		var msgs []sei.SEIMessage
+		switch codec {
+		case sei.AVC:
+			if avc.GetNaluType(nalu[0]) != avc.NALU_SEI {
+				continue
+			}
+			msgs, err = avc.ParseSEINalu(nalu, nil)
+		case sei.HEVC:
+			switch hevc.GetNaluType(nalu[0]) {
+			case hevc.NALU_SEI_PREFIX, hevc.NALU_SEI_SUFFIX:
+				msgs, err = hevc.ParseSEINalu(nalu, nil)
+			default:
+				continue
+			}
+		default:
+			return nil, fmt.Errorf("unknown codec %d", codec)
+		}
+		if err != nil && !errors.Is(err, sei.ErrRbspTrailingBitsMissing) {
+			return nil, err
+		}
+		for _, msg := range msgs {
+			if cea608, ok := msg.(*sei.CEA608sei); ok && cea608.ProcessCCData {
+				ccData = append(ccData, cea608.CCData...)
+			}
+		}
+	}
+	return ccData, nil
+}
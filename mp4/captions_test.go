@@ -0,0 +1,57 @@
+package mp4_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/Eyevinn/mp4ff/mp4"
+	"github.com/Eyevinn/mp4ff/sei"
+	"github.com/go-test/deep"
+)
+
+func TestExtractCaptions(t *testing.T) {
+	// AVC SEI NALU with user_data_registered_itu_t_t35 and cc_data with two triples
+	seiNalu, _ := hex.DecodeString("06" + "0411b500314741393403c2fffc9420fd8080ff80")
+	idrNalu := []byte{0x65, 0x88, 0x84}
+	sample := make([]byte, 0, 8+len(seiNalu)+len(idrNalu))
+	for _, nalu := range [][]byte{seiNalu, idrNalu} {
+		sample = append(sample, 0, 0, 0, byte(len(nalu)))
+		sample = append(sample, nalu...)
+	}
+	frag, err := mp4.CreateFragment(1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	frag.AddFullSample(mp4.FullSample{
+		Sample:     mp4.NewSample(mp4.SyncSampleFlags, 1000, uint32(len(sample)), 500),
+		DecodeTime: 9000,
+		Data:       sample,
+	})
+	buf := bytes.Buffer{}
+	err = frag.Encode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decFile, err := mp4.DecodeFile(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decFrag := decFile.Segments[0].Fragments[0]
+	captions, err := decFrag.ExtractCaptions(nil, sei.AVC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wanted := []mp4.CaptionData{
+		{
+			PresentationTime: 9500,
+			CCData: []sei.CCData{
+				{Valid: true, Type: sei.CCTypeNTSCField1, Data1: 0x94, Data2: 0x20},
+				{Valid: true, Type: sei.CCTypeNTSCField2, Data1: 0x80, Data2: 0x80},
+			},
+		},
+	}
+	if diff := deep.Equal(captions, wanted); diff != nil {
+		t.Error(diff)
+	}
+}
@@ -0,0 +1,110 @@
+package mp4
+
+import (
+	"io"
+
+	"github.com/Eyevinn/mp4ff/bits"
+)
+
+// CMAFChunk - CMAF chunk ([emsg/prft] + moof + mdat) following the first moof and mdat of a Fragment.
+// A low-latency CMAF fragment consists of one or more chunks, where only the first chunk
+// needs to start with a sync sample. The first chunk is stored directly in the Fragment.
+type CMAFChunk struct {
+	Emsgs       []*EmsgBox
+	Prft        *PrftBox
+	Moof        *MoofBox
+	Mdat        *MdatBox
+	Children    []Box       // All top-level boxes in order
+	EncOptimize EncOptimize // Bit field with optimizations being done at encoding
+	StartPos    uint64      // Start position in file added by parser
+}
+
+// AddChild adds a top-level box to CMAFChunk. Add in proper order.
+func (c *CMAFChunk) AddChild(b Box) {
+	switch box := b.(type) {
+	case *EmsgBox:
+		c.Emsgs = append(c.Emsgs, box)
+	case *PrftBox:
+		c.Prft = box
+	case *MoofBox:
+		c.Moof = box
+	case *MdatBox:
+		c.Mdat = box
+	}
+	c.Children = append(c.Children, b)
+}
+
+// asFragment returns a Fragment sharing the boxes of the chunk.
+func (c *CMAFChunk) asFragment() *Fragment {
+	return &Fragment{
+		Emsgs:       c.Emsgs,
+		Prft:        c.Prft,
+		Moof:        c.Moof,
+		Mdat:        c.Mdat,
+		Children:    c.Children,
+		EncOptimize: c.EncOptimize,
+		StartPos:    c.StartPos,
+	}
+}
+
+// Size - return size of chunk including all boxes.
+func (c *CMAFChunk) Size() uint64 {
+	var size uint64
+	for _, b := range c.Children {
+		size += b.Size()
+	}
+	return size
+}
+
+// GetFullSamples - get full samples of the track given by trex (first track if nil)
+func (c *CMAFChunk) GetFullSamples(trex *TrexBox) ([]FullSample, error) {
+	return c.asFragment().GetFullSamples(trex)
+}
+
+// SetTrunDataOffsets - set dataOffset in truns given the moof size
+func (c *CMAFChunk) SetTrunDataOffsets() {
+	c.asFragment().SetTrunDataOffsets()
+}
+
+// Encode - write chunk via writer
+func (c *CMAFChunk) Encode(w io.Writer) error {
+	return c.asFragment().Encode(w)
+}
+
+// EncodeSW - write chunk via SliceWriter
+func (c *CMAFChunk) EncodeSW(sw bits.SliceWriter) error {
+	return c.asFragment().EncodeSW(sw)
+}
+
+// Info - write box-specific information
+func (c *CMAFChunk) Info(w io.Writer, specificBoxLevels, indent, indentStep string) error {
+	for _, box := range c.Children {
+		err := box.Info(w, specificBoxLevels, indent, indentStep)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isChunkContinuation - true if the first sample of the first traf in moof is flagged as non-sync,
+// so that moof cannot start a new CMAF fragment.
+func isChunkContinuation(moof *MoofBox, trex *TrexBox) bool {
+	traf := moof.Traf
+	if traf == nil || traf.Trun == nil || traf.Trun.SampleCount() == 0 {
+		return false
+	}
+	trun := traf.Trun
+	var flags uint32
+	switch {
+	case trun.HasFirstSampleFlags():
+		flags, _ = trun.FirstSampleFlags()
+	case trun.HasSampleFlags():
+		flags = trun.Samples[0].Flags
+	case traf.Tfhd.HasDefaultSampleFlags():
+		flags = traf.Tfhd.DefaultSampleFlags
+	case trex != nil:
+		flags = trex.DefaultSampleFlags
+	}
+	return DecodeSampleFlags(flags).SampleIsNonSync
+}
@@ -0,0 +1,101 @@
+package mp4
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/go-test/deep"
+)
+
+// createTestFragment - fragment with nrSamples samples, where only the first is sync if firstSync is set
+func createTestFragment(t *testing.T, seqNr uint32, decodeTime uint64, nrSamples int, firstSync bool) *Fragment {
+	t.Helper()
+	frag, err := CreateFragment(seqNr, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < nrSamples; i++ {
+		flags := NonSyncSampleFlags
+		if i == 0 && firstSync {
+			flags = SyncSampleFlags
+		}
+		data := []byte{byte(seqNr), byte(i)}
+		frag.AddFullSample(FullSample{Sample{flags, 1000, 2, 0}, decodeTime + uint64(i)*1000, data})
+	}
+	return frag
+}
+
+func TestCMAFChunks(t *testing.T) {
+	init := CreateEmptyInit()
+	init.AddEmptyTrack(10000, "video", "und")
+
+	seg := NewMediaSegment()
+	frag := createTestFragment(t, 1, 0, 3, true)
+	chunkFrag := createTestFragment(t, 2, 3000, 2, false)
+	chunk := &CMAFChunk{}
+	for _, c := range chunkFrag.Children {
+		chunk.AddChild(c)
+	}
+	frag.AddChunk(chunk)
+	seg.AddFragment(frag)
+	seg.AddFragment(createTestFragment(t, 3, 5000, 2, true))
+
+	buf := bytes.Buffer{}
+	if err := init.Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if err := seg.Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	encoded := buf.Bytes()
+
+	plain, err := DecodeFile(bytes.NewReader(encoded))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nrFrags := len(plain.Segments[0].Fragments); nrFrags != 3 {
+		t.Errorf("%d fragments instead of 3 without DecCMAFChunks", nrFrags)
+	}
+
+	dec, err := DecodeFile(bytes.NewReader(encoded), WithDecodeFlags(DecCMAFChunks))
+	if err != nil {
+		t.Fatal(err)
+	}
+	decFrags := dec.Segments[0].Fragments
+	if len(decFrags) != 2 {
+		t.Fatalf("%d fragments instead of 2 with DecCMAFChunks", len(decFrags))
+	}
+	if len(decFrags[0].Chunks) != 1 || len(decFrags[1].Chunks) != 0 {
+		t.Errorf("got %d and %d chunks instead of 1 and 0", len(decFrags[0].Chunks), len(decFrags[1].Chunks))
+	}
+	trex := dec.Init.Moov.Mvex.Trex
+	samples, err := decFrags[0].GetFullSamples(trex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wantedSamples []FullSample
+	for _, pf := range plain.Segments[0].Fragments[:2] {
+		fs, err := pf.GetFullSamples(trex)
+		if err != nil {
+			t.Fatal(err)
+		}
+		wantedSamples = append(wantedSamples, fs...)
+	}
+	if len(samples) != 5 {
+		t.Errorf("%d samples instead of 5", len(samples))
+	}
+	if diff := deep.Equal(samples, wantedSamples); diff != nil {
+		t.Error(diff)
+	}
+
+	out := bytes.Buffer{}
+	if err = dec.Encode(&out); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out.Bytes(), encoded) {
+		t.Error("encoded chunked file differs from original")
+	}
+	if dec.Size() != uint64(len(encoded)) {
+		t.Errorf("file size %d instead of %d", dec.Size(), len(encoded))
+	}
+}
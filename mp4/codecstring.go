@@ -0,0 +1,132 @@
+package mp4
+
+import (
+	"fmt"
+
+	"github.com/Eyevinn/mp4ff/avc"
+	"github.com/Eyevinn/mp4ff/hevc"
+)
+
+// CodecString returns the RFC 6381 codecs parameter, as used in DASH and HLS manifests, for the
+// first sample entry of the track with trackID. Examples are avc1.640028, hvc1.2.4.L123.B0,
+// mp4a.40.2, av01.0.08M.08, and vp09.00.10.08.
+// For encrypted tracks, the original format in the sinf box is used as sample entry type.
+// Sample entries like ac-3, ec-3, Opus, fLaC, wvtt, stpp, and tx3g result in just the sample entry type.
+func (s *InitSegment) CodecString(trackID uint32) (string, error) {
+	trak, err := findTrak(s, trackID)
+	if err != nil {
+		return "", err
+	}
+	stsd := trak.Mdia.Minf.Stbl.Stsd
+	if stsd == nil || len(stsd.Children) == 0 {
+		return "", fmt.Errorf("no sample entry for track %d", trackID)
+	}
+	switch se := stsd.Children[0].(type) {
+	case *VisualSampleEntryBox:
+		return visualCodecString(se)
+	case *AudioSampleEntryBox:
+		return audioCodecString(se)
+	default:
+		switch entry := se.Type(); entry {
+		case "wvtt", "stpp", "tx3g", "evte":
+			return entry, nil
+		default:
+			return "", fmt.Errorf("codec string not supported for sample entry %s", entry)
+		}
+	}
+}
+
+// visualCodecString returns the codec string given the configuration box of the sample entry.
+func visualCodecString(se *VisualSampleEntryBox) (string, error) {
+	entry := se.Type()
+	if se.Sinf != nil && se.Sinf.OriginalFormat() != "" {
+		entry = se.Sinf.OriginalFormat()
+	}
+	switch {
+	case se.AvcC != nil:
+		dcr := se.AvcC.DecConfRec
+		sps := avc.SPS{
+			Profile:              uint32(dcr.AVCProfileIndication),
+			ProfileCompatibility: uint32(dcr.ProfileCompatibility),
+			Level:                uint32(dcr.AVCLevelIndication),
+		}
+		return avc.CodecString(entry, &sps), nil
+	case se.HvcC != nil:
+		dcr := se.HvcC.DecConfRec
+		sps := hevc.SPS{
+			ProfileTierLevel: hevc.ProfileTierLevel{
+				GeneralProfileSpace:              dcr.GeneralProfileSpace,
+				GeneralTierFlag:                  dcr.GeneralTierFlag,
+				GeneralProfileIDC:                dcr.GeneralProfileIDC,
+				GeneralProfileCompatibilityFlags: dcr.GeneralProfileCompatibilityFlags,
+				GeneralConstraintIndicatorFlags:  dcr.GeneralConstraintIndicatorFlags,
+				GeneralLevelIDC:                  dcr.GeneralLevelIDC,
+			},
+		}
+		return hevc.CodecString(entry, &sps), nil
+	case se.Av1C != nil:
+		ccr := se.Av1C.CodecConfRec
+		tier := "M"
+		if ccr.SeqTier0 == 1 {
+			tier = "H"
+		}
+		bitDepth := 8
+		if ccr.HighBitdepth == 1 {
+			bitDepth = 10
+			if ccr.SeqProfile == 2 && ccr.TwelveBit == 1 {
+				bitDepth = 12
+			}
+		}
+		return fmt.Sprintf("%s.%d.%02d%s.%02d", entry, ccr.SeqProfile, ccr.SeqLevelIdx0, tier, bitDepth), nil
+	case se.VppC != nil:
+		v := se.VppC
+		return fmt.Sprintf("%s.%02d.%02d.%02d", entry, v.Profile, v.Level, v.BitDepth), nil
+	default:
+		return "", fmt.Errorf("no supported configuration box in sample entry %s", se.Type())
+	}
+}
+
+// audioCodecString returns the codec string given the sample entry and its esds box for MPEG-4 audio.
+func audioCodecString(se *AudioSampleEntryBox) (string, error) {
+	entry := se.Type()
+	if se.Sinf != nil && se.Sinf.OriginalFormat() != "" {
+		entry = se.Sinf.OriginalFormat()
+	}
+	switch entry {
+	case "mp4a":
+		if se.Esds == nil || se.Esds.DecConfigDescriptor == nil {
+			return "", fmt.Errorf("no esds decoder config descriptor in mp4a sample entry")
+		}
+		dcd := se.Esds.DecConfigDescriptor
+		if dcd.ObjectType != 0x40 { // Not MPEG-4 audio with AudioSpecificConfig
+			return fmt.Sprintf("%s.%02X", entry, dcd.ObjectType), nil
+		}
+		if dcd.DecSpecificInfo == nil {
+			return "", fmt.Errorf("no decoder specific info in esds")
+		}
+		aot, err := audioObjectType(dcd.DecSpecificInfo.DecConfig)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s.40.%d", entry, aot), nil
+	case "ac-3", "ec-3", "Opus", "fLaC":
+		return entry, nil
+	default:
+		return "", fmt.Errorf("codec string not supported for audio sample entry %s", entry)
+	}
+}
+
+// audioObjectType returns the audio object type at the start of an AudioSpecificConfig.
+func audioObjectType(asc []byte) (byte, error) {
+	if len(asc) < 1 {
+		return 0, fmt.Errorf("empty AudioSpecificConfig")
+	}
+	aot := asc[0] >> 3
+	if aot == 31 { // Escape value, 6 more bits follow
+		if len(asc) < 2 {
+			return 0, fmt.Errorf("AudioSpecificConfig too short for extended audio object type")
+		}
+		aot = 32 + ((asc[0]&0x07)<<3 | asc[1]>>5)
+	}
+	return aot, nil
+}
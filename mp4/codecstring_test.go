@@ -0,0 +1,72 @@
+package mp4
+
+import (
+	"os"
+	"testing"
+
+	"github.com/Eyevinn/mp4ff/av1"
+)
+
+func TestCodecString(t *testing.T) {
+	testCases := []struct {
+		file    string
+		trackID uint32
+		want    string
+	}{
+		{"testdata/hvc1_init.mp4", 1, "hvc1.1.6.L63.90"},
+		{"testdata/init.mp4", 2, "avc1.64001E"},
+		{"testdata/aac_init.mp4", 1, "mp4a.40.2"},
+		{"testdata/init_cenc.cmfv", 1, "avc3.64001E"},
+		{"testdata/init1.cmfv", 1, "avc3.4D001F"},
+	}
+	for _, tc := range testCases {
+		fh, err := os.Open(tc.file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f, err := DecodeFile(fh)
+		fh.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := f.Init.CodecString(tc.trackID)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.file, err)
+		}
+		if got != tc.want {
+			t.Errorf("%s: got %q instead of %q", tc.file, got, tc.want)
+		}
+	}
+}
+
+func TestCodecStringAV1AndVP9(t *testing.T) {
+	init := CreateEmptyInit()
+	init.AddEmptyTrack(90000, "video", "und")
+	init.AddEmptyTrack(90000, "video", "und")
+	av1C := &Av1CBox{CodecConfRec: av1.CodecConfRec{Version: 1, SeqLevelIdx0: 8, HighBitdepth: 1}}
+	init.Moov.Traks[0].Mdia.Minf.Stbl.Stsd.AddChild(CreateVisualSampleEntryBox("av01", 1280, 720, av1C))
+	vppC := &VppCBox{Version: 1, Profile: 0, Level: 10, BitDepth: 8}
+	init.Moov.Traks[1].Mdia.Minf.Stbl.Stsd.AddChild(CreateVisualSampleEntryBox("vp09", 1280, 720, vppC))
+	for i, want := range []string{"av01.0.08M.10", "vp09.00.10.08"} {
+		got, err := init.CodecString(init.Moov.Traks[i].Tkhd.TrackID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("got %q instead of %q", got, want)
+		}
+	}
+	if _, err := init.CodecString(17); err == nil {
+		t.Error("no error for unknown track")
+	}
+}
+
+func TestAudioObjectType(t *testing.T) {
+	aot, err := audioObjectType([]byte{0xf8, 0x20}) // escape 31 followed by 6 bits 000001
+	if err != nil {
+		t.Fatal(err)
+	}
+	if aot != 33 {
+		t.Errorf("got audio object type %d instead of 33", aot)
+	}
+}
@@ -0,0 +1,231 @@
+package mp4
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// ConcatenateFiles concatenates two fragmented files into one file with a continuous timeline.
+// The media segments of second are appended after those of first. The baseMediaDecodeTime of
+// second is shifted so that track trackID continues where it ends in first, and the moof
+// sequence numbers continue from the last one in first.
+// The init segments must have the same timescale and sample descriptions for the track.
+// Only fragments with the single track trackID are supported. CMAF chunks in the fragments
+// are shifted and numbered like the first moof of each fragment.
+// A top-level sidx box in first is recalculated to cover all segments.
+// The result shares init segment and media segments with first and second, which are modified
+// in place: the times, sequence numbers, and data offsets of second are shifted, and the sidx of
+// first is updated. All checks are made before any modification, so the inputs are unchanged if
+// an error is returned, except for an error when updating the sidx.
+func ConcatenateFiles(first, second *File, trackID uint32) (*File, error) {
+	for i, f := range []*File{first, second} {
+		if !f.IsFragmented() || f.Init == nil {
+			return nil, fmt.Errorf("file %d is not fragmented with init segment", i+1)
+		}
+		if len(f.Segments) == 0 {
+			return nil, fmt.Errorf("file %d has no media segments", i+1)
+		}
+		if len(f.Sidxs) > 1 {
+			return nil, fmt.Errorf("file %d has %d top-level sidx boxes, only one supported", i+1, len(f.Sidxs))
+		}
+		for _, seg := range f.Segments {
+			for _, frag := range seg.Fragments {
+				for _, moof := range fragmentMoofs(frag) {
+					if moof == nil || len(moof.Trafs) == 0 {
+						return nil, fmt.Errorf("file %d: fragment without moof and traf boxes", i+1)
+					}
+					for _, traf := range moof.Trafs {
+						if traf.Tfhd.TrackID != trackID {
+							return nil, fmt.Errorf("file %d: fragment with track %d, only track %d supported",
+								i+1, traf.Tfhd.TrackID, trackID)
+						}
+						if traf.Tfdt == nil {
+							return nil, fmt.Errorf("file %d: traf without tfdt box", i+1)
+						}
+					}
+				}
+			}
+		}
+	}
+	firstTrak, err := findTrak(first.Init, trackID)
+	if err != nil {
+		return nil, fmt.Errorf("first file: %w", err)
+	}
+	secondTrak, err := findTrak(second.Init, trackID)
+	if err != nil {
+		return nil, fmt.Errorf("second file: %w", err)
+	}
+	firstTimescale := firstTrak.Mdia.Mdhd.Timescale
+	secondTimescale := secondTrak.Mdia.Mdhd.Timescale
+	if firstTimescale != secondTimescale {
+		return nil, fmt.Errorf("track %d timescale mismatch: %d and %d", trackID, firstTimescale, secondTimescale)
+	}
+	if err := compareSampleDescriptions(firstTrak, secondTrak); err != nil {
+		return nil, fmt.Errorf("track %d: %w", trackID, err)
+	}
+	if first.Init.Moov.Mvex == nil {
+		return nil, fmt.Errorf("no mvex box in first file")
+	}
+	trex, ok := first.Init.Moov.Mvex.GetTrex(trackID)
+	if !ok {
+		return nil, fmt.Errorf("no trex box found for track %d", trackID)
+	}
+
+	var endTime uint64
+	var lastSeqNr uint32
+	for _, seg := range first.Segments {
+		for _, frag := range seg.Fragments {
+			for _, moof := range fragmentMoofs(frag) {
+				lastSeqNr = moof.Mfhd.SequenceNumber
+				for _, traf := range moof.Trafs {
+					endTime = traf.Tfdt.BaseMediaDecodeTime() + trafDuration(traf, trex)
+				}
+			}
+		}
+	}
+
+	// Check all times of second before modifying it, so that it is unchanged on error
+	secondStart := second.Segments[0].Fragments[0].Moof.Traf.Tfdt.BaseMediaDecodeTime()
+	checkTime := func(t uint64) error {
+		if t < secondStart {
+			return fmt.Errorf("time %d before start time %d of second file", t, secondStart)
+		}
+		return nil
+	}
+	for _, seg := range second.Segments {
+		for _, sidxs := range seg.SidxsByFrag {
+			for _, sidx := range sidxs {
+				if sidx.Timescale != firstTimescale {
+					return nil, fmt.Errorf("segment sidx timescale %d differs from track timescale %d",
+						sidx.Timescale, firstTimescale)
+				}
+				if err := checkTime(sidx.EarliestPresentationTime); err != nil {
+					return nil, err
+				}
+			}
+		}
+		for _, frag := range seg.Fragments {
+			for _, moof := range fragmentMoofs(frag) {
+				for _, traf := range moof.Trafs {
+					if err := checkTime(traf.Tfdt.BaseMediaDecodeTime()); err != nil {
+						return nil, err
+					}
+				}
+			}
+		}
+	}
+
+	shiftTime := func(t uint64) uint64 {
+		return t - secondStart + endTime
+	}
+	for _, seg := range second.Segments {
+		for _, sidxs := range seg.SidxsByFrag {
+			for _, sidx := range sidxs {
+				sidx.EarliestPresentationTime = shiftTime(sidx.EarliestPresentationTime)
+			}
+		}
+		for _, frag := range seg.Fragments {
+			for _, moof := range fragmentMoofs(frag) {
+				lastSeqNr++
+				moof.Mfhd.SequenceNumber = lastSeqNr
+				for _, traf := range moof.Trafs {
+					oldSize := traf.Tfdt.Size()
+					traf.Tfdt.SetBaseMediaDecodeTime(shiftTime(traf.Tfdt.BaseMediaDecodeTime()))
+					sizeDiff := int32(traf.Tfdt.Size()) - int32(oldSize)
+					if sizeDiff != 0 && !traf.Tfhd.HasBaseDataOffset() {
+						for _, trun := range traf.Truns {
+							if trun.HasDataOffset() {
+								trun.DataOffset += sizeDiff
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+
+	out := NewFile()
+	out.isFragmented = true
+	out.FragEncMode = first.FragEncMode
+	out.EncOptimize = first.EncOptimize
+	out.Init = first.Init
+	out.Ftyp = first.Ftyp
+	out.Moov = first.Moov
+	out.Sidx = first.Sidx
+	out.Sidxs = first.Sidxs
+	for _, c := range first.Children {
+		if _, isMfra := c.(*MfraBox); !isMfra {
+			out.Children = append(out.Children, c)
+		}
+	}
+	firstMediaBox, err := second.Segments[0].FirstBox()
+	if err != nil {
+		return nil, fmt.Errorf("second file: %w", err)
+	}
+	inMedia := false
+	for _, c := range second.Children {
+		if c == firstMediaBox {
+			inMedia = true
+		}
+		if _, isMfra := c.(*MfraBox); inMedia && !isMfra {
+			out.Children = append(out.Children, c)
+		}
+	}
+	out.Segments = append(out.Segments, first.Segments...)
+	out.Segments = append(out.Segments, second.Segments...)
+	if out.Sidx != nil {
+		err = out.UpdateSidx(false, out.Sidx.EarliestPresentationTime > 0)
+		if err != nil {
+			return nil, fmt.Errorf("update sidx: %w", err)
+		}
+	}
+	return out, nil
+}
+
+// fragmentMoofs returns the moof box of frag followed by the moof boxes of its CMAF chunks.
+func fragmentMoofs(frag *Fragment) []*MoofBox {
+	moofs := make([]*MoofBox, 0, 1+len(frag.Chunks))
+	moofs = append(moofs, frag.Moof)
+	for _, c := range frag.Chunks {
+		moofs = append(moofs, c.Moof)
+	}
+	return moofs
+}
+
+// findTrak returns the trak with trackID in the init segment.
+func findTrak(init *InitSegment, trackID uint32) (*TrakBox, error) {
+	for _, trak := range init.Moov.Traks {
+		if trak.Tkhd.TrackID == trackID {
+			return trak, nil
+		}
+	}
+	return nil, fmt.Errorf("no track with trackID %d", trackID)
+}
+
+// compareSampleDescriptions returns an error if the stsd boxes of the two traks differ.
+func compareSampleDescriptions(a, b *TrakBox) error {
+	bufA, bufB := bytes.Buffer{}, bytes.Buffer{}
+	if err := a.Mdia.Minf.Stbl.Stsd.Encode(&bufA); err != nil {
+		return err
+	}
+	if err := b.Mdia.Minf.Stbl.Stsd.Encode(&bufB); err != nil {
+		return err
+	}
+	if !bytes.Equal(bufA.Bytes(), bufB.Bytes()) {
+		return fmt.Errorf("sample descriptions (codec configurations) differ")
+	}
+	return nil
+}
+
+// trafDuration returns the total sample duration of all truns in traf.
+func trafDuration(traf *TrafBox, trex *TrexBox) uint64 {
+	defaultDur := trex.DefaultSampleDuration
+	if traf.Tfhd.HasDefaultSampleDuration() {
+		defaultDur = traf.Tfhd.DefaultSampleDuration
+	}
+	var dur uint64
+	for _, trun := range traf.Truns {
+		dur += trun.Duration(defaultDur)
+	}
+	return dur
+}
@@ -0,0 +1,167 @@
+package mp4
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/go-test/deep"
+)
+
+func TestConcatenateFiles(t *testing.T) {
+	const inFile = "./testdata/bbb5s_aac_sidx.mp4"
+	const trackID = 3
+	first, err := ReadMP4File(inFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := ReadMP4File(inFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	trex, _ := first.Init.Moov.Mvex.GetTrex(trackID)
+	var firstDur uint64
+	var secondSamples []FullSample
+	nrFrags := 0
+	for _, seg := range first.Segments {
+		for _, frag := range seg.Fragments {
+			firstDur += trafDuration(frag.Moof.Traf, trex)
+			nrFrags++
+			samples, err := frag.GetFullSamples(trex)
+			if err != nil {
+				t.Fatal(err)
+			}
+			secondSamples = append(secondSamples, samples...)
+		}
+	}
+	nrSidxRefs := len(first.Sidx.SidxRefs)
+
+	out, err := ConcatenateFiles(first, second, trackID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := bytes.Buffer{}
+	if err := out.Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	dec, err := DecodeFile(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var seqNrs []uint32
+	var samples []FullSample
+	nextTime := uint64(0)
+	for _, seg := range dec.Segments {
+		for _, frag := range seg.Fragments {
+			seqNrs = append(seqNrs, frag.Moof.Mfhd.SequenceNumber)
+			if tfdt := frag.Moof.Traf.Tfdt.BaseMediaDecodeTime(); tfdt != nextTime {
+				t.Errorf("fragment %d: tfdt %d instead of %d", len(seqNrs), tfdt, nextTime)
+			}
+			nextTime += trafDuration(frag.Moof.Traf, trex)
+			if len(seqNrs) > nrFrags {
+				fs, err := frag.GetFullSamples(trex)
+				if err != nil {
+					t.Fatal(err)
+				}
+				samples = append(samples, fs...)
+			}
+		}
+	}
+	if len(seqNrs) != 2*nrFrags || seqNrs[len(seqNrs)-1] != uint32(2*nrFrags) {
+		t.Errorf("got sequence numbers %v", seqNrs)
+	}
+	if nextTime != 2*firstDur {
+		t.Errorf("got total duration %d instead of %d", nextTime, 2*firstDur)
+	}
+	for i := range secondSamples {
+		secondSamples[i].DecodeTime += firstDur
+	}
+	if diff := deep.Equal(samples, secondSamples); diff != nil {
+		t.Errorf("samples of second file differ: %v", diff)
+	}
+	if dec.Sidx == nil || len(dec.Sidx.SidxRefs) != 2*nrSidxRefs {
+		t.Errorf("sidx not merged")
+	}
+
+	// Mismatching timescale
+	first, _ = ReadMP4File(inFile)
+	second, _ = ReadMP4File(inFile)
+	second.Moov.Trak.Mdia.Mdhd.Timescale = 44100
+	if _, err := ConcatenateFiles(first, second, trackID); err == nil {
+		t.Error("expected error for timescale mismatch")
+	}
+	if _, err := ConcatenateFiles(first, first, 1); err == nil {
+		t.Error("expected error for wrong trackID")
+	}
+
+	// Time before start of second file should give error and leave second unchanged
+	second, _ = ReadMP4File(inFile)
+	firstFrag := second.Segments[0].Fragments[0]
+	firstFrag.Moof.Traf.Tfdt.SetBaseMediaDecodeTime(1 << 20)
+	seqNr := firstFrag.Moof.Mfhd.SequenceNumber
+	if _, err := ConcatenateFiles(first, second, trackID); err == nil {
+		t.Error("expected error for time before start of second file")
+	}
+	if firstFrag.Moof.Mfhd.SequenceNumber != seqNr || firstFrag.Moof.Traf.Tfdt.BaseMediaDecodeTime() != 1<<20 {
+		t.Error("second file modified despite error")
+	}
+}
+
+func TestConcatenateFilesWithChunks(t *testing.T) {
+	init := CreateEmptyInit()
+	init.AddEmptyTrack(10000, "video", "und")
+	seg := NewMediaSegment()
+	frag := createTestFragment(t, 1, 0, 3, true)
+	chunkFrag := createTestFragment(t, 2, 3000, 2, false)
+	chunk := &CMAFChunk{}
+	for _, c := range chunkFrag.Children {
+		chunk.AddChild(c)
+	}
+	frag.AddChunk(chunk)
+	seg.AddFragment(frag)
+	buf := bytes.Buffer{}
+	if err := init.Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if err := seg.Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	decode := func(data []byte) *File {
+		t.Helper()
+		f, err := DecodeFile(bytes.NewReader(data), WithDecodeFlags(DecCMAFChunks))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return f
+	}
+	first, second := decode(buf.Bytes()), decode(buf.Bytes())
+	trackID := first.Init.Moov.Trak.Tkhd.TrackID
+	out, err := ConcatenateFiles(first, second, trackID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	outBuf := bytes.Buffer{}
+	if err := out.Encode(&outBuf); err != nil {
+		t.Fatal(err)
+	}
+	dec := decode(outBuf.Bytes())
+	trex := dec.Init.Moov.Mvex.Trex
+	var seqNrs []uint32
+	nextTime := uint64(0)
+	for _, seg := range dec.Segments {
+		for _, frag := range seg.Fragments {
+			for _, moof := range fragmentMoofs(frag) {
+				seqNrs = append(seqNrs, moof.Mfhd.SequenceNumber)
+				if tfdt := moof.Traf.Tfdt.BaseMediaDecodeTime(); tfdt != nextTime {
+					t.Errorf("moof %d: tfdt %d instead of %d", len(seqNrs), tfdt, nextTime)
+				}
+				nextTime += trafDuration(moof.Traf, trex)
+			}
+		}
+	}
+	if diff := deep.Equal(seqNrs, []uint32{1, 2, 3, 4}); diff != nil {
+		t.Errorf("sequence numbers: %v", diff)
+	}
+	if nextTime != 10000 {
+		t.Errorf("got total duration %d instead of 10000", nextTime)
+	}
+}
@@ -99,7 +99,7 @@ func DecodeContainerChildren(hdr BoxHeader, startPos, endPos uint64, r io.Reader
 	children := make([]Box, 0, 8)
 	pos := startPos
 	for {
-		child, err := DecodeBox(pos, r)
+		child, err := decodeChildBox(pos, r)
 		if err == io.EOF {
 			return children, nil
 		}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 
 	"github.com/Eyevinn/mp4ff/avc"
+	"github.com/Eyevinn/mp4ff/bits"
 	"github.com/Eyevinn/mp4ff/hevc"
 )
 
@@ -295,14 +296,77 @@ type InitProtectData struct {
 	Scheme   string
 }
 
+// CryptPattern - crypt_byte_block and skip_byte_block pattern for cbcs video in units of 16-byte blocks.
+type CryptPattern struct {
+	CryptByteBlock byte
+	SkipByteBlock  byte
+}
+
+// DefaultCbcsVideoPattern is the 1:9 pattern recommended for cbcs video.
+var DefaultCbcsVideoPattern = CryptPattern{CryptByteBlock: 1, SkipByteBlock: 9}
+
 // InitProtect modifies the init segment to add protection information and return what is needed to encrypt fragments.
+// For scheme cbcs, video is encrypted with the DefaultCbcsVideoPattern.
 func InitProtect(init *InitSegment, key, iv []byte, scheme string, kid UUID, psshBoxes []*PsshBox) (*InitProtectData, error) {
-	ipd := InitProtectData{Scheme: scheme}
+	return InitProtectWithPattern(init, key, iv, scheme, kid, psshBoxes, DefaultCbcsVideoPattern)
+}
+
+// InitProtectWithPattern is like InitProtect, but with an explicit cbcs pattern for video.
+// The pattern is ignored for the cenc scheme and for audio, which is fully encrypted.
+func InitProtectWithPattern(init *InitSegment, key, iv []byte, scheme string, kid UUID, psshBoxes []*PsshBox,
+	pattern CryptPattern) (*InitProtectData, error) {
 	moov := init.Moov
 	if len(moov.Traks) != 1 {
 		return nil, fmt.Errorf("only one track supported")
 	}
-	stsd := moov.Trak.Mdia.Minf.Stbl.Stsd
+	ipd, err := protectTrak(moov.Trak, moov.Mvex.Trex, iv, scheme, kid, pattern)
+	if err != nil {
+		return nil, err
+	}
+	for _, pssh := range psshBoxes {
+		init.Moov.AddChild(pssh)
+	}
+	return ipd, nil
+}
+
+// protectTrak changes the sample entry of trak to encv or enca with a sinf box for scheme and kid,
+// and returns what is needed to encrypt the samples of the track.
+func protectTrak(trak *TrakBox, trex *TrexBox, iv []byte, scheme string, kid UUID,
+	pattern CryptPattern) (*InitProtectData, error) {
+	tp, err := prepareTrakProtection(trak, trex, iv, scheme, kid, pattern)
+	if err != nil {
+		return nil, err
+	}
+	tp.apply()
+	return tp.ipd, nil
+}
+
+// trakProtection is the protection of a track prepared by prepareTrakProtection.
+type trakProtection struct {
+	ipd      *InitProtectData
+	se       Box // Sample entry
+	sinf     *SinfBox
+	protType string // encv or enca
+}
+
+// prepareTrakProtection checks that trak can be protected with scheme and pattern, and creates
+// the sinf box and the data needed to encrypt the samples, without changing trak.
+func prepareTrakProtection(trak *TrakBox, trex *TrexBox, iv []byte, scheme string, kid UUID,
+	pattern CryptPattern) (*trakProtection, error) {
+	switch scheme {
+	case "cenc":
+	case "cbcs":
+		if pattern.CryptByteBlock > 15 || pattern.SkipByteBlock > 15 {
+			return nil, fmt.Errorf("cbcs pattern %d:%d values must be less than 16", pattern.CryptByteBlock, pattern.SkipByteBlock)
+		}
+		if pattern.CryptByteBlock == 0 && pattern.SkipByteBlock != 0 {
+			return nil, fmt.Errorf("cbcs pattern %d:%d has no encrypted blocks", pattern.CryptByteBlock, pattern.SkipByteBlock)
+		}
+	default:
+		return nil, fmt.Errorf("unknown protection scheme %s", scheme)
+	}
+	ipd := InitProtectData{Scheme: scheme}
+	stsd := trak.Mdia.Minf.Stbl.Stsd
 	if len(stsd.Children) != 1 {
 		return nil, fmt.Errorf("only one stsd child supported")
 	}
@@ -314,17 +378,16 @@ func InitProtect(init *InitSegment, key, iv []byte, scheme string, kid UUID, pss
 		copy(iv, iv8)
 	}
 	var err error
-	ipd.Trex = moov.Mvex.Trex
-	sinf := SinfBox{}
+	ipd.Trex = trex
+	tp := trakProtection{ipd: &ipd, se: stsd.Children[0], sinf: &SinfBox{}}
+	sinf := tp.sinf
 	var mediaType string
 	switch se := stsd.Children[0].(type) {
 	case *VisualSampleEntryBox:
 		mediaType = "video"
+		tp.protType = "encv"
 		veType := se.Type()
-		se.SetType("encv")
-		frma := FrmaBox{DataFormat: veType}
-		sinf.AddChild(&frma)
-		se.AddChild(&sinf)
+		sinf.AddChild(&FrmaBox{DataFormat: veType})
 		switch veType {
 		case "avc1", "avc3":
 			ipd.ProtFunc, err = getAVCProtFunc(se.AvcC)
@@ -341,11 +404,8 @@ func InitProtect(init *InitSegment, key, iv []byte, scheme string, kid UUID, pss
 		}
 	case *AudioSampleEntryBox:
 		mediaType = "audio"
-		aeType := se.Type()
-		se.SetType("enca")
-		frma := FrmaBox{DataFormat: aeType}
-		sinf.AddChild(&frma)
-		se.AddChild(&sinf)
+		tp.protType = "enca"
+		sinf.AddChild(&FrmaBox{DataFormat: se.Type()})
 		ipd.ProtFunc = getAudioProtectRanges
 	default:
 		return nil, fmt.Errorf("sample entry type %s should not be encrypted", se.Type())
@@ -358,24 +418,31 @@ func InitProtect(init *InitSegment, key, iv []byte, scheme string, kid UUID, pss
 	case "cbcs":
 		switch mediaType {
 		case "video":
-			ipd.Tenc = &TencBox{Version: 1, DefaultCryptByteBlock: 1, DefaultSkipByteBlock: 9,
-				DefaultIsProtected: 1, DefaultPerSampleIVSize: 0, DefaultKID: kid,
-				DefaultConstantIV: iv}
+			ipd.Tenc = &TencBox{Version: 1, DefaultCryptByteBlock: pattern.CryptByteBlock,
+				DefaultSkipByteBlock: pattern.SkipByteBlock, DefaultIsProtected: 1,
+				DefaultPerSampleIVSize: 0, DefaultKID: kid, DefaultConstantIV: iv}
 		case "audio":
 			ipd.Tenc = &TencBox{Version: 1, DefaultCryptByteBlock: 0, DefaultSkipByteBlock: 0,
 				DefaultIsProtected: 1, DefaultPerSampleIVSize: 0, DefaultKID: kid,
 				DefaultConstantIV: iv}
 		}
 		sinf.AddChild(&SchmBox{SchemeType: "cbcs", SchemeVersion: 65536})
-	default:
-		return nil, fmt.Errorf("unknown protection scheme %s", scheme)
 	}
 	schi.AddChild(ipd.Tenc)
 	sinf.AddChild(&schi)
-	for _, pssh := range psshBoxes {
-		init.Moov.AddChild(pssh)
+	return &tp, nil
+}
+
+// apply changes the sample entry to encv or enca and adds the sinf box.
+func (tp *trakProtection) apply() {
+	switch se := tp.se.(type) {
+	case *VisualSampleEntryBox:
+		se.SetType(tp.protType)
+		se.AddChild(tp.sinf)
+	case *AudioSampleEntryBox:
+		se.SetType(tp.protType)
+		se.AddChild(tp.sinf)
 	}
-	return &ipd, nil
 }
 
 func getAVCPSMaps(spss [][]byte, ppss [][]byte) (map[uint32]*avc.SPS, map[uint32]*avc.PPS, error) {
@@ -469,11 +536,25 @@ func EncryptFragment(f *Fragment, key, iv []byte, ipd *InitProtectData) error {
 	if len(f.Moof.Trafs) != 1 {
 		return fmt.Errorf("only one traf supported")
 	}
-	traf := f.Moof.Traf
+	if len(f.Chunks) > 0 {
+		return fmt.Errorf("fragments with CMAF chunks not supported")
+	}
+	_, err := encryptTraf(f, f.Moof.Traf, key, iv, ipd)
+	if err != nil {
+		return err
+	}
+	setSaioOffsets(f.Moof)
+	return nil
+}
+
+// encryptTraf encrypts the samples of traf in place and adds saiz, saio, and senc boxes to traf.
+// iv must be 16 bytes. The IV following the last sample is returned for the cenc scheme.
+// The saio offset must be set afterwards with setSaioOffsets, since it depends on all boxes in moof.
+func encryptTraf(f *Fragment, traf *TrafBox, key, iv []byte, ipd *InitProtectData) (nextIV []byte, err error) {
 	if len(traf.Truns) != 1 {
-		return fmt.Errorf("only one trun supported")
+		return nil, fmt.Errorf("only one trun supported")
 	}
-	nrSamples := int(f.Moof.Traf.Trun.SampleCount())
+	nrSamples := int(traf.Trun.SampleCount())
 	saiz := NewSaizBox(nrSamples)
 	_ = traf.AddChild(saiz)
 	saio := NewSaioBox()
@@ -485,25 +566,25 @@ func EncryptFragment(f *Fragment, key, iv []byte, ipd *InitProtectData) error {
 	case "cbcs":
 		senc = NewSencBox(0, nrSamples)
 	default:
-		return fmt.Errorf("unknown scheme %s", ipd.Scheme)
+		return nil, fmt.Errorf("unknown scheme %s", ipd.Scheme)
 	}
 	_ = traf.AddChild(senc)
-	fss, err := f.GetFullSamples(ipd.Trex)
+	fss, err := f.getMoofFullSamples(ipd.Trex)
 	if err != nil {
-		return fmt.Errorf("get full samples: %w", err)
+		return nil, fmt.Errorf("get full samples: %w", err)
 	}
 
 	for _, fs := range fss {
 		sample := fs.Data
 		subsamplePatterns, err := ipd.ProtFunc(sample, ipd.Scheme)
 		if err != nil {
-			return fmt.Errorf("get protect ranges: %w", err)
+			return nil, fmt.Errorf("get protect ranges: %w", err)
 		}
 		switch ipd.Scheme {
 		case "cenc":
 			err = CryptSampleCenc(sample, key, iv, subsamplePatterns)
 			if err != nil {
-				return fmt.Errorf("crypt sample cenc: %w", err)
+				return nil, fmt.Errorf("crypt sample cenc: %w", err)
 			}
 			// Store IVs in the senc box and update depending on blocks of encrypted data
 			_ = senc.AddSample(SencSample{IV: iv, SubSamples: subsamplePatterns})
@@ -512,35 +593,34 @@ func EncryptFragment(f *Fragment, key, iv []byte, ipd *InitProtectData) error {
 		case "cbcs":
 			err = EncryptSampleCbcs(sample, key, iv, subsamplePatterns, ipd.Tenc)
 			if err != nil {
-				return fmt.Errorf("crypt sample cbcs: %w", err)
+				return nil, fmt.Errorf("crypt sample cbcs: %w", err)
 			}
 			// iv is constant and not sent t senc
 			_ = senc.AddSample(SencSample{IV: nil, SubSamples: subsamplePatterns})
 			saiz.AddSampleInfo(nil, subsamplePatterns)
-		default:
-			return fmt.Errorf("unknown scheme %s", ipd.Scheme)
 		}
 	}
-	moof := f.Moof
+	return iv, nil
+}
+
+// setSaioOffsets sets the saio offset of each traf with saio and senc boxes to the start of the
+// senc sample data relative to the start of moof.
+func setSaioOffsets(moof *MoofBox) {
 	offset := uint64(8)
-	sencDataOffset := uint64(0) // Offset to the senc box data to be set in saio
 	for _, c := range moof.Children {
-		if c.Type() != "traf" {
+		traf, ok := c.(*TrafBox)
+		if !ok {
 			offset += c.Size()
 			continue
 		}
-		traf := c.(*TrafBox)
 		offset += 8
 		for _, tc := range traf.Children {
-			if tc.Type() == "senc" {
-				sencDataOffset = offset + 12 + 4 // 12 for full box and 4 for sample count
+			if tc.Type() == "senc" && traf.Saio != nil {
+				traf.Saio.Offset[0] = int64(offset + 12 + 4) // 12 for full box and 4 for sample count
 			}
 			offset += tc.Size()
 		}
-		break
 	}
-	saio.Offset[0] = int64(sencDataOffset)
-	return nil
 }
 
 type DecryptInfo struct {
@@ -553,6 +633,7 @@ type DecryptTrackInfo struct {
 	Sinf    *SinfBox
 	Trex    *TrexBox
 	Psshs   []*PsshBox
+	Sgpd    *SgpdBox // seig sample group descriptions in stbl, if any
 }
 
 func (d DecryptInfo) findTrackInfo(trackID uint32) DecryptTrackInfo {
@@ -598,9 +679,11 @@ func DecryptInit(init *InitSegment) (DecryptInfo, error) {
 			default:
 				continue
 			}
+			_, sgpd := trak.Mdia.Minf.Stbl.GetSampleGroup("seig")
 			di.TrackInfos = append(di.TrackInfos, DecryptTrackInfo{
 				TrackID: trackID,
 				Sinf:    sinf,
+				Sgpd:    sgpd,
 			})
 		}
 		if schemeType != "" && schemeType != "cenc" && schemeType != "cbcs" {
@@ -627,13 +710,83 @@ func DecryptInit(init *InitSegment) (DecryptInfo, error) {
 	return di, nil
 }
 
+// ClearEncryption converts all encv and enca sample entries back to their original format
+// given by the frma box, removes their sinf boxes, and removes pssh boxes and seig sample groups.
+// It can be used after the samples have been decrypted, e.g. with DecryptSegment after DecryptInit
+// on a copy of the init segment. Use DecryptInit instead to get the DecryptInfo at the same time.
+func (s *InitSegment) ClearEncryption() error {
+	for _, trak := range s.Moov.Traks {
+		stbl := trak.Mdia.Minf.Stbl
+		for _, child := range stbl.Stsd.Children {
+			var err error
+			switch se := child.(type) {
+			case *VisualSampleEntryBox:
+				if se.Type() == "encv" {
+					_, err = se.RemoveEncryption()
+				}
+			case *AudioSampleEntryBox:
+				if se.Type() == "enca" {
+					_, err = se.RemoveEncryption()
+				}
+			}
+			if err != nil {
+				return fmt.Errorf("track %d: %w", trak.Tkhd.TrackID, err)
+			}
+		}
+		stbl.removeSampleGroup("seig")
+	}
+	s.Moov.RemovePsshs()
+	return nil
+}
+
+// RemoveEncryptionBoxes removes senc, saiz, saio, and pssh boxes, and seig sample groups, from all
+// fragments and chunks of the segment, and updates the trun data offsets. Any sidx box inside the
+// segment is dropped, since its references are no longer valid. The samples are not decrypted.
+func (s *MediaSegment) RemoveEncryptionBoxes() {
+	for _, frag := range s.Fragments {
+		frags := []*Fragment{frag}
+		for _, chunk := range frag.Chunks {
+			frags = append(frags, chunk.asFragment())
+		}
+		for _, f := range frags {
+			var nrBytesRemoved uint64
+			for _, traf := range f.Moof.Trafs {
+				nrBytesRemoved += traf.RemoveEncryptionBoxes()
+			}
+			_, psshBytesRemoved := f.Moof.RemovePsshs()
+			f.adjustForRemovedMoofBytes(nrBytesRemoved + psshBytesRemoved)
+		}
+	}
+	if len(s.SidxsByFrag) > 0 {
+		s.Sidx = nil // drop sidx inside segment, since not modified properly
+		s.SidxsByFrag = nil
+	}
+}
+
 // DecryptSegment decrypts a media segment in place
 func DecryptSegment(seg *MediaSegment, di DecryptInfo, key []byte) error {
+	return decryptSegment(seg, di, singleKey(key))
+}
+
+// DecryptSegmentWithKeys decrypts a media segment in place using the key for the KID of each sample.
+// The KID is given by a seig sample group entry, if the sample belongs to one, and by the tenc box
+// otherwise. This is needed for content with key rotation.
+func DecryptSegmentWithKeys(seg *MediaSegment, di DecryptInfo, keys map[KID][]byte) error {
+	return decryptSegment(seg, di, keysByKID(keys))
+}
+
+func decryptSegment(seg *MediaSegment, di DecryptInfo, keyFor keyFunc) error {
 	for _, frag := range seg.Fragments {
-		err := DecryptFragment(frag, di, key)
+		err := decryptFragment(frag, di, keyFor)
 		if err != nil {
 			return err
 		}
+		for _, chunk := range frag.Chunks {
+			err := decryptFragment(chunk.asFragment(), di, keyFor)
+			if err != nil {
+				return err
+			}
+		}
 	}
 	if len(seg.SidxsByFrag) > 0 {
 		seg.Sidx = nil // drop sidx inside segment, since not modified properly
@@ -644,6 +797,16 @@ func DecryptSegment(seg *MediaSegment, di DecryptInfo, key []byte) error {
 
 // DecryptFragment decrypts a fragment in place
 func DecryptFragment(frag *Fragment, di DecryptInfo, key []byte) error {
+	return decryptFragment(frag, di, singleKey(key))
+}
+
+// DecryptFragmentWithKeys decrypts a fragment in place using the key for the KID of each sample.
+// See DecryptSegmentWithKeys.
+func DecryptFragmentWithKeys(frag *Fragment, di DecryptInfo, keys map[KID][]byte) error {
+	return decryptFragment(frag, di, keysByKID(keys))
+}
+
+func decryptFragment(frag *Fragment, di DecryptInfo, keyFor keyFunc) error {
 	moof := frag.Moof
 	var nrBytesRemoved uint64 = 0
 	for _, traf := range moof.Trafs {
@@ -653,31 +816,48 @@ func DecryptFragment(frag *Fragment, di DecryptInfo, key []byte) error {
 			if schemeType != "cenc" && schemeType != "cbcs" {
 				return fmt.Errorf("scheme type %s not supported", schemeType)
 			}
-			hasSenc, isParsed := traf.ContainsSencBox()
-			if !hasSenc {
-				return fmt.Errorf("no senc box in traf")
+			tenc := ti.Sinf.Schi.Tenc
+			tencs, err := sampleTencs(traf, tenc, ti.Sgpd)
+			if err != nil {
+				return err
 			}
-			if !isParsed {
-				defaultPerSampleIVSize := ti.Sinf.Schi.Tenc.DefaultPerSampleIVSize
-				err := traf.ParseReadSenc(defaultPerSampleIVSize, moof.StartPos)
+			perSampleIVSize, err := commonPerSampleIVSize(tencs, tenc.DefaultPerSampleIVSize)
+			if err != nil {
+				return err
+			}
+			var senc *SencBox
+			hasSenc, isParsed := traf.ContainsSencBox()
+			switch {
+			case hasSenc:
+				if !isParsed {
+					err := traf.ParseReadSenc(perSampleIVSize, moof.StartPos)
+					if err != nil {
+						return fmt.Errorf("parseReadSenc: %w", err)
+					}
+				}
+				if traf.Senc != nil {
+					senc = traf.Senc
+				} else {
+					senc = traf.UUIDSenc.Senc
+				}
+			case traf.Saiz != nil && traf.Saio != nil:
+				senc, err = frag.ReadAuxInfoSenc(traf, perSampleIVSize)
 				if err != nil {
-					return fmt.Errorf("parseReadSenc: %w", err)
+					return fmt.Errorf("read auxiliary information: %w", err)
 				}
+			default:
+				return fmt.Errorf("no senc box or saiz and saio boxes in traf")
 			}
 
-			tenc := ti.Sinf.Schi.Tenc
-			samples, err := frag.GetFullSamples(ti.Trex)
+			samples, err := frag.getMoofFullSamples(ti.Trex)
 			if err != nil {
 				return err
 			}
-			var senc *SencBox
-			if traf.Senc != nil {
-				senc = traf.Senc
-			} else {
-				senc = traf.UUIDSenc.Senc
+			if len(samples) != len(tencs) {
+				return fmt.Errorf("got %d samples but %d in truns", len(samples), len(tencs))
 			}
 
-			err = decryptSamplesInPlace(schemeType, samples, key, tenc, senc)
+			err = decryptSamplesInPlace(schemeType, samples, keyFor, tencs, senc)
 			if err != nil {
 				return err
 			}
@@ -686,41 +866,418 @@ func DecryptFragment(frag *Fragment, di DecryptInfo, key []byte) error {
 	}
 	_, psshBytesRemoved := moof.RemovePsshs()
 	nrBytesRemoved += psshBytesRemoved
-	for _, traf := range moof.Trafs {
+	frag.adjustForRemovedMoofBytes(nrBytesRemoved)
+	return nil
+}
+
+// adjustForRemovedMoofBytes updates trun data offsets and the mdat position after
+// nrBytesRemoved bytes have been removed from the moof box.
+func (f *Fragment) adjustForRemovedMoofBytes(nrBytesRemoved uint64) {
+	for _, traf := range f.Moof.Trafs {
 		for _, trun := range traf.Truns {
 			trun.DataOffset -= int32(nrBytesRemoved)
 		}
 	}
-	if frag.Mdat.StartPos > frag.Moof.StartPos {
-		frag.Mdat.StartPos -= nrBytesRemoved
+	if f.Mdat.StartPos > f.Moof.StartPos {
+		f.Mdat.StartPos -= nrBytesRemoved
 	}
+}
 
+// ReencryptFragment decrypts the protected samples of frag with oldKey and encrypts them again in place
+// with newKey, keeping the subsample patterns, so that the senc and saiz boxes stay consistent.
+// init is the encrypted init segment, which is not changed. newIVs are the new per-sample IVs
+// for all protected samples with per-sample IVs of the fragment in traf order, and must have
+// the per-sample IV size. If newIVs is nil, the existing IVs are used. Constant IVs
+// (per-sample IV size 0) are kept, so newIVs is not used for such samples.
+// Only fragments with senc boxes and without CMAF chunks are supported, and the scheme must be
+// cenc or cbcs. All checks are done before any sample is changed.
+func ReencryptFragment(frag *Fragment, init *InitSegment, oldKey, newKey []byte, newIVs [][]byte) error {
+	if len(frag.Chunks) > 0 {
+		return fmt.Errorf("fragments with CMAF chunks not supported")
+	}
+	if len(oldKey) != 16 || len(newKey) != 16 {
+		return fmt.Errorf("keys must be 16 bytes")
+	}
+	moof := frag.Moof
+	type reencTraf struct {
+		schemeType string
+		samples    []FullSample
+		tencs      []*TencBox
+		senc       *SencBox
+		ivIdxs     []int // Indices of protected samples with per-sample IVs
+	}
+	var trafs []reencTraf
+	nrIVs := 0
+	for _, traf := range moof.Trafs {
+		trackID := traf.Tfhd.TrackID
+		trak, err := findTrak(init, trackID)
+		if err != nil {
+			return err
+		}
+		sinf := encryptedSampleEntrySinf(trak)
+		if sinf == nil {
+			continue // Track in the clear
+		}
+		schemeType := sinf.Schm.SchemeType
+		if schemeType != "cenc" && schemeType != "cbcs" {
+			return fmt.Errorf("scheme type %s not supported", schemeType)
+		}
+		tenc := sinf.Schi.Tenc
+		_, trackSgpd := trak.Mdia.Minf.Stbl.GetSampleGroup("seig")
+		tencs, err := sampleTencs(traf, tenc, trackSgpd)
+		if err != nil {
+			return err
+		}
+		perSampleIVSize, err := commonPerSampleIVSize(tencs, tenc.DefaultPerSampleIVSize)
+		if err != nil {
+			return err
+		}
+		hasSenc, isParsed := traf.ContainsSencBox()
+		if !hasSenc {
+			return fmt.Errorf("track %d: only fragments with senc box supported", trackID)
+		}
+		if !isParsed {
+			if err := traf.ParseReadSenc(perSampleIVSize, moof.StartPos); err != nil {
+				return fmt.Errorf("parseReadSenc: %w", err)
+			}
+		}
+		senc := traf.Senc
+		if senc == nil {
+			senc = traf.UUIDSenc.Senc
+		}
+		trex := &TrexBox{TrackID: trackID}
+		if init.Moov.Mvex != nil {
+			if t, ok := init.Moov.Mvex.GetTrex(trackID); ok {
+				trex = t
+			}
+		}
+		samples, err := frag.getMoofFullSamples(trex)
+		if err != nil {
+			return err
+		}
+		if len(samples) != len(tencs) {
+			return fmt.Errorf("got %d samples but %d in truns", len(samples), len(tencs))
+		}
+		rt := reencTraf{schemeType: schemeType, samples: samples, tencs: tencs, senc: senc}
+		if len(senc.IVs) == len(samples) && len(samples) > 0 {
+			for i := range samples {
+				if tencs[i].DefaultIsProtected == 0 {
+					continue
+				}
+				if newIVs != nil {
+					if nrIVs >= len(newIVs) {
+						return fmt.Errorf("too few new IVs, %d", len(newIVs))
+					}
+					if len(newIVs[nrIVs]) != senc.GetPerSampleIVSize() {
+						return fmt.Errorf("new IV %d has size %d instead of %d", nrIVs+1, len(newIVs[nrIVs]),
+							senc.GetPerSampleIVSize())
+					}
+				}
+				rt.ivIdxs = append(rt.ivIdxs, i)
+				nrIVs++
+			}
+		}
+		trafs = append(trafs, rt)
+	}
+	if newIVs != nil && nrIVs > 0 && nrIVs != len(newIVs) {
+		return fmt.Errorf("%d new IVs for %d samples with per-sample IVs", len(newIVs), nrIVs)
+	}
+
+	ivNr := 0
+	for _, rt := range trafs {
+		if err := decryptSamplesInPlace(rt.schemeType, rt.samples, singleKey(oldKey), rt.tencs, rt.senc); err != nil {
+			return err
+		}
+		if newIVs != nil {
+			for _, i := range rt.ivIdxs {
+				rt.senc.IVs[i] = newIVs[ivNr]
+				ivNr++
+			}
+		}
+		if err := encryptSamplesInPlace(rt.schemeType, rt.samples, newKey, rt.tencs, rt.senc); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
-// decryptSample - decrypt samples inplace
-func decryptSamplesInPlace(schemeType string, samples []FullSample, key []byte, tenc *TencBox, senc *SencBox) error {
+// encryptSamplesInPlace - encrypt samples in place with key and the IVs and subsamples in senc.
+// Samples that are not protected according to tencs are left unchanged.
+func encryptSamplesInPlace(schemeType string, samples []FullSample, key []byte, tencs []*TencBox, senc *SencBox) error {
+	iv := make([]byte, 16)
+	for i := range samples {
+		tenc := tencs[i]
+		if tenc.DefaultIsProtected == 0 {
+			continue
+		}
+		for j := range iv {
+			iv[j] = 0
+		}
+		if len(senc.IVs) == len(samples) {
+			copy(iv, senc.IVs[i])
+		} else {
+			copy(iv, tenc.DefaultConstantIV)
+		}
+		var subSamplePatterns []SubSamplePattern
+		if len(senc.SubSamples) != 0 {
+			subSamplePatterns = senc.SubSamples[i]
+		}
+		switch schemeType {
+		case "cenc":
+			if err := CryptSampleCenc(samples[i].Data, key, iv, subSamplePatterns); err != nil {
+				return err
+			}
+		case "cbcs":
+			if err := EncryptSampleCbcs(samples[i].Data, key, iv, subSamplePatterns, tenc); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
 
-	// TODO. Interpret saio and saiz to get to the right place
-	// Saio tells where the IV starts relative to moof start
-	// It typically ends up inside senc (16 bytes after start)
+// encryptedSampleEntrySinf returns the sinf box of the first encv or enca sample entry of trak, or nil.
+func encryptedSampleEntrySinf(trak *TrakBox) *SinfBox {
+	for _, c := range trak.Mdia.Minf.Stbl.Stsd.Children {
+		switch se := c.(type) {
+		case *VisualSampleEntryBox:
+			if se.Type() == "encv" && se.Sinf != nil {
+				return se.Sinf
+			}
+		case *AudioSampleEntryBox:
+			if se.Type() == "enca" && se.Sinf != nil {
+				return se.Sinf
+			}
+		}
+	}
+	return nil
+}
 
-	iv := make([]byte, 16)
-	if tenc.DefaultConstantIV != nil {
-		copy(iv, tenc.DefaultConstantIV)
+// KID - key ID in a form that can be used as map key
+type KID [16]byte
+
+// NewKIDFromUUID returns the KID with the same 16 bytes as u.
+func NewKIDFromUUID(u UUID) (KID, error) {
+	var k KID
+	if len(u) != len(k) {
+		return k, fmt.Errorf("kid has %d bytes instead of 16", len(u))
+	}
+	copy(k[:], u)
+	return k, nil
+}
+
+// String - KID in UUID format
+func (k KID) String() string {
+	return UUID(k[:]).String()
+}
+
+// keyFunc returns the key for a KID.
+type keyFunc func(kid UUID) ([]byte, error)
+
+// singleKey returns a keyFunc that returns key for all KIDs.
+func singleKey(key []byte) keyFunc {
+	return func(kid UUID) ([]byte, error) {
+		return key, nil
+	}
+}
+
+// keysByKID returns a keyFunc that looks up keys by KID.
+func keysByKID(keys map[KID][]byte) keyFunc {
+	return func(kid UUID) ([]byte, error) {
+		k, err := NewKIDFromUUID(kid)
+		if err != nil {
+			return nil, err
+		}
+		key, ok := keys[k]
+		if !ok {
+			return nil, fmt.Errorf("no key for KID %s", k)
+		}
+		return key, nil
+	}
+}
+
+// sampleTencs returns the encryption parameters of each sample of traf in the form of tenc boxes.
+// Samples in a seig sample group get parameters from their seig entry, and other samples get tenc.
+// Group description indices above 0x10000 refer to the sgpd box in traf, and lower indices to
+// trackSgpd, which is the seig sgpd box of the sample table. If trackSgpd is nil, samples in
+// groups described in the sample table get tenc.
+func sampleTencs(traf *TrafBox, tenc *TencBox, trackSgpd *SgpdBox) ([]*TencBox, error) {
+	nrSamples := 0
+	for _, trun := range traf.Truns {
+		nrSamples += int(trun.SampleCount())
+	}
+	tencs := make([]*TencBox, nrSamples)
+	for i := range tencs {
+		tencs[i] = tenc
+	}
+	sbgp, sgpd := traf.GetSampleGroup("seig")
+	if sbgp == nil {
+		return tencs, nil
+	}
+	if len(sbgp.SampleCounts) != len(sbgp.GroupDescriptionIndices) {
+		return nil, fmt.Errorf("seig sbgp has %d sample counts and %d indices",
+			len(sbgp.SampleCounts), len(sbgp.GroupDescriptionIndices))
+	}
+	sampleNr := 0
+	for i, count := range sbgp.SampleCounts {
+		entryTenc := tenc
+		if index := sbgp.GroupDescriptionIndices[i]; index != 0 {
+			groupSgpd := trackSgpd
+			if index > sbgpInsideOffset {
+				groupSgpd = sgpd
+				index -= sbgpInsideOffset
+			} else if trackSgpd == nil {
+				sampleNr += int(count)
+				continue
+			}
+			if groupSgpd == nil || int(index) > len(groupSgpd.SampleGroupEntries) {
+				return nil, fmt.Errorf("seig sample group description %d not found", sbgp.GroupDescriptionIndices[i])
+			}
+			seig, ok := groupSgpd.SampleGroupEntries[index-1].(*SeigSampleGroupEntry)
+			if !ok {
+				return nil, fmt.Errorf("sample group entry %d is not seig", sbgp.GroupDescriptionIndices[i])
+			}
+			entryTenc = &TencBox{
+				DefaultCryptByteBlock:  seig.CryptByteBlock,
+				DefaultSkipByteBlock:   seig.SkipByteBlock,
+				DefaultIsProtected:     seig.IsProtected,
+				DefaultPerSampleIVSize: seig.PerSampleIVSize,
+				DefaultKID:             seig.KID,
+				DefaultConstantIV:      seig.ConstantIV,
+			}
+		}
+		for j := uint32(0); j < count && sampleNr < nrSamples; j++ {
+			tencs[sampleNr] = entryTenc
+			sampleNr++
+		}
 	}
+	return tencs, nil
+}
+
+// commonPerSampleIVSize returns the per-sample IV size of the protected samples in tencs,
+// which must be the same for all of them. Unprotected samples, as in clear lead, are ignored.
+// defaultIVSize is returned if there are no samples, and 0 if no sample is protected.
+func commonPerSampleIVSize(tencs []*TencBox, defaultIVSize byte) (byte, error) {
+	if len(tencs) == 0 {
+		return defaultIVSize, nil
+	}
+	var ivSize byte
+	found := false
+	for _, tenc := range tencs {
+		if tenc.DefaultIsProtected == 0 {
+			continue
+		}
+		if found && tenc.DefaultPerSampleIVSize != ivSize {
+			return 0, fmt.Errorf("varying per-sample IV sizes in traf not supported")
+		}
+		ivSize = tenc.DefaultPerSampleIVSize
+		found = true
+	}
+	return ivSize, nil
+}
 
+// ReadAuxInfoSenc reads the CENC sample auxiliary information of traf from the mdat box and
+// returns it as a parsed SencBox. The offsets are given by the saio box relative to the traf
+// base offset, and the sizes by the saiz box. This is needed for files without senc box.
+// The saio box must have one offset, or one offset per trun.
+func (f *Fragment) ReadAuxInfoSenc(traf *TrafBox, perSampleIVSize byte) (*SencBox, error) {
+	saiz, saio := traf.Saiz, traf.Saio
+	if saiz == nil || saio == nil {
+		return nil, fmt.Errorf("no saiz or saio box")
+	}
+	if f.Mdat == nil || f.Mdat.IsLazy() {
+		return nil, fmt.Errorf("no mdat data available")
+	}
+	nrSamples := int(saiz.SampleCount)
+	nrTrunSamples := 0
+	for _, trun := range traf.Truns {
+		nrTrunSamples += int(trun.SampleCount())
+	}
+	if nrSamples != nrTrunSamples {
+		return nil, fmt.Errorf("saiz sample count %d differs from %d samples in truns", nrSamples, nrTrunSamples)
+	}
+	// chunkStarts lists the first sample number of each saio offset
+	chunkStarts := []int{0}
+	switch len(saio.Offset) {
+	case 1:
+	case len(traf.Truns):
+		nr := 0
+		for _, trun := range traf.Truns[:len(traf.Truns)-1] {
+			nr += int(trun.SampleCount())
+			chunkStarts = append(chunkStarts, nr)
+		}
+	default:
+		return nil, fmt.Errorf("saio has %d offsets for %d truns", len(saio.Offset), len(traf.Truns))
+	}
+	baseOffset := int64(f.trafBaseOffset(traf))
+	mdatPayloadStart := int64(f.Mdat.PayloadAbsoluteOffset())
+	mdatData := f.Mdat.Data
+	senc := &SencBox{
+		SampleCount:     saiz.SampleCount,
+		perSampleIVSize: perSampleIVSize,
+	}
+	subSamples := make([][]SubSamplePattern, nrSamples)
+	var pos int64
+	chunkNr := 0
+	for i := 0; i < nrSamples; i++ {
+		if chunkNr < len(chunkStarts) && i == chunkStarts[chunkNr] {
+			pos = baseOffset + saio.Offset[chunkNr] - mdatPayloadStart
+			chunkNr++
+		}
+		size := int64(saiz.DefaultSampleInfoSize)
+		if size == 0 {
+			if i >= len(saiz.SampleInfo) {
+				return nil, fmt.Errorf("no saiz size for sample %d", i+1)
+			}
+			size = int64(saiz.SampleInfo[i])
+		}
+		if pos < 0 || pos+size > int64(len(mdatData)) {
+			return nil, fmt.Errorf("auxiliary information of sample %d outside mdat", i+1)
+		}
+		sr := bits.NewFixedSliceReader(mdatData[pos : pos+size])
+		if perSampleIVSize > 0 {
+			senc.IVs = append(senc.IVs, sr.ReadBytes(int(perSampleIVSize)))
+		}
+		if sr.NrRemainingBytes() > 0 {
+			subsampleCount := int(sr.ReadUint16())
+			subSamples[i] = make([]SubSamplePattern, subsampleCount)
+			for j := 0; j < subsampleCount; j++ {
+				subSamples[i][j].BytesOfClearData = sr.ReadUint16()
+				subSamples[i][j].BytesOfProtectedData = sr.ReadUint32()
+			}
+			senc.Flags |= UseSubSampleEncryption
+		}
+		if err := sr.AccError(); err != nil || sr.NrRemainingBytes() != 0 {
+			return nil, fmt.Errorf("auxiliary information of sample %d does not match size %d", i+1, size)
+		}
+		pos += size
+	}
+	if senc.Flags&UseSubSampleEncryption != 0 {
+		senc.SubSamples = subSamples
+	}
+	return senc, nil
+}
+
+// decryptSamplesInPlace - decrypt samples inplace with the parameters in tencs, one per sample.
+// Samples that are not protected are left unchanged.
+func decryptSamplesInPlace(schemeType string, samples []FullSample, keyFor keyFunc, tencs []*TencBox, senc *SencBox) error {
+	iv := make([]byte, 16)
 	for i := range samples {
+		tenc := tencs[i]
+		if tenc.DefaultIsProtected == 0 {
+			continue
+		}
+		key, err := keyFor(tenc.DefaultKID)
+		if err != nil {
+			return fmt.Errorf("sample %d: %w", i+1, err)
+		}
+		for j := range iv {
+			iv[j] = 0
+		}
 		if len(senc.IVs) == len(samples) {
-			if len(senc.IVs[i]) < 16 {
-				for i := 0; i < 16; i++ {
-					iv[i] = 0
-				}
-			}
 			copy(iv, senc.IVs[i])
-		}
-		if len(iv) == 0 {
-			return fmt.Errorf("iv has length 0")
+		} else {
+			copy(iv, tenc.DefaultConstantIV)
 		}
 
 		var subSamplePatterns []SubSamplePattern
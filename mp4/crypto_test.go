@@ -276,3 +276,421 @@ func TestDecryptInit(t *testing.T) {
 		}
 	}
 }
+
+func TestEncryptDecryptCbcsPattern(t *testing.T) {
+	key, _ := hex.DecodeString("00112233445566778899aabbccddeeff")
+	iv, _ := hex.DecodeString("ffeeddccbbaa99887766554433221100")
+	kidUUID, _ := NewUUIDFromString("11112222333344445555666677778888")
+	init, err := ReadMP4File("testdata/init.mp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = InitProtectWithPattern(init.Init, key, iv, "cbcs", kidUUID, nil, CryptPattern{0, 9})
+	if err == nil {
+		t.Error("expected error for pattern without encrypted blocks")
+	}
+	pattern := CryptPattern{CryptByteBlock: 2, SkipByteBlock: 8}
+	ipd, err := InitProtectWithPattern(init.Init, key, iv, "cbcs", kidUUID, nil, pattern)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ipd.Tenc.DefaultCryptByteBlock != 2 || ipd.Tenc.DefaultSkipByteBlock != 8 {
+		t.Errorf("got pattern %d:%d", ipd.Tenc.DefaultCryptByteBlock, ipd.Tenc.DefaultSkipByteBlock)
+	}
+	seg, err := ReadMP4File("testdata/1.m4s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	frag := seg.Segments[0].Fragments[0]
+	origSamples, err := frag.GetFullSamples(ipd.Trex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	origData := make([][]byte, len(origSamples))
+	for i := range origSamples {
+		origData[i] = append([]byte(nil), origSamples[i].Data...)
+	}
+	err = EncryptFragment(frag, key, iv, ipd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	di, err := DecryptInit(init.Init)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = DecryptFragment(frag, di, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decSamples, err := frag.GetFullSamples(ipd.Trex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range decSamples {
+		if !bytes.Equal(decSamples[i].Data, origData[i]) {
+			t.Errorf("sample %d differs after encryption and decryption", i+1)
+		}
+	}
+}
+
+func TestDecryptWithAuxInfoInMdat(t *testing.T) {
+	key, _ := hex.DecodeString("00112233445566778899aabbccddeeff")
+	iv, _ := hex.DecodeString("0011223344556677")
+	kidUUID, _ := NewUUIDFromString("11112222333344445555666677778888")
+	init, err := ReadMP4File("testdata/init.mp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ipd, err := InitProtect(init.Init, key, iv, "cenc", kidUUID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seg, err := ReadMP4File("testdata/1.m4s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	frag := seg.Segments[0].Fragments[0]
+	origSamples, err := frag.GetFullSamples(ipd.Trex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	origData := make([][]byte, len(origSamples))
+	for i := range origSamples {
+		origData[i] = append([]byte(nil), origSamples[i].Data...)
+	}
+	err = EncryptFragment(frag, key, iv, ipd)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Move the auxiliary information from senc to the end of mdat
+	traf := frag.Moof.Traf
+	senc := traf.Senc
+	sw := bits.NewFixedSliceWriter(int(senc.Size()))
+	err = senc.EncodeSW(sw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	auxInfo := sw.Bytes()[16:] // skip header, version, flags, and sample count
+	children := traf.Children[:0]
+	for _, c := range traf.Children {
+		if c != senc {
+			children = append(children, c)
+		}
+	}
+	traf.Children = children
+	traf.Senc = nil
+	mdatDataLen := len(frag.Mdat.Data)
+	frag.Mdat.Data = append(frag.Mdat.Data, auxInfo...)
+	traf.Saio.Offset[0] = int64(frag.Moof.Size() + frag.Mdat.HeaderSize() + uint64(mdatDataLen))
+	buf := bytes.Buffer{}
+	err = frag.Encode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decFile, err := DecodeFile(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decFrag := decFile.Segments[0].Fragments[0]
+	if hasSenc, _ := decFrag.Moof.Traf.ContainsSencBox(); hasSenc {
+		t.Fatal("senc box should have been removed")
+	}
+	di, err := DecryptInit(init.Init)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = DecryptFragment(decFrag, di, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decSamples, err := decFrag.GetFullSamples(ipd.Trex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(decSamples) != len(origData) {
+		t.Fatalf("got %d samples instead of %d", len(decSamples), len(origData))
+	}
+	for i := range decSamples {
+		if !bytes.Equal(decSamples[i].Data, origData[i]) {
+			t.Errorf("sample %d differs after encryption and decryption", i+1)
+		}
+	}
+}
+
+func TestDecryptWithKeyRotation(t *testing.T) {
+	key1, _ := hex.DecodeString("00112233445566778899aabbccddeeff")
+	key2, _ := hex.DecodeString("ffeeddccbbaa99887766554433221100")
+	iv, _ := hex.DecodeString("0011223344556677")
+	kid1, _ := NewUUIDFromString("11112222333344445555666677778888")
+	kid2, _ := NewUUIDFromString("88887777666655554444333322221111")
+	init, err := ReadMP4File("testdata/init.mp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ipd, err := InitProtect(init.Init, key1, iv, "cenc", kid1, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seg, err := ReadMP4File("testdata/1.m4s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	frag := seg.Segments[0].Fragments[0]
+	origSamples, err := frag.GetFullSamples(ipd.Trex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	origData := make([][]byte, len(origSamples))
+	for i := range origSamples {
+		origData[i] = append([]byte(nil), origSamples[i].Data...)
+	}
+
+	// The first half of the samples use kid1 and the second half kid2
+	nrSamples := uint32(len(origSamples))
+	half := nrSamples / 2
+	traf := frag.Moof.Traf
+	seig1 := &SeigSampleGroupEntry{IsProtected: 1, PerSampleIVSize: 16, KID: kid1}
+	seig2 := &SeigSampleGroupEntry{IsProtected: 1, PerSampleIVSize: 16, KID: kid2}
+	_ = traf.AddChild(&SbgpBox{
+		GroupingType:            "seig",
+		SampleCounts:            []uint32{half, nrSamples - half},
+		GroupDescriptionIndices: []uint32{sbgpInsideOffset + 1, sbgpInsideOffset + 2},
+	})
+	_ = traf.AddChild(&SgpdBox{
+		Version:            1,
+		GroupingType:       "seig",
+		DefaultLength:      uint32(seig1.Size()),
+		SampleGroupEntries: []SampleGroupEntry{seig1, seig2},
+	})
+	err = EncryptFragment(frag, key1, iv, ipd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	samples, err := frag.GetFullSamples(ipd.Trex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	senc := traf.Senc
+	for i := half; i < nrSamples; i++ {
+		for _, key := range [][]byte{key1, key2} {
+			err = CryptSampleCenc(samples[i].Data, key, senc.IVs[i], senc.SubSamples[i])
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	buf := bytes.Buffer{}
+	err = frag.Encode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decode := func() *Fragment {
+		t.Helper()
+		decFile, err := DecodeFile(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return decFile.Segments[0].Fragments[0]
+	}
+	di, err := DecryptInit(init.Init)
+	if err != nil {
+		t.Fatal(err)
+	}
+	k1, _ := NewKIDFromUUID(kid1)
+	k2, _ := NewKIDFromUUID(kid2)
+	err = DecryptFragmentWithKeys(decode(), di, map[KID][]byte{k1: key1})
+	if err == nil {
+		t.Error("no error for missing key")
+	}
+	decFrag := decode()
+	err = DecryptFragmentWithKeys(decFrag, di, map[KID][]byte{k1: key1, k2: key2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sbgp, sgpd := decFrag.Moof.Traf.GetSampleGroup("seig"); sbgp != nil || sgpd != nil {
+		t.Error("seig sample group not removed")
+	}
+	decSamples, err := decFrag.GetFullSamples(ipd.Trex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(decSamples) != len(origData) {
+		t.Fatalf("got %d samples instead of %d", len(decSamples), len(origData))
+	}
+	for i := range decSamples {
+		if !bytes.Equal(decSamples[i].Data, origData[i]) {
+			t.Errorf("sample %d differs after encryption and decryption", i+1)
+		}
+	}
+}
+
+func TestReencryptFragment(t *testing.T) {
+	key, _ := hex.DecodeString("00112233445566778899aabbccddeeff")
+	newKey, _ := hex.DecodeString("ffeeddccbbaa99887766554433221100")
+	iv, _ := hex.DecodeString("7766554433221100")
+	kidUUID, _ := NewUUIDFromString("11112222333344445555666677778888")
+	for _, scheme := range []string{"cenc", "cbcs"} {
+		t.Run(scheme, func(t *testing.T) {
+			init, err := ReadMP4File("testdata/init.mp4")
+			if err != nil {
+				t.Fatal(err)
+			}
+			ipd, err := InitProtect(init.Init, key, iv, scheme, kidUUID, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rawSeg, err := os.ReadFile("testdata/1.m4s")
+			if err != nil {
+				t.Fatal(err)
+			}
+			seg, err := DecodeFile(bytes.NewBuffer(rawSeg))
+			if err != nil {
+				t.Fatal(err)
+			}
+			frag := seg.Segments[0].Fragments[0]
+			if err = EncryptFragment(frag, key, iv, ipd); err != nil {
+				t.Fatal(err)
+			}
+			encBuf := bytes.Buffer{}
+			if err = seg.Encode(&encBuf); err != nil {
+				t.Fatal(err)
+			}
+
+			encSeg, err := DecodeFileSR(bits.NewFixedSliceReader(encBuf.Bytes()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			frag = encSeg.Segments[0].Fragments[0]
+			// New IVs are not used for cbcs with constant IV
+			var newIVs [][]byte
+			for i := 0; i < int(frag.Moof.Traf.Trun.SampleCount()); i++ {
+				newIVs = append(newIVs, []byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1, byte(i)})
+			}
+			if scheme == "cenc" {
+				// Errors must leave the fragment unchanged
+				for _, badIVs := range [][][]byte{newIVs[:len(newIVs)-1], append(newIVs, newIVs[0]),
+					append([][]byte{{1, 2}}, newIVs[1:]...)} {
+					if err = ReencryptFragment(frag, init.Init, key, newKey, badIVs); err == nil {
+						t.Error("no error for bad new IVs")
+					}
+				}
+				unchangedBuf := bytes.Buffer{}
+				if err = encSeg.Encode(&unchangedBuf); err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(unchangedBuf.Bytes(), encBuf.Bytes()) {
+					t.Error("fragment changed by failed re-encryption")
+				}
+			}
+			chunkedFrag := &Fragment{Moof: frag.Moof, Mdat: frag.Mdat, Chunks: []*CMAFChunk{{}}}
+			if err = ReencryptFragment(chunkedFrag, init.Init, key, newKey, newIVs); err == nil {
+				t.Error("no error for fragment with CMAF chunks")
+			}
+			if err = ReencryptFragment(frag, init.Init, key, newKey, newIVs); err != nil {
+				t.Fatal(err)
+			}
+			reencBuf := bytes.Buffer{}
+			if err = encSeg.Encode(&reencBuf); err != nil {
+				t.Fatal(err)
+			}
+			if reencBuf.Len() != encBuf.Len() {
+				t.Errorf("re-encrypted size %d differs from encrypted size %d", reencBuf.Len(), encBuf.Len())
+			}
+
+			decInfo, err := DecryptInit(init.Init)
+			if err != nil {
+				t.Fatal(err)
+			}
+			decSeg, err := DecodeFileSR(bits.NewFixedSliceReader(reencBuf.Bytes()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if scheme == "cenc" {
+				if ivs := decSeg.Segments[0].Fragments[0].Moof.Traf.Senc.IVs; !bytes.Equal(ivs[1], newIVs[1]) {
+					t.Errorf("got IV %x instead of %x", ivs[1], newIVs[1])
+				}
+			}
+			if err = DecryptSegment(decSeg.Segments[0], decInfo, newKey); err != nil {
+				t.Fatal(err)
+			}
+			decBuf := bytes.Buffer{}
+			if err = decSeg.Encode(&decBuf); err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(rawSeg, decBuf.Bytes()) {
+				t.Errorf("segment not equal after re-encryption and decryption")
+			}
+		})
+	}
+}
+
+func TestClearEncryption(t *testing.T) {
+	key, _ := hex.DecodeString("00112233445566778899aabbccddeeff")
+	iv, _ := hex.DecodeString("7766554433221100")
+	kid, _ := NewUUIDFromString("11112222333344445555666677778888")
+	rawInit, err := os.ReadFile("testdata/init.mp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rawSeg, err := os.ReadFile("testdata/1.m4s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	initFile, err := DecodeFile(bytes.NewBuffer(rawInit))
+	if err != nil {
+		t.Fatal(err)
+	}
+	init := initFile.Init
+	pssh := &PsshBox{SystemID: kid}
+	ipd, err := InitProtect(init, key, iv, "cenc", kid, []*PsshBox{pssh})
+	if err != nil {
+		t.Fatal(err)
+	}
+	segFile, err := DecodeFile(bytes.NewBuffer(rawSeg))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range segFile.Segments[0].Fragments {
+		if err := EncryptFragment(f, key, iv, ipd); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var encSeg bytes.Buffer
+	if err := segFile.Encode(&encSeg); err != nil {
+		t.Fatal(err)
+	}
+	encFile, err := DecodeFile(&encSeg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seg := encFile.Segments[0]
+
+	if err := init.ClearEncryption(); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := init.Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), rawInit) {
+		t.Error("init segment differs from original after ClearEncryption")
+	}
+
+	wantedFile, err := DecodeFile(bytes.NewBuffer(rawSeg))
+	if err != nil {
+		t.Fatal(err)
+	}
+	seg.RemoveEncryptionBoxes()
+	for i, f := range seg.Fragments {
+		if diff := DiffBoxes(wantedFile.Segments[0].Fragments[i].Moof, f.Moof); diff != nil {
+			t.Errorf("fragment %d: %v", i, diff)
+		}
+		if f.Mdat.StartPos != wantedFile.Segments[0].Fragments[i].Mdat.StartPos {
+			t.Errorf("fragment %d: mdat start %d instead of %d", i, f.Mdat.StartPos,
+				wantedFile.Segments[0].Fragments[i].Mdat.StartPos)
+		}
+	}
+}
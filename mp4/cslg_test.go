@@ -25,3 +25,32 @@ func TestCslgEncodeDecode(t *testing.T) {
 
 	boxDiffAfterEncodeAndDecode(t, &cslg)
 }
+
+func TestComputeCslg(t *testing.T) {
+	stbl := NewStblBox()
+	stbl.AddChild(&SttsBox{SampleCount: []uint32{4}, SampleTimeDelta: []uint32{10}})
+	ctts := &CttsBox{Version: 1}
+	if err := ctts.AddSampleCountsAndOffset([]uint32{1, 1, 2}, []int32{0, 20, -10}); err != nil {
+		t.Fatal(err)
+	}
+	stbl.AddChild(ctts)
+	cslg, err := stbl.ComputeCslg()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Composition times are 0, 30, 10, 20
+	want := CslgBox{
+		CompositionToDTSShift:        10,
+		LeastDecodeToDisplayDelta:    -10,
+		GreatestDecodeToDisplayDelta: 20,
+		CompositionStartTime:         0,
+		CompositionEndTime:           40,
+	}
+	if *cslg != want {
+		t.Errorf("got %+v instead of %+v", *cslg, want)
+	}
+	stbl.Stts.SampleCount[0] = 5
+	if _, err = stbl.ComputeCslg(); err == nil {
+		t.Error("no error for ctts with fewer samples than stts")
+	}
+}
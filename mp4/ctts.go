@@ -88,6 +88,14 @@ func (b *CttsBox) EncodeSW(sw bits.SliceWriter) error {
 	if err != nil {
 		return err
 	}
+	if b.Version == 0 {
+		for _, offset := range b.SampleOffset {
+			if offset < 0 {
+				b.Version = 1 // Version 0 has unsigned sample offsets
+				break
+			}
+		}
+	}
 	versionAndFlags := (uint32(b.Version) << 24) + b.Flags
 	sw.WriteUint32(versionAndFlags)
 	sw.WriteUint32(uint32(len(b.SampleOffset)))
@@ -111,10 +119,16 @@ func (b *CttsBox) SampleCount(i int) uint32 {
 }
 
 // AddSampleCountsAndOffsets - populate this box with data. Need the same number of entries in both
+// Version is set to 1 if any offset is negative.
 func (b *CttsBox) AddSampleCountsAndOffset(counts []uint32, offsets []int32) error {
 	if len(counts) != len(offsets) {
 		return fmt.Errorf("not same number of sampleCounts %d and sampleOffsets %d", len(counts), len(offsets))
 	}
+	for _, offset := range offsets {
+		if offset < 0 {
+			b.Version = 1
+		}
+	}
 	b.SampleOffset = append(b.SampleOffset, offsets...)
 	if len(b.EndSampleNr) == 0 {
 		b.EndSampleNr = append(b.EndSampleNr, 0)
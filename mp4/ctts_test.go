@@ -1,6 +1,9 @@
 package mp4
 
-import "testing"
+import (
+	"bytes"
+	"testing"
+)
 
 func TestCtts(t *testing.T) {
 	ctts := &CttsBox{
@@ -12,7 +15,32 @@ func TestCtts(t *testing.T) {
 		t.Error(err)
 	}
 
+	if ctts.Version != 1 {
+		t.Errorf("version %d instead of 1 for negative offsets", ctts.Version)
+	}
 	boxDiffAfterEncodeAndDecode(t, ctts)
+
+	// Negative offsets set directly in a version 0 box must be written as version 1
+	ctts = &CttsBox{}
+	err = ctts.AddSampleCountsAndOffset([]uint32{2}, []int32{1000})
+	if err != nil {
+		t.Error(err)
+	}
+	ctts.SampleOffset[0] = -1000
+	buf := bytes.Buffer{}
+	err = ctts.Encode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	box, err := DecodeBox(0, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decCtts := box.(*CttsBox)
+	if decCtts.Version != 1 || decCtts.GetCompositionTimeOffset(2) != -1000 {
+		t.Errorf("got version %d offset %d instead of version 1 offset -1000", decCtts.Version,
+			decCtts.GetCompositionTimeOffset(2))
+	}
 }
 
 func TestGetCompositionTimeOffset(t *testing.T) {
@@ -0,0 +1,224 @@
+package mp4
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/Eyevinn/mp4ff/bits"
+)
+
+// AC4SampleRates - base sample rates signaled by fs_index in ETSI TS 103 190-2 E.6.4
+var AC4SampleRates = []int{44100, 48000}
+
+// AC4FrameRates - frame rates signaled by frame_rate_index for 48kHz in ETSI TS 103 190-1 Table 83
+var AC4FrameRates = []string{
+	"23.976", "24", "25", "29.97", "30", "47.95", "48", "50", "59.94", "60", "100", "119.88", "120", "23.4375",
+}
+
+// AC4ChModeChannels - number of channels for presentation channel modes in ETSI TS 103 190-2 Table 78
+var AC4ChModeChannels = []int{1, 2, 3, 5, 6, 7, 8, 7, 8, 7, 8, 11, 12, 13, 14, 24}
+
+// Dac4Box - AC4SpecificBox from ETSI TS 103 190-2 V1.2.1 E.5 (2018)
+// The ac4_dsi is kept in DSI and written unchanged by Encode.
+// Header, bitrate and the start of each presentation (ac4_dsi_v1) are parsed into the other fields.
+type Dac4Box struct {
+	DSIVersion       byte
+	BitstreamVersion byte
+	FSIndex          byte
+	FrameRateIndex   byte
+	NPresentations   uint16
+	BitRateMode      byte
+	BitRate          uint32
+	BitRatePrecision uint32
+	Presentations    []AC4Presentation
+	DSI              []byte
+}
+
+// AC4Presentation - start of ac4_presentation_v0_dsi or ac4_presentation_v1_dsi.
+// The fields after Config are only parsed for presentation version 1 and 2.
+type AC4Presentation struct {
+	Version        byte
+	Size           uint32 // pres_bytes
+	Config         byte
+	MDCompat       byte
+	PresentationID int // -1 if not present
+	ChannelCoded   bool
+	ChMode         byte
+	ChannelMask    uint32
+}
+
+// DecodeDac4 - box-specific decode
+func DecodeDac4(hdr BoxHeader, startPos uint64, r io.Reader) (Box, error) {
+	data, err := readBoxBody(r, hdr)
+	if err != nil {
+		return nil, err
+	}
+	return decodeDac4FromData(data)
+}
+
+// DecodeDac4SR - box-specific decode
+func DecodeDac4SR(hdr BoxHeader, startPos uint64, sr bits.SliceReader) (Box, error) {
+	data := sr.ReadBytes(hdr.payloadLen())
+	if sr.AccError() != nil {
+		return nil, sr.AccError()
+	}
+	return decodeDac4FromData(data)
+}
+
+func decodeDac4FromData(data []byte) (Box, error) {
+	b := Dac4Box{DSI: data}
+	br := bits.NewReader(bytes.NewBuffer(data))
+	b.DSIVersion = byte(br.Read(3))
+	b.BitstreamVersion = byte(br.Read(7))
+	b.FSIndex = byte(br.Read(1))
+	b.FrameRateIndex = byte(br.Read(4))
+	b.NPresentations = uint16(br.Read(9))
+	if br.AccError() != nil {
+		return nil, fmt.Errorf("dac4: %w", br.AccError())
+	}
+	if b.DSIVersion != 1 {
+		return &b, nil // Only ac4_dsi_v1 is parsed further
+	}
+	if b.BitstreamVersion > 1 {
+		bProgramID := br.ReadFlag()
+		if bProgramID {
+			_ = br.Read(16) // short_program_id
+			bUUID := br.ReadFlag()
+			if bUUID {
+				for i := 0; i < 16; i++ {
+					_ = br.Read(8) // program_uuid
+				}
+			}
+		}
+	}
+	b.BitRateMode = byte(br.Read(2))
+	b.BitRate = uint32(br.Read(32))
+	b.BitRatePrecision = uint32(br.Read(32))
+	if br.AccError() != nil {
+		return nil, fmt.Errorf("dac4: %w", br.AccError())
+	}
+	pos := (br.NrBitsRead() + 7) / 8 // byte_align
+	for i := 0; i < int(b.NPresentations); i++ {
+		if pos+2 > len(data) {
+			return nil, fmt.Errorf("dac4: presentation %d outside data", i)
+		}
+		p := AC4Presentation{Version: data[pos], Size: uint32(data[pos+1]), PresentationID: -1}
+		pos += 2
+		if p.Size == 255 {
+			if pos+2 > len(data) {
+				return nil, fmt.Errorf("dac4: presentation %d outside data", i)
+			}
+			p.Size += uint32(data[pos])<<8 | uint32(data[pos+1]) // add_pres_bytes
+			pos += 2
+		}
+		if pos+int(p.Size) > len(data) {
+			return nil, fmt.Errorf("dac4: presentation %d size %d outside data", i, p.Size)
+		}
+		p.parse(data[pos : pos+int(p.Size)])
+		b.Presentations = append(b.Presentations, p)
+		pos += int(p.Size)
+	}
+	return &b, nil
+}
+
+// parse - parse the start of the presentation data. Missing bits are read as zero.
+func (p *AC4Presentation) parse(data []byte) {
+	br := bits.NewReader(bytes.NewBuffer(data))
+	p.Config = byte(br.Read(5))
+	if p.Version == 0 || p.Config == 0x06 {
+		return
+	}
+	p.MDCompat = byte(br.Read(3))
+	if br.ReadFlag() {
+		p.PresentationID = int(br.Read(5))
+	}
+	_ = br.Read(2)  // dsi_frame_rate_multiply_info
+	_ = br.Read(2)  // dsi_frame_rate_fraction_info
+	_ = br.Read(5)  // presentation_emdf_version
+	_ = br.Read(10) // presentation_key_id
+	p.ChannelCoded = br.ReadFlag()
+	if p.ChannelCoded {
+		p.ChMode = byte(br.Read(5))
+		if p.ChMode >= 11 && p.ChMode <= 14 {
+			_ = br.Read(1) // pres_b_4_back_channels_present
+			_ = br.Read(2) // pres_top_channel_pairs
+		}
+		p.ChannelMask = uint32(br.Read(24))
+	}
+}
+
+// NrChannels - number of channels given by ChMode, or 0 if not channel coded
+func (p *AC4Presentation) NrChannels() int {
+	if !p.ChannelCoded || int(p.ChMode) >= len(AC4ChModeChannels) {
+		return 0
+	}
+	return AC4ChModeChannels[p.ChMode]
+}
+
+// Type - box type
+func (b *Dac4Box) Type() string {
+	return "dac4"
+}
+
+// Size - calculated size of box
+func (b *Dac4Box) Size() uint64 {
+	return uint64(boxHeaderSize + len(b.DSI))
+}
+
+// Encode - write box to w
+func (b *Dac4Box) Encode(w io.Writer) error {
+	sw := bits.NewFixedSliceWriter(int(b.Size()))
+	err := b.EncodeSW(sw)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(sw.Bytes())
+	return err
+}
+
+// EncodeSW - write box to sw
+func (b *Dac4Box) EncodeSW(sw bits.SliceWriter) error {
+	err := EncodeHeaderSW(b, sw)
+	if err != nil {
+		return err
+	}
+	sw.WriteBytes(b.DSI)
+	return sw.AccError()
+}
+
+// SamplingFrequency - base sampling frequency given by fs_index
+func (b *Dac4Box) SamplingFrequency() int {
+	return AC4SampleRates[b.FSIndex]
+}
+
+// Info - write box-specific information
+func (b *Dac4Box) Info(w io.Writer, specificBoxLevels, indent, indentStep string) error {
+	bd := newInfoDumper(w, indent, b, -1, 0)
+	bd.write(" - dsiVersion=%d bitstreamVersion=%d", b.DSIVersion, b.BitstreamVersion)
+	bd.write(" - fsIndex=%d => sampleRate=%d", b.FSIndex, b.SamplingFrequency())
+	if b.FSIndex == 1 && int(b.FrameRateIndex) < len(AC4FrameRates) {
+		bd.write(" - frameRateIndex=%d => frameRate=%s", b.FrameRateIndex, AC4FrameRates[b.FrameRateIndex])
+	} else {
+		bd.write(" - frameRateIndex=%d", b.FrameRateIndex)
+	}
+	bd.write(" - nPresentations=%d", b.NPresentations)
+	if b.DSIVersion != 1 {
+		return bd.err
+	}
+	bd.write(" - bitRateMode=%d bitRate=%d bitRatePrecision=%d", b.BitRateMode, b.BitRate, b.BitRatePrecision)
+	for i, p := range b.Presentations {
+		msg := fmt.Sprintf(" - presentation %d: version=%d size=%d config=%d", i+1, p.Version, p.Size, p.Config)
+		if p.Version > 0 && p.Config != 0x06 {
+			msg += fmt.Sprintf(" mdcompat=%d", p.MDCompat)
+			if p.PresentationID >= 0 {
+				msg += fmt.Sprintf(" id=%d", p.PresentationID)
+			}
+			if p.ChannelCoded {
+				msg += fmt.Sprintf(" chMode=%d => nrChannels=%d channelMask=%06x", p.ChMode, p.NrChannels(), p.ChannelMask)
+			}
+		}
+		bd.write("%s", msg)
+	}
+	return bd.err
+}
@@ -0,0 +1,85 @@
+package mp4
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/Eyevinn/mp4ff/bits"
+)
+
+// makeAC4DSI - ac4_dsi_v1 with one 5.1 presentation (presentation_version 1)
+func makeAC4DSI(t *testing.T) []byte {
+	t.Helper()
+	buf := bytes.Buffer{}
+	w := bits.NewWriter(&buf)
+	w.Write(1, 3)  // ac4_dsi_version
+	w.Write(2, 7)  // bitstream_version
+	w.Write(1, 1)  // fs_index
+	w.Write(2, 4)  // frame_rate_index
+	w.Write(1, 9)  // n_presentations
+	w.Write(0, 1)  // b_program_id
+	w.Write(0, 2)  // bit_rate_mode
+	w.Write(0, 32) // bit_rate
+	w.Write(0xffffffff, 32)
+	w.Write(0, 5) // byte_align
+	w.Write(1, 8) // presentation_version
+	w.Write(8, 8) // pres_bytes
+	w.Write(1, 5) // presentation_config_v1
+	w.Write(0, 3) // mdcompat
+	w.Write(1, 1) // b_presentation_id
+	w.Write(3, 5) // presentation_id
+	w.Write(0, 2) // dsi_frame_rate_multiply_info
+	w.Write(0, 2) // dsi_frame_rate_fraction_info
+	w.Write(0, 5) // presentation_emdf_version
+	w.Write(0, 10)
+	w.Write(1, 1)     // b_presentation_channel_coded
+	w.Write(4, 5)     // dsi_presentation_ch_mode
+	w.Write(0x47, 24) // presentation_channel_mask_v1
+	w.Write(0, 1)
+	w.Flush()
+	if w.AccError() != nil {
+		t.Fatal(w.AccError())
+	}
+	return buf.Bytes()
+}
+
+func TestDac4(t *testing.T) {
+	dsi := makeAC4DSI(t)
+	boxData := append([]byte{0, 0, 0, byte(8 + len(dsi)), 'd', 'a', 'c', '4'}, dsi...)
+	box, err := DecodeBoxSR(0, bits.NewFixedSliceReader(boxData))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dac4 := box.(*Dac4Box)
+	if dac4.DSIVersion != 1 || dac4.BitstreamVersion != 2 || dac4.SamplingFrequency() != 48000 ||
+		dac4.FrameRateIndex != 2 || dac4.NPresentations != 1 || dac4.BitRatePrecision != 0xffffffff {
+		t.Errorf("bad dac4 header values %+v", dac4)
+	}
+	if len(dac4.Presentations) != 1 {
+		t.Fatalf("%d presentations instead of 1", len(dac4.Presentations))
+	}
+	wantedPres := AC4Presentation{Version: 1, Size: 8, Config: 1, PresentationID: 3,
+		ChannelCoded: true, ChMode: 4, ChannelMask: 0x47}
+	if dac4.Presentations[0] != wantedPres {
+		t.Errorf("got presentation %+v instead of %+v", dac4.Presentations[0], wantedPres)
+	}
+	if dac4.Presentations[0].NrChannels() != 6 {
+		t.Errorf("got %d channels instead of 6", dac4.Presentations[0].NrChannels())
+	}
+	boxDiffAfterEncodeAndDecode(t, dac4)
+
+	ac4 := CreateAudioSampleEntryBox("ac-4", 6, 16, 48000, dac4)
+	boxDiffAfterEncodeAndDecode(t, ac4)
+
+	buf := bytes.Buffer{}
+	if err = dac4.Info(&buf, "", "", "  "); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(buf.Bytes(), []byte("chMode=4 => nrChannels=6")) {
+		t.Errorf("channel info missing in %q", buf.String())
+	}
+
+	if _, err = DecodeBoxSR(0, bits.NewFixedSliceReader(boxData[:len(boxData)-4])); err == nil {
+		t.Error("no error for truncated dac4")
+	}
+}
@@ -0,0 +1,231 @@
+package mp4
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+
+	"github.com/Eyevinn/mp4ff/bits"
+)
+
+// FLAC metadata block types as defined in RFC 9639 Section 8.1
+const (
+	FlacBlockTypeStreamInfo    = 0
+	FlacBlockTypePadding       = 1
+	FlacBlockTypeApplication   = 2
+	FlacBlockTypeSeekTable     = 3
+	FlacBlockTypeVorbisComment = 4
+	FlacBlockTypeCueSheet      = 5
+	FlacBlockTypePicture       = 6
+)
+
+const flacStreamInfoSize = 34
+
+// DflaBox - FLACSpecificBox as defined in Encapsulation of FLAC in ISO Base Media File Format Section 3.3.2
+//
+// Contained in : FLAC sample entry (fLaC)
+type DflaBox struct {
+	Version        byte
+	Flags          uint32
+	MetadataBlocks []FlacMetadataBlock
+}
+
+// FlacMetadataBlock - FLAC METADATA_BLOCK with header.
+// The STREAMINFO block is parsed into StreamInfo, while other blocks are kept as raw bytes in BlockData.
+type FlacMetadataBlock struct {
+	LastMetadataBlockFlag bool
+	BlockType             byte
+	StreamInfo            *FlacStreamInfo
+	BlockData             []byte
+}
+
+// FlacStreamInfo - FLAC METADATA_BLOCK_STREAMINFO as defined in RFC 9639 Section 8.2
+type FlacStreamInfo struct {
+	MinBlockSize  uint16
+	MaxBlockSize  uint16
+	MinFrameSize  uint32
+	MaxFrameSize  uint32
+	SampleRate    uint32
+	NrChannels    byte
+	BitsPerSample byte
+	TotalSamples  uint64
+	MD5           []byte
+}
+
+// CreateDfla - create a dfLa box with a single STREAMINFO metadata block
+func CreateDfla(streamInfo FlacStreamInfo) *DflaBox {
+	return &DflaBox{
+		MetadataBlocks: []FlacMetadataBlock{
+			{
+				LastMetadataBlockFlag: true,
+				BlockType:             FlacBlockTypeStreamInfo,
+				StreamInfo:            &streamInfo,
+			},
+		},
+	}
+}
+
+// DecodeDfla - box-specific decode
+func DecodeDfla(hdr BoxHeader, startPos uint64, r io.Reader) (Box, error) {
+	data, err := readBoxBody(r, hdr)
+	if err != nil {
+		return nil, err
+	}
+	sr := bits.NewFixedSliceReader(data)
+	return DecodeDflaSR(hdr, startPos, sr)
+}
+
+// DecodeDflaSR - box-specific decode
+func DecodeDflaSR(hdr BoxHeader, startPos uint64, sr bits.SliceReader) (Box, error) {
+	versionAndFlags := sr.ReadUint32()
+	b := DflaBox{
+		Version: byte(versionAndFlags >> 24),
+		Flags:   versionAndFlags & flagsMask,
+	}
+	endPos := sr.GetPos() + hdr.payloadLen() - 4
+	for sr.GetPos() < endPos {
+		headerAndLength := sr.ReadUint32()
+		if sr.AccError() != nil {
+			return nil, sr.AccError()
+		}
+		mb := FlacMetadataBlock{
+			LastMetadataBlockFlag: headerAndLength>>31 == 1,
+			BlockType:             byte((headerAndLength >> 24) & 0x7f),
+		}
+		length := int(headerAndLength & 0xffffff)
+		if mb.BlockType == FlacBlockTypeStreamInfo {
+			if length != flacStreamInfoSize {
+				return nil, fmt.Errorf("dfLa: STREAMINFO length %d instead of %d", length, flacStreamInfoSize)
+			}
+			mb.StreamInfo = decodeFlacStreamInfo(sr)
+		} else {
+			mb.BlockData = sr.ReadBytes(length)
+		}
+		if sr.AccError() != nil {
+			return nil, sr.AccError()
+		}
+		b.MetadataBlocks = append(b.MetadataBlocks, mb)
+		if mb.LastMetadataBlockFlag {
+			break
+		}
+	}
+	if sr.GetPos() != endPos {
+		return nil, fmt.Errorf("dfLa: data after last metadata block")
+	}
+	return &b, sr.AccError()
+}
+
+func decodeFlacStreamInfo(sr bits.SliceReader) *FlacStreamInfo {
+	si := FlacStreamInfo{}
+	si.MinBlockSize = sr.ReadUint16()
+	si.MaxBlockSize = sr.ReadUint16()
+	si.MinFrameSize = sr.ReadUint24()
+	si.MaxFrameSize = sr.ReadUint24()
+	combined := sr.ReadUint64()
+	si.SampleRate = uint32(combined >> 44)
+	si.NrChannels = byte((combined>>41)&0x7) + 1
+	si.BitsPerSample = byte((combined>>36)&0x1f) + 1
+	si.TotalSamples = combined & 0xfffffffff
+	si.MD5 = sr.ReadBytes(16)
+	return &si
+}
+
+// GetStreamInfo - get the STREAMINFO block if present
+func (b *DflaBox) GetStreamInfo() *FlacStreamInfo {
+	for _, mb := range b.MetadataBlocks {
+		if mb.BlockType == FlacBlockTypeStreamInfo {
+			return mb.StreamInfo
+		}
+	}
+	return nil
+}
+
+// Type - return box type
+func (b *DflaBox) Type() string {
+	return "dfLa"
+}
+
+// Size - return calculated size
+func (b *DflaBox) Size() uint64 {
+	size := uint64(boxHeaderSize + 4)
+	for _, mb := range b.MetadataBlocks {
+		size += 4 + uint64(mb.dataSize())
+	}
+	return size
+}
+
+func (mb *FlacMetadataBlock) dataSize() int {
+	if mb.BlockType == FlacBlockTypeStreamInfo {
+		return flacStreamInfoSize
+	}
+	return len(mb.BlockData)
+}
+
+// Encode - write box to w
+func (b *DflaBox) Encode(w io.Writer) error {
+	sw := bits.NewFixedSliceWriter(int(b.Size()))
+	err := b.EncodeSW(sw)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(sw.Bytes())
+	return err
+}
+
+// EncodeSW - box-specific encode to slicewriter
+func (b *DflaBox) EncodeSW(sw bits.SliceWriter) error {
+	err := EncodeHeaderSW(b, sw)
+	if err != nil {
+		return err
+	}
+	versionAndFlags := (uint32(b.Version) << 24) + b.Flags
+	sw.WriteUint32(versionAndFlags)
+	for _, mb := range b.MetadataBlocks {
+		headerAndLength := uint32(mb.BlockType&0x7f)<<24 | uint32(mb.dataSize())
+		if mb.LastMetadataBlockFlag {
+			headerAndLength |= 1 << 31
+		}
+		sw.WriteUint32(headerAndLength)
+		if mb.BlockType == FlacBlockTypeStreamInfo {
+			if mb.StreamInfo == nil {
+				return fmt.Errorf("dfLa: STREAMINFO block without data")
+			}
+			mb.StreamInfo.encodeSW(sw)
+		} else {
+			sw.WriteBytes(mb.BlockData)
+		}
+	}
+	return sw.AccError()
+}
+
+func (si *FlacStreamInfo) encodeSW(sw bits.SliceWriter) {
+	sw.WriteUint16(si.MinBlockSize)
+	sw.WriteUint16(si.MaxBlockSize)
+	sw.WriteUint24(si.MinFrameSize)
+	sw.WriteUint24(si.MaxFrameSize)
+	combined := uint64(si.SampleRate&0xfffff)<<44 | uint64((si.NrChannels-1)&0x7)<<41 |
+		uint64((si.BitsPerSample-1)&0x1f)<<36 | si.TotalSamples&0xfffffffff
+	sw.WriteUint64(combined)
+	md5 := make([]byte, 16)
+	copy(md5, si.MD5)
+	sw.WriteBytes(md5)
+}
+
+// Info - write box-specific information
+func (b *DflaBox) Info(w io.Writer, specificBoxLevels, indent, indentStep string) error {
+	bd := newInfoDumper(w, indent, b, int(b.Version), b.Flags)
+	for i, mb := range b.MetadataBlocks {
+		bd.write(" - metadataBlock[%d]: type=%d last=%t size=%d", i+1, mb.BlockType,
+			mb.LastMetadataBlockFlag, mb.dataSize())
+		if mb.BlockType == FlacBlockTypeStreamInfo && mb.StreamInfo != nil {
+			si := mb.StreamInfo
+			bd.write("   - minBlockSize=%d maxBlockSize=%d", si.MinBlockSize, si.MaxBlockSize)
+			bd.write("   - minFrameSize=%d maxFrameSize=%d", si.MinFrameSize, si.MaxFrameSize)
+			bd.write("   - sampleRate=%d nrChannels=%d bitsPerSample=%d", si.SampleRate, si.NrChannels,
+				si.BitsPerSample)
+			bd.write("   - totalSamples=%d", si.TotalSamples)
+			bd.write("   - md5=%s", hex.EncodeToString(si.MD5))
+		}
+	}
+	return bd.err
+}
@@ -0,0 +1,58 @@
+package mp4
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDfla(t *testing.T) {
+	streamInfo := FlacStreamInfo{
+		MinBlockSize:  4096,
+		MaxBlockSize:  4096,
+		MinFrameSize:  14,
+		MaxFrameSize:  12345,
+		SampleRate:    48000,
+		NrChannels:    2,
+		BitsPerSample: 24,
+		TotalSamples:  0x123456789,
+		MD5:           []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
+	}
+	dfla := CreateDfla(streamInfo)
+	boxDiffAfterEncodeAndDecode(t, dfla)
+
+	dflaExtra := &DflaBox{
+		MetadataBlocks: []FlacMetadataBlock{
+			{BlockType: FlacBlockTypeStreamInfo, StreamInfo: &streamInfo},
+			{BlockType: FlacBlockTypeVorbisComment, BlockData: []byte("vorbis comment")},
+			{LastMetadataBlockFlag: true, BlockType: FlacBlockTypePadding, BlockData: make([]byte, 8)},
+		},
+	}
+	boxDiffAfterEncodeAndDecode(t, dflaExtra)
+	cmpAfterDecodeEncodeBox(t, encodeBox(t, dflaExtra))
+	si := dflaExtra.GetStreamInfo()
+	if si == nil || si.SampleRate != 48000 {
+		t.Error("stream info not found")
+	}
+
+	flac := CreateAudioSampleEntryBox("fLaC", 2, 24, 48000, dfla)
+	boxDiffAfterEncodeAndDecode(t, flac)
+	stsd := NewStsdBox()
+	stsd.AddChild(flac)
+	if stsd.Flac == nil || stsd.Flac.Dfla == nil {
+		t.Error("fLaC and dfLa not found in stsd")
+	}
+}
+
+func TestBadDfla(t *testing.T) {
+	dfla := &DflaBox{
+		MetadataBlocks: []FlacMetadataBlock{
+			{LastMetadataBlockFlag: true, BlockType: FlacBlockTypeApplication, BlockData: []byte{1, 2, 3, 4}},
+		},
+	}
+	data := encodeBox(t, dfla)
+	data[15] = 0x05 // Block length larger than box
+	_, err := DecodeBox(0, bytes.NewBuffer(data))
+	if err == nil {
+		t.Error("expected error for too long metadata block")
+	}
+}
@@ -0,0 +1,249 @@
+package mp4
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// Difference - a field-level difference between two box trees found by DiffBoxes
+type Difference struct {
+	// Path is the box path, like moov/trak[1]/tkhd, followed by the field path, like .Width
+	Path     string
+	Expected interface{}
+	Actual   interface{}
+}
+
+// String - description of the difference
+func (d Difference) String() string {
+	return fmt.Sprintf("%s: expected %v, got %v", d.Path, d.Expected, d.Actual)
+}
+
+var boxInterface = reflect.TypeOf((*Box)(nil)).Elem()
+
+// DiffBoxes walks the box trees a and b and returns the differences of field values, with a
+// treated as the expected tree. Child boxes are compared in order and identified by type and
+// index among the siblings of the same type, for example moov/trak[1]/mdia/mdhd.Timescale.
+// Box pointer fields like Mdia of TrakBox are not compared separately, since they refer to
+// boxes in Children. StartPos fields are ignored, since they depend on the position in the file.
+// The result is nil if the trees are equal.
+func DiffBoxes(a, b Box) []Difference {
+	d := boxDiffer{visited: make(map[[2]uintptr]bool)}
+	d.diffBoxes(boxPathName(a, nil, 0), a, b)
+	return d.diffs
+}
+
+type boxDiffer struct {
+	diffs   []Difference
+	visited map[[2]uintptr]bool
+}
+
+func (d *boxDiffer) add(path string, expected, actual interface{}) {
+	d.diffs = append(d.diffs, Difference{Path: path, Expected: expected, Actual: actual})
+}
+
+func (d *boxDiffer) diffBoxes(path string, a, b Box) {
+	isNil := func(box Box) bool {
+		return box == nil || (reflect.ValueOf(box).Kind() == reflect.Ptr && reflect.ValueOf(box).IsNil())
+	}
+	switch {
+	case isNil(a) && isNil(b):
+		return
+	case isNil(a) || isNil(b):
+		d.add(path, boxTypeOrNil(a), boxTypeOrNil(b))
+		return
+	case a.Type() != b.Type() || reflect.TypeOf(a) != reflect.TypeOf(b):
+		d.add(path, a.Type(), b.Type())
+		return
+	}
+	d.diffValues(path, reflect.ValueOf(a), reflect.ValueOf(b))
+}
+
+// diffValues compares values of the same type.
+func (d *boxDiffer) diffValues(path string, a, b reflect.Value) {
+	switch a.Kind() {
+	case reflect.Ptr:
+		if a.IsNil() || b.IsNil() {
+			if a.IsNil() != b.IsNil() {
+				d.add(path, nilOrSet(a), nilOrSet(b))
+			}
+			return
+		}
+		key := [2]uintptr{a.Pointer(), b.Pointer()}
+		if d.visited[key] {
+			return
+		}
+		d.visited[key] = true
+		d.diffValues(path, a.Elem(), b.Elem())
+	case reflect.Interface:
+		if a.IsNil() || b.IsNil() {
+			if a.IsNil() != b.IsNil() {
+				d.add(path, nilOrSet(a), nilOrSet(b))
+			}
+			return
+		}
+		if a.Elem().Type() != b.Elem().Type() {
+			d.add(path, a.Elem().Type().String(), b.Elem().Type().String())
+			return
+		}
+		d.diffValues(path, a.Elem(), b.Elem())
+	case reflect.Struct:
+		d.diffStructs(path, a, b)
+	case reflect.Slice, reflect.Array:
+		if a.Kind() == reflect.Slice && a.Type().Elem().Kind() == reflect.Uint8 {
+			if !bytes.Equal(a.Bytes(), b.Bytes()) {
+				d.add(path, append([]byte(nil), a.Bytes()...), append([]byte(nil), b.Bytes()...))
+			}
+			return
+		}
+		if a.Len() != b.Len() {
+			d.add(path+".len", a.Len(), b.Len())
+		}
+		for i := 0; i < a.Len() && i < b.Len(); i++ {
+			d.diffValues(fmt.Sprintf("%s[%d]", path, i), a.Index(i), b.Index(i))
+		}
+	case reflect.Map:
+		keys := make(map[string]reflect.Value)
+		for _, k := range append(a.MapKeys(), b.MapKeys()...) {
+			keys[fmt.Sprintf("%v", leafValue(k))] = k
+		}
+		names := make([]string, 0, len(keys))
+		for name := range keys {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			av, bv := a.MapIndex(keys[name]), b.MapIndex(keys[name])
+			keyPath := fmt.Sprintf("%s[%s]", path, name)
+			if !av.IsValid() || !bv.IsValid() {
+				d.add(keyPath, validValue(av), validValue(bv))
+				continue
+			}
+			d.diffValues(keyPath, av, bv)
+		}
+	case reflect.Func, reflect.Chan, reflect.UnsafePointer:
+		// Not comparable field values
+	default:
+		av, bv := leafValue(a), leafValue(b)
+		if av != bv {
+			d.add(path, av, bv)
+		}
+	}
+}
+
+// diffStructs compares struct fields, with child boxes given by a Children field.
+func (d *boxDiffer) diffStructs(path string, a, b reflect.Value) {
+	t := a.Type()
+	_, hasChildren := t.FieldByName("Children")
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Name == "StartPos" {
+			continue
+		}
+		af, bf := a.Field(i), b.Field(i)
+		if field.Name == "Children" && isBoxSlice(field.Type) {
+			d.diffChildren(path, af, bf)
+			continue
+		}
+		if hasChildren && (field.Type.Implements(boxInterface) || isBoxSlice(field.Type)) {
+			continue // Refers to boxes in Children
+		}
+		d.diffValues(path+"."+field.Name, af, bf)
+	}
+}
+
+// diffChildren compares child boxes pairwise in order.
+func (d *boxDiffer) diffChildren(path string, a, b reflect.Value) {
+	if a.Len() != b.Len() {
+		d.add(path+".len(Children)", a.Len(), b.Len())
+	}
+	aChildren := boxSlice(a)
+	bChildren := boxSlice(b)
+	for i := 0; i < len(aChildren) && i < len(bChildren); i++ {
+		childPath := path + "/" + boxPathName(aChildren[i], aChildren, i)
+		ab, bb := aChildren[i], bChildren[i]
+		if ab != nil && bb != nil && ab.Type() != bb.Type() {
+			d.add(childPath, ab.Type(), bb.Type())
+			continue
+		}
+		d.diffBoxes(childPath, ab, bb)
+	}
+}
+
+// boxPathName returns the box type with an index if there are siblings of the same type.
+func boxPathName(box Box, siblings []Box, idx int) string {
+	name := boxTypeOrNil(box)
+	nrSame, pos := 0, 0
+	for i, s := range siblings {
+		if boxTypeOrNil(s) == name {
+			if i < idx {
+				pos++
+			}
+			nrSame++
+		}
+	}
+	if nrSame > 1 {
+		return fmt.Sprintf("%s[%d]", name, pos)
+	}
+	return name
+}
+
+func boxTypeOrNil(box Box) string {
+	if box == nil || (reflect.ValueOf(box).Kind() == reflect.Ptr && reflect.ValueOf(box).IsNil()) {
+		return "nil"
+	}
+	return box.Type()
+}
+
+func isBoxSlice(t reflect.Type) bool {
+	return t.Kind() == reflect.Slice && t.Elem().Implements(boxInterface)
+}
+
+func boxSlice(v reflect.Value) []Box {
+	boxes := make([]Box, v.Len())
+	for i := range boxes {
+		e := v.Index(i)
+		if e.Kind() == reflect.Interface && e.IsNil() {
+			continue
+		}
+		if e.CanInterface() {
+			boxes[i], _ = e.Interface().(Box)
+		}
+	}
+	return boxes
+}
+
+// leafValue returns the value of a basic type, also for unexported fields.
+func leafValue(v reflect.Value) interface{} {
+	switch v.Kind() {
+	case reflect.Bool:
+		return v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint()
+	case reflect.Float32, reflect.Float64:
+		return v.Float()
+	case reflect.Complex64, reflect.Complex128:
+		return v.Complex()
+	case reflect.String:
+		return v.String()
+	default:
+		return v.Type().String()
+	}
+}
+
+func nilOrSet(v reflect.Value) string {
+	if v.IsNil() {
+		return "nil"
+	}
+	return "set"
+}
+
+func validValue(v reflect.Value) string {
+	if !v.IsValid() {
+		return "missing"
+	}
+	return "present"
+}
@@ -0,0 +1,39 @@
+package mp4
+
+import (
+	"testing"
+
+	"github.com/go-test/deep"
+)
+
+func TestDiffBoxes(t *testing.T) {
+	readMoov := func() *MoovBox {
+		f, err := ReadMP4File("testdata/prog_8s.mp4")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return f.Moov
+	}
+	a, b := readMoov(), readMoov()
+	if diffs := DiffBoxes(a, b); diffs != nil {
+		t.Fatalf("unexpected differences: %v", diffs)
+	}
+	b.Traks[1].Tkhd.Width = 0
+	b.Traks[0].Mdia.Mdhd.Timescale = 90000
+	b.Traks[0].Mdia.Minf.Stbl.Stsz.SampleSize = b.Traks[0].Mdia.Minf.Stbl.Stsz.SampleSize[:10]
+	b.Traks[1].AddChild(&UdtaBox{})
+	want := []Difference{
+		{Path: "moov/trak[0]/mdia/mdhd.Timescale", Expected: uint64(a.Traks[0].Mdia.Mdhd.Timescale), Actual: uint64(90000)},
+		{Path: "moov/trak[0]/mdia/minf/stbl/stsz.SampleSize.len", Expected: len(a.Traks[0].Mdia.Minf.Stbl.Stsz.SampleSize),
+			Actual: 10},
+		{Path: "moov/trak[1].len(Children)", Expected: len(a.Traks[1].Children), Actual: len(b.Traks[1].Children)},
+		{Path: "moov/trak[1]/tkhd.Width", Expected: uint64(a.Traks[1].Tkhd.Width), Actual: uint64(0)},
+	}
+	got := DiffBoxes(a, b)
+	if diff := deep.Equal(got, want); diff != nil {
+		t.Errorf("unexpected differences %v: %v", got, diff)
+	}
+	if d := DiffBoxes(a, &FreeBox{Name: "free"}); len(d) != 1 || d[0].Expected != "moov" {
+		t.Errorf("unexpected differences for different box types: %v", d)
+	}
+}
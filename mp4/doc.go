@@ -108,6 +108,8 @@ For decoding, this is supported by running
 
 	parsedMp4, err = mp4.DecodeFile(ifd, mp4.WithDecodeMode(mp4.DecModeLazyMdat))
 
+or equivalently with the [WithLazyMdat] option.
+
 In this case, the media data of the [mp4.MdatBox] box will not be read, but only its size is being saved.
 To read or copy the actual data corresponding to a sample, one must calculate the
 corresponding byte range and either call
@@ -118,6 +120,10 @@ or
 
 	func (m *MdatBox) CopyData(start, size int64, rs io.ReadSeeker, w io.Writer) (nrWritten int64, err error)
 
+For progressive files, [File.ReadSamples] reads full samples of a track by seeking to the
+byte ranges given by the sample tables, and [MdatBox.NewSectionReader] provides an
+[io.SectionReader] for the mdat payload given an [io.ReaderAt] such as an [os.File].
+
 Example code for this, including lazy writing of [mp4.MdatBox], can be found in [examples/segmenter]
 with the lazy mode set.
 
@@ -186,9 +192,9 @@ For fragmented files, one can choose to either encode all boxes in a [mp4.File],
 the ones which are included in the init and media segments. The attribute that controls that
 is called [mp4.FragEncMode].
 Another attribute [mp4.EncOptimize] controls possible optimizations of the file encoding process.
-Currently, there is only one possible optimization called [mp4.OptimizeTrun].
-It can reduce the size of the [mp4.TrunBox] by finding and writing default
+The optimization [mp4.OptimizeTrun] can reduce the size of the [mp4.TrunBox] by finding and writing default
 values in the [mp4.TfhdBox] and omitting the corresponding values from the [mp4.TrunBox].
+[mp4.OptimizeTruns] does the same for all truns of a traf, and checks that the sample values are unchanged.
 Note that this may change the size of all ancestor boxes of the [mp4.TrunBox].
 
 # Sample Number Offset
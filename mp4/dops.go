@@ -0,0 +1,119 @@
+package mp4
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/Eyevinn/mp4ff/bits"
+)
+
+// DopsBox - OpusSpecificBox as defined in Encapsulation of Opus in ISO Base Media File Format Section 4.3.2
+//
+// Contained in : Opus sample entry (Opus)
+type DopsBox struct {
+	Version              byte
+	OutputChannelCount   byte
+	PreSkip              uint16
+	InputSampleRate      uint32
+	OutputGain           int16
+	ChannelMappingFamily byte
+	StreamCount          byte
+	CoupledCount         byte
+	ChannelMapping       []byte // One byte per output channel if ChannelMappingFamily != 0
+}
+
+// DecodeDops - box-specific decode
+func DecodeDops(hdr BoxHeader, startPos uint64, r io.Reader) (Box, error) {
+	data, err := readBoxBody(r, hdr)
+	if err != nil {
+		return nil, err
+	}
+	sr := bits.NewFixedSliceReader(data)
+	return DecodeDopsSR(hdr, startPos, sr)
+}
+
+// DecodeDopsSR - box-specific decode
+func DecodeDopsSR(hdr BoxHeader, startPos uint64, sr bits.SliceReader) (Box, error) {
+	b := DopsBox{}
+	b.Version = sr.ReadUint8()
+	if b.Version != 0 {
+		return nil, fmt.Errorf("dOps: unknown version %d", b.Version)
+	}
+	b.OutputChannelCount = sr.ReadUint8()
+	b.PreSkip = sr.ReadUint16()
+	b.InputSampleRate = sr.ReadUint32()
+	b.OutputGain = sr.ReadInt16()
+	b.ChannelMappingFamily = sr.ReadUint8()
+	if b.ChannelMappingFamily != 0 {
+		b.StreamCount = sr.ReadUint8()
+		b.CoupledCount = sr.ReadUint8()
+		b.ChannelMapping = sr.ReadBytes(int(b.OutputChannelCount))
+	}
+	return &b, sr.AccError()
+}
+
+// Type - return box type
+func (b *DopsBox) Type() string {
+	return "dOps"
+}
+
+// Size - return calculated size
+func (b *DopsBox) Size() uint64 {
+	size := uint64(boxHeaderSize + 11)
+	if b.ChannelMappingFamily != 0 {
+		size += 2 + uint64(b.OutputChannelCount)
+	}
+	return size
+}
+
+// Encode - write box to w
+func (b *DopsBox) Encode(w io.Writer) error {
+	sw := bits.NewFixedSliceWriter(int(b.Size()))
+	err := b.EncodeSW(sw)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(sw.Bytes())
+	return err
+}
+
+// EncodeSW - box-specific encode to slicewriter
+func (b *DopsBox) EncodeSW(sw bits.SliceWriter) error {
+	if b.ChannelMappingFamily != 0 && len(b.ChannelMapping) != int(b.OutputChannelCount) {
+		return fmt.Errorf("dOps: channel mapping length %d does not match output channel count %d",
+			len(b.ChannelMapping), b.OutputChannelCount)
+	}
+	err := EncodeHeaderSW(b, sw)
+	if err != nil {
+		return err
+	}
+	sw.WriteUint8(b.Version)
+	sw.WriteUint8(b.OutputChannelCount)
+	sw.WriteUint16(b.PreSkip)
+	sw.WriteUint32(b.InputSampleRate)
+	sw.WriteInt16(b.OutputGain)
+	sw.WriteUint8(b.ChannelMappingFamily)
+	if b.ChannelMappingFamily != 0 {
+		sw.WriteUint8(b.StreamCount)
+		sw.WriteUint8(b.CoupledCount)
+		sw.WriteBytes(b.ChannelMapping)
+	}
+	return sw.AccError()
+}
+
+// Info - write box-specific information
+func (b *DopsBox) Info(w io.Writer, specificBoxLevels, indent, indentStep string) error {
+	bd := newInfoDumper(w, indent, b, -1, 0)
+	bd.write(" - version: %d", b.Version)
+	bd.write(" - outputChannelCount: %d", b.OutputChannelCount)
+	bd.write(" - preSkip: %d", b.PreSkip)
+	bd.write(" - inputSampleRate: %d", b.InputSampleRate)
+	bd.write(" - outputGain: %d", b.OutputGain)
+	bd.write(" - channelMappingFamily: %d", b.ChannelMappingFamily)
+	if b.ChannelMappingFamily != 0 {
+		bd.write(" - streamCount: %d", b.StreamCount)
+		bd.write(" - coupledCount: %d", b.CoupledCount)
+		bd.write(" - channelMapping: %v", b.ChannelMapping)
+	}
+	return bd.err
+}
@@ -0,0 +1,44 @@
+package mp4
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+func TestDops(t *testing.T) {
+	boxes := []Box{
+		&DopsBox{
+			OutputChannelCount: 2,
+			PreSkip:            312,
+			InputSampleRate:    48000,
+			OutputGain:         -256,
+		},
+		&DopsBox{
+			OutputChannelCount:   6,
+			PreSkip:              3840,
+			InputSampleRate:      48000,
+			ChannelMappingFamily: 1,
+			StreamCount:          4,
+			CoupledCount:         2,
+			ChannelMapping:       []byte{0, 4, 1, 2, 3, 5},
+		},
+	}
+	for _, inBox := range boxes {
+		boxDiffAfterEncodeAndDecode(t, inBox)
+	}
+	opus := CreateAudioSampleEntryBox("Opus", 2, 16, 48000, boxes[0])
+	boxDiffAfterEncodeAndDecode(t, opus)
+	stsd := NewStsdBox()
+	stsd.AddChild(opus)
+	if stsd.Opus == nil || stsd.Opus.Dops == nil {
+		t.Error("Opus and dOps not found in stsd")
+	}
+}
+
+func TestDopsDecodeEncode(t *testing.T) {
+	data, err := hex.DecodeString("00000013644f7073000201380000bb80000000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmpAfterDecodeEncodeBox(t, data)
+}
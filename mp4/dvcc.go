@@ -0,0 +1,144 @@
+package mp4
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/Eyevinn/mp4ff/bits"
+)
+
+// DoViConfigurationBox - Dolby Vision configuration box (dvcC, dvvC, or dvwC)
+// Contains a DOVIDecoderConfigurationRecord as defined in
+// Dolby Vision Streams Within the ISO Base Media File Format v2.4.
+// dvcC is used for profiles up to 7, dvvC for profiles 8 to 10, and dvwC for profile 10 and above.
+type DoViConfigurationBox struct {
+	name                      string
+	DVVersionMajor            byte
+	DVVersionMinor            byte
+	DVProfile                 byte
+	DVLevel                   byte
+	RPUPresentFlag            bool
+	ELPresentFlag             bool
+	BLPresentFlag             bool
+	DVBLSignalCompatibilityID byte
+	Reserved                  []byte // Bytes after the decoded fields, kept as read
+	reservedBits              byte   // 4 bits after DVBLSignalCompatibilityID
+}
+
+// doViConfRecSize is the size of DOVIDecoderConfigurationRecord including reserved bytes
+const doViConfRecSize = 24
+
+// doViConfRecDecodedSize is the number of bytes of DOVIDecoderConfigurationRecord before the reserved bytes
+const doViConfRecDecodedSize = 5
+
+// CreateDoViConfigurationBox creates a dvcC, dvvC or dvwC box depending on profile
+func CreateDoViConfigurationBox(profile, level byte, rpu, el, bl bool, blCompatibilityID byte) *DoViConfigurationBox {
+	name := "dvcC"
+	switch {
+	case profile > 10:
+		name = "dvwC"
+	case profile > 7:
+		name = "dvvC"
+	}
+	return &DoViConfigurationBox{
+		name:                      name,
+		DVVersionMajor:            1,
+		DVVersionMinor:            0,
+		DVProfile:                 profile,
+		DVLevel:                   level,
+		RPUPresentFlag:            rpu,
+		ELPresentFlag:             el,
+		BLPresentFlag:             bl,
+		DVBLSignalCompatibilityID: blCompatibilityID,
+		Reserved:                  make([]byte, doViConfRecSize-doViConfRecDecodedSize),
+	}
+}
+
+// DecodeDoViConfiguration - box-specific decode
+func DecodeDoViConfiguration(hdr BoxHeader, startPos uint64, r io.Reader) (Box, error) {
+	data, err := readBoxBody(r, hdr)
+	if err != nil {
+		return nil, err
+	}
+	sr := bits.NewFixedSliceReader(data)
+	return DecodeDoViConfigurationSR(hdr, startPos, sr)
+}
+
+// DecodeDoViConfigurationSR - box-specific decode
+func DecodeDoViConfigurationSR(hdr BoxHeader, startPos uint64, sr bits.SliceReader) (Box, error) {
+	if hdr.payloadLen() < doViConfRecSize {
+		return nil, fmt.Errorf("%s: too small payload size %d", hdr.Name, hdr.payloadLen())
+	}
+	b := DoViConfigurationBox{name: hdr.Name}
+	b.DVVersionMajor = sr.ReadUint8()
+	b.DVVersionMinor = sr.ReadUint8()
+	bitFields := sr.ReadUint16()
+	b.DVProfile = byte(bitFields >> 9)
+	b.DVLevel = byte((bitFields >> 3) & 0x3f)
+	b.RPUPresentFlag = bitFields&0x04 != 0
+	b.ELPresentFlag = bitFields&0x02 != 0
+	b.BLPresentFlag = bitFields&0x01 != 0
+	compatibilityByte := sr.ReadUint8()
+	b.DVBLSignalCompatibilityID = compatibilityByte >> 4
+	b.reservedBits = compatibilityByte & 0x0f
+	b.Reserved = sr.ReadBytes(hdr.payloadLen() - doViConfRecDecodedSize)
+	return &b, sr.AccError()
+}
+
+// Type - box type
+func (b *DoViConfigurationBox) Type() string {
+	return b.name
+}
+
+// Size - calculated size of box
+func (b *DoViConfigurationBox) Size() uint64 {
+	return uint64(boxHeaderSize + doViConfRecDecodedSize + len(b.Reserved))
+}
+
+// Encode - write box to w
+func (b *DoViConfigurationBox) Encode(w io.Writer) error {
+	sw := bits.NewFixedSliceWriter(int(b.Size()))
+	err := b.EncodeSW(sw)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(sw.Bytes())
+	return err
+}
+
+// EncodeSW - box-specific encode to slicewriter
+func (b *DoViConfigurationBox) EncodeSW(sw bits.SliceWriter) error {
+	err := EncodeHeaderSW(b, sw)
+	if err != nil {
+		return err
+	}
+	sw.WriteUint8(b.DVVersionMajor)
+	sw.WriteUint8(b.DVVersionMinor)
+	bitFields := uint16(b.DVProfile&0x7f)<<9 | uint16(b.DVLevel&0x3f)<<3
+	if b.RPUPresentFlag {
+		bitFields |= 0x04
+	}
+	if b.ELPresentFlag {
+		bitFields |= 0x02
+	}
+	if b.BLPresentFlag {
+		bitFields |= 0x01
+	}
+	sw.WriteUint16(bitFields)
+	sw.WriteUint8(b.DVBLSignalCompatibilityID<<4 | b.reservedBits&0x0f)
+	sw.WriteBytes(b.Reserved)
+	return sw.AccError()
+}
+
+// Info - write box-specific information
+func (b *DoViConfigurationBox) Info(w io.Writer, specificBoxLevels, indent, indentStep string) error {
+	bd := newInfoDumper(w, indent, b, -1, 0)
+	bd.write(" - dvVersion: %d.%d", b.DVVersionMajor, b.DVVersionMinor)
+	bd.write(" - dvProfile: %d", b.DVProfile)
+	bd.write(" - dvLevel: %d", b.DVLevel)
+	bd.write(" - rpuPresentFlag: %t", b.RPUPresentFlag)
+	bd.write(" - elPresentFlag: %t", b.ELPresentFlag)
+	bd.write(" - blPresentFlag: %t", b.BLPresentFlag)
+	bd.write(" - dvBLSignalCompatibilityID: %d", b.DVBLSignalCompatibilityID)
+	return bd.err
+}
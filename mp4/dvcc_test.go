@@ -0,0 +1,81 @@
+package mp4
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/go-test/deep"
+)
+
+func TestDoViConfiguration(t *testing.T) {
+	// dvvC for profile 8.1, level 6 with RPU and BL, compatibility id 1
+	dvvCHex := "0000002064767643010010351000000000000000000000000000000000000000"
+	data, err := hex.DecodeString(dvvCHex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	box, err := DecodeBox(0, bytes.NewBuffer(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dvvC := box.(*DoViConfigurationBox)
+	wanted := CreateDoViConfigurationBox(8, 6, true, false, true, 1)
+	if diff := deep.Equal(dvvC, wanted); diff != nil {
+		t.Error(diff)
+	}
+	cmpAfterDecodeEncodeBox(t, data)
+
+	// Non-zero reserved bits and bytes, and a longer record, should be kept
+	dvcCHex := "0000002164766343010010351f00000000000000000000000000000000000000ab"
+	data, err = hex.DecodeString(dvcCHex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmpAfterDecodeEncodeBox(t, data)
+
+	for _, profile := range []byte{5, 8, 20} {
+		boxDiffAfterEncodeAndDecode(t, CreateDoViConfigurationBox(profile, 9, true, true, false, 0))
+	}
+}
+
+func TestDolbyVisionSampleEntries(t *testing.T) {
+	vpsNalu, _ := hex.DecodeString(vpsHex)
+	spsNalu, _ := hex.DecodeString(spsHex)
+	ppsNalu, _ := hex.DecodeString(ppsHex)
+	hvcC, err := CreateHvcC([][]byte{vpsNalu}, [][]byte{spsNalu}, [][]byte{ppsNalu}, true, true, true, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"hvc1", "dvh1"} {
+		vse := CreateVisualSampleEntryBox(name, 1920, 1080, hvcC)
+		vse.AddChild(CreateDoViConfigurationBox(8, 6, true, false, true, 1))
+		stsd := NewStsdBox()
+		stsd.AddChild(vse)
+		boxDiffAfterEncodeAndDecode(t, stsd)
+
+		buf := bytes.Buffer{}
+		if err := stsd.Encode(&buf); err != nil {
+			t.Fatal(err)
+		}
+		box, err := DecodeBox(0, &buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decStsd := box.(*StsdBox)
+		var decVse *VisualSampleEntryBox
+		switch name {
+		case "hvc1":
+			decVse = decStsd.HvcX
+		case "dvh1":
+			decVse = decStsd.DvXX
+		}
+		if decVse == nil || decVse.HvcC == nil || decVse.DoVi == nil {
+			t.Errorf("%s: hvcC and dvvC not both available", name)
+			continue
+		}
+		if decVse.DoVi.Type() != "dvvC" || decVse.DoVi.DVProfile != 8 {
+			t.Errorf("%s: got %s with profile %d", name, decVse.DoVi.Type(), decVse.DoVi.DVProfile)
+		}
+	}
+}
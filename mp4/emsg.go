@@ -6,6 +6,7 @@ import (
 	"io"
 
 	"github.com/Eyevinn/mp4ff/bits"
+	"github.com/Eyevinn/mp4ff/emsg"
 )
 
 // EmsgBox - DASHEventMessageBox as defined in ISO/IEC 23009-1
@@ -128,6 +129,20 @@ func (b *EmsgBox) EncodeSW(sw bits.SliceWriter) error {
 	return sw.AccError()
 }
 
+// ParseMessageData - parse MessageData based on SchemeIDURI.
+// Returns *emsg.ID3Tag for ID3 and *emsg.SpliceInfoSection for binary SCTE-35 messages.
+// Returns nil and no error for unknown schemes.
+func (b *EmsgBox) ParseMessageData() (interface{}, error) {
+	switch b.SchemeIDURI {
+	case emsg.SchemeIDURIID3:
+		return emsg.ParseID3(b.MessageData)
+	case emsg.SchemeIDURISCTE35:
+		return emsg.ParseSCTE35(b.MessageData)
+	default:
+		return nil, nil
+	}
+}
+
 // Info - write box-specific information
 func (b *EmsgBox) Info(w io.Writer, specificBoxLevels, indent, indentStep string) error {
 	bd := newInfoDumper(w, indent, b, int(b.Version), b.Flags)
@@ -151,6 +166,20 @@ func (b *EmsgBox) Info(w io.Writer, specificBoxLevels, indent, indentStep string
 		} else {
 			bd.write(" - messageData size=%d", msgDataLen)
 		}
+		msg, err := b.ParseMessageData()
+		if err != nil {
+			bd.write(" - messageData parse error: %s", err)
+			return bd.err
+		}
+		switch m := msg.(type) {
+		case *emsg.ID3Tag:
+			bd.write(" - ID3v2.%d tag", m.MajorVersion)
+			for _, f := range m.Frames {
+				bd.write("   - %s", f)
+			}
+		case *emsg.SpliceInfoSection:
+			bd.write(" - %s", m)
+		}
 	}
 
 	return bd.err
@@ -1,11 +1,15 @@
 package mp4
 
 import (
+	"bytes"
+	"encoding/base64"
+	"strings"
 	"testing"
 
 	"github.com/go-test/deep"
 
 	"github.com/Eyevinn/mp4ff/bits"
+	"github.com/Eyevinn/mp4ff/emsg"
 )
 
 func TestEmsg(t *testing.T) {
@@ -91,3 +95,47 @@ func TestEmsgMessageDataIsEncoded(t *testing.T) {
 	}
 
 }
+
+func TestEmsgParseMessageData(t *testing.T) {
+	scte35, _ := base64.StdEncoding.DecodeString("/DA0AAAAAAAA///wBQb+cr0AUAAeAhxDVUVJSAAAjn/PAAGlmbAICAAAAAAsoKGKNAIAmsnRfg==")
+	id3 := []byte{73, 68, 51, 4, 0, 0, 0, 0, 0, 16, 80, 82, 73, 86, 0, 0, 0, 6, 0, 0, 111, 119, 110, 0, 1, 2}
+	testCases := []struct {
+		name        string
+		schemeIDURI string
+		data        []byte
+		wantedInfo  string
+	}{
+		{"scte35", emsg.SchemeIDURISCTE35, scte35, "time_signal ptsTime=1924989008"},
+		{"id3", emsg.SchemeIDURIID3, id3, `PRIV: owner="own" size=2`},
+		{"unknown", "schid", id3, "messageData size=26"},
+	}
+	for _, tc := range testCases {
+		b := &EmsgBox{Version: 1, TimeScale: 90000, SchemeIDURI: tc.schemeIDURI, MessageData: tc.data}
+		msg, err := b.ParseMessageData()
+		if err != nil {
+			t.Errorf("%s: %s", tc.name, err)
+		}
+		switch tc.schemeIDURI {
+		case emsg.SchemeIDURISCTE35:
+			if _, ok := msg.(*emsg.SpliceInfoSection); !ok {
+				t.Errorf("%s: got %T", tc.name, msg)
+			}
+		case emsg.SchemeIDURIID3:
+			if _, ok := msg.(*emsg.ID3Tag); !ok {
+				t.Errorf("%s: got %T", tc.name, msg)
+			}
+		default:
+			if msg != nil {
+				t.Errorf("%s: got %T", tc.name, msg)
+			}
+		}
+		buf := bytes.Buffer{}
+		err = b.Info(&buf, "", "", "  ")
+		if err != nil {
+			t.Error(err)
+		}
+		if !strings.Contains(buf.String(), tc.wantedInfo) {
+			t.Errorf("%s: %q not in info output %q", tc.name, tc.wantedInfo, buf.String())
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package mp4
+
+import (
+	"fmt"
+)
+
+// TrackEncConfig - encryption parameters for one track used by EncryptFile
+type TrackEncConfig struct {
+	Scheme string // cenc or cbcs
+	KID    UUID
+	Key    []byte // 16 bytes
+	IV     []byte // 8 or 16 bytes. Constant IV for cbcs, and IV of the first sample for cenc
+	// Pattern is the cbcs crypt:skip pattern for video. nil means DefaultCbcsVideoPattern.
+	Pattern   *CryptPattern
+	PsshBoxes []*PsshBox // Added at the end of moov
+}
+
+// EncryptFile encrypts the tracks of a fragmented file with init segment in place, with
+// one configuration per trackID. Tracks without configuration are left in the clear, so
+// for example audio can be kept clear while video is encrypted, or different KIDs and
+// schemes can be used for different tracks.
+// For cenc, the IV continues from sample to sample across all fragments of a track.
+// Every traf must have exactly one trun and CMAF chunks are not supported.
+// The configurations and fragments are checked before anything is changed.
+// Sidx boxes are not changed, but can be updated with UpdateSidx.
+func EncryptFile(f *File, perTrack map[uint32]TrackEncConfig) error {
+	if f.Init == nil {
+		return fmt.Errorf("no init segment")
+	}
+	moov := f.Init.Moov
+	if moov.Mvex == nil {
+		return fmt.Errorf("no mvex box in init segment")
+	}
+	for trackID := range perTrack {
+		if _, err := findTrak(f.Init, trackID); err != nil {
+			return err
+		}
+	}
+	protections := make(map[uint32]*trakProtection, len(perTrack))
+	ivs := make(map[uint32][]byte, len(perTrack))
+	var psshBoxes []*PsshBox
+	for _, trak := range moov.Traks {
+		trackID := trak.Tkhd.TrackID
+		cfg, ok := perTrack[trackID]
+		if !ok {
+			continue
+		}
+		if len(cfg.Key) != 16 {
+			return fmt.Errorf("track %d: key must be 16 bytes", trackID)
+		}
+		iv := cfg.IV
+		if len(iv) == 8 {
+			iv = make([]byte, 16)
+			copy(iv, cfg.IV)
+		}
+		if len(iv) != 16 {
+			return fmt.Errorf("track %d: iv must be 8 or 16 bytes", trackID)
+		}
+		pattern := DefaultCbcsVideoPattern
+		if cfg.Pattern != nil {
+			pattern = *cfg.Pattern
+		}
+		trex, ok := moov.Mvex.GetTrex(trackID)
+		if !ok {
+			return fmt.Errorf("track %d: no trex box", trackID)
+		}
+		tp, err := prepareTrakProtection(trak, trex, iv, cfg.Scheme, cfg.KID, pattern)
+		if err != nil {
+			return fmt.Errorf("track %d: %w", trackID, err)
+		}
+		protections[trackID] = tp
+		ivs[trackID] = iv
+		psshBoxes = append(psshBoxes, cfg.PsshBoxes...)
+	}
+	for _, seg := range f.Segments {
+		for _, frag := range seg.Fragments {
+			if len(frag.Chunks) > 0 {
+				return fmt.Errorf("fragments with CMAF chunks not supported")
+			}
+			for _, traf := range frag.Moof.Trafs {
+				if _, ok := protections[traf.Tfhd.TrackID]; ok && len(traf.Truns) != 1 {
+					return fmt.Errorf("track %d, fragment %d: only one trun supported", traf.Tfhd.TrackID,
+						frag.Moof.Mfhd.SequenceNumber)
+				}
+			}
+		}
+	}
+
+	for _, tp := range protections {
+		tp.apply()
+	}
+	for _, pssh := range psshBoxes {
+		moov.AddChild(pssh)
+	}
+	for _, seg := range f.Segments {
+		for _, frag := range seg.Fragments {
+			for _, traf := range frag.Moof.Trafs {
+				trackID := traf.Tfhd.TrackID
+				tp, ok := protections[trackID]
+				if !ok {
+					continue
+				}
+				nextIV, err := encryptTraf(frag, traf, perTrack[trackID].Key, ivs[trackID], tp.ipd)
+				if err != nil {
+					return fmt.Errorf("track %d, fragment %d: %w", trackID, frag.Moof.Mfhd.SequenceNumber, err)
+				}
+				if tp.ipd.Scheme == "cenc" {
+					ivs[trackID] = nextIV
+				}
+			}
+			setSaioOffsets(frag.Moof)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,189 @@
+package mp4
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+// createTwoTrackFile returns a fragmented file with video track 2 and audio track 1
+// in one fragment, and the samples of each track.
+func createTwoTrackFile(t *testing.T) (*File, map[uint32][]FullSample) {
+	t.Helper()
+	videoInit, err := ReadMP4File("testdata/init.mp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	audioInit, err := ReadMP4File("testdata/aac_init.mp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	init := videoInit.Init
+	init.Moov.AddChild(audioInit.Init.Moov.Trak)
+	init.Moov.Mvex.AddChild(audioInit.Init.Moov.Mvex.Trex)
+	samples := make(map[uint32][]FullSample)
+	for trackID, segFile := range map[uint32]string{2: "testdata/1.m4s", 1: "testdata/aac_1.m4s"} {
+		seg, err := ReadMP4File(segFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		trex, _ := init.Moov.Mvex.GetTrex(trackID)
+		samples[trackID], err = seg.Segments[0].Fragments[0].GetFullSamples(trex)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	frag, err := CreateMultiTrackFragment(1, []uint32{2, 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, trackID := range []uint32{2, 1} {
+		for _, s := range samples[trackID] {
+			if err := frag.AddFullSampleToTrack(s, trackID); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	var buf bytes.Buffer
+	if err := init.Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if err := frag.Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	raw := buf.Bytes()
+	f, err := DecodeFile(bytes.NewBuffer(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Decode a second time to get sample data not shared with f
+	orig, err := DecodeFile(bytes.NewBuffer(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for trackID := range samples {
+		trex, _ := orig.Init.Moov.Mvex.GetTrex(trackID)
+		samples[trackID], err = orig.Segments[0].Fragments[0].GetFullSamples(trex)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return f, samples
+}
+
+func TestEncryptFilePerTrack(t *testing.T) {
+	videoKey, _ := hex.DecodeString("00112233445566778899aabbccddeeff")
+	audioKey, _ := hex.DecodeString("ffeeddccbbaa99887766554433221100")
+	iv, _ := hex.DecodeString("7766554433221100")
+	videoKID, _ := NewUUIDFromString("11112222333344445555666677778888")
+	audioKID, _ := NewUUIDFromString("88887777666655554444333322221111")
+
+	cases := []struct {
+		desc     string
+		perTrack map[uint32]TrackEncConfig
+	}{
+		{"clear audio", map[uint32]TrackEncConfig{
+			2: {Scheme: "cenc", KID: videoKID, Key: videoKey, IV: iv},
+		}},
+		{"different KIDs and schemes", map[uint32]TrackEncConfig{
+			2: {Scheme: "cenc", KID: videoKID, Key: videoKey, IV: iv},
+			1: {Scheme: "cbcs", KID: audioKID, Key: audioKey, IV: append(iv, iv...)},
+		}},
+	}
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			f, wantedSamples := createTwoTrackFile(t)
+			if err := EncryptFile(f, c.perTrack); err != nil {
+				t.Fatal(err)
+			}
+			var buf bytes.Buffer
+			if err := f.Encode(&buf); err != nil {
+				t.Fatal(err)
+			}
+			enc, err := DecodeFile(&buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, trak := range enc.Init.Moov.Traks {
+				trackID := trak.Tkhd.TrackID
+				_, isEncrypted := c.perTrack[trackID]
+				if gotEncrypted := encryptedSampleEntrySinf(trak) != nil; gotEncrypted != isEncrypted {
+					t.Errorf("track %d: encrypted sample entry %t", trackID, gotEncrypted)
+				}
+			}
+			encFrag := enc.Segments[0].Fragments[0]
+			videoTrex, _ := enc.Init.Moov.Mvex.GetTrex(2)
+			encSamples, err := encFrag.GetFullSamples(videoTrex)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if bytes.Equal(encSamples[0].Data, wantedSamples[2][0].Data) {
+				t.Error("video sample not encrypted")
+			}
+			keys := make(map[KID][]byte)
+			for _, cfg := range c.perTrack {
+				kid, _ := NewKIDFromUUID(cfg.KID)
+				keys[kid] = cfg.Key
+			}
+			di, err := DecryptInit(enc.Init)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := DecryptSegmentWithKeys(enc.Segments[0], di, keys); err != nil {
+				t.Fatal(err)
+			}
+			for trackID, wanted := range wantedSamples {
+				trex, _ := enc.Init.Moov.Mvex.GetTrex(trackID)
+				got, err := encFrag.GetFullSamples(trex)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(got) != len(wanted) {
+					t.Fatalf("track %d: got %d samples instead of %d", trackID, len(got), len(wanted))
+				}
+				for i := range got {
+					if !bytes.Equal(got[i].Data, wanted[i].Data) {
+						t.Errorf("track %d: sample %d differs after decryption", trackID, i+1)
+						break
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestEncryptFileErrors(t *testing.T) {
+	key, _ := hex.DecodeString("00112233445566778899aabbccddeeff")
+	iv, _ := hex.DecodeString("7766554433221100")
+	f, _ := createTwoTrackFile(t)
+	if err := EncryptFile(f, map[uint32]TrackEncConfig{3: {Scheme: "cenc", Key: key, IV: iv}}); err == nil {
+		t.Error("no error for unknown track")
+	}
+	if err := EncryptFile(f, map[uint32]TrackEncConfig{2: {Scheme: "cenc", Key: key[:8], IV: iv}}); err == nil {
+		t.Error("no error for short key")
+	}
+	if err := EncryptFile(f, map[uint32]TrackEncConfig{1: {Scheme: "cens", Key: key, IV: iv}}); err == nil {
+		t.Error("no error for unsupported scheme")
+	}
+	// An error for one track must not leave the other track protected
+	if err := EncryptFile(f, map[uint32]TrackEncConfig{
+		2: {Scheme: "cenc", Key: key, IV: iv},
+		1: {Scheme: "cenc", Key: key, IV: iv[:4]},
+	}); err == nil {
+		t.Error("no error for short iv")
+	}
+	f.Segments[0].Fragments[0].Chunks = []*CMAFChunk{{}}
+	if err := EncryptFile(f, map[uint32]TrackEncConfig{2: {Scheme: "cenc", Key: key, IV: iv}}); err == nil {
+		t.Error("no error for CMAF chunks")
+	}
+	for _, trak := range f.Init.Moov.Traks {
+		if sinf := encryptedSampleEntrySinf(trak); sinf != nil {
+			t.Errorf("track %d protected after failed encryption", trak.Tkhd.TrackID)
+		}
+	}
+	for _, traf := range f.Segments[0].Fragments[0].Moof.Trafs {
+		if traf.Senc != nil {
+			t.Errorf("track %d encrypted after failed encryption", traf.Tfhd.TrackID)
+		}
+	}
+}
@@ -12,9 +12,13 @@ type CTooBox struct {
 	Children []Box
 }
 
-// DataBox - data box used by ffmpeg for providing information.
+// DataBox - data box used by ffmpeg and iTunes metadata items for providing information.
+// DataType is the type indicator, where the well-known types are given by the DataType constants,
+// and Locale is 0 for the default locale.
 type DataBox struct {
-	Data []byte
+	DataType uint32
+	Locale   uint32
+	Data     []byte
 }
 
 // DecodeData - decode Data (from mov_write_string_data_tag in movenc.c in ffmpeg)
@@ -29,9 +33,11 @@ func DecodeData(hdr BoxHeader, startPos uint64, r io.Reader) (Box, error) {
 
 // DecodeDataSR - decode Data (from mov_write_string_data_tag in movenc.c in ffmpeg)
 func DecodeDataSR(hdr BoxHeader, startPos uint64, sr bits.SliceReader) (Box, error) {
-	_ = sr.ReadUint32() // Should be 1
-	_ = sr.ReadUint32() // Should be 0
-	return &DataBox{sr.ReadBytes(hdr.payloadLen() - 8)}, sr.AccError()
+	b := &DataBox{}
+	b.DataType = sr.ReadUint32()
+	b.Locale = sr.ReadUint32()
+	b.Data = sr.ReadBytes(hdr.payloadLen() - 8)
+	return b, sr.AccError()
 }
 
 // Type - box type
@@ -61,8 +67,8 @@ func (b *DataBox) EncodeSW(sw bits.SliceWriter) error {
 	if err != nil {
 		return err
 	}
-	sw.WriteUint32(0x00000001)
-	sw.WriteUint32(0x00000000)
+	sw.WriteUint32(b.DataType)
+	sw.WriteUint32(b.Locale)
 	sw.WriteBytes(b.Data)
 	return sw.AccError()
 }
@@ -70,6 +76,11 @@ func (b *DataBox) EncodeSW(sw bits.SliceWriter) error {
 // Info - box-specific Info
 func (b *DataBox) Info(w io.Writer, specificBoxLevels, indent, indentStep string) error {
 	bd := newInfoDumper(w, indent, b, -1, 0)
-	bd.write(" - data: %s", string(b.Data))
+	bd.write(" - dataType: %d", b.DataType)
+	if b.DataType == DataTypeUTF8 {
+		bd.write(" - data: %s", string(b.Data))
+	} else {
+		bd.write(" - data: %d bytes", len(b.Data))
+	}
 	return bd.err
 }
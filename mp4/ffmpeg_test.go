@@ -33,6 +33,6 @@ func TestDecodeFFMpeg(t *testing.T) {
 
 func TestEncodeData(t *testing.T) {
 	data := []byte("dummy")
-	db := &DataBox{Data: data}
+	db := &DataBox{DataType: DataTypeUTF8, Data: data}
 	boxDiffAfterEncodeAndDecode(t, db)
 }
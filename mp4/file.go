@@ -1,8 +1,13 @@
 package mp4
 
 import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
+	"math"
 	"os"
 	"strings"
 
@@ -38,6 +43,8 @@ type File struct {
 	FragEncMode  EncFragFileMode // Determine how fragmented files are encoded
 	EncOptimize  EncOptimize     // Bit field with optimizations being done at encoding
 	fileDecFlags DecFileFlags    // Bit field with flags for decoding
+	maxBoxSize   uint64          // Max size of top-level boxes read into memory. 0 means no limit
+	boxFilter    map[string]bool // Top-level box types to decode. nil means all
 	isFragmented bool
 	fileDecMode  DecFileMode
 }
@@ -72,10 +79,23 @@ const (
 	DecISMFlag DecFileFlags = (1 << 0)
 	// DecStartOnMoof starts a segment at each moof boundary
 	// This is provided no styp, or sidx/mfra box gives other information
-	DecStartOnMoof = (1 << 1)
-	// if no styp box, or sidx/mfra strudture
+	DecStartOnMoof = (1 << 1) // if no styp box, or sidx/mfra strudture
+	// DecInitSegmentOnly stops decoding directly after the moov box, leaving the reader
+	// positioned after it. Decoding also stops at the first moof, styp, or sidx box. The reader
+	// is then positioned at that box if it is an io.Seeker, and after its header otherwise.
+	// To not read an mdat box before moov, combine with DecModeLazyMdat.
+	DecInitSegmentOnly DecFileFlags = (1 << 2)
+	// DecCMAFChunks adds a moof box whose first sample is non-sync, together with its mdat and
+	// preceding emsg boxes, as a CMAFChunk to the previous fragment instead of starting a new fragment.
+	DecCMAFChunks DecFileFlags = (1 << 3)
+	// DecStrictErrors returns an error if a box cannot be decoded, instead of the boxes decoded
+	// before it. Use DecodeFilePartial to accept a truncated last box.
+	DecStrictErrors DecFileFlags = (1 << 4)
 )
 
+// DefaultMaxBoxSize - default max size of a top-level box read into memory by DecodeFile (16GiB)
+const DefaultMaxBoxSize uint64 = 1 << 34
+
 // EncOptimize - encoder optimization mode
 type EncOptimize uint32
 
@@ -84,6 +104,8 @@ const (
 	OptimizeNone = EncOptimize(0)
 	// OptimizeTrun - optimize trun box by moving default values to tfhd
 	OptimizeTrun = EncOptimize(1 << 0)
+	// OptimizeTruns - optimize all trun boxes of a traf by TrafBox.OptimizeTruns
+	OptimizeTruns = EncOptimize(1 << 1)
 )
 
 func (eo EncOptimize) String() string {
@@ -92,6 +114,9 @@ func (eo EncOptimize) String() string {
 	if eo&OptimizeTrun != 0 {
 		optList = append(optList, "OptimizeTrun")
 	}
+	if eo&OptimizeTruns != 0 {
+		optList = append(optList, "OptimizeTruns")
+	}
 	if len(optList) > 0 {
 		msg = strings.Join(optList, " | ")
 	}
@@ -104,6 +129,7 @@ func NewFile() *File {
 		FragEncMode: EncModeSegment,
 		EncOptimize: OptimizeNone,
 		fileDecMode: DecModeNormal,
+		maxBoxSize:  DefaultMaxBoxSize,
 		Children:    make([]Box, 0, 8), // Reasonable number of children
 	}
 }
@@ -147,6 +173,71 @@ func (f *File) AddMediaSegment(m *MediaSegment) {
 // DecodeFile - parse and decode a file from reader r with optional file options.
 // For example, the file options overwrite the default decode or encode mode.
 func DecodeFile(r io.Reader, options ...Option) (*File, error) {
+	return DecodeFileCtx(context.Background(), r, options...)
+}
+
+// DecodeFilePartial - parse and decode the complete top-level boxes at the start of data, like
+// DecodeFile, but tolerate that the last box is truncated. This is useful for growing segments,
+// where a moof box may be available, but only part of its mdat box.
+// The truncated box is not decoded, and needMoreBytes is the number of bytes missing to complete it
+// (or its header). needMoreBytes is 0 if all of data has been decoded.
+// The decoded boxes cover the first f.Size() bytes of data, so decoding can be retried when
+// at least needMoreBytes more bytes are available.
+// A box with size 0 is treated as extending to the end of data.
+// A fragment whose mdat box is truncated has Mdat nil, and GetFullSamples returns an error for it.
+// Errors in the complete boxes are always returned, as with DecStrictErrors.
+func DecodeFilePartial(data []byte, options ...Option) (f *File, needMoreBytes uint64, err error) {
+	end := uint64(0)
+	dataLen := uint64(len(data))
+	for end < dataLen {
+		size, hdrLen := uint64(0), uint64(boxHeaderSize)
+		if dataLen-end >= boxHeaderSize {
+			size = uint64(binary.BigEndian.Uint32(data[end : end+4]))
+			if size == 1 {
+				hdrLen += largeSizeLen
+			}
+		}
+		if dataLen-end < hdrLen {
+			needMoreBytes = hdrLen - (dataLen - end)
+			break
+		}
+		switch size {
+		case 0:
+			size = dataLen - end
+		case 1:
+			size = binary.BigEndian.Uint64(data[end+8 : end+16])
+		}
+		if size < hdrLen {
+			return nil, 0, fmt.Errorf("box header size %d exceeds box size %d at pos %d", hdrLen, size, end)
+		}
+		if size > dataLen-end {
+			needMoreBytes = size - (dataLen - end)
+			break
+		}
+		end += size
+	}
+	strict := func(f *File) { f.fileDecFlags |= DecStrictErrors }
+	opts := append(append([]Option{}, options...), strict)
+	f, err = DecodeFile(bytes.NewReader(data[:end]), opts...)
+	if err != nil {
+		return nil, 0, err
+	}
+	return f, needMoreBytes, nil
+}
+
+// isSegmentStartBox returns true for the box types that may start a media segment.
+func isSegmentStartBox(boxType string) bool {
+	switch boxType {
+	case "moof", "styp", "sidx":
+		return true
+	default:
+		return false
+	}
+}
+
+// DecodeFileCtx - parse and decode a file like DecodeFile, but stop and return ctx.Err()
+// if ctx is done. The context is checked before each top-level box is decoded.
+func DecodeFileCtx(ctx context.Context, r io.Reader, options ...Option) (*File, error) {
 	f := NewFile()
 
 	// apply options to change the default decode or encode mode
@@ -155,6 +246,8 @@ func DecodeFile(r io.Reader, options ...Option) (*File, error) {
 	var boxStartPos uint64 = 0
 	lastBoxType := ""
 
+	prevSkipped := false
+
 	var rs io.ReadSeeker
 	if f.fileDecMode == DecModeLazyMdat {
 		ok := false
@@ -173,25 +266,59 @@ func DecodeFile(r io.Reader, options ...Option) (*File, error) {
 
 LoopBoxes:
 	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
 		var box Box
-		var err error
-		switch f.fileDecMode {
-		case DecModeLazyMdat:
-			box, err = DecodeBoxLazyMdat(boxStartPos, rs)
-		case DecModeNormal:
-			box, err = DecodeBox(boxStartPos, r)
-		default:
-			return nil, fmt.Errorf("unknown DecFileMode=%d", f.fileDecMode)
+		hdr, br, err := decodeHeaderToEnd(r, f.maxBoxSize)
+		if errors.Is(err, errMaxBoxSize) {
+			return nil, err
+		}
+		if err == nil && (f.fileDecFlags&DecInitSegmentOnly) != 0 && isSegmentStartBox(hdr.Name) {
+			if s, ok := r.(io.Seeker); ok {
+				if _, err = s.Seek(-int64(hdr.Hdrlen), io.SeekCurrent); err != nil {
+					return nil, err
+				}
+			}
+			break LoopBoxes
+		}
+		if err == nil {
+			skip := f.skipBox(hdr, prevSkipped)
+			if !skip {
+				err = f.checkBoxSize(hdr)
+				if err != nil {
+					return nil, err
+				}
+			}
+			switch {
+			case skip:
+				box, err = skipBoxBody(hdr, br)
+			case f.fileDecMode == DecModeLazyMdat:
+				box, err = decodeBoxBodyLazyMdat(hdr, boxStartPos, rs)
+			case f.fileDecMode == DecModeNormal:
+				box, err = decodeBoxBody(hdr, boxStartPos, br)
+			default:
+				return nil, fmt.Errorf("unknown DecFileMode=%d", f.fileDecMode)
+			}
 		}
 		if err == io.EOF {
 			break LoopBoxes
 		}
 		if err != nil {
-			//return nil, err
+			if (f.fileDecFlags & DecStrictErrors) != 0 {
+				return nil, fmt.Errorf("decode box at pos %d after %s box: %w", boxStartPos, lastBoxType, err)
+			}
 			fmt.Printf("error: %v, last box type=%s\n", err, lastBoxType) // FIXME should not consume the error here
 			break LoopBoxes                                               // return what we've parsed so far
 		}
 		boxType, boxSize := box.Type(), box.Size()
+		prevSkipped = isSkippedBox(box)
+		if prevSkipped {
+			f.Children = append(f.Children, box)
+			lastBoxType = boxType
+			boxStartPos += boxSize
+			continue LoopBoxes
+		}
 		switch boxType {
 		case "mdat":
 			if f.isFragmented {
@@ -235,6 +362,9 @@ LoopBoxes:
 		f.AddChild(box, boxStartPos)
 		lastBoxType = boxType
 		boxStartPos += boxSize
+		if boxType == "moov" && (f.fileDecFlags&DecInitSegmentOnly) != 0 {
+			break LoopBoxes
+		}
 	}
 	f.tfra = nil // Not needed anymore
 	return f, nil
@@ -260,6 +390,11 @@ func (f *File) AddChild(child Box, boxStartPos uint64) {
 			f.isFragmented = true
 			f.Init = NewMP4Init()
 			f.Init.AddChild(f.Ftyp)
+			for _, c := range f.Children {
+				if free, ok := c.(*FreeBox); ok {
+					f.Init.AddChild(free)
+				}
+			}
 			f.Init.AddChild(f.Moov)
 		}
 	case *SidxBox:
@@ -299,6 +434,9 @@ func (f *File) AddChild(child Box, boxStartPos uint64) {
 		moof.StartPos = boxStartPos
 		f.startSegmentIfNeeded(moof, boxStartPos)
 		currSeg := f.LastSegment()
+		if (f.fileDecFlags&DecCMAFChunks) != 0 && f.addCMAFChunk(currSeg, moof, boxStartPos) {
+			break
+		}
 		lastFrag := currSeg.LastFragment()
 		if lastFrag == nil || lastFrag.Moof != nil {
 			currSeg.AddFragment(&Fragment{StartPos: boxStartPos})
@@ -312,14 +450,76 @@ func (f *File) AddChild(child Box, boxStartPos uint64) {
 			}
 		} else {
 			currentFragment := f.LastSegment().LastFragment()
-			currentFragment.AddChild(box)
+			if n := len(currentFragment.Chunks); n > 0 {
+				currentFragment.Chunks[n-1].AddChild(box)
+			} else {
+				currentFragment.AddChild(box)
+			}
 		}
 	case *MfraBox:
 		f.Mfra = box
+	case *FreeBox:
+		f.addFreeBox(box, boxStartPos)
 	}
 	f.Children = append(f.Children, child)
 }
 
+// addFreeBox adds a free or skip box to the init segment or the current fragment or chunk,
+// so that it is kept when a fragmented file is encoded in EncModeSegment.
+func (f *File) addFreeBox(free *FreeBox, boxStartPos uint64) {
+	switch {
+	case len(f.Segments) > 0:
+		seg := f.LastSegment()
+		if len(seg.Fragments) == 0 {
+			seg.AddFragment(&Fragment{StartPos: boxStartPos})
+		}
+		frag := seg.LastFragment()
+		if n := len(frag.Chunks); n > 0 {
+			frag.Chunks[n-1].AddChild(free)
+		} else {
+			frag.AddChild(free)
+		}
+	case f.Init != nil:
+		f.Init.AddChild(free)
+	}
+}
+
+// addCMAFChunk adds moof as a new chunk of the previous fragment in seg if moof continues it.
+// Boxes in a last fragment without moof, like emsg, are moved to the chunk.
+func (f *File) addCMAFChunk(seg *MediaSegment, moof *MoofBox, boxStartPos uint64) bool {
+	nrFrags := len(seg.Fragments)
+	if nrFrags == 0 {
+		return false
+	}
+	var pending *Fragment // Started by boxes before moof
+	prev := seg.Fragments[nrFrags-1]
+	if prev.Moof == nil {
+		if nrFrags == 1 {
+			return false
+		}
+		pending = prev
+		prev = seg.Fragments[nrFrags-2]
+	}
+	var trex *TrexBox
+	if f.Init != nil && f.Init.Moov.Mvex != nil && moof.Traf != nil {
+		trex, _ = f.Init.Moov.Mvex.GetTrex(moof.Traf.Tfhd.TrackID)
+	}
+	if !isChunkContinuation(moof, trex) {
+		return false
+	}
+	chunk := &CMAFChunk{StartPos: boxStartPos}
+	if pending != nil {
+		chunk.StartPos = pending.StartPos
+		for _, c := range pending.Children {
+			chunk.AddChild(c)
+		}
+		seg.Fragments = seg.Fragments[:nrFrags-1]
+	}
+	chunk.AddChild(moof)
+	prev.AddChunk(chunk)
+	return true
+}
+
 // startSegmentIfNeeded starts a new segment if there is none or if position match with sidx of tfra.
 func (f *File) startSegmentIfNeeded(b Box, boxStartPos uint64) {
 	segStart := false
@@ -442,7 +642,7 @@ func (f *File) Encode(w io.Writer) error {
 				}
 			}
 			for _, seg := range f.Segments {
-				if f.EncOptimize&OptimizeTrun != 0 {
+				if f.EncOptimize != OptimizeNone {
 					seg.EncOptimize = f.EncOptimize
 				}
 				err := seg.Encode(w)
@@ -499,7 +699,7 @@ func (f *File) EncodeSW(sw bits.SliceWriter) error {
 				}
 			}
 			for _, seg := range f.Segments {
-				if f.EncOptimize&OptimizeTrun != 0 {
+				if f.EncOptimize != OptimizeNone {
 					seg.EncOptimize = f.EncOptimize
 				}
 				err := seg.EncodeSW(sw)
@@ -507,6 +707,12 @@ func (f *File) EncodeSW(sw bits.SliceWriter) error {
 					return err
 				}
 			}
+			if f.Mfra != nil {
+				err := f.Mfra.EncodeSW(sw)
+				if err != nil {
+					return err
+				}
+			}
 		case EncModeBoxTree:
 			for _, b := range f.Children {
 				err := b.EncodeSW(sw)
@@ -553,6 +759,58 @@ func (f *File) IsFragmented() bool {
 	return f.isFragmented
 }
 
+// Brands returns the major brand, minor version, and compatible brands of the ftyp box, or of the
+// styp box of the first segment if there is no ftyp box, as for a single media segment.
+// An empty major brand is returned if neither is present.
+func (f *File) Brands() (major string, minor uint32, compatible []string) {
+	switch {
+	case f.Ftyp != nil:
+		return f.Ftyp.MajorBrand(), f.Ftyp.MinorVersion(), f.Ftyp.CompatibleBrands()
+	case len(f.Segments) > 0 && f.Segments[0].Styp != nil:
+		styp := f.Segments[0].Styp
+		return styp.MajorBrand(), styp.MinorVersion(), styp.CompatibleBrands()
+	default:
+		return "", 0, nil
+	}
+}
+
+// Samples returns an iterator over all samples of a track in a fragmented file in decode order.
+// The samples of all segments and fragments are returned with times and data as FullSample,
+// and the trex defaults of the init segment are applied.
+// The returned function has the signature of iter.Seq2[FullSample, error], so it can be used
+// with range-over-func in Go 1.23 and later. Iteration stops after the first error.
+func (f *File) Samples(trackID uint32) func(yield func(FullSample, error) bool) {
+	return func(yield func(FullSample, error) bool) {
+		if !f.isFragmented {
+			yield(FullSample{}, fmt.Errorf("only available for fragmented files"))
+			return
+		}
+		if f.Init == nil || f.Init.Moov.Mvex == nil {
+			yield(FullSample{}, fmt.Errorf("no init segment with mvex"))
+			return
+		}
+		trex, ok := f.Init.Moov.Mvex.GetTrex(trackID)
+		if !ok {
+			yield(FullSample{}, fmt.Errorf("no trex for trackID %d", trackID))
+			return
+		}
+		for _, seg := range f.Segments {
+			for _, frag := range seg.Fragments {
+				samples, err := frag.GetFullSamples(trex)
+				if err != nil {
+					yield(FullSample{}, err)
+					return
+				}
+				for _, s := range samples {
+					if !yield(s, nil) {
+						return
+					}
+				}
+			}
+		}
+	}
+}
+
 // ApplyOptions - applies options for decoding or encoding a file
 func (f *File) ApplyOptions(opts ...Option) {
 	for _, opt := range opts {
@@ -574,6 +832,32 @@ func WithDecodeMode(mode DecFileMode) Option {
 	return func(f *File) { f.fileDecMode = mode }
 }
 
+// WithLazyMdat sets decode mode to DecModeLazyMdat so that mdat data is not read into memory
+func WithLazyMdat() Option {
+	return WithDecodeMode(DecModeLazyMdat)
+}
+
+// WithMaxBoxSize sets the max size of top-level boxes read into memory during decoding.
+// A larger size in a box header results in an error before the box is read.
+// Lazily decoded mdat boxes are not limited. 0 means no limit. The default is DefaultMaxBoxSize.
+func WithMaxBoxSize(maxSize uint64) Option {
+	return func(f *File) { f.maxBoxSize = maxSize }
+}
+
+// WithBoxFilter sets the top-level box types, like emsg, prft, and styp, to decode.
+// Other boxes are skipped without reading their payload, by seeking if the reader is an
+// io.Seeker, and are added as UnknownBox stubs with only type and size.
+// An mdat box after a skipped moof box is also skipped.
+// Skipped boxes are only present in Children, so they result in an encode error in EncModeBoxTree.
+func WithBoxFilter(types []string) Option {
+	return func(f *File) {
+		f.boxFilter = make(map[string]bool, len(types))
+		for _, t := range types {
+			f.boxFilter[t] = true
+		}
+	}
+}
+
 // WithDecodeFlags sets up DecodeFlags
 func WithDecodeFlags(flags DecFileFlags) Option {
 	return func(f *File) { f.fileDecFlags = flags }
@@ -607,6 +891,10 @@ func (f *File) CopySampleData(w io.Writer, rs io.ReadSeeker, trak *TrakBox,
 	default:
 		return fmt.Errorf("neither stco nor co64 available")
 	}
+	sizes := stbl.sampleSizes()
+	if sizes == nil {
+		return fmt.Errorf("neither stsz nor stz2 available")
+	}
 	var startNr, endNr uint32
 	var offset uint64
 	workPos := 0
@@ -620,7 +908,7 @@ func (f *File) CopySampleData(w io.Writer, rs io.ReadSeeker, trak *TrakBox,
 		}
 		if i == 0 {
 			for sNr := chunk.StartSampleNr; sNr < startSampleNr; sNr++ {
-				offset += uint64(stbl.Stsz.GetSampleSize(int(sNr)))
+				offset += uint64(sizes.GetSampleSize(int(sNr)))
 			}
 			startNr = startSampleNr
 		}
@@ -630,7 +918,7 @@ func (f *File) CopySampleData(w io.Writer, rs io.ReadSeeker, trak *TrakBox,
 		}
 		var size int64
 		for sNr := startNr; sNr <= endNr; sNr++ {
-			size += int64(stbl.Stsz.GetSampleSize(int(sNr)))
+			size += int64(sizes.GetSampleSize(int(sNr)))
 		}
 		if mdat.IsLazy() {
 			_, err := rs.Seek(int64(offset), io.SeekStart)
@@ -695,6 +983,260 @@ func (f *File) CopySampleData(w io.Writer, rs io.ReadSeeker, trak *TrakBox,
 	return nil
 }
 
+// ReadSamples reads samples startSampleNr to endSampleNr (one-based and inclusive) from a track in a progressive file.
+// The sample data is read chunk by chunk using the stbl tables. In lazy mdat mode, rs is used to seek and read
+// only the needed byte ranges, while rs can be nil if the mdat data is in memory.
+func (f *File) ReadSamples(rs io.ReadSeeker, trak *TrakBox, startSampleNr, endSampleNr uint32) ([]FullSample, error) {
+	if f.isFragmented {
+		return nil, fmt.Errorf("only available for progressive files")
+	}
+	mdat := f.Mdat
+	if mdat == nil {
+		return nil, fmt.Errorf("no mdat box")
+	}
+	samples, err := trak.GetSampleData(startSampleNr, endSampleNr)
+	if err != nil {
+		return nil, err
+	}
+	ranges, err := trak.GetRangesForSampleInterval(startSampleNr, endSampleNr)
+	if err != nil {
+		return nil, err
+	}
+	decTime, _ := trak.Mdia.Minf.Stbl.Stts.GetDecodeTime(startSampleNr)
+	fullSamples := make([]FullSample, 0, len(samples))
+	idx := 0
+	for _, dr := range ranges {
+		data, err := mdat.ReadData(int64(dr.Offset), int64(dr.Size), rs)
+		if err != nil {
+			return nil, fmt.Errorf("read data: %w", err)
+		}
+		pos := uint64(0)
+		for pos < dr.Size {
+			if idx >= len(samples) {
+				return nil, fmt.Errorf("data range larger than samples")
+			}
+			s := samples[idx]
+			end := pos + uint64(s.Size)
+			if end > dr.Size {
+				return nil, fmt.Errorf("sample %d beyond data range", startSampleNr+uint32(idx))
+			}
+			fullSamples = append(fullSamples, FullSample{
+				Sample:     s,
+				DecodeTime: decTime,
+				Data:       data[pos:end],
+			})
+			decTime += uint64(s.Dur)
+			pos = end
+			idx++
+		}
+	}
+	return fullSamples, nil
+}
+
+// FixChunkOffsets adjusts the stco/co64 chunk offsets of all tracks in a progressive file
+// after boxes before the mdat box, typically moov, have changed size.
+// The shift is the difference between the mdat position given by the current box sizes and
+// Mdat.StartPos, which is the position when the file was decoded.
+// An stco box is replaced by a co64 box if any offset would exceed 32 bits.
+// Mdat.StartPos is updated, so the method can be called again after further changes.
+func (f *File) FixChunkOffsets() error {
+	if f.isFragmented {
+		return fmt.Errorf("chunk offsets are only defined for progressive files")
+	}
+	if f.Moov == nil || f.Mdat == nil {
+		return fmt.Errorf("moov or mdat box missing")
+	}
+	for {
+		newPos, err := f.boxStartPos(f.Mdat)
+		if err != nil {
+			return err
+		}
+		delta := int64(newPos) - int64(f.Mdat.StartPos)
+		promoted := false
+		for _, trak := range f.Moov.Traks {
+			stbl := trak.Mdia.Minf.Stbl
+			if stbl.Stco == nil {
+				continue
+			}
+			for _, offset := range stbl.Stco.ChunkOffset {
+				if int64(offset)+delta > math.MaxUint32 {
+					stbl.promoteStcoToCo64()
+					promoted = true
+					break
+				}
+			}
+		}
+		if promoted {
+			continue // moov size changed, so recalculate delta
+		}
+		if delta == 0 {
+			return nil
+		}
+		// Check all tracks before changing any offset
+		for _, trak := range f.Moov.Traks {
+			stbl := trak.Mdia.Minf.Stbl
+			switch {
+			case stbl.Stco != nil:
+				for _, offset := range stbl.Stco.ChunkOffset {
+					if int64(offset)+delta < 0 {
+						return fmt.Errorf("track %d: negative chunk offset after shift", trak.Tkhd.TrackID)
+					}
+				}
+			case stbl.Co64 != nil:
+				for _, offset := range stbl.Co64.ChunkOffset {
+					if int64(offset)+delta < 0 {
+						return fmt.Errorf("track %d: negative chunk offset after shift", trak.Tkhd.TrackID)
+					}
+				}
+			}
+		}
+		for _, trak := range f.Moov.Traks {
+			stbl := trak.Mdia.Minf.Stbl
+			switch {
+			case stbl.Stco != nil:
+				for i, offset := range stbl.Stco.ChunkOffset {
+					stbl.Stco.ChunkOffset[i] = uint32(int64(offset) + delta)
+				}
+			case stbl.Co64 != nil:
+				for i, offset := range stbl.Co64.ChunkOffset {
+					stbl.Co64.ChunkOffset[i] = uint64(int64(offset) + delta)
+				}
+			}
+		}
+		f.Mdat.StartPos = newPos
+		return nil
+	}
+}
+
+// PadTo inserts a free box before the mdat box of a progressive file, so that mdat starts at offset.
+// A free box directly before the mdat box is resized instead, so PadTo can be called repeatedly.
+// The chunk offsets are updated with FixChunkOffsets.
+func (f *File) PadTo(offset uint64) error {
+	if f.isFragmented {
+		return fmt.Errorf("only available for progressive files")
+	}
+	if f.Moov == nil || f.Mdat == nil {
+		return fmt.Errorf("moov or mdat box missing")
+	}
+	idx := -1
+	for i, c := range f.Children {
+		if c == f.Mdat {
+			idx = i
+			break
+		}
+	}
+	if idx < 0 {
+		return fmt.Errorf("mdat not found among top-level boxes")
+	}
+	pos, err := f.boxStartPos(f.Mdat)
+	if err != nil {
+		return err
+	}
+	var oldFree Box
+	if idx > 0 {
+		if free, ok := f.Children[idx-1].(*FreeBox); ok {
+			oldFree = free
+			pos -= free.Size()
+		}
+	}
+	// moov grows if stco must be replaced by co64 for the new offsets
+	moovPos, err := f.boxStartPos(f.Moov)
+	if err != nil {
+		return err
+	}
+	if moovPos < pos {
+		pos += co64Growth(f.Moov, int64(offset)-int64(f.Mdat.StartPos))
+	}
+	if offset < pos {
+		return fmt.Errorf("offset %d is before end of boxes before mdat at %d", offset, pos)
+	}
+	padding := offset - pos
+	if padding > 0 && (padding < boxHeaderSize || padding > math.MaxUint32) {
+		return fmt.Errorf("cannot pad %d bytes with a free box", padding)
+	}
+
+	if oldFree != nil {
+		f.Children = append(f.Children[:idx-1], f.Children[idx:]...)
+		idx--
+	}
+	if padding > 0 {
+		f.Children = append(f.Children[:idx+1], f.Children[idx:]...)
+		f.Children[idx] = NewFreeBox(padding)
+	}
+	err = f.FixChunkOffsets()
+	if err != nil {
+		return err
+	}
+	if f.Mdat.StartPos != offset {
+		return fmt.Errorf("mdat at %d instead of %d after padding", f.Mdat.StartPos, offset)
+	}
+	return nil
+}
+
+// co64Growth returns the number of bytes moov grows if the stco boxes that cannot hold
+// their offsets shifted by delta are replaced by co64 boxes.
+func co64Growth(moov *MoovBox, delta int64) uint64 {
+	var growth uint64
+	for _, trak := range moov.Traks {
+		stco := trak.Mdia.Minf.Stbl.Stco
+		if stco == nil {
+			continue
+		}
+		for _, chunkOffset := range stco.ChunkOffset {
+			if int64(chunkOffset)+delta > math.MaxUint32 {
+				growth += 4 * uint64(len(stco.ChunkOffset))
+				break
+			}
+		}
+	}
+	return growth
+}
+
+// FastStart moves the moov box of a progressive file to directly after the ftyp box, if any,
+// so that playback can start before the whole file is downloaded. This is the qt-faststart operation.
+// The stco/co64 chunk offsets are updated with FixChunkOffsets, so stco may be replaced by co64.
+// Only files with a single mdat box are supported.
+func (f *File) FastStart() error {
+	if f.isFragmented {
+		return fmt.Errorf("only available for progressive files")
+	}
+	if f.Moov == nil || f.Mdat == nil {
+		return fmt.Errorf("moov or mdat box missing")
+	}
+	children := make([]Box, 0, len(f.Children))
+	insertPos := 0
+	nrMdats := 0
+	for _, c := range f.Children {
+		switch c.Type() {
+		case "moov":
+			continue
+		case "ftyp":
+			insertPos = len(children) + 1
+		case "mdat":
+			nrMdats++
+		}
+		children = append(children, c)
+	}
+	if nrMdats != 1 {
+		return fmt.Errorf("%d mdat boxes, but only one is supported", nrMdats)
+	}
+	children = append(children[:insertPos], append([]Box{f.Moov}, children[insertPos:]...)...)
+	f.Children = children
+	return f.FixChunkOffsets()
+}
+
+// boxStartPos returns the start position of a top-level box given current box sizes.
+func (f *File) boxStartPos(box Box) (uint64, error) {
+	var pos uint64
+	for _, c := range f.Children {
+		if c == box {
+			return pos, nil
+		}
+		pos += c.Size()
+	}
+	return 0, fmt.Errorf("box %s not found among top-level boxes", box.Type())
+}
+
 func (f *File) UpdateSidx(addIfNotExists, nonZeroEPT bool) error {
 
 	if !f.IsFragmented() {
@@ -762,6 +1304,9 @@ type segData struct {
 	baseDecodeTime   uint64
 	dur              uint32
 	size             uint32
+	startsWithSAP    uint8
+	sapType          uint8
+	sapDeltaTime     uint32
 }
 
 // findSegmentData returns a slice of segment media data using a reference track.
@@ -791,12 +1336,19 @@ func findSegmentData(segs []*MediaSegment, refTrak *TrakBox, trex *TrexBox) ([]s
 				}
 			}
 		}
+		startsWithSAP, sapType, sapDeltaTime, err := subsegmentSAP(seg.Fragments, trex)
+		if err != nil {
+			return nil, err
+		}
 		sd := segData{
 			startPos:         seg.StartPos,
 			presentationTime: uint64(int64(baseTime) + firstCompositionTimeOffest),
 			baseDecodeTime:   baseTime,
 			dur:              dur,
 			size:             uint32(seg.Size()),
+			startsWithSAP:    startsWithSAP,
+			sapType:          sapType,
+			sapDeltaTime:     sapDeltaTime,
 		}
 		segDatas = append(segDatas, sd)
 	}
@@ -820,8 +1372,9 @@ func fillSidx(sidx *SidxBox, refTrak *TrakBox, segDatas []segData, nonZeroEPT bo
 		sidx.SidxRefs = append(sidx.SidxRefs, SidxRef{
 			ReferencedSize:     size,
 			SubSegmentDuration: segData.dur,
-			StartsWithSAP:      1,
-			SAPType:            1,
+			SAPDeltaTime:       segData.sapDeltaTime,
+			StartsWithSAP:      segData.startsWithSAP,
+			SAPType:            segData.sapType,
 		})
 	}
 }
@@ -849,6 +1402,280 @@ func insertSidx(inFile *File, segDatas []segData, sidx *SidxBox) error {
 	return nil
 }
 
+// SidxSegment - byte range and time interval of a media segment referenced by top-level sidx boxes
+type SidxSegment struct {
+	// Index is the number of the segment in the references (0-based), which is the index
+	// in f.Segments for a file decoded from the start
+	Index     int
+	Offset    uint64 // Byte offset of the segment relative to file start
+	Size      uint64
+	StartTime uint64 // Presentation time in Timescale
+	Duration  uint64
+	Timescale uint32
+}
+
+// SidxSegments returns the media segments referenced by the top-level sidx boxes of a decoded file.
+// The references of f.Sidx are used, and references to other sidx boxes (reference_type 1) are
+// replaced by the references of those boxes, so they must also be in f.Sidxs.
+// Offsets are only available for decoded files, since they depend on sidx positions.
+func (f *File) SidxSegments() ([]SidxSegment, error) {
+	if f.Sidx == nil {
+		return nil, fmt.Errorf("no top-level sidx box")
+	}
+	var segs []SidxSegment
+	err := f.appendSidxSegments(&segs, f.Sidx, 0)
+	if err != nil {
+		return nil, err
+	}
+	return segs, nil
+}
+
+// appendSidxSegments appends the segments referenced by sidx and referenced sidx boxes to segs.
+func (f *File) appendSidxSegments(segs *[]SidxSegment, sidx *SidxBox, depth int) error {
+	if depth > len(f.Sidxs) {
+		return fmt.Errorf("sidx reference loop")
+	}
+	offset := sidx.AnchorPoint
+	startTime := sidx.EarliestPresentationTime
+	for _, ref := range sidx.SidxRefs {
+		if ref.ReferenceType == 1 {
+			subSidx := f.sidxAtPos(offset)
+			if subSidx == nil {
+				return fmt.Errorf("no decoded sidx box at referenced offset %d", offset)
+			}
+			err := f.appendSidxSegments(segs, subSidx, depth+1)
+			if err != nil {
+				return err
+			}
+		} else {
+			*segs = append(*segs, SidxSegment{
+				Index:     len(*segs),
+				Offset:    offset,
+				Size:      uint64(ref.ReferencedSize),
+				StartTime: startTime,
+				Duration:  uint64(ref.SubSegmentDuration),
+				Timescale: sidx.Timescale,
+			})
+		}
+		offset += uint64(ref.ReferencedSize)
+		startTime += uint64(ref.SubSegmentDuration)
+	}
+	return nil
+}
+
+// sidxAtPos returns the top-level sidx box starting at pos, or nil if there is none.
+func (f *File) sidxAtPos(pos uint64) *SidxBox {
+	for _, sidx := range f.Sidxs {
+		if sidx.AnchorPoint-sidx.FirstOffset-sidx.Size() == pos {
+			return sidx
+		}
+	}
+	return nil
+}
+
+// SegmentForTime returns the media segment referenced by the top-level sidx boxes with
+// a time interval containing the presentation time t given in the timescale of f.Sidx.
+// The byte range of the segment can then be used for range-based fetching.
+func (f *File) SegmentForTime(t uint64) (SidxSegment, error) {
+	segs, err := f.SidxSegments()
+	if err != nil {
+		return SidxSegment{}, err
+	}
+	for _, seg := range segs {
+		segTime := t
+		if seg.Timescale != f.Sidx.Timescale {
+			segTime = t * uint64(seg.Timescale) / uint64(f.Sidx.Timescale)
+		}
+		if segTime >= seg.StartTime && segTime < seg.StartTime+seg.Duration {
+			return seg, nil
+		}
+	}
+	return SidxSegment{}, fmt.Errorf("no segment contains time %d", t)
+}
+
+// BuildMfra creates an mfra box with one tfra box per track and sets it as f.Mfra.
+// The tfra boxes have an entry with presentation time, moof offset, and traf, trun,
+// and sample numbers for each sync sample. The moof offsets correspond to the output
+// of Encode with the current FragEncMode, so BuildMfra should be called after
+// all other changes. If EncOptimize has OptimizeTrun or OptimizeTruns set, the optimization is done here.
+func (f *File) BuildMfra() error {
+	if !f.isFragmented || f.Init == nil {
+		return fmt.Errorf("not a fragmented file with init segment")
+	}
+	moofOffsets, err := f.moofOffsets()
+	if err != nil {
+		return err
+	}
+	mfra := &MfraBox{}
+	for _, trak := range f.Init.Moov.Traks {
+		trackID := trak.Tkhd.TrackID
+		var trex *TrexBox
+		if f.Init.Moov.Mvex != nil {
+			trex, _ = f.Init.Moov.Mvex.GetTrex(trackID)
+		}
+		tfra := &TfraBox{TrackID: trackID}
+		for _, seg := range f.Segments {
+			for _, frag := range seg.Fragments {
+				tfra.addSyncSampleEntries(frag.Moof, moofOffsets[frag.Moof], trex)
+				for _, chunk := range frag.Chunks {
+					tfra.addSyncSampleEntries(chunk.Moof, moofOffsets[chunk.Moof], trex)
+				}
+			}
+		}
+		var maxTrafNr, maxTrunNr, maxSampleNr uint32
+		for _, te := range tfra.Entries {
+			if te.Time > math.MaxUint32 || te.MoofOffset > math.MaxUint32 {
+				tfra.Version = 1
+			}
+			if te.TrafNumber > maxTrafNr {
+				maxTrafNr = te.TrafNumber
+			}
+			if te.TrunNumber > maxTrunNr {
+				maxTrunNr = te.TrunNumber
+			}
+			if te.SampleNumber > maxSampleNr {
+				maxSampleNr = te.SampleNumber
+			}
+		}
+		tfra.LengthSizeOfTrafNum = lengthSizeMinusOne(maxTrafNr)
+		tfra.LengthSizeOfTrunNum = lengthSizeMinusOne(maxTrunNr)
+		tfra.LengthSizeOfSampleNum = lengthSizeMinusOne(maxSampleNr)
+		_ = mfra.AddChild(tfra)
+	}
+	mfro := &MfroBox{}
+	_ = mfra.AddChild(mfro)
+	mfro.ParentSize = uint32(mfra.Size())
+
+	replaced := false
+	for i, c := range f.Children {
+		if _, ok := c.(*MfraBox); ok {
+			f.Children[i] = mfra
+			replaced = true
+			break
+		}
+	}
+	if !replaced {
+		f.Children = append(f.Children, mfra)
+	}
+	f.Mfra = mfra
+	return nil
+}
+
+// skipBox returns true if the box with header hdr should be skipped given the box filter.
+// An mdat box is skipped if the previous box was skipped, since it belongs to a skipped moof box.
+func (f *File) skipBox(hdr BoxHeader, prevSkipped bool) bool {
+	if f.boxFilter == nil {
+		return false
+	}
+	if hdr.Name == "mdat" && prevSkipped && f.isFragmented {
+		return true
+	}
+	return !f.boxFilter[hdr.Name]
+}
+
+// skipBoxBody skips the payload of a box and returns a stub, seeking if r is an io.Seeker.
+func skipBoxBody(hdr BoxHeader, r io.Reader) (Box, error) {
+	payloadLen := int64(hdr.Size) - int64(hdr.Hdrlen)
+	var err error
+	if s, ok := r.(io.Seeker); ok {
+		_, err = s.Seek(payloadLen, io.SeekCurrent)
+	} else {
+		_, err = io.CopyN(io.Discard, r, payloadLen)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("skip %s box: %w", hdr.Name, err)
+	}
+	return newSkippedBox(hdr), nil
+}
+
+// isSkippedBox returns true if box is a stub for a box skipped during decoding.
+func isSkippedBox(box Box) bool {
+	ub, ok := box.(*UnknownBox)
+	return ok && ub.IsSkipped()
+}
+
+// checkBoxSize returns an error if the box with header hdr is too big to be read into memory.
+func (f *File) checkBoxSize(hdr BoxHeader) error {
+	if f.maxBoxSize == 0 || hdr.Size <= f.maxBoxSize {
+		return nil
+	}
+	if hdr.Name == "mdat" && f.fileDecMode == DecModeLazyMdat {
+		return nil
+	}
+	return fmt.Errorf("%s box size %d larger than max box size %d", hdr.Name, hdr.Size, f.maxBoxSize)
+}
+
+// moofOffsets returns the start offset of each moof box when the file is encoded with FragEncMode.
+func (f *File) moofOffsets() (map[*MoofBox]uint64, error) {
+	offsets := make(map[*MoofBox]uint64)
+	var pos uint64
+	switch f.FragEncMode {
+	case EncModeSegment:
+		pos = f.Init.Size()
+		for _, sidx := range f.Sidxs {
+			pos += sidx.Size()
+		}
+		for _, seg := range f.Segments {
+			if seg.Styp != nil {
+				pos += seg.Styp.Size()
+			}
+			for i, frag := range seg.Fragments {
+				if i < len(seg.SidxsByFrag) {
+					for _, sidx := range seg.SidxsByFrag[i] {
+						pos += sidx.Size()
+					}
+				}
+				err := optimizeTrafs(frag.Moof.Trafs, f.EncOptimize)
+				if err != nil {
+					return nil, err
+				}
+				for _, c := range frag.Children {
+					if moof, ok := c.(*MoofBox); ok {
+						offsets[moof] = pos
+					}
+					pos += c.Size()
+				}
+				for _, chunk := range frag.Chunks {
+					err := optimizeTrafs(chunk.Moof.Trafs, f.EncOptimize)
+					if err != nil {
+						return nil, err
+					}
+					for _, c := range chunk.Children {
+						if moof, ok := c.(*MoofBox); ok {
+							offsets[moof] = pos
+						}
+						pos += c.Size()
+					}
+				}
+			}
+		}
+	case EncModeBoxTree:
+		for _, c := range f.Children {
+			if moof, ok := c.(*MoofBox); ok {
+				offsets[moof] = pos
+			}
+			pos += c.Size()
+		}
+	default:
+		return nil, fmt.Errorf("unknown FragEncMode=%d", f.FragEncMode)
+	}
+	return offsets, nil
+}
+
+// lengthSizeMinusOne returns the number of bytes minus one needed to store nr.
+func lengthSizeMinusOne(nr uint32) byte {
+	switch {
+	case nr <= math.MaxUint8:
+		return 0
+	case nr <= math.MaxUint16:
+		return 1
+	case nr < 1<<24:
+		return 2
+	default:
+		return 3
+	}
+}
+
 func min(a, b int) int {
 	if a < b {
 		return a
@@ -2,12 +2,17 @@ package mp4
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/Eyevinn/mp4ff/aac"
 	"github.com/Eyevinn/mp4ff/bits"
+	"github.com/go-test/deep"
 )
 
 func TestDecodeFileWithLazyMdatOption(t *testing.T) {
@@ -36,6 +41,222 @@ func TestDecodeFileWithLazyMdatOption(t *testing.T) {
 
 }
 
+func TestDecodeFileInitSegmentOnly(t *testing.T) {
+	for _, fileName := range []string{"testdata/prog_8s_dec_dashinit.mp4", "testdata/prog_8s.mp4"} {
+		fd, err := os.Open(fileName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer fd.Close()
+		f, err := DecodeFile(fd, WithDecodeMode(DecModeLazyMdat), WithDecodeFlags(DecInitSegmentOnly))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if f.Moov == nil {
+			t.Fatalf("%s: no moov", fileName)
+		}
+		if len(f.Segments) != 0 {
+			t.Errorf("%s: got %d segments", fileName, len(f.Segments))
+		}
+		lastBox := f.Children[len(f.Children)-1]
+		if lastBox != f.Moov {
+			t.Errorf("%s: last decoded box is %s", fileName, lastBox.Type())
+		}
+		pos, err := fd.Seek(0, io.SeekCurrent)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if uint64(pos) != f.Size() {
+			t.Errorf("%s: reader at %d instead of after moov at %d", fileName, pos, f.Size())
+		}
+	}
+	fd, err := os.Open("testdata/1.m4s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fd.Close()
+	f, err := DecodeFile(fd, WithDecodeFlags(DecInitSegmentOnly))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(f.Children) != 0 {
+		t.Errorf("got %d boxes for media segment", len(f.Children))
+	}
+	pos, err := fd.Seek(0, io.SeekCurrent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pos != 0 {
+		t.Errorf("reader at %d instead of at styp box at 0", pos)
+	}
+}
+
+// cancelingReader cancels a context at the first read.
+type cancelingReader struct {
+	r      io.Reader
+	cancel context.CancelFunc
+}
+
+func (c *cancelingReader) Read(p []byte) (int, error) {
+	c.cancel()
+	return c.r.Read(p)
+}
+
+func TestDecodeFileCtx(t *testing.T) {
+	data, err := os.ReadFile("./testdata/prog_8s_dec_dashinit.mp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := DecodeFileCtx(context.Background(), bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(f.Segments) == 0 {
+		t.Error("no segments decoded")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	_, err = DecodeFileCtx(ctx, &cancelingReader{bytes.NewReader(data), cancel})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("got error %v instead of %v", err, context.Canceled)
+	}
+}
+
+func TestDecodeFileMaxBoxSize(t *testing.T) {
+	data, err := os.ReadFile("./testdata/prog_8s.mp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = DecodeFile(bytes.NewReader(data), WithMaxBoxSize(uint64(len(data)))); err != nil {
+		t.Error(err)
+	}
+	if _, err = DecodeFile(bytes.NewReader(data), WithMaxBoxSize(1000)); err == nil {
+		t.Error("no error for box larger than max box size")
+	}
+	if _, err = DecodeFile(bytes.NewReader(data), WithMaxBoxSize(1000), WithLazyMdat()); err == nil {
+		t.Error("no error for moov box larger than max box size in lazy mode")
+	}
+	// A header claiming a huge size must give an error before reading
+	huge := []byte{0xff, 0xff, 0xff, 0xf0, 'f', 'r', 'e', 'e', 0, 0, 0, 0}
+	if _, err = DecodeFile(bytes.NewReader(huge), WithMaxBoxSize(1<<20)); err == nil {
+		t.Error("no error for free box with huge size")
+	}
+}
+
+func TestDecodeFilePartial(t *testing.T) {
+	initData, err := os.ReadFile("./testdata/init.mp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	segData, err := os.ReadFile("./testdata/1.m4s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := append(initData, segData...)
+	full, err := DecodeFile(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mdat := full.Segments[0].Fragments[0].Mdat
+	mdatStart := int(mdat.StartPos)
+	moofStart := int(full.Segments[0].Fragments[0].Moof.StartPos)
+	if _, err := DecodeFile(bytes.NewReader(data[:mdatStart+100])); err != nil {
+		t.Errorf("error for truncated mdat box in DecodeFile without DecStrictErrors: %v", err)
+	}
+	if _, err := DecodeFile(bytes.NewReader(data[:mdatStart+100]), WithDecodeFlags(DecStrictErrors)); err == nil {
+		t.Error("no error for truncated mdat box in DecodeFile with DecStrictErrors")
+	}
+
+	cases := []struct {
+		desc          string
+		dataLen       int
+		needMoreBytes uint64
+		wantMoof      bool
+	}{
+		{desc: "init segment header", dataLen: 4, needMoreBytes: 4},
+		{desc: "init segment only", dataLen: len(initData), needMoreBytes: 0},
+		{desc: "truncated moof", dataLen: moofStart + 100, needMoreBytes: uint64(mdatStart - moofStart - 100)},
+		{desc: "truncated mdat header", dataLen: mdatStart + 5, needMoreBytes: 3, wantMoof: true},
+		{desc: "truncated mdat", dataLen: mdatStart + 100, needMoreBytes: mdat.Size() - 100, wantMoof: true},
+		{desc: "complete", dataLen: len(data), needMoreBytes: 0, wantMoof: true},
+	}
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			f, needMoreBytes, err := DecodeFilePartial(data[:c.dataLen])
+			if err != nil {
+				t.Fatal(err)
+			}
+			if needMoreBytes != c.needMoreBytes {
+				t.Errorf("got needMoreBytes %d instead of %d", needMoreBytes, c.needMoreBytes)
+			}
+			if f.Size() > uint64(c.dataLen) {
+				t.Errorf("decoded size %d larger than data size %d", f.Size(), c.dataLen)
+			}
+			gotMoof := len(f.Segments) > 0 && len(f.Segments[0].Fragments) > 0
+			if gotMoof != c.wantMoof {
+				t.Errorf("got moof %t instead of %t", gotMoof, c.wantMoof)
+			}
+			if c.wantMoof && needMoreBytes > 0 {
+				frag := f.Segments[0].Fragments[0]
+				if frag.Mdat != nil {
+					t.Error("truncated mdat should not be decoded")
+				}
+				trex, _ := f.Init.Moov.Mvex.GetTrex(frag.Moof.Traf.Tfhd.TrackID)
+				if _, err := frag.GetFullSamples(trex); err == nil {
+					t.Error("no error for full samples of fragment without mdat")
+				}
+			}
+		})
+	}
+	bad := []byte{0, 0, 0, 4, 'f', 'r', 'e', 'e'}
+	if _, _, err := DecodeFilePartial(bad); err == nil {
+		t.Error("no error for box size smaller than header")
+	}
+}
+
+func TestDecodeFileWithBoxFilter(t *testing.T) {
+	data, err := os.ReadFile("./testdata/multi_sidx_segment.m4s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	full, err := DecodeFile(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	filter := WithBoxFilter([]string{"styp", "sidx"})
+	decoders := map[string]func() (*File, error){
+		"seeker": func() (*File, error) { return DecodeFile(bytes.NewReader(data), filter) },
+		"reader": func() (*File, error) { return DecodeFile(bytes.NewBuffer(data), filter) },
+		"sr":     func() (*File, error) { return DecodeFileSR(bits.NewFixedSliceReader(data), filter) },
+	}
+	for name, decode := range decoders {
+		f, err := decode()
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if len(f.Children) != len(full.Children) {
+			t.Fatalf("%s: got %d boxes instead of %d", name, len(f.Children), len(full.Children))
+		}
+		for i, c := range f.Children {
+			fc := full.Children[i]
+			if c.Type() != fc.Type() || c.Size() != fc.Size() {
+				t.Errorf("%s: box %d is %s of size %d instead of %s of size %d", name, i, c.Type(), c.Size(),
+					fc.Type(), fc.Size())
+			}
+			ub, skipped := c.(*UnknownBox)
+			if skipped != (c.Type() == "moof" || c.Type() == "mdat") || (skipped && !ub.IsSkipped()) {
+				t.Errorf("%s: unexpected skip status for %s box", name, c.Type())
+			}
+		}
+		if len(f.Segments) != 1 || f.Segments[0].Styp == nil || f.Segments[0].Sidx == nil {
+			t.Errorf("%s: styp and sidx boxes not decoded", name)
+		}
+		f.FragEncMode = EncModeBoxTree
+		if err := f.Encode(io.Discard); err == nil {
+			t.Errorf("%s: no error when encoding file with skipped boxes", name)
+		}
+	}
+}
+
 func TestDecodeFileWithNoLazyMdatOption(t *testing.T) {
 
 	// load a segment
@@ -300,6 +521,60 @@ func TestGetSegmentBoundariesFromMfra(t *testing.T) {
 	}
 }
 
+func TestBuildMfra(t *testing.T) {
+	for _, mode := range []EncFragFileMode{EncModeSegment, EncModeBoxTree} {
+		parsedFile, err := ReadMP4File("./testdata/prog_8s_dec_dashinit.mp4")
+		if err != nil {
+			t.Fatal(err)
+		}
+		parsedFile.FragEncMode = mode
+		err = parsedFile.BuildMfra()
+		if err != nil {
+			t.Fatal(err)
+		}
+		buf := bytes.Buffer{}
+		err = parsedFile.Encode(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decFile, err := DecodeFile(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if decFile.Mfra == nil || len(decFile.Mfra.Tfras) != len(decFile.Init.Moov.Traks) {
+			t.Fatalf("mode %d: no mfra or wrong number of tfra boxes", mode)
+		}
+		if decFile.Mfra.Mfro.ParentSize != uint32(decFile.Mfra.Size()) {
+			t.Errorf("mode %d: mfro parent size %d, not %d", mode, decFile.Mfra.Mfro.ParentSize, decFile.Mfra.Size())
+		}
+		for _, tfra := range decFile.Mfra.Tfras {
+			if len(tfra.Entries) == 0 {
+				t.Errorf("mode %d: no entries for track %d", mode, tfra.TrackID)
+			}
+			for _, te := range tfra.Entries {
+				var frag *Fragment
+				for _, seg := range decFile.Segments {
+					for _, fr := range seg.Fragments {
+						if fr.Moof.StartPos == te.MoofOffset {
+							frag = fr
+						}
+					}
+				}
+				if frag == nil {
+					t.Fatalf("mode %d: no moof at offset %d", mode, te.MoofOffset)
+				}
+				traf := frag.Moof.Trafs[te.TrafNumber-1]
+				trex, _ := decFile.Init.Moov.Mvex.GetTrex(tfra.TrackID)
+				trun := traf.Truns[te.TrunNumber-1]
+				trun.AddSampleDefaultValues(traf.Tfhd, trex)
+				if traf.Tfhd.TrackID != tfra.TrackID || !trun.Samples[te.SampleNumber-1].IsSync() {
+					t.Errorf("mode %d: tfra entry %+v does not point to sync sample", mode, te)
+				}
+			}
+		}
+	}
+}
+
 func TestUpdateSidx(t *testing.T) {
 	file, err := os.Open("./testdata/prog_8s_dec_dashinit.mp4")
 	if err != nil {
@@ -322,7 +597,17 @@ func TestUpdateSidx(t *testing.T) {
 		t.Error(err)
 	}
 	if parsedFile.Sidx == nil {
-		t.Error("sidx should be present")
+		t.Fatal("sidx should be present")
+	}
+	trex, _ := parsedFile.Init.Moov.Mvex.GetTrex(parsedFile.Init.Moov.Trak.Tkhd.TrackID)
+	for i, ref := range parsedFile.Sidx.SidxRefs {
+		sapType, startsWithSAP, sapDeltaTime, err := parsedFile.Segments[i].Fragments[0].SAPType(trex)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if uint32(ref.SAPType) != sapType || (ref.StartsWithSAP == 1) != startsWithSAP || ref.SAPDeltaTime != sapDeltaTime {
+			t.Errorf("sidx ref %d: SAP values %+v differ from fragment SAP type %d", i+1, ref, sapType)
+		}
 	}
 }
 
@@ -379,3 +664,403 @@ func TestEmptyMdat(t *testing.T) {
 		}
 	}
 }
+
+func TestReadSamplesLazyAndNormal(t *testing.T) {
+	fd, err := os.Open("./testdata/bbb_prog_10s.mp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fd.Close()
+	lazyFile, err := DecodeFile(fd, WithLazyMdat())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !lazyFile.Mdat.IsLazy() {
+		t.Fatal("mdat not lazy")
+	}
+	normalFile, err := ReadMP4File("./testdata/bbb_prog_10s.mp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, trak := range lazyFile.Moov.Traks {
+		nrSamples := trak.GetNrSamples()
+		startNr, endNr := uint32(2), nrSamples-1
+		lazySamples, err := lazyFile.ReadSamples(fd, trak, startNr, endNr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		normalSamples, err := normalFile.ReadSamples(nil, normalFile.Moov.Traks[i], startNr, endNr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(lazySamples) != int(endNr-startNr+1) {
+			t.Errorf("got %d samples instead of %d", len(lazySamples), endNr-startNr+1)
+		}
+		if diff := deep.Equal(lazySamples, normalSamples); diff != nil {
+			t.Errorf("track %d: %v", i+1, diff)
+		}
+		decTime, _ := trak.Mdia.Minf.Stbl.Stts.GetDecodeTime(startNr)
+		if lazySamples[0].DecodeTime != decTime {
+			t.Errorf("got decode time %d instead of %d", lazySamples[0].DecodeTime, decTime)
+		}
+	}
+	sr := lazyFile.Mdat.NewSectionReader(fd)
+	if uint64(sr.Size()) != lazyFile.Mdat.GetLazyDataSize() {
+		t.Errorf("section reader size %d instead of %d", sr.Size(), lazyFile.Mdat.GetLazyDataSize())
+	}
+	buf := make([]byte, 16)
+	_, err = sr.ReadAt(buf, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf, normalFile.Mdat.Data[:16]) {
+		t.Errorf("section reader data differs")
+	}
+}
+
+func TestFileSamples(t *testing.T) {
+	f, err := ReadMP4File("./testdata/prog_8s_dec_dashinit.mp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	trackID := f.Init.Moov.Trak.Tkhd.TrackID
+	trex, _ := f.Init.Moov.Mvex.GetTrex(trackID)
+	var expected []FullSample
+	for _, seg := range f.Segments {
+		for _, frag := range seg.Fragments {
+			fs, err := frag.GetFullSamples(trex)
+			if err != nil {
+				t.Fatal(err)
+			}
+			expected = append(expected, fs...)
+		}
+	}
+	var samples []FullSample
+	f.Samples(trackID)(func(s FullSample, err error) bool {
+		if err != nil {
+			t.Fatal(err)
+		}
+		samples = append(samples, s)
+		return true
+	})
+	if len(samples) == 0 || len(samples) != len(expected) {
+		t.Fatalf("got %d samples instead of %d", len(samples), len(expected))
+	}
+	for i := 1; i < len(samples); i++ {
+		if samples[i].DecodeTime != samples[i-1].DecodeTime+uint64(samples[i-1].Dur) {
+			t.Errorf("sample %d: decode time %d not continuous", i+1, samples[i].DecodeTime)
+		}
+	}
+	nrYielded := 0
+	f.Samples(trackID)(func(s FullSample, err error) bool {
+		nrYielded++
+		return nrYielded < 3
+	})
+	if nrYielded != 3 {
+		t.Errorf("iteration did not stop, got %d samples", nrYielded)
+	}
+	var gotErr error
+	f.Samples(trackID + 100)(func(s FullSample, err error) bool {
+		gotErr = err
+		return true
+	})
+	if gotErr == nil {
+		t.Error("expected error for unknown trackID")
+	}
+}
+
+func TestFixChunkOffsets(t *testing.T) {
+	readAllSamples := func(f *File) [][]FullSample {
+		var all [][]FullSample
+		for _, trak := range f.Moov.Traks {
+			samples, err := f.ReadSamples(nil, trak, 1, trak.GetNrSamples())
+			if err != nil {
+				t.Fatal(err)
+			}
+			all = append(all, samples)
+		}
+		return all
+	}
+	f, err := ReadMP4File("./testdata/prog_8s.mp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	origSamples := readAllSamples(f)
+	for _, trak := range f.Moov.Traks {
+		trak.SetEditList([]ElstEntry{{SegmentDuration: 1000, MediaTime: 0, MediaRateInteger: 1}})
+	}
+	if err := f.FixChunkOffsets(); err != nil {
+		t.Fatal(err)
+	}
+	buf := bytes.Buffer{}
+	if err := f.Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	decFile, err := DecodeFile(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := deep.Equal(readAllSamples(decFile), origSamples); diff != nil {
+		t.Errorf("samples differ after edts insertion: %v", diff)
+	}
+	if decFile.Moov.Traks[0].GetEditList() == nil {
+		t.Error("no edit list after encode and decode")
+	}
+
+	// Force a shift beyond 32 bits to trigger stco to co64 promotion
+	stbl := f.Moov.Trak.Mdia.Minf.Stbl
+	firstOffset := uint64(stbl.Stco.ChunkOffset[0])
+	prevMdatPos := f.Mdat.StartPos
+	f.Mdat.StartPos -= 1 << 32
+	if err := f.FixChunkOffsets(); err != nil {
+		t.Fatal(err)
+	}
+	if stbl.Stco != nil || stbl.Co64 == nil {
+		t.Fatal("stco not promoted to co64")
+	}
+	mdatPos, _ := f.boxStartPos(f.Mdat)
+	wantedShift := 1<<32 + mdatPos - prevMdatPos // moov has grown due to co64
+	if shift := stbl.Co64.ChunkOffset[0] - firstOffset; shift != wantedShift {
+		t.Errorf("got shift %d instead of %d", shift, wantedShift)
+	}
+	if f.Mdat.StartPos != mdatPos {
+		t.Errorf("mdat start pos %d not updated to %d", f.Mdat.StartPos, mdatPos)
+	}
+
+	// A negative offset in the last track should leave all tracks unchanged
+	f, err = ReadMP4File("./testdata/prog_8s.mp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(f.Moov.Traks) < 2 {
+		t.Fatal("test file should have at least 2 tracks")
+	}
+	lastStco := f.Moov.Traks[len(f.Moov.Traks)-1].Mdia.Minf.Stbl.Stco
+	lastStco.ChunkOffset[0] = 10
+	firstStco := f.Moov.Traks[0].Mdia.Minf.Stbl.Stco
+	firstOffsets := append([]uint32{}, firstStco.ChunkOffset...)
+	f.Mdat.StartPos += 100
+	if err := f.FixChunkOffsets(); err == nil {
+		t.Error("no error for negative chunk offset")
+	}
+	if diff := deep.Equal(firstStco.ChunkOffset, firstOffsets); diff != nil {
+		t.Errorf("first track offsets changed despite error: %v", diff)
+	}
+}
+
+func TestSegmentForTime(t *testing.T) {
+	parsedFile, err := ReadMP4File("testdata/bbb5s_aac_sidx.mp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	segs, err := parsedFile.SidxSegments()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(segs) != len(parsedFile.Segments) {
+		t.Fatalf("%d sidx segments instead of %d", len(segs), len(parsedFile.Segments))
+	}
+	for i, seg := range parsedFile.Segments {
+		if segs[i].Offset != seg.StartPos || segs[i].Size != seg.Size() {
+			t.Errorf("segment %d: range %d+%d instead of %d+%d", i, segs[i].Offset, segs[i].Size, seg.StartPos, seg.Size())
+		}
+	}
+	last := segs[len(segs)-1]
+	got, err := parsedFile.SegmentForTime(last.StartTime + last.Duration/2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != last {
+		t.Errorf("got segment %+v instead of %+v", got, last)
+	}
+	if _, err = parsedFile.SegmentForTime(last.StartTime + last.Duration); err == nil {
+		t.Error("no error for time after last segment")
+	}
+	if _, err = NewFile().SegmentForTime(0); err == nil {
+		t.Error("no error for file without sidx")
+	}
+
+	// Add a root sidx referencing the existing sidx
+	leaf := parsedFile.Sidx
+	root := &SidxBox{ReferenceID: leaf.ReferenceID, Timescale: leaf.Timescale,
+		EarliestPresentationTime: leaf.EarliestPresentationTime}
+	rootRef := SidxRef{ReferencedSize: uint32(leaf.Size()), ReferenceType: 1}
+	for _, ref := range leaf.SidxRefs {
+		rootRef.ReferencedSize += ref.ReferencedSize
+		rootRef.SubSegmentDuration += ref.SubSegmentDuration
+	}
+	root.SidxRefs = []SidxRef{rootRef}
+	parsedFile.Sidx = root
+	parsedFile.Sidxs = []*SidxBox{root, leaf}
+	buf := bytes.Buffer{}
+	if err = parsedFile.Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	hierFile, err := DecodeFile(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hierSegs, err := hierFile.SidxSegments()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hierSegs) != len(segs) {
+		t.Fatalf("%d segments with root sidx instead of %d", len(hierSegs), len(segs))
+	}
+	for i, seg := range hierFile.Segments {
+		if hierSegs[i].Offset != seg.StartPos || hierSegs[i].StartTime != segs[i].StartTime {
+			t.Errorf("segment %d: offset %d and time %d instead of %d and %d", i, hierSegs[i].Offset,
+				hierSegs[i].StartTime, seg.StartPos, segs[i].StartTime)
+		}
+	}
+}
+
+func TestPadTo(t *testing.T) {
+	f, err := ReadMP4File("./testdata/prog_8s.mp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	trak := f.Moov.Trak
+	origSamples, err := f.ReadSamples(nil, trak, 1, trak.GetNrSamples())
+	if err != nil {
+		t.Fatal(err)
+	}
+	mdatPos, err := f.boxStartPos(f.Mdat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	offset := (mdatPos/4096 + 1) * 4096
+	for _, o := range []uint64{offset, offset + 4096} {
+		if err := f.PadTo(o); err != nil {
+			t.Fatal(err)
+		}
+		buf := bytes.Buffer{}
+		if err := f.Encode(&buf); err != nil {
+			t.Fatal(err)
+		}
+		decFile, err := DecodeFile(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if decFile.Mdat.StartPos != o {
+			t.Errorf("mdat at %d instead of %d", decFile.Mdat.StartPos, o)
+		}
+		samples, err := decFile.ReadSamples(nil, decFile.Moov.Trak, 1, trak.GetNrSamples())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := deep.Equal(samples, origSamples); diff != nil {
+			t.Errorf("samples differ after padding: %v", diff)
+		}
+	}
+	if err := f.PadTo(mdatPos + 4); err == nil {
+		t.Error("expected error for padding smaller than box header")
+	}
+	nrChildren := len(f.Children)
+	if err := f.PadTo(mdatPos - 1); err == nil {
+		t.Error("expected error for offset before end of moov")
+	}
+	if len(f.Children) != nrChildren || f.Mdat.StartPos != offset+4096 {
+		t.Error("file modified despite error")
+	}
+	// Offsets beyond 32 bits make moov grow when stco is replaced by co64
+	var wantedGrowth uint64
+	for _, trak := range f.Moov.Traks {
+		wantedGrowth += 4 * uint64(len(trak.Mdia.Minf.Stbl.Stco.ChunkOffset))
+	}
+	if growth := co64Growth(f.Moov, 1<<32); growth != wantedGrowth {
+		t.Errorf("got co64 growth %d instead of %d", growth, wantedGrowth)
+	}
+	if growth := co64Growth(f.Moov, 4096); growth != 0 {
+		t.Errorf("got co64 growth %d for small shift", growth)
+	}
+}
+
+func TestFastStart(t *testing.T) {
+	f, err := ReadMP4File("./testdata/prog_8s.mp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	trak := f.Moov.Trak
+	origSamples, err := f.ReadSamples(nil, trak, 1, trak.GetNrSamples())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Make a file with moov at the end
+	var children []Box
+	for _, c := range f.Children {
+		if c != f.Moov {
+			children = append(children, c)
+		}
+	}
+	f.Children = append(children, f.Moov)
+	if err := f.FixChunkOffsets(); err != nil {
+		t.Fatal(err)
+	}
+	buf := bytes.Buffer{}
+	if err := f.Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	moovLast, err := DecodeFile(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := moovLast.FastStart(); err != nil {
+		t.Fatal(err)
+	}
+	buf.Reset()
+	if err := moovLast.Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	decFile, err := DecodeFile(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var types []string
+	for _, c := range decFile.Children {
+		types = append(types, c.Type())
+	}
+	if strings.Join(types, ",") != "ftyp,moov,mdat,free" {
+		t.Errorf("got top-level boxes %v after FastStart", types)
+	}
+	samples, err := decFile.ReadSamples(nil, decFile.Moov.Trak, 1, trak.GetNrSamples())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := deep.Equal(samples, origSamples); diff != nil {
+		t.Errorf("samples differ after FastStart: %v", diff)
+	}
+}
+
+func TestFreeBoxesKeptInFragmentedFile(t *testing.T) {
+	init := CreateEmptyInit()
+	init.AddEmptyTrack(1000, "video", "und")
+	frag := createTestFragment(t, 1, 0, 2, true)
+	buf := bytes.Buffer{}
+	for _, b := range []Box{init.Ftyp, NewFreeBox(16), init.Moov, NewFreeBox(8)} {
+		if err := b.Encode(&buf); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := frag.Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if err := NewFreeBox(12).Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	f, err := DecodeFile(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.FragEncMode != EncModeSegment {
+		t.Fatalf("unexpected encode mode %d", f.FragEncMode)
+	}
+	out := bytes.Buffer{}
+	if err := f.Encode(&out); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out.Bytes(), data) {
+		t.Errorf("re-encoded file differs: %d instead of %d bytes", out.Len(), len(data))
+	}
+}
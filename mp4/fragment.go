@@ -3,6 +3,7 @@ package mp4
 import (
 	"fmt"
 	"io"
+	"math"
 	"sort"
 
 	"github.com/Eyevinn/mp4ff/bits"
@@ -14,10 +15,11 @@ type Fragment struct {
 	Prft        *PrftBox
 	Moof        *MoofBox
 	Mdat        *MdatBox
-	Children    []Box       // All top-level boxes in order
-	nextTrunNr  uint32      // To handle multi-trun cases
-	EncOptimize EncOptimize // Bit field with optimizations being done at encoding
-	StartPos    uint64      // Start position in file added by parser
+	Children    []Box        // All top-level boxes in order
+	Chunks      []*CMAFChunk // CMAF chunks after the first moof and mdat
+	nextTrunNr  uint32       // To handle multi-trun cases
+	EncOptimize EncOptimize  // Bit field with optimizations being done at encoding
+	StartPos    uint64       // Start position in file added by parser
 }
 
 // NewFragment creates an empty MP4 Fragment.
@@ -84,6 +86,11 @@ func (f *Fragment) AddChild(b Box) {
 	f.Children = append(f.Children, b)
 }
 
+// AddChunk adds a CMAF chunk after the first moof and mdat, and any previous chunks.
+func (f *Fragment) AddChunk(c *CMAFChunk) {
+	f.Chunks = append(f.Chunks, c)
+}
+
 // AddEmsg inserts an emsg box at the end of a sequence of emsg boxes at the start of the fragment.
 func (f *Fragment) AddEmsg(emsg *EmsgBox) {
 	prevEmsg := -1
@@ -95,22 +102,94 @@ func (f *Fragment) AddEmsg(emsg *EmsgBox) {
 	newIdx := prevEmsg + 1
 	f.Children = append(f.Children[:newIdx+1], f.Children[newIdx:]...)
 	f.Children[newIdx] = emsg
+	f.Emsgs = append(f.Emsgs, emsg)
 }
 
-// Size - return size of fragment including all boxes.
+// AddPrft inserts a prft box with flags PrftTimeEncoderInput just before the moof box, or replaces an existing one.
+// mediaTime must be equal to the baseMediaDecodeTime of the tfdt box of the track with trackID.
+// Version 1 is used if mediaTime does not fit in 32 bits.
+func (f *Fragment) AddPrft(trackID uint32, ntpTime uint64, mediaTime uint64) error {
+	if f.Moof == nil {
+		return fmt.Errorf("moof not set in fragment")
+	}
+	var traf *TrafBox
+	for _, tr := range f.Moof.Trafs {
+		if tr.Tfhd.TrackID == trackID {
+			traf = tr
+			break
+		}
+	}
+	if traf == nil {
+		return fmt.Errorf("no traf for trackID %d", trackID)
+	}
+	if traf.Tfdt == nil {
+		return fmt.Errorf("no tfdt for trackID %d", trackID)
+	}
+	if bmdt := traf.Tfdt.BaseMediaDecodeTime(); bmdt != mediaTime {
+		return fmt.Errorf("prft mediaTime %d differs from tfdt baseMediaDecodeTime %d", mediaTime, bmdt)
+	}
+	var version byte
+	if mediaTime > 0xffffffff {
+		version = 1
+	}
+	prft := CreatePrftBox(version, PrftTimeEncoderInput, trackID, NTP64(ntpTime), mediaTime)
+	for i, c := range f.Children {
+		switch c.(type) {
+		case *PrftBox:
+			f.Children[i] = prft
+			f.Prft = prft
+			return nil
+		case *MoofBox:
+			f.Children = append(f.Children, nil)
+			copy(f.Children[i+1:], f.Children[i:])
+			f.Children[i] = prft
+			f.Prft = prft
+			return nil
+		}
+	}
+	return fmt.Errorf("moof not found among fragment children")
+}
+
+// Size - return size of fragment including all boxes and chunks.
 // Be aware that TrafBox.OptimizeTfhdTrun() can change size
 func (f *Fragment) Size() uint64 {
 	var size uint64 = 0
 	for _, c := range f.Children {
 		size += c.Size()
 	}
+	for _, c := range f.Chunks {
+		size += c.Size()
+	}
 	return size
 }
 
-// GetFullSamples - Get full samples including media and accumulated time
+// GetFullSamples - Get full samples including media and accumulated time.
+// Samples in CMAF chunks are included.
 func (f *Fragment) GetFullSamples(trex *TrexBox) ([]FullSample, error) {
+	samples, err := f.getMoofFullSamples(trex)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range f.Chunks {
+		chunkSamples, err := c.GetFullSamples(trex)
+		if err != nil {
+			return nil, fmt.Errorf("chunk at %d: %w", c.StartPos, err)
+		}
+		samples = append(samples, chunkSamples...)
+	}
+	return samples, nil
+}
+
+// getMoofFullSamples - get full samples of the first moof and mdat, excluding CMAF chunks
+func (f *Fragment) getMoofFullSamples(trex *TrexBox) ([]FullSample, error) {
 	moof := f.Moof
 	mdat := f.Mdat
+	if moof == nil {
+		return nil, fmt.Errorf("no moof box in fragment")
+	}
+	if mdat == nil {
+		return nil, fmt.Errorf("no mdat box in fragment")
+	}
 	//seqNr := moof.Mfhd.SequenceNumber
 	var traf *TrafBox
 	foundTrak := false
@@ -132,25 +211,20 @@ func (f *Fragment) GetFullSamples(trex *TrexBox) ([]FullSample, error) {
 	if traf.Tfdt != nil {
 		baseTime = traf.Tfdt.BaseMediaDecodeTime()
 	}
-	moofStartPos := moof.StartPos
+	baseOffset := f.trafBaseOffset(traf)
+	// A trun without data offset starts directly after the data of the previous trun
+	dataPos := baseOffset
 	var samples []FullSample
 	for _, trun := range traf.Truns {
 		totalDur := trun.AddSampleDefaultValues(tfhd, trex)
-		// The default is moofStartPos according to Section 8.8.7.1
-		baseOffset := moofStartPos
-		if tfhd.HasBaseDataOffset() {
-			baseOffset = tfhd.BaseDataOffset
-		} else if tfhd.DefaultBaseIfMoof() {
-			baseOffset = moofStartPos
-		}
 		if trun.HasDataOffset() {
-			baseOffset = uint64(int64(trun.DataOffset) + int64(baseOffset))
+			dataPos = uint64(int64(trun.DataOffset) + int64(baseOffset))
 		}
 		mdatDataLength := uint64(len(mdat.Data)) // len should be fine for 64-bit
 		var offsetInMdat uint64
-		if baseOffset > 0 {
-			offsetInMdat = baseOffset - mdat.PayloadAbsoluteOffset()
-			if offsetInMdat > mdatDataLength {
+		if dataPos > 0 {
+			offsetInMdat = dataPos - mdat.PayloadAbsoluteOffset()
+			if offsetInMdat > mdatDataLength || offsetInMdat+trun.SizeOfData() > mdatDataLength {
 				return nil, fmt.Errorf("offset in mdata beyond size")
 			}
 		} else {
@@ -158,11 +232,38 @@ func (f *Fragment) GetFullSamples(trex *TrexBox) ([]FullSample, error) {
 		}
 		samples = append(samples, trun.GetFullSamples(uint32(offsetInMdat), baseTime, mdat)...)
 		baseTime += totalDur // Next trun start after this
+		dataPos += trun.SizeOfData()
 	}
 
 	return samples, nil
 }
 
+// trafBaseOffset returns the absolute base offset for data offsets in traf.
+func (f *Fragment) trafBaseOffset(traf *TrafBox) uint64 {
+	moofStartPos := f.Moof.StartPos
+	tfhd := traf.Tfhd
+	switch {
+	case tfhd.HasBaseDataOffset():
+		return tfhd.BaseDataOffset
+	case tfhd.DefaultBaseIfMoof():
+		return moofStartPos
+	}
+	// The implicit base offset is moofStartPos for the first traf, and the end of the data
+	// of the preceding traf for subsequent ones according to Section 8.8.7.1
+	implicitBase := moofStartPos
+	for _, t := range f.Moof.Trafs {
+		if t == traf {
+			break
+		}
+		end, ok := t.dataEnd(implicitBase, moofStartPos)
+		if !ok {
+			end = moofStartPos
+		}
+		implicitBase = end
+	}
+	return implicitBase
+}
+
 // AddFullSample - add a full sample to the first (and only) trun of a track
 // AddFullSampleToTrack is the more general function
 func (f *Fragment) AddFullSample(s FullSample) {
@@ -222,8 +323,9 @@ func (f *Fragment) AddSamples(ss []Sample, baseMediaDecodeTime uint64) {
 // baseMediaDecodeTime will be used only for first sample in a trun
 func (f *Fragment) AddSampleToTrack(s Sample, trackID uint32, baseMediaDecodeTime uint64) error {
 	var traf *TrafBox
-	for _, traf = range f.Moof.Trafs {
-		if traf.Tfhd.TrackID == trackID {
+	for _, t := range f.Moof.Trafs {
+		if t.Tfhd.TrackID == trackID {
+			traf = t
 			break
 		}
 	}
@@ -262,12 +364,9 @@ func (f *Fragment) Encode(w io.Writer) error {
 	if f.Moof == nil {
 		return fmt.Errorf("moof not set in fragment")
 	}
-	traf := f.Moof.Traf
-	if f.EncOptimize&OptimizeTrun != 0 {
-		err := traf.OptimizeTfhdTrun()
-		if err != nil {
-			return err
-		}
+	err := optimizeTrafs(f.Moof.Trafs, f.EncOptimize)
+	if err != nil {
+		return err
 	}
 	if f.Mdat == nil {
 		return fmt.Errorf("mdat not set in fragment")
@@ -279,20 +378,25 @@ func (f *Fragment) Encode(w io.Writer) error {
 			return err
 		}
 	}
+	for _, c := range f.Chunks {
+		c.EncOptimize = f.EncOptimize
+		err := c.Encode(w)
+		if err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
 // EncodeSW - write fragment via SliceWriter
+// To reduce allocations when encoding many fragments, sw can be a bits.PooledSliceWriter of size f.Size().
 func (f *Fragment) EncodeSW(sw bits.SliceWriter) error {
 	if f.Moof == nil {
 		return fmt.Errorf("moof not set in fragment")
 	}
-	traf := f.Moof.Traf
-	if f.EncOptimize&OptimizeTrun != 0 {
-		err := traf.OptimizeTfhdTrun()
-		if err != nil {
-			return err
-		}
+	err := optimizeTrafs(f.Moof.Trafs, f.EncOptimize)
+	if err != nil {
+		return err
 	}
 	if f.Mdat == nil {
 		return fmt.Errorf("mdat not set in fragment")
@@ -304,6 +408,13 @@ func (f *Fragment) EncodeSW(sw bits.SliceWriter) error {
 			return err
 		}
 	}
+	for _, c := range f.Chunks {
+		c.EncOptimize = f.EncOptimize
+		err := c.EncodeSW(sw)
+		if err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -315,6 +426,12 @@ func (f *Fragment) Info(w io.Writer, specificBoxLevels, indent, indentStep strin
 			return err
 		}
 	}
+	for _, c := range f.Chunks {
+		err := c.Info(w, specificBoxLevels, indent, indentStep)
+		if err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -323,34 +440,104 @@ func (f *Fragment) GetChildren() []Box {
 	return f.Children
 }
 
-// SetTrunDataOffsets - if writeOrder available, sort and set dataOffset in truns
+// OptimizeTruns optimizes the tfhd and trun boxes of all trafs in the fragment and its chunks
+// by moving common sample values to tfhd. See TrafBox.OptimizeTruns.
+func (f *Fragment) OptimizeTruns() error {
+	if f.Moof == nil {
+		return fmt.Errorf("moof not set in fragment")
+	}
+	err := optimizeTrafs(f.Moof.Trafs, OptimizeTruns)
+	if err != nil {
+		return err
+	}
+	for _, c := range f.Chunks {
+		err = optimizeTrafs(c.Moof.Trafs, OptimizeTruns)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// optimizeTrafs applies the trun optimizations in eo to trafs.
+func optimizeTrafs(trafs []*TrafBox, eo EncOptimize) error {
+	for _, traf := range trafs {
+		if eo&OptimizeTruns != 0 {
+			err := traf.OptimizeTruns()
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		if eo&OptimizeTrun != 0 {
+			err := traf.OptimizeTfhdTrun()
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// SetTrunDataOffsets sets the data offset of all truns given the moof size, so that the sample data
+// starts right after the mdat header. This is done when encoding the fragment, but must be done
+// explicitly before encoding Moof and Mdat separately.
+// Truns created by AddSampleToTrack have consecutive data in write order, other truns without
+// data offsets have consecutive data in moof order. If the truns already have data offsets, as in a
+// decoded fragment, the offsets are shifted by the same amount, so that the layout of the data is kept.
+// Truns in trafs with explicit base data offset are not changed.
 func (f *Fragment) SetTrunDataOffsets() {
-	nrTruns := 0
+	var truns []*TrunBox
+	var dataSizes []uint64
 	writeOrderSet := false
+	offsetsSet := true
+	var minOffset int32
 	for _, traf := range f.Moof.Trafs {
+		if traf.Tfhd.HasBaseDataOffset() {
+			return
+		}
 		for _, trun := range traf.Truns {
-			nrTruns++
 			if trun.writeOrderNr != 0 {
 				writeOrderSet = true
 			}
+			if trun.DataOffset == 0 {
+				offsetsSet = false
+			}
+			if len(truns) == 0 || trun.DataOffset < minOffset {
+				minOffset = trun.DataOffset
+			}
+			truns = append(truns, trun)
+			dataSizes = append(dataSizes, trunDataSize(traf.Tfhd, trun))
 		}
 	}
-	if !writeOrderSet && nrTruns > 1 {
+	dataStart := int64(f.Moof.Size() + f.Mdat.HeaderSize())
+	if !writeOrderSet && offsetsSet {
+		delta := dataStart - int64(minOffset)
+		for _, trun := range truns {
+			trun.DataOffset = int32(int64(trun.DataOffset) + delta)
+		}
 		return
 	}
-
-	truns := make([]*TrunBox, 0, nrTruns)
-	for _, traf := range f.Moof.Trafs {
-		truns = append(truns, traf.Truns...)
+	order := make([]int, len(truns))
+	for i := range order {
+		order[i] = i
 	}
-	sort.Slice(truns, func(i, j int) bool {
-		return truns[i].writeOrderNr < truns[j].writeOrderNr
+	sort.SliceStable(order, func(i, j int) bool {
+		return truns[order[i]].writeOrderNr < truns[order[j]].writeOrderNr
 	})
-	dataOffset := f.Moof.Size() + f.Mdat.HeaderSize()
-	for _, trun := range truns {
-		trun.DataOffset = int32(dataOffset)
-		dataOffset += trun.SizeOfData()
+	dataOffset := dataStart
+	for _, i := range order {
+		truns[i].DataOffset = int32(dataOffset)
+		dataOffset += int64(dataSizes[i])
+	}
+}
+
+// trunDataSize returns the size of the sample data of trun, using the default sample size in tfhd if needed.
+func trunDataSize(tfhd *TfhdBox, trun *TrunBox) uint64 {
+	if !trun.HasSampleSize() && tfhd.HasDefaultSampleSize() {
+		return uint64(tfhd.DefaultSampleSize) * uint64(trun.SampleCount())
 	}
+	return trun.SizeOfData()
 }
 
 // GetSampleNrFromTime - look up sample number from a specified time. Return error if no matching time
@@ -419,6 +606,192 @@ func (f *Fragment) AddSampleInterval(sItvl SampleInterval) error {
 	return nil
 }
 
+// Trim - keep only the samples with decode time in [startTime, endTime) in a fragment with one track.
+// If the first sample in the range is not a sync sample, the start is moved back to the closest
+// preceding sync sample (if any), so that the fragment can still be decoded.
+// The remaining samples are written to one trun with explicit sample values, the tfdt
+// baseMediaDecodeTime is set to the decode time of the first sample, and the mdat data is replaced.
+// Composition time offsets are relative to the decode time of each sample and are kept.
+// Fragments with sample auxiliary information, sample groups, or explicit base data offset
+// cannot be trimmed.
+func (f *Fragment) Trim(trex *TrexBox, startTime, endTime uint64) error {
+	moof := f.Moof
+	if len(moof.Trafs) != 1 {
+		return fmt.Errorf("not exactly one track in fragment")
+	}
+	if len(f.Chunks) > 0 {
+		return fmt.Errorf("cannot trim fragment with chunks")
+	}
+	traf := moof.Traf
+	if traf.Senc != nil || traf.UUIDSenc != nil || traf.Saiz != nil || len(traf.Sbgps) > 0 {
+		return fmt.Errorf("cannot trim traf with sample auxiliary information or sample groups")
+	}
+	if traf.Tfdt == nil {
+		return fmt.Errorf("no tfdt box")
+	}
+	if traf.Tfhd.HasBaseDataOffset() {
+		return fmt.Errorf("cannot trim traf with explicit base data offset")
+	}
+	if f.Mdat.IsLazy() {
+		return fmt.Errorf("cannot trim fragment with lazy mdat")
+	}
+	if trex != nil && trex.TrackID != traf.Tfhd.TrackID {
+		return fmt.Errorf("trex trackID %d does not match fragment trackID %d", trex.TrackID, traf.Tfhd.TrackID)
+	}
+	samples, err := f.GetFullSamples(trex)
+	if err != nil {
+		return err
+	}
+	start, end := -1, len(samples)
+	for i := range samples {
+		if samples[i].DecodeTime >= endTime {
+			end = i
+			break
+		}
+		if start < 0 && samples[i].DecodeTime >= startTime {
+			start = i
+		}
+	}
+	if start < 0 || start >= end {
+		return fmt.Errorf("no samples in range [%d, %d)", startTime, endTime)
+	}
+	for i := start; i >= 0; i-- {
+		if samples[i].IsSync() {
+			start = i
+			break
+		}
+	}
+	trun := CreateTrun(0)
+	trun.Version = traf.Trun.Version
+	var dataSize uint64
+	for i := start; i < end; i++ {
+		dataSize += uint64(len(samples[i].Data))
+	}
+	data := make([]byte, 0, dataSize)
+	for i := start; i < end; i++ {
+		trun.AddSample(samples[i].Sample)
+		data = append(data, samples[i].Data...)
+	}
+	children := make([]Box, 0, len(traf.Children))
+	for _, c := range traf.Children {
+		switch c.(type) {
+		case *TrunBox:
+			if c == traf.Trun {
+				children = append(children, trun)
+			}
+		default:
+			children = append(children, c)
+		}
+	}
+	traf.Children = children
+	traf.Trun = trun
+	traf.Truns = []*TrunBox{trun}
+	traf.Tfdt.SetBaseMediaDecodeTime(samples[start].DecodeTime)
+	f.Mdat.SetData(data)
+	f.SetTrunDataOffsets()
+	return nil
+}
+
+// MergeFragments merges single-track fragments, e.g. small audio fragments, into one fragment.
+// The samples are concatenated into one trun and one mdat, and common sample values are moved
+// to tfhd defaults. The sequence number and tfdt baseMediaDecodeTime of the first fragment are kept.
+// The trun version is that of the first fragment, or 1 if any sample has a negative composition time offset.
+// The fragments must be of the same track and be contiguous in decode time. Fragments with
+// sample auxiliary information, sample groups, or lazy mdat are not supported.
+// Other boxes, like emsg and prft, are not included in the result.
+func MergeFragments(frags []*Fragment, trex *TrexBox) (*Fragment, error) {
+	if len(frags) == 0 {
+		return nil, fmt.Errorf("no fragments to merge")
+	}
+	var trackID uint32
+	var samples []FullSample
+	var nextDecodeTime uint64
+	for i, frag := range frags {
+		if frag.Moof == nil || len(frag.Moof.Trafs) != 1 {
+			return nil, fmt.Errorf("fragment %d: not exactly one track", i)
+		}
+		traf := frag.Moof.Traf
+		if traf.Senc != nil || traf.UUIDSenc != nil || traf.Saiz != nil || len(traf.Sbgps) > 0 {
+			return nil, fmt.Errorf("fragment %d: sample auxiliary information or sample groups not supported", i)
+		}
+		if traf.Tfdt == nil {
+			return nil, fmt.Errorf("fragment %d: no tfdt box", i)
+		}
+		if frag.Mdat == nil || frag.Mdat.IsLazy() {
+			return nil, fmt.Errorf("fragment %d: no mdat data", i)
+		}
+		if i == 0 {
+			trackID = traf.Tfhd.TrackID
+			if trex != nil && trex.TrackID != trackID {
+				return nil, fmt.Errorf("trex trackID %d does not match fragment trackID %d", trex.TrackID, trackID)
+			}
+		} else {
+			if traf.Tfhd.TrackID != trackID {
+				return nil, fmt.Errorf("fragment %d: trackID %d instead of %d", i, traf.Tfhd.TrackID, trackID)
+			}
+			if bmdt := traf.Tfdt.BaseMediaDecodeTime(); bmdt != nextDecodeTime {
+				return nil, fmt.Errorf("fragment %d: decode time %d instead of %d", i, bmdt, nextDecodeTime)
+			}
+		}
+		fragSamples, err := frag.GetFullSamples(trex)
+		if err != nil {
+			return nil, fmt.Errorf("fragment %d: %w", i, err)
+		}
+		if len(fragSamples) == 0 {
+			return nil, fmt.Errorf("fragment %d: no samples", i)
+		}
+		last := fragSamples[len(fragSamples)-1]
+		nextDecodeTime = last.DecodeTime + uint64(last.Dur)
+		samples = append(samples, fragSamples...)
+	}
+	out, err := CreateFragment(frags[0].Moof.Mfhd.SequenceNumber, trackID)
+	if err != nil {
+		return nil, err
+	}
+	version := frags[0].Moof.Traf.Trun.Version
+	for _, s := range samples {
+		if s.CompositionTimeOffset < 0 {
+			version = 1 // Version 0 has unsigned composition time offsets
+		}
+		out.AddFullSample(s)
+	}
+	out.Moof.Traf.Trun.Version = version
+	err = out.OptimizeTruns()
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SetTrackID changes the track ID oldID to newID in tfhd and prft boxes.
+// It is an error if newID is already used in the fragment.
+// A fragment without oldID is not changed.
+func (f *Fragment) SetTrackID(oldID, newID uint32) error {
+	if oldID == newID {
+		return nil
+	}
+	for _, traf := range f.Moof.Trafs {
+		if traf.Tfhd.TrackID == newID {
+			return fmt.Errorf("trackID %d already used in fragment", newID)
+		}
+	}
+	for _, traf := range f.Moof.Trafs {
+		if traf.Tfhd.TrackID == oldID {
+			traf.Tfhd.TrackID = newID
+		}
+	}
+	if f.Prft != nil && f.Prft.ReferenceTrackID == oldID {
+		f.Prft.ReferenceTrackID = newID
+	}
+	for _, c := range f.Chunks {
+		err := c.asFragment().SetTrackID(oldID, newID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // CommonSampleDuration returns a common non-zero sample duration for a track defined by trex if available.
 func (f *Fragment) CommonSampleDuration(trex *TrexBox) (uint32, error) {
 	if trex == nil {
@@ -458,3 +831,143 @@ func (f *Fragment) CommonSampleDuration(trex *TrexBox) (uint32, error) {
 	}
 	return commonDur, nil
 }
+
+// PresentationDuration returns the presentation duration of the track given by trex in track timescale,
+// including samples in CMAF chunks. If trex is nil, the first traf is used and no defaults are applied.
+// In contrast to the sum of sample durations, composition time offsets are taken into account, so the
+// result is the latest composition end time minus the earliest composition time of the samples.
+// This is the duration to use for segments in manifests. 0 is returned if there are no samples.
+func (f *Fragment) PresentationDuration(trex *TrexBox) uint64 {
+	if f.Moof == nil {
+		return 0
+	}
+	moofs := []*MoofBox{f.Moof}
+	for _, c := range f.Chunks {
+		moofs = append(moofs, c.Moof)
+	}
+	var start, end int64
+	first := true
+	for _, moof := range moofs {
+		traf := trafForTrex(moof, trex)
+		if traf == nil {
+			continue
+		}
+		var decTime uint64
+		if traf.Tfdt != nil {
+			decTime = traf.Tfdt.BaseMediaDecodeTime()
+		}
+		for _, trun := range traf.Truns {
+			trun.AddSampleDefaultValues(traf.Tfhd, trex)
+			for _, s := range trun.GetSamples() {
+				compTime := int64(decTime) + int64(s.CompositionTimeOffset)
+				if first || compTime < start {
+					start = compTime
+				}
+				if first || compTime+int64(s.Dur) > end {
+					end = compTime + int64(s.Dur)
+				}
+				first = false
+				decTime += uint64(s.Dur)
+			}
+		}
+	}
+	return uint64(end - start)
+}
+
+// SAPType returns the SAP (Stream Access Point) parameters of the fragment for the track given
+// by trex, as used for the sidx references of a subsegment consisting of the fragment.
+// Chunks are included. If trex is nil, the first traf is used and no defaults are applied.
+// The first SAP is the first sync sample in decode order. Its SAP type is
+//
+//   - 1 if no later sample in decode order is presented before it,
+//   - 2 if there are such leading samples, and they are all decodable,
+//   - 3 if some leading samples are not decodable, as signaled by is_leading = 1 in the sample flags.
+//
+// startsWithSAP is true if the first sample is the SAP, and sapDeltaTime is the presentation time
+// of the SAP (the earliest presentation time from which all samples are decodable) minus the
+// earliest presentation time of the fragment. sapType 0 is returned if there is no sync sample.
+func (f *Fragment) SAPType(trex *TrexBox) (sapType uint32, startsWithSAP bool, sapDeltaTime uint32, err error) {
+	sapType, startsWithSAP, ept, tSAP, err := f.sapTimes(trex)
+	if err != nil || sapType == 0 {
+		return sapType, false, 0, err
+	}
+	if tSAP-ept > math.MaxUint32 {
+		return 0, false, 0, fmt.Errorf("SAP delta time %d does not fit in 32 bits", tSAP-ept)
+	}
+	return sapType, startsWithSAP, uint32(tSAP - ept), nil
+}
+
+// sapTimes returns the SAP type as for SAPType, together with the earliest presentation time
+// ept of the fragment and the presentation time tSAP of the SAP.
+func (f *Fragment) sapTimes(trex *TrexBox) (sapType uint32, startsWithSAP bool, ept, tSAP int64, err error) {
+	if f.Moof == nil {
+		return 0, false, 0, 0, fmt.Errorf("no moof")
+	}
+	moofs := []*MoofBox{f.Moof}
+	for _, c := range f.Chunks {
+		moofs = append(moofs, c.Moof)
+	}
+	type sapSample struct {
+		presTime int64
+		flags    SampleFlags
+	}
+	var samples []sapSample
+	for _, moof := range moofs {
+		traf := trafForTrex(moof, trex)
+		if traf == nil {
+			continue
+		}
+		var decTime uint64
+		if traf.Tfdt != nil {
+			decTime = traf.Tfdt.BaseMediaDecodeTime()
+		}
+		for _, trun := range traf.Truns {
+			trun.AddSampleDefaultValues(traf.Tfhd, trex)
+			for _, s := range trun.GetSamples() {
+				samples = append(samples, sapSample{
+					presTime: int64(decTime) + int64(s.CompositionTimeOffset),
+					flags:    DecodeSampleFlags(s.Flags),
+				})
+				decTime += uint64(s.Dur)
+			}
+		}
+	}
+	if len(samples) == 0 {
+		return 0, false, 0, 0, fmt.Errorf("no samples")
+	}
+	ept = samples[0].presTime
+	for _, s := range samples[1:] {
+		if s.presTime < ept {
+			ept = s.presTime
+		}
+	}
+	sapIdx := -1
+	for i, s := range samples {
+		if !s.flags.SampleIsNonSync {
+			sapIdx = i
+			break
+		}
+	}
+	if sapIdx < 0 {
+		return 0, false, ept, 0, nil
+	}
+	ptf := samples[sapIdx].presTime // Presentation time of first sample in decode order
+	tSAP = ptf                      // Earliest presentation time of decodable samples
+	sapType = 1
+	for _, s := range samples[sapIdx+1:] {
+		if s.presTime >= ptf {
+			continue
+		}
+		if s.flags.IsLeading == 1 { // Leading sample that depends on samples before the SAP
+			sapType = 3
+			continue
+		}
+		if sapType == 1 {
+			sapType = 2
+		}
+		if s.presTime < tSAP {
+			tSAP = s.presTime
+		}
+	}
+	return sapType, sapIdx == 0, ept, tSAP, nil
+}
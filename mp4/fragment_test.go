@@ -1,7 +1,11 @@
 package mp4
 
 import (
+	"bytes"
 	"testing"
+
+	"github.com/Eyevinn/mp4ff/bits"
+	"github.com/go-test/deep"
 )
 
 func TestCreateMultiTrackFragment(t *testing.T) {
@@ -60,3 +64,420 @@ func TestFragmentSampleIntervals(t *testing.T) {
 	}
 	sampleItvl.Reset()
 }
+
+func TestFragmentAddEmsg(t *testing.T) {
+	frag, err := CreateFragment(1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	emsgs := []*EmsgBox{{ID: 1}, {ID: 2}}
+	for _, emsg := range emsgs {
+		frag.AddEmsg(emsg)
+	}
+	if diff := deep.Equal(frag.Emsgs, emsgs); diff != nil {
+		t.Error(diff)
+	}
+	if frag.Children[0] != emsgs[0] || frag.Children[1] != emsgs[1] {
+		t.Error("emsg boxes not first in fragment")
+	}
+}
+
+func TestFragmentAddPrft(t *testing.T) {
+	frag, err := CreateFragment(1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	frag.AddFullSample(FullSample{
+		Sample:     NewSample(SyncSampleFlags, 1000, 4, 0),
+		DecodeTime: 1 << 33,
+		Data:       []byte{1, 2, 3, 4},
+	})
+	frag.AddEmsg(&EmsgBox{Version: 1, SchemeIDURI: "urn:test", Value: "1"})
+	if err := frag.AddPrft(1, 123, 1<<33); err == nil {
+		t.Error("expected error for unknown trackID")
+	}
+	if err := frag.AddPrft(2, 123, 1000); err == nil {
+		t.Error("expected error for mediaTime not matching tfdt")
+	}
+	err = frag.AddPrft(2, 123, 1<<33)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = frag.AddPrft(2, 456, 1<<33)
+	if err != nil {
+		t.Fatal(err)
+	}
+	types := make([]string, 0, len(frag.Children))
+	for _, c := range frag.Children {
+		types = append(types, c.Type())
+	}
+	if diff := deep.Equal(types, []string{"emsg", "prft", "moof", "mdat"}); diff != nil {
+		t.Error(diff)
+	}
+	if frag.Prft.Version != 1 || frag.Prft.NTPTimestamp != 456 || frag.Prft.ReferenceTrackID != 2 {
+		t.Errorf("unexpected prft %+v", frag.Prft)
+	}
+}
+
+func TestMultiTrackFragmentSamples(t *testing.T) {
+	trackIDs := []uint32{1, 2}
+	frag, err := CreateMultiTrackFragment(3, trackIDs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	trexs := []*TrexBox{{TrackID: 1}, {TrackID: 2}}
+	wanted := make([][]FullSample, len(trackIDs))
+	// Interleave samples of the two tracks in two chunks each
+	for chunk := 0; chunk < 2; chunk++ {
+		for i, trackID := range trackIDs {
+			for j := 0; j < 2; j++ {
+				nr := len(wanted[i])
+				size := uint32(10*trackID + uint32(nr))
+				fs := FullSample{
+					Sample:     NewSample(SyncSampleFlags, 1000*trackID, size, 0),
+					DecodeTime: uint64(nr) * uint64(1000*trackID),
+					Data:       bytes.Repeat([]byte{byte(trackID<<4 | uint32(nr))}, int(size)),
+				}
+				if err := frag.AddFullSampleToTrack(fs, trackID); err != nil {
+					t.Fatal(err)
+				}
+				wanted[i] = append(wanted[i], fs)
+			}
+		}
+	}
+	if err := frag.AddFullSampleToTrack(FullSample{}, 3); err == nil {
+		t.Error("expected error when adding sample to non-existing track")
+	}
+	buf := bytes.Buffer{}
+	if err := frag.Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	encoded := buf.Bytes()
+	decFrag := decodeFragment(t, encoded)
+	for i, trex := range trexs {
+		samples, err := decFrag.GetFullSamples(trex)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := deep.Equal(samples, wanted[i]); diff != nil {
+			t.Errorf("track %d: %v", trex.TrackID, diff)
+		}
+	}
+
+	// Single trun per track without data offsets and default-base-is-moof.
+	// The data of the second track then starts directly after the first track's data.
+	single, err := CreateMultiTrackFragment(4, trackIDs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, trackID := range trackIDs {
+		for _, fs := range wanted[i] {
+			if err := single.AddFullSampleToTrack(fs, trackID); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	buf.Reset()
+	if err := single.Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	decFrag = decodeFragment(t, buf.Bytes())
+	for _, traf := range decFrag.Moof.Trafs {
+		traf.Tfhd.Flags &^= defaultBaseIsMoof
+	}
+	secondTrun := decFrag.Moof.Trafs[1].Trun
+	secondTrun.Flags &^= TrunDataOffsetPresentFlag
+	for i, trex := range trexs {
+		samples, err := decFrag.GetFullSamples(trex)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := deep.Equal(samples, wanted[i]); diff != nil {
+			t.Errorf("implicit base, track %d: %v", trex.TrackID, diff)
+		}
+	}
+}
+
+func TestSetTrunDataOffsetsMultiTraf(t *testing.T) {
+	trackIDs := []uint32{1, 2}
+	trexs := []*TrexBox{{TrackID: 1}, {TrackID: 2}}
+	frag, err := CreateMultiTrackFragment(1, trackIDs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Truns without write order, so data is in moof order
+	wanted := make([][]FullSample, len(trackIDs))
+	for i, traf := range frag.Moof.Trafs {
+		trun := CreateTrun(0)
+		if err := traf.AddChild(trun); err != nil {
+			t.Fatal(err)
+		}
+		for nr := 0; nr < 3; nr++ {
+			size := 5 + 3*i + nr
+			fs := FullSample{
+				Sample:     NewSample(SyncSampleFlags, 1000, uint32(size), 0),
+				DecodeTime: uint64(nr) * 1000,
+				Data:       bytes.Repeat([]byte{byte(i<<4 | nr)}, size),
+			}
+			if nr == 0 {
+				traf.Tfdt.SetBaseMediaDecodeTime(0)
+			}
+			trun.AddSample(fs.Sample)
+			wanted[i] = append(wanted[i], fs)
+		}
+	}
+	for i := range trackIDs {
+		for _, fs := range wanted[i] {
+			frag.Mdat.AddSampleData(fs.Data)
+		}
+	}
+	frag.SetTrunDataOffsets()
+	dataStart := int32(frag.Moof.Size() + frag.Mdat.HeaderSize())
+	if do := frag.Moof.Trafs[0].Trun.DataOffset; do != dataStart {
+		t.Errorf("first data offset %d instead of %d", do, dataStart)
+	}
+	if do := frag.Moof.Trafs[1].Trun.DataOffset; do != dataStart+5+6+7 {
+		t.Errorf("second data offset %d instead of %d", do, dataStart+18)
+	}
+
+	checkSamples := func(desc string, frag *Fragment) {
+		t.Helper()
+		buf := bytes.Buffer{}
+		if err := frag.Encode(&buf); err != nil {
+			t.Fatal(err)
+		}
+		decFrag := decodeFragment(t, buf.Bytes())
+		for i, trex := range trexs {
+			samples, err := decFrag.GetFullSamples(trex)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := deep.Equal(samples, wanted[i]); diff != nil {
+				t.Errorf("%s, track %d: %v", desc, trex.TrackID, diff)
+			}
+		}
+	}
+	checkSamples("created", frag)
+
+	// Make the moof of a decoded fragment larger. The data offsets must be shifted on encode.
+	buf := bytes.Buffer{}
+	if err := frag.Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	decFrag := decodeFragment(t, buf.Bytes())
+	for _, traf := range decFrag.Moof.Trafs {
+		traf.Tfdt.Version = 1
+	}
+	checkSamples("decoded with larger moof", decFrag)
+}
+
+func decodeFragment(t *testing.T, data []byte) *Fragment {
+	t.Helper()
+	sr := bits.NewFixedSliceReader(data)
+	frag := NewFragment()
+	var pos uint64
+	for pos < uint64(len(data)) {
+		box, err := DecodeBoxSR(pos, sr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		frag.AddChild(box)
+		pos += box.Size()
+	}
+	return frag
+}
+
+func TestFragmentTrim(t *testing.T) {
+	frag, err := CreateFragment(3, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 8; i++ {
+		flags := NonSyncSampleFlags
+		if i%4 == 0 {
+			flags = SyncSampleFlags
+		}
+		data := []byte{byte(i), byte(i)}
+		fs := FullSample{Sample{flags, 1000, 2, int32(1000 * (i % 2))}, 5000 + uint64(i)*1000, data}
+		frag.AddFullSample(fs)
+	}
+	buf := bytes.Buffer{}
+	if err = frag.Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	frag = decodeFragment(t, buf.Bytes())
+	orig, err := frag.GetFullSamples(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Start at sample 6 (not sync) is moved back to sample 4
+	if err = frag.Trim(nil, 10500, 12000); err != nil {
+		t.Fatal(err)
+	}
+	buf.Reset()
+	if err = frag.Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	trimmed := decodeFragment(t, buf.Bytes())
+	if tfdt := trimmed.Moof.Traf.Tfdt.BaseMediaDecodeTime(); tfdt != 9000 {
+		t.Errorf("baseMediaDecodeTime %d instead of 9000", tfdt)
+	}
+	got, err := trimmed.GetFullSamples(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := deep.Equal(got, orig[4:7]); diff != nil {
+		t.Error(diff)
+	}
+	if err = trimmed.Trim(nil, 20000, 30000); err == nil {
+		t.Error("no error when trimming to range without samples")
+	}
+}
+
+func TestMergeFragments(t *testing.T) {
+	var frags []*Fragment
+	var orig []FullSample
+	decodeTime := uint64(3000)
+	for i, nrSamples := range []int{3, 2, 4} {
+		buf := bytes.Buffer{}
+		if err := createTestFragment(t, uint32(i+1), decodeTime, nrSamples, true).Encode(&buf); err != nil {
+			t.Fatal(err)
+		}
+		frag := decodeFragment(t, buf.Bytes())
+		samples, err := frag.GetFullSamples(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		orig = append(orig, samples...)
+		frags = append(frags, frag)
+		decodeTime += uint64(nrSamples) * 1000
+	}
+	merged, err := MergeFragments(frags, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(merged.Moof.Traf.Truns) != 1 {
+		t.Errorf("got %d truns instead of 1", len(merged.Moof.Traf.Truns))
+	}
+	buf := bytes.Buffer{}
+	if err = merged.Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	dec := decodeFragment(t, buf.Bytes())
+	if seqNr := dec.Moof.Mfhd.SequenceNumber; seqNr != 1 {
+		t.Errorf("sequence number %d instead of 1", seqNr)
+	}
+	if tfdt := dec.Moof.Traf.Tfdt.BaseMediaDecodeTime(); tfdt != 3000 {
+		t.Errorf("baseMediaDecodeTime %d instead of 3000", tfdt)
+	}
+	got, err := dec.GetFullSamples(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := deep.Equal(got, orig); diff != nil {
+		t.Error(diff)
+	}
+	if _, err = MergeFragments([]*Fragment{frags[0], frags[2]}, nil); err == nil {
+		t.Error("no error for fragments that are not contiguous")
+	}
+
+	// A negative composition time offset in a later fragment needs trun version 1
+	frags[0].Moof.Traf.Trun.Version = 0
+	frags[2].Moof.Traf.Trun.Samples[1].CompositionTimeOffset = -1000
+	merged, err = MergeFragments(frags, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := merged.Moof.Traf.Trun.Version; v != 1 {
+		t.Errorf("trun version %d instead of 1 with negative composition time offset", v)
+	}
+}
+
+func TestFragmentPresentationDuration(t *testing.T) {
+	frag, err := CreateFragment(1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Constant composition time offset, so presentation duration equals sum of durations
+	for i := 0; i < 4; i++ {
+		data := []byte{byte(i)}
+		frag.AddFullSample(FullSample{Sample{SyncSampleFlags, 1000, 1, 1000}, 10000 + uint64(i)*1000, data})
+	}
+	if dur := frag.PresentationDuration(nil); dur != 4000 {
+		t.Errorf("presentation duration %d instead of 4000", dur)
+	}
+	// Composition times 11000, 14000, 12000, 15000, so the last sample ends after the decode end
+	frag.Moof.Traf.Trun.Samples[1].CompositionTimeOffset = 3000
+	frag.Moof.Traf.Trun.Samples[2].CompositionTimeOffset = 0
+	frag.Moof.Traf.Trun.Samples[3].CompositionTimeOffset = 2000
+	if dur := frag.PresentationDuration(nil); dur != 5000 {
+		t.Errorf("presentation duration %d instead of 5000", dur)
+	}
+}
+
+func TestFragmentSAPType(t *testing.T) {
+	leadingNonDecodable := SampleFlags{IsLeading: 1, SampleIsNonSync: true}.Encode()
+	testCases := []struct {
+		desc              string
+		samples           []Sample // flags, dur, size, compositionTimeOffset
+		wantSAPType       uint32
+		wantStartsWithSAP bool
+		wantSAPDeltaTime  uint32
+	}{
+		{"no reordering", []Sample{{SyncSampleFlags, 1000, 1, 0}, {NonSyncSampleFlags, 1000, 1, 0}}, 1, true, 0},
+		{"closed GOP with B-frames", []Sample{{SyncSampleFlags, 1000, 1, 1000}, {NonSyncSampleFlags, 1000, 1, 2000},
+			{NonSyncSampleFlags, 1000, 1, -1000}}, 1, true, 0},
+		{"decodable leading sample", []Sample{{SyncSampleFlags, 1000, 1, 2000}, {NonSyncSampleFlags, 1000, 1, 0}},
+			2, true, 0},
+		{"non-decodable leading samples", []Sample{{SyncSampleFlags, 1000, 1, 3000}, {leadingNonDecodable, 1000, 1, 0},
+			{leadingNonDecodable, 1000, 1, 0}, {NonSyncSampleFlags, 1000, 1, 1000}}, 3, true, 2000},
+		{"not starting with SAP", []Sample{{NonSyncSampleFlags, 1000, 1, 0}, {SyncSampleFlags, 1000, 1, 0}},
+			1, false, 1000},
+		{"no SAP", []Sample{{NonSyncSampleFlags, 1000, 1, 0}}, 0, false, 0},
+	}
+	for _, tc := range testCases {
+		frag, err := CreateFragment(1, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		frag.AddSamples(tc.samples, 10000)
+		sapType, startsWithSAP, sapDeltaTime, err := frag.SAPType(&TrexBox{TrackID: 1})
+		if err != nil {
+			t.Errorf("%s: %v", tc.desc, err)
+			continue
+		}
+		if sapType != tc.wantSAPType || startsWithSAP != tc.wantStartsWithSAP || sapDeltaTime != tc.wantSAPDeltaTime {
+			t.Errorf("%s: got SAP type %d startsWithSAP %t delta %d, wanted %d %t %d", tc.desc, sapType,
+				startsWithSAP, sapDeltaTime, tc.wantSAPType, tc.wantStartsWithSAP, tc.wantSAPDeltaTime)
+		}
+	}
+	frag, err := CreateFragment(1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, _, err = frag.SAPType(&TrexBox{TrackID: 1}); err == nil {
+		t.Error("no error for fragment without samples")
+	}
+}
+
+func TestFragmentSetTrackID(t *testing.T) {
+	frag, err := CreateMultiTrackFragment(1, []uint32{1, 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = frag.AddPrft(1, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err = frag.SetTrackID(1, 2); err == nil {
+		t.Error("no error when changing to existing trackID")
+	}
+	if err = frag.SetTrackID(1, 10); err != nil {
+		t.Fatal(err)
+	}
+	if id := frag.Moof.Trafs[0].Tfhd.TrackID; id != 10 {
+		t.Errorf("tfhd trackID %d instead of 10", id)
+	}
+	if id := frag.Prft.ReferenceTrackID; id != 10 {
+		t.Errorf("prft trackID %d instead of 10", id)
+	}
+}
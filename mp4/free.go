@@ -12,6 +12,15 @@ type FreeBox struct {
 	notDecoded []byte
 }
 
+// NewFreeBox creates a free box with total size size including the 8-byte header.
+// The payload is zeros, and a size below 8 gives an empty box.
+func NewFreeBox(size uint64) *FreeBox {
+	if size < boxHeaderSize {
+		size = boxHeaderSize
+	}
+	return &FreeBox{Name: "free", notDecoded: make([]byte, size-boxHeaderSize)}
+}
+
 // DecodeFree - box-specific decode
 func DecodeFree(hdr BoxHeader, startPos uint64, r io.Reader) (Box, error) {
 	data, err := readBoxBody(r, hdr)
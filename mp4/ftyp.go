@@ -2,6 +2,7 @@ package mp4
 
 import (
 	"encoding/binary"
+	"fmt"
 	"io"
 
 	"github.com/Eyevinn/mp4ff/bits"
@@ -29,6 +30,34 @@ func (b *FtypBox) AddCompatibleBrands(compatibleBrands []string) {
 	}
 }
 
+// AddCompatibleBrand adds brand as compatible brand unless it is already one.
+// An error is returned if brand is not 4 characters.
+func (b *FtypBox) AddCompatibleBrand(brand string) error {
+	if len(brand) != 4 {
+		return fmt.Errorf("brand %q is not 4 characters", brand)
+	}
+	for _, cb := range b.CompatibleBrands() {
+		if cb == brand {
+			return nil
+		}
+	}
+	b.data = append(b.data, []byte(brand)...)
+	return nil
+}
+
+// HasBrand - true if brand is the major brand or one of the compatible brands
+func (b *FtypBox) HasBrand(brand string) bool {
+	if b.MajorBrand() == brand {
+		return true
+	}
+	for _, cb := range b.CompatibleBrands() {
+		if cb == brand {
+			return true
+		}
+	}
+	return false
+}
+
 // CompatibleBrands - slice of compatible brands (4 chars each)
 func (b *FtypBox) CompatibleBrands() []string {
 	nrCompatibleBrands := (len(b.data) - 8) / 4
@@ -2,6 +2,8 @@ package mp4
 
 import (
 	"testing"
+
+	"github.com/go-test/deep"
 )
 
 func TestFtyp(t *testing.T) {
@@ -9,3 +11,55 @@ func TestFtyp(t *testing.T) {
 	ftyp := CreateFtyp()
 	boxDiffAfterEncodeAndDecode(t, ftyp)
 }
+
+func TestBrandHelpers(t *testing.T) {
+	ftyp := CreateFtyp()
+	if err := ftyp.AddCompatibleBrand("cmf2"); err != nil {
+		t.Error(err)
+	}
+	if err := ftyp.AddCompatibleBrand("dash"); err != nil {
+		t.Error(err)
+	}
+	if err := ftyp.AddCompatibleBrand("toolong"); err == nil {
+		t.Error("no error for brand with 7 characters")
+	}
+	if diff := deep.Equal(ftyp.CompatibleBrands(), []string{"dash", "iso6", "cmf2"}); diff != nil {
+		t.Error(diff)
+	}
+	for brand, wanted := range map[string]bool{"cmfc": true, "cmf2": true, "iso6": true, "msdh": false} {
+		if got := ftyp.HasBrand(brand); got != wanted {
+			t.Errorf("ftyp HasBrand(%q) = %t", brand, got)
+		}
+	}
+	boxDiffAfterEncodeAndDecode(t, ftyp)
+
+	styp := CreateStyp()
+	if err := styp.AddCompatibleBrand("cmfc"); err != nil {
+		t.Error(err)
+	}
+	if err := styp.AddCompatibleBrand("cmfc"); err != nil {
+		t.Error(err)
+	}
+	if diff := deep.Equal(styp.CompatibleBrands(), []string{"dash", "msdh", "cmfc"}); diff != nil {
+		t.Error(diff)
+	}
+	if !styp.HasBrand("cmfc") || !styp.HasBrand("cmfs") || styp.HasBrand("iso6") {
+		t.Error("wrong styp HasBrand result")
+	}
+
+	f := NewFile()
+	if major, _, _ := f.Brands(); major != "" {
+		t.Errorf("got major brand %q for empty file", major)
+	}
+	seg := NewMediaSegmentWithStyp(styp)
+	f.AddMediaSegment(seg)
+	major, minor, compatible := f.Brands()
+	if major != "cmfs" || minor != 0 || len(compatible) != 3 {
+		t.Errorf("got brands %s %d %v from styp", major, minor, compatible)
+	}
+	f.AddChild(NewFtyp("isom", 512, []string{"iso2"}), 0)
+	major, minor, compatible = f.Brands()
+	if major != "isom" || minor != 512 || len(compatible) != 1 {
+		t.Errorf("got brands %s %d %v from ftyp", major, minor, compatible)
+	}
+}
@@ -33,13 +33,13 @@ func CreateHdlr(mediaOrHdlrType string) (*HdlrBox, error) {
 	case "audio", "soun":
 		hdlr.HandlerType = "soun"
 		hdlr.Name = "mp4ff audio handler"
-	case "subtitle", "subt":
+	case "subtitle", "subtitles", "subt", "stpp":
 		hdlr.HandlerType = "subt"
 		hdlr.Name = "mp4ff subtitle handler"
 	case "text", "wvtt":
 		hdlr.HandlerType = "text"
 		hdlr.Name = "mp4ff text handler"
-	case "meta":
+	case "meta", "metadata":
 		hdlr.HandlerType = "meta"
 		hdlr.Name = "mp4ff timed metadata handler"
 	case "clcp":
@@ -19,9 +19,11 @@ func TestHdlr(t *testing.T) {
 		{"audio", "soun", "mp4ff audio handler", ""},
 		{"soun", "soun", "mp4ff audio handler", ""},
 		{"subtitle", "subt", "mp4ff subtitle handler", ""},
+		{"stpp", "subt", "mp4ff subtitle handler", ""},
 		{"text", "text", "mp4ff text handler", ""},
 		{"wvtt", "text", "mp4ff text handler", ""},
 		{"meta", "meta", "mp4ff timed metadata handler", ""},
+		{"metadata", "meta", "mp4ff timed metadata handler", ""},
 		{"clcp", "subt", "mp4ff closed captions handler", ""},
 		{"roses", "", "", "handler type is not four characters: roses"},
 		{"auxv", "auxv", "mp4ff auxv handler", ""},
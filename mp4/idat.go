@@ -0,0 +1,69 @@
+package mp4
+
+import (
+	"io"
+
+	"github.com/Eyevinn/mp4ff/bits"
+)
+
+// IdatBox - Item Data Box (idat)
+//
+// Contained in: Meta Box (meta)
+//
+// Defined in ISO/IEC 14496-12 Section 8.11.11. Holds item data referred to by
+// iloc items with construction method 1. Offsets of such items are relative to the start of Data.
+type IdatBox struct {
+	Data []byte
+}
+
+// DecodeIdat - box-specific decode
+func DecodeIdat(hdr BoxHeader, startPos uint64, r io.Reader) (Box, error) {
+	data, err := readBoxBody(r, hdr)
+	if err != nil {
+		return nil, err
+	}
+	return &IdatBox{Data: data}, nil
+}
+
+// DecodeIdatSR - box-specific decode
+func DecodeIdatSR(hdr BoxHeader, startPos uint64, sr bits.SliceReader) (Box, error) {
+	return &IdatBox{Data: sr.ReadBytes(hdr.payloadLen())}, sr.AccError()
+}
+
+// Type - box type
+func (b *IdatBox) Type() string {
+	return "idat"
+}
+
+// Size - calculated size of box
+func (b *IdatBox) Size() uint64 {
+	return uint64(boxHeaderSize + len(b.Data))
+}
+
+// Encode - write box to w
+func (b *IdatBox) Encode(w io.Writer) error {
+	sw := bits.NewFixedSliceWriter(int(b.Size()))
+	err := b.EncodeSW(sw)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(sw.Bytes())
+	return err
+}
+
+// EncodeSW - box-specific encode to slicewriter
+func (b *IdatBox) EncodeSW(sw bits.SliceWriter) error {
+	err := EncodeHeaderSW(b, sw)
+	if err != nil {
+		return err
+	}
+	sw.WriteBytes(b.Data)
+	return sw.AccError()
+}
+
+// Info - write box-specific information
+func (b *IdatBox) Info(w io.Writer, specificBoxLevels, indent, indentStep string) error {
+	bd := newInfoDumper(w, indent, b, -1, 0)
+	bd.write(" - dataSize: %d", len(b.Data))
+	return bd.err
+}
@@ -0,0 +1,177 @@
+package mp4
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/Eyevinn/mp4ff/bits"
+)
+
+// IinfBox - Item Information Box (iinf)
+//
+// Contained in: Meta Box (meta)
+//
+// Defined in ISO/IEC 14496-12 Section 8.11.6. Has one infe child box per item.
+// The entry count is 16 bits for version 0 and 32 bits otherwise.
+type IinfBox struct {
+	Version  byte
+	Flags    uint32
+	Infes    []*InfeBox
+	Children []Box
+}
+
+// AddChild - Add a child box
+func (b *IinfBox) AddChild(child Box) {
+	if infe, ok := child.(*InfeBox); ok {
+		b.Infes = append(b.Infes, infe)
+	}
+	b.Children = append(b.Children, child)
+}
+
+// DecodeIinf - box-specific decode
+func DecodeIinf(hdr BoxHeader, startPos uint64, r io.Reader) (Box, error) {
+	var versionAndFlags uint32
+	err := binary.Read(r, binary.BigEndian, &versionAndFlags)
+	if err != nil {
+		return nil, err
+	}
+	b := &IinfBox{
+		Version: byte(versionAndFlags >> 24),
+		Flags:   versionAndFlags & flagsMask,
+	}
+	var entryCount uint32
+	var headerSize uint64 = 8 + 4 + 2
+	if b.Version == 0 {
+		var count16 uint16
+		err = binary.Read(r, binary.BigEndian, &count16)
+		entryCount = uint32(count16)
+	} else {
+		err = binary.Read(r, binary.BigEndian, &entryCount)
+		headerSize += 2
+	}
+	if err != nil {
+		return nil, err
+	}
+	// Note higher startPos for children since not simple container.
+	children, err := DecodeContainerChildren(hdr, startPos+headerSize, startPos+hdr.Size, r)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range children {
+		b.AddChild(c)
+	}
+	if int(entryCount) != len(b.Children) {
+		return nil, fmt.Errorf("inconsistent entry count in iinf")
+	}
+	return b, nil
+}
+
+// DecodeIinfSR - box-specific decode
+func DecodeIinfSR(hdr BoxHeader, startPos uint64, sr bits.SliceReader) (Box, error) {
+	versionAndFlags := sr.ReadUint32()
+	b := &IinfBox{
+		Version: byte(versionAndFlags >> 24),
+		Flags:   versionAndFlags & flagsMask,
+	}
+	var entryCount uint32
+	var headerSize uint64 = 8 + 4 + 2
+	if b.Version == 0 {
+		entryCount = uint32(sr.ReadUint16())
+	} else {
+		entryCount = sr.ReadUint32()
+		headerSize += 2
+	}
+	if err := sr.AccError(); err != nil {
+		return nil, fmt.Errorf("decode iinf: %w", err)
+	}
+	// Note higher startPos for children since not simple container.
+	children, err := DecodeContainerChildrenSR(hdr, startPos+headerSize, startPos+hdr.Size, sr)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range children {
+		b.AddChild(c)
+	}
+	if int(entryCount) != len(b.Children) {
+		return nil, fmt.Errorf("inconsistent entry count in iinf")
+	}
+	return b, sr.AccError()
+}
+
+// GetInfe - get item info entry for itemID
+func (b *IinfBox) GetInfe(itemID uint32) (*InfeBox, bool) {
+	for _, infe := range b.Infes {
+		if infe.ItemID == itemID {
+			return infe, true
+		}
+	}
+	return nil, false
+}
+
+// Type - box type
+func (b *IinfBox) Type() string {
+	return "iinf"
+}
+
+// Size - calculated size of box
+func (b *IinfBox) Size() uint64 {
+	if b.Version == 0 {
+		return containerSize(b.Children) + 6
+	}
+	return containerSize(b.Children) + 8
+}
+
+// GetChildren - list of child boxes
+func (b *IinfBox) GetChildren() []Box {
+	return b.Children
+}
+
+// Encode - write iinf box to w including children
+func (b *IinfBox) Encode(w io.Writer) error {
+	sw := bits.NewFixedSliceWriter(int(b.Size()))
+	err := b.EncodeSW(sw)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(sw.Bytes())
+	return err
+}
+
+// EncodeSW - write iinf box to sw including children
+func (b *IinfBox) EncodeSW(sw bits.SliceWriter) error {
+	err := EncodeHeaderSW(b, sw)
+	if err != nil {
+		return err
+	}
+	versionAndFlags := (uint32(b.Version) << 24) + b.Flags
+	sw.WriteUint32(versionAndFlags)
+	if b.Version == 0 {
+		sw.WriteUint16(uint16(len(b.Children)))
+	} else {
+		sw.WriteUint32(uint32(len(b.Children)))
+	}
+	for _, c := range b.Children {
+		err = c.EncodeSW(sw)
+		if err != nil {
+			return err
+		}
+	}
+	return sw.AccError()
+}
+
+// Info - write box-specific information
+func (b *IinfBox) Info(w io.Writer, specificBoxLevels, indent, indentStep string) error {
+	bd := newInfoDumper(w, indent, b, int(b.Version), b.Flags)
+	if bd.err != nil {
+		return bd.err
+	}
+	var err error
+	for _, c := range b.Children {
+		err = c.Info(w, specificBoxLevels, indent+indentStep, indentStep)
+		if err != nil {
+			return err
+		}
+	}
+	return err
+}
@@ -0,0 +1,38 @@
+package mp4
+
+import (
+	"testing"
+)
+
+func TestIinf(t *testing.T) {
+	for _, version := range []byte{0, 1} {
+		iinf := &IinfBox{Version: version}
+		iinf.AddChild(&InfeBox{Version: 2, ItemID: 1, ItemType: "hvc1", ItemName: "image"})
+		iinf.AddChild(&InfeBox{Version: 2, ItemID: 2, ItemType: "hvc1"})
+		iinf.AddChild(&InfeBox{Version: 3, ItemID: 70000, ItemType: "Exif"})
+		boxDiffAfterEncodeAndDecode(t, iinf)
+		infe, ok := iinf.GetInfe(2)
+		if !ok || infe.ItemType != "hvc1" {
+			t.Errorf("version %d: did not get infe for item 2", version)
+		}
+		if _, ok := iinf.GetInfe(3); ok {
+			t.Errorf("version %d: got infe for non-existing item 3", version)
+		}
+	}
+}
+
+func TestInfe(t *testing.T) {
+	testCases := []*InfeBox{
+		{Version: 0, ItemID: 1, ItemName: "name", ContentType: "text/plain"},
+		{Version: 0, ItemID: 1, ItemName: "name", ContentType: "text/plain", ContentEncoding: "gzip"},
+		{Version: 1, ItemID: 1, ItemName: "name", ContentType: "text/plain", ContentEncoding: "gzip",
+			Extension: []byte("fdel\x00\x01\x02")},
+		{Version: 2, ItemID: 2, ItemProtectionIndex: 1, ItemType: "mime", ItemName: "xmp",
+			ContentType: "application/rdf+xml"},
+		{Version: 2, ItemID: 3, ItemType: "uri ", ItemURIType: "urn:example"},
+		{Version: 3, ItemID: 100000, ItemType: "grid", ItemName: "grid"},
+	}
+	for _, infe := range testCases {
+		boxDiffAfterEncodeAndDecode(t, infe)
+	}
+}
@@ -0,0 +1,247 @@
+package mp4
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/Eyevinn/mp4ff/bits"
+)
+
+// IlocBox - Item Location Box (iloc)
+//
+// Contained in: Meta Box (meta)
+//
+// Defined in ISO/IEC 14496-12 Section 8.11.3. Gives the location of the data of items as extents.
+// OffsetSize, LengthSize, BaseOffsetSize and IndexSize are field sizes in bytes, and are 0, 4, or 8.
+// IndexSize is only used for version 1 and 2.
+type IlocBox struct {
+	Version        byte
+	Flags          uint32
+	OffsetSize     byte
+	LengthSize     byte
+	BaseOffsetSize byte
+	IndexSize      byte
+	Items          []IlocItem
+}
+
+// IlocItem - location of the data of one item.
+// ConstructionMethod 0 is file offset, 1 is offset in the idat box, and 2 is offset in a referred item.
+// ConstructionMethod is only present in version 1 and 2.
+type IlocItem struct {
+	ItemID             uint32
+	ConstructionMethod byte
+	DataReferenceIndex uint16
+	BaseOffset         uint64
+	Extents            []IlocExtent
+}
+
+// IlocExtent - one extent of item data. The data starts at BaseOffset + Offset of the item.
+// A Length of 0 means that the extent extends to the end of the referred data.
+type IlocExtent struct {
+	Index  uint64
+	Offset uint64
+	Length uint64
+}
+
+// DecodeIloc - box-specific decode
+func DecodeIloc(hdr BoxHeader, startPos uint64, r io.Reader) (Box, error) {
+	data, err := readBoxBody(r, hdr)
+	if err != nil {
+		return nil, err
+	}
+	sr := bits.NewFixedSliceReader(data)
+	return DecodeIlocSR(hdr, startPos, sr)
+}
+
+// DecodeIlocSR - box-specific decode
+func DecodeIlocSR(hdr BoxHeader, startPos uint64, sr bits.SliceReader) (Box, error) {
+	versionAndFlags := sr.ReadUint32()
+	b := IlocBox{
+		Version: byte(versionAndFlags >> 24),
+		Flags:   versionAndFlags & flagsMask,
+	}
+	if b.Version > 2 {
+		return nil, fmt.Errorf("decode iloc: unknown version %d", b.Version)
+	}
+	sizes := sr.ReadUint16()
+	b.OffsetSize = byte(sizes >> 12)
+	b.LengthSize = byte(sizes>>8) & 0x0f
+	b.BaseOffsetSize = byte(sizes>>4) & 0x0f
+	if b.Version > 0 {
+		b.IndexSize = byte(sizes) & 0x0f
+	}
+	for _, size := range []byte{b.OffsetSize, b.LengthSize, b.BaseOffsetSize, b.IndexSize} {
+		if size != 0 && size != 4 && size != 8 {
+			return nil, fmt.Errorf("decode iloc: bad field size %d", size)
+		}
+	}
+	var itemCount uint32
+	if b.Version < 2 {
+		itemCount = uint32(sr.ReadUint16())
+	} else {
+		itemCount = sr.ReadUint32()
+	}
+	if int(itemCount)*4 > hdr.payloadLen()-6 {
+		return nil, fmt.Errorf("decode iloc: item count %d too big for box size %d", itemCount, hdr.Size)
+	}
+	b.Items = make([]IlocItem, itemCount)
+	for i := range b.Items {
+		item := &b.Items[i]
+		if b.Version < 2 {
+			item.ItemID = uint32(sr.ReadUint16())
+		} else {
+			item.ItemID = sr.ReadUint32()
+		}
+		if b.Version > 0 {
+			item.ConstructionMethod = byte(sr.ReadUint16() & 0x0f)
+		}
+		item.DataReferenceIndex = sr.ReadUint16()
+		item.BaseOffset = readIlocValue(sr, b.BaseOffsetSize)
+		extentCount := sr.ReadUint16()
+		if sr.AccError() != nil {
+			break
+		}
+		item.Extents = make([]IlocExtent, extentCount)
+		for j := range item.Extents {
+			if b.Version > 0 {
+				item.Extents[j].Index = readIlocValue(sr, b.IndexSize)
+			}
+			item.Extents[j].Offset = readIlocValue(sr, b.OffsetSize)
+			item.Extents[j].Length = readIlocValue(sr, b.LengthSize)
+		}
+	}
+	if err := sr.AccError(); err != nil {
+		return nil, fmt.Errorf("decode iloc: %w", err)
+	}
+	return &b, nil
+}
+
+// readIlocValue - read value of size 0, 4, or 8 bytes
+func readIlocValue(sr bits.SliceReader, size byte) uint64 {
+	switch size {
+	case 4:
+		return uint64(sr.ReadUint32())
+	case 8:
+		return sr.ReadUint64()
+	default:
+		return 0
+	}
+}
+
+// writeIlocValue - write value of size 0, 4, or 8 bytes
+func writeIlocValue(sw bits.SliceWriter, size byte, value uint64) {
+	switch size {
+	case 4:
+		sw.WriteUint32(uint32(value))
+	case 8:
+		sw.WriteUint64(value)
+	}
+}
+
+// GetItem - get location of item with itemID
+func (b *IlocBox) GetItem(itemID uint32) (IlocItem, bool) {
+	for _, item := range b.Items {
+		if item.ItemID == itemID {
+			return item, true
+		}
+	}
+	return IlocItem{}, false
+}
+
+// Type - box type
+func (b *IlocBox) Type() string {
+	return "iloc"
+}
+
+// Size - calculated size of box
+func (b *IlocBox) Size() uint64 {
+	size := uint64(boxHeaderSize + 4 + 2 + 2)
+	itemIDSize := uint64(2)
+	if b.Version == 2 {
+		size += 2
+		itemIDSize = 4
+	}
+	for _, item := range b.Items {
+		size += itemIDSize + 2 + uint64(b.BaseOffsetSize) + 2
+		if b.Version > 0 {
+			size += 2
+		}
+		extentSize := uint64(b.OffsetSize) + uint64(b.LengthSize)
+		if b.Version > 0 {
+			extentSize += uint64(b.IndexSize)
+		}
+		size += extentSize * uint64(len(item.Extents))
+	}
+	return size
+}
+
+// Encode - write box to w
+func (b *IlocBox) Encode(w io.Writer) error {
+	sw := bits.NewFixedSliceWriter(int(b.Size()))
+	err := b.EncodeSW(sw)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(sw.Bytes())
+	return err
+}
+
+// EncodeSW - box-specific encode to slicewriter
+func (b *IlocBox) EncodeSW(sw bits.SliceWriter) error {
+	err := EncodeHeaderSW(b, sw)
+	if err != nil {
+		return err
+	}
+	versionAndFlags := (uint32(b.Version) << 24) + b.Flags
+	sw.WriteUint32(versionAndFlags)
+	sizes := uint16(b.OffsetSize)<<12 | uint16(b.LengthSize&0x0f)<<8 | uint16(b.BaseOffsetSize&0x0f)<<4
+	if b.Version > 0 {
+		sizes |= uint16(b.IndexSize & 0x0f)
+	}
+	sw.WriteUint16(sizes)
+	if b.Version < 2 {
+		sw.WriteUint16(uint16(len(b.Items)))
+	} else {
+		sw.WriteUint32(uint32(len(b.Items)))
+	}
+	for _, item := range b.Items {
+		if b.Version < 2 {
+			sw.WriteUint16(uint16(item.ItemID))
+		} else {
+			sw.WriteUint32(item.ItemID)
+		}
+		if b.Version > 0 {
+			sw.WriteUint16(uint16(item.ConstructionMethod & 0x0f))
+		}
+		sw.WriteUint16(item.DataReferenceIndex)
+		writeIlocValue(sw, b.BaseOffsetSize, item.BaseOffset)
+		sw.WriteUint16(uint16(len(item.Extents)))
+		for _, e := range item.Extents {
+			if b.Version > 0 {
+				writeIlocValue(sw, b.IndexSize, e.Index)
+			}
+			writeIlocValue(sw, b.OffsetSize, e.Offset)
+			writeIlocValue(sw, b.LengthSize, e.Length)
+		}
+	}
+	return sw.AccError()
+}
+
+// Info - write box-specific information
+func (b *IlocBox) Info(w io.Writer, specificBoxLevels, indent, indentStep string) error {
+	bd := newInfoDumper(w, indent, b, int(b.Version), b.Flags)
+	bd.write(" - offsetSize=%d lengthSize=%d baseOffsetSize=%d indexSize=%d",
+		b.OffsetSize, b.LengthSize, b.BaseOffsetSize, b.IndexSize)
+	bd.write(" - itemCount: %d", len(b.Items))
+	level := getInfoLevel(b, specificBoxLevels)
+	if level > 0 {
+		for _, item := range b.Items {
+			bd.write(" - item: itemID=%d constructionMethod=%d dataReferenceIndex=%d baseOffset=%d",
+				item.ItemID, item.ConstructionMethod, item.DataReferenceIndex, item.BaseOffset)
+			for _, e := range item.Extents {
+				bd.write("   - extent: index=%d offset=%d length=%d", e.Index, e.Offset, e.Length)
+			}
+		}
+	}
+	return bd.err
+}
@@ -0,0 +1,37 @@
+package mp4
+
+import (
+	"testing"
+)
+
+func TestIloc(t *testing.T) {
+	testCases := []*IlocBox{
+		{Version: 0, OffsetSize: 4, LengthSize: 4, BaseOffsetSize: 0,
+			Items: []IlocItem{
+				{ItemID: 1, Extents: []IlocExtent{{Offset: 1000, Length: 2000}}},
+				{ItemID: 2, Extents: []IlocExtent{{Offset: 3000, Length: 100}, {Offset: 4000, Length: 200}}},
+			}},
+		{Version: 1, OffsetSize: 8, LengthSize: 4, BaseOffsetSize: 4, IndexSize: 4,
+			Items: []IlocItem{
+				{ItemID: 1, ConstructionMethod: 1, DataReferenceIndex: 0, BaseOffset: 16,
+					Extents: []IlocExtent{{Index: 1, Offset: 8, Length: 24}}},
+			}},
+		{Version: 2, OffsetSize: 0, LengthSize: 8, BaseOffsetSize: 8,
+			Items: []IlocItem{
+				{ItemID: 70000, BaseOffset: 1 << 33, Extents: []IlocExtent{{Length: 1 << 32}}},
+			}},
+	}
+	for _, iloc := range testCases {
+		boxDiffAfterEncodeAndDecode(t, iloc)
+	}
+	item, ok := testCases[0].GetItem(2)
+	if !ok || len(item.Extents) != 2 || item.Extents[1].Offset != 4000 {
+		t.Errorf("did not get expected item 2: %v", item)
+	}
+}
+
+func TestIlocBadFieldSize(t *testing.T) {
+	iloc := &IlocBox{Version: 0, OffsetSize: 2, LengthSize: 4}
+	data := encodeBox(t, iloc)
+	assertBoxDecodeError(t, data, 0, "decode iloc pos 0: decode iloc: bad field size 2")
+}
@@ -1,6 +1,7 @@
 package mp4
 
 import (
+	"fmt"
 	"io"
 
 	"github.com/Eyevinn/mp4ff/bits"
@@ -18,29 +19,55 @@ func (b *IlstBox) AddChild(child Box) {
 }
 
 // DecodeIlstSR - box-specific decode
+//
+// All children are decoded as metadata item containers, since the item box types are keys
+// like ©nam or covr. Items that cannot be decoded as containers are kept as UnknownBox.
 func DecodeIlstSR(hdr BoxHeader, startPos uint64, sr bits.SliceReader) (Box, error) {
-	children, err := DecodeContainerChildrenSR(hdr, startPos+8, startPos+hdr.Size, sr)
+	b := &IlstBox{}
+	pos := startPos + 8
+	endPos := startPos + hdr.Size
+	for pos < endPos {
+		item, err := decodeIlstItemSR(pos, sr)
+		if err != nil {
+			return nil, err
+		}
+		b.AddChild(item)
+		pos += item.Size()
+	}
+	if pos != endPos {
+		return nil, fmt.Errorf("non-matching children box sizes in ilst")
+	}
+	return b, sr.AccError()
+}
+
+// decodeIlstItemSR decodes one metadata item box starting at startPos.
+func decodeIlstItemSR(startPos uint64, sr bits.SliceReader) (Box, error) {
+	hdr, err := DecodeHeaderSR(sr)
 	if err != nil {
 		return nil, err
 	}
-	b := &IlstBox{}
-	for _, c := range children {
-		b.AddChild(c)
+	if hdr.payloadLen() > sr.NrRemainingBytes() {
+		return nil, fmt.Errorf("ilst item %s size %d too large", hdr.Name, hdr.Size)
 	}
-	return b, nil
+	payload := sr.ReadBytes(hdr.payloadLen())
+	if err := sr.AccError(); err != nil {
+		return nil, err
+	}
+	item, err := DecodeGenericContainerBoxSR(hdr, startPos, bits.NewFixedSliceReader(payload))
+	if err != nil || item.Size() != hdr.Size {
+		return &UnknownBox{name: hdr.Name, size: hdr.Size, notDecoded: payload}, nil
+	}
+	return item, nil
 }
 
 // DecodeIlst - box-specific decode
 func DecodeIlst(hdr BoxHeader, startPos uint64, r io.Reader) (Box, error) {
-	children, err := DecodeContainerChildren(hdr, startPos+8, startPos+hdr.Size, r)
+	data, err := readBoxBody(r, hdr)
 	if err != nil {
 		return nil, err
 	}
-	b := &IlstBox{}
-	for _, c := range children {
-		b.AddChild(c)
-	}
-	return b, nil
+	sr := bits.NewFixedSliceReader(data)
+	return DecodeIlstSR(hdr, startPos, sr)
 }
 
 // Type - box-specific type
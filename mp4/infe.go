@@ -0,0 +1,178 @@
+package mp4
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/Eyevinn/mp4ff/bits"
+)
+
+// InfeBox - Item Info Entry Box (infe)
+//
+// Contained in: Item Information Box (iinf)
+//
+// Defined in ISO/IEC 14496-12 Section 8.11.6. Version 2 and 3 have an ItemType, like hvc1, grid,
+// Exif, mime, or uri. Version 0 and 1 have no ItemType, but always have ContentType.
+// ContentType and ContentEncoding are used for version 0 and 1 and for ItemType mime, and
+// ItemURIType is used for ItemType "uri ". The extension of version 1 is kept as raw bytes.
+type InfeBox struct {
+	Version             byte
+	Flags               uint32
+	ItemID              uint32
+	ItemProtectionIndex uint16
+	ItemType            string
+	ItemName            string
+	ContentType         string
+	ContentEncoding     string
+	ItemURIType         string
+	Extension           []byte
+	hasContentEncoding  bool
+}
+
+// DecodeInfe - box-specific decode
+func DecodeInfe(hdr BoxHeader, startPos uint64, r io.Reader) (Box, error) {
+	data, err := readBoxBody(r, hdr)
+	if err != nil {
+		return nil, err
+	}
+	sr := bits.NewFixedSliceReader(data)
+	return DecodeInfeSR(hdr, startPos, sr)
+}
+
+// DecodeInfeSR - box-specific decode
+func DecodeInfeSR(hdr BoxHeader, startPos uint64, sr bits.SliceReader) (Box, error) {
+	endPos := sr.GetPos() + hdr.payloadLen()
+	versionAndFlags := sr.ReadUint32()
+	b := InfeBox{
+		Version: byte(versionAndFlags >> 24),
+		Flags:   versionAndFlags & flagsMask,
+	}
+	if b.Version > 3 {
+		return nil, fmt.Errorf("decode infe: unknown version %d", b.Version)
+	}
+	if b.Version == 3 {
+		b.ItemID = sr.ReadUint32()
+	} else {
+		b.ItemID = uint32(sr.ReadUint16())
+	}
+	b.ItemProtectionIndex = sr.ReadUint16()
+	if b.Version >= 2 {
+		b.ItemType = sr.ReadFixedLengthString(4)
+	}
+	b.ItemName = sr.ReadZeroTerminatedString(endPos - sr.GetPos())
+	switch {
+	case b.Version < 2 || b.ItemType == "mime":
+		b.ContentType = sr.ReadZeroTerminatedString(endPos - sr.GetPos())
+		if sr.AccError() == nil && endPos > sr.GetPos() {
+			b.ContentEncoding = sr.ReadZeroTerminatedString(endPos - sr.GetPos())
+			b.hasContentEncoding = true
+		}
+		if b.Version == 1 && sr.AccError() == nil && endPos > sr.GetPos() {
+			b.Extension = sr.ReadBytes(endPos - sr.GetPos())
+		}
+	case b.ItemType == "uri ":
+		b.ItemURIType = sr.ReadZeroTerminatedString(endPos - sr.GetPos())
+	}
+	if err := sr.AccError(); err != nil {
+		return nil, fmt.Errorf("decode infe: %w", err)
+	}
+	return &b, nil
+}
+
+// Type - box type
+func (b *InfeBox) Type() string {
+	return "infe"
+}
+
+// Size - calculated size of box
+func (b *InfeBox) Size() uint64 {
+	size := uint64(boxHeaderSize + 4 + 2 + 2)
+	if b.Version == 3 {
+		size += 2
+	}
+	if b.Version >= 2 {
+		size += 4
+	}
+	size += uint64(len(b.ItemName) + 1)
+	switch {
+	case b.Version < 2 || b.ItemType == "mime":
+		size += uint64(len(b.ContentType) + 1)
+		if b.hasContentEncoding || b.ContentEncoding != "" || len(b.Extension) > 0 {
+			size += uint64(len(b.ContentEncoding) + 1)
+		}
+		if b.Version == 1 {
+			size += uint64(len(b.Extension))
+		}
+	case b.ItemType == "uri ":
+		size += uint64(len(b.ItemURIType) + 1)
+	}
+	return size
+}
+
+// Encode - write box to w
+func (b *InfeBox) Encode(w io.Writer) error {
+	sw := bits.NewFixedSliceWriter(int(b.Size()))
+	err := b.EncodeSW(sw)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(sw.Bytes())
+	return err
+}
+
+// EncodeSW - box-specific encode to slicewriter
+func (b *InfeBox) EncodeSW(sw bits.SliceWriter) error {
+	err := EncodeHeaderSW(b, sw)
+	if err != nil {
+		return err
+	}
+	versionAndFlags := (uint32(b.Version) << 24) + b.Flags
+	sw.WriteUint32(versionAndFlags)
+	if b.Version == 3 {
+		sw.WriteUint32(b.ItemID)
+	} else {
+		sw.WriteUint16(uint16(b.ItemID))
+	}
+	sw.WriteUint16(b.ItemProtectionIndex)
+	if b.Version >= 2 {
+		if len(b.ItemType) != 4 {
+			return fmt.Errorf("infe item type %q is not 4 characters", b.ItemType)
+		}
+		sw.WriteString(b.ItemType, false)
+	}
+	sw.WriteString(b.ItemName, true)
+	switch {
+	case b.Version < 2 || b.ItemType == "mime":
+		sw.WriteString(b.ContentType, true)
+		if b.hasContentEncoding || b.ContentEncoding != "" || len(b.Extension) > 0 {
+			sw.WriteString(b.ContentEncoding, true)
+		}
+		if b.Version == 1 {
+			sw.WriteBytes(b.Extension)
+		}
+	case b.ItemType == "uri ":
+		sw.WriteString(b.ItemURIType, true)
+	}
+	return sw.AccError()
+}
+
+// Info - write box-specific information
+func (b *InfeBox) Info(w io.Writer, specificBoxLevels, indent, indentStep string) error {
+	bd := newInfoDumper(w, indent, b, int(b.Version), b.Flags)
+	bd.write(" - itemID: %d", b.ItemID)
+	bd.write(" - itemProtectionIndex: %d", b.ItemProtectionIndex)
+	if b.Version >= 2 {
+		bd.write(" - itemType: %q", b.ItemType)
+	}
+	bd.write(" - itemName: %q", b.ItemName)
+	switch {
+	case b.Version < 2 || b.ItemType == "mime":
+		bd.write(" - contentType: %q", b.ContentType)
+		if b.ContentEncoding != "" {
+			bd.write(" - contentEncoding: %q", b.ContentEncoding)
+		}
+	case b.ItemType == "uri ":
+		bd.write(" - itemURIType: %q", b.ItemURIType)
+	}
+	return bd.err
+}
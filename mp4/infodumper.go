@@ -75,16 +75,39 @@ func (b *infoDumper) write(format string, p ...interface{}) {
 }
 
 // getInfoLevel - get info level for specific boxLike, or from all
+// specificBoxLevels is a comma-separated list of box:level or all:level.
+// A level may be replaced by a sample range, as in trun:1-100, which implies level 1,
+// or combined with one, as in trun:2:1-100. See getInfoLevelAndRange.
 func getInfoLevel(b boxLike, specificBoxLevels string) (level int) {
+	level, _ = getInfoLevelAndRange(b, specificBoxLevels)
+	return level
+}
+
+// infoSampleRange - 1-based inclusive range of sample numbers to write. last == 0 means no upper limit.
+type infoSampleRange struct {
+	first, last int
+}
+
+// contains - true if 1-based sample number nr is in range
+func (r infoSampleRange) contains(nr int) bool {
+	return nr >= r.first && (r.last == 0 || nr <= r.last)
+}
+
+// getInfoLevelAndRange - get info level and sample range for specific boxLike, or from all.
+// The range is used by boxes with per-sample info like trun and senc, and is given as
+// box:first-last or box:level:first-last, where first and last are 1-based and inclusive.
+// An empty last, as in trun:100-, means all samples from first.
+// The full range is returned if no range is specified.
+func getInfoLevelAndRange(b boxLike, specificBoxLevels string) (level int, sr infoSampleRange) {
+	sr = infoSampleRange{first: 1}
 	if len(specificBoxLevels) == 0 {
-		return level
+		return level, sr
 	}
 	boxesLevels := strings.Split(specificBoxLevels, ",")
 	boxType := b.Type()
 	if _, ok := b.(Descriptor); ok {
 		boxType = "esds"
 	}
-	var err error
 	for _, bl := range boxesLevels {
 		splitPos := strings.Index(bl, ":")
 		if splitPos < 1 {
@@ -93,17 +116,50 @@ func getInfoLevel(b boxLike, specificBoxLevels string) (level int) {
 		bt := bl[:splitPos]
 		nr := bl[splitPos+1:]
 		if bt == boxType {
-			level, err = strconv.Atoi(nr)
-			if err != nil {
-				level = 0
-			}
-			return level
+			return parseInfoLevelAndRange(nr)
 		} else if bt == "all" {
-			level, err = strconv.Atoi(nr)
-			if err != nil {
-				level = 0
-			}
+			level, sr = parseInfoLevelAndRange(nr)
 		}
 	}
-	return level
+	return level, sr
+}
+
+// parseInfoLevelAndRange - parse level, first-last, or level:first-last. Bad values give level 0.
+func parseInfoLevelAndRange(value string) (level int, sr infoSampleRange) {
+	sr = infoSampleRange{first: 1}
+	rangePart := ""
+	if dashPos := strings.Index(value, "-"); dashPos >= 0 {
+		level = 1
+		rangePart = value
+		if colonPos := strings.Index(value, ":"); colonPos >= 0 {
+			rangePart = value[colonPos+1:]
+			value = value[:colonPos]
+		} else {
+			value = ""
+		}
+	}
+	if value != "" {
+		var err error
+		level, err = strconv.Atoi(value)
+		if err != nil {
+			return 0, infoSampleRange{first: 1}
+		}
+	}
+	if rangePart == "" {
+		return level, sr
+	}
+	parts := strings.SplitN(rangePart, "-", 2)
+	first, err := strconv.Atoi(parts[0])
+	if err != nil || first < 1 {
+		return 0, infoSampleRange{first: 1}
+	}
+	sr.first = first
+	if parts[1] != "" {
+		last, err := strconv.Atoi(parts[1])
+		if err != nil || last < first {
+			return 0, infoSampleRange{first: 1}
+		}
+		sr.last = last
+	}
+	return level, sr
 }
@@ -43,3 +43,28 @@ func compareOrUpdateInfo(t *testing.T, b Informer, path string) error {
 	}
 	return nil
 }
+
+func TestGetInfoLevelAndRange(t *testing.T) {
+	trun := CreateTrun(0)
+	testCases := []struct {
+		levels    string
+		wantLevel int
+		wantRange infoSampleRange
+	}{
+		{"", 0, infoSampleRange{1, 0}},
+		{"trun:2", 2, infoSampleRange{1, 0}},
+		{"all:1,trun:3-5", 1, infoSampleRange{3, 5}},
+		{"trun:2:10-", 2, infoSampleRange{10, 0}},
+		{"all:1-2", 1, infoSampleRange{1, 2}},
+		{"senc:1-2", 0, infoSampleRange{1, 0}},
+		{"trun:5-3", 0, infoSampleRange{1, 0}},
+		{"trun:x-3", 0, infoSampleRange{1, 0}},
+	}
+	for _, tc := range testCases {
+		level, sr := getInfoLevelAndRange(trun, tc.levels)
+		if level != tc.wantLevel || sr != tc.wantRange {
+			t.Errorf("%q: got level %d range %v, wanted level %d range %v", tc.levels, level, sr,
+				tc.wantLevel, tc.wantRange)
+		}
+	}
+}
@@ -4,8 +4,10 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"math"
 
 	"github.com/Eyevinn/mp4ff/aac"
+	"github.com/Eyevinn/mp4ff/av1"
 	"github.com/Eyevinn/mp4ff/avc"
 	"github.com/Eyevinn/mp4ff/bits"
 	"github.com/Eyevinn/mp4ff/hevc"
@@ -103,6 +105,8 @@ func (s *InitSegment) AddEmptyTrack(timeScale uint32, mediaType, language string
 }
 
 // CreateEmptyTrak - create a full trak-tree for an empty (fragmented) track with no samples or stsd content
+// The media header box is vmhd for video, smhd for audio, sthd for subtitle tracks with subt handler
+// (mediaType subtitle or stpp), and nmhd for other tracks like text (wvtt) and meta (timed metadata).
 func CreateEmptyTrak(trackID, timeScale uint32, mediaType, language string) *TrakBox {
 	/*  Built tree like
 	- trak
@@ -156,11 +160,9 @@ func CreateEmptyTrak(trackID, timeScale uint32, mediaType, language string) *Tra
 		minf.AddChild(CreateVmhd())
 	case "audio":
 		minf.AddChild(CreateSmhd())
-	case "subtitle", "subtitles", "stpp":
+	case "subtitle", "subtitles", "subt", "stpp":
 		minf.AddChild(&SthdBox{})
-	case "text", "wvtt":
-		minf.AddChild(&NmhdBox{})
-	default:
+	default: // text and wvtt (ISO/IEC 14496-30), meta, and other handlers
 		minf.AddChild(&NmhdBox{})
 	}
 	dinf := &DinfBox{}
@@ -177,6 +179,30 @@ func CreateEmptyTrak(trackID, timeScale uint32, mediaType, language string) *Tra
 	return trak
 }
 
+// SetVmhd sets graphicsmode and opcolor of the vmhd box of a video track.
+// CreateEmptyTrak adds a vmhd box with copy mode (0) and zero opcolor.
+func (t *TrakBox) SetVmhd(graphicsMode uint16, opColor [3]uint16) error {
+	if t.Mdia == nil || t.Mdia.Minf == nil || t.Mdia.Minf.Vmhd == nil {
+		return fmt.Errorf("no vmhd box in track")
+	}
+	t.Mdia.Minf.Vmhd.GraphicsMode = graphicsMode
+	t.Mdia.Minf.Vmhd.OpColor = opColor
+	return nil
+}
+
+// SetSmhdBalance sets the balance of the smhd box of an audio track as a signed 8.8 fixed-point
+// number, where 0 is centre, -256 (-1.0) is full left, and 256 (1.0) is full right.
+func (t *TrakBox) SetSmhdBalance(balance int16) error {
+	if t.Mdia == nil || t.Mdia.Minf == nil || t.Mdia.Minf.Smhd == nil {
+		return fmt.Errorf("no smhd box in track")
+	}
+	if balance < -256 || balance > 256 {
+		return fmt.Errorf("balance %d outside range -256 to 256", balance)
+	}
+	t.Mdia.Minf.Smhd.Balance = uint16(balance)
+	return nil
+}
+
 // SetAVCDescriptor - Set AVC SampleDescriptor based on SPS and PPS
 func (t *TrakBox) SetAVCDescriptor(sampleDescriptorType string, spsNALUs, ppsNALUs [][]byte, includePS bool) error {
 	if sampleDescriptorType != "avc1" && sampleDescriptorType != "avc3" {
@@ -248,6 +274,147 @@ func (s *InitSegment) GetMediaType() string {
 	}
 }
 
+// VideoFrameRate returns the frame rate of a video track as a reduced fraction num/den.
+// The frame rate is taken from the AVC or HEVC SPS VUI timing info or the AV1 sequence header
+// timing info. If not available, the fallback is mdhd timescale / trex default sample duration.
+func (s *InitSegment) VideoFrameRate(trackID uint32) (num, den uint32, err error) {
+	trak, err := findTrak(s, trackID)
+	if err != nil {
+		return 0, 0, err
+	}
+	var vse *VisualSampleEntryBox
+	for _, c := range trak.Mdia.Minf.Stbl.Stsd.Children {
+		if b, ok := c.(*VisualSampleEntryBox); ok {
+			vse = b
+			break
+		}
+	}
+	if vse == nil {
+		return 0, 0, fmt.Errorf("track %d is not a video track", trackID)
+	}
+	var n, d uint64
+	var ok bool
+	switch {
+	case vse.AvcC != nil && len(vse.AvcC.SPSnalus) > 0:
+		sps, err := avc.ParseSPSNALUnit(vse.AvcC.SPSnalus[0], true)
+		if err != nil {
+			return 0, 0, fmt.Errorf("parse AVC SPS: %w", err)
+		}
+		var un, ud uint
+		un, ud, ok = sps.FrameRate()
+		n, d = uint64(un), uint64(ud)
+	case vse.HvcC != nil:
+		spss := vse.HvcC.GetNalusForType(hevc.NALU_SPS)
+		if len(spss) > 0 {
+			sps, err := hevc.ParseSPSNALUnit(spss[0])
+			if err != nil {
+				return 0, 0, fmt.Errorf("parse HEVC SPS: %w", err)
+			}
+			var un, ud uint
+			un, ud, ok = sps.FrameRate()
+			n, d = uint64(un), uint64(ud)
+		}
+	case vse.Av1C != nil:
+		obus, err := av1.ParseOBUs(vse.Av1C.ConfigOBUs)
+		if err != nil {
+			return 0, 0, fmt.Errorf("parse AV1 config OBUs: %w", err)
+		}
+		for _, obu := range obus {
+			if obu.Type != av1.OBU_SEQUENCE_HEADER {
+				continue
+			}
+			sh, err := av1.ParseSequenceHeaderPayload(obu.Payload)
+			if err != nil {
+				return 0, 0, fmt.Errorf("parse AV1 sequence header: %w", err)
+			}
+			ti := sh.TimingInfo
+			if ti != nil && ti.EqualPictureInterval && ti.NumUnitsInDisplayTick > 0 {
+				n = uint64(ti.TimeScale)
+				d = uint64(ti.NumUnitsInDisplayTick) * (uint64(ti.NumTicksPerPictureMinus1) + 1)
+				ok = true
+			}
+			break
+		}
+	}
+	if !ok {
+		var trex *TrexBox
+		if s.Moov.Mvex != nil {
+			trex, _ = s.Moov.Mvex.GetTrex(trackID)
+		}
+		if trex == nil || trex.DefaultSampleDuration == 0 {
+			return 0, 0, fmt.Errorf("no timing info or default sample duration for track %d", trackID)
+		}
+		n, d = uint64(trak.Mdia.Mdhd.Timescale), uint64(trex.DefaultSampleDuration)
+	}
+	g := gcd(n, d)
+	n, d = n/g, d/g
+	if n > math.MaxUint32 || d > math.MaxUint32 {
+		return 0, 0, fmt.Errorf("frame rate %d/%d does not fit in 32 bits", n, d)
+	}
+	return uint32(n), uint32(d), nil
+}
+
+// gcd returns the greatest common divisor of a and b, or 1 if both are zero.
+func gcd(a, b uint64) uint64 {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	if a == 0 {
+		return 1
+	}
+	return a
+}
+
+// SetTrackID changes the track ID oldID to newID in tkhd, trex, and all track reference (tref) boxes.
+// mvhd nextTrackID is increased if needed. It is an error if newID is already used.
+// Use Fragment.SetTrackID to make the same change in the media segments.
+func (s *InitSegment) SetTrackID(oldID, newID uint32) error {
+	if oldID == newID {
+		return nil
+	}
+	var trak *TrakBox
+	for _, t := range s.Moov.Traks {
+		switch t.Tkhd.TrackID {
+		case newID:
+			return fmt.Errorf("trackID %d already used", newID)
+		case oldID:
+			trak = t
+		}
+	}
+	if trak == nil {
+		return fmt.Errorf("no track with trackID %d", oldID)
+	}
+	trak.Tkhd.TrackID = newID
+	if s.Moov.Mvex != nil {
+		for _, trex := range s.Moov.Mvex.Trexs {
+			if trex.TrackID == oldID {
+				trex.TrackID = newID
+			}
+		}
+	}
+	for _, t := range s.Moov.Traks {
+		for _, c := range t.Children {
+			tref, ok := c.(*TrefBox)
+			if !ok {
+				continue
+			}
+			for _, tc := range tref.Children {
+				if trefType, ok := tc.(*TrefTypeBox); ok {
+					for i, id := range trefType.TrackIDs {
+						if id == oldID {
+							trefType.TrackIDs[i] = newID
+						}
+					}
+				}
+			}
+		}
+	}
+	if s.Moov.Mvhd.NextTrackID <= newID {
+		s.Moov.Mvhd.NextTrackID = newID + 1
+	}
+	return nil
+}
+
 // TweakSingleTrakLive assures that there is only one track and removes any mehd box.
 func (s *InitSegment) TweakSingleTrakLive() error {
 	if len(s.Moov.Traks) != 1 {
@@ -358,3 +525,93 @@ func (t *TrakBox) SetStppDescriptor(namespace, schemaLocation, auxiliaryMimeType
 	t.Mdia.Minf.Stbl.Stsd.AddChild(stpp)
 	return nil
 }
+
+// NewVideoInitSegment creates an init segment with one video track given the sample entry type
+// codec (avc1, avc3, hvc1, or hev1) and the parameter set NAL units in spsPpsVps in any order.
+// SEI NAL units are also accepted for HEVC. width and height set the display size in tkhd and
+// the sample entry, and the coded size from the SPS is used if they are zero.
+func NewVideoInitSegment(codec string, width, height uint16, timescale uint32, spsPpsVps [][]byte) (*InitSegment, error) {
+	if timescale == 0 {
+		return nil, fmt.Errorf("timescale must be non-zero")
+	}
+	init := CreateEmptyInit()
+	init.AddEmptyTrack(timescale, "video", "und")
+	trak := init.Moov.Trak
+	switch codec {
+	case "avc1", "avc3":
+		var spss, ppss [][]byte
+		for _, nalu := range spsPpsVps {
+			if len(nalu) == 0 {
+				continue
+			}
+			switch avc.GetNaluType(nalu[0]) {
+			case avc.NALU_SPS:
+				spss = append(spss, nalu)
+			case avc.NALU_PPS:
+				ppss = append(ppss, nalu)
+			default:
+				return nil, fmt.Errorf("unexpected AVC NAL unit type %s", avc.GetNaluType(nalu[0]))
+			}
+		}
+		if len(spss) == 0 || len(ppss) == 0 {
+			return nil, fmt.Errorf("need both SPS and PPS for %s", codec)
+		}
+		if err := trak.SetAVCDescriptor(codec, spss, ppss, true); err != nil {
+			return nil, err
+		}
+	case "hvc1", "hev1":
+		var vpss, spss, ppss, seis [][]byte
+		for _, nalu := range spsPpsVps {
+			if len(nalu) == 0 {
+				continue
+			}
+			switch hevc.GetNaluType(nalu[0]) {
+			case hevc.NALU_VPS:
+				vpss = append(vpss, nalu)
+			case hevc.NALU_SPS:
+				spss = append(spss, nalu)
+			case hevc.NALU_PPS:
+				ppss = append(ppss, nalu)
+			case hevc.NALU_SEI_PREFIX:
+				seis = append(seis, nalu)
+			default:
+				return nil, fmt.Errorf("unexpected HEVC NAL unit type %s", hevc.GetNaluType(nalu[0]))
+			}
+		}
+		if len(vpss) == 0 || len(spss) == 0 || len(ppss) == 0 {
+			return nil, fmt.Errorf("need VPS, SPS, and PPS for %s", codec)
+		}
+		if err := trak.SetHEVCDescriptor(codec, vpss, spss, ppss, seis, true); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("video codec %q not supported", codec)
+	}
+	if width != 0 && height != 0 {
+		trak.Tkhd.Width = Fixed32(uint32(width) << 16)
+		trak.Tkhd.Height = Fixed32(uint32(height) << 16)
+		vse := trak.Mdia.Minf.Stbl.Stsd.Children[0].(*VisualSampleEntryBox)
+		vse.Width = width
+		vse.Height = height
+	}
+	return init, nil
+}
+
+// NewAudioInitSegment creates an init segment with one mp4a audio track given an
+// AudioSpecificConfig. The sampling frequency is used as timescale if timescale is zero.
+func NewAudioInitSegment(asc []byte, timescale uint32) (*InitSegment, error) {
+	cfg, err := aac.DecodeAudioSpecificConfig(bytes.NewReader(asc))
+	if err != nil {
+		return nil, fmt.Errorf("decode AudioSpecificConfig: %w", err)
+	}
+	if timescale == 0 {
+		timescale = uint32(cfg.SamplingFrequency)
+	}
+	init := CreateEmptyInit()
+	init.AddEmptyTrack(timescale, "audio", "und")
+	esds := CreateEsdsBox(asc)
+	mp4a := CreateAudioSampleEntryBox("mp4a", uint16(cfg.ChannelConfiguration), 16,
+		uint16(cfg.SamplingFrequency), esds)
+	init.Moov.Trak.Mdia.Minf.Stbl.Stsd.AddChild(mp4a)
+	return init, nil
+}
@@ -228,3 +228,72 @@ func createSubtitlesStppInitSegment() (*mp4.InitSegment, error) {
 	}
 	return init, nil
 }
+
+func TestNewVideoInitSegment(t *testing.T) {
+	sps, _ := hex.DecodeString(avcSPSnalu)
+	pps, _ := hex.DecodeString(avcPPSnalu)
+	init, err := mp4.NewVideoInitSegment("avc1", 0, 0, 90000, [][]byte{pps, sps})
+	if err != nil {
+		t.Fatal(err)
+	}
+	avcX := init.Moov.Trak.Mdia.Minf.Stbl.Stsd.AvcX
+	if avcX == nil || avcX.Width != 1280 || avcX.Height != 720 {
+		t.Errorf("bad avc1 sample entry %v", avcX)
+	}
+	if init.Moov.Trak.Mdia.Mdhd.Timescale != 90000 || init.Moov.Mvex == nil {
+		t.Errorf("bad timescale or missing mvex")
+	}
+	init, err = mp4.NewVideoInitSegment("avc3", 1920, 1080, 90000, [][]byte{sps, pps})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w := init.Moov.Trak.Tkhd.Width; w != mp4.Fixed32(1920<<16) {
+		t.Errorf("got tkhd width %d instead of 1920", w>>16)
+	}
+
+	vps, _ := hex.DecodeString(hevcVPSnalu)
+	sps, _ = hex.DecodeString(hevcSPSnalu)
+	pps, _ = hex.DecodeString(hevcPPSnalu)
+	init, err = mp4.NewVideoInitSegment("hvc1", 0, 0, 90000, [][]byte{vps, sps, pps})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hvcX := init.Moov.Trak.Mdia.Minf.Stbl.Stsd.HvcX
+	if hvcX == nil || hvcX.Width != 960 || hvcX.Height != 540 {
+		t.Errorf("bad hvc1 sample entry %v", hvcX)
+	}
+	_, err = mp4.NewVideoInitSegment("hvc1", 0, 0, 90000, [][]byte{sps, pps})
+	if err == nil {
+		t.Errorf("expected error for missing VPS")
+	}
+	_, err = mp4.NewVideoInitSegment("vp09", 0, 0, 90000, nil)
+	if err == nil {
+		t.Errorf("expected error for unsupported codec")
+	}
+}
+
+func TestNewAudioInitSegment(t *testing.T) {
+	asc := []byte{0x11, 0x90} // AAC-LC, 48kHz, stereo
+	init, err := mp4.NewAudioInitSegment(asc, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if init.GetMediaType() != "audio" {
+		t.Errorf("got %s, wanted audio", init.GetMediaType())
+	}
+	mp4a := init.Moov.Trak.Mdia.Minf.Stbl.Stsd.Mp4a
+	if mp4a == nil || mp4a.SampleRate != 48000 || mp4a.ChannelCount != 2 {
+		t.Errorf("bad mp4a sample entry %v", mp4a)
+	}
+	if ts := init.Moov.Trak.Mdia.Mdhd.Timescale; ts != 48000 {
+		t.Errorf("got timescale %d instead of 48000", ts)
+	}
+	codec, err := init.CodecString(1)
+	if err != nil || codec != "mp4a.40.2" {
+		t.Errorf("got codec %q (%v) instead of mp4a.40.2", codec, err)
+	}
+	_, err = mp4.NewAudioInitSegment(nil, 0)
+	if err == nil {
+		t.Errorf("expected error for empty AudioSpecificConfig")
+	}
+}
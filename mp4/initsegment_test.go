@@ -238,3 +238,146 @@ func TestInitTweakSingleTrakLive(t *testing.T) {
 		t.Errorf(`Did not get error %q but %q"`, wantedErrMsg, err)
 	}
 }
+
+func TestVideoFrameRate(t *testing.T) {
+	testCases := []struct {
+		fileName string
+		trackID  uint32
+		num, den uint32
+	}{
+		{"testdata/init.mp4", 2, 30, 1},
+		{"testdata/hvc1_init.mp4", 1, 24, 1},
+	}
+	for _, tc := range testCases {
+		f, err := ReadMP4File(tc.fileName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		num, den, err := f.Init.VideoFrameRate(tc.trackID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if num != tc.num || den != tc.den {
+			t.Errorf("%s: got frame rate %d/%d instead of %d/%d", tc.fileName, num, den, tc.num, tc.den)
+		}
+	}
+
+	// Fallback to mdhd timescale and trex default sample duration
+	f, err := ReadMP4File("testdata/init.mp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	init := f.Init
+	trak := init.Moov.Traks[0]
+	trak.Mdia.Minf.Stbl.Stsd.AvcX.AvcC = nil
+	trak.Mdia.Mdhd.Timescale = 90000
+	if _, _, err = init.VideoFrameRate(2); err == nil {
+		t.Error("expected error without timing info and default sample duration")
+	}
+	trex, _ := init.Moov.Mvex.GetTrex(2)
+	trex.DefaultSampleDuration = 3003
+	num, den, err := init.VideoFrameRate(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if num != 30000 || den != 1001 {
+		t.Errorf("got fallback frame rate %d/%d instead of 30000/1001", num, den)
+	}
+	if _, _, err = init.VideoFrameRate(5); err == nil {
+		t.Error("expected error for non-existing track")
+	}
+}
+
+func TestInitSetTrackID(t *testing.T) {
+	init := CreateEmptyInit()
+	init.AddEmptyTrack(90000, "video", "und")
+	init.AddEmptyTrack(48000, "audio", "und")
+	audioTrak := init.Moov.Traks[1]
+	tref := &TrefBox{}
+	tref.AddChild(&TrefTypeBox{Name: "sync", TrackIDs: []uint32{1}})
+	audioTrak.AddChild(tref)
+
+	if err := init.SetTrackID(1, 2); err == nil {
+		t.Error("no error when changing to existing trackID")
+	}
+	if err := init.SetTrackID(3, 4); err == nil {
+		t.Error("no error for non-existing trackID")
+	}
+	if err := init.SetTrackID(1, 10); err != nil {
+		t.Fatal(err)
+	}
+	if id := init.Moov.Traks[0].Tkhd.TrackID; id != 10 {
+		t.Errorf("tkhd trackID %d instead of 10", id)
+	}
+	if _, ok := init.Moov.Mvex.GetTrex(10); !ok {
+		t.Error("no trex for trackID 10")
+	}
+	if id := tref.Children[0].(*TrefTypeBox).TrackIDs[0]; id != 10 {
+		t.Errorf("tref trackID %d instead of 10", id)
+	}
+	if next := init.Moov.Mvhd.NextTrackID; next != 11 {
+		t.Errorf("nextTrackID %d instead of 11", next)
+	}
+}
+
+func TestEmptyTrackMediaHeaders(t *testing.T) {
+	cases := []struct {
+		mediaType   string
+		handlerType string
+		mediaHeader string
+	}{
+		{"video", "vide", "vmhd"},
+		{"audio", "soun", "smhd"},
+		{"stpp", "subt", "sthd"},
+		{"subtitle", "subt", "sthd"},
+		{"wvtt", "text", "nmhd"},
+		{"meta", "meta", "nmhd"},
+	}
+	for _, c := range cases {
+		t.Run(c.mediaType, func(t *testing.T) {
+			trak := CreateEmptyTrak(1, 1000, c.mediaType, "und")
+			if ht := trak.Mdia.Hdlr.HandlerType; ht != c.handlerType {
+				t.Errorf("got handler type %s instead of %s", ht, c.handlerType)
+			}
+			minf := trak.Mdia.Minf
+			if mh := minf.Children[0].Type(); mh != c.mediaHeader {
+				t.Errorf("got media header %s instead of %s", mh, c.mediaHeader)
+			}
+			if c.mediaHeader == "sthd" && minf.Sthd == nil || c.mediaHeader == "nmhd" && minf.Nmhd == nil {
+				t.Errorf("%s not set in minf", c.mediaHeader)
+			}
+			boxDiffAfterEncodeAndDecode(t, minf.Children[0])
+			if err := trak.Mdia.CheckMediaHeader(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestSetMediaHeaderValues(t *testing.T) {
+	video := CreateEmptyTrak(1, 90000, "video", "und")
+	if err := video.SetVmhd(0x40, [3]uint16{1, 2, 3}); err != nil {
+		t.Error(err)
+	}
+	vmhd := video.Mdia.Minf.Vmhd
+	if vmhd.GraphicsMode != 0x40 || vmhd.OpColor != [3]uint16{1, 2, 3} {
+		t.Errorf("got graphicsmode %d and opcolor %v", vmhd.GraphicsMode, vmhd.OpColor)
+	}
+	boxDiffAfterEncodeAndDecode(t, vmhd)
+	if err := video.SetSmhdBalance(0); err == nil {
+		t.Error("no error for smhd balance in video track")
+	}
+	audio := CreateEmptyTrak(2, 48000, "audio", "und")
+	if err := audio.SetSmhdBalance(-128); err != nil {
+		t.Error(err)
+	}
+	if b := int16(audio.Mdia.Minf.Smhd.Balance); b != -128 {
+		t.Errorf("got balance %d instead of -128", b)
+	}
+	if err := audio.SetSmhdBalance(257); err == nil {
+		t.Error("no error for balance out of range")
+	}
+	if err := audio.SetVmhd(0, [3]uint16{}); err == nil {
+		t.Error("no error for vmhd in audio track")
+	}
+}
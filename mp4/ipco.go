@@ -0,0 +1,87 @@
+package mp4
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/Eyevinn/mp4ff/bits"
+)
+
+// IpcoBox - Item Property Container Box (ipco)
+//
+// Contained in: Item Properties Box (iprp)
+//
+// Defined in ISO/IEC 23008-12 Section 9.3. The children are item properties like hvcC and ispe,
+// which are referred to by 1-based index from the ipma box.
+type IpcoBox struct {
+	Children []Box
+}
+
+// AddChild - Add a child box
+func (b *IpcoBox) AddChild(child Box) {
+	b.Children = append(b.Children, child)
+}
+
+// DecodeIpco - box-specific decode
+func DecodeIpco(hdr BoxHeader, startPos uint64, r io.Reader) (Box, error) {
+	children, err := DecodeContainerChildren(hdr, startPos+8, startPos+hdr.Size, r)
+	if err != nil {
+		return nil, err
+	}
+	b := &IpcoBox{}
+	for _, c := range children {
+		b.AddChild(c)
+	}
+	return b, nil
+}
+
+// DecodeIpcoSR - box-specific decode
+func DecodeIpcoSR(hdr BoxHeader, startPos uint64, sr bits.SliceReader) (Box, error) {
+	children, err := DecodeContainerChildrenSR(hdr, startPos+8, startPos+hdr.Size, sr)
+	if err != nil {
+		return nil, err
+	}
+	b := &IpcoBox{}
+	for _, c := range children {
+		b.AddChild(c)
+	}
+	return b, nil
+}
+
+// GetProperty - get property box given 1-based property index as used in ipma
+func (b *IpcoBox) GetProperty(propertyIndex uint16) (Box, error) {
+	if propertyIndex == 0 || int(propertyIndex) > len(b.Children) {
+		return nil, fmt.Errorf("property index %d out of range 1-%d", propertyIndex, len(b.Children))
+	}
+	return b.Children[propertyIndex-1], nil
+}
+
+// Type - box type
+func (b *IpcoBox) Type() string {
+	return "ipco"
+}
+
+// Size - calculated size of box
+func (b *IpcoBox) Size() uint64 {
+	return containerSize(b.Children)
+}
+
+// GetChildren - list of child boxes
+func (b *IpcoBox) GetChildren() []Box {
+	return b.Children
+}
+
+// Encode - write ipco container to w
+func (b *IpcoBox) Encode(w io.Writer) error {
+	return EncodeContainer(b, w)
+}
+
+// EncodeSW - write container using slice writer
+func (b *IpcoBox) EncodeSW(sw bits.SliceWriter) error {
+	return EncodeContainerSW(b, sw)
+}
+
+// Info - write box info to w
+func (b *IpcoBox) Info(w io.Writer, specificBoxLevels, indent, indentStep string) error {
+	return ContainerInfo(b, w, specificBoxLevels, indent, indentStep)
+}
@@ -0,0 +1,175 @@
+package mp4
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/Eyevinn/mp4ff/bits"
+)
+
+// IpmaBox - Item Property Association Box (ipma)
+//
+// Contained in: Item Properties Box (iprp)
+//
+// Defined in ISO/IEC 23008-12 Section 9.3. Associates items with properties in the ipco box.
+// Version 0 has 16-bit item IDs, and flags bit 0 set gives 15-bit instead of 7-bit property indices.
+type IpmaBox struct {
+	Version byte
+	Flags   uint32
+	Entries []IpmaEntry
+}
+
+// IpmaEntry - property associations of one item
+type IpmaEntry struct {
+	ItemID       uint32
+	Associations []ItemPropertyAssociation
+}
+
+// ItemPropertyAssociation - 1-based index into ipco properties. 0 means no property.
+type ItemPropertyAssociation struct {
+	Essential     bool
+	PropertyIndex uint16
+}
+
+// DecodeIpma - box-specific decode
+func DecodeIpma(hdr BoxHeader, startPos uint64, r io.Reader) (Box, error) {
+	data, err := readBoxBody(r, hdr)
+	if err != nil {
+		return nil, err
+	}
+	sr := bits.NewFixedSliceReader(data)
+	return DecodeIpmaSR(hdr, startPos, sr)
+}
+
+// DecodeIpmaSR - box-specific decode
+func DecodeIpmaSR(hdr BoxHeader, startPos uint64, sr bits.SliceReader) (Box, error) {
+	versionAndFlags := sr.ReadUint32()
+	b := IpmaBox{
+		Version: byte(versionAndFlags >> 24),
+		Flags:   versionAndFlags & flagsMask,
+	}
+	entryCount := sr.ReadUint32()
+	minEntrySize := 3
+	if b.Version > 0 {
+		minEntrySize = 5
+	}
+	if int(entryCount)*minEntrySize > hdr.payloadLen()-8 {
+		return nil, fmt.Errorf("decode ipma: entry count %d too big for box size %d", entryCount, hdr.Size)
+	}
+	b.Entries = make([]IpmaEntry, entryCount)
+	for i := range b.Entries {
+		if b.Version == 0 {
+			b.Entries[i].ItemID = uint32(sr.ReadUint16())
+		} else {
+			b.Entries[i].ItemID = sr.ReadUint32()
+		}
+		nrAssociations := sr.ReadUint8()
+		b.Entries[i].Associations = make([]ItemPropertyAssociation, nrAssociations)
+		for j := range b.Entries[i].Associations {
+			a := &b.Entries[i].Associations[j]
+			if b.Flags&1 != 0 {
+				val := sr.ReadUint16()
+				a.Essential = val>>15 == 1
+				a.PropertyIndex = val & 0x7fff
+			} else {
+				val := sr.ReadUint8()
+				a.Essential = val>>7 == 1
+				a.PropertyIndex = uint16(val & 0x7f)
+			}
+		}
+	}
+	if err := sr.AccError(); err != nil {
+		return nil, fmt.Errorf("decode ipma: %w", err)
+	}
+	return &b, nil
+}
+
+// Type - box type
+func (b *IpmaBox) Type() string {
+	return "ipma"
+}
+
+// Size - calculated size of box
+func (b *IpmaBox) Size() uint64 {
+	size := uint64(boxHeaderSize + 8)
+	for _, e := range b.Entries {
+		if b.Version == 0 {
+			size += 2
+		} else {
+			size += 4
+		}
+		size++
+		if b.Flags&1 != 0 {
+			size += 2 * uint64(len(e.Associations))
+		} else {
+			size += uint64(len(e.Associations))
+		}
+	}
+	return size
+}
+
+// Encode - write box to w
+func (b *IpmaBox) Encode(w io.Writer) error {
+	sw := bits.NewFixedSliceWriter(int(b.Size()))
+	err := b.EncodeSW(sw)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(sw.Bytes())
+	return err
+}
+
+// EncodeSW - box-specific encode to slicewriter
+func (b *IpmaBox) EncodeSW(sw bits.SliceWriter) error {
+	err := EncodeHeaderSW(b, sw)
+	if err != nil {
+		return err
+	}
+	versionAndFlags := (uint32(b.Version) << 24) + b.Flags
+	sw.WriteUint32(versionAndFlags)
+	sw.WriteUint32(uint32(len(b.Entries)))
+	for _, e := range b.Entries {
+		if b.Version == 0 {
+			sw.WriteUint16(uint16(e.ItemID))
+		} else {
+			sw.WriteUint32(e.ItemID)
+		}
+		sw.WriteUint8(uint8(len(e.Associations)))
+		for _, a := range e.Associations {
+			if b.Flags&1 != 0 {
+				val := a.PropertyIndex & 0x7fff
+				if a.Essential {
+					val |= 0x8000
+				}
+				sw.WriteUint16(val)
+			} else {
+				val := uint8(a.PropertyIndex & 0x7f)
+				if a.Essential {
+					val |= 0x80
+				}
+				sw.WriteUint8(val)
+			}
+		}
+	}
+	return sw.AccError()
+}
+
+// Info - write box-specific information
+func (b *IpmaBox) Info(w io.Writer, specificBoxLevels, indent, indentStep string) error {
+	bd := newInfoDumper(w, indent, b, int(b.Version), b.Flags)
+	bd.write(" - entryCount: %d", len(b.Entries))
+	level := getInfoLevel(b, specificBoxLevels)
+	if level > 0 {
+		for _, e := range b.Entries {
+			msg := fmt.Sprintf(" - itemID=%d properties:", e.ItemID)
+			for _, a := range e.Associations {
+				msg += fmt.Sprintf(" %d", a.PropertyIndex)
+				if a.Essential {
+					msg += "(essential)"
+				}
+			}
+			bd.write(msg)
+		}
+	}
+	return bd.err
+}
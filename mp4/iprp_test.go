@@ -0,0 +1,49 @@
+package mp4
+
+import (
+	"testing"
+)
+
+func TestIprp(t *testing.T) {
+	ipco := &IpcoBox{}
+	ipco.AddChild(&IspeBox{ImageWidth: 1920, ImageHeight: 1080})
+	ipco.AddChild(&IspeBox{ImageWidth: 320, ImageHeight: 180})
+	ipco.AddChild(&PaspBox{HSpacing: 1, VSpacing: 1})
+	ipma := &IpmaBox{
+		Entries: []IpmaEntry{
+			{ItemID: 1, Associations: []ItemPropertyAssociation{{false, 1}, {true, 3}}},
+			{ItemID: 2, Associations: []ItemPropertyAssociation{{false, 2}, {false, 0}}},
+		},
+	}
+	iprp := &IprpBox{}
+	iprp.AddChild(ipco)
+	iprp.AddChild(ipma)
+	boxDiffAfterEncodeAndDecode(t, iprp)
+
+	props, err := iprp.GetItemProperties(2)
+	if err != nil {
+		t.Error(err)
+	}
+	if len(props) != 1 {
+		t.Fatalf("got %d properties for item 2 instead of 1", len(props))
+	}
+	if ispe, ok := props[0].(*IspeBox); !ok || ispe.ImageWidth != 320 {
+		t.Errorf("did not get 320-wide ispe for item 2: %v", props[0])
+	}
+	if _, err := ipco.GetProperty(4); err == nil {
+		t.Error("expected error for property index 4")
+	}
+}
+
+func TestIpma(t *testing.T) {
+	entries := []IpmaEntry{
+		{ItemID: 1, Associations: []ItemPropertyAssociation{{true, 1}, {false, 200}}},
+		{ItemID: 2, Associations: []ItemPropertyAssociation{{false, 0x7fff}}},
+	}
+	for _, version := range []byte{0, 1} {
+		boxDiffAfterEncodeAndDecode(t, &IpmaBox{Version: version, Flags: 1, Entries: entries})
+	}
+	boxDiffAfterEncodeAndDecode(t, &IpmaBox{Version: 0, Flags: 0, Entries: []IpmaEntry{
+		{ItemID: 1, Associations: []ItemPropertyAssociation{{true, 1}, {false, 127}}},
+	}})
+}
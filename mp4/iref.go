@@ -0,0 +1,164 @@
+package mp4
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/Eyevinn/mp4ff/bits"
+)
+
+// IrefBox - Item Reference Box (iref)
+//
+// Contained in: Meta Box (meta)
+//
+// Defined in ISO/IEC 14496-12 Section 8.11.12. Each reference is a SingleItemTypeReferenceBox
+// with the reference type as box type, like thmb (thumbnail), cdsc (content description),
+// dimg (derived image), or auxl (auxiliary image). Item IDs are 16 bits for version 0 and 32 bits otherwise.
+type IrefBox struct {
+	Version    byte
+	Flags      uint32
+	References []ItemReference
+}
+
+// ItemReference - reference of ReferenceType from item FromItemID to the items ToItemIDs.
+// For a thmb reference, FromItemID is the thumbnail and ToItemIDs are the images it represents.
+type ItemReference struct {
+	ReferenceType string
+	FromItemID    uint32
+	ToItemIDs     []uint32
+}
+
+// DecodeIref - box-specific decode
+func DecodeIref(hdr BoxHeader, startPos uint64, r io.Reader) (Box, error) {
+	data, err := readBoxBody(r, hdr)
+	if err != nil {
+		return nil, err
+	}
+	sr := bits.NewFixedSliceReader(data)
+	return DecodeIrefSR(hdr, startPos, sr)
+}
+
+// DecodeIrefSR - box-specific decode
+func DecodeIrefSR(hdr BoxHeader, startPos uint64, sr bits.SliceReader) (Box, error) {
+	endPos := sr.GetPos() + hdr.payloadLen()
+	versionAndFlags := sr.ReadUint32()
+	b := IrefBox{
+		Version: byte(versionAndFlags >> 24),
+		Flags:   versionAndFlags & flagsMask,
+	}
+	idSize := b.itemIDSize()
+	for sr.AccError() == nil && sr.GetPos() < endPos {
+		refStart := sr.GetPos()
+		size := int(sr.ReadUint32())
+		ref := ItemReference{ReferenceType: sr.ReadFixedLengthString(4)}
+		ref.FromItemID = readItemID(sr, idSize)
+		refCount := int(sr.ReadUint16())
+		if sr.AccError() != nil {
+			break
+		}
+		if size != boxHeaderSize+idSize+2+refCount*idSize || refStart+size > endPos {
+			return nil, fmt.Errorf("decode iref: bad size %d of %s reference", size, ref.ReferenceType)
+		}
+		ref.ToItemIDs = make([]uint32, refCount)
+		for i := range ref.ToItemIDs {
+			ref.ToItemIDs[i] = readItemID(sr, idSize)
+		}
+		b.References = append(b.References, ref)
+	}
+	if err := sr.AccError(); err != nil {
+		return nil, fmt.Errorf("decode iref: %w", err)
+	}
+	return &b, nil
+}
+
+func (b *IrefBox) itemIDSize() int {
+	if b.Version == 0 {
+		return 2
+	}
+	return 4
+}
+
+// readItemID - read item ID of size 2 or 4 bytes
+func readItemID(sr bits.SliceReader, size int) uint32 {
+	if size == 2 {
+		return uint32(sr.ReadUint16())
+	}
+	return sr.ReadUint32()
+}
+
+// GetReferences - get references of refType from item fromItemID, like thmb for thumbnails.
+func (b *IrefBox) GetReferences(refType string, fromItemID uint32) []uint32 {
+	var toItemIDs []uint32
+	for _, ref := range b.References {
+		if ref.ReferenceType == refType && ref.FromItemID == fromItemID {
+			toItemIDs = append(toItemIDs, ref.ToItemIDs...)
+		}
+	}
+	return toItemIDs
+}
+
+// Type - box type
+func (b *IrefBox) Type() string {
+	return "iref"
+}
+
+// Size - calculated size of box
+func (b *IrefBox) Size() uint64 {
+	idSize := b.itemIDSize()
+	size := boxHeaderSize + 4
+	for _, ref := range b.References {
+		size += boxHeaderSize + idSize + 2 + len(ref.ToItemIDs)*idSize
+	}
+	return uint64(size)
+}
+
+// Encode - write box to w
+func (b *IrefBox) Encode(w io.Writer) error {
+	sw := bits.NewFixedSliceWriter(int(b.Size()))
+	err := b.EncodeSW(sw)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(sw.Bytes())
+	return err
+}
+
+// EncodeSW - box-specific encode to slicewriter
+func (b *IrefBox) EncodeSW(sw bits.SliceWriter) error {
+	err := EncodeHeaderSW(b, sw)
+	if err != nil {
+		return err
+	}
+	versionAndFlags := (uint32(b.Version) << 24) + b.Flags
+	sw.WriteUint32(versionAndFlags)
+	idSize := b.itemIDSize()
+	writeID := func(id uint32) {
+		if idSize == 2 {
+			sw.WriteUint16(uint16(id))
+		} else {
+			sw.WriteUint32(id)
+		}
+	}
+	for _, ref := range b.References {
+		if len(ref.ReferenceType) != 4 {
+			return fmt.Errorf("iref reference type %q is not 4 characters", ref.ReferenceType)
+		}
+		sw.WriteUint32(uint32(boxHeaderSize + idSize + 2 + len(ref.ToItemIDs)*idSize))
+		sw.WriteString(ref.ReferenceType, false)
+		writeID(ref.FromItemID)
+		sw.WriteUint16(uint16(len(ref.ToItemIDs)))
+		for _, id := range ref.ToItemIDs {
+			writeID(id)
+		}
+	}
+	return sw.AccError()
+}
+
+// Info - write box-specific information
+func (b *IrefBox) Info(w io.Writer, specificBoxLevels, indent, indentStep string) error {
+	bd := newInfoDumper(w, indent, b, int(b.Version), b.Flags)
+	for _, ref := range b.References {
+		bd.write(" - %s: fromItemID=%d toItemIDs=%v", ref.ReferenceType, ref.FromItemID, ref.ToItemIDs)
+	}
+	return bd.err
+}
@@ -0,0 +1,32 @@
+package mp4
+
+import (
+	"testing"
+
+	"github.com/go-test/deep"
+)
+
+func TestIref(t *testing.T) {
+	for _, version := range []byte{0, 1} {
+		iref := &IrefBox{Version: version,
+			References: []ItemReference{
+				{ReferenceType: "thmb", FromItemID: 2, ToItemIDs: []uint32{1}},
+				{ReferenceType: "cdsc", FromItemID: 3, ToItemIDs: []uint32{1, 2}},
+				{ReferenceType: "thmb", FromItemID: 2, ToItemIDs: []uint32{4}},
+			}}
+		boxDiffAfterEncodeAndDecode(t, iref)
+		if diff := deep.Equal(iref.GetReferences("thmb", 2), []uint32{1, 4}); diff != nil {
+			t.Errorf("version %d: %v", version, diff)
+		}
+		if refs := iref.GetReferences("thmb", 1); refs != nil {
+			t.Errorf("version %d: got thmb references %v from item 1", version, refs)
+		}
+	}
+}
+
+func TestIrefBadReferenceSize(t *testing.T) {
+	iref := &IrefBox{References: []ItemReference{{ReferenceType: "thmb", FromItemID: 2, ToItemIDs: []uint32{1}}}}
+	data := encodeBox(t, iref)
+	data[15] = 16 // Size of thmb reference box, which should be 14
+	assertBoxDecodeError(t, data, 0, "decode iref pos 0: decode iref: bad size 16 of thmb reference")
+}
@@ -32,7 +32,9 @@ aligned(8) class LevelAssignmentBox extends FullBox('leva', 0, 0) {
 }
 */
 
-// LevaBox - Subsegment Index Box according to ISO/IEC 14496-12 Section 8.8.13.2.
+// LevaBox - Level Assignment Box according to ISO/IEC 14496-12 Section 8.8.13.2.
+//
+// Contained in : Movie Extends Box (mvex)
 type LevaBox struct {
 	Version byte
 	Flags   uint32
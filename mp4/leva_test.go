@@ -31,3 +31,23 @@ func TestLeva(t *testing.T) {
 	leva.Levels = append(leva.Levels, lvl)
 	boxDiffAfterEncodeAndDecode(t, &leva)
 }
+
+func TestLevaInMvex(t *testing.T) {
+	mvex := NewMvexBox()
+	mvex.AddChild(CreateTrex(1))
+	lvl, err := NewLevaLevel(1, false, 0, 0x74656c65, 0, 0) // "tele"
+	if err != nil {
+		t.Error(err)
+	}
+	mvex.AddChild(&LevaBox{Levels: []LevaLevel{lvl}})
+	decMvex := boxAfterEncodeAndDecode(t, mvex).(*MvexBox)
+	if decMvex.Leva == nil || len(decMvex.Leva.Levels) != 1 {
+		t.Fatal("no leva box with one level in decoded mvex")
+	}
+	if gt := decMvex.Leva.Levels[0].GroupingType; gt != 0x74656c65 {
+		t.Errorf("got grouping type %x instead of 74656c65", gt)
+	}
+	if _, err := NewLevaLevel(1, false, 5, 0, 0, 0); err == nil {
+		t.Error("no error for reserved assignment type")
+	}
+}
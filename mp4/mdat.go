@@ -177,6 +177,17 @@ func (m *MdatBox) PayloadAbsoluteOffset() uint64 {
 	return m.StartPos + m.HeaderSize()
 }
 
+// NewSectionReader returns an io.SectionReader for the mdat payload.
+// Offset 0 in the section corresponds to PayloadAbsoluteOffset in ra.
+// This is mainly useful in lazy mode where the payload is not in memory.
+func (m *MdatBox) NewSectionReader(ra io.ReaderAt) *io.SectionReader {
+	size := m.lazyDataSize
+	if size == 0 {
+		size = m.DataLength()
+	}
+	return io.NewSectionReader(ra, int64(m.PayloadAbsoluteOffset()), int64(size))
+}
+
 // ReadData reads Mdat data specified by the start and size.
 // Input argument start is the position relative to the start of a file.
 // The ReadSeeker is used for lazily loaded mdat case.
@@ -207,7 +218,7 @@ func (m *MdatBox) ReadData(start, size int64, rs io.ReadSeeker) ([]byte, error)
 
 	// validate if indexes are valid to avoid panics
 	dataLen := m.DataLength()
-	if offsetInMdatData >= dataLen || endIndexInMdatData >= dataLen {
+	if offsetInMdatData >= dataLen || endIndexInMdatData > dataLen {
 		return nil, fmt.Errorf("normal mdat mode - invalid range provided")
 	}
 	if len(m.DataParts) > 0 {
@@ -240,7 +251,7 @@ func (m *MdatBox) CopyData(start, size int64, rs io.ReadSeeker, w io.Writer) (nr
 
 	// validate if indexes are valid to avoid panics
 	dataLen := m.DataLength()
-	if offsetInMdatData >= dataLen || endIndexInMdatData >= dataLen {
+	if offsetInMdatData >= dataLen || endIndexInMdatData > dataLen {
 		return 0, fmt.Errorf("normal mdat mode - invalid range provided")
 	}
 	if len(m.DataParts) > 0 {
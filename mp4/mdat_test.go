@@ -2,8 +2,11 @@ package mp4
 
 import (
 	"bytes"
+	"encoding/binary"
+	"io"
 	"testing"
 
+	"github.com/Eyevinn/mp4ff/bits"
 	"github.com/go-test/deep"
 )
 
@@ -43,6 +46,88 @@ func TestEncodeAndDecodeMdat(t *testing.T) {
 	}
 }
 
+// TestDecodeMdatSizeZero tests a final mdat box with size 0, meaning that it extends to end of file.
+func TestDecodeMdatSizeZero(t *testing.T) {
+	ftyp := NewFtyp("isom", 0x200, []string{"isom"})
+	payload := []byte{0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06}
+	var buf bytes.Buffer
+	if err := ftyp.Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	buf.Write([]byte{0, 0, 0, 0, 'm', 'd', 'a', 't'})
+	buf.Write(payload)
+	data := buf.Bytes()
+	ftypSize := int(ftyp.Size())
+	mdatSize := uint64(8 + len(payload))
+
+	readers := map[string]func() io.Reader{
+		"buffer": func() io.Reader { return bytes.NewBuffer(data[ftypSize:]) },
+		"seeker": func() io.Reader { return bytes.NewReader(data[ftypSize:]) },
+	}
+	for name, newReader := range readers {
+		box, err := DecodeBox(0, newReader())
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		mdat := box.(*MdatBox)
+		if mdat.Size() != mdatSize || !bytes.Equal(mdat.Data, payload) {
+			t.Errorf("%s: got size %d and data %v", name, mdat.Size(), mdat.Data)
+		}
+	}
+	if _, err := DecodeHeader(bytes.NewBuffer(data[ftypSize:])); err == nil {
+		t.Error("no error for size 0 header from non-seekable reader")
+	}
+	hdr, err := DecodeHeader(bytes.NewReader(data[ftypSize:]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hdr.Size != mdatSize {
+		t.Errorf("got header size %d instead of %d", hdr.Size, mdatSize)
+	}
+	box, err := DecodeBoxSR(0, bits.NewFixedSliceReader(data[ftypSize:]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if box.Size() != mdatSize || !bytes.Equal(box.(*MdatBox).Data, payload) {
+		t.Errorf("SR: got size %d", box.Size())
+	}
+
+	f, err := DecodeFile(bytes.NewBuffer(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Mdat == nil || !bytes.Equal(f.Mdat.Data, payload) {
+		t.Fatal("mdat not decoded in file")
+	}
+	if _, err := DecodeFile(bytes.NewBuffer(data[ftypSize:]), WithMaxBoxSize(mdatSize-1)); err == nil {
+		t.Error("no error for size 0 box larger than max box size from non-seekable reader")
+	}
+	if _, err := DecodeFile(bytes.NewBuffer(data[ftypSize:]), WithMaxBoxSize(mdatSize)); err != nil {
+		t.Errorf("error for size 0 box with max box size: %v", err)
+	}
+	var out bytes.Buffer
+	if err := f.Encode(&out); err != nil {
+		t.Fatal(err)
+	}
+	encMdat := out.Bytes()[ftypSize:]
+	if size := binary.BigEndian.Uint32(encMdat[:4]); uint64(size) != mdatSize {
+		t.Errorf("encoded mdat size field %d instead of %d", size, mdatSize)
+	}
+}
+
+func TestDecodeChildSizeZero(t *testing.T) {
+	// A dinf box with a child box of size 0, which is only allowed at top level
+	data := []byte{0, 0, 0, 16, 'd', 'i', 'n', 'f', 0, 0, 0, 0, 'f', 'r', 'e', 'e'}
+	for name, r := range map[string]io.Reader{
+		"buffer": bytes.NewBuffer(data),
+		"seeker": bytes.NewReader(data),
+	} {
+		if _, err := DecodeBox(0, r); err == nil {
+			t.Errorf("%s: no error for child box with size 0", name)
+		}
+	}
+}
+
 func TestEncodeAndDecodeMdatLargeSize(t *testing.T) {
 
 	mdat := &MdatBox{
@@ -3,6 +3,7 @@ package mp4
 import (
 	"fmt"
 	"io"
+	"math"
 
 	"github.com/Eyevinn/mp4ff/bits"
 )
@@ -16,7 +17,7 @@ const charOffset = 0x60 // According to Section 8.4.2.3 of 14496-12
 // Timescale defines the timescale used for this track.
 // Language is a ISO-639-2/T language code stored as 1bit padding + [3]int5
 type MdhdBox struct {
-	Version          byte // Only version 0
+	Version          byte // Version 1 is needed for 64-bit times
 	Flags            uint32
 	CreationTime     uint64 // Seconds since 1904-01-01
 	ModificationTime uint64 // Seconds since 1904-01-01
@@ -78,6 +79,15 @@ func (m *MdhdBox) SetLanguage(lang string) {
 	m.Language = l
 }
 
+// SetDuration sets the media duration and changes the box to version 1 if needed,
+// so that durations above 2^32-1 are not truncated.
+func (m *MdhdBox) SetDuration(duration uint64) {
+	m.Duration = duration
+	if duration > math.MaxUint32 {
+		m.Version = 1
+	}
+}
+
 // Type - box type
 func (m *MdhdBox) Type() string {
 	return "mdhd"
@@ -104,6 +114,9 @@ func (m *MdhdBox) Encode(w io.Writer) error {
 
 // EncodeSW - box-specific encode to slicewriter
 func (m *MdhdBox) EncodeSW(sw bits.SliceWriter) error {
+	if m.Version == 0 && m.Duration > math.MaxUint32 {
+		return fmt.Errorf("duration %d too large for version 0", m.Duration)
+	}
 	err := EncodeHeaderSW(m, sw)
 	if err != nil {
 		return err
@@ -1,6 +1,7 @@
 package mp4
 
 import (
+	"bytes"
 	"testing"
 )
 
@@ -39,3 +40,20 @@ func TestMdhd(t *testing.T) {
 		}
 	}
 }
+
+func TestMdhdSetDuration(t *testing.T) {
+	mdhd := &MdhdBox{Timescale: 90000}
+	mdhd.SetDuration(1 << 20)
+	if mdhd.Version != 0 {
+		t.Errorf("version %d instead of 0", mdhd.Version)
+	}
+	mdhd.SetDuration(1 << 33)
+	if mdhd.Version != 1 {
+		t.Errorf("version %d instead of 1 for large duration", mdhd.Version)
+	}
+	boxDiffAfterEncodeAndDecode(t, mdhd)
+	mdhd.Version = 0
+	if err := mdhd.Encode(&bytes.Buffer{}); err == nil {
+		t.Error("no error when encoding large duration with version 0")
+	}
+}
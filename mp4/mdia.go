@@ -1,7 +1,9 @@
 package mp4
 
 import (
+	"fmt"
 	"io"
+	"strings"
 
 	"github.com/Eyevinn/mp4ff/bits"
 )
@@ -90,6 +92,94 @@ func (m *MdiaBox) EncodeSW(sw bits.SliceWriter) error {
 }
 
 // Info - write box-specific information
+// A warning is written if the media header box does not match the handler type.
 func (m *MdiaBox) Info(w io.Writer, specificBoxLevels, indent, indentStep string) error {
-	return ContainerInfo(m, w, specificBoxLevels, indent, indentStep)
+	mhdErr := m.CheckMediaHeader()
+	if mhdErr == nil {
+		return ContainerInfo(m, w, specificBoxLevels, indent, indentStep)
+	}
+	bd := newInfoDumper(w, indent, m, -1, 0)
+	bd.write(" - Warning: %s", mhdErr)
+	if bd.err != nil {
+		return bd.err
+	}
+	for _, child := range m.Children {
+		err := child.Info(w, specificBoxLevels, indent+indentStep, indentStep)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CheckMediaHeader returns an error if the media header box in minf does not match the handler type.
+// vide requires vmhd, soun requires smhd, subt requires sthd, and text and meta require nmhd.
+// Other handler types are not checked.
+func (m *MdiaBox) CheckMediaHeader() error {
+	if m.Hdlr == nil || m.Minf == nil {
+		return nil
+	}
+	var wanted string
+	switch m.Hdlr.HandlerType {
+	case "vide":
+		wanted = "vmhd"
+	case "soun":
+		wanted = "smhd"
+	case "subt":
+		wanted = "sthd"
+	case "text", "meta":
+		wanted = "nmhd"
+	default:
+		return nil
+	}
+	var found []string
+	for _, c := range m.Minf.Children {
+		switch c.(type) {
+		case *VmhdBox, *SmhdBox, *SthdBox, *NmhdBox:
+			found = append(found, c.Type())
+		}
+	}
+	switch {
+	case len(found) == 0:
+		return fmt.Errorf("no media header box for handler %s, expected %s", m.Hdlr.HandlerType, wanted)
+	case len(found) > 1 || found[0] != wanted:
+		return fmt.Errorf("media header %s for handler %s, expected %s",
+			strings.Join(found, ","), m.Hdlr.HandlerType, wanted)
+	}
+	return nil
+}
+
+// SetExtendedLanguage - set the BCP-47 language tag (e.g. "en-US" or "zh-Hant") in an elng box.
+// An existing elng box is replaced. A new one is inserted after hdlr (or mdhd),
+// which places it before minf. The packed language code in mdhd is not changed.
+func (m *MdiaBox) SetExtendedLanguage(bcp47 string) {
+	elng := CreateElng(bcp47)
+	insertPos := -1
+	for i, c := range m.Children {
+		switch c.Type() {
+		case "elng":
+			m.Children[i] = elng
+			m.Elng = elng
+			return
+		case "mdhd", "hdlr":
+			insertPos = i
+		}
+	}
+	insertPos++
+	m.Children = append(m.Children, nil)
+	copy(m.Children[insertPos+1:], m.Children[insertPos:])
+	m.Children[insertPos] = elng
+	m.Elng = elng
+}
+
+// GetLanguage - get the language of the media, i.e. the elng language if present,
+// and otherwise the three-letter code in mdhd.
+func (m *MdiaBox) GetLanguage() string {
+	if m.Elng != nil {
+		return m.Elng.Language
+	}
+	if m.Mdhd != nil {
+		return m.Mdhd.GetLanguage()
+	}
+	return ""
 }
@@ -0,0 +1,74 @@
+package mp4
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestMdiaSetExtendedLanguage(t *testing.T) {
+	mdia := NewMdiaBox()
+	mdhd := &MdhdBox{Timescale: 48000}
+	mdhd.SetLanguage("swe")
+	mdia.AddChild(mdhd)
+	hdlr, err := CreateHdlr("audio")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mdia.AddChild(hdlr)
+	mdia.AddChild(NewMinfBox())
+	if mdia.Elng != nil {
+		t.Fatal("unexpected elng box")
+	}
+	if lang := mdia.GetLanguage(); lang != "swe" {
+		t.Errorf("got language %q instead of swe", lang)
+	}
+	mdia.SetExtendedLanguage("en-US")
+	if mdia.Children[2] != mdia.Elng || mdia.Children[3] != mdia.Minf {
+		t.Errorf("elng not inserted between hdlr and minf")
+	}
+	mdia.SetExtendedLanguage("zh-Hant")
+	nrElng := 0
+	for _, c := range mdia.Children {
+		if c.Type() == "elng" {
+			nrElng++
+		}
+	}
+	if nrElng != 1 {
+		t.Errorf("got %d elng boxes instead of 1", nrElng)
+	}
+	if lang := mdia.GetLanguage(); lang != "zh-Hant" {
+		t.Errorf("got language %q instead of zh-Hant", lang)
+	}
+	if lang := mdia.Mdhd.GetLanguage(); lang != "swe" {
+		t.Errorf("mdhd language changed to %q", lang)
+	}
+	boxDiffAfterEncodeAndDecode(t, mdia)
+}
+
+func TestMdiaCheckMediaHeader(t *testing.T) {
+	trak := CreateEmptyTrak(1, 48000, "audio", "und")
+	mdia := trak.Mdia
+	if err := mdia.CheckMediaHeader(); err != nil {
+		t.Error(err)
+	}
+	mdia.Minf.Children[0] = CreateVmhd()
+	wantedErr := "media header vmhd for handler soun, expected smhd"
+	err := mdia.CheckMediaHeader()
+	if err == nil || err.Error() != wantedErr {
+		t.Errorf("got error %v instead of %q", err, wantedErr)
+	}
+	var buf bytes.Buffer
+	if err := mdia.Info(&buf, "", "", "  "); err != nil {
+		t.Error(err)
+	}
+	if !strings.Contains(buf.String(), " - Warning: "+wantedErr) {
+		t.Errorf("no warning in info output:\n%s", buf.String())
+	}
+	mdia.Minf.Children = mdia.Minf.Children[1:]
+	wantedErr = "no media header box for handler soun, expected smhd"
+	err = mdia.CheckMediaHeader()
+	if err == nil || err.Error() != wantedErr {
+		t.Errorf("got error %v instead of %q", err, wantedErr)
+	}
+}
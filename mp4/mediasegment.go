@@ -3,6 +3,7 @@ package mp4
 import (
 	"fmt"
 	"io"
+	"math"
 
 	"github.com/Eyevinn/mp4ff/bits"
 )
@@ -66,6 +67,14 @@ func (s *MediaSegment) AddFragment(f *Fragment) {
 	}
 }
 
+// sidxsBeforeFrag returns the sidx boxes before fragment i, allowing SidxsByFrag to be shorter than Fragments.
+func (s *MediaSegment) sidxsBeforeFrag(i int) []*SidxBox {
+	if i >= len(s.SidxsByFrag) {
+		return nil
+	}
+	return s.SidxsByFrag[i]
+}
+
 // LastFragment returns the currently last fragment, or nil if no fragments.
 func (s *MediaSegment) LastFragment() *Fragment {
 	if len(s.Fragments) == 0 {
@@ -74,6 +83,28 @@ func (s *MediaSegment) LastFragment() *Fragment {
 	return s.Fragments[len(s.Fragments)-1]
 }
 
+// PsshBoxes returns the pssh boxes of all fragments and their CMAF chunks in order.
+func (s *MediaSegment) PsshBoxes() []*PsshBox {
+	var psshs []*PsshBox
+	for _, frag := range s.Fragments {
+		frags := []*Fragment{frag}
+		for _, chunk := range frag.Chunks {
+			frags = append(frags, chunk.asFragment())
+		}
+		for _, f := range frags {
+			if f.Moof != nil {
+				psshs = append(psshs, f.Moof.Psshs...)
+			}
+		}
+	}
+	return psshs
+}
+
+// FindPsshBySystemID returns the first pssh box in the fragments with systemID, or nil if not present.
+func (s *MediaSegment) FindPsshBySystemID(systemID UUID) *PsshBox {
+	return findPsshBySystemID(s.PsshBoxes(), systemID)
+}
+
 // Size - return size of media segment
 func (s *MediaSegment) Size() uint64 {
 	var size uint64 = 0
@@ -81,7 +112,7 @@ func (s *MediaSegment) Size() uint64 {
 		size += s.Styp.Size()
 	}
 	for i, f := range s.Fragments {
-		for _, sidx := range s.SidxsByFrag[i] {
+		for _, sidx := range s.sidxsBeforeFrag(i) {
 			size += sidx.Size()
 		}
 		size += f.Size()
@@ -98,7 +129,7 @@ func (s *MediaSegment) Encode(w io.Writer) error {
 		}
 	}
 	for i, f := range s.Fragments {
-		for _, sidx := range s.SidxsByFrag[i] {
+		for _, sidx := range s.sidxsBeforeFrag(i) {
 			err := sidx.Encode(w)
 			if err != nil {
 				return err
@@ -122,7 +153,7 @@ func (s *MediaSegment) EncodeSW(sw bits.SliceWriter) error {
 		}
 	}
 	for i, f := range s.Fragments {
-		for _, sidx := range s.SidxsByFrag[i] {
+		for _, sidx := range s.sidxsBeforeFrag(i) {
 			err := sidx.EncodeSW(sw)
 			if err != nil {
 				return err
@@ -146,7 +177,7 @@ func (s *MediaSegment) Info(w io.Writer, specificBoxLevels, indent, indentStep s
 		}
 	}
 	for i, f := range s.Fragments {
-		for _, sidx := range s.SidxsByFrag[i] {
+		for _, sidx := range s.sidxsBeforeFrag(i) {
 			err := sidx.Info(w, specificBoxLevels, indent, indentStep)
 			if err != nil {
 				return err
@@ -170,6 +201,9 @@ func (s *MediaSegment) Fragmentify(timescale uint64, trex *TrexBox, duration uin
 
 	for _, inFrag := range inFragments {
 		trackID := inFrag.Moof.Traf.Tfhd.TrackID
+		if trex != nil {
+			trackID = trex.TrackID
+		}
 
 		samples, err := inFrag.GetFullSamples(trex)
 		if err != nil {
@@ -234,3 +268,306 @@ func (s *MediaSegment) FirstBox() (Box, error) {
 	}
 	return nil, fmt.Errorf("no boxes in segment")
 }
+
+// RegenerateSidx recomputes all sidx boxes of the segment from the fragments, e.g. after editing.
+// Each sidx box references the fragments from the one it precedes up to the next fragment
+// preceded by a sidx box, or the end of the segment. This covers both one sidx box for all
+// fragments and one sidx box per fragment. The references are one per fragment, or one for all
+// of them if the sidx has a single reference. If there are several sidx boxes before the same
+// fragment, the later ones are counted in first_offset of the earlier ones.
+// Timescale should be the media timescale of refTrackID, since durations are taken from the samples
+// of that track, and must be given by trun or tfhd. The earliest presentation time is the tfdt value
+// plus the composition time offset of the first sample. The SAP values are computed from the samples
+// as for Fragment.SAPType, where a sample without flags in trun or tfhd is considered to be sync.
+// References to other sidx boxes are not supported.
+func (s *MediaSegment) RegenerateSidx(timescale uint32, refTrackID uint32) error {
+	for i := range s.Fragments {
+		sidxs := s.sidxsBeforeFrag(i)
+		if len(sidxs) == 0 {
+			continue
+		}
+		end := i + 1
+		for end < len(s.Fragments) && len(s.sidxsBeforeFrag(end)) == 0 {
+			end++
+		}
+		for j, sidx := range sidxs {
+			var firstOffset uint64
+			for _, later := range sidxs[j+1:] {
+				firstOffset += later.Size()
+			}
+			err := regenerateSidx(sidx, s.Fragments[i:end], timescale, refTrackID, firstOffset)
+			if err != nil {
+				return fmt.Errorf("sidx before fragment %d: %w", i+1, err)
+			}
+		}
+	}
+	return nil
+}
+
+// regenerateSidx sets the values of sidx from frags.
+func regenerateSidx(sidx *SidxBox, frags []*Fragment, timescale, refTrackID uint32, firstOffset uint64) error {
+	var groups [][]*Fragment
+	switch len(sidx.SidxRefs) {
+	case len(frags):
+		for _, frag := range frags {
+			groups = append(groups, []*Fragment{frag})
+		}
+	case 1:
+		groups = [][]*Fragment{frags}
+	default:
+		return fmt.Errorf("%d references for %d fragments", len(sidx.SidxRefs), len(frags))
+	}
+	refs := make([]SidxRef, 0, len(groups))
+	for i, group := range groups {
+		if sidx.SidxRefs[i].ReferenceType == 1 {
+			return fmt.Errorf("references to sidx boxes not supported")
+		}
+		var ref SidxRef
+		var dur uint64
+		for _, frag := range group {
+			ref.ReferencedSize += uint32(frag.Size())
+			fragDur, err := fragmentDuration(frag, refTrackID)
+			if err != nil {
+				return err
+			}
+			dur += fragDur
+		}
+		if dur > math.MaxUint32 {
+			return fmt.Errorf("subsegment duration %d does not fit in 32 bits", dur)
+		}
+		ref.SubSegmentDuration = uint32(dur)
+		ept, err := fragmentStart(group[0], refTrackID)
+		if err != nil {
+			return err
+		}
+		ref.StartsWithSAP, ref.SAPType, ref.SAPDeltaTime, err = subsegmentSAP(group, &TrexBox{TrackID: refTrackID})
+		if err != nil {
+			return err
+		}
+		if i == 0 {
+			sidx.EarliestPresentationTime = ept
+		}
+		refs = append(refs, ref)
+	}
+	sidx.ReferenceID = refTrackID
+	sidx.Timescale = timescale
+	sidx.FirstOffset = firstOffset
+	sidx.SidxRefs = refs
+	if sidx.EarliestPresentationTime > math.MaxUint32 || firstOffset > math.MaxUint32 {
+		sidx.Version = 1
+	}
+	return nil
+}
+
+// subsegmentSAP returns the SAP values of a sidx reference to the subsegment frags as given by
+// Fragment.SAPType for the first fragment with a SAP. sapDeltaTime is relative to the earliest
+// presentation time of the subsegment.
+func subsegmentSAP(frags []*Fragment, trex *TrexBox) (startsWithSAP, sapType uint8, sapDeltaTime uint32, err error) {
+	var subsegEPT, tSAP int64
+	for i, frag := range frags {
+		fragSAPType, fragStartsWithSAP, ept, fragTSAP, err := frag.sapTimes(trex)
+		if err != nil {
+			return 0, 0, 0, fmt.Errorf("fragment %d: %w", i+1, err)
+		}
+		if i == 0 || ept < subsegEPT {
+			subsegEPT = ept
+		}
+		if sapType == 0 && fragSAPType != 0 {
+			sapType = uint8(fragSAPType)
+			tSAP = fragTSAP
+			if i == 0 && fragStartsWithSAP {
+				startsWithSAP = 1
+			}
+		}
+	}
+	if sapType == 0 {
+		return 0, 0, 0, nil
+	}
+	if tSAP-subsegEPT > math.MaxUint32 {
+		return 0, 0, 0, fmt.Errorf("SAP delta time %d does not fit in 32 bits", tSAP-subsegEPT)
+	}
+	return startsWithSAP, sapType, uint32(tSAP - subsegEPT), nil
+}
+
+// fragmentDuration returns the duration of the samples of trackID in frag including chunks.
+func fragmentDuration(frag *Fragment, trackID uint32) (uint64, error) {
+	var dur uint64
+	for _, traf := range fragmentTrafs(frag, trackID) {
+		var defaultDur uint32
+		if traf.Tfhd.HasDefaultSampleDuration() {
+			defaultDur = traf.Tfhd.DefaultSampleDuration
+		}
+		for _, trun := range traf.Truns {
+			if !trun.HasSampleDuration() && !traf.Tfhd.HasDefaultSampleDuration() {
+				return 0, fmt.Errorf("track %d: sample durations not given in trun or tfhd", trackID)
+			}
+			dur += trun.Duration(defaultDur)
+		}
+	}
+	return dur, nil
+}
+
+// fragmentStart returns the presentation time of the first sample of trackID in frag.
+func fragmentStart(frag *Fragment, trackID uint32) (presTime uint64, err error) {
+	trafs := fragmentTrafs(frag, trackID)
+	if len(trafs) == 0 || len(trafs[0].Truns) == 0 || trafs[0].Truns[0].SampleCount() == 0 {
+		return 0, fmt.Errorf("no samples for track %d", trackID)
+	}
+	traf := trafs[0]
+	if traf.Tfdt == nil {
+		return 0, fmt.Errorf("no tfdt for track %d", trackID)
+	}
+	trun := traf.Truns[0]
+	presTime = traf.Tfdt.BaseMediaDecodeTime()
+	if trun.HasSampleCompositionTimeOffset() {
+		presTime = uint64(int64(presTime) + int64(trun.Samples[0].CompositionTimeOffset))
+	}
+	return presTime, nil
+}
+
+// fragmentTrafs returns the trafs for trackID in frag and its chunks.
+func fragmentTrafs(frag *Fragment, trackID uint32) []*TrafBox {
+	var trafs []*TrafBox
+	moofs := []*MoofBox{frag.Moof}
+	for _, c := range frag.Chunks {
+		moofs = append(moofs, c.Moof)
+	}
+	for _, moof := range moofs {
+		for _, traf := range moof.Trafs {
+			if traf.Tfhd.TrackID == trackID {
+				trafs = append(trafs, traf)
+			}
+		}
+	}
+	return trafs
+}
+
+// cmafBrands - brands of which at least one is expected in the styp box of a CMAF segment
+var cmafBrands = []string{"cmfs", "cmff", "cmfl", "cmfc"}
+
+// ValidateCMAF checks the segment against a set of CMAF (ISO/IEC 23000-19) constraints and
+// returns all violations found. The checks are: styp (if present) with a CMAF brand,
+// exactly one traf per moof, tfdt present, default-base-is-moof set without base-data-offset,
+// data offset in all trun boxes, and sidx boxes (if present) covering the following fragments
+// of the track. If init is not nil, the track and its trex box must be present in it.
+func (s *MediaSegment) ValidateCMAF(init *InitSegment) []error {
+	var errs []error
+	if s.Styp != nil {
+		found := false
+		brands := append([]string{s.Styp.MajorBrand()}, s.Styp.CompatibleBrands()...)
+		for _, b := range brands {
+			for _, cb := range cmafBrands {
+				if b == cb {
+					found = true
+				}
+			}
+		}
+		if !found {
+			errs = append(errs, fmt.Errorf("styp: no CMAF brand in %v", brands))
+		}
+	}
+	if len(s.Fragments) == 0 {
+		errs = append(errs, fmt.Errorf("no fragments"))
+	}
+	for i, frag := range s.Fragments {
+		moofs := []*MoofBox{frag.Moof}
+		for _, c := range frag.Chunks {
+			moofs = append(moofs, c.Moof)
+		}
+		for j, moof := range moofs {
+			prefix := fmt.Sprintf("fragment %d", i+1)
+			if j > 0 {
+				prefix += fmt.Sprintf(" chunk %d", j)
+			}
+			for _, err := range validateCMAFMoof(moof, init) {
+				errs = append(errs, fmt.Errorf("%s: %w", prefix, err))
+			}
+		}
+		for _, err := range s.validateCMAFSidxs(i) {
+			errs = append(errs, fmt.Errorf("sidx before fragment %d: %w", i+1, err))
+		}
+	}
+	return errs
+}
+
+// validateCMAFMoof returns CMAF violations in moof.
+func validateCMAFMoof(moof *MoofBox, init *InitSegment) []error {
+	if moof == nil {
+		return []error{fmt.Errorf("no moof box")}
+	}
+	if len(moof.Trafs) != 1 {
+		return []error{fmt.Errorf("%d traf boxes instead of 1", len(moof.Trafs))}
+	}
+	var errs []error
+	traf := moof.Traf
+	tfhd := traf.Tfhd
+	if traf.Tfdt == nil {
+		errs = append(errs, fmt.Errorf("no tfdt box"))
+	}
+	if !tfhd.DefaultBaseIfMoof() {
+		errs = append(errs, fmt.Errorf("tfhd default-base-is-moof not set"))
+	}
+	if tfhd.HasBaseDataOffset() {
+		errs = append(errs, fmt.Errorf("tfhd base-data-offset present"))
+	}
+	for k, trun := range traf.Truns {
+		if !trun.HasDataOffset() {
+			errs = append(errs, fmt.Errorf("trun %d: no data offset", k+1))
+		}
+	}
+	if init != nil {
+		trackID := tfhd.TrackID
+		if _, err := findTrak(init, trackID); err != nil {
+			errs = append(errs, fmt.Errorf("init segment: %w", err))
+		}
+		if init.Moov.Mvex == nil {
+			errs = append(errs, fmt.Errorf("init segment: no mvex box"))
+		} else if _, ok := init.Moov.Mvex.GetTrex(trackID); !ok {
+			errs = append(errs, fmt.Errorf("init segment: no trex for trackID %d", trackID))
+		}
+	}
+	return errs
+}
+
+// validateCMAFSidxs checks that the sidx boxes before fragment fragIdx reference the track of
+// the fragment and that the referenced sizes of the first sidx cover the rest of the segment.
+func (s *MediaSegment) validateCMAFSidxs(fragIdx int) []error {
+	sidxs := s.sidxsBeforeFrag(fragIdx)
+	if len(sidxs) == 0 {
+		return nil
+	}
+	moof := s.Fragments[fragIdx].Moof
+	if moof == nil || moof.Traf == nil || moof.Traf.Tfhd == nil {
+		return []error{fmt.Errorf("no moof with traf and tfhd to check referenceID")}
+	}
+	var errs []error
+	trackID := moof.Traf.Tfhd.TrackID
+	for _, sidx := range sidxs {
+		if sidx.ReferenceID != trackID {
+			errs = append(errs, fmt.Errorf("referenceID %d differs from trackID %d", sidx.ReferenceID, trackID))
+		}
+	}
+	// Bytes after the first sidx box until the end of the segment
+	var remaining uint64
+	for _, sidx := range sidxs[1:] {
+		remaining += sidx.Size()
+	}
+	for i := fragIdx; i < len(s.Fragments); i++ {
+		if i > fragIdx {
+			for _, sidx := range s.sidxsBeforeFrag(i) {
+				remaining += sidx.Size()
+			}
+		}
+		remaining += s.Fragments[i].Size()
+	}
+	first := sidxs[0]
+	var referenced uint64
+	for _, ref := range first.SidxRefs {
+		referenced += uint64(ref.ReferencedSize)
+	}
+	if first.FirstOffset+referenced != remaining {
+		errs = append(errs, fmt.Errorf("first offset %d and referenced size %d do not match remaining segment size %d",
+			first.FirstOffset, referenced, remaining))
+	}
+	return errs
+}
@@ -262,3 +262,139 @@ func TestCommonSampleDuration(t *testing.T) {
 		}
 	}
 }
+
+func TestValidateCMAF(t *testing.T) {
+	init := CreateEmptyInit()
+	init.AddEmptyTrack(10000, "video", "und")
+	seg := NewMediaSegment()
+	seg.AddFragment(createTestFragment(t, 1, 0, 3, true))
+	seg.AddFragment(createTestFragment(t, 2, 3000, 3, true))
+	buf := bytes.Buffer{}
+	if err := seg.Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	f, err := DecodeFile(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seg = f.Segments[0]
+	if errs := seg.ValidateCMAF(init); len(errs) != 0 {
+		t.Errorf("unexpected errors for CMAF segment: %v", errs)
+	}
+
+	sidxFile, err := os.Open("testdata/bbb5s_aac_sidx.mp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sidxFile.Close()
+	sf, err := DecodeFile(sidxFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if errs := sf.Segments[0].ValidateCMAF(sf.Init); len(errs) != 0 {
+		t.Errorf("unexpected errors for segment with sidx: %v", errs)
+	}
+
+	seg.Styp = NewStyp("msdh", 0, []string{"msdh", "dash"})
+	seg.Fragments[0].Moof.Traf.Tfdt = nil
+	seg.Fragments[1].Moof.Traf.Tfhd.Flags &^= defaultBaseIsMoof
+	otherInit := CreateEmptyInit()
+	otherInit.AddEmptyTrack(10000, "video", "und")
+	if err = otherInit.SetTrackID(1, 2); err != nil {
+		t.Fatal(err)
+	}
+	errs := seg.ValidateCMAF(otherInit)
+	wanted := []string{
+		"styp: no CMAF brand in [msdh msdh dash]",
+		"fragment 1: no tfdt box",
+		"fragment 1: init segment: no track with trackID 1",
+		"fragment 1: init segment: no trex for trackID 1",
+		"fragment 2: tfhd default-base-is-moof not set",
+		"fragment 2: init segment: no track with trackID 1",
+		"fragment 2: init segment: no trex for trackID 1",
+	}
+	gotMsgs := make([]string, 0, len(errs))
+	for _, e := range errs {
+		gotMsgs = append(gotMsgs, e.Error())
+	}
+	if diff := deep.Equal(gotMsgs, wanted); diff != nil {
+		t.Error(diff)
+	}
+
+	// A sidx before fragments without moof or traf should give errors
+	for _, frag := range []*Fragment{NewFragment(), {Moof: &MoofBox{}}} {
+		noMoofSeg := NewMediaSegment()
+		noMoofSeg.AddSidx(&SidxBox{ReferenceID: 1})
+		noMoofSeg.AddFragment(frag)
+		if errs := noMoofSeg.ValidateCMAF(nil); len(errs) == 0 {
+			t.Error("no error for sidx before fragment without moof or traf")
+		}
+	}
+}
+
+func TestRegenerateSidx(t *testing.T) {
+	staleRef := SidxRef{ReferencedSize: 1, SubSegmentDuration: 1}
+	t.Run("one sidx", func(t *testing.T) {
+		seg := NewMediaSegment()
+		seg.AddSidx(&SidxBox{SidxRefs: []SidxRef{staleRef, staleRef}})
+		seg.AddFragment(createTestFragment(t, 1, 0, 3, true))
+		seg.AddFragment(createTestFragment(t, 2, 3000, 2, false))
+		if err := seg.RegenerateSidx(1000, 1); err != nil {
+			t.Fatal(err)
+		}
+		sidx := seg.Sidx
+		wanted := []SidxRef{
+			{ReferencedSize: uint32(seg.Fragments[0].Size()), SubSegmentDuration: 3000, StartsWithSAP: 1, SAPType: 1},
+			{ReferencedSize: uint32(seg.Fragments[1].Size()), SubSegmentDuration: 2000},
+		}
+		if diff := deep.Equal(sidx.SidxRefs, wanted); diff != nil {
+			t.Error(diff)
+		}
+		if sidx.ReferenceID != 1 || sidx.Timescale != 1000 || sidx.EarliestPresentationTime != 0 {
+			t.Errorf("bad sidx header values %+v", sidx)
+		}
+		if errs := seg.ValidateCMAF(nil); len(errs) != 0 {
+			t.Errorf("errors after regenerating sidx: %v", errs)
+		}
+	})
+	t.Run("SAP after first sample", func(t *testing.T) {
+		frag, err := CreateFragment(1, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, flags := range []uint32{NonSyncSampleFlags, SyncSampleFlags, NonSyncSampleFlags} {
+			frag.AddFullSample(FullSample{Sample{flags, 1000, 1, 1000}, uint64(i) * 1000, []byte{byte(i)}})
+		}
+		seg := NewMediaSegment()
+		seg.AddSidx(&SidxBox{SidxRefs: []SidxRef{staleRef}})
+		seg.AddFragment(frag)
+		if err := seg.RegenerateSidx(1000, 1); err != nil {
+			t.Fatal(err)
+		}
+		ref := seg.Sidx.SidxRefs[0]
+		if ref.StartsWithSAP != 0 || ref.SAPType != 1 || ref.SAPDeltaTime != 1000 {
+			t.Errorf("got SAP values %+v", ref)
+		}
+	})
+	t.Run("sidx per fragment", func(t *testing.T) {
+		seg := NewMediaSegment()
+		for i := 0; i < 2; i++ {
+			seg.AddSidx(&SidxBox{SidxRefs: []SidxRef{staleRef}})
+			seg.AddFragment(createTestFragment(t, uint32(i+1), uint64(i)*3000, 3, true))
+		}
+		if err := seg.RegenerateSidx(1000, 1); err != nil {
+			t.Fatal(err)
+		}
+		for i, sidxs := range seg.SidxsByFrag {
+			sidx := sidxs[0]
+			if sidx.EarliestPresentationTime != uint64(i)*3000 || sidx.SidxRefs[0].SubSegmentDuration != 3000 ||
+				sidx.SidxRefs[0].ReferencedSize != uint32(seg.Fragments[i].Size()) {
+				t.Errorf("sidx %d: bad values %+v", i+1, sidx)
+			}
+		}
+		seg.SidxsByFrag[0][0].SidxRefs = []SidxRef{staleRef, staleRef}
+		if err := seg.RegenerateSidx(1000, 1); err == nil {
+			t.Error("no error for 2 references to 1 fragment")
+		}
+	})
+}
@@ -13,12 +13,21 @@ import (
 
 // MPEG box defined in ISO/IEC 14496-12 Ed. 6 2020 Section 8.11
 //
+// For HEIF-style items, like images and thumbnails, the item info is in Iinf, the item data
+// location in Iloc, references like thumbnails in Iref, and properties like image size in Iprp.
+//
 // Note. QuickTime meta atom has no version and flags field.
 // https://developer.apple.com/library/archive/documentation/QuickTime/QTFF/Metadata/Metadata.html#//apple_ref/doc/uid/TP40000939-CH1-SW10
 type MetaBox struct {
 	Version     byte
 	Flags       uint32
 	Hdlr        *HdlrBox
+	Pitm        *PitmBox
+	Iinf        *IinfBox
+	Iloc        *IlocBox
+	Iref        *IrefBox
+	Iprp        *IprpBox
+	Idat        *IdatBox
 	Children    []Box
 	isQuickTime bool // Has no version and flags
 }
@@ -43,6 +52,18 @@ func (b *MetaBox) AddChild(child Box) {
 	switch box := child.(type) {
 	case *HdlrBox:
 		b.Hdlr = box
+	case *PitmBox:
+		b.Pitm = box
+	case *IinfBox:
+		b.Iinf = box
+	case *IlocBox:
+		b.Iloc = box
+	case *IrefBox:
+		b.Iref = box
+	case *IprpBox:
+		b.Iprp = box
+	case *IdatBox:
+		b.Idat = box
 	}
 	b.Children = append(b.Children, child)
 }
@@ -134,8 +155,10 @@ func (b *MetaBox) EncodeSW(sw bits.SliceWriter) error {
 	if err != nil {
 		return err
 	}
-	versionAndFlags := (uint32(b.Version) << 24) + b.Flags
-	sw.WriteUint32(versionAndFlags)
+	if !b.isQuickTime {
+		versionAndFlags := (uint32(b.Version) << 24) + b.Flags
+		sw.WriteUint32(versionAndFlags)
+	}
 	for _, c := range b.Children {
 		err = c.EncodeSW(sw)
 		if err != nil {
@@ -53,4 +53,53 @@ func TestQuickTimeMeta(t *testing.T) {
 	if !bytes.Equal(data, outBuf.Bytes()) {
 		t.Errorf("output meta for QuickTime differs from input")
 	}
+	sw := bits.NewFixedSliceWriter(int(meta.Size()))
+	err = meta.EncodeSW(sw)
+	if err != nil {
+		t.Error(err)
+	}
+	if !bytes.Equal(data, sw.Bytes()) {
+		t.Errorf("output meta for QuickTime with EncodeSW differs from input")
+	}
+}
+
+func TestMetaWithImageItems(t *testing.T) {
+	hdlr, err := CreateHdlr("pict")
+	if err != nil {
+		t.Error(err)
+	}
+	meta := CreateMetaBox(0, hdlr)
+	meta.AddChild(&PitmBox{ItemID: 1})
+	iinf := &IinfBox{}
+	iinf.AddChild(&InfeBox{Version: 2, ItemID: 1, ItemType: "hvc1"})
+	iinf.AddChild(&InfeBox{Version: 2, ItemID: 2, ItemType: "hvc1"})
+	meta.AddChild(iinf)
+	meta.AddChild(&IrefBox{References: []ItemReference{{ReferenceType: "thmb", FromItemID: 2, ToItemIDs: []uint32{1}}}})
+	meta.AddChild(&IlocBox{Version: 1, OffsetSize: 4, LengthSize: 4,
+		Items: []IlocItem{
+			{ItemID: 1, Extents: []IlocExtent{{Offset: 1000, Length: 5000}}},
+			{ItemID: 2, ConstructionMethod: 1, Extents: []IlocExtent{{Offset: 0, Length: 4}}},
+		}})
+	meta.AddChild(&IdatBox{Data: []byte{0, 1, 2, 3}})
+	boxDiffAfterEncodeAndDecode(t, meta)
+
+	decMeta := boxAfterEncodeAndDecode(t, meta).(*MetaBox)
+	if decMeta.Pitm == nil || decMeta.Iinf == nil || decMeta.Iref == nil || decMeta.Iloc == nil || decMeta.Idat == nil {
+		t.Fatalf("item boxes not set in meta box")
+	}
+	if len(decMeta.Iinf.Infes) != 2 {
+		t.Errorf("got %d item infos instead of 2", len(decMeta.Iinf.Infes))
+	}
+	thumbOf := decMeta.Iref.GetReferences("thmb", 2)
+	if len(thumbOf) != 1 || thumbOf[0] != decMeta.Pitm.ItemID {
+		t.Errorf("item 2 is not thumbnail of primary item: %v", thumbOf)
+	}
+	loc, ok := decMeta.Iloc.GetItem(2)
+	if !ok || loc.ConstructionMethod != 1 {
+		t.Fatalf("no idat location for item 2")
+	}
+	ext := loc.Extents[0]
+	if thumb := decMeta.Idat.Data[ext.Offset : ext.Offset+ext.Length]; !bytes.Equal(thumb, []byte{0, 1, 2, 3}) {
+		t.Errorf("got thumbnail data %v", thumb)
+	}
 }
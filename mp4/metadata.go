@@ -0,0 +1,184 @@
+package mp4
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// Well-known data types of the data box in iTunes metadata items
+// See https://developer.apple.com/library/archive/documentation/QuickTime/QTFF/Metadata/Metadata.html
+const (
+	DataTypeBinary        = 0
+	DataTypeUTF8          = 1
+	DataTypeUTF16         = 2
+	DataTypeJPEG          = 13
+	DataTypePNG           = 14
+	DataTypeBESignedInt   = 21
+	DataTypeBEUnsignedInt = 22
+	DataTypeBMP           = 27
+)
+
+const (
+	metadataHandlerType   = "mdir"
+	metadataKeyCopyright  = "©"
+	metadataByteCopyright = "\xa9"
+)
+
+// MetaValue - value of an iTunes metadata item, i.e. the content of its data box
+type MetaValue struct {
+	DataType uint32
+	Data     []byte
+}
+
+// TextMetaValue returns a UTF-8 text value.
+func TextMetaValue(text string) MetaValue {
+	return MetaValue{DataType: DataTypeUTF8, Data: []byte(text)}
+}
+
+// BinaryMetaValue returns a binary value. The data type is set to JPEG or PNG if
+// data starts with the corresponding signature, as needed for cover art (covr).
+func BinaryMetaValue(data []byte) MetaValue {
+	var dataType uint32 = DataTypeBinary
+	switch {
+	case bytes.HasPrefix(data, []byte{0xff, 0xd8, 0xff}):
+		dataType = DataTypeJPEG
+	case bytes.HasPrefix(data, []byte{0x89, 'P', 'N', 'G'}):
+		dataType = DataTypePNG
+	}
+	return MetaValue{DataType: dataType, Data: data}
+}
+
+// IsText returns true if the value is UTF-8 text.
+func (v MetaValue) IsText() bool {
+	return v.DataType == DataTypeUTF8
+}
+
+// String returns the text of a UTF-8 value, or a short description of other values.
+func (v MetaValue) String() string {
+	if v.IsText() {
+		return string(v.Data)
+	}
+	return fmt.Sprintf("data type %d, %d bytes", v.DataType, len(v.Data))
+}
+
+// GetMetadata returns the iTunes metadata items in moov/udta/meta/ilst.
+// Keys are the item box types with a leading 0xa9 byte given as ©, e.g. "©nam", "©ART" and "covr".
+// The first data box of each item is used, and freeform (----) items are not included.
+// The map is empty if there is no metadata.
+func (m *MoovBox) GetMetadata() map[string]MetaValue {
+	md := make(map[string]MetaValue)
+	ilst := m.findIlst()
+	if ilst == nil {
+		return md
+	}
+	for _, item := range ilst.Children {
+		if item.Type() == "----" {
+			continue
+		}
+		container, ok := item.(*GenericContainerBox)
+		if !ok {
+			continue
+		}
+		for _, c := range container.Children {
+			if data, ok := c.(*DataBox); ok {
+				md[metadataKey(item.Type())] = MetaValue{DataType: data.DataType, Data: data.Data}
+				break
+			}
+		}
+	}
+	return md
+}
+
+// SetMetadata sets the iTunes metadata item key to value, replacing any previous item with the same key.
+// The key is given as for GetMetadata, with © or a 0xa9 byte as first character.
+// Missing udta, meta, hdlr and ilst boxes are created. A created meta box has an mdir handler.
+func (m *MoovBox) SetMetadata(key string, value MetaValue) error {
+	boxType := metadataBoxType(key)
+	if len(boxType) != 4 {
+		return fmt.Errorf("metadata key %q is not four characters", key)
+	}
+	ilst := m.findIlst()
+	if ilst == nil {
+		ilst = m.createIlst()
+	}
+	item := NewGenericContainerBox(boxType)
+	item.AddChild(&DataBox{DataType: value.DataType, Data: value.Data})
+	for i, c := range ilst.Children {
+		if c.Type() == boxType {
+			ilst.Children[i] = item
+			return nil
+		}
+	}
+	ilst.AddChild(item)
+	return nil
+}
+
+// findIlst returns the ilst box in moov/udta/meta, or nil if not present.
+func (m *MoovBox) findIlst() *IlstBox {
+	meta := m.findUdtaMeta()
+	if meta == nil {
+		return nil
+	}
+	for _, c := range meta.Children {
+		if ilst, ok := c.(*IlstBox); ok {
+			return ilst
+		}
+	}
+	return nil
+}
+
+// findUdtaMeta returns the first meta box in moov/udta, or nil if not present.
+func (m *MoovBox) findUdtaMeta() *MetaBox {
+	for _, c := range m.Children {
+		udta, ok := c.(*UdtaBox)
+		if !ok {
+			continue
+		}
+		for _, uc := range udta.Children {
+			if meta, ok := uc.(*MetaBox); ok {
+				return meta
+			}
+		}
+	}
+	return nil
+}
+
+// createIlst creates an ilst box together with any missing udta and meta boxes.
+func (m *MoovBox) createIlst() *IlstBox {
+	meta := m.findUdtaMeta()
+	if meta == nil {
+		var udta *UdtaBox
+		for _, c := range m.Children {
+			if u, ok := c.(*UdtaBox); ok {
+				udta = u
+				break
+			}
+		}
+		if udta == nil {
+			udta = &UdtaBox{}
+			m.AddChild(udta)
+		}
+		meta = CreateMetaBox(0, &HdlrBox{HandlerType: metadataHandlerType})
+		udta.AddChild(meta)
+	}
+	ilst := &IlstBox{}
+	meta.AddChild(ilst)
+	return ilst
+}
+
+// metadataKey returns the metadata key for an item box type.
+func metadataKey(boxType string) string {
+	if strings.HasPrefix(boxType, metadataByteCopyright) {
+		return metadataKeyCopyright + boxType[1:]
+	}
+	return boxType
+}
+
+// metadataBoxType returns the item box type for a metadata key.
+func metadataBoxType(key string) string {
+	if strings.HasPrefix(key, metadataKeyCopyright) {
+		return metadataByteCopyright + key[len(metadataKeyCopyright):]
+	}
+	return key
+}
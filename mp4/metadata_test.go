@@ -0,0 +1,64 @@
+package mp4
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/go-test/deep"
+)
+
+func TestMetadata(t *testing.T) {
+	f, err := ReadMP4File("testdata/bbb_prog_10s.mp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	md := f.Moov.GetMetadata()
+	if got := md["©too"].String(); got != "Lavf61.7.100" {
+		t.Errorf("got ©too %q", got)
+	}
+
+	moov := NewMoovBox()
+	moov.AddChild(CreateMvhd())
+	if len(moov.GetMetadata()) != 0 {
+		t.Error("metadata in empty moov")
+	}
+	cover := []byte{0xff, 0xd8, 0xff, 0xe0, 0x00, 0x10}
+	values := map[string]MetaValue{
+		"©nam": TextMetaValue("Episode 1"),
+		"©ART": TextMetaValue("Narrator"),
+		"covr": BinaryMetaValue(cover),
+		"trkn": BinaryMetaValue([]byte{0, 0, 0, 1, 0, 8, 0, 0}),
+	}
+	for key, value := range values {
+		if err := moov.SetMetadata(key, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := moov.SetMetadata("\xa9nam", TextMetaValue("Episode 2")); err != nil {
+		t.Fatal(err)
+	}
+	values["©nam"] = TextMetaValue("Episode 2")
+	if values["covr"].DataType != DataTypeJPEG {
+		t.Errorf("covr data type %d instead of JPEG", values["covr"].DataType)
+	}
+	if err := moov.SetMetadata("title", TextMetaValue("x")); err == nil {
+		t.Error("no error for too long key")
+	}
+
+	buf := bytes.Buffer{}
+	if err := moov.Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	box, err := DecodeBox(0, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decMoov := box.(*MoovBox)
+	if diff := deep.Equal(decMoov.GetMetadata(), values); diff != nil {
+		t.Error(diff)
+	}
+	meta := decMoov.findUdtaMeta()
+	if meta.Hdlr == nil || meta.Hdlr.HandlerType != "mdir" {
+		t.Error("meta box has no mdir handler")
+	}
+}
@@ -13,6 +13,7 @@ type MinfBox struct {
 	Vmhd     *VmhdBox
 	Smhd     *SmhdBox
 	Sthd     *SthdBox
+	Nmhd     *NmhdBox
 	Dinf     *DinfBox
 	Stbl     *StblBox
 	Children []Box
@@ -32,6 +33,8 @@ func (m *MinfBox) AddChild(child Box) {
 		m.Smhd = box
 	case *SthdBox:
 		m.Sthd = box
+	case *NmhdBox:
+		m.Nmhd = box
 	case *DinfBox:
 		m.Dinf = box
 	case *StblBox:
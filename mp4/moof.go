@@ -96,9 +96,11 @@ func (m *MoofBox) Size() uint64 {
 
 // Encode - write moof after updating trun dataoffset
 func (m *MoofBox) Encode(w io.Writer) error {
-	for _, trun := range m.Traf.Truns {
-		if trun.HasDataOffset() && trun.DataOffset == 0 {
-			return fmt.Errorf("dataoffset in trun not set")
+	for _, traf := range m.Trafs {
+		for _, trun := range traf.Truns {
+			if trun.HasDataOffset() && trun.DataOffset == 0 {
+				return fmt.Errorf("dataoffset in trun not set")
+			}
 		}
 	}
 	err := EncodeHeader(m, w)
@@ -116,9 +118,11 @@ func (m *MoofBox) Encode(w io.Writer) error {
 
 // Encode - write moof after updating trun dataoffset
 func (m *MoofBox) EncodeSW(sw bits.SliceWriter) error {
-	for _, trun := range m.Traf.Truns {
-		if trun.HasDataOffset() && trun.DataOffset == 0 {
-			return fmt.Errorf("dataoffset in trun not set")
+	for _, traf := range m.Trafs {
+		for _, trun := range traf.Truns {
+			if trun.HasDataOffset() && trun.DataOffset == 0 {
+				return fmt.Errorf("dataoffset in trun not set")
+			}
 		}
 	}
 	err := EncodeHeaderSW(m, sw)
@@ -144,6 +148,16 @@ func (m *MoofBox) Info(w io.Writer, specificBoxLevels, indent, indentStep string
 	return ContainerInfo(m, w, specificBoxLevels, indent, indentStep)
 }
 
+// PsshBoxes - all pssh children boxes in order
+func (m *MoofBox) PsshBoxes() []*PsshBox {
+	return m.Psshs
+}
+
+// FindPsshBySystemID - first pssh child box with systemID, or nil if not present
+func (m *MoofBox) FindPsshBySystemID(systemID UUID) *PsshBox {
+	return findPsshBySystemID(m.Psshs, systemID)
+}
+
 // RemovePsshs - remove and return all psshs children boxes
 func (m *MoofBox) RemovePsshs() (psshs []*PsshBox, totalSize uint64) {
 	if m.Pssh == nil {
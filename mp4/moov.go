@@ -126,6 +126,16 @@ func (m *MoovBox) Info(w io.Writer, specificBoxLevels, indent, indentStep string
 	return ContainerInfo(m, w, specificBoxLevels, indent, indentStep)
 }
 
+// PsshBoxes - all pssh children boxes in order
+func (m *MoovBox) PsshBoxes() []*PsshBox {
+	return m.Psshs
+}
+
+// FindPsshBySystemID - first pssh child box with systemID, or nil if not present
+func (m *MoovBox) FindPsshBySystemID(systemID UUID) *PsshBox {
+	return findPsshBySystemID(m.Psshs, systemID)
+}
+
 // RemovePsshs - remove and return all psshs children boxes
 func (m *MoovBox) RemovePsshs() []*PsshBox {
 	if m.Pssh == nil {
@@ -15,6 +15,8 @@ type MvexBox struct {
 	Mehd     *MehdBox
 	Trex     *TrexBox
 	Trexs    []*TrexBox
+	Treps    []*TrepBox
+	Leva     *LevaBox
 	Children []Box
 }
 
@@ -33,6 +35,10 @@ func (m *MvexBox) AddChild(child Box) {
 			m.Trex = box
 		}
 		m.Trexs = append(m.Trexs, box)
+	case *TrepBox:
+		m.Treps = append(m.Treps, box)
+	case *LevaBox:
+		m.Leva = box
 	}
 	m.Children = append(m.Children, child)
 }
@@ -102,3 +108,13 @@ func (m *MvexBox) GetTrex(trackID uint32) (trex *TrexBox, ok bool) {
 	}
 	return nil, false
 }
+
+// GetTrep - get trep box for trackID
+func (m *MvexBox) GetTrep(trackID uint32) (trep *TrepBox, ok bool) {
+	for _, trep := range m.Treps {
+		if trep.TrackID == trackID {
+			return trep, true
+		}
+	}
+	return nil, false
+}
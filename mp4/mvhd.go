@@ -1,7 +1,9 @@
 package mp4
 
 import (
+	"fmt"
 	"io"
+	"math"
 	"time"
 
 	"github.com/Eyevinn/mp4ff/bits"
@@ -79,6 +81,15 @@ func DecodeMvhdSR(hdr BoxHeader, startPos uint64, sr bits.SliceReader) (Box, err
 	return m, sr.AccError()
 }
 
+// SetDuration sets the movie duration and changes the box to version 1 if needed,
+// so that durations above 2^32-1 are not truncated.
+func (b *MvhdBox) SetDuration(duration uint64) {
+	b.Duration = duration
+	if duration > math.MaxUint32 {
+		b.Version = 1
+	}
+}
+
 // Type - return box type
 func (b *MvhdBox) Type() string {
 	return "mvhd"
@@ -105,6 +116,9 @@ func (b *MvhdBox) Encode(w io.Writer) error {
 
 // EncodeSW - box-specific encode to slicewriter
 func (b *MvhdBox) EncodeSW(sw bits.SliceWriter) error {
+	if b.Version == 0 && b.Duration > math.MaxUint32 {
+		return fmt.Errorf("duration %d too large for version 0", b.Duration)
+	}
 	err := EncodeHeaderSW(b, sw)
 	if err != nil {
 		return err
@@ -42,3 +42,16 @@ func TestMvhdTimeDecodeS(t *testing.T) {
 		t.Errorf("ModificationTimeS %d not 0", mvhd.ModificationTimeS())
 	}
 }
+
+func TestMvhdSetDuration(t *testing.T) {
+	mvhd := CreateMvhd()
+	mvhd.SetDuration(1 << 33)
+	if mvhd.Version != 1 {
+		t.Errorf("version %d instead of 1 for large duration", mvhd.Version)
+	}
+	boxDiffAfterEncodeAndDecode(t, mvhd)
+	mvhd.Version = 0
+	if err := mvhd.Encode(&bytes.Buffer{}); err == nil {
+		t.Error("no error when encoding large duration with version 0")
+	}
+}
@@ -0,0 +1,10 @@
+package mp4
+
+import (
+	"testing"
+)
+
+func TestPitm(t *testing.T) {
+	boxDiffAfterEncodeAndDecode(t, &PitmBox{Version: 0, ItemID: 1})
+	boxDiffAfterEncodeAndDecode(t, &PitmBox{Version: 1, ItemID: 100000})
+}
@@ -1,11 +1,15 @@
 package mp4
 
 import (
+	"bytes"
 	"encoding/base64"
+	"encoding/binary"
 	"encoding/hex"
+	"encoding/xml"
 	"fmt"
 	"io"
 	"strings"
+	"unicode/utf16"
 
 	"github.com/Eyevinn/mp4ff/bits"
 )
@@ -83,6 +87,21 @@ func DecodePsshSR(hdr BoxHeader, startPos uint64, sr bits.SliceReader) (Box, err
 	return &b, sr.AccError()
 }
 
+// SystemIDName returns the name of the protection system if known, and "Unknown" otherwise.
+func (b *PsshBox) SystemIDName() string {
+	return ProtectionSystemName(b.SystemID)
+}
+
+// findPsshBySystemID returns the first pssh box in psshs with systemID, or nil.
+func findPsshBySystemID(psshs []*PsshBox, systemID UUID) *PsshBox {
+	for _, pssh := range psshs {
+		if pssh.SystemID.Equal(systemID) {
+			return pssh
+		}
+	}
+	return nil
+}
+
 // Type - return box type
 func (b *PsshBox) Type() string {
 	return "pssh"
@@ -173,3 +192,159 @@ func PsshBoxesFromBase64(psshBase64 string) ([]*PsshBox, error) {
 	}
 	return PsshBoxesFromBytes(data)
 }
+
+// PlayReady Object record type for the Rights Management Header (WRMHEADER)
+const playReadyRecordTypeRMHeader = 1
+
+// CreatePlayReadyPssh creates a version 0 PlayReady pssh box with a PlayReady Object containing one WRMHEADER record.
+// The WRMHEADER is version 4.0.0.0 for one key ID and 4.2.0.0 for multiple key IDs. The key IDs are in
+// big-endian (UUID) byte order and are converted to the little-endian GUID format used by PlayReady.
+// checksum is optional and can only be used with a single key ID. laURL is optional.
+func CreatePlayReadyPssh(keyIDs [][]byte, laURL string, checksum []byte) (*PsshBox, error) {
+	if len(keyIDs) == 0 {
+		return nil, fmt.Errorf("no key IDs")
+	}
+	if len(checksum) > 0 && len(keyIDs) > 1 {
+		return nil, fmt.Errorf("checksum only supported for a single key ID")
+	}
+	var wrm strings.Builder
+	version := "4.0.0.0"
+	if len(keyIDs) > 1 {
+		version = "4.2.0.0"
+	}
+	wrm.WriteString(`<WRMHEADER xmlns="http://schemas.microsoft.com/DRM/2007/03/PlayReadyHeader" version="`)
+	wrm.WriteString(version + `"><DATA>`)
+	enc := base64.StdEncoding
+	if len(keyIDs) == 1 {
+		guid, err := playReadyGUID(keyIDs[0])
+		if err != nil {
+			return nil, err
+		}
+		wrm.WriteString(`<PROTECTINFO><KEYLEN>16</KEYLEN><ALGID>AESCTR</ALGID></PROTECTINFO>`)
+		wrm.WriteString(`<KID>` + enc.EncodeToString(guid) + `</KID>`)
+		if len(checksum) > 0 {
+			wrm.WriteString(`<CHECKSUM>` + enc.EncodeToString(checksum) + `</CHECKSUM>`)
+		}
+	} else {
+		wrm.WriteString(`<PROTECTINFO><KIDS>`)
+		for _, keyID := range keyIDs {
+			guid, err := playReadyGUID(keyID)
+			if err != nil {
+				return nil, err
+			}
+			wrm.WriteString(`<KID ALGID="AESCTR" VALUE="` + enc.EncodeToString(guid) + `"></KID>`)
+		}
+		wrm.WriteString(`</KIDS></PROTECTINFO>`)
+	}
+	if laURL != "" {
+		var esc bytes.Buffer
+		if err := xml.EscapeText(&esc, []byte(laURL)); err != nil {
+			return nil, err
+		}
+		wrm.WriteString(`<LA_URL>` + esc.String() + `</LA_URL>`)
+	}
+	wrm.WriteString(`</DATA></WRMHEADER>`)
+
+	u16 := utf16.Encode([]rune(wrm.String()))
+	recordLen := 2 * len(u16)
+	totalLen := 4 + 2 + 2 + 2 + recordLen
+	data := make([]byte, totalLen)
+	binary.LittleEndian.PutUint32(data[0:4], uint32(totalLen))
+	binary.LittleEndian.PutUint16(data[4:6], 1) // Record count
+	binary.LittleEndian.PutUint16(data[6:8], playReadyRecordTypeRMHeader)
+	binary.LittleEndian.PutUint16(data[8:10], uint16(recordLen))
+	for i, c := range u16 {
+		binary.LittleEndian.PutUint16(data[10+2*i:], c)
+	}
+	return &PsshBox{
+		Version:  0,
+		SystemID: mustCreateUUID(UUIDPlayReady),
+		Data:     data,
+	}, nil
+}
+
+// PlayReadyWRMHeader returns the WRMHEADER XML string from the data of a PlayReady pssh box.
+func PlayReadyWRMHeader(psshData []byte) (string, error) {
+	le := binary.LittleEndian
+	if len(psshData) < 6 {
+		return "", fmt.Errorf("PlayReady Object too short: %d bytes", len(psshData))
+	}
+	totalLen := int(le.Uint32(psshData[0:4]))
+	if totalLen != len(psshData) {
+		return "", fmt.Errorf("PlayReady Object length %d differs from data length %d", totalLen, len(psshData))
+	}
+	nrRecords := int(le.Uint16(psshData[4:6]))
+	pos := 6
+	for i := 0; i < nrRecords; i++ {
+		if pos+4 > totalLen {
+			return "", fmt.Errorf("PlayReady record %d beyond data", i+1)
+		}
+		recordType := le.Uint16(psshData[pos:])
+		recordLen := int(le.Uint16(psshData[pos+2:]))
+		pos += 4
+		if pos+recordLen > totalLen {
+			return "", fmt.Errorf("PlayReady record %d beyond data", i+1)
+		}
+		record := psshData[pos : pos+recordLen]
+		pos += recordLen
+		if recordType != playReadyRecordTypeRMHeader {
+			continue
+		}
+		if recordLen%2 != 0 {
+			return "", fmt.Errorf("odd WRMHEADER length %d", recordLen)
+		}
+		u16 := make([]uint16, recordLen/2)
+		for j := range u16 {
+			u16[j] = le.Uint16(record[2*j:])
+		}
+		return string(utf16.Decode(u16)), nil
+	}
+	return "", fmt.Errorf("no WRMHEADER record found")
+}
+
+// playReadyGUID converts a 16-byte UUID to PlayReady little-endian GUID byte order.
+func playReadyGUID(keyID []byte) ([]byte, error) {
+	if len(keyID) != 16 {
+		return nil, fmt.Errorf("key ID length %d instead of 16", len(keyID))
+	}
+	guid := make([]byte, 16)
+	copy(guid, keyID)
+	guid[0], guid[1], guid[2], guid[3] = keyID[3], keyID[2], keyID[1], keyID[0]
+	guid[4], guid[5] = keyID[5], keyID[4]
+	guid[6], guid[7] = keyID[7], keyID[6]
+	return guid, nil
+}
+
+// CreateWidevinePssh creates a version 0 Widevine pssh box with key IDs and optional content ID.
+// The data is a protobuf-encoded WidevinePsshData message with key_id (2) and content_id (4) fields.
+func CreateWidevinePssh(keyIDs [][]byte, contentID []byte) (*PsshBox, error) {
+	if len(keyIDs) == 0 {
+		return nil, fmt.Errorf("no key IDs")
+	}
+	var data []byte
+	for _, keyID := range keyIDs {
+		if len(keyID) != 16 {
+			return nil, fmt.Errorf("key ID length %d instead of 16", len(keyID))
+		}
+		data = append(data, 0x12, 16)
+		data = append(data, keyID...)
+	}
+	if len(contentID) > 0 {
+		data = append(data, 0x22)
+		data = appendProtobufVarint(data, uint64(len(contentID)))
+		data = append(data, contentID...)
+	}
+	return &PsshBox{
+		Version:  0,
+		SystemID: mustCreateUUID(UUIDWidevine),
+		Data:     data,
+	}, nil
+}
+
+func appendProtobufVarint(data []byte, v uint64) []byte {
+	for v >= 0x80 {
+		data = append(data, byte(v)|0x80)
+		v >>= 7
+	}
+	return append(data, byte(v))
+}
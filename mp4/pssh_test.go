@@ -2,6 +2,7 @@ package mp4
 
 import (
 	"bytes"
+	"encoding/hex"
 	"strings"
 	"testing"
 
@@ -80,3 +81,102 @@ func TestPsshUUIDs(t *testing.T) {
 		}
 	}
 }
+
+func TestCreatePlayReadyPssh(t *testing.T) {
+	kid, _ := hex.DecodeString("00112233445566778899aabbccddeeff")
+	kid2, _ := hex.DecodeString("ffeeddccbbaa99887766554433221100")
+	checksum := []byte{1, 2, 3, 4, 5, 6, 7, 8}
+	laURL := "https://pr.example.com/rightsmanager.asmx?a=1&b=2"
+	pssh, err := CreatePlayReadyPssh([][]byte{kid}, laURL, checksum)
+	if err != nil {
+		t.Fatal(err)
+	}
+	boxDiffAfterEncodeAndDecode(t, pssh)
+	wrm, err := PlayReadyWRMHeader(pssh.Data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `<WRMHEADER xmlns="http://schemas.microsoft.com/DRM/2007/03/PlayReadyHeader" version="4.0.0.0">` +
+		`<DATA><PROTECTINFO><KEYLEN>16</KEYLEN><ALGID>AESCTR</ALGID></PROTECTINFO>` +
+		`<KID>MyIRAFVEd2aImaq7zN3u/w==</KID><CHECKSUM>AQIDBAUGBwg=</CHECKSUM>` +
+		`<LA_URL>https://pr.example.com/rightsmanager.asmx?a=1&amp;b=2</LA_URL></DATA></WRMHEADER>`
+	if wrm != expected {
+		t.Errorf("got WRMHEADER %s", wrm)
+	}
+	if len(pssh.Data) != 10+2*len(expected) {
+		t.Errorf("got data length %d instead of %d", len(pssh.Data), 10+2*len(expected))
+	}
+
+	pssh, err = CreatePlayReadyPssh([][]byte{kid, kid2}, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrm, err = PlayReadyWRMHeader(pssh.Data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(wrm, `version="4.2.0.0"`) || strings.Count(wrm, "<KID ") != 2 {
+		t.Errorf("got WRMHEADER %s", wrm)
+	}
+	_, err = CreatePlayReadyPssh([][]byte{kid, kid2}, "", checksum)
+	if err == nil {
+		t.Error("expected error for checksum with multiple key IDs")
+	}
+}
+
+func TestCreateWidevinePssh(t *testing.T) {
+	kid, _ := hex.DecodeString("00112233445566778899aabbccddeeff")
+	pssh, err := CreateWidevinePssh([][]byte{kid}, []byte("content"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	boxDiffAfterEncodeAndDecode(t, pssh)
+	expectedData := append(append([]byte{0x12, 0x10}, kid...), 0x22, 0x07, 'c', 'o', 'n', 't', 'e', 'n', 't')
+	if !bytes.Equal(pssh.Data, expectedData) {
+		t.Errorf("got data %x", pssh.Data)
+	}
+	if ProtectionSystemName(pssh.SystemID) != "Widevine" {
+		t.Errorf("got system %s", ProtectionSystemName(pssh.SystemID))
+	}
+}
+
+func TestFindPsshBySystemID(t *testing.T) {
+	systemIDs := make(map[string]UUID)
+	for _, u := range []string{UUIDWidevine, UUIDPlayReady, UUIDFairPlay} {
+		id, err := NewUUIDFromString(u)
+		if err != nil {
+			t.Fatal(err)
+		}
+		systemIDs[u] = id
+	}
+	moov := NewMoovBox()
+	moof := &MoofBox{}
+	for _, u := range []string{UUIDWidevine, UUIDPlayReady} {
+		moov.AddChild(&PsshBox{SystemID: systemIDs[u], Data: []byte(u)})
+		moof.AddChild(&PsshBox{SystemID: systemIDs[u], Data: []byte(u)})
+	}
+	if len(moov.PsshBoxes()) != 2 {
+		t.Errorf("got %d pssh boxes instead of 2", len(moov.PsshBoxes()))
+	}
+	seg := NewMediaSegment()
+	seg.AddFragment(&Fragment{Moof: moof})
+	if len(seg.PsshBoxes()) != 2 {
+		t.Errorf("got %d pssh boxes in segment instead of 2", len(seg.PsshBoxes()))
+	}
+	pr := moov.FindPsshBySystemID(systemIDs[UUIDPlayReady])
+	if pr == nil || pr.SystemIDName() != "PlayReady" || string(pr.Data) != UUIDPlayReady {
+		t.Errorf("wrong PlayReady pssh %v", pr)
+	}
+	if pssh := seg.FindPsshBySystemID(systemIDs[UUIDPlayReady]); pssh != moof.Psshs[1] {
+		t.Errorf("wrong PlayReady pssh in segment")
+	}
+	if pssh := moof.FindPsshBySystemID(systemIDs[UUIDFairPlay]); pssh != nil {
+		t.Errorf("found FairPlay pssh")
+	}
+	chunkMoof := &MoofBox{}
+	chunkMoof.AddChild(&PsshBox{SystemID: systemIDs[UUIDFairPlay], Data: []byte(UUIDFairPlay)})
+	seg.Fragments[0].Chunks = append(seg.Fragments[0].Chunks, &CMAFChunk{Moof: chunkMoof})
+	if pssh := seg.FindPsshBySystemID(systemIDs[UUIDFairPlay]); pssh != chunkMoof.Psshs[0] {
+		t.Errorf("FairPlay pssh in chunk not found")
+	}
+}
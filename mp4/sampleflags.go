@@ -41,14 +41,17 @@ func IsSyncSampleFlags(flags uint32) bool {
 	return flags&SyncSampleFlags == SyncSampleFlags
 }
 
-// SetSyncSampleFlags - return flags with syncsample pattern
+// sampleDependsOnMask - bits for sample_depends_on
+const sampleDependsOnMask uint32 = 0x03000000
+
+// SetSyncSampleFlags - return flags with syncsample pattern. Other fields are kept.
 func SetSyncSampleFlags(flags uint32) uint32 {
-	return flags & SyncSampleFlags & ^NonSyncSampleFlags
+	return flags&^(sampleDependsOnMask|NonSyncSampleFlags) | SyncSampleFlags
 }
 
-// SetNonSyncSampleFlags - return flags with nonsyncsample pattern
+// SetNonSyncSampleFlags - return flags with nonsyncsample pattern. Other fields are kept.
 func SetNonSyncSampleFlags(flags uint32) uint32 {
-	return flags & ^SyncSampleFlags & NonSyncSampleFlags
+	return flags&^sampleDependsOnMask | NonSyncSampleFlags
 }
 
 // DecodeSampleFlags - decode a uint32 flags field
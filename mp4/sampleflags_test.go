@@ -24,3 +24,37 @@ func TestSampleFlags(t *testing.T) {
 		t.Error(diff)
 	}
 }
+
+func TestSetSyncSampleFlags(t *testing.T) {
+	sf := SampleFlags{
+		IsLeading:                 2,
+		SampleDependsOn:           1,
+		SampleIsDependedOn:        1,
+		SampleIsNonSync:           true,
+		SampleDegradationPriority: 7,
+	}
+	syncFlags := SetSyncSampleFlags(sf.Encode())
+	if !IsSyncSampleFlags(syncFlags) {
+		t.Errorf("flags %08x not sync", syncFlags)
+	}
+	s := Sample{Flags: syncFlags}
+	if !s.IsSync() {
+		t.Error("sample with sync flags is not sync")
+	}
+	wanted := sf
+	wanted.SampleDependsOn = 2
+	wanted.SampleIsNonSync = false
+	if diff := deep.Equal(DecodeSampleFlags(syncFlags), wanted); diff != nil {
+		t.Error(diff)
+	}
+	nonSyncFlags := SetNonSyncSampleFlags(syncFlags)
+	s.Flags = nonSyncFlags
+	if s.IsSync() || IsSyncSampleFlags(nonSyncFlags) {
+		t.Errorf("flags %08x are sync", nonSyncFlags)
+	}
+	wanted.SampleDependsOn = 0
+	wanted.SampleIsNonSync = true
+	if diff := deep.Equal(DecodeSampleFlags(nonSyncFlags), wanted); diff != nil {
+		t.Error(diff)
+	}
+}
@@ -0,0 +1,187 @@
+package mp4
+
+import (
+	"fmt"
+)
+
+// GetSampleGroup returns the sbgp and sgpd boxes for groupingType, or nil if not present.
+func (t *TrafBox) GetSampleGroup(groupingType string) (*SbgpBox, *SgpdBox) {
+	return findSampleGroup(t.Sbgps, t.Sgpds, groupingType)
+}
+
+// AddRollGroup adds sgpd and sbgp boxes of grouping type roll to the track fragment.
+// The first sampleCount samples get the roll distance. For AAC, a distance of -1 signals
+// that one sample of pre-roll is needed (AudioRollRecoveryEntry).
+func (t *TrafBox) AddRollGroup(rollDistance int16, sampleCount uint32) error {
+	if sampleCount == 0 {
+		return fmt.Errorf("sampleCount must be positive")
+	}
+	return t.addSampleGroup(&RollSampleGroupEntry{RollDistance: rollDistance},
+		[]uint32{sampleCount}, []uint32{sbgpInsideOffset + 1})
+}
+
+// AddRapGroup adds sgpd and sbgp boxes of grouping type "rap " to the track fragment.
+// sampleNrs are the 1-based sample numbers in the fragment of the random access points that
+// are not sync samples. numLeadingSamples < 0 signals that the number is unknown.
+func (t *TrafBox) AddRapGroup(sampleNrs []uint32, numLeadingSamples int) error {
+	var nrSamples uint32
+	for _, trun := range t.Truns {
+		nrSamples += trun.SampleCount()
+	}
+	entry, err := createRapSampleGroupEntry(numLeadingSamples)
+	if err != nil {
+		return err
+	}
+	counts, indices, err := sampleGroupRuns(sampleNrs, nrSamples, sbgpInsideOffset+1)
+	if err != nil {
+		return err
+	}
+	return t.addSampleGroup(entry, counts, indices)
+}
+
+func (t *TrafBox) addSampleGroup(entry SampleGroupEntry, counts, indices []uint32) error {
+	if sbgp, sgpd := t.GetSampleGroup(entry.Type()); sbgp != nil || sgpd != nil {
+		return fmt.Errorf("traf already has sample group %q", entry.Type())
+	}
+	sbgp, sgpd := createSampleGroup(entry, counts, indices)
+	_ = t.AddChild(sbgp)
+	return t.AddChild(sgpd)
+}
+
+// GetSampleGroup returns the sbgp and sgpd boxes for groupingType, or nil if not present.
+func (s *StblBox) GetSampleGroup(groupingType string) (*SbgpBox, *SgpdBox) {
+	return findSampleGroup(s.Sbgps, s.Sgpds, groupingType)
+}
+
+// AddRollGroup adds sgpd and sbgp boxes of grouping type roll to the sample table.
+// The first sampleCount samples get the roll distance.
+func (s *StblBox) AddRollGroup(rollDistance int16, sampleCount uint32) error {
+	if sampleCount == 0 {
+		return fmt.Errorf("sampleCount must be positive")
+	}
+	return s.addSampleGroup(&RollSampleGroupEntry{RollDistance: rollDistance},
+		[]uint32{sampleCount}, []uint32{1})
+}
+
+// AddRapGroup adds sgpd and sbgp boxes of grouping type "rap " to the sample table.
+// sampleNrs are the 1-based sample numbers of the random access points that are not
+// sync samples. numLeadingSamples < 0 signals that the number is unknown.
+// The number of samples is given by the stsz or stz2 box.
+func (s *StblBox) AddRapGroup(sampleNrs []uint32, numLeadingSamples int) error {
+	sizes := s.sampleSizes()
+	if sizes == nil {
+		return fmt.Errorf("no stsz box")
+	}
+	entry, err := createRapSampleGroupEntry(numLeadingSamples)
+	if err != nil {
+		return err
+	}
+	counts, indices, err := sampleGroupRuns(sampleNrs, sizes.GetNrSamples(), 1)
+	if err != nil {
+		return err
+	}
+	return s.addSampleGroup(entry, counts, indices)
+}
+
+// removeSampleGroup removes the sbgp and sgpd boxes of groupingType.
+func (s *StblBox) removeSampleGroup(groupingType string) {
+	children := make([]Box, 0, len(s.Children))
+	for _, c := range s.Children {
+		switch box := c.(type) {
+		case *SbgpBox:
+			if box.GroupingType == groupingType {
+				continue
+			}
+		case *SgpdBox:
+			if box.GroupingType == groupingType {
+				continue
+			}
+		}
+		children = append(children, c)
+	}
+	s.Children = nil
+	s.Sbgp, s.Sbgps, s.Sgpd, s.Sgpds = nil, nil, nil, nil
+	for _, c := range children {
+		s.AddChild(c)
+	}
+}
+
+func (s *StblBox) addSampleGroup(entry SampleGroupEntry, counts, indices []uint32) error {
+	if sbgp, sgpd := s.GetSampleGroup(entry.Type()); sbgp != nil || sgpd != nil {
+		return fmt.Errorf("stbl already has sample group %q", entry.Type())
+	}
+	sbgp, sgpd := createSampleGroup(entry, counts, indices)
+	s.AddChild(sbgp)
+	s.AddChild(sgpd)
+	return nil
+}
+
+func findSampleGroup(sbgps []*SbgpBox, sgpds []*SgpdBox, groupingType string) (sbgp *SbgpBox, sgpd *SgpdBox) {
+	for _, b := range sbgps {
+		if b.GroupingType == groupingType {
+			sbgp = b
+			break
+		}
+	}
+	for _, b := range sgpds {
+		if b.GroupingType == groupingType {
+			sgpd = b
+			break
+		}
+	}
+	return sbgp, sgpd
+}
+
+// createSampleGroup creates sbgp and sgpd boxes with one sample group entry.
+func createSampleGroup(entry SampleGroupEntry, counts, indices []uint32) (*SbgpBox, *SgpdBox) {
+	sbgp := &SbgpBox{
+		GroupingType:            entry.Type(),
+		SampleCounts:            counts,
+		GroupDescriptionIndices: indices,
+	}
+	sgpd := &SgpdBox{
+		Version:            1,
+		GroupingType:       entry.Type(),
+		DefaultLength:      uint32(entry.Size()),
+		SampleGroupEntries: []SampleGroupEntry{entry},
+	}
+	return sbgp, sgpd
+}
+
+func createRapSampleGroupEntry(numLeadingSamples int) (*RapSampleGroupEntry, error) {
+	if numLeadingSamples > 127 {
+		return nil, fmt.Errorf("numLeadingSamples %d too big", numLeadingSamples)
+	}
+	if numLeadingSamples < 0 {
+		return &RapSampleGroupEntry{}, nil
+	}
+	return &RapSampleGroupEntry{NumLeadingSamplesKnown: 1, NumLeadingSamples: uint8(numLeadingSamples)}, nil
+}
+
+// sampleGroupRuns returns run-length coded sample counts and group description indices with
+// index for the samples in sampleNrs and 0 (no group) for the others.
+// sampleNrs must be increasing and in the range 1 to nrSamples.
+func sampleGroupRuns(sampleNrs []uint32, nrSamples, index uint32) (counts, indices []uint32, err error) {
+	if len(sampleNrs) == 0 {
+		return nil, nil, fmt.Errorf("no sample numbers")
+	}
+	next := uint32(1)
+	for _, nr := range sampleNrs {
+		if nr < next || nr > nrSamples {
+			return nil, nil, fmt.Errorf("sample number %d not increasing or outside 1-%d", nr, nrSamples)
+		}
+		if nr > next {
+			counts = append(counts, nr-next)
+			indices = append(indices, 0)
+		}
+		last := len(counts) - 1
+		if last >= 0 && indices[last] == index {
+			counts[last]++
+		} else {
+			counts = append(counts, 1)
+			indices = append(indices, index)
+		}
+		next = nr + 1
+	}
+	return counts, indices, nil
+}
@@ -0,0 +1,72 @@
+package mp4
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/go-test/deep"
+)
+
+func TestTrafAddRollGroup(t *testing.T) {
+	traf := createTestTrafBox()
+	for i := 0; i < 4; i++ {
+		traf.Trun.AddSample(Sample{SyncSampleFlags, 1024, 200, 0})
+	}
+	traf.Trun.DataOffset = 100
+	err := traf.AddRollGroup(-1, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = traf.AddRollGroup(-1, 4); err == nil {
+		t.Error("no error when adding second roll group")
+	}
+	buf := bytes.Buffer{}
+	if err = traf.Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	box, err := DecodeBox(0, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decTraf := box.(*TrafBox)
+	sbgp, sgpd := decTraf.GetSampleGroup("roll")
+	if sbgp == nil || sgpd == nil {
+		t.Fatal("roll sample group not found after decode")
+	}
+	if diff := deep.Equal(sbgp.SampleCounts, []uint32{4}); diff != nil {
+		t.Error(diff)
+	}
+	if diff := deep.Equal(sbgp.GroupDescriptionIndices, []uint32{sbgpInsideOffset + 1}); diff != nil {
+		t.Error(diff)
+	}
+	if diff := deep.Equal(sgpd.SampleGroupEntries, []SampleGroupEntry{&RollSampleGroupEntry{RollDistance: -1}}); diff != nil {
+		t.Error(diff)
+	}
+}
+
+func TestStblAddRapGroup(t *testing.T) {
+	stbl := NewStblBox()
+	stbl.AddChild(&StszBox{SampleNumber: 10, SampleSize: make([]uint32, 10)})
+	err := stbl.AddRapGroup([]uint32{3, 4, 8}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sbgp, sgpd := stbl.GetSampleGroup("rap ")
+	if sbgp == nil || sgpd == nil {
+		t.Fatal("rap sample group not found")
+	}
+	if diff := deep.Equal(sbgp.SampleCounts, []uint32{2, 2, 3, 1}); diff != nil {
+		t.Error(diff)
+	}
+	if diff := deep.Equal(sbgp.GroupDescriptionIndices, []uint32{0, 1, 0, 1}); diff != nil {
+		t.Error(diff)
+	}
+	boxDiffAfterEncodeAndDecode(t, sbgp)
+	boxDiffAfterEncodeAndDecode(t, sgpd)
+
+	for _, nrs := range [][]uint32{{}, {0}, {11}, {4, 3}} {
+		if err := NewStblBox().AddRapGroup(nrs, -1); err == nil {
+			t.Errorf("no error for sample numbers %v", nrs)
+		}
+	}
+}
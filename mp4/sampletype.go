@@ -0,0 +1,215 @@
+package mp4
+
+import (
+	"fmt"
+
+	"github.com/Eyevinn/mp4ff/hevc"
+)
+
+// SampleType - coarse picture type of a video sample derived from sample flags and composition time offsets
+type SampleType byte
+
+const (
+	// SampleTypeIDR - sync sample
+	SampleTypeIDR SampleType = iota
+	// SampleTypeI - non-sync sample that does not depend on other samples
+	SampleTypeI
+	// SampleTypeP - predicted sample presented after all samples decoded before it
+	SampleTypeP
+	// SampleTypeB - predicted sample presented before a sample decoded before it
+	SampleTypeB
+)
+
+// String - name of sample type
+func (s SampleType) String() string {
+	switch s {
+	case SampleTypeIDR:
+		return "IDR"
+	case SampleTypeI:
+		return "I"
+	case SampleTypeP:
+		return "P"
+	case SampleTypeB:
+		return "B"
+	default:
+		return fmt.Sprintf("unknown(%d)", byte(s))
+	}
+}
+
+// IsIntra - true for IDR and I samples which can be decoded on their own
+func (s SampleType) IsIntra() bool {
+	return s == SampleTypeIDR || s == SampleTypeI
+}
+
+// SampleTypes classifies the samples of the track given by trex in decode order, including
+// samples in chunks. If trex is nil, the first traf is used and no defaults are applied.
+// Sync samples are IDR, and non-sync samples with sample_depends_on=2 are I.
+// Other samples are B if their composition time is earlier than that of a previously decoded
+// sample, and P otherwise. Since only sample flags and composition time offsets are used,
+// the result depends on the sample flags written by the packager.
+func (f *Fragment) SampleTypes(trex *TrexBox) ([]SampleType, error) {
+	if f.Moof == nil {
+		return nil, fmt.Errorf("moof not set in fragment")
+	}
+	moofs := []*MoofBox{f.Moof}
+	for _, c := range f.Chunks {
+		moofs = append(moofs, c.Moof)
+	}
+	var types []SampleType
+	var sc sampleClassifier
+	for _, moof := range moofs {
+		traf := trafForTrex(moof, trex)
+		if traf == nil {
+			continue
+		}
+		var decTime uint64
+		if traf.Tfdt != nil {
+			decTime = traf.Tfdt.BaseMediaDecodeTime()
+		}
+		for _, trun := range traf.Truns {
+			trun.AddSampleDefaultValues(traf.Tfhd, trex)
+			for _, s := range trun.GetSamples() {
+				sampleType, _ := sc.classify(s.Flags, int64(decTime)+int64(s.CompositionTimeOffset))
+				types = append(types, sampleType)
+				decTime += uint64(s.Dur)
+			}
+		}
+	}
+	return types, nil
+}
+
+// sampleClassifier - classifies samples given in decode order
+type sampleClassifier struct {
+	nrSamples        int
+	maxCompTime      int64
+	lastSyncCompTime int64
+}
+
+// classify returns the sample type and if the sample is leading, i.e. has a composition time
+// before that of the last preceding sync sample.
+func (c *sampleClassifier) classify(flags uint32, compTime int64) (sampleType SampleType, isLeading bool) {
+	sampleType = SampleTypeP
+	sf := DecodeSampleFlags(flags)
+	switch {
+	case !sf.SampleIsNonSync:
+		sampleType = SampleTypeIDR
+		c.lastSyncCompTime = compTime
+	case sf.SampleDependsOn == 2:
+		sampleType = SampleTypeI
+	case c.nrSamples > 0 && compTime < c.maxCompTime:
+		sampleType = SampleTypeB
+		isLeading = compTime < c.lastSyncCompTime
+	}
+	if c.nrSamples == 0 || compTime > c.maxCompTime {
+		c.maxCompTime = compTime
+	}
+	c.nrSamples++
+	return sampleType, isLeading
+}
+
+// CreateIFrameFragment creates a fragment with only the IDR and I samples of the track given by trex,
+// e.g. for a trick-play rendition. The duration of each sample is extended up to the next
+// intra sample, or the end of the fragment, so that decode times remain unchanged.
+// Composition time offsets and sample flags are kept. The sample data is shared with f.
+func (f *Fragment) CreateIFrameFragment(trex *TrexBox) (*Fragment, error) {
+	types, err := f.SampleTypes(trex)
+	if err != nil {
+		return nil, err
+	}
+	samples, err := f.GetFullSamples(trex)
+	if err != nil {
+		return nil, err
+	}
+	if len(samples) != len(types) {
+		return nil, fmt.Errorf("got %d samples but %d sample types", len(samples), len(types))
+	}
+	var intraIdxs []int
+	for i, t := range types {
+		if t.IsIntra() {
+			intraIdxs = append(intraIdxs, i)
+		}
+	}
+	if len(intraIdxs) == 0 {
+		return nil, fmt.Errorf("no intra samples in fragment")
+	}
+	trackID := trafForTrex(f.Moof, trex).Tfhd.TrackID
+	out, err := CreateFragment(f.Moof.Mfhd.SequenceNumber, trackID)
+	if err != nil {
+		return nil, err
+	}
+	last := samples[len(samples)-1]
+	endTime := last.DecodeTime + uint64(last.Dur)
+	for j, idx := range intraIdxs {
+		s := samples[idx]
+		nextTime := endTime
+		if j+1 < len(intraIdxs) {
+			nextTime = samples[intraIdxs[j+1]].DecodeTime
+		}
+		s.Dur = uint32(nextTime - s.DecodeTime)
+		out.AddFullSample(s)
+	}
+	return out, nil
+}
+
+// FilterHEVCByTemporalID creates a fragment with only the HEVC NALUs with TemporalId not larger
+// than maxTid, using hevc.FilterByTemporalID on each sample of the track given by trex.
+// lengthSize is the NALU length field size given by the hvcC box. Samples without remaining
+// NALUs are removed, and the duration of the preceding sample is extended, so that decode times
+// remain unchanged. This gives a lower frame rate, e.g. for trick play, from a single encode.
+// The sample data is shared with f for samples that are not changed.
+func (f *Fragment) FilterHEVCByTemporalID(trex *TrexBox, maxTid uint8, lengthSize int) (*Fragment, error) {
+	samples, err := f.GetFullSamples(trex)
+	if err != nil {
+		return nil, err
+	}
+	if len(samples) == 0 {
+		return nil, fmt.Errorf("no samples in fragment")
+	}
+	kept := make([]FullSample, 0, len(samples))
+	for _, s := range samples {
+		data := hevc.FilterByTemporalID(s.Data, maxTid, lengthSize)
+		if len(data) == 0 {
+			continue
+		}
+		s.Data = data
+		s.Size = uint32(len(data))
+		kept = append(kept, s)
+	}
+	if len(kept) == 0 {
+		return nil, fmt.Errorf("no samples with TemporalId up to %d", maxTid)
+	}
+	// Start at the original decode time and keep the composition time of the first sample
+	if shift := kept[0].DecodeTime - samples[0].DecodeTime; shift > 0 {
+		kept[0].DecodeTime = samples[0].DecodeTime
+		kept[0].CompositionTimeOffset += int32(shift)
+	}
+	trackID := trafForTrex(f.Moof, trex).Tfhd.TrackID
+	out, err := CreateFragment(f.Moof.Mfhd.SequenceNumber, trackID)
+	if err != nil {
+		return nil, err
+	}
+	last := samples[len(samples)-1]
+	endTime := last.DecodeTime + uint64(last.Dur)
+	for i, s := range kept {
+		nextTime := endTime
+		if i+1 < len(kept) {
+			nextTime = kept[i+1].DecodeTime
+		}
+		s.Dur = uint32(nextTime - s.DecodeTime)
+		out.AddFullSample(s)
+	}
+	return out, nil
+}
+
+// trafForTrex returns the traf for the track of trex, or the first traf if trex is nil.
+func trafForTrex(moof *MoofBox, trex *TrexBox) *TrafBox {
+	if trex == nil {
+		return moof.Traf
+	}
+	for _, traf := range moof.Trafs {
+		if traf.Tfhd.TrackID == trex.TrackID {
+			return traf
+		}
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package mp4
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/Eyevinn/mp4ff/hevc"
+	"github.com/go-test/deep"
+)
+
+func TestSampleTypes(t *testing.T) {
+	intraFlags := SampleFlags{SampleIsNonSync: true, SampleDependsOn: 2}.Encode()
+	flags := []uint32{SyncSampleFlags, NonSyncSampleFlags, NonSyncSampleFlags, NonSyncSampleFlags,
+		intraFlags, NonSyncSampleFlags, NonSyncSampleFlags, NonSyncSampleFlags}
+	ctos := []int32{1000, 3000, 0, 0, 1000, 3000, 0, 0}
+	frag, err := CreateFragment(1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range flags {
+		data := []byte{byte(i)}
+		frag.AddFullSample(FullSample{Sample{flags[i], 1000, 1, ctos[i]}, uint64(i) * 1000, data})
+	}
+	buf := bytes.Buffer{}
+	if err = frag.Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	frag = decodeFragment(t, buf.Bytes())
+	trex := CreateTrex(1)
+
+	types, err := frag.SampleTypes(trex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantedTypes := []SampleType{SampleTypeIDR, SampleTypeP, SampleTypeB, SampleTypeB,
+		SampleTypeI, SampleTypeP, SampleTypeB, SampleTypeB}
+	if diff := deep.Equal(types, wantedTypes); diff != nil {
+		t.Error(diff)
+	}
+
+	iFrag, err := frag.CreateIFrameFragment(trex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf.Reset()
+	if err = iFrag.Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	iSamples, err := decodeFragment(t, buf.Bytes()).GetFullSamples(trex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantedSamples := []FullSample{
+		{Sample{SyncSampleFlags, 4000, 1, 1000}, 0, []byte{0}},
+		{Sample{intraFlags, 4000, 1, 1000}, 4000, []byte{4}},
+	}
+	if diff := deep.Equal(iSamples, wantedSamples); diff != nil {
+		t.Error(diff)
+	}
+	if SampleTypeB.String() != "B" || SampleTypeIDR.String() != "IDR" {
+		t.Error("bad sample type names")
+	}
+}
+
+func TestFilterHEVCByTemporalID(t *testing.T) {
+	frag, err := CreateFragment(1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 4; i++ {
+		tid := byte(i % 2)
+		naluType := byte(hevc.NALU_TRAIL_R)
+		flags := NonSyncSampleFlags
+		if i == 0 {
+			naluType = byte(hevc.NALU_IDR_W_RADL)
+			flags = SyncSampleFlags
+		}
+		data := []byte{0, 0, 0, 3, naluType << 1, tid + 1, byte(i)}
+		frag.AddFullSample(FullSample{Sample{flags, 1000, uint32(len(data)), 0}, 5000 + uint64(i)*1000, data})
+	}
+	out, err := frag.FilterHEVCByTemporalID(nil, 0, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := out.GetFullSamples(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("got %d samples instead of 2", len(got))
+	}
+	for i, s := range got {
+		if s.DecodeTime != 5000+uint64(i)*2000 || s.Dur != 2000 || s.Data[6] != byte(2*i) {
+			t.Errorf("sample %d: decode time %d, duration %d, data %v", i, s.DecodeTime, s.Dur, s.Data)
+		}
+	}
+}
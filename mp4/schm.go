@@ -15,6 +15,38 @@ type SchmBox struct {
 	SchemeURI     string // Absolute null-terminated URL
 }
 
+// EncScheme - Common Encryption scheme according to ISO/IEC 23001-7
+type EncScheme byte
+
+const (
+	// EncSchemeUnknown - scheme type not defined in ISO/IEC 23001-7
+	EncSchemeUnknown EncScheme = iota
+	// EncSchemeCENC - AES-CTR full sample and video NAL subsample encryption
+	EncSchemeCENC
+	// EncSchemeCBC1 - AES-CBC full sample and video NAL subsample encryption
+	EncSchemeCBC1
+	// EncSchemeCENS - AES-CTR subsample pattern encryption
+	EncSchemeCENS
+	// EncSchemeCBCS - AES-CBC subsample pattern encryption
+	EncSchemeCBCS
+)
+
+// String - scheme type 4CC, or "unknown"
+func (s EncScheme) String() string {
+	switch s {
+	case EncSchemeCENC:
+		return "cenc"
+	case EncSchemeCBC1:
+		return "cbc1"
+	case EncSchemeCENS:
+		return "cens"
+	case EncSchemeCBCS:
+		return "cbcs"
+	default:
+		return "unknown"
+	}
+}
+
 // DecodeSchm - box-specific decode
 func DecodeSchm(hdr BoxHeader, startPos uint64, r io.Reader) (Box, error) {
 	data, err := readBoxBody(r, hdr)
@@ -42,6 +74,22 @@ func DecodeSchmSR(hdr BoxHeader, startPos uint64, sr bits.SliceReader) (Box, err
 	return &b, sr.AccError()
 }
 
+// Scheme - encryption scheme given by SchemeType
+func (b *SchmBox) Scheme() EncScheme {
+	switch b.SchemeType {
+	case "cenc":
+		return EncSchemeCENC
+	case "cbc1":
+		return EncSchemeCBC1
+	case "cens":
+		return EncSchemeCENS
+	case "cbcs":
+		return EncSchemeCBCS
+	default:
+		return EncSchemeUnknown
+	}
+}
+
 // Type - return box type
 func (b *SchmBox) Type() string {
 	return "schm"
@@ -0,0 +1,24 @@
+package mp4
+
+import "testing"
+
+func TestSchm(t *testing.T) {
+	schm := &SchmBox{SchemeType: "cbcs", SchemeVersion: 0x00010000}
+	boxDiffAfterEncodeAndDecode(t, schm)
+	schemes := map[string]EncScheme{
+		"cenc": EncSchemeCENC,
+		"cbc1": EncSchemeCBC1,
+		"cens": EncSchemeCENS,
+		"cbcs": EncSchemeCBCS,
+		"piff": EncSchemeUnknown,
+	}
+	for schemeType, scheme := range schemes {
+		schm := &SchmBox{SchemeType: schemeType}
+		if got := schm.Scheme(); got != scheme {
+			t.Errorf("%s: got scheme %s instead of %s", schemeType, got, scheme)
+		}
+		if scheme != EncSchemeUnknown && scheme.String() != schemeType {
+			t.Errorf("scheme string %q instead of %q", scheme.String(), schemeType)
+		}
+	}
+}
@@ -26,7 +26,7 @@ type SdtpEntry uint8
 
 // NewSdtpEntry - make new SdtpEntry from 2-bit parameters
 func NewSdtpEntry(isLeading, sampleDependsOn, sampleDependedOn, hasRedundancy uint8) SdtpEntry {
-	return SdtpEntry(isLeading<<6 | sampleDependedOn<<4 | sampleDependedOn<<2 | hasRedundancy)
+	return SdtpEntry(isLeading<<6 | sampleDependsOn<<4 | sampleDependedOn<<2 | hasRedundancy)
 }
 
 // IsLeading (bits 0-1)
@@ -72,6 +72,57 @@ func CreateSdtpBox(entries []SdtpEntry) *SdtpBox {
 	}
 }
 
+// GenerateSdtp - generate an sdtp box from the stss, stts, and ctts boxes of a progressive track.
+// Samples are classified as for Fragment.SampleTypes. Sync and I samples do not depend on others,
+// and P and B samples do. B samples are set as leading and not decodable if their composition
+// time is before that of the preceding sync sample, and sync samples are set as not leading.
+// Other values are set to unknown. The box is not added to stbl.
+func (s *StblBox) GenerateSdtp() (*SdtpBox, error) {
+	sizes := s.sampleSizes()
+	if sizes == nil || s.Stts == nil {
+		return nil, fmt.Errorf("stsz or stts missing")
+	}
+	nrSamples := sizes.GetNrSamples()
+	var nrSttsSamples uint64
+	for _, count := range s.Stts.SampleCount {
+		nrSttsSamples += uint64(count)
+	}
+	if nrSttsSamples != uint64(nrSamples) {
+		return nil, fmt.Errorf("stts has %d samples instead of %d", nrSttsSamples, nrSamples)
+	}
+	entries := make([]SdtpEntry, 0, nrSamples)
+	var sc sampleClassifier
+	var decTime uint64
+	sttsIdx, sttsNr := 0, uint32(0) // stts entry and number of its samples already passed
+	for nr := uint32(1); nr <= nrSamples; nr++ {
+		for sttsNr == s.Stts.SampleCount[sttsIdx] {
+			sttsIdx, sttsNr = sttsIdx+1, 0
+		}
+		flags := createSampleFlagsFromProgressiveBoxes(s.Stss, nil, nr)
+		compTime := int64(decTime)
+		if s.Ctts != nil {
+			compTime += int64(s.Ctts.GetCompositionTimeOffset(nr))
+		}
+		sampleType, isLeading := sc.classify(flags, compTime)
+		var leading, dependsOn uint8
+		switch sampleType {
+		case SampleTypeIDR:
+			leading, dependsOn = 2, 2
+		case SampleTypeI:
+			dependsOn = 2
+		default:
+			dependsOn = 1
+			if isLeading {
+				leading = 1
+			}
+		}
+		entries = append(entries, NewSdtpEntry(leading, dependsOn, 0, 0))
+		decTime += uint64(s.Stts.SampleTimeDelta[sttsIdx])
+		sttsNr++
+	}
+	return CreateSdtpBox(entries), nil
+}
+
 // DecodeSdtp - box-specific decode
 func DecodeSdtp(hdr BoxHeader, startPos uint64, r io.Reader) (Box, error) {
 	data, err := readBoxBody(r, hdr)
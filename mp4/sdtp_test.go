@@ -2,6 +2,8 @@ package mp4
 
 import (
 	"testing"
+
+	"github.com/go-test/deep"
 )
 
 func TestSdtp(t *testing.T) {
@@ -13,3 +15,46 @@ func TestSdtp(t *testing.T) {
 
 	boxDiffAfterEncodeAndDecode(t, CreateSdtpBox(entries))
 }
+
+func TestSdtpEntryFields(t *testing.T) {
+	entry := NewSdtpEntry(1, 2, 3, 1)
+	if entry.IsLeading() != 1 || entry.SampleDependsOn() != 2 || entry.SampleIsDependedOn() != 3 ||
+		entry.SampleHasRedundancy() != 1 {
+		t.Errorf("bad fields of entry %08b", entry)
+	}
+}
+
+func TestGenerateSdtp(t *testing.T) {
+	stbl := NewStblBox()
+	stbl.AddChild(&SttsBox{SampleCount: []uint32{2, 0, 4}, SampleTimeDelta: []uint32{1000, 500, 1000}})
+	ctts := &CttsBox{}
+	if err := ctts.AddSampleCountsAndOffset([]uint32{1, 1, 2, 1, 1}, []int32{1000, 3000, 0, 2000, 0}); err != nil {
+		t.Fatal(err)
+	}
+	stbl.AddChild(ctts)
+	stbl.AddChild(&StszBox{SampleNumber: 6, SampleUniformSize: 10})
+	stbl.AddChild(&StssBox{SampleNumber: []uint32{1, 5}})
+	sdtp, err := stbl.GenerateSdtp()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The last sample is presented before the second sync sample
+	wanted := []SdtpEntry{
+		NewSdtpEntry(2, 2, 0, 0),
+		NewSdtpEntry(0, 1, 0, 0),
+		NewSdtpEntry(0, 1, 0, 0),
+		NewSdtpEntry(0, 1, 0, 0),
+		NewSdtpEntry(2, 2, 0, 0),
+		NewSdtpEntry(1, 1, 0, 0),
+	}
+	if diff := deep.Equal(sdtp.Entries, wanted); diff != nil {
+		t.Error(diff)
+	}
+	boxDiffAfterEncodeAndDecode(t, sdtp)
+
+	stbl.Stts.SampleCount = []uint32{5}
+	stbl.Stts.SampleTimeDelta = []uint32{1000}
+	if _, err = stbl.GenerateSdtp(); err == nil {
+		t.Error("no error for stts with fewer samples than stsz")
+	}
+}
@@ -0,0 +1,114 @@
+package mp4
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/Eyevinn/mp4ff/bits"
+)
+
+// SegmentWriter writes media segments box by box to an io.Writer, so that fragments can be
+// written as soon as they are complete and need not be kept in memory.
+// The byte offsets of all written boxes are tracked. A sidx box can therefore be written
+// with preliminary values and be rewritten with PatchSidx once the referenced sizes are known.
+type SegmentWriter struct {
+	w           io.Writer
+	offset      uint64
+	sidxRanges  map[*SidxBox]DataRange
+	fragRanges  []DataRange
+	EncOptimize EncOptimize
+}
+
+// NewSegmentWriter creates a SegmentWriter writing to w starting at offset 0.
+func NewSegmentWriter(w io.Writer) *SegmentWriter {
+	return &SegmentWriter{
+		w:          w,
+		sidxRanges: make(map[*SidxBox]DataRange),
+	}
+}
+
+// Offset returns the number of bytes written so far.
+func (s *SegmentWriter) Offset() uint64 {
+	return s.offset
+}
+
+// WriteStyp writes a styp box, which normally starts a new segment.
+func (s *SegmentWriter) WriteStyp(styp *StypBox) error {
+	return styp.Encode(s)
+}
+
+// WriteSidx writes a sidx box and records its offset for PatchSidx.
+// The AnchorPoint of sidx is set to the position of the first referenced byte.
+func (s *SegmentWriter) WriteSidx(sidx *SidxBox) error {
+	start := s.offset
+	sidx.AnchorPoint = start + sidx.Size() + sidx.FirstOffset
+	err := sidx.Encode(s)
+	if err != nil {
+		return err
+	}
+	s.sidxRanges[sidx] = DataRange{Offset: start, Size: s.offset - start}
+	return nil
+}
+
+// WriteFragment writes a fragment including its chunks and records its byte range.
+// The EncOptimize value of the SegmentWriter is applied to the fragment.
+func (s *SegmentWriter) WriteFragment(frag *Fragment) error {
+	start := s.offset
+	frag.EncOptimize = s.EncOptimize
+	err := frag.Encode(s)
+	if err != nil {
+		return err
+	}
+	s.fragRanges = append(s.fragRanges, DataRange{Offset: start, Size: s.offset - start})
+	return nil
+}
+
+// WriteChunk writes a CMAF chunk continuing the last written fragment.
+// The byte range of the last fragment is extended to include the chunk.
+func (s *SegmentWriter) WriteChunk(chunk *CMAFChunk) error {
+	if len(s.fragRanges) == 0 {
+		return fmt.Errorf("no fragment written before chunk")
+	}
+	start := s.offset
+	chunk.EncOptimize = s.EncOptimize
+	err := chunk.Encode(s)
+	if err != nil {
+		return err
+	}
+	s.fragRanges[len(s.fragRanges)-1].Size += s.offset - start
+	return nil
+}
+
+// FragmentRanges returns the byte ranges of all written fragments, including their chunks.
+func (s *SegmentWriter) FragmentRanges() []DataRange {
+	return s.fragRanges
+}
+
+// PatchSidx rewrites a sidx box previously written by WriteSidx, e.g. after updating
+// ReferencedSize and SubSegmentDuration of its references.
+// wa must address the same bytes as the writer of the SegmentWriter, and the size of sidx
+// must not have changed since it was written.
+func (s *SegmentWriter) PatchSidx(wa io.WriterAt, sidx *SidxBox) error {
+	dr, ok := s.sidxRanges[sidx]
+	if !ok {
+		return fmt.Errorf("sidx box not written by this SegmentWriter")
+	}
+	size := sidx.Size()
+	if size != dr.Size {
+		return fmt.Errorf("sidx size %d differs from written size %d", size, dr.Size)
+	}
+	sw := bits.NewFixedSliceWriter(int(size))
+	err := sidx.EncodeSW(sw)
+	if err != nil {
+		return err
+	}
+	_, err = wa.WriteAt(sw.Bytes(), int64(dr.Offset))
+	return err
+}
+
+// Write makes SegmentWriter an io.Writer that keeps track of the offset.
+func (s *SegmentWriter) Write(p []byte) (int, error) {
+	n, err := s.w.Write(p)
+	s.offset += uint64(n)
+	return n, err
+}
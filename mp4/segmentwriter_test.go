@@ -0,0 +1,79 @@
+package mp4
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSegmentWriter(t *testing.T) {
+	ofh, err := os.Create(filepath.Join(t.TempDir(), "seg.m4s"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ofh.Close()
+	sw := NewSegmentWriter(ofh)
+	styp := CreateStyp()
+	if err = sw.WriteStyp(styp); err != nil {
+		t.Fatal(err)
+	}
+	sidx := &SidxBox{ReferenceID: 1, Timescale: 1000, SidxRefs: make([]SidxRef, 2)}
+	if err = sw.WriteSidx(sidx); err != nil {
+		t.Fatal(err)
+	}
+	frags := []*Fragment{createTestFragment(t, 1, 0, 3, true), createTestFragment(t, 2, 3000, 2, true)}
+	chunkFrag := createTestFragment(t, 3, 5000, 2, false)
+	chunk := &CMAFChunk{}
+	for _, c := range chunkFrag.Children {
+		chunk.AddChild(c)
+	}
+	for _, frag := range frags {
+		if err = sw.WriteFragment(frag); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = sw.WriteChunk(chunk); err != nil {
+		t.Fatal(err)
+	}
+	ranges := sw.FragmentRanges()
+	if len(ranges) != 2 {
+		t.Fatalf("%d fragment ranges instead of 2", len(ranges))
+	}
+	if ranges[0].Offset != sidx.AnchorPoint || ranges[1].Offset+ranges[1].Size != sw.Offset() {
+		t.Errorf("bad fragment ranges %v", ranges)
+	}
+	for i, dr := range ranges {
+		sidx.SidxRefs[i] = SidxRef{ReferencedSize: uint32(dr.Size), SubSegmentDuration: 3000 - uint32(i)*1000,
+			StartsWithSAP: 1, SAPType: 1}
+	}
+	if err = sw.PatchSidx(ofh, sidx); err != nil {
+		t.Fatal(err)
+	}
+
+	frags[1].AddChunk(chunk)
+	seg := NewMediaSegmentWithStyp(styp)
+	seg.AddSidx(sidx)
+	for _, frag := range frags {
+		seg.AddFragment(frag)
+	}
+	wanted := bytes.Buffer{}
+	if err = seg.Encode(&wanted); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(ofh.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, wanted.Bytes()) {
+		t.Error("written segment differs from encoded media segment")
+	}
+
+	sidx.SidxRefs = append(sidx.SidxRefs, SidxRef{})
+	if err = sw.PatchSidx(ofh, sidx); err == nil {
+		t.Error("no error for changed sidx size")
+	}
+	if err = NewSegmentWriter(&bytes.Buffer{}).WriteChunk(chunk); err == nil {
+		t.Error("no error for chunk without fragment")
+	}
+}
@@ -37,6 +37,7 @@ type SencBox struct {
 	IVs              []InitializationVector // 8 or 16 bytes if present
 	SubSamples       [][]SubSamplePattern
 	readBoxSize      uint64 // As read from box header
+	saizLayout       bool   // Samples with nil SubSamples have no subsample count, as given by saiz
 }
 
 // CreateSencBox - create an empty SencBox
@@ -77,9 +78,16 @@ func (s *SencBox) AddSample(sample SencSample) error {
 		}
 	}
 
-	if len(sample.SubSamples) > 0 {
+	switch {
+	case len(sample.SubSamples) > 0:
+		// Samples without subsamples get nil entries, so that entries and samples stay aligned
+		for len(s.SubSamples) < int(s.SampleCount) {
+			s.SubSamples = append(s.SubSamples, nil)
+		}
 		s.SubSamples = append(s.SubSamples, sample.SubSamples)
 		s.Flags |= UseSubSampleEncryption
+	case len(s.SubSamples) > 0:
+		s.SubSamples = append(s.SubSamples, nil)
 	}
 	s.SampleCount++
 	return nil
@@ -166,74 +174,70 @@ func DecodeSencSR(hdr BoxHeader, startPos uint64, sr bits.SliceReader) (Box, err
 }
 
 // ParseReadBox - second phase when perSampleIVSize should be known from tenc or sgpd boxes
-// if perSampleIVSize is 0, we try to find the appropriate error given data length
+// if perSampleIVSize is 0, we try to find the appropriate size given data length.
+// Since some files have senc flags that do not match the data, other layouts are tried
+// if the data cannot be parsed as indicated by the flags: per-sample subsample presence
+// given by the sample info sizes in saiz, and the opposite setting of the subsample flag.
+// If no layout works with perSampleIVSize, the other IV sizes 0, 8, and 16 are tried.
 func (s *SencBox) ParseReadBox(perSampleIVSize byte, saiz *SaizBox) error {
 	if !s.readButNotParsed {
 		return fmt.Errorf("senc box already parsed")
 	}
+	ivSizes := []byte{0, 8, 16}
 	if perSampleIVSize != 0 {
-		s.perSampleIVSize = byte(perSampleIVSize)
+		ivSizes = []byte{perSampleIVSize}
+		for _, size := range []byte{0, 8, 16} {
+			if size != perSampleIVSize {
+				ivSizes = append(ivSizes, size)
+			}
+		}
 	}
+	useSubSamples := s.Flags&UseSubSampleEncryption != 0
 	sr := bits.NewFixedSliceReader(s.rawData)
-	nrBytesLeft := uint32(sr.NrRemainingBytes())
-
-	if s.Flags&UseSubSampleEncryption == 0 {
-		// No subsamples
-		if perSampleIVSize == 0 { // Infer the size
-			perSampleIVSize = byte(nrBytesLeft / s.SampleCount)
-			s.perSampleIVSize = perSampleIVSize
+	for _, ivSize := range ivSizes {
+		sr.SetPos(0)
+		if useSubSamples && s.parseAndFillSamples(sr, ivSize) || !useSubSamples && s.parseIVs(sr, ivSize) {
+			s.readButNotParsed = false
+			return nil
 		}
-
-		s.IVs = make([]InitializationVector, 0, s.SampleCount)
-		switch perSampleIVSize {
-		case 0:
-			// Nothing to do
-		case 8:
-			for i := 0; i < int(s.SampleCount); i++ {
-				s.IVs = append(s.IVs, sr.ReadBytes(8))
-			}
-		case 16:
-			for i := 0; i < int(s.SampleCount); i++ {
-				s.IVs = append(s.IVs, sr.ReadBytes(16))
-			}
-		default:
-			return fmt.Errorf("strange derived PerSampleIVSize: %d", perSampleIVSize)
+		sr.SetPos(0)
+		if saiz != nil && saiz.SampleCount == s.SampleCount && s.parseWithSaiz(sr, ivSize, saiz) {
+			s.readButNotParsed = false
+			return nil
+		}
+		sr.SetPos(0)
+		if useSubSamples && s.parseIVs(sr, ivSize) || !useSubSamples && s.parseAndFillSamples(sr, ivSize) {
+			s.Flags ^= UseSubSampleEncryption
+			s.readButNotParsed = false
+			return nil
 		}
-		s.readButNotParsed = false
-		return nil
 	}
-	// 6 bytes of subsamplecount per subsample and known perSampleIVSize
-	// The total length for each sample should correspond to
-	// sizes in saiz (defaultSampleInfoSize or SampleInfo value)
-	// We don't check that though, but it could be implemented here.
 	if perSampleIVSize != 0 {
-		if ok := s.parseAndFillSamples(sr, perSampleIVSize); !ok {
-			return fmt.Errorf("error decoding senc with perSampleIVSize = %d", perSampleIVSize)
-		}
-		s.readButNotParsed = false
-		return nil
+		return fmt.Errorf("error decoding senc with perSampleIVSize = %d", perSampleIVSize)
 	}
+	return fmt.Errorf("could not decode senc")
+}
 
-	// Finally, 6 bytes of subsamplecount per subsample and unknown perSampleIVSize
-	startPos := sr.GetPos()
-	ok := false
-	for perSampleIVSize := byte(0); perSampleIVSize <= 16; perSampleIVSize += 8 {
-		sr.SetPos(startPos)
-		ok = s.parseAndFillSamples(sr, perSampleIVSize)
-		if ok {
-			break // We have found a working perSampleIVSize
-		}
+// parseIVs - parse senc samples without subsamples given perSampleIVSize
+func (s *SencBox) parseIVs(sr bits.SliceReader, perSampleIVSize byte) (ok bool) {
+	if sr.NrRemainingBytes() != int(s.SampleCount)*int(perSampleIVSize) {
+		return false
 	}
-	if !ok {
-		return fmt.Errorf("could not decode senc")
+	s.IVs = make([]InitializationVector, 0, s.SampleCount)
+	if perSampleIVSize > 0 {
+		for i := 0; i < int(s.SampleCount); i++ {
+			s.IVs = append(s.IVs, sr.ReadBytes(int(perSampleIVSize)))
+		}
 	}
-	s.readButNotParsed = false
-	return nil
+	s.SubSamples = nil
+	s.perSampleIVSize = perSampleIVSize
+	return true
 }
 
 // parseAndFillSamples - parse and fill senc samples given perSampleIVSize
 func (s *SencBox) parseAndFillSamples(sr bits.SliceReader, perSampleIVSize byte) (ok bool) {
 	ok = true
+	s.IVs = nil
 	s.SubSamples = make([][]SubSamplePattern, s.SampleCount)
 	for i := 0; i < int(s.SampleCount); i++ {
 		if perSampleIVSize > 0 {
@@ -268,6 +272,61 @@ func (s *SencBox) parseAndFillSamples(sr bits.SliceReader, perSampleIVSize byte)
 	return ok
 }
 
+// parseWithSaiz - parse senc samples where the saiz sample info size tells if a sample
+// has subsamples. A sample with info size equal to perSampleIVSize has no subsamples.
+// The layout is kept, so that samples without subsamples are encoded without subsample count,
+// and the box stays consistent with the saiz and saio boxes.
+func (s *SencBox) parseWithSaiz(sr bits.SliceReader, perSampleIVSize byte, saiz *SaizBox) (ok bool) {
+	ivs := make([]InitializationVector, 0, s.SampleCount)
+	subSamples := make([][]SubSamplePattern, s.SampleCount)
+	hasSubSamples := false
+	for i := 0; i < int(s.SampleCount); i++ {
+		infoSize := int(saiz.DefaultSampleInfoSize)
+		if infoSize == 0 {
+			if i >= len(saiz.SampleInfo) {
+				return false
+			}
+			infoSize = int(saiz.SampleInfo[i])
+		}
+		if infoSize < int(perSampleIVSize) || sr.NrRemainingBytes() < infoSize {
+			return false
+		}
+		if perSampleIVSize > 0 {
+			ivs = append(ivs, sr.ReadBytes(int(perSampleIVSize)))
+		}
+		subSampleSize := infoSize - int(perSampleIVSize)
+		if subSampleSize == 0 {
+			continue
+		}
+		if subSampleSize < 2 {
+			return false
+		}
+		subsampleCount := int(sr.ReadUint16())
+		if subSampleSize != 2+6*subsampleCount {
+			return false
+		}
+		subSamples[i] = make([]SubSamplePattern, subsampleCount)
+		for j := 0; j < subsampleCount; j++ {
+			subSamples[i][j].BytesOfClearData = sr.ReadUint16()
+			subSamples[i][j].BytesOfProtectedData = sr.ReadUint32()
+		}
+		hasSubSamples = true
+	}
+	if sr.NrRemainingBytes() != 0 {
+		return false
+	}
+	s.IVs = ivs
+	s.SubSamples = nil
+	s.Flags &^= UseSubSampleEncryption
+	if hasSubSamples {
+		s.SubSamples = subSamples
+		s.Flags |= UseSubSampleEncryption
+	}
+	s.perSampleIVSize = perSampleIVSize
+	s.saizLayout = hasSubSamples
+	return true
+}
+
 // Type - box-specific type
 func (s *SencBox) Type() string {
 	return "senc"
@@ -296,13 +355,18 @@ func (s *SencBox) calcSize() uint64 {
 	perSampleIVSize := uint64(s.GetPerSampleIVSize())
 	for i := uint32(0); i < s.SampleCount; i++ {
 		totalSize += perSampleIVSize
-		if s.Flags&UseSubSampleEncryption != 0 {
+		if s.Flags&UseSubSampleEncryption != 0 && !s.omitSubSampleCount(i) {
 			totalSize += 2 + 6*uint64(len(s.SubSamples[i]))
 		}
 	}
 	return totalSize
 }
 
+// omitSubSampleCount - true if sample i was read without subsample count
+func (s *SencBox) omitSubSampleCount(i uint32) bool {
+	return s.saizLayout && s.SubSamples[i] == nil
+}
+
 // Encode - write box to w
 func (s *SencBox) Encode(w io.Writer) error {
 	// First check if subsamplencryption is to be used since it influences the box size
@@ -341,7 +405,7 @@ func (s *SencBox) EncodeSWNoHdr(sw bits.SliceWriter) error {
 		if perSampleIVSize > 0 {
 			sw.WriteBytes(s.IVs[i])
 		}
-		if s.Flags&UseSubSampleEncryption != 0 {
+		if s.Flags&UseSubSampleEncryption != 0 && !s.omitSubSampleCount(uint32(i)) {
 			sw.WriteUint16(uint16(len(s.SubSamples[i])))
 			for _, subSample := range s.SubSamples[i] {
 				sw.WriteUint16(subSample.BytesOfClearData)
@@ -367,9 +431,12 @@ func (s *SencBox) Info(w io.Writer, specificBoxLevels, indent, indentStep string
 	}
 	perSampleIVSize := s.GetPerSampleIVSize()
 	bd.write(" - perSampleIVSize: %d", perSampleIVSize)
-	level := getInfoLevel(s, specificBoxLevels)
+	level, sampleRange := getInfoLevelAndRange(s, specificBoxLevels)
 	if level > 0 && (perSampleIVSize > 0 || s.Flags&UseSubSampleEncryption != 0) {
 		for i := 0; i < int(s.SampleCount); i++ {
+			if !sampleRange.contains(i + 1) {
+				continue
+			}
 			line := fmt.Sprintf(" - sample[%d]:", i+1)
 			if perSampleIVSize > 0 {
 				line += fmt.Sprintf(" iv=%s", hex.EncodeToString(s.IVs[i]))
@@ -390,3 +457,67 @@ func (s *SencBox) Info(w io.Writer, specificBoxLevels, indent, indentStep string
 func (s *SencBox) GetPerSampleIVSize() int {
 	return int(s.perSampleIVSize)
 }
+
+// SubSamplePatterns returns the subsample patterns for each sample. The entry for a sample without
+// subsamples is nil, which means that the full sample is protected.
+// A senc box that has only been read must first be parsed with ParseReadBox.
+func (s *SencBox) SubSamplePatterns() ([][]SubSamplePattern, error) {
+	if s.readButNotParsed {
+		return nil, fmt.Errorf("senc box not parsed")
+	}
+	patterns := make([][]SubSamplePattern, s.SampleCount)
+	switch len(s.SubSamples) {
+	case 0:
+	case int(s.SampleCount):
+		copy(patterns, s.SubSamples)
+	default:
+		return nil, fmt.Errorf("%d subsample entries for %d samples", len(s.SubSamples), s.SampleCount)
+	}
+	return patterns, nil
+}
+
+// CryptRange - byte range in a sample which is either clear or protected
+type CryptRange struct {
+	Offset    uint32
+	Size      uint32
+	Protected bool
+}
+
+// SampleCryptRanges returns the clear and protected byte ranges of (one-based) sampleNr given
+// its sampleSize. Empty ranges are left out and adjacent ranges of the same kind are merged. Any bytes after the last subsample are clear.
+func (s *SencBox) SampleCryptRanges(sampleNr uint32, sampleSize uint32) ([]CryptRange, error) {
+	if sampleNr < 1 || sampleNr > s.SampleCount {
+		return nil, fmt.Errorf("sample number %d outside range 1-%d", sampleNr, s.SampleCount)
+	}
+	patterns, err := s.SubSamplePatterns()
+	if err != nil {
+		return nil, err
+	}
+	subSamples := patterns[sampleNr-1]
+	if subSamples == nil {
+		return []CryptRange{{Offset: 0, Size: sampleSize, Protected: true}}, nil
+	}
+	var ranges []CryptRange
+	var offset uint64
+	addRange := func(size uint64, protected bool) {
+		if size == 0 {
+			return
+		}
+		if n := len(ranges); n > 0 && ranges[n-1].Protected == protected {
+			ranges[n-1].Size += uint32(size)
+		} else {
+			ranges = append(ranges, CryptRange{Offset: uint32(offset), Size: uint32(size), Protected: protected})
+		}
+		offset += size
+	}
+	for _, ss := range subSamples {
+		addRange(uint64(ss.BytesOfClearData), false)
+		addRange(uint64(ss.BytesOfProtectedData), true)
+	}
+	if offset > uint64(sampleSize) {
+		return nil, fmt.Errorf("subsamples of sample %d cover %d bytes, more than sample size %d",
+			sampleNr, offset, sampleSize)
+	}
+	addRange(uint64(sampleSize)-offset, false)
+	return ranges, nil
+}
@@ -212,3 +212,171 @@ func TestBadSencData(t *testing.T) {
 		})
 	}
 }
+
+func TestSencSubSamplePatterns(t *testing.T) {
+	iv8 := InitializationVector("01234567")
+	senc := CreateSencBox()
+	for _, subSamples := range [][]SubSamplePattern{{{10, 1000}, {0, 16}}, {{20, 0}}} {
+		if err := senc.AddSample(SencSample{iv8, subSamples}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	patterns, err := senc.SubSamplePatterns()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := deep.Equal(patterns, senc.SubSamples); diff != nil {
+		t.Error(diff)
+	}
+	ranges, err := senc.SampleCryptRanges(1, 1030)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wanted := []CryptRange{{0, 10, false}, {10, 1016, true}, {1026, 4, false}}
+	if diff := deep.Equal(ranges, wanted); diff != nil {
+		t.Error(diff)
+	}
+	if _, err = senc.SampleCryptRanges(2, 10); err == nil {
+		t.Error("no error for subsamples larger than sample")
+	}
+	if _, err = senc.SampleCryptRanges(3, 10); err == nil {
+		t.Error("no error for sample number out of range")
+	}
+
+	noSubs := CreateSencBox()
+	if err := noSubs.AddSample(SencSample{iv8, nil}); err != nil {
+		t.Fatal(err)
+	}
+	ranges, err = noSubs.SampleCryptRanges(1, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := deep.Equal(ranges, []CryptRange{{0, 100, true}}); diff != nil {
+		t.Error(diff)
+	}
+}
+
+// TestSencInconsistentSubSamples tests parsing of senc boxes where the data does not match the flags
+func TestSencInconsistentSubSamples(t *testing.T) {
+	iv8 := InitializationVector("01234567")
+	sencData := func(flags uint32, sampleCount uint32, payload []byte) []byte {
+		sw := bits.NewFixedSliceWriter(16 + len(payload))
+		sw.WriteUint32(uint32(16 + len(payload)))
+		sw.WriteString("senc", false)
+		sw.WriteUint32(flags)
+		sw.WriteUint32(sampleCount)
+		sw.WriteBytes(payload)
+		return sw.Bytes()
+	}
+	withSubSamples := append(append([]byte{}, iv8...), 0, 1, 0, 10, 0, 0, 0x03, 0xe8) // 1 subsample {10, 1000}
+	withTwoSubSamples := append(append([]byte{}, iv8...), 0, 2, 0, 10, 0, 0, 0x03, 0xe8, 0, 20, 0, 0, 0, 0)
+
+	cases := []struct {
+		desc            string
+		raw             []byte
+		perSampleIVSize byte
+		saiz            *SaizBox
+		wantedFlags     uint32
+		wantedIVs       []InitializationVector
+		wantedSubs      [][]SubSamplePattern
+		keepsLayout     bool
+	}{
+		{
+			desc:            "subsample flag but only IVs",
+			raw:             sencData(UseSubSampleEncryption, 2, append(append([]byte{}, iv8...), iv8...)),
+			perSampleIVSize: 8,
+			wantedFlags:     0,
+			wantedIVs:       []InitializationVector{iv8, iv8},
+		},
+		{
+			desc:            "subsamples without flag",
+			raw:             sencData(0, 1, withSubSamples),
+			perSampleIVSize: 8,
+			wantedFlags:     UseSubSampleEncryption,
+			wantedIVs:       []InitializationVector{iv8},
+			wantedSubs:      [][]SubSamplePattern{{{10, 1000}}},
+		},
+		{
+			desc:            "subsample count missing for second sample",
+			raw:             sencData(UseSubSampleEncryption, 2, append(append([]byte{}, withSubSamples...), iv8...)),
+			perSampleIVSize: 8,
+			saiz:            &SaizBox{SampleCount: 2, SampleInfo: []byte{16, 8}},
+			wantedFlags:     UseSubSampleEncryption,
+			wantedIVs:       []InitializationVector{iv8, iv8},
+			wantedSubs:      [][]SubSamplePattern{{{10, 1000}}, nil},
+			keepsLayout:     true,
+		},
+		{
+			desc:            "wrong perSampleIVSize",
+			raw:             sencData(UseSubSampleEncryption, 1, withTwoSubSamples),
+			perSampleIVSize: 16,
+			wantedFlags:     UseSubSampleEncryption,
+			wantedIVs:       []InitializationVector{iv8},
+			wantedSubs:      [][]SubSamplePattern{{{10, 1000}, {20, 0}}},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			box, err := DecodeBoxSR(0, bits.NewFixedSliceReader(c.raw))
+			if err != nil {
+				t.Fatal(err)
+			}
+			senc := box.(*SencBox)
+			if err = senc.ParseReadBox(c.perSampleIVSize, c.saiz); err != nil {
+				t.Fatal(err)
+			}
+			if senc.Flags != c.wantedFlags {
+				t.Errorf("got flags %d instead of %d", senc.Flags, c.wantedFlags)
+			}
+			if diff := deep.Equal(senc.IVs, c.wantedIVs); diff != nil {
+				t.Error(diff)
+			}
+			if diff := deep.Equal(senc.SubSamples, c.wantedSubs); diff != nil {
+				t.Error(diff)
+			}
+			buf := bytes.Buffer{}
+			if err = senc.Encode(&buf); err != nil {
+				t.Fatal(err)
+			}
+			if uint64(buf.Len()) != senc.Size() {
+				t.Errorf("encoded size %d differs from size %d", buf.Len(), senc.Size())
+			}
+			if c.keepsLayout && !bytes.Equal(buf.Bytes(), c.raw) {
+				t.Errorf("encoded senc differs from input")
+			}
+		})
+	}
+}
+
+func TestSencAddSamplesWithoutSubSamples(t *testing.T) {
+	iv8 := InitializationVector("01234567")
+	senc := CreateSencBox()
+	for _, subSamples := range [][]SubSamplePattern{nil, {{10, 1000}}, nil} {
+		if err := senc.AddSample(SencSample{iv8, subSamples}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	wanted := [][]SubSamplePattern{nil, {{10, 1000}}, nil}
+	if diff := deep.Equal(senc.SubSamples, wanted); diff != nil {
+		t.Error(diff)
+	}
+	buf := bytes.Buffer{}
+	if err := senc.Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	box, err := DecodeBox(0, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decSenc := box.(*SencBox)
+	if err = decSenc.ParseReadBox(8, nil); err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := decSenc.SubSamplePatterns()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded[0]) != 0 || len(decoded[1]) != 1 || len(decoded[2]) != 0 {
+		t.Errorf("unexpected subsample patterns %v", decoded)
+	}
+}
@@ -53,6 +53,14 @@ func DecodeSinfSR(hdr BoxHeader, startPos uint64, sr bits.SliceReader) (Box, err
 	return &b, sr.AccError()
 }
 
+// OriginalFormat - data format of the original sample entry given by the frma box, or "" if no frma box
+func (b *SinfBox) OriginalFormat() string {
+	if b.Frma == nil {
+		return ""
+	}
+	return b.Frma.DataFormat
+}
+
 // Type - box type
 func (b *SinfBox) Type() string {
 	return "sinf"
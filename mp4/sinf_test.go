@@ -0,0 +1,16 @@
+package mp4
+
+import "testing"
+
+func TestSinfOriginalFormat(t *testing.T) {
+	sinf := &SinfBox{}
+	if got := sinf.OriginalFormat(); got != "" {
+		t.Errorf("got original format %q without frma", got)
+	}
+	sinf.AddChild(&FrmaBox{DataFormat: "hvc1"})
+	sinf.AddChild(&SchmBox{SchemeType: "cenc", SchemeVersion: 0x00010000})
+	boxDiffAfterEncodeAndDecode(t, sinf)
+	if got := sinf.OriginalFormat(); got != "hvc1" {
+		t.Errorf("got original format %q instead of hvc1", got)
+	}
+}
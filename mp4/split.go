@@ -0,0 +1,246 @@
+package mp4
+
+import (
+	"fmt"
+	"math"
+)
+
+// splitTrack - samples and timeline of one track in a progressive file to be split
+type splitTrack struct {
+	trak           *TrakBox
+	samples        []FullSample
+	sampleDescrIDs []uint32
+	mediaOffset    int64  // media time at presentation start given by the edit list
+	presDuration   uint64 // presentation duration in media timescale
+	cutIdx         int    // index of first sample in after part
+	afterStart     int64  // media time where the presentation of the after part starts
+}
+
+// SplitAt splits a progressive file into two files before and after a sync sample of track trackID.
+// time is a presentation time in the media timescale of the track, where 0 is the start of the
+// presentation given by the edit list. The cut is made at the last sync sample with a composition
+// time not after time. Other tracks are cut at their last sync sample not after the composition
+// time of that sample.
+//
+// The stbl tables of both files are rebuilt with one chunk per track and sample description,
+// and the decode times of the after file start at zero. Each track gets one edit list entry,
+// so that the before file ends at the composition time of the cut sample and the after file starts
+// at time. Samples between the cut sample and time are thereby present, but not presented, which
+// handles composition time offsets of B-frames around the cut. Durations in mvhd, tkhd and mdhd
+// are set accordingly.
+//
+// Only edit lists with one entry with media rate 1 are supported, and the mdat data must be
+// in memory. The sample data is shared with f.
+func (f *File) SplitAt(trackID uint32, time uint64) (before, after *File, err error) {
+	if f.isFragmented {
+		return nil, nil, fmt.Errorf("only available for progressive files")
+	}
+	if f.Moov == nil || f.Mdat == nil {
+		return nil, nil, fmt.Errorf("moov or mdat box missing")
+	}
+	tracks := make([]*splitTrack, 0, len(f.Moov.Traks))
+	var ref *splitTrack
+	for _, trak := range f.Moov.Traks {
+		st, err := f.newSplitTrack(trak)
+		if err != nil {
+			return nil, nil, fmt.Errorf("track %d: %w", trak.Tkhd.TrackID, err)
+		}
+		if trak.Tkhd.TrackID == trackID {
+			ref = st
+		}
+		tracks = append(tracks, st)
+	}
+	if ref == nil {
+		return nil, nil, fmt.Errorf("no track with trackID %d", trackID)
+	}
+	if time >= ref.presDuration {
+		return nil, nil, fmt.Errorf("time %d not before end of track %d", time, trackID)
+	}
+	refCutIdx := ref.lastSyncSampleIdx(int64(time) + ref.mediaOffset)
+	if refCutIdx <= 0 {
+		return nil, nil, fmt.Errorf("no sync sample after first sample before time %d in track %d", time, trackID)
+	}
+	splitTime := compositionTime(ref.samples[refCutIdx]) - ref.mediaOffset
+	if splitTime < 0 {
+		return nil, nil, fmt.Errorf("cut sample of track %d is before start of presentation", trackID)
+	}
+	refTimescale := ref.trak.Mdia.Mdhd.Timescale
+	for _, st := range tracks {
+		timescale := st.trak.Mdia.Mdhd.Timescale
+		trackSplitTime := int64(scaleTime(uint64(splitTime), refTimescale, timescale))
+		st.cutIdx = st.lastSyncSampleIdx(trackSplitTime + st.mediaOffset)
+		if st.trak == ref.trak {
+			st.cutIdx = refCutIdx
+		}
+		if st.cutIdx <= 0 || compositionTime(st.samples[st.cutIdx]) <= st.mediaOffset {
+			return nil, nil, fmt.Errorf("track %d: no samples before split", st.trak.Tkhd.TrackID)
+		}
+		startTime := scaleTime(time, refTimescale, timescale)
+		if startTime >= st.presDuration {
+			return nil, nil, fmt.Errorf("track %d: no samples after split", st.trak.Tkhd.TrackID)
+		}
+		st.afterStart = int64(startTime) + st.mediaOffset
+	}
+	before, err = f.buildSplitFile(tracks, false)
+	if err != nil {
+		return nil, nil, fmt.Errorf("before: %w", err)
+	}
+	after, err = f.buildSplitFile(tracks, true)
+	if err != nil {
+		return nil, nil, fmt.Errorf("after: %w", err)
+	}
+	return before, after, nil
+}
+
+// newSplitTrack reads the samples and edit list of trak.
+func (f *File) newSplitTrack(trak *TrakBox) (*splitTrack, error) {
+	nrSamples := trak.GetNrSamples()
+	if nrSamples == 0 {
+		return nil, fmt.Errorf("no samples")
+	}
+	samples, err := f.ReadSamples(nil, trak, 1, nrSamples)
+	if err != nil {
+		return nil, err
+	}
+	st := &splitTrack{trak: trak, samples: samples, sampleDescrIDs: make([]uint32, nrSamples)}
+	stsc := trak.Mdia.Minf.Stbl.Stsc
+	for i := range samples {
+		chunkNr, _, err := stsc.ChunkNrFromSampleNr(i + 1)
+		if err != nil {
+			return nil, err
+		}
+		st.sampleDescrIDs[i] = stsc.GetSampleDescriptionID(chunkNr)
+	}
+	var mediaDuration uint64
+	for i := range samples {
+		mediaDuration += uint64(samples[i].Dur)
+	}
+	entries := trak.GetEditList()
+	switch {
+	case len(entries) == 0:
+		st.presDuration = mediaDuration
+	case len(entries) == 1 && entries[0].MediaTime >= 0 && entries[0].MediaRateInteger == 1:
+		st.mediaOffset = entries[0].MediaTime
+		if uint64(st.mediaOffset) >= mediaDuration {
+			return nil, fmt.Errorf("edit list media time %d after end of media", st.mediaOffset)
+		}
+		st.presDuration = mediaDuration - uint64(st.mediaOffset)
+		if entries[0].SegmentDuration > 0 {
+			segDur := scaleTime(entries[0].SegmentDuration, f.Moov.Mvhd.Timescale, trak.Mdia.Mdhd.Timescale)
+			if segDur < st.presDuration {
+				st.presDuration = segDur
+			}
+		}
+	default:
+		return nil, fmt.Errorf("only edit lists with one entry with media rate 1 are supported")
+	}
+	return st, nil
+}
+
+// lastSyncSampleIdx returns the index of the last sync sample with composition time not after
+// mediaTime, or -1 if there is no such sample.
+func (st *splitTrack) lastSyncSampleIdx(mediaTime int64) int {
+	idx := -1
+	for i := range st.samples {
+		if st.samples[i].IsSync() && compositionTime(st.samples[i]) <= mediaTime {
+			idx = i
+		}
+	}
+	return idx
+}
+
+// buildSplitFile creates the before or after file given the cut of each track.
+func (f *File) buildSplitFile(tracks []*splitTrack, isAfter bool) (*File, error) {
+	moov, err := copyMoovWithoutMvex(f.Moov)
+	if err != nil {
+		return nil, err
+	}
+	movieTimescale := moov.Mvhd.Timescale
+	var payload [][]byte
+	var payloadSize uint64
+	uts := make([]*unfragTrack, len(tracks))
+	edits := make([]ElstEntry, len(tracks))
+	for i, st := range tracks {
+		ut := &unfragTrack{trak: moov.Traks[i], stts: &SttsBox{}, stsc: &StscBox{}}
+		timescale := st.trak.Mdia.Mdhd.Timescale
+		samples := st.samples[:st.cutIdx]
+		sdIDs := st.sampleDescrIDs[:st.cutIdx]
+		cutTime := compositionTime(st.samples[st.cutIdx])
+		edit := ElstEntry{
+			SegmentDuration:  scaleTime(uint64(cutTime-st.mediaOffset), timescale, movieTimescale),
+			MediaTime:        st.mediaOffset,
+			MediaRateInteger: 1,
+		}
+		if isAfter {
+			samples = st.samples[st.cutIdx:]
+			sdIDs = st.sampleDescrIDs[st.cutIdx:]
+			presStart := uint64(st.afterStart - st.mediaOffset)
+			edit = ElstEntry{
+				SegmentDuration:  scaleTime(st.presDuration-presStart, timescale, movieTimescale),
+				MediaTime:        st.afterStart - int64(st.samples[st.cutIdx].DecodeTime),
+				MediaRateInteger: 1,
+			}
+		}
+		start := 0
+		for j := 1; j <= len(samples); j++ {
+			if j < len(samples) && sdIDs[j] == sdIDs[start] {
+				continue
+			}
+			err = ut.addChunk(samples[start:j], payloadSize, sdIDs[start])
+			if err != nil {
+				return nil, fmt.Errorf("track %d: %w", st.trak.Tkhd.TrackID, err)
+			}
+			for k := start; k < j; k++ {
+				payload = append(payload, samples[k].Data)
+				payloadSize += uint64(len(samples[k].Data))
+			}
+			start = j
+		}
+		uts[i] = ut
+		edits[i] = edit
+	}
+
+	mdat := &MdatBox{}
+	if payloadSize+8 > math.MaxUint32 {
+		mdat.LargeSize = true
+	}
+	for _, data := range payload {
+		mdat.AddSampleData(data)
+	}
+	var movieDuration uint64
+	for i, ut := range uts {
+		ut.setSampleTable(mdat.HeaderSize())
+		ut.trak.SetEditList([]ElstEntry{edits[i]})
+		trackDuration := edits[i].SegmentDuration
+		setDuration(ut.trak, trackDuration, ut.duration, ut.trak.Mdia.Mdhd.Timescale, movieTimescale)
+		if trackDuration > movieDuration {
+			movieDuration = trackDuration
+		}
+	}
+	moov.Mvhd.SetDuration(movieDuration)
+
+	out := NewFile()
+	if f.Ftyp != nil {
+		out.AddChild(f.Ftyp, 0)
+	}
+	out.AddChild(moov, 0)
+	out.AddChild(mdat, 0)
+	err = out.FixChunkOffsets()
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// compositionTime returns the composition time of a sample in media timescale.
+func compositionTime(s FullSample) int64 {
+	return int64(s.DecodeTime) + int64(s.CompositionTimeOffset)
+}
+
+// scaleTime converts t from timescale from to timescale to.
+func scaleTime(t uint64, from, to uint32) uint64 {
+	if from == to {
+		return t
+	}
+	return t * uint64(to) / uint64(from)
+}
@@ -0,0 +1,104 @@
+package mp4
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSplitAt(t *testing.T) {
+	f, err := ReadMP4File("testdata/prog_8s.mp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	const videoTrackID = 2
+	var videoTrak *TrakBox
+	for _, trak := range f.Moov.Traks {
+		if trak.Tkhd.TrackID == videoTrackID {
+			videoTrak = trak
+		}
+	}
+	timescale := videoTrak.Mdia.Mdhd.Timescale
+	splitTime := uint64(timescale) * 5 / 2 // 2.5s is inside the GOP starting with sample 61
+	before, after, err := f.SplitAt(videoTrackID, splitTime)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decode := func(file *File) *File {
+		t.Helper()
+		buf := bytes.Buffer{}
+		if err := file.Encode(&buf); err != nil {
+			t.Fatal(err)
+		}
+		dec, err := DecodeFile(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return dec
+	}
+	before, after = decode(before), decode(after)
+
+	for i, trak := range f.Moov.Traks {
+		trackID := trak.Tkhd.TrackID
+		nrSamples := trak.GetNrSamples()
+		bTrak, aTrak := before.Moov.Traks[i], after.Moov.Traks[i]
+		nrBefore, nrAfter := bTrak.GetNrSamples(), aTrak.GetNrSamples()
+		if nrBefore+nrAfter != nrSamples {
+			t.Errorf("track %d: %d + %d samples instead of %d", trackID, nrBefore, nrAfter, nrSamples)
+		}
+		orig, err := f.ReadSamples(nil, trak, 1, nrSamples)
+		if err != nil {
+			t.Fatal(err)
+		}
+		bSamples, err := before.ReadSamples(nil, bTrak, 1, nrBefore)
+		if err != nil {
+			t.Fatal(err)
+		}
+		aSamples, err := after.ReadSamples(nil, aTrak, 1, nrAfter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !aSamples[0].IsSync() {
+			t.Errorf("track %d: first sample after split is not sync", trackID)
+		}
+		cutDecodeTime := orig[nrBefore].DecodeTime
+		for j, s := range append(bSamples, aSamples...) {
+			o := orig[j]
+			if j >= int(nrBefore) {
+				s.DecodeTime += cutDecodeTime
+			}
+			if s.DecodeTime != o.DecodeTime || s.CompositionTimeOffset != o.CompositionTimeOffset ||
+				!bytes.Equal(s.Data, o.Data) {
+				t.Fatalf("track %d: sample %d differs from original", trackID, j+1)
+			}
+		}
+		if trackID != videoTrackID {
+			continue
+		}
+		if nrBefore != 60 {
+			t.Errorf("video split before sample %d instead of 61", nrBefore+1)
+		}
+		bEdits, aEdits := bTrak.GetEditList(), aTrak.GetEditList()
+		cutCompTime := compositionTime(orig[nrBefore])
+		movieTimescale := f.Moov.Mvhd.Timescale
+		wantedBefore := uint64(cutCompTime) * uint64(movieTimescale) / uint64(timescale)
+		if len(bEdits) != 1 || bEdits[0].MediaTime != 0 || bEdits[0].SegmentDuration != wantedBefore {
+			t.Errorf("before edit list %+v, wanted duration %d", bEdits, wantedBefore)
+		}
+		wantedMediaTime := int64(splitTime - cutDecodeTime)
+		if len(aEdits) != 1 || aEdits[0].MediaTime != wantedMediaTime {
+			t.Errorf("after edit list %+v, wanted media time %d", aEdits, wantedMediaTime)
+		}
+		wantedAfter := (trak.Mdia.Mdhd.Duration - splitTime) * uint64(movieTimescale) / uint64(timescale)
+		if aTrak.Tkhd.Duration != wantedAfter {
+			t.Errorf("after tkhd duration %d instead of %d", aTrak.Tkhd.Duration, wantedAfter)
+		}
+	}
+
+	if _, _, err = f.SplitAt(videoTrackID, uint64(timescale)/2); err == nil {
+		t.Error("no error for split inside first GOP")
+	}
+	if _, _, err = f.SplitAt(3, splitTime); err == nil {
+		t.Error("no error for unknown track")
+	}
+}
@@ -1,7 +1,9 @@
 package mp4
 
 import (
+	"fmt"
 	"io"
+	"math"
 
 	"github.com/Eyevinn/mp4ff/bits"
 )
@@ -16,8 +18,10 @@ type StblBox struct {
 	Stsd  *StsdBox
 	Stts  *SttsBox
 	Ctts  *CttsBox
+	Cslg  *CslgBox
 	Stsc  *StscBox
 	Stsz  *StszBox
+	Stz2  *Stz2Box
 	Stss  *StssBox
 	Stco  *StcoBox
 	Co64  *Co64Box
@@ -48,10 +52,14 @@ func (s *StblBox) AddChild(child Box) {
 		s.Stts = box
 	case *CttsBox:
 		s.Ctts = box
+	case *CslgBox:
+		s.Cslg = box
 	case *StscBox:
 		s.Stsc = box
 	case *StszBox:
 		s.Stsz = box
+	case *Stz2Box:
+		s.Stz2 = box
 	case *StssBox:
 		s.Stss = box
 	case *StcoBox:
@@ -135,3 +143,174 @@ func (b *StblBox) EncodeSW(sw bits.SliceWriter) error {
 func (s *StblBox) Info(w io.Writer, specificBoxLevels, indent, indentStep string) error {
 	return ContainerInfo(s, w, specificBoxLevels, indent, indentStep)
 }
+
+// sampleSizeTable - sample size access common to stsz and stz2
+type sampleSizeTable interface {
+	GetNrSamples() uint32
+	GetSampleSize(i int) uint32
+	GetTotalSampleSize(startNr, endNr uint32) (uint64, error)
+}
+
+// sampleSizes returns the stsz box, or the stz2 box if there is no stsz box, or nil if neither is present.
+func (s *StblBox) sampleSizes() sampleSizeTable {
+	switch {
+	case s.Stsz != nil:
+		return s.Stsz
+	case s.Stz2 != nil:
+		return s.Stz2
+	default:
+		return nil
+	}
+}
+
+// promoteStcoToCo64 replaces the stco box with a co64 box with the same offsets.
+func (s *StblBox) promoteStcoToCo64() {
+	if s.Stco == nil {
+		return
+	}
+	co64 := &Co64Box{ChunkOffset: make([]uint64, len(s.Stco.ChunkOffset))}
+	for i, offset := range s.Stco.ChunkOffset {
+		co64.ChunkOffset[i] = uint64(offset)
+	}
+	for i, c := range s.Children {
+		if c == s.Stco {
+			s.Children[i] = co64
+			break
+		}
+	}
+	s.Stco = nil
+	s.Co64 = co64
+}
+
+// demoteCo64ToStco replaces the co64 box with an stco box with the same offsets.
+// All offsets must fit in 32 bits.
+func (s *StblBox) demoteCo64ToStco() {
+	if s.Co64 == nil {
+		return
+	}
+	stco := &StcoBox{ChunkOffset: make([]uint32, len(s.Co64.ChunkOffset))}
+	for i, offset := range s.Co64.ChunkOffset {
+		stco.ChunkOffset[i] = uint32(offset)
+	}
+	for i, c := range s.Children {
+		if c == s.Co64 {
+			s.Children[i] = stco
+			break
+		}
+	}
+	s.Co64 = nil
+	s.Stco = stco
+}
+
+// NormalizeChunkOffsetBox - make all chunk offsets absolute by adding mdatWillStartAt, and
+// use an stco box if all offsets fit in 32 bits, and a co64 box otherwise.
+// Use mdatWillStartAt = 0 if the offsets are already absolute, and only the box type should be fixed.
+// Chunk offsets relative to the start of an mdat box, like after concatenation, are made absolute
+// with the final position of the mdat box.
+// The box is replaced in Children, so the size of stbl and its parents change accordingly.
+// This may in turn change the position of an mdat box after the moov box.
+func (s *StblBox) NormalizeChunkOffsetBox(mdatWillStartAt uint64) error {
+	var offsets []uint64
+	switch {
+	case s.Stco != nil:
+		offsets = make([]uint64, len(s.Stco.ChunkOffset))
+		for i, offset := range s.Stco.ChunkOffset {
+			offsets[i] = uint64(offset)
+		}
+	case s.Co64 != nil:
+		offsets = append([]uint64(nil), s.Co64.ChunkOffset...)
+	default:
+		return fmt.Errorf("no stco or co64 box")
+	}
+	maxOffset := uint64(0)
+	for i, offset := range offsets {
+		if offset > math.MaxUint64-mdatWillStartAt {
+			return fmt.Errorf("chunk offset %d overflows when adding %d", offset, mdatWillStartAt)
+		}
+		offsets[i] = offset + mdatWillStartAt
+		if offsets[i] > maxOffset {
+			maxOffset = offsets[i]
+		}
+	}
+	switch {
+	case s.Stco != nil && maxOffset > math.MaxUint32:
+		s.promoteStcoToCo64()
+		s.Co64.ChunkOffset = offsets
+	case s.Stco != nil:
+		for i, offset := range offsets {
+			s.Stco.ChunkOffset[i] = uint32(offset)
+		}
+	default:
+		s.Co64.ChunkOffset = offsets
+		if maxOffset <= math.MaxUint32 {
+			s.demoteCo64ToStco()
+		}
+	}
+	return nil
+}
+
+// ComputeCslg - compute a cslg box from the stts and ctts tables.
+// The least and greatest ctts offsets give compositionToDTSShift, which is the smallest shift
+// making all composition times at least as large as the decode times. Composition start and end
+// times are without edit list. Version 1 is used if a value does not fit in 32 bits.
+// The box is not added to stbl.
+func (s *StblBox) ComputeCslg() (*CslgBox, error) {
+	if s.Stts == nil || len(s.Stts.SampleCount) == 0 {
+		return nil, fmt.Errorf("no stts entries")
+	}
+	var least, greatest, startTime, maxCompTime, endTime, decTime int64
+	first := true
+	sampleNr := uint32(1)
+	cttsIdx := 0
+	for i, count := range s.Stts.SampleCount {
+		dur := int64(s.Stts.SampleTimeDelta[i])
+		for j := uint32(0); j < count; j++ {
+			var offset int64
+			if s.Ctts != nil {
+				for cttsIdx+1 < len(s.Ctts.EndSampleNr) && s.Ctts.EndSampleNr[cttsIdx+1] < sampleNr {
+					cttsIdx++
+				}
+				if cttsIdx+1 >= len(s.Ctts.EndSampleNr) {
+					return nil, fmt.Errorf("no ctts entry for sample %d", sampleNr)
+				}
+				offset = int64(s.Ctts.SampleOffset[cttsIdx])
+			}
+			compTime := decTime + offset
+			if first || offset < least {
+				least = offset
+			}
+			if first || offset > greatest {
+				greatest = offset
+			}
+			if first || compTime < startTime {
+				startTime = compTime
+			}
+			if first || compTime > maxCompTime {
+				maxCompTime = compTime
+				endTime = compTime + dur
+			}
+			first = false
+			decTime += dur
+			sampleNr++
+		}
+	}
+	if first {
+		return nil, fmt.Errorf("no samples in stts")
+	}
+	cslg := &CslgBox{
+		LeastDecodeToDisplayDelta:    least,
+		GreatestDecodeToDisplayDelta: greatest,
+		CompositionStartTime:         startTime,
+		CompositionEndTime:           endTime,
+	}
+	if least < 0 {
+		cslg.CompositionToDTSShift = -least
+	}
+	for _, v := range []int64{cslg.CompositionToDTSShift, least, greatest, startTime, endTime} {
+		if v > math.MaxInt32 || v < math.MinInt32 {
+			cslg.Version = 1
+			break
+		}
+	}
+	return cslg, nil
+}
@@ -0,0 +1,70 @@
+package mp4
+
+import (
+	"math"
+	"testing"
+
+	"github.com/go-test/deep"
+)
+
+func TestNormalizeChunkOffsetBox(t *testing.T) {
+	cases := []struct {
+		desc            string
+		chunkOffset     Box
+		mdatWillStartAt uint64
+		wantType        string
+		wantOffsets     []uint64
+	}{
+		{"stco kept", &StcoBox{ChunkOffset: []uint32{8, 1000}}, 0, "stco", []uint64{8, 1000}},
+		{"stco shifted", &StcoBox{ChunkOffset: []uint32{8, 1000}}, 2000, "stco", []uint64{2008, 3000}},
+		{"stco promoted", &StcoBox{ChunkOffset: []uint32{8, math.MaxUint32 - 100}}, 1000, "co64",
+			[]uint64{1008, math.MaxUint32 + 900}},
+		{"co64 kept", &Co64Box{ChunkOffset: []uint64{8, 1 << 33}}, 0, "co64", []uint64{8, 1 << 33}},
+		{"co64 demoted", &Co64Box{ChunkOffset: []uint64{8, 1000}}, 100, "stco", []uint64{108, 1100}},
+	}
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			stbl := &StblBox{}
+			stbl.AddChild(&StszBox{})
+			stbl.AddChild(c.chunkOffset)
+			if err := stbl.NormalizeChunkOffsetBox(c.mdatWillStartAt); err != nil {
+				t.Fatal(err)
+			}
+			if len(stbl.Children) != 2 || stbl.Children[1].Type() != c.wantType {
+				t.Fatalf("got children %v, wanted %s as second child", stbl.Children, c.wantType)
+			}
+			var gotOffsets []uint64
+			var wantSize uint64
+			switch c.wantType {
+			case "stco":
+				if stbl.Co64 != nil || stbl.Stco != stbl.Children[1] {
+					t.Fatal("stco not set correctly")
+				}
+				for _, offset := range stbl.Stco.ChunkOffset {
+					gotOffsets = append(gotOffsets, uint64(offset))
+				}
+				wantSize = stbl.Stsz.Size() + 16 + 4*uint64(len(c.wantOffsets))
+			case "co64":
+				if stbl.Stco != nil || stbl.Co64 != stbl.Children[1] {
+					t.Fatal("co64 not set correctly")
+				}
+				gotOffsets = stbl.Co64.ChunkOffset
+				wantSize = stbl.Stsz.Size() + 16 + 8*uint64(len(c.wantOffsets))
+			}
+			if diff := deep.Equal(gotOffsets, c.wantOffsets); diff != nil {
+				t.Error(diff)
+			}
+			if stbl.Size() != boxHeaderSize+wantSize {
+				t.Errorf("got stbl size %d instead of %d", stbl.Size(), boxHeaderSize+wantSize)
+			}
+		})
+	}
+	stbl := &StblBox{}
+	if err := stbl.NormalizeChunkOffsetBox(0); err == nil {
+		t.Error("no error for missing chunk offset box")
+	}
+	stbl.AddChild(&Co64Box{ChunkOffset: []uint64{math.MaxUint64 - 10}})
+	if err := stbl.NormalizeChunkOffsetBox(100); err == nil {
+		t.Error("no error for overflowing chunk offset")
+	}
+}
@@ -34,7 +34,7 @@ func NewStppBox(namespace, schemaLocation, auxiliaryMimeTypes string) *StppBox {
 	}
 }
 
-// AddChild - add a child box (avcC normally, but clap and pasp could be part of visual entry)
+// AddChild - add a child box (btrt is the only box defined, but others are kept in Children)
 func (b *StppBox) AddChild(child Box) {
 	switch box := child.(type) {
 	case *BtrtBox:
@@ -108,4 +108,30 @@ func TestStpp(t *testing.T) {
 			t.Errorf("stpp size %d not same as %d", stpp.Size(), len(data))
 		}
 	})
+	t.Run("IMSC1 entry in stsd", func(t *testing.T) {
+		stpp := NewStppBox("http://www.w3.org/ns/ttml http://www.w3.org/ns/ttml#parameter",
+			"http://www.w3.org/ns/ttml http://www.w3.org/2001/XMLSchema-instance", "image/png")
+		stpp.AddChild(&BtrtBox{BufferSizeDB: 1000, MaxBitrate: 2000, AvgBitrate: 1500})
+		stsd := NewStsdBox()
+		stsd.AddChild(stpp)
+		buf := bytes.Buffer{}
+		if err := stsd.Encode(&buf); err != nil {
+			t.Fatal(err)
+		}
+		box, err := DecodeBoxSR(0, bits.NewFixedSliceReader(buf.Bytes()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		decStpp := box.(*StsdBox).Stpp
+		if decStpp == nil || decStpp.Btrt == nil {
+			t.Fatal("stpp or btrt not set after decode")
+		}
+		if decStpp.Namespace != stpp.Namespace || decStpp.SchemaLocation != stpp.SchemaLocation ||
+			decStpp.AuxiliaryMimeTypes != stpp.AuxiliaryMimeTypes {
+			t.Errorf("got stpp %+v instead of %+v", decStpp, stpp)
+		}
+		if decStpp.Btrt.AvgBitrate != 1500 {
+			t.Errorf("btrt avgBitrate %d instead of 1500", decStpp.Btrt.AvgBitrate)
+		}
+	})
 }
@@ -0,0 +1,67 @@
+package mp4
+
+import (
+	"fmt"
+)
+
+// StreamDecryptor decrypts a stream of media segments for an init segment, as in live or DASH
+// pipelines where segments arrive one by one. The decryption info, including the default KID of
+// each track, is extracted once from the init segment and reused for all segments.
+type StreamDecryptor struct {
+	di          DecryptInfo
+	keyFor      keyFunc
+	defaultKIDs map[uint32]KID
+}
+
+// NewStreamDecryptor returns a StreamDecryptor for the tracks of init with keys given by KID.
+// As for DecryptInit, init is modified in place into a clear init segment.
+// Samples get the key of the KID in their seig sample group entry, if any, and of the default KID otherwise.
+func NewStreamDecryptor(init *InitSegment, keys map[KID][]byte) (*StreamDecryptor, error) {
+	if init == nil || init.Moov == nil {
+		return nil, fmt.Errorf("no init segment")
+	}
+	if init.Moov.Mvex == nil {
+		return nil, fmt.Errorf("init segment has no mvex box")
+	}
+	di, err := DecryptInit(init)
+	if err != nil {
+		return nil, err
+	}
+	sd := StreamDecryptor{
+		di:          di,
+		keyFor:      keysByKID(keys),
+		defaultKIDs: make(map[uint32]KID),
+	}
+	for _, ti := range di.TrackInfos {
+		if ti.Sinf == nil || ti.Sinf.Schi == nil || ti.Sinf.Schi.Tenc == nil {
+			continue
+		}
+		kid, err := NewKIDFromUUID(ti.Sinf.Schi.Tenc.DefaultKID)
+		if err != nil {
+			return nil, fmt.Errorf("track %d: %w", ti.TrackID, err)
+		}
+		sd.defaultKIDs[ti.TrackID] = kid
+	}
+	return &sd, nil
+}
+
+// DecryptInfo returns the decryption info extracted from the init segment.
+func (sd *StreamDecryptor) DecryptInfo() DecryptInfo {
+	return sd.di
+}
+
+// DefaultKID returns the default KID from the tenc box of track trackID, and false for clear tracks.
+func (sd *StreamDecryptor) DefaultKID(trackID uint32) (KID, bool) {
+	kid, ok := sd.defaultKIDs[trackID]
+	return kid, ok
+}
+
+// DecryptSegment decrypts a media segment in place.
+func (sd *StreamDecryptor) DecryptSegment(seg *MediaSegment) error {
+	return decryptSegment(seg, sd.di, sd.keyFor)
+}
+
+// DecryptFragment decrypts a fragment in place.
+func (sd *StreamDecryptor) DecryptFragment(frag *Fragment) error {
+	return decryptFragment(frag, sd.di, sd.keyFor)
+}
@@ -0,0 +1,96 @@
+package mp4
+
+import (
+	"bytes"
+	"encoding/hex"
+	"os"
+	"testing"
+)
+
+func TestStreamDecryptor(t *testing.T) {
+	key, _ := hex.DecodeString("00112233445566778899aabbccddeeff")
+	iv, _ := hex.DecodeString("7766554433221100")
+	kidUUID, _ := NewUUIDFromString("11112222333344445555666677778888")
+	kid, err := NewKIDFromUUID(kidUUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	init, err := ReadMP4File("testdata/init.mp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ipd, err := InitProtect(init.Init, key, iv, "cenc", kidUUID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rawSeg, err := os.ReadFile("testdata/1.m4s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Two encrypted copies of the segment arriving one by one
+	var encSegs [][]byte
+	for i := 0; i < 2; i++ {
+		seg, err := DecodeFile(bytes.NewBuffer(rawSeg))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, f := range seg.Segments[0].Fragments {
+			if err := EncryptFragment(f, key, iv, ipd); err != nil {
+				t.Fatal(err)
+			}
+		}
+		buf := bytes.Buffer{}
+		if err := seg.Encode(&buf); err != nil {
+			t.Fatal(err)
+		}
+		encSegs = append(encSegs, buf.Bytes())
+	}
+
+	sd, err := NewStreamDecryptor(init.Init, map[KID][]byte{kid: key})
+	if err != nil {
+		t.Fatal(err)
+	}
+	trackID := init.Init.Moov.Trak.Tkhd.TrackID
+	if gotKID, ok := sd.DefaultKID(trackID); !ok || gotKID != kid {
+		t.Errorf("got default KID %s, %t for track %d", gotKID, ok, trackID)
+	}
+	if init.Init.Moov.Trak.Mdia.Minf.Stbl.Stsd.AvcX.Type() != "avc1" {
+		t.Errorf("init segment not decrypted in place")
+	}
+	for i, encSeg := range encSegs {
+		seg, err := DecodeFile(bytes.NewBuffer(encSeg))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := sd.DecryptSegment(seg.Segments[0]); err != nil {
+			t.Fatal(err)
+		}
+		buf := bytes.Buffer{}
+		if err := seg.Encode(&buf); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(rawSeg, buf.Bytes()) {
+			t.Errorf("segment %d not equal after encryption and stream decryption", i)
+		}
+	}
+
+	// A decryptor without the key fails on the segments
+	init, err = ReadMP4File("testdata/init.mp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = InitProtect(init.Init, key, iv, "cenc", kidUUID, nil); err != nil {
+		t.Fatal(err)
+	}
+	sd, err = NewStreamDecryptor(init.Init, map[KID][]byte{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	seg, err := DecodeFile(bytes.NewBuffer(encSegs[0]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sd.DecryptSegment(seg.Segments[0]); err == nil {
+		t.Error("expected error when decrypting without key")
+	}
+}
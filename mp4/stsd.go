@@ -20,8 +20,12 @@ type StsdBox struct {
 	AvcX *VisualSampleEntryBox
 	// HvcX is a pointer to a box with name hvc1 or hev1
 	HvcX *VisualSampleEntryBox
+	// VvcX is a pointer to a box with name vvc1 or vvi1
+	VvcX *VisualSampleEntryBox
 	// Av01 is a pointer to a box with name av01
 	Av01 *VisualSampleEntryBox
+	// DvXX is a pointer to a Dolby Vision box with name dvh1, dvhe, dvav, or dva1
+	DvXX *VisualSampleEntryBox
 	// Encv is a pointer to a box with name encv
 	Encv *VisualSampleEntryBox
 	// VpXX is a pointer to a box with name vp08 or vp09 (VP8 or VP9 video)
@@ -32,12 +36,20 @@ type StsdBox struct {
 	AC3 *AudioSampleEntryBox
 	// EC3 is a pointer to a box with name ec-3
 	EC3 *AudioSampleEntryBox
+	// AC4 is a pointer to a box with name ac-4
+	AC4 *AudioSampleEntryBox
+	// Opus is a pointer to a box with name Opus
+	Opus *AudioSampleEntryBox
+	// Flac is a pointer to a box with name fLaC
+	Flac *AudioSampleEntryBox
 	// Enca is a pointer to a box with name enca
 	Enca *AudioSampleEntryBox
 	// Wvtt is a pointer to a WvttBox
 	Wvtt *WvttBox
 	// Stpp is a pointer to a StppBox
 	Stpp *StppBox
+	// Tx3g is a pointer to a Tx3gBox
+	Tx3g *Tx3gBox
 	// Evte is a pointer to an EvteBox
 	Evte     *EvteBox
 	Children []Box
@@ -55,6 +67,10 @@ func (s *StsdBox) AddChild(box Box) {
 		s.AvcX = box.(*VisualSampleEntryBox)
 	case "hvc1", "hev1":
 		s.HvcX = box.(*VisualSampleEntryBox)
+	case "vvc1", "vvi1":
+		s.VvcX = box.(*VisualSampleEntryBox)
+	case "dvh1", "dvhe", "dvav", "dva1":
+		s.DvXX = box.(*VisualSampleEntryBox)
 	case "encv":
 		s.Encv = box.(*VisualSampleEntryBox)
 	case "av01":
@@ -67,12 +83,20 @@ func (s *StsdBox) AddChild(box Box) {
 		s.AC3 = box.(*AudioSampleEntryBox)
 	case "ec-3":
 		s.EC3 = box.(*AudioSampleEntryBox)
+	case "ac-4":
+		s.AC4 = box.(*AudioSampleEntryBox)
+	case "Opus":
+		s.Opus = box.(*AudioSampleEntryBox)
+	case "fLaC":
+		s.Flac = box.(*AudioSampleEntryBox)
 	case "enca":
 		s.Enca = box.(*AudioSampleEntryBox)
 	case "wvtt":
 		s.Wvtt = box.(*WvttBox)
 	case "stpp":
 		s.Stpp = box.(*StppBox)
+	case "tx3g":
+		s.Tx3g = box.(*Tx3gBox)
 	case "evte":
 		s.Evte = box.(*EvteBox)
 	}
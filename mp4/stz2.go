@@ -0,0 +1,173 @@
+package mp4
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/Eyevinn/mp4ff/bits"
+)
+
+// Stz2Box - Compact Sample Size Box (stz2)
+//
+// Contained in : Sample Table box (stbl)
+//
+// Compact variant of stsz with sample sizes stored in 4, 8, or 16 bits.
+// For FieldSize 4, two sizes are packed per byte, and the last byte is padded
+// with zero bits if the number of samples is odd.
+type Stz2Box struct {
+	Version    byte
+	Flags      uint32
+	FieldSize  byte
+	SampleSize []uint32
+}
+
+// DecodeStz2 - box-specific decode
+func DecodeStz2(hdr BoxHeader, startPos uint64, r io.Reader) (Box, error) {
+	data, err := readBoxBody(r, hdr)
+	if err != nil {
+		return nil, err
+	}
+	sr := bits.NewFixedSliceReader(data)
+	return DecodeStz2SR(hdr, startPos, sr)
+}
+
+// DecodeStz2SR - box-specific decode
+func DecodeStz2SR(hdr BoxHeader, startPos uint64, sr bits.SliceReader) (Box, error) {
+	versionAndFlags := sr.ReadUint32()
+	b := Stz2Box{
+		Version: byte(versionAndFlags >> 24),
+		Flags:   versionAndFlags & flagsMask,
+	}
+	b.FieldSize = byte(sr.ReadUint32() & 0xff) // 24 bits reserved
+	sampleCount := sr.ReadUint32()
+	if sr.AccError() != nil {
+		return nil, sr.AccError()
+	}
+	switch b.FieldSize {
+	case 4, 8, 16:
+	default:
+		return nil, fmt.Errorf("stz2: field size %d not 4, 8, or 16", b.FieldSize)
+	}
+	if hdr.Size != b.sizeForNrSamples(uint64(sampleCount)) {
+		return nil, fmt.Errorf("stz2: expected size %d, got %d", b.sizeForNrSamples(uint64(sampleCount)), hdr.Size)
+	}
+	b.SampleSize = make([]uint32, 0, sampleCount)
+	for uint32(len(b.SampleSize)) < sampleCount {
+		switch b.FieldSize {
+		case 4:
+			p := sr.ReadUint8()
+			b.SampleSize = append(b.SampleSize, uint32(p>>4))
+			if uint32(len(b.SampleSize)) < sampleCount {
+				b.SampleSize = append(b.SampleSize, uint32(p&0x0f))
+			}
+		case 8:
+			b.SampleSize = append(b.SampleSize, uint32(sr.ReadUint8()))
+		case 16:
+			b.SampleSize = append(b.SampleSize, uint32(sr.ReadUint16()))
+		}
+	}
+	return &b, sr.AccError()
+}
+
+// Type - box-specific type
+func (b *Stz2Box) Type() string {
+	return "stz2"
+}
+
+// Size - box-specific size
+func (b *Stz2Box) Size() uint64 {
+	return b.sizeForNrSamples(uint64(len(b.SampleSize)))
+}
+
+// sizeForNrSamples - calculate size based on FieldSize and number of samples
+func (b *Stz2Box) sizeForNrSamples(nrSamples uint64) uint64 {
+	// 12 = version + flags(4) + reserved(3) + fieldSize(1) + sampleCount(4)
+	return uint64(boxHeaderSize+12) + (nrSamples*uint64(b.FieldSize)+7)/8
+}
+
+// Encode - write box to w
+func (b *Stz2Box) Encode(w io.Writer) error {
+	sw := bits.NewFixedSliceWriter(int(b.Size()))
+	err := b.EncodeSW(sw)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(sw.Bytes())
+	return err
+}
+
+// EncodeSW - box-specific encode to slicewriter
+func (b *Stz2Box) EncodeSW(sw bits.SliceWriter) error {
+	switch b.FieldSize {
+	case 4, 8, 16:
+	default:
+		return fmt.Errorf("stz2: field size %d not 4, 8, or 16", b.FieldSize)
+	}
+	maxSize := uint32(1)<<b.FieldSize - 1
+	for i, size := range b.SampleSize {
+		if size > maxSize {
+			return fmt.Errorf("stz2: sample[%d] size %d does not fit in %d bits", i+1, size, b.FieldSize)
+		}
+	}
+	err := EncodeHeaderSW(b, sw)
+	if err != nil {
+		return err
+	}
+	versionAndFlags := (uint32(b.Version) << 24) + b.Flags
+	sw.WriteUint32(versionAndFlags)
+	sw.WriteUint32(uint32(b.FieldSize))
+	sw.WriteUint32(uint32(len(b.SampleSize)))
+	for i := 0; i < len(b.SampleSize); i++ {
+		switch b.FieldSize {
+		case 4:
+			p := byte(b.SampleSize[i] << 4)
+			if i+1 < len(b.SampleSize) {
+				i++
+				p |= byte(b.SampleSize[i])
+			}
+			sw.WriteUint8(p)
+		case 8:
+			sw.WriteUint8(byte(b.SampleSize[i]))
+		case 16:
+			sw.WriteUint16(uint16(b.SampleSize[i]))
+		}
+	}
+	return sw.AccError()
+}
+
+// Info - write box-specific information
+func (b *Stz2Box) Info(w io.Writer, specificBoxLevels, indent, indentStep string) error {
+	bd := newInfoDumper(w, indent, b, int(b.Version), b.Flags)
+	bd.write(" - fieldSize: %d", b.FieldSize)
+	bd.write(" - sampleCount: %d", len(b.SampleSize))
+	level := getInfoLevel(b, specificBoxLevels)
+	if level >= 1 {
+		for i := range b.SampleSize {
+			bd.write(" - sample[%d] size=%d", i+1, b.SampleSize[i])
+		}
+	}
+	return bd.err
+}
+
+// GetNrSamples - get number of samples
+func (b *Stz2Box) GetNrSamples() uint32 {
+	return uint32(len(b.SampleSize))
+}
+
+// GetSampleSize returns the size (in bytes) of the 1-based sample i
+func (b *Stz2Box) GetSampleSize(i int) uint32 {
+	return b.SampleSize[i-1]
+}
+
+// GetTotalSampleSize - get total size of a range [startNr, endNr] of samples
+func (b *Stz2Box) GetTotalSampleSize(startNr, endNr uint32) (uint64, error) {
+	nrSamples := b.GetNrSamples()
+	if startNr <= 0 || endNr > nrSamples {
+		return 0, fmt.Errorf("startNr or calculated endNr outside range 1-%d", nrSamples)
+	}
+	size := uint64(0)
+	for nr := startNr; nr <= endNr; nr++ {
+		size += uint64(b.SampleSize[nr-1]) // 1-based numbers
+	}
+	return size, nil
+}
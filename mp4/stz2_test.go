@@ -0,0 +1,51 @@
+package mp4
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestStz2EncDec(t *testing.T) {
+	for _, stz2 := range []*Stz2Box{
+		{FieldSize: 4, SampleSize: []uint32{1, 15, 7}},
+		{FieldSize: 4, SampleSize: []uint32{3, 4}},
+		{FieldSize: 8, SampleSize: []uint32{112, 234, 120}},
+		{FieldSize: 16, SampleSize: []uint32{1120, 65535, 0}},
+	} {
+		boxDiffAfterEncodeAndDecode(t, stz2)
+	}
+}
+
+func TestStz2PackedSizes(t *testing.T) {
+	stz2 := &Stz2Box{FieldSize: 4, SampleSize: []uint32{1, 15, 7}}
+	data := encodeBox(t, stz2)
+	if len(data) != 22 {
+		t.Fatalf("got size %d instead of 22", len(data))
+	}
+	if data[20] != 0x1f || data[21] != 0x70 {
+		t.Errorf("got packed sizes %02x%02x instead of 1f70", data[20], data[21])
+	}
+	size, err := stz2.GetTotalSampleSize(2, 3)
+	if err != nil {
+		t.Error(err)
+	}
+	if size != 22 {
+		t.Errorf("got total size %d instead of 22", size)
+	}
+	if _, err := stz2.GetTotalSampleSize(1, 4); err == nil {
+		t.Error("no error for endNr outside range")
+	}
+}
+
+func TestStz2Errors(t *testing.T) {
+	stz2 := &Stz2Box{FieldSize: 8, SampleSize: []uint32{256}}
+	if err := stz2.Encode(&bytes.Buffer{}); err == nil {
+		t.Error("no error for too big sample size")
+	}
+	stz2 = &Stz2Box{FieldSize: 12, SampleSize: []uint32{1}}
+	if err := stz2.Encode(&bytes.Buffer{}); err == nil {
+		t.Error("no error for field size 12")
+	}
+	data := []byte{0, 0, 0, 21, 's', 't', 'z', '2', 0, 0, 0, 0, 0, 0, 0, 12, 0, 0, 0, 1, 0}
+	assertBoxDecodeError(t, data, 0, "decode stz2 pos 0: stz2: field size 12 not 4, 8, or 16")
+}
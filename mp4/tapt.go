@@ -0,0 +1,183 @@
+package mp4
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/Eyevinn/mp4ff/bits"
+)
+
+// TaptBox - Track Aperture Mode Dimensions Box (tapt) from the QuickTime File Format
+//
+// Contained in : Track Box (trak)
+//
+// The box has the clean aperture (clef), production aperture (prof),
+// and encoded pixels (enof) dimensions of a visual track.
+type TaptBox struct {
+	Clef     *TrackApertureDimensionsBox
+	Prof     *TrackApertureDimensionsBox
+	Enof     *TrackApertureDimensionsBox
+	Children []Box
+}
+
+// AddChild - Add a child box
+func (b *TaptBox) AddChild(child Box) {
+	if dims, ok := child.(*TrackApertureDimensionsBox); ok {
+		switch dims.Type() {
+		case "clef":
+			b.Clef = dims
+		case "prof":
+			b.Prof = dims
+		case "enof":
+			b.Enof = dims
+		}
+	}
+	b.Children = append(b.Children, child)
+}
+
+// DecodeTapt - box-specific decode
+func DecodeTapt(hdr BoxHeader, startPos uint64, r io.Reader) (Box, error) {
+	children, err := DecodeContainerChildren(hdr, startPos+8, startPos+hdr.Size, r)
+	if err != nil {
+		return nil, err
+	}
+	b := &TaptBox{}
+	for _, c := range children {
+		b.AddChild(c)
+	}
+	return b, nil
+}
+
+// DecodeTaptSR - box-specific decode
+func DecodeTaptSR(hdr BoxHeader, startPos uint64, sr bits.SliceReader) (Box, error) {
+	children, err := DecodeContainerChildrenSR(hdr, startPos+8, startPos+hdr.Size, sr)
+	if err != nil {
+		return nil, err
+	}
+	b := &TaptBox{}
+	for _, c := range children {
+		b.AddChild(c)
+	}
+	return b, sr.AccError()
+}
+
+// Type - box type
+func (b *TaptBox) Type() string {
+	return "tapt"
+}
+
+// Size - calculated size of box
+func (b *TaptBox) Size() uint64 {
+	return containerSize(b.Children)
+}
+
+// GetChildren - list of child boxes
+func (b *TaptBox) GetChildren() []Box {
+	return b.Children
+}
+
+// Encode - write tapt container to w
+func (b *TaptBox) Encode(w io.Writer) error {
+	return EncodeContainer(b, w)
+}
+
+// EncodeSW - write container using slice writer
+func (b *TaptBox) EncodeSW(sw bits.SliceWriter) error {
+	return EncodeContainerSW(b, sw)
+}
+
+// Info - write box info to w
+func (b *TaptBox) Info(w io.Writer, specificBoxLevels, indent, indentStep string) error {
+	return ContainerInfo(b, w, specificBoxLevels, indent, indentStep)
+}
+
+// TrackApertureDimensionsBox - clef, prof, or enof box in tapt from the QuickTime File Format
+//
+// The box types are Track Clean Aperture Dimensions (clef), Track Production Aperture
+// Dimensions (prof), and Track Encoded Pixels Dimensions (enof). Width and Height are
+// 16.16 fixed-point numbers like in tkhd.
+type TrackApertureDimensionsBox struct {
+	boxType string
+	Version byte
+	Flags   uint32
+	Width   Fixed32
+	Height  Fixed32
+}
+
+// NewTrackApertureDimensionsBox - create a clef, prof, or enof box with width and height in pixels
+func NewTrackApertureDimensionsBox(boxType string, width, height uint16) (*TrackApertureDimensionsBox, error) {
+	switch boxType {
+	case "clef", "prof", "enof":
+	default:
+		return nil, fmt.Errorf("box type %q is not clef, prof, or enof", boxType)
+	}
+	return &TrackApertureDimensionsBox{
+		boxType: boxType,
+		Width:   Fixed32(uint32(width) << 16),
+		Height:  Fixed32(uint32(height) << 16),
+	}, nil
+}
+
+// DecodeTrackApertureDimensions - box-specific decode of clef, prof, and enof
+func DecodeTrackApertureDimensions(hdr BoxHeader, startPos uint64, r io.Reader) (Box, error) {
+	data, err := readBoxBody(r, hdr)
+	if err != nil {
+		return nil, err
+	}
+	sr := bits.NewFixedSliceReader(data)
+	return DecodeTrackApertureDimensionsSR(hdr, startPos, sr)
+}
+
+// DecodeTrackApertureDimensionsSR - box-specific decode of clef, prof, and enof
+func DecodeTrackApertureDimensionsSR(hdr BoxHeader, startPos uint64, sr bits.SliceReader) (Box, error) {
+	versionAndFlags := sr.ReadUint32()
+	b := &TrackApertureDimensionsBox{
+		boxType: hdr.Name,
+		Version: byte(versionAndFlags >> 24),
+		Flags:   versionAndFlags & flagsMask,
+	}
+	b.Width = Fixed32(sr.ReadUint32())
+	b.Height = Fixed32(sr.ReadUint32())
+	return b, sr.AccError()
+}
+
+// Type - box type
+func (b *TrackApertureDimensionsBox) Type() string {
+	return b.boxType
+}
+
+// Size - calculated size of box
+func (b *TrackApertureDimensionsBox) Size() uint64 {
+	return uint64(boxHeaderSize + 12)
+}
+
+// Encode - write box to w
+func (b *TrackApertureDimensionsBox) Encode(w io.Writer) error {
+	sw := bits.NewFixedSliceWriter(int(b.Size()))
+	err := b.EncodeSW(sw)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(sw.Bytes())
+	return err
+}
+
+// EncodeSW - box-specific encode to slicewriter
+func (b *TrackApertureDimensionsBox) EncodeSW(sw bits.SliceWriter) error {
+	err := EncodeHeaderSW(b, sw)
+	if err != nil {
+		return err
+	}
+	versionAndFlags := (uint32(b.Version) << 24) + b.Flags
+	sw.WriteUint32(versionAndFlags)
+	sw.WriteUint32(uint32(b.Width))
+	sw.WriteUint32(uint32(b.Height))
+	return sw.AccError()
+}
+
+// Info - write box-specific information
+func (b *TrackApertureDimensionsBox) Info(w io.Writer, specificBoxLevels, indent, indentStep string) error {
+	bd := newInfoDumper(w, indent, b, int(b.Version), b.Flags)
+	bd.write(" - Width: %s, Height: %s", b.Width, b.Height)
+	return bd.err
+}
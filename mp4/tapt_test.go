@@ -0,0 +1,46 @@
+package mp4
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestTapt(t *testing.T) {
+	tapt := &TaptBox{}
+	for _, c := range []struct {
+		boxType       string
+		width, height uint16
+	}{
+		{"clef", 1440, 1080},
+		{"prof", 1920, 1080},
+		{"enof", 1440, 1080},
+	} {
+		dims, err := NewTrackApertureDimensionsBox(c.boxType, c.width, c.height)
+		if err != nil {
+			t.Fatal(err)
+		}
+		boxDiffAfterEncodeAndDecode(t, dims)
+		tapt.AddChild(dims)
+	}
+	if tapt.Clef == nil || tapt.Prof == nil || tapt.Enof == nil {
+		t.Fatal("clef, prof, or enof not set")
+	}
+	if tapt.Prof.Width.String() != "1920.0" {
+		t.Errorf("got prof width %s instead of 1920.0", tapt.Prof.Width)
+	}
+	boxDiffAfterEncodeAndDecode(t, tapt)
+
+	trak := &TrakBox{}
+	trak.AddChild(tapt)
+	decTrak := boxAfterEncodeAndDecode(t, trak).(*TrakBox)
+	if decTrak.Tapt == nil || decTrak.Tapt.Clef.Width != tapt.Clef.Width {
+		t.Error("tapt not preserved in trak")
+	}
+	var buf bytes.Buffer
+	if err := tapt.Info(&buf, "", "", "  "); err != nil {
+		t.Error(err)
+	}
+	if _, err := NewTrackApertureDimensionsBox("tapt", 1, 1); err == nil {
+		t.Error("no error for bad box type")
+	}
+}
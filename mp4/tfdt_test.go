@@ -3,6 +3,7 @@ package mp4
 import (
 	"bytes"
 	"encoding/hex"
+	"math"
 	"testing"
 )
 
@@ -75,3 +76,18 @@ func TestTfdtWriteV1(t *testing.T) {
 		t.Errorf("Encoded tfdt body not same as decoded")
 	}
 }
+
+func TestTfdtVersionSelection(t *testing.T) {
+	tfdt := CreateTfdt(math.MaxUint32)
+	if tfdt.Version != 0 {
+		t.Errorf("version %d instead of 0 for 2^32-1", tfdt.Version)
+	}
+	tfdt.SetBaseMediaDecodeTime(math.MaxUint32 + 1)
+	if tfdt.Version != 1 {
+		t.Errorf("version %d instead of 1 for 2^32", tfdt.Version)
+	}
+	boxDiffAfterEncodeAndDecode(t, tfdt)
+	if CreateTfdt(1<<40).Version != 1 {
+		t.Error("CreateTfdt did not select version 1 for large time")
+	}
+}
@@ -222,3 +222,33 @@ func (b *TfraBox) FindEntry(moofStart uint64) *TfraEntry {
 	}
 	return nil
 }
+
+// addSyncSampleEntries adds an entry for each sync sample of the track in moof starting at moofOffset.
+func (b *TfraBox) addSyncSampleEntries(moof *MoofBox, moofOffset uint64, trex *TrexBox) {
+	for i, traf := range moof.Trafs {
+		if traf.Tfhd.TrackID != b.TrackID {
+			continue
+		}
+		var decodeTime uint64
+		if traf.Tfdt != nil {
+			decodeTime = traf.Tfdt.BaseMediaDecodeTime()
+		}
+		for j, trun := range traf.Truns {
+			trun.AddSampleDefaultValues(traf.Tfhd, trex)
+			for k := range trun.Samples {
+				s := FullSample{Sample: trun.Samples[k], DecodeTime: decodeTime}
+				decodeTime += uint64(s.Dur)
+				if !s.IsSync() {
+					continue
+				}
+				b.Entries = append(b.Entries, TfraEntry{
+					Time:         s.PresentationTime(),
+					MoofOffset:   moofOffset,
+					TrafNumber:   uint32(i + 1),
+					TrunNumber:   uint32(j + 1),
+					SampleNumber: uint32(k + 1),
+				})
+			}
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package mp4
+
+import (
+	"fmt"
+)
+
+// TimelineIssueType - type of discontinuity in the decode timeline of a track
+type TimelineIssueType byte
+
+const (
+	// TimelineGap - a fragment starts after the end of the previous fragment
+	TimelineGap TimelineIssueType = iota
+	// TimelineOverlap - a fragment starts before the end of the previous fragment
+	TimelineOverlap
+)
+
+// String - name of timeline issue type
+func (t TimelineIssueType) String() string {
+	switch t {
+	case TimelineGap:
+		return "gap"
+	case TimelineOverlap:
+		return "overlap"
+	default:
+		return fmt.Sprintf("unknown(%d)", byte(t))
+	}
+}
+
+// TimelineIssue - discontinuity between a fragment and the previous fragment of the same track.
+// SegmentNr and FragmentNr are 0-based indices in File.Segments and MediaSegment.Fragments.
+// ExpectedTime is the end of the previous fragment and ActualTime is the baseMediaDecodeTime.
+type TimelineIssue struct {
+	Type           TimelineIssueType
+	SegmentNr      int
+	FragmentNr     int
+	SequenceNumber uint32
+	ExpectedTime   uint64
+	ActualTime     uint64
+}
+
+// String - description of the timeline issue
+func (ti TimelineIssue) String() string {
+	return fmt.Sprintf("%s at segment %d fragment %d (seqNr %d): tfdt %d instead of %d", ti.Type,
+		ti.SegmentNr, ti.FragmentNr, ti.SequenceNumber, ti.ActualTime, ti.ExpectedTime)
+}
+
+// timelineTraf - traf of a track with its position in the file
+type timelineTraf struct {
+	segNr, fragNr int
+	seqNr         uint32
+	traf          *TrafBox
+}
+
+// CheckTimeline returns the gaps and overlaps between the baseMediaDecodeTime of consecutive
+// fragments of track trackID and the end (baseMediaDecodeTime + total duration) of the previous one.
+// CMAF chunks are checked as separate fragments. Default sample durations are taken from
+// the init segment trex box if present. Trafs without tfdt are not checked.
+// The result is nil if there are no issues.
+func (f *File) CheckTimeline(trackID uint32) []TimelineIssue {
+	trafs := f.timelineTrafs(trackID)
+	trex := f.timelineTrex(trackID)
+	var issues []TimelineIssue
+	var prevEnd uint64
+	havePrev := false
+	for _, tt := range trafs {
+		if tt.traf.Tfdt == nil {
+			continue
+		}
+		start := tt.traf.Tfdt.BaseMediaDecodeTime()
+		if havePrev && start != prevEnd {
+			issueType := TimelineGap
+			if start < prevEnd {
+				issueType = TimelineOverlap
+			}
+			issues = append(issues, TimelineIssue{
+				Type:           issueType,
+				SegmentNr:      tt.segNr,
+				FragmentNr:     tt.fragNr,
+				SequenceNumber: tt.seqNr,
+				ExpectedTime:   prevEnd,
+				ActualTime:     start,
+			})
+		}
+		prevEnd = start + trafDuration(tt.traf, trex)
+		havePrev = true
+	}
+	return issues
+}
+
+// RebaseTimeline makes the decode timeline of all tracks monotonic by adjusting tfdt.
+// A fragment that starts before the end of the previous fragment of the same track is
+// moved to that end, and all following fragments of the track are shifted by the same amount.
+// Gaps are kept. Sidx boxes are not changed, but can be updated with UpdateSidx.
+func (f *File) RebaseTimeline() error {
+	if !f.isFragmented {
+		return fmt.Errorf("only available for fragmented files")
+	}
+	var trackIDs []uint32
+	for _, seg := range f.Segments {
+		for _, frag := range seg.Fragments {
+			if frag.Moof == nil {
+				continue
+			}
+			for _, traf := range frag.Moof.Trafs {
+				if !containsTrackID(trackIDs, traf.Tfhd.TrackID) {
+					trackIDs = append(trackIDs, traf.Tfhd.TrackID)
+				}
+			}
+		}
+	}
+	trackTrafs := make([][]timelineTraf, len(trackIDs))
+	for i, trackID := range trackIDs {
+		trackTrafs[i] = f.timelineTrafs(trackID)
+		for _, tt := range trackTrafs[i] {
+			if tt.traf.Tfdt == nil {
+				return fmt.Errorf("track %d: traf without tfdt in segment %d fragment %d", trackID, tt.segNr, tt.fragNr)
+			}
+		}
+	}
+	for i, trackID := range trackIDs {
+		trex := f.timelineTrex(trackID)
+		var shift, prevEnd uint64
+		for j, tt := range trackTrafs[i] {
+			start := tt.traf.Tfdt.BaseMediaDecodeTime() + shift
+			if j > 0 && start < prevEnd {
+				shift += prevEnd - start
+				start = prevEnd
+			}
+			tt.traf.Tfdt.SetBaseMediaDecodeTime(start)
+			prevEnd = start + trafDuration(tt.traf, trex)
+		}
+	}
+	return nil
+}
+
+// timelineTrafs returns the trafs of track trackID in fragments and chunks in file order.
+func (f *File) timelineTrafs(trackID uint32) []timelineTraf {
+	var trafs []timelineTraf
+	for segNr, seg := range f.Segments {
+		for fragNr, frag := range seg.Fragments {
+			if frag.Moof == nil {
+				continue
+			}
+			moofs := []*MoofBox{frag.Moof}
+			for _, c := range frag.Chunks {
+				moofs = append(moofs, c.Moof)
+			}
+			for _, moof := range moofs {
+				for _, traf := range moof.Trafs {
+					if traf.Tfhd.TrackID != trackID {
+						continue
+					}
+					trafs = append(trafs, timelineTraf{segNr, fragNr, moof.Mfhd.SequenceNumber, traf})
+				}
+			}
+		}
+	}
+	return trafs
+}
+
+// timelineTrex returns the trex box of trackID, or an empty trex box if not available.
+func (f *File) timelineTrex(trackID uint32) *TrexBox {
+	if f.Init != nil && f.Init.Moov.Mvex != nil {
+		if trex, ok := f.Init.Moov.Mvex.GetTrex(trackID); ok {
+			return trex
+		}
+	}
+	return &TrexBox{TrackID: trackID}
+}
@@ -0,0 +1,48 @@
+package mp4
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/go-test/deep"
+)
+
+func TestCheckAndRebaseTimeline(t *testing.T) {
+	init := CreateEmptyInit()
+	init.AddEmptyTrack(1000, "audio", "und")
+	buf := bytes.Buffer{}
+	if err := init.Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	seg := NewMediaSegment()
+	seg.AddFragment(createTestFragment(t, 1, 0, 3, true))
+	seg.AddFragment(createTestFragment(t, 2, 3000, 2, true))
+	seg.AddFragment(createTestFragment(t, 3, 4000, 2, true)) // Overlap of 1000
+	seg.AddFragment(createTestFragment(t, 4, 8000, 2, true)) // Gap of 2000
+	if err := seg.Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	f, err := DecodeFile(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wanted := []TimelineIssue{
+		{Type: TimelineOverlap, SegmentNr: 0, FragmentNr: 2, SequenceNumber: 3, ExpectedTime: 5000, ActualTime: 4000},
+		{Type: TimelineGap, SegmentNr: 0, FragmentNr: 3, SequenceNumber: 4, ExpectedTime: 6000, ActualTime: 8000},
+	}
+	if diff := deep.Equal(f.CheckTimeline(1), wanted); diff != nil {
+		t.Error(diff)
+	}
+	if err = f.RebaseTimeline(); err != nil {
+		t.Fatal(err)
+	}
+	wanted = []TimelineIssue{
+		{Type: TimelineGap, SegmentNr: 0, FragmentNr: 3, SequenceNumber: 4, ExpectedTime: 7000, ActualTime: 9000},
+	}
+	if diff := deep.Equal(f.CheckTimeline(1), wanted); diff != nil {
+		t.Error(diff)
+	}
+	if issues := f.CheckTimeline(2); issues != nil {
+		t.Errorf("got issues %v for track without fragments", issues)
+	}
+}
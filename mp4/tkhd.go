@@ -1,7 +1,9 @@
 package mp4
 
 import (
+	"fmt"
 	"io"
+	"math"
 
 	"github.com/Eyevinn/mp4ff/bits"
 )
@@ -82,6 +84,15 @@ func DecodeTkhdSR(hdr BoxHeader, startPos uint64, sr bits.SliceReader) (Box, err
 	return &t, sr.AccError()
 }
 
+// SetDuration sets the track duration and changes the box to version 1 if needed,
+// so that durations above 2^32-1 are not truncated.
+func (b *TkhdBox) SetDuration(duration uint64) {
+	b.Duration = duration
+	if duration > math.MaxUint32 {
+		b.Version = 1
+	}
+}
+
 // Type - box type
 func (b *TkhdBox) Type() string {
 	return "tkhd"
@@ -108,6 +119,9 @@ func (b *TkhdBox) Encode(w io.Writer) error {
 
 // EncodeSW - box-specific encode to slicewriter
 func (b *TkhdBox) EncodeSW(sw bits.SliceWriter) error {
+	if b.Version == 0 && b.Duration > math.MaxUint32 {
+		return fmt.Errorf("duration %d too large for version 0", b.Duration)
+	}
 	err := EncodeHeaderSW(b, sw)
 	if err != nil {
 		return err
@@ -32,3 +32,16 @@ func TestTkhd(t *testing.T) {
 		t.Errorf("Mismatch mvhdCreated vs mvhdRead:\n%+v\n%+v", tkhdCreated, tkhdRead)
 	}
 }
+
+func TestTkhdSetDuration(t *testing.T) {
+	tkhd := CreateTkhd()
+	tkhd.SetDuration(1 << 33)
+	if tkhd.Version != 1 {
+		t.Errorf("version %d instead of 1 for large duration", tkhd.Version)
+	}
+	boxDiffAfterEncodeAndDecode(t, tkhd)
+	tkhd.Version = 0
+	if err := tkhd.Encode(&bytes.Buffer{}); err == nil {
+		t.Error("no error when encoding large duration with version 0")
+	}
+}
@@ -16,8 +16,10 @@ type TrafBox struct {
 	Tfdt     *TfdtBox
 	Saiz     *SaizBox
 	Saio     *SaioBox
-	Sbgp     *SbgpBox
-	Sgpd     *SgpdBox
+	Sbgp     *SbgpBox   // The first
+	Sbgps    []*SbgpBox // All
+	Sgpd     *SgpdBox   // The first
+	Sgpds    []*SgpdBox // All
 	Senc     *SencBox
 	UUIDSenc *UUIDBox // A PIFF box of subtype senc
 	Trun     *TrunBox // The first TrunBox
@@ -94,28 +96,30 @@ func (t *TrafBox) ParseReadSenc(defaultIVSize byte, moofStartPos uint64) error {
 
 		}
 	}
-	perSampleIVSize := defaultIVSize
-	sbgp, sgpd := t.Sbgp, t.Sgpd
-	if sbgp != nil && sbgp.GroupingType == "seig" && sgpd != nil && sgpd.GroupingType == "seig" {
-		nrSbgpEntries := len(sbgp.SampleCounts)
-		if nrSbgpEntries != 1 {
-			return fmt.Errorf("sbgp entries = %d, only 1 supported for now", nrSbgpEntries)
-		}
-		sgpdEntryNr := sbgp.GroupDescriptionIndices[0]
-		if sgpdEntryNr != sbgpInsideOffset+1 {
-			return fmt.Errorf("sgpd entry number must be first inside = 65536 + 1")
-		}
-		sgpdEntry := sgpd.SampleGroupEntries[sgpdEntryNr-sbgpInsideOffset-1]
-		seigEntry := sgpdEntry.(*SeigSampleGroupEntry)
-		perSampleIVSize = seigEntry.PerSampleIVSize
+	perSampleIVSize, err := t.PerSampleIVSize(defaultIVSize)
+	if err != nil {
+		return err
 	}
-	err := senc.ParseReadBox(perSampleIVSize, t.Saiz)
+	err = senc.ParseReadBox(perSampleIVSize, t.Saiz)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
+// PerSampleIVSize returns the per-sample IV size of the protected samples, given by the seig
+// sample group entries if present, and defaultIVSize otherwise. Sample groups described in the
+// sample table are assumed to have defaultIVSize. Unprotected samples are ignored, and all
+// protected samples must have the same per-sample IV size.
+func (t *TrafBox) PerSampleIVSize(defaultIVSize byte) (byte, error) {
+	tenc := &TencBox{DefaultIsProtected: 1, DefaultPerSampleIVSize: defaultIVSize}
+	tencs, err := sampleTencs(t, tenc, nil)
+	if err != nil {
+		return 0, err
+	}
+	return commonPerSampleIVSize(tencs, defaultIVSize)
+}
+
 // AddChild - add child box
 func (t *TrafBox) AddChild(child Box) error {
 	switch box := child.(type) {
@@ -128,9 +132,15 @@ func (t *TrafBox) AddChild(child Box) error {
 	case *SaioBox:
 		t.Saio = box
 	case *SbgpBox:
-		t.Sbgp = box
+		if t.Sbgp == nil {
+			t.Sbgp = box
+		}
+		t.Sbgps = append(t.Sbgps, box)
 	case *SgpdBox:
-		t.Sgpd = box
+		if t.Sgpd == nil {
+			t.Sgpd = box
+		}
+		t.Sgpds = append(t.Sgpds, box)
 	case *SencBox:
 		t.Senc = box
 	case *TrunBox:
@@ -148,6 +158,34 @@ func (t *TrafBox) AddChild(child Box) error {
 	return nil
 }
 
+// dataEnd returns the position after the data of all truns given the implicit base offset.
+// ok is false if the sample sizes cannot be determined without trex defaults.
+func (t *TrafBox) dataEnd(implicitBase, moofStartPos uint64) (end uint64, ok bool) {
+	tfhd := t.Tfhd
+	baseOffset := implicitBase
+	switch {
+	case tfhd.HasBaseDataOffset():
+		baseOffset = tfhd.BaseDataOffset
+	case tfhd.DefaultBaseIfMoof():
+		baseOffset = moofStartPos
+	}
+	pos := baseOffset
+	for _, trun := range t.Truns {
+		if trun.HasDataOffset() {
+			pos = uint64(int64(trun.DataOffset) + int64(baseOffset))
+		}
+		switch {
+		case trun.HasSampleSize():
+			pos += trun.SizeOfData()
+		case tfhd.HasDefaultSampleSize():
+			pos += uint64(tfhd.DefaultSampleSize) * uint64(trun.SampleCount())
+		default:
+			return 0, false
+		}
+	}
+	return pos, true
+}
+
 // Type - return box type
 func (t *TrafBox) Type() string {
 	return "traf"
@@ -178,6 +216,28 @@ func (t *TrafBox) Info(w io.Writer, specificBoxLevels, indent, indentStep string
 	return ContainerInfo(t, w, specificBoxLevels, indent, indentStep)
 }
 
+// GetTfxd returns the data of an MSS tfxd uuid box with the absolute time and duration
+// of the fragment, or nil if not present.
+func (t *TrafBox) GetTfxd() *TfxdData {
+	for _, c := range t.Children {
+		if u, ok := c.(*UUIDBox); ok && u.Tfxd != nil {
+			return u.Tfxd
+		}
+	}
+	return nil
+}
+
+// GetTfrf returns the data of an MSS tfrf uuid box with the absolute times and durations
+// of following fragments, or nil if not present.
+func (t *TrafBox) GetTfrf() *TfrfData {
+	for _, c := range t.Children {
+		if u, ok := c.(*UUIDBox); ok && u.Tfrf != nil {
+			return u.Tfrf
+		}
+	}
+	return nil
+}
+
 // OptimizeTfhdTrun - optimize trun by default values in tfhd box
 // Only look at first trun, even if there is more than one
 // Don't optimize again, if already done so that no data is present
@@ -262,7 +322,188 @@ func (t *TrafBox) OptimizeTfhdTrun() error {
 	return nil
 }
 
-// RemoveEncryptionBoxes - remove encryption boxes and return number of bytes removed
+// OptimizeTruns - set sample durations, sizes, and flags that are common to all samples in the truns
+// as default values in tfhd and remove them from the truns. This works on all truns, in contrast to
+// OptimizeTfhdTrun. Values that differ between samples are written in the truns instead, and flags
+// that only differ for the first sample of a trun are written as first sample flags.
+// Composition time offsets are removed from truns where they are all zero.
+// Values that are given neither in the truns nor in tfhd come from trex and are left unchanged.
+// The sample values are checked to be the same before and after the optimization.
+func (t *TrafBox) OptimizeTruns() error {
+	if len(t.Truns) == 0 {
+		return errors.New("no trun in traf")
+	}
+	before := t.sampleValues()
+	if len(before.durs) == 0 {
+		return nil
+	}
+	tfhd := t.Tfhd
+	if before.dursKnown {
+		common := allEqual(before.durs)
+		tfhd.Flags &^= defaultSampleDurationPresent
+		tfhd.DefaultSampleDuration = 0
+		if common {
+			tfhd.Flags |= defaultSampleDurationPresent
+			tfhd.DefaultSampleDuration = before.durs[0]
+		}
+		nr := 0
+		for _, trun := range t.Truns {
+			trun.Flags &^= TrunSampleDurationPresentFlag
+			if !common {
+				trun.Flags |= TrunSampleDurationPresentFlag
+			}
+			for i := range trun.Samples {
+				trun.Samples[i].Dur = before.durs[nr]
+				nr++
+			}
+		}
+	}
+	if before.sizesKnown {
+		common := allEqual(before.sizes)
+		tfhd.Flags &^= defaultSampleSizePresent
+		tfhd.DefaultSampleSize = 0
+		if common {
+			tfhd.Flags |= defaultSampleSizePresent
+			tfhd.DefaultSampleSize = before.sizes[0]
+		}
+		nr := 0
+		for _, trun := range t.Truns {
+			trun.Flags &^= TrunSampleSizePresentFlag
+			if !common {
+				trun.Flags |= TrunSampleSizePresentFlag
+			}
+			for i := range trun.Samples {
+				trun.Samples[i].Size = before.sizes[nr]
+				nr++
+			}
+		}
+	}
+	if before.flagsKnown {
+		// Flags of samples that are not first in their trun must be the same to use a default
+		var otherFlags []uint32
+		nr := 0
+		for _, trun := range t.Truns {
+			if len(trun.Samples) > 1 {
+				otherFlags = append(otherFlags, before.flags[nr+1:nr+len(trun.Samples)]...)
+			}
+			nr += len(trun.Samples)
+		}
+		common := allEqual(otherFlags)
+		defaultFlags := before.flags[0]
+		if len(otherFlags) > 0 {
+			defaultFlags = otherFlags[0]
+		}
+		tfhd.Flags &^= defaultSampleFlagsPresent
+		tfhd.DefaultSampleFlags = 0
+		if common {
+			tfhd.Flags |= defaultSampleFlagsPresent
+			tfhd.DefaultSampleFlags = defaultFlags
+		}
+		nr = 0
+		for _, trun := range t.Truns {
+			trun.Flags &^= TrunSampleFlagsPresentFlag
+			trun.RemoveFirstSampleFlags()
+			switch {
+			case !common:
+				trun.Flags |= TrunSampleFlagsPresentFlag
+			case len(trun.Samples) > 0 && before.flags[nr] != defaultFlags:
+				trun.SetFirstSampleFlags(before.flags[nr])
+			}
+			for i := range trun.Samples {
+				trun.Samples[i].Flags = before.flags[nr]
+				nr++
+			}
+		}
+	}
+	for _, trun := range t.Truns {
+		if !trun.HasSampleCompositionTimeOffset() {
+			continue
+		}
+		allZeroCTO := true
+		for _, s := range trun.Samples {
+			if s.CompositionTimeOffset != 0 {
+				allZeroCTO = false
+				break
+			}
+		}
+		if allZeroCTO {
+			trun.Flags &^= TrunSampleCompositionTimeOffsetPresentFlag
+		}
+	}
+	after := t.sampleValues()
+	if !before.sameAs(after) {
+		return errors.New("sample values changed by trun optimization")
+	}
+	return nil
+}
+
+// trafSampleValues - sample durations, sizes, and flags of a traf as given by truns and tfhd.
+// A known indicator is false if some values are not available, since they come from trex.
+type trafSampleValues struct {
+	durs, sizes, flags                []uint32
+	dursKnown, sizesKnown, flagsKnown bool
+}
+
+// sampleValues - values of all samples in the truns with the tfhd default values applied
+func (t *TrafBox) sampleValues() trafSampleValues {
+	v := trafSampleValues{dursKnown: true, sizesKnown: true, flagsKnown: true}
+	tfhd := t.Tfhd
+	for _, trun := range t.Truns {
+		firstFlags, hasFirstFlags := trun.FirstSampleFlags()
+		for i, s := range trun.Samples {
+			switch {
+			case trun.HasSampleDuration():
+				v.durs = append(v.durs, s.Dur)
+			case tfhd.HasDefaultSampleDuration():
+				v.durs = append(v.durs, tfhd.DefaultSampleDuration)
+			default:
+				v.durs = append(v.durs, 0)
+				v.dursKnown = false
+			}
+			switch {
+			case trun.HasSampleSize():
+				v.sizes = append(v.sizes, s.Size)
+			case tfhd.HasDefaultSampleSize():
+				v.sizes = append(v.sizes, tfhd.DefaultSampleSize)
+			default:
+				v.sizes = append(v.sizes, 0)
+				v.sizesKnown = false
+			}
+			switch {
+			case trun.HasSampleFlags():
+				v.flags = append(v.flags, s.Flags)
+			case i == 0 && hasFirstFlags:
+				v.flags = append(v.flags, firstFlags)
+			case tfhd.HasDefaultSampleFlags():
+				v.flags = append(v.flags, tfhd.DefaultSampleFlags)
+			default:
+				v.flags = append(v.flags, 0)
+				v.flagsKnown = false
+			}
+		}
+	}
+	return v
+}
+
+// sameAs - true if all values known in v are known and equal in o
+func (v trafSampleValues) sameAs(o trafSampleValues) bool {
+	equal := func(a, b []uint32) bool {
+		if len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if a[i] != b[i] {
+				return false
+			}
+		}
+		return true
+	}
+	return (!v.dursKnown || (o.dursKnown && equal(v.durs, o.durs))) &&
+		(!v.sizesKnown || (o.sizesKnown && equal(v.sizes, o.sizes))) &&
+		(!v.flagsKnown || (o.flagsKnown && equal(v.flags, o.flags)))
+}
+
+// RemoveEncryptionBoxes - remove encryption boxes, including seig sample groups, and return number of bytes removed
 func (t *TrafBox) RemoveEncryptionBoxes() uint64 {
 	remainingChildren := make([]Box, 0, len(t.Children))
 	var nrBytesRemoved uint64 = 0
@@ -282,10 +523,37 @@ func (t *TrafBox) RemoveEncryptionBoxes() uint64 {
 				nrBytesRemoved += ch.Size()
 				t.UUIDSenc = nil
 			}
+		case *SbgpBox:
+			if box.GroupingType != "seig" {
+				remainingChildren = append(remainingChildren, ch)
+				continue
+			}
+			nrBytesRemoved += ch.Size()
+		case *SgpdBox:
+			if box.GroupingType != "seig" {
+				remainingChildren = append(remainingChildren, ch)
+				continue
+			}
+			nrBytesRemoved += ch.Size()
 		default:
 			remainingChildren = append(remainingChildren, ch)
 		}
 	}
 	t.Children = remainingChildren
+	t.Sbgp, t.Sbgps, t.Sgpd, t.Sgpds = nil, nil, nil, nil
+	for _, ch := range remainingChildren {
+		switch box := ch.(type) {
+		case *SbgpBox:
+			if t.Sbgp == nil {
+				t.Sbgp = box
+			}
+			t.Sbgps = append(t.Sbgps, box)
+		case *SgpdBox:
+			if t.Sgpd == nil {
+				t.Sgpd = box
+			}
+			t.Sgpds = append(t.Sgpds, box)
+		}
+	}
 	return nrBytesRemoved
 }
@@ -105,3 +105,132 @@ func runEncodeDecode(t *testing.T, test testSamples, withOptimization bool) {
 			test.name, withOptimization, outSamples, test.samples)
 	}
 }
+
+func TestTrafOptimizeTruns(t *testing.T) {
+	tests := []struct {
+		name       string
+		truns      [][]Sample
+		tfhdFlags  uint32
+		firstFlags bool
+	}{
+		{
+			name: "audio",
+			truns: [][]Sample{
+				{{SyncSampleFlags, 1024, 234, 0}, {SyncSampleFlags, 1024, 236, 0}},
+				{{SyncSampleFlags, 1024, 230, 0}},
+			},
+			tfhdFlags: defaultSampleDurationPresent | defaultSampleFlagsPresent,
+		},
+		{
+			name: "video",
+			truns: [][]Sample{
+				{{SyncSampleFlags, 512, 3000, 1024}, {NonSyncSampleFlags, 512, 300, 0}},
+				{{SyncSampleFlags, 512, 2000, 1024}, {NonSyncSampleFlags, 512, 200, 0}},
+			},
+			tfhdFlags:  defaultSampleDurationPresent | defaultSampleFlagsPresent,
+			firstFlags: true,
+		},
+		{
+			name: "varying",
+			truns: [][]Sample{
+				{{SyncSampleFlags, 1000, 20, 0}, {NonSyncSampleFlags, 1001, 20, 0}},
+				{{NonSyncSampleFlags, 1000, 20, 0}, {SyncSampleFlags, 1001, 20, 0}},
+			},
+			tfhdFlags: defaultSampleSizePresent,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			traf := &TrafBox{}
+			_ = traf.AddChild(&TfhdBox{})
+			var wanted []Sample
+			for _, samples := range tc.truns {
+				trun := CreateTrun(0)
+				trun.DataOffset = 100 // Needs to be set. Value not important in test
+				for _, s := range samples {
+					trun.AddSample(s)
+				}
+				_ = traf.AddChild(trun)
+				wanted = append(wanted, samples...)
+			}
+			sizeBefore := traf.Size()
+			if err := traf.OptimizeTruns(); err != nil {
+				t.Fatal(err)
+			}
+			if traf.Size() >= sizeBefore {
+				t.Errorf("size %d not smaller than %d", traf.Size(), sizeBefore)
+			}
+			if traf.Tfhd.Flags != tc.tfhdFlags {
+				t.Errorf("tfhd flags %06x instead of %06x", traf.Tfhd.Flags, tc.tfhdFlags)
+			}
+			if got := traf.Trun.HasFirstSampleFlags(); got != tc.firstFlags {
+				t.Errorf("first sample flags present %t", got)
+			}
+			var buf bytes.Buffer
+			if err := traf.Encode(&buf); err != nil {
+				t.Fatal(err)
+			}
+			box, err := DecodeBox(0, &buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			outTraf := box.(*TrafBox)
+			var got []Sample
+			for _, trun := range outTraf.Truns {
+				trun.AddSampleDefaultValues(outTraf.Tfhd, &TrexBox{})
+				got = append(got, trun.Samples...)
+			}
+			if !reflect.DeepEqual(got, wanted) {
+				t.Errorf("got %v instead of %v", got, wanted)
+			}
+		})
+	}
+}
+
+func TestTrafPerSampleIVSize(t *testing.T) {
+	clearEntry := &SeigSampleGroupEntry{IsProtected: 0, PerSampleIVSize: 0}
+	prot8 := &SeigSampleGroupEntry{IsProtected: 1, PerSampleIVSize: 8}
+	prot16 := &SeigSampleGroupEntry{IsProtected: 1, PerSampleIVSize: 16}
+	testCases := []struct {
+		desc    string
+		entries []SampleGroupEntry
+		wanted  byte
+		wantErr bool
+	}{
+		{desc: "clear lead", entries: []SampleGroupEntry{clearEntry, prot8}, wanted: 8},
+		{desc: "all clear", entries: []SampleGroupEntry{clearEntry, clearEntry}, wanted: 0},
+		{desc: "varying protected", entries: []SampleGroupEntry{prot8, prot16}, wantErr: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			traf := createTestTrafBox()
+			for i := 0; i < 4; i++ {
+				traf.Trun.AddSample(Sample{Flags: SyncSampleFlags, Dur: 1024, Size: 100})
+			}
+			_ = traf.AddChild(&SbgpBox{
+				GroupingType:            "seig",
+				SampleCounts:            []uint32{2, 2},
+				GroupDescriptionIndices: []uint32{sbgpInsideOffset + 1, sbgpInsideOffset + 2},
+			})
+			_ = traf.AddChild(&SgpdBox{
+				Version:            1,
+				GroupingType:       "seig",
+				DefaultLength:      20,
+				SampleGroupEntries: tc.entries,
+			})
+			ivSize, err := traf.PerSampleIVSize(16)
+			if tc.wantErr {
+				if err == nil {
+					t.Error("no error for varying per-sample IV sizes")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ivSize != tc.wanted {
+				t.Errorf("got per-sample IV size %d instead of %d", ivSize, tc.wanted)
+			}
+		})
+	}
+}
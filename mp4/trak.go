@@ -3,6 +3,7 @@ package mp4
 import (
 	"fmt"
 	"io"
+	"math"
 
 	"github.com/Eyevinn/mp4ff/bits"
 )
@@ -17,8 +18,11 @@ const DefaultTrakID = 1
 // A media file can contain one or more tracks.
 type TrakBox struct {
 	Tkhd     *TkhdBox
+	Tref     *TrefBox
 	Edts     *EdtsBox
 	Mdia     *MdiaBox
+	Udta     *UdtaBox
+	Tapt     *TaptBox
 	Children []Box
 }
 
@@ -34,12 +38,117 @@ func (t *TrakBox) AddChild(child Box) {
 		t.Tkhd = box
 	case *MdiaBox:
 		t.Mdia = box
+	case *TrefBox:
+		t.Tref = box
 	case *EdtsBox:
 		t.Edts = box
+	case *UdtaBox:
+		t.Udta = box
+	case *TaptBox:
+		t.Tapt = box
 	}
 	t.Children = append(t.Children, child)
 }
 
+// AddKind - add a kind box with schemeURI and value to the udta box of the track.
+// A udta box is created if not present. Multiple kind boxes can be added,
+// for example "urn:mpeg:dash:role:2011" with value "main" or "subtitle".
+func (t *TrakBox) AddKind(schemeURI, value string) {
+	if t.Udta == nil {
+		t.AddChild(&UdtaBox{})
+	}
+	t.Udta.AddChild(&KindBox{SchemeURI: schemeURI, Value: value})
+}
+
+// GetKinds - get all kind boxes in the udta box of the track
+func (t *TrakBox) GetKinds() []*KindBox {
+	if t.Udta == nil {
+		return nil
+	}
+	var kinds []*KindBox
+	for _, c := range t.Udta.Children {
+		if kind, ok := c.(*KindBox); ok {
+			kinds = append(kinds, kind)
+		}
+	}
+	return kinds
+}
+
+// AddTrackReference - add references of type refType (e.g. cdsc, subt, or chap) to trackIDs.
+// A tref box is inserted after tkhd if not present. IDs are appended to an existing
+// reference box of the same type, skipping IDs that are already referenced.
+func (t *TrakBox) AddTrackReference(refType string, trackIDs []uint32) error {
+	if len(refType) != 4 {
+		return fmt.Errorf("track reference type %q is not four characters", refType)
+	}
+	if len(trackIDs) == 0 {
+		return fmt.Errorf("no trackIDs for track reference %q", refType)
+	}
+	for _, trackID := range trackIDs {
+		if trackID == 0 {
+			return fmt.Errorf("trackID 0 not allowed in track reference %q", refType)
+		}
+	}
+	if t.Tref == nil {
+		insertPos := 0
+		for i, c := range t.Children {
+			if c.Type() == "tkhd" {
+				insertPos = i + 1
+				break
+			}
+		}
+		tref := &TrefBox{}
+		t.Children = append(t.Children, nil)
+		copy(t.Children[insertPos+1:], t.Children[insertPos:])
+		t.Children[insertPos] = tref
+		t.Tref = tref
+	}
+	for _, c := range t.Tref.Children {
+		ref, ok := c.(*TrefTypeBox)
+		if !ok || ref.Name != refType {
+			continue
+		}
+		for _, trackID := range trackIDs {
+			if !containsTrackID(ref.TrackIDs, trackID) {
+				ref.TrackIDs = append(ref.TrackIDs, trackID)
+			}
+		}
+		return nil
+	}
+	ids := make([]uint32, 0, len(trackIDs))
+	for _, trackID := range trackIDs {
+		if !containsTrackID(ids, trackID) {
+			ids = append(ids, trackID)
+		}
+	}
+	t.Tref.AddChild(&TrefTypeBox{Name: refType, TrackIDs: ids})
+	return nil
+}
+
+// GetTrackReferences - get the trackIDs referenced with type refType, or nil if none.
+func (t *TrakBox) GetTrackReferences(refType string) []uint32 {
+	if t.Tref == nil {
+		return nil
+	}
+	var trackIDs []uint32
+	for _, c := range t.Tref.Children {
+		if ref, ok := c.(*TrefTypeBox); ok && ref.Name == refType {
+			trackIDs = append(trackIDs, ref.TrackIDs...)
+		}
+	}
+	return trackIDs
+}
+
+// containsTrackID - true if trackID is in trackIDs
+func containsTrackID(trackIDs []uint32, trackID uint32) bool {
+	for _, id := range trackIDs {
+		if id == trackID {
+			return true
+		}
+	}
+	return false
+}
+
 // DecodeTrak - box-specific decode
 func DecodeTrak(hdr BoxHeader, startPos uint64, r io.Reader) (Box, error) {
 	children, err := DecodeContainerChildren(hdr, startPos+8, startPos+hdr.Size, r)
@@ -96,17 +205,187 @@ func (t *TrakBox) Info(w io.Writer, specificBoxLevels, indent, indentStep string
 	return ContainerInfo(t, w, specificBoxLevels, indent, indentStep)
 }
 
+// SetEditList - set the edit list of the track to one elst box with entries.
+// An existing edts box is replaced, and a new one is otherwise inserted after tkhd.
+// The elst version is 1 if any value does not fit in 32 bits, and 0 otherwise.
+func (t *TrakBox) SetEditList(entries []ElstEntry) {
+	var version byte
+	for _, e := range entries {
+		if e.SegmentDuration > math.MaxUint32 || e.MediaTime > math.MaxInt32 || e.MediaTime < math.MinInt32 {
+			version = 1
+			break
+		}
+	}
+	edts := &EdtsBox{}
+	edts.AddChild(&ElstBox{Version: version, Entries: entries})
+	for i, c := range t.Children {
+		if c == t.Edts && t.Edts != nil {
+			t.Children[i] = edts
+			t.Edts = edts
+			return
+		}
+	}
+	insertPos := 0
+	for i, c := range t.Children {
+		if c.Type() == "tkhd" {
+			insertPos = i + 1
+			break
+		}
+	}
+	t.Children = append(t.Children, nil)
+	copy(t.Children[insertPos+1:], t.Children[insertPos:])
+	t.Children[insertPos] = edts
+	t.Edts = edts
+}
+
+// SetEncoderDelay - set an edit list that skips the first samples of audio priming (encoder delay)
+// given in units of timescale, typically the sample rate. The media_time of the edit is the delay
+// in the mdhd timescale, and the segment_duration is the remaining media duration converted to the
+// movie timescale by the ratio of tkhd and mdhd durations. tkhd duration is set to the segment
+// duration. For fragmented files, where the durations are 0, the segment_duration is 0 which means
+// the rest of the media.
+func (t *TrakBox) SetEncoderDelay(samples uint32, timescale uint32) error {
+	if timescale == 0 {
+		return fmt.Errorf("timescale is 0")
+	}
+	mdhd := t.Mdia.Mdhd
+	if mdhd.Timescale == 0 {
+		return fmt.Errorf("mdhd timescale is 0")
+	}
+	mediaTime := uint64(samples) * uint64(mdhd.Timescale) / uint64(timescale)
+	var segmentDuration uint64
+	if mdhd.Duration > 0 {
+		if mediaTime >= mdhd.Duration {
+			return fmt.Errorf("encoder delay %d not less than media duration %d", mediaTime, mdhd.Duration)
+		}
+		segmentDuration = (mdhd.Duration - mediaTime) * t.Tkhd.Duration / mdhd.Duration
+		t.Tkhd.SetDuration(segmentDuration)
+	}
+	t.SetEditList([]ElstEntry{{SegmentDuration: segmentDuration, MediaTime: int64(mediaTime), MediaRateInteger: 1}})
+	return nil
+}
+
+// GetEditList - get all edit list entries of the track independent of elst version.
+// Returns nil if there is no edit list.
+func (t *TrakBox) GetEditList() []ElstEntry {
+	if t.Edts == nil {
+		return nil
+	}
+	var entries []ElstEntry
+	for _, elst := range t.Edts.Elst {
+		entries = append(entries, elst.Entries...)
+	}
+	return entries
+}
+
 // GetNrSamples - get number of samples for this track defined in the parent moov box.
+// The sizes may be given by stsz or stz2.
 func (t *TrakBox) GetNrSamples() uint32 {
 	stbl := t.Mdia.Minf.Stbl
-	return stbl.Stsz.GetNrSamples()
+	if sizes := stbl.sampleSizes(); sizes != nil {
+		return sizes.GetNrSamples()
+	}
+	return 0
+}
+
+// SampleSizes - get the sizes of all samples of the track from stsz, including the case of
+// a uniform sample size, or from stz2.
+func (t *TrakBox) SampleSizes() []uint32 {
+	stbl := t.Mdia.Minf.Stbl
+	if stbl.Stsz == nil && stbl.Stz2 != nil {
+		sizes := make([]uint32, len(stbl.Stz2.SampleSize))
+		copy(sizes, stbl.Stz2.SampleSize)
+		return sizes
+	}
+	stsz := stbl.Stsz
+	nrSamples := stsz.GetNrSamples()
+	sizes := make([]uint32, nrSamples)
+	if stsz.SampleUniformSize != 0 || len(stsz.SampleSize) == 0 {
+		for i := range sizes {
+			sizes[i] = stsz.SampleUniformSize
+		}
+		return sizes
+	}
+	copy(sizes, stsz.SampleSize)
+	return sizes
+}
+
+// SampleDurations - get the durations of all samples of the track by expanding the stts entries.
+func (t *TrakBox) SampleDurations() []uint32 {
+	stts := t.Mdia.Minf.Stbl.Stts
+	var nrSamples uint64
+	for _, count := range stts.SampleCount {
+		nrSamples += uint64(count)
+	}
+	durations := make([]uint32, 0, nrSamples)
+	for i, count := range stts.SampleCount {
+		delta := stts.SampleTimeDelta[i]
+		for j := uint32(0); j < count; j++ {
+			durations = append(durations, delta)
+		}
+	}
+	return durations
+}
+
+// ComputeBitrate - compute bitrates in bits per second from the sample sizes and durations in stbl.
+// avgBitrate is based on the total size and duration, and maxBitrate is the largest bitrate
+// of any one-second window starting at a sample. bufferSizeDB is the largest sample size in bytes.
+// The values can be used for a btrt box, e.g. via VisualSampleEntryBox.SetBitrate.
+func (t *TrakBox) ComputeBitrate() (bufferSizeDB, maxBitrate, avgBitrate uint32, err error) {
+	stbl := t.Mdia.Minf.Stbl
+	nrSamples := t.GetNrSamples()
+	if nrSamples == 0 {
+		return 0, 0, 0, fmt.Errorf("no samples in stbl")
+	}
+	timescale := uint64(t.Mdia.Mdhd.Timescale)
+	sizes := make([]uint64, nrSamples)
+	times := make([]uint64, nrSamples+1) // decode times including end time
+	var totalSize uint64
+	nr := 0
+	for i, count := range stbl.Stts.SampleCount {
+		for j := uint32(0); j < count && nr < int(nrSamples); j++ {
+			times[nr+1] = times[nr] + uint64(stbl.Stts.SampleTimeDelta[i])
+			nr++
+		}
+	}
+	if nr != int(nrSamples) {
+		return 0, 0, 0, fmt.Errorf("stts has %d samples instead of %d", nr, nrSamples)
+	}
+	for i := range sizes {
+		sizes[i] = uint64(stbl.sampleSizes().GetSampleSize(i + 1))
+		totalSize += sizes[i]
+		if uint32(sizes[i]) > bufferSizeDB {
+			bufferSizeDB = uint32(sizes[i])
+		}
+	}
+	duration := times[nrSamples]
+	if duration == 0 || timescale == 0 {
+		return 0, 0, 0, fmt.Errorf("zero track duration")
+	}
+	avgBitrate = uint32(totalSize * 8 * timescale / duration)
+	var windowSize uint64
+	end := 0
+	for start := range sizes {
+		for end < len(sizes) && times[end] < times[start]+timescale {
+			windowSize += sizes[end]
+			end++
+		}
+		if br := uint32(windowSize * 8); br > maxBitrate {
+			maxBitrate = br
+		}
+		windowSize -= sizes[start]
+	}
+	if maxBitrate < avgBitrate { // Tracks shorter than one second
+		maxBitrate = avgBitrate
+	}
+	return bufferSizeDB, maxBitrate, avgBitrate, nil
 }
 
 // GetSampleData - get sample metadata for a specific interval of samples defined in moov.
 // If going outside the range of available samples, an error is returned.
 func (t *TrakBox) GetSampleData(startSampleNr, endSampleNr uint32) ([]Sample, error) {
 	stbl := t.Mdia.Minf.Stbl
-	nrSamples := stbl.Stsz.GetNrSamples()
+	nrSamples := t.GetNrSamples()
 	if startSampleNr < 1 || endSampleNr > nrSamples {
 		return nil, fmt.Errorf("sample interval %d-%d not inside available %d-%d", startSampleNr, endSampleNr, 1, nrSamples)
 	}
@@ -115,16 +394,17 @@ func (t *TrakBox) GetSampleData(startSampleNr, endSampleNr uint32) ([]Sample, er
 	ctts := stbl.Ctts
 	stss := stbl.Stss
 	sdtp := stbl.Sdtp
+	sizes := stbl.sampleSizes()
 
 	for nr := startSampleNr; nr <= endSampleNr; nr++ {
 		var cto int32
 		if ctts != nil {
 			cto = ctts.GetCompositionTimeOffset(nr)
 		}
-		samples[nr-1] = Sample{
+		samples[nr-startSampleNr] = Sample{
 			Flags:                 createSampleFlagsFromProgressiveBoxes(stss, sdtp, nr),
 			Dur:                   stts.GetDur(nr),
-			Size:                  stbl.Stsz.GetSampleSize(int(nr)),
+			Size:                  sizes.GetSampleSize(int(nr)),
 			CompositionTimeOffset: cto,
 		}
 	}
@@ -133,12 +413,13 @@ func (t *TrakBox) GetSampleData(startSampleNr, endSampleNr uint32) ([]Sample, er
 
 func createSampleFlagsFromProgressiveBoxes(stss *StssBox, sdtp *SdtpBox, sampleNr uint32) uint32 {
 	var sampleFlags SampleFlags
+	isSync := true // All samples are sync samples if there is no stss box
 	if stss != nil {
-		isSync := stss.IsSyncSample(uint32(sampleNr))
-		sampleFlags.SampleIsNonSync = !isSync
-		if isSync {
-			sampleFlags.SampleDependsOn = 2 //2 = does not depend on others (I-picture). May be overridden by sdtp entry
-		}
+		isSync = stss.IsSyncSample(uint32(sampleNr))
+	}
+	sampleFlags.SampleIsNonSync = !isSync
+	if isSync {
+		sampleFlags.SampleDependsOn = 2 //2 = does not depend on others (I-picture). May be overridden by sdtp entry
 	}
 	if sdtp != nil {
 		entry := sdtp.Entries[uint32(sampleNr)-1] // table starts at 0, but sampleNr is one-based
@@ -156,14 +437,52 @@ type DataRange struct {
 	Size   uint64
 }
 
+// SampleRange - get byte offset and size in file of the 1-based sampleNr in a progressive file.
+// The chunk is found via stsc, the chunk offset via stco or co64, and the sizes of the
+// preceding samples in the chunk via stsz or stz2. This enables HTTP range requests for single samples.
+func (t *TrakBox) SampleRange(sampleNr uint32) (offset, size uint64, err error) {
+	if t.Mdia == nil || t.Mdia.Minf == nil || t.Mdia.Minf.Stbl == nil {
+		return 0, 0, fmt.Errorf("no stbl box in track")
+	}
+	stbl := t.Mdia.Minf.Stbl
+	sizes := stbl.sampleSizes()
+	if stbl.Stsc == nil || sizes == nil || len(stbl.Stsc.Entries) == 0 {
+		return 0, 0, fmt.Errorf("stsc or stsz box missing or empty")
+	}
+	nrSamples := sizes.GetNrSamples()
+	if sampleNr < 1 || sampleNr > nrSamples {
+		return 0, 0, fmt.Errorf("sampleNr %d not inside available 1-%d", sampleNr, nrSamples)
+	}
+	chunkNr, firstSampleNr, err := stbl.Stsc.ChunkNrFromSampleNr(int(sampleNr))
+	if err != nil {
+		return 0, 0, err
+	}
+	switch {
+	case stbl.Stco != nil:
+		offset, err = stbl.Stco.GetOffset(chunkNr)
+	case stbl.Co64 != nil:
+		offset, err = stbl.Co64.GetOffset(chunkNr)
+	default:
+		err = fmt.Errorf("neither stco nor co64 box present")
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+	sizeBefore, err := sizes.GetTotalSampleSize(uint32(firstSampleNr), sampleNr-1)
+	if err != nil {
+		return 0, 0, err
+	}
+	return offset + sizeBefore, uint64(sizes.GetSampleSize(int(sampleNr))), nil
+}
+
 // GetRangesForSampleInterval - get ranges inside file for sample range [startSampleNr, endSampleNr]
 func (t *TrakBox) GetRangesForSampleInterval(startSampleNr, endSampleNr uint32) ([]DataRange, error) {
 	stbl := t.Mdia.Minf.Stbl
 	stsc := stbl.Stsc
 	stco := stbl.Stco
 	co64 := stbl.Co64
-	stsz := stbl.Stsz
-	nrSamples := stbl.Stsz.GetNrSamples()
+	stsz := stbl.sampleSizes()
+	nrSamples := t.GetNrSamples()
 	if startSampleNr < 1 || endSampleNr > nrSamples {
 		return nil, fmt.Errorf("sample interval %d-%d not inside available %d-%d", startSampleNr, endSampleNr, 1, nrSamples)
 	}
@@ -1,8 +1,11 @@
 package mp4
 
 import (
+	"bytes"
 	"os"
 	"testing"
+
+	"github.com/go-test/deep"
 )
 
 func TestTrakSampleFunctions(t *testing.T) {
@@ -40,3 +43,213 @@ func TestTrakSampleFunctions(t *testing.T) {
 		t.Fatalf("expected 1 range, got %d", len(ranges))
 	}
 }
+
+func TestTrakSampleRange(t *testing.T) {
+	data, err := os.ReadFile("testdata/bbb_prog_10s.mp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mf, err := DecodeFile(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, trak := range mf.Moov.Traks {
+		nrSamples := trak.GetNrSamples()
+		samples, err := trak.GetSampleData(1, nrSamples)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for nr := uint32(1); nr <= nrSamples; nr++ {
+			offset, size, err := trak.SampleRange(nr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ranges, err := trak.GetRangesForSampleInterval(nr, nr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ranges[0].Offset != offset || ranges[0].Size != size {
+				t.Fatalf("track %d sample %d: range %d+%d instead of %d+%d", trak.Tkhd.TrackID, nr,
+					offset, size, ranges[0].Offset, ranges[0].Size)
+			}
+			if size != uint64(samples[nr-1].Size) {
+				t.Fatalf("track %d sample %d: size %d instead of %d", trak.Tkhd.TrackID, nr, size, samples[nr-1].Size)
+			}
+		}
+		if _, _, err := trak.SampleRange(nrSamples + 1); err == nil {
+			t.Errorf("no error for sampleNr after last sample")
+		}
+		if _, _, err := trak.SampleRange(0); err == nil {
+			t.Errorf("no error for sampleNr 0")
+		}
+	}
+}
+
+func TestTrakEditList(t *testing.T) {
+	init := CreateEmptyInit()
+	init.AddEmptyTrack(90000, "video", "und")
+	trak := init.Moov.Trak
+	if trak.GetEditList() != nil {
+		t.Error("expected no edit list")
+	}
+	entries := []ElstEntry{
+		{SegmentDuration: 3000, MediaTime: -1, MediaRateInteger: 1},
+		{SegmentDuration: 0, MediaTime: 1001, MediaRateInteger: 1},
+	}
+	trak.SetEditList(entries)
+	if trak.Children[1] != trak.Edts {
+		t.Error("edts not inserted after tkhd")
+	}
+	if trak.Edts.Elst[0].Version != 0 {
+		t.Errorf("got elst version %d instead of 0", trak.Edts.Elst[0].Version)
+	}
+	entries64 := []ElstEntry{{SegmentDuration: 1 << 33, MediaTime: 1 << 32, MediaRateInteger: 1}}
+	trak.SetEditList(entries64)
+	nrEdts := 0
+	for _, c := range trak.Children {
+		if c.Type() == "edts" {
+			nrEdts++
+		}
+	}
+	if nrEdts != 1 {
+		t.Errorf("got %d edts boxes instead of 1", nrEdts)
+	}
+	if trak.Edts.Elst[0].Version != 1 {
+		t.Errorf("got elst version %d instead of 1", trak.Edts.Elst[0].Version)
+	}
+	buf := bytes.Buffer{}
+	err := trak.Edts.Encode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	box, err := DecodeBox(0, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decTrak := &TrakBox{}
+	decTrak.AddChild(box)
+	if diff := deep.Equal(decTrak.GetEditList(), entries64); diff != nil {
+		t.Error(diff)
+	}
+}
+
+func TestTrakAddKind(t *testing.T) {
+	init := CreateEmptyInit()
+	init.AddEmptyTrack(1000, "text", "en")
+	trak := init.Moov.Trak
+	trak.AddKind("urn:mpeg:dash:role:2011", "subtitle")
+	trak.AddKind("urn:mpeg:dash:role:2011", "forced-subtitle")
+	buf := bytes.Buffer{}
+	err := init.Encode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decFile, err := DecodeFile(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := decFile.Init.Moov.Trak.GetKinds()
+	want := []*KindBox{
+		{SchemeURI: "urn:mpeg:dash:role:2011", Value: "subtitle"},
+		{SchemeURI: "urn:mpeg:dash:role:2011", Value: "forced-subtitle"},
+	}
+	if diff := deep.Equal(got, want); diff != nil {
+		t.Error(diff)
+	}
+}
+
+func TestTrakComputeBitrate(t *testing.T) {
+	init := CreateEmptyInit()
+	init.AddEmptyTrack(1000, "video", "und")
+	trak := init.Moov.Trak
+	stbl := trak.Mdia.Minf.Stbl
+	stbl.Stts.SampleCount = []uint32{4}
+	stbl.Stts.SampleTimeDelta = []uint32{500}
+	stbl.Stsz.SampleNumber = 4
+	stbl.Stsz.SampleSize = []uint32{100, 200, 300, 400}
+	bufferSizeDB, maxBitrate, avgBitrate, err := trak.ComputeBitrate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 1000 bytes in 2s, and max in window with samples 3 and 4
+	if bufferSizeDB != 400 || maxBitrate != 5600 || avgBitrate != 4000 {
+		t.Errorf("got bufferSizeDB=%d maxBitrate=%d avgBitrate=%d instead of 400, 5600, 4000",
+			bufferSizeDB, maxBitrate, avgBitrate)
+	}
+
+	vse := CreateVisualSampleEntryBox("avc1", 640, 360, nil)
+	vse.SetBitrate(1, 2, 3)
+	vse.SetBitrate(bufferSizeDB, maxBitrate, avgBitrate)
+	if len(vse.Children) != 1 || vse.Btrt.AvgBitrate != 4000 || vse.Btrt.MaxBitrate != 5600 {
+		t.Errorf("btrt not set correctly: %+v", vse.Btrt)
+	}
+	boxDiffAfterEncodeAndDecode(t, vse)
+
+	stbl.Stsz.SampleNumber = 0
+	stbl.Stsz.SampleSize = nil
+	if _, _, _, err = trak.ComputeBitrate(); err == nil {
+		t.Error("no error for track without samples")
+	}
+}
+
+func TestTrakSampleSizesAndDurations(t *testing.T) {
+	init := CreateEmptyInit()
+	init.AddEmptyTrack(1000, "audio", "und")
+	trak := init.Moov.Trak
+	stbl := trak.Mdia.Minf.Stbl
+	stbl.Stts.SampleCount = []uint32{2, 1}
+	stbl.Stts.SampleTimeDelta = []uint32{1024, 512}
+	stbl.Stsz.SampleNumber = 3
+	stbl.Stsz.SampleSize = []uint32{100, 200, 300}
+	if diff := deep.Equal(trak.SampleSizes(), []uint32{100, 200, 300}); diff != nil {
+		t.Error(diff)
+	}
+	if diff := deep.Equal(trak.SampleDurations(), []uint32{1024, 1024, 512}); diff != nil {
+		t.Error(diff)
+	}
+
+	stbl.Stsz.SampleUniformSize = 6
+	stbl.Stsz.SampleSize = nil
+	if diff := deep.Equal(trak.SampleSizes(), []uint32{6, 6, 6}); diff != nil {
+		t.Error(diff)
+	}
+
+	stbl.Stsz = nil
+	stbl.Stz2 = &Stz2Box{FieldSize: 8, SampleSize: []uint32{10, 20, 30}}
+	if diff := deep.Equal(trak.SampleSizes(), []uint32{10, 20, 30}); diff != nil {
+		t.Error(diff)
+	}
+	if nr := trak.GetNrSamples(); nr != 3 {
+		t.Errorf("got %d samples instead of 3", nr)
+	}
+}
+
+func TestTrakSetEncoderDelay(t *testing.T) {
+	init := CreateEmptyInit()
+	init.AddEmptyTrack(48000, "audio", "und")
+	trak := init.Moov.Trak
+	if err := trak.SetEncoderDelay(2112, 48000); err != nil {
+		t.Fatal(err)
+	}
+	wanted := []ElstEntry{{SegmentDuration: 0, MediaTime: 2112, MediaRateInteger: 1}}
+	if diff := deep.Equal(trak.GetEditList(), wanted); diff != nil {
+		t.Error(diff)
+	}
+
+	// Progressive track with movie timescale 1000 and 10s duration
+	trak.Mdia.Mdhd.SetDuration(480000)
+	trak.Tkhd.SetDuration(10000)
+	if err := trak.SetEncoderDelay(1024, 24000); err != nil {
+		t.Fatal(err)
+	}
+	wanted = []ElstEntry{{SegmentDuration: 9957, MediaTime: 2048, MediaRateInteger: 1}}
+	if diff := deep.Equal(trak.GetEditList(), wanted); diff != nil {
+		t.Error(diff)
+	}
+	if trak.Tkhd.Duration != 9957 {
+		t.Errorf("got tkhd duration %d instead of 9957", trak.Tkhd.Duration)
+	}
+	if err := trak.SetEncoderDelay(480000, 48000); err == nil {
+		t.Error("no error for delay longer than media")
+	}
+}
@@ -2,6 +2,8 @@ package mp4
 
 import (
 	"testing"
+
+	"github.com/go-test/deep"
 )
 
 func TestTref(t *testing.T) {
@@ -20,3 +22,37 @@ func TestTref(t *testing.T) {
 	tref.AddChild(&TrefTypeBox{Name: "sync", TrackIDs: []uint32{12, 13}})
 	boxDiffAfterEncodeAndDecode(t, &tref)
 }
+
+func TestAddTrackReference(t *testing.T) {
+	trak := NewTrakBox()
+	trak.AddChild(&TkhdBox{TrackID: 3})
+	trak.AddChild(&MdiaBox{})
+	if err := trak.AddTrackReference("cdsc", []uint32{1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := trak.AddTrackReference("chap", []uint32{2}); err != nil {
+		t.Fatal(err)
+	}
+	if err := trak.AddTrackReference("cdsc", []uint32{1, 4}); err != nil {
+		t.Fatal(err)
+	}
+	if trak.Children[1] != trak.Tref {
+		t.Errorf("tref not inserted after tkhd")
+	}
+	if diff := deep.Equal(trak.GetTrackReferences("cdsc"), []uint32{1, 4}); diff != nil {
+		t.Errorf("cdsc references: %v", diff)
+	}
+	if diff := deep.Equal(trak.GetTrackReferences("chap"), []uint32{2}); diff != nil {
+		t.Errorf("chap references: %v", diff)
+	}
+	if trak.GetTrackReferences("subt") != nil {
+		t.Errorf("unexpected subt references")
+	}
+	if err := trak.AddTrackReference("sub", []uint32{1}); err == nil {
+		t.Errorf("no error for three-character reference type")
+	}
+	if err := trak.AddTrackReference("subt", []uint32{0}); err == nil {
+		t.Errorf("no error for trackID 0")
+	}
+	boxDiffAfterEncodeAndDecode(t, trak.Tref)
+}
@@ -8,7 +8,7 @@ import (
 )
 
 // TrepBox - Track Extension Properties Box (trep)
-// Contained in mvex
+// Contained in mvex. The child boxes, like cslg, are kept as they are, also if not known.
 type TrepBox struct {
 	Version  byte
 	Flags    uint32
@@ -16,7 +16,7 @@ type TrepBox struct {
 	Children []Box
 }
 
-// AddChild - Add a child box and update SampleCount
+// AddChild - Add a child box
 func (b *TrepBox) AddChild(child Box) {
 	b.Children = append(b.Children, child)
 }
@@ -62,7 +62,12 @@ func (b *TrepBox) Size() uint64 {
 	return containerSize(b.Children) + 8
 }
 
-// Encode - box-specific encode of stsd - not a usual container
+// GetChildren - list of child boxes
+func (b *TrepBox) GetChildren() []Box {
+	return b.Children
+}
+
+// Encode - box-specific encode of trep - not a usual container
 func (b *TrepBox) Encode(w io.Writer) error {
 	err := EncodeHeader(b, w)
 	if err != nil {
@@ -86,7 +91,7 @@ func (b *TrepBox) Encode(w io.Writer) error {
 	return nil
 }
 
-// EncodeSW- box-specific encode of stsd - not a usual container
+// EncodeSW- box-specific encode of trep - not a usual container
 func (b *TrepBox) EncodeSW(sw bits.SliceWriter) error {
 	err := EncodeHeaderSW(b, sw)
 	if err != nil {
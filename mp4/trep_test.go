@@ -1,7 +1,10 @@
 package mp4
 
 import (
+	"bytes"
 	"testing"
+
+	"github.com/go-test/deep"
 )
 
 func TestTrep(t *testing.T) {
@@ -9,3 +12,38 @@ func TestTrep(t *testing.T) {
 	trep.AddChild(&KindBox{SchemeURI: "X", Value: "Y"})
 	boxDiffAfterEncodeAndDecode(t, trep)
 }
+
+func TestTrepInMvexKeepsUnknownChildren(t *testing.T) {
+	mvex := NewMvexBox()
+	mvex.AddChild(CreateTrex(2))
+	trep := &TrepBox{TrackID: 2}
+	trep.AddChild(&UnknownBox{name: "xyzw", size: 12, notDecoded: []byte{1, 2, 3, 4}})
+	mvex.AddChild(trep)
+	buf := bytes.Buffer{}
+	if err := mvex.Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	encoded := buf.Bytes()
+	box, err := DecodeBox(0, bytes.NewBuffer(encoded))
+	if err != nil {
+		t.Fatal(err)
+	}
+	decMvex := box.(*MvexBox)
+	decTrep, ok := decMvex.GetTrep(2)
+	if !ok {
+		t.Fatal("trep for track 2 not found")
+	}
+	if diff := deep.Equal(decTrep, trep); diff != nil {
+		t.Error(diff)
+	}
+	if _, ok := decMvex.GetTrep(1); ok {
+		t.Error("trep for track 1 found")
+	}
+	out := bytes.Buffer{}
+	if err := decMvex.Encode(&out); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out.Bytes(), encoded) {
+		t.Error("mvex with trep changed after decode and encode")
+	}
+}
@@ -295,6 +295,9 @@ func (t *TrunBox) EncodeSW(sw bits.SliceWriter) error {
 	if err != nil {
 		return err
 	}
+	if t.Version == 0 && t.HasSampleCompositionTimeOffset() && t.hasNegativeCompositionTimeOffset() {
+		t.Version = 1 // Version 0 has unsigned composition time offsets
+	}
 	versionAndFlags := (uint32(t.Version) << 24) + t.Flags
 	sw.WriteUint32(versionAndFlags)
 	sw.WriteUint32(t.SampleCount())
@@ -330,7 +333,7 @@ func (t *TrunBox) EncodeSW(sw bits.SliceWriter) error {
 func (t *TrunBox) Info(w io.Writer, specificBoxLevels, indent, indentStep string) error {
 	bd := newInfoDumper(w, indent, t, int(t.Version), t.Flags)
 	bd.write(" - sampleCount: %d", t.SampleCount())
-	level := getInfoLevel(t, specificBoxLevels)
+	level, sampleRange := getInfoLevelAndRange(t, specificBoxLevels)
 	if level > 0 {
 		if t.HasDataOffset() {
 			bd.write(" - DataOffset: %d", t.DataOffset)
@@ -339,6 +342,9 @@ func (t *TrunBox) Info(w io.Writer, specificBoxLevels, indent, indentStep string
 			bd.write(" - firstSampleFlags: %08x (%s)", t.firstSampleFlags, DecodeSampleFlags(t.firstSampleFlags))
 		}
 		for i := 0; i < int(t.SampleCount()); i++ {
+			if !sampleRange.contains(i + 1) {
+				continue
+			}
 			msg := fmt.Sprintf(" - sample[%d]:", i+1)
 			if t.HasSampleDuration() {
 				msg += fmt.Sprintf(" dur=%d", t.Samples[i].Dur)
@@ -438,6 +444,16 @@ func (t *TrunBox) AddFullSample(s *FullSample) {
 	t.Samples = append(t.Samples, s.Sample)
 }
 
+// hasNegativeCompositionTimeOffset returns true if some sample has a negative composition time offset.
+func (t *TrunBox) hasNegativeCompositionTimeOffset() bool {
+	for _, s := range t.Samples {
+		if s.CompositionTimeOffset < 0 {
+			return true
+		}
+	}
+	return false
+}
+
 // AddSample - add a Sample
 func (t *TrunBox) AddSample(s Sample) {
 	t.Samples = append(t.Samples, s)
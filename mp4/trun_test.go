@@ -1,6 +1,9 @@
 package mp4
 
 import (
+	"bytes"
+	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/go-test/deep"
@@ -29,6 +32,25 @@ func TestTrunInfo(t *testing.T) {
 	}
 }
 
+func TestTrunInfoSampleRange(t *testing.T) {
+	trun := CreateTrun(0)
+	for i := 0; i < 4; i++ {
+		trun.AddSample(Sample{Flags: SyncSampleFlags, Dur: 1024, Size: uint32(10 + i)})
+	}
+	var buf bytes.Buffer
+	err := trun.Info(&buf, "trun:2-3", "", "  ")
+	if err != nil {
+		t.Error(err)
+	}
+	info := buf.String()
+	for nr, want := range []bool{false, true, true, false} {
+		got := strings.Contains(info, fmt.Sprintf("sample[%d]:", nr+1))
+		if got != want {
+			t.Errorf("sample[%d] in info: got %t, wanted %t", nr+1, got, want)
+		}
+	}
+}
+
 func TestGetSampleNrForRelativeTime(t *testing.T) {
 	trun := CreateTrun(0)
 	trun.AddSamples([]Sample{
@@ -190,3 +212,37 @@ func TestCommonDuration(t *testing.T) {
 		}
 	}
 }
+
+func TestTrunNegativeCompositionTimeOffsets(t *testing.T) {
+	trun := &TrunBox{Version: 0, Flags: TrunDataOffsetPresentFlag | TrunSampleDurationPresentFlag |
+		TrunSampleCompositionTimeOffsetPresentFlag, DataOffset: 100}
+	trun.AddSample(Sample{Dur: 1000, CompositionTimeOffset: 0})
+	trun.AddSample(Sample{Dur: 1000, CompositionTimeOffset: -1000})
+	box := encodeDecodeTrun(t, trun)
+	if box.Version != 1 {
+		t.Errorf("version %d instead of 1 for negative composition time offsets", box.Version)
+	}
+	if diff := deep.Equal(box.Samples, trun.Samples); diff != nil {
+		t.Errorf("samples differ after encode and decode: %v", diff)
+	}
+
+	trun = &TrunBox{Version: 0, Flags: TrunDataOffsetPresentFlag | TrunSampleCompositionTimeOffsetPresentFlag,
+		DataOffset: 100}
+	trun.AddSample(Sample{CompositionTimeOffset: 2000})
+	if box := encodeDecodeTrun(t, trun); box.Version != 0 {
+		t.Errorf("version %d instead of 0 for non-negative composition time offsets", box.Version)
+	}
+}
+
+func encodeDecodeTrun(t *testing.T, trun *TrunBox) *TrunBox {
+	t.Helper()
+	buf := bytes.Buffer{}
+	if err := trun.Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	box, err := DecodeBox(0, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return box.(*TrunBox)
+}
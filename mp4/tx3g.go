@@ -0,0 +1,411 @@
+package mp4
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/Eyevinn/mp4ff/bits"
+)
+
+// Boxes needed for 3GPP timed text according to 3GPP TS 26.245
+
+////////////////////////////// tx3g //////////////////////////////
+
+// Tx3gBoxRecord - BoxRecord with text box position relative to the track
+type Tx3gBoxRecord struct {
+	Top    int16
+	Left   int16
+	Bottom int16
+	Right  int16
+}
+
+// Tx3gStyleRecord - StyleRecord used for default style and in styl boxes.
+// StartChar and EndChar are character offsets and EndChar is not included.
+type Tx3gStyleRecord struct {
+	StartChar      uint16
+	EndChar        uint16
+	FontID         uint16
+	FaceStyleFlags byte
+	FontSize       byte
+	TextColorRGBA  [4]byte
+}
+
+// Face style flags in Tx3gStyleRecord
+const (
+	Tx3gFaceBold      = 0x01
+	Tx3gFaceItalic    = 0x02
+	Tx3gFaceUnderline = 0x04
+)
+
+const tx3gStyleRecordSize = 12
+
+// Tx3gBox - TextSampleEntry (tx3g)
+type Tx3gBox struct {
+	DataReferenceIndex      uint16
+	DisplayFlags            uint32
+	HorizontalJustification int8
+	VerticalJustification   int8
+	BackgroundColorRGBA     [4]byte
+	DefaultTextBox          Tx3gBoxRecord
+	DefaultStyle            Tx3gStyleRecord
+	Ftab                    *FtabBox
+	Children                []Box
+}
+
+// NewTx3gBox - Create new empty tx3g box
+func NewTx3gBox() *Tx3gBox {
+	return &Tx3gBox{DataReferenceIndex: 1}
+}
+
+// AddChild - add a child box
+func (b *Tx3gBox) AddChild(child Box) {
+	if ftab, ok := child.(*FtabBox); ok {
+		b.Ftab = ftab
+	}
+	b.Children = append(b.Children, child)
+}
+
+const nrTx3gBytesBeforeChildren = 46
+
+// DecodeTx3g - Decoder tx3g Sample Entry (tx3g)
+func DecodeTx3g(hdr BoxHeader, startPos uint64, r io.Reader) (Box, error) {
+	data, err := readBoxBody(r, hdr)
+	if err != nil {
+		return nil, err
+	}
+	sr := bits.NewFixedSliceReader(data)
+	return DecodeTx3gSR(hdr, startPos, sr)
+}
+
+// DecodeTx3gSR - Decoder tx3g Sample Entry (tx3g)
+func DecodeTx3gSR(hdr BoxHeader, startPos uint64, sr bits.SliceReader) (Box, error) {
+	b := Tx3gBox{}
+	sr.SkipBytes(6) // Skip 6 reserved bytes
+	b.DataReferenceIndex = sr.ReadUint16()
+	b.DisplayFlags = sr.ReadUint32()
+	b.HorizontalJustification = int8(sr.ReadUint8())
+	b.VerticalJustification = int8(sr.ReadUint8())
+	copy(b.BackgroundColorRGBA[:], sr.ReadBytes(4))
+	b.DefaultTextBox = Tx3gBoxRecord{
+		Top:    sr.ReadInt16(),
+		Left:   sr.ReadInt16(),
+		Bottom: sr.ReadInt16(),
+		Right:  sr.ReadInt16(),
+	}
+	b.DefaultStyle = readTx3gStyleRecord(sr)
+	if err := sr.AccError(); err != nil {
+		return nil, err
+	}
+	pos := startPos + nrTx3gBytesBeforeChildren
+	endPos := startPos + uint64(hdr.Hdrlen+hdr.payloadLen())
+	for pos < endPos {
+		box, err := DecodeBoxSR(pos, sr)
+		if err != nil {
+			return nil, err
+		}
+		if box == nil {
+			return nil, fmt.Errorf("no child of tx3g")
+		}
+		b.AddChild(box)
+		pos += box.Size()
+	}
+	return &b, nil
+}
+
+// Type - return box type
+func (b *Tx3gBox) Type() string {
+	return "tx3g"
+}
+
+// Size - return calculated size
+func (b *Tx3gBox) Size() uint64 {
+	totalSize := uint64(nrTx3gBytesBeforeChildren)
+	for _, child := range b.Children {
+		totalSize += child.Size()
+	}
+	return totalSize
+}
+
+// Encode - write box to w
+func (b *Tx3gBox) Encode(w io.Writer) error {
+	sw := bits.NewFixedSliceWriter(int(b.Size()))
+	err := b.EncodeSW(sw)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(sw.Bytes())
+	return err
+}
+
+// EncodeSW - write box to sw
+func (b *Tx3gBox) EncodeSW(sw bits.SliceWriter) error {
+	err := EncodeHeaderSW(b, sw)
+	if err != nil {
+		return err
+	}
+	sw.WriteZeroBytes(6)
+	sw.WriteUint16(b.DataReferenceIndex)
+	sw.WriteUint32(b.DisplayFlags)
+	sw.WriteUint8(byte(b.HorizontalJustification))
+	sw.WriteUint8(byte(b.VerticalJustification))
+	sw.WriteBytes(b.BackgroundColorRGBA[:])
+	sw.WriteInt16(b.DefaultTextBox.Top)
+	sw.WriteInt16(b.DefaultTextBox.Left)
+	sw.WriteInt16(b.DefaultTextBox.Bottom)
+	sw.WriteInt16(b.DefaultTextBox.Right)
+	writeTx3gStyleRecord(sw, b.DefaultStyle)
+	for _, child := range b.Children {
+		err = child.EncodeSW(sw)
+		if err != nil {
+			return err
+		}
+	}
+	return sw.AccError()
+}
+
+// Info - write box-specific information
+func (b *Tx3gBox) Info(w io.Writer, specificBoxLevels, indent, indentStep string) error {
+	bd := newInfoDumper(w, indent, b, -1, 0)
+	bd.write(" - dataReferenceIndex: %d", b.DataReferenceIndex)
+	bd.write(" - displayFlags: %08x", b.DisplayFlags)
+	bd.write(" - justification: horizontal=%d vertical=%d", b.HorizontalJustification, b.VerticalJustification)
+	bd.write(" - backgroundColorRGBA: %x", b.BackgroundColorRGBA)
+	tb := b.DefaultTextBox
+	bd.write(" - defaultTextBox: top=%d left=%d bottom=%d right=%d", tb.Top, tb.Left, tb.Bottom, tb.Right)
+	ds := b.DefaultStyle
+	bd.write(" - defaultStyle: fontID=%d faceStyleFlags=%02x fontSize=%d textColorRGBA=%x",
+		ds.FontID, ds.FaceStyleFlags, ds.FontSize, ds.TextColorRGBA)
+	if bd.err != nil {
+		return bd.err
+	}
+	for _, child := range b.Children {
+		err := child.Info(w, specificBoxLevels, indent+indentStep, indent)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readTx3gStyleRecord(sr bits.SliceReader) Tx3gStyleRecord {
+	s := Tx3gStyleRecord{
+		StartChar:      sr.ReadUint16(),
+		EndChar:        sr.ReadUint16(),
+		FontID:         sr.ReadUint16(),
+		FaceStyleFlags: sr.ReadUint8(),
+		FontSize:       sr.ReadUint8(),
+	}
+	copy(s.TextColorRGBA[:], sr.ReadBytes(4))
+	return s
+}
+
+func writeTx3gStyleRecord(sw bits.SliceWriter, s Tx3gStyleRecord) {
+	sw.WriteUint16(s.StartChar)
+	sw.WriteUint16(s.EndChar)
+	sw.WriteUint16(s.FontID)
+	sw.WriteUint8(s.FaceStyleFlags)
+	sw.WriteUint8(s.FontSize)
+	sw.WriteBytes(s.TextColorRGBA[:])
+}
+
+////////////////////////////// ftab //////////////////////////////
+
+// FtabFontRecord - font identifier and name in ftab box
+type FtabFontRecord struct {
+	FontID uint16
+	Name   string
+}
+
+// FtabBox - FontTableBox (ftab)
+type FtabBox struct {
+	Fonts []FtabFontRecord
+}
+
+// DecodeFtab - box-specific decode
+func DecodeFtab(hdr BoxHeader, startPos uint64, r io.Reader) (Box, error) {
+	data, err := readBoxBody(r, hdr)
+	if err != nil {
+		return nil, err
+	}
+	sr := bits.NewFixedSliceReader(data)
+	return DecodeFtabSR(hdr, startPos, sr)
+}
+
+// DecodeFtabSR - box-specific decode
+func DecodeFtabSR(hdr BoxHeader, startPos uint64, sr bits.SliceReader) (Box, error) {
+	b := FtabBox{}
+	entryCount := int(sr.ReadUint16())
+	for i := 0; i < entryCount; i++ {
+		fontID := sr.ReadUint16()
+		nameLen := int(sr.ReadUint8())
+		b.Fonts = append(b.Fonts, FtabFontRecord{FontID: fontID, Name: sr.ReadFixedLengthString(nameLen)})
+	}
+	return &b, sr.AccError()
+}
+
+// Type - box-specific type
+func (b *FtabBox) Type() string {
+	return "ftab"
+}
+
+// Size - calculated size of box
+func (b *FtabBox) Size() uint64 {
+	size := uint64(boxHeaderSize + 2)
+	for _, f := range b.Fonts {
+		size += uint64(3 + len(f.Name))
+	}
+	return size
+}
+
+// Encode - write box to w
+func (b *FtabBox) Encode(w io.Writer) error {
+	sw := bits.NewFixedSliceWriter(int(b.Size()))
+	err := b.EncodeSW(sw)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(sw.Bytes())
+	return err
+}
+
+// EncodeSW - box-specific encode to slicewriter
+func (b *FtabBox) EncodeSW(sw bits.SliceWriter) error {
+	err := EncodeHeaderSW(b, sw)
+	if err != nil {
+		return err
+	}
+	sw.WriteUint16(uint16(len(b.Fonts)))
+	for _, f := range b.Fonts {
+		if len(f.Name) > 255 {
+			return fmt.Errorf("font name %q longer than 255 bytes", f.Name)
+		}
+		sw.WriteUint16(f.FontID)
+		sw.WriteUint8(byte(len(f.Name)))
+		sw.WriteString(f.Name, false)
+	}
+	return sw.AccError()
+}
+
+// Info - write box-specific information
+func (b *FtabBox) Info(w io.Writer, specificBoxLevels, indent, indentStep string) error {
+	bd := newInfoDumper(w, indent, b, -1, 0)
+	for _, f := range b.Fonts {
+		bd.write(" - font %d: %q", f.FontID, f.Name)
+	}
+	return bd.err
+}
+
+////////////////////////////// sample text //////////////////////////////
+
+// Tx3gSampleToText converts a 3GPP timed text sample to a WebVTT cue payload.
+// The sample consists of a 16-bit text length, the UTF-8 or UTF-16 text, and modifier boxes.
+// Bold, italic, and underline from styl boxes, or from the default style of entry if
+// not overridden, are converted to <b>, <i>, and <u> tags. Other modifier boxes like tbox,
+// hlit, krok, and href have no WebVTT payload equivalent and are skipped.
+// entry may be nil. An empty string is returned for an empty sample, which ends the previous cue.
+func Tx3gSampleToText(sample []byte, entry *Tx3gBox) (string, error) {
+	if len(sample) < 2 {
+		return "", fmt.Errorf("tx3g sample too short: %d bytes", len(sample))
+	}
+	textLen := int(binary.BigEndian.Uint16(sample[0:2]))
+	if 2+textLen > len(sample) {
+		return "", fmt.Errorf("tx3g text length %d beyond sample size %d", textLen, len(sample))
+	}
+	text := decodeTx3gText(sample[2 : 2+textLen])
+	var styles []Tx3gStyleRecord
+	rest := sample[2+textLen:]
+	for len(rest) > 0 {
+		if len(rest) < boxHeaderSize {
+			return "", fmt.Errorf("tx3g modifier box header truncated")
+		}
+		size := int(binary.BigEndian.Uint32(rest[0:4]))
+		boxType := string(rest[4:8])
+		if size < boxHeaderSize || size > len(rest) {
+			return "", fmt.Errorf("bad size %d of tx3g modifier box %s", size, boxType)
+		}
+		if boxType == "styl" {
+			sr := bits.NewFixedSliceReader(rest[boxHeaderSize:size])
+			entryCount := int(sr.ReadUint16())
+			for i := 0; i < entryCount; i++ {
+				styles = append(styles, readTx3gStyleRecord(sr))
+			}
+			if err := sr.AccError(); err != nil {
+				return "", fmt.Errorf("styl box: %w", err)
+			}
+		}
+		rest = rest[size:]
+	}
+	var defaultFlags byte
+	if entry != nil {
+		defaultFlags = entry.DefaultStyle.FaceStyleFlags
+	}
+	return tx3gToWebVTT(text, styles, defaultFlags), nil
+}
+
+// decodeTx3gText decodes UTF-16 text with byte order mark, and otherwise UTF-8 text.
+func decodeTx3gText(data []byte) []rune {
+	if len(data) >= 2 && data[0] == 0xfe && data[1] == 0xff {
+		u16 := make([]uint16, (len(data)-2)/2)
+		for i := range u16 {
+			u16[i] = binary.BigEndian.Uint16(data[2+2*i:])
+		}
+		return utf16.Decode(u16)
+	}
+	return []rune(string(data))
+}
+
+// tx3gToWebVTT writes text with tags for the face style flags of each character.
+func tx3gToWebVTT(text []rune, styles []Tx3gStyleRecord, defaultFlags byte) string {
+	tags := []struct {
+		flag byte
+		name string
+	}{{Tx3gFaceBold, "b"}, {Tx3gFaceItalic, "i"}, {Tx3gFaceUnderline, "u"}}
+	var sb strings.Builder
+	var open []string
+	closeAll := func() {
+		for i := len(open) - 1; i >= 0; i-- {
+			sb.WriteString("</" + open[i] + ">")
+		}
+		open = open[:0]
+	}
+	var curFlags byte
+	for i := 0; i < len(text); i++ {
+		flags := defaultFlags
+		for _, s := range styles {
+			if int(s.StartChar) <= i && i < int(s.EndChar) {
+				flags = s.FaceStyleFlags
+			}
+		}
+		flags &= Tx3gFaceBold | Tx3gFaceItalic | Tx3gFaceUnderline
+		if flags != curFlags {
+			closeAll()
+			for _, tag := range tags {
+				if flags&tag.flag != 0 {
+					sb.WriteString("<" + tag.name + ">")
+					open = append(open, tag.name)
+				}
+			}
+			curFlags = flags
+		}
+		switch c := text[i]; c {
+		case '&':
+			sb.WriteString("&amp;")
+		case '<':
+			sb.WriteString("&lt;")
+		case '>':
+			sb.WriteString("&gt;")
+		case '\r':
+			if i+1 < len(text) && text[i+1] == '\n' {
+				continue
+			}
+			sb.WriteByte('\n')
+		default:
+			sb.WriteRune(c)
+		}
+	}
+	closeAll()
+	return sb.String()
+}
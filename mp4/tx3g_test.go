@@ -0,0 +1,86 @@
+package mp4
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+func TestTx3g(t *testing.T) {
+	tx3g := NewTx3gBox()
+	tx3g.DisplayFlags = 0x20000000
+	tx3g.HorizontalJustification = 1
+	tx3g.VerticalJustification = -1
+	tx3g.BackgroundColorRGBA = [4]byte{0, 0, 0, 0xff}
+	tx3g.DefaultTextBox = Tx3gBoxRecord{Top: 0, Left: 0, Bottom: 60, Right: 400}
+	tx3g.DefaultStyle = Tx3gStyleRecord{FontID: 1, FontSize: 18, TextColorRGBA: [4]byte{0xff, 0xff, 0xff, 0xff}}
+	ftab := &FtabBox{Fonts: []FtabFontRecord{{FontID: 1, Name: "Sans-Serif"}}}
+	tx3g.AddChild(ftab)
+	if tx3g.Ftab != ftab {
+		t.Error("ftab pointer not set")
+	}
+	boxDiffAfterEncodeAndDecode(t, tx3g)
+}
+
+func TestTx3gSampleToText(t *testing.T) {
+	makeSample := func(text []byte, modifiers ...[]byte) []byte {
+		sample := make([]byte, 2, 2+len(text))
+		binary.BigEndian.PutUint16(sample, uint16(len(text)))
+		sample = append(sample, text...)
+		for _, m := range modifiers {
+			sample = append(sample, m...)
+		}
+		return sample
+	}
+	makeStyl := func(records ...Tx3gStyleRecord) []byte {
+		size := boxHeaderSize + 2 + tx3gStyleRecordSize*len(records)
+		b := make([]byte, 0, size)
+		b = append(b, 0, 0, 0, byte(size), 's', 't', 'y', 'l', 0, byte(len(records)))
+		for _, r := range records {
+			b = append(b, byte(r.StartChar>>8), byte(r.StartChar), byte(r.EndChar>>8), byte(r.EndChar),
+				0, 1, r.FaceStyleFlags, 18, 0xff, 0xff, 0xff, 0xff)
+		}
+		return b
+	}
+	tbox := []byte{0, 0, 0, 16, 't', 'b', 'o', 'x', 0, 0, 0, 0, 0, 60, 1, 144}
+	boldEntry := NewTx3gBox()
+	boldEntry.DefaultStyle.FaceStyleFlags = Tx3gFaceBold
+
+	testCases := []struct {
+		desc     string
+		sample   []byte
+		entry    *Tx3gBox
+		wantText string
+		wantErr  bool
+	}{
+		{desc: "empty", sample: makeSample(nil), wantText: ""},
+		{desc: "plain", sample: makeSample([]byte("Hello\r\nworld")), wantText: "Hello\nworld"},
+		{desc: "escaped", sample: makeSample([]byte("a < b & c")), wantText: "a &lt; b &amp; c"},
+		{desc: "utf16", sample: makeSample([]byte{0xfe, 0xff, 0, 'h', 0, 0xe9}), wantText: "hé"},
+		{desc: "styled", sample: makeSample([]byte("one twö three"), tbox,
+			makeStyl(Tx3gStyleRecord{StartChar: 4, EndChar: 7, FaceStyleFlags: Tx3gFaceItalic | Tx3gFaceUnderline})),
+			wantText: "one <i><u>twö</u></i> three"},
+		{desc: "default style", sample: makeSample([]byte("ab"),
+			makeStyl(Tx3gStyleRecord{StartChar: 1, EndChar: 2, FaceStyleFlags: 0})),
+			entry: boldEntry, wantText: "<b>a</b>b"},
+		{desc: "too short", sample: []byte{0}, wantErr: true},
+		{desc: "bad text length", sample: []byte{0, 5, 'a'}, wantErr: true},
+		{desc: "bad modifier size", sample: makeSample([]byte("a"), []byte{0, 0, 0, 20, 's', 't', 'y', 'l'}), wantErr: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			text, err := Tx3gSampleToText(tc.sample, tc.entry)
+			if tc.wantErr {
+				if err == nil {
+					t.Error("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if text != tc.wantText {
+				t.Errorf("got %q instead of %q", text, tc.wantText)
+			}
+		})
+	}
+}
@@ -0,0 +1,313 @@
+package mp4
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/Eyevinn/mp4ff/bits"
+)
+
+// unfragTrack - sample tables for one track collected from fragments
+type unfragTrack struct {
+	trak          *TrakBox
+	trex          *TrexBox
+	stts          *SttsBox
+	stsc          *StscBox
+	sizes         []uint32
+	cttsOffsets   []int32
+	syncSampleNrs []uint32
+	chunkOffsets  []uint64
+	duration      uint64
+	nrSamples     uint32
+	startTime     uint64
+}
+
+// Unfragment creates a progressive file from an init segment and a sequence of media segments.
+// All samples are collected in one mdat box with one chunk per track and fragment,
+// and the moov box gets the corresponding stts, stsc, stsz, stco/co64, ctts, and stss tables.
+// The durations in mvhd, tkhd, and mdhd are set from the sample durations and the timeline
+// of each track starts at zero. Edit list entries with zero duration get the track duration.
+// A track whose first tfdt is later than that of the earliest track gets an empty edit for
+// the difference, so that the tracks stay in sync.
+// The init and media segments are not modified, but the sample data is shared.
+func Unfragment(init *InitSegment, segs []*MediaSegment) (*File, error) {
+	if init == nil || init.Moov == nil {
+		return nil, fmt.Errorf("no init segment")
+	}
+	moov, err := copyMoovWithoutMvex(init.Moov)
+	if err != nil {
+		return nil, err
+	}
+	tracks := make([]*unfragTrack, 0, len(moov.Traks))
+	for _, trak := range moov.Traks {
+		trackID := trak.Tkhd.TrackID
+		trex := CreateTrex(trackID)
+		if init.Moov.Mvex != nil {
+			if t, ok := init.Moov.Mvex.GetTrex(trackID); ok {
+				trex = t
+			}
+		}
+		tracks = append(tracks, &unfragTrack{trak: trak, trex: trex, stts: &SttsBox{}, stsc: &StscBox{}})
+	}
+	findTrack := func(trackID uint32) *unfragTrack {
+		for _, t := range tracks {
+			if t.trak.Tkhd.TrackID == trackID {
+				return t
+			}
+		}
+		return nil
+	}
+
+	// Offsets are first calculated with mdat at position 0 and then fixed by FixChunkOffsets
+	var payload [][]byte
+	var payloadSize uint64
+	for _, seg := range segs {
+		for _, frag := range seg.Fragments {
+			for _, traf := range frag.Moof.Trafs {
+				track := findTrack(traf.Tfhd.TrackID)
+				if track == nil {
+					return nil, fmt.Errorf("track %d not in init segment", traf.Tfhd.TrackID)
+				}
+				samples, err := frag.GetFullSamples(track.trex)
+				if err != nil {
+					return nil, fmt.Errorf("track %d: %w", traf.Tfhd.TrackID, err)
+				}
+				if len(samples) == 0 {
+					continue
+				}
+				if track.nrSamples == 0 {
+					track.startTime = samples[0].DecodeTime
+				}
+				sampleDescriptionID := track.trex.DefaultSampleDescriptionIndex
+				if traf.Tfhd.HasSampleDescriptionIndex() {
+					sampleDescriptionID = traf.Tfhd.SampleDescriptionIndex
+				}
+				err = track.addChunk(samples, payloadSize, sampleDescriptionID)
+				if err != nil {
+					return nil, fmt.Errorf("track %d: %w", traf.Tfhd.TrackID, err)
+				}
+				for i := range samples {
+					payload = append(payload, samples[i].Data)
+					payloadSize += uint64(len(samples[i].Data))
+				}
+			}
+		}
+	}
+
+	mdat := &MdatBox{}
+	if payloadSize+8 > math.MaxUint32 {
+		mdat.LargeSize = true
+	}
+	for _, data := range payload {
+		mdat.AddSampleData(data)
+	}
+	movieTimescale := moov.Mvhd.Timescale
+	startTimes := make([]uint64, len(tracks))
+	var earliestStart uint64 = math.MaxUint64
+	for i, track := range tracks {
+		if track.nrSamples == 0 {
+			return nil, fmt.Errorf("track %d has no samples", track.trak.Tkhd.TrackID)
+		}
+		startTimes[i] = track.startTime * uint64(movieTimescale) / uint64(track.trak.Mdia.Mdhd.Timescale)
+		if startTimes[i] < earliestStart {
+			earliestStart = startTimes[i]
+		}
+	}
+	var movieDuration uint64
+	for i, track := range tracks {
+		track.setSampleTable(mdat.HeaderSize())
+		trackDuration := track.duration
+		mediaTimescale := track.trak.Mdia.Mdhd.Timescale
+		if movieTimescale != mediaTimescale {
+			trackDuration = track.duration * uint64(movieTimescale) / uint64(mediaTimescale)
+		}
+		setDuration(track.trak, trackDuration, track.duration, mediaTimescale, movieTimescale)
+		if delay := startTimes[i] - earliestStart; delay > 0 {
+			addEmptyEdit(track.trak, delay, trackDuration)
+			trackDuration += delay
+		}
+		if trackDuration > movieDuration {
+			movieDuration = trackDuration
+		}
+	}
+	moov.Mvhd.SetDuration(movieDuration)
+
+	f := NewFile()
+	if init.Ftyp != nil {
+		f.AddChild(init.Ftyp, 0)
+	}
+	f.AddChild(moov, 0)
+	f.AddChild(mdat, 0)
+	err = f.FixChunkOffsets()
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// copyMoovWithoutMvex makes a deep copy of moov without the mvex box.
+func copyMoovWithoutMvex(moov *MoovBox) (*MoovBox, error) {
+	sw := bits.NewFixedSliceWriter(int(moov.Size()))
+	err := moov.EncodeSW(sw)
+	if err != nil {
+		return nil, fmt.Errorf("encode moov: %w", err)
+	}
+	box, err := DecodeBoxSR(0, bits.NewFixedSliceReader(sw.Bytes()))
+	if err != nil {
+		return nil, fmt.Errorf("decode moov: %w", err)
+	}
+	moovCopy := box.(*MoovBox)
+	out := NewMoovBox()
+	for _, c := range moovCopy.Children {
+		if _, isMvex := c.(*MvexBox); !isMvex {
+			out.AddChild(c)
+		}
+	}
+	return out, nil
+}
+
+// addChunk adds samples as one chunk starting at offset in mdat payload.
+func (t *unfragTrack) addChunk(samples []FullSample, offset uint64, sampleDescriptionID uint32) error {
+	if sampleDescriptionID == 0 {
+		sampleDescriptionID = 1
+	}
+	nrInChunk := uint32(len(samples))
+	nrChunks := len(t.chunkOffsets)
+	entries := t.stsc.Entries
+	if nrChunks == 0 || entries[len(entries)-1].SamplesPerChunk != nrInChunk ||
+		t.stsc.GetSampleDescriptionID(nrChunks) != sampleDescriptionID {
+		err := t.stsc.AddEntry(uint32(nrChunks+1), nrInChunk, sampleDescriptionID)
+		if err != nil {
+			return err
+		}
+	}
+	t.chunkOffsets = append(t.chunkOffsets, offset)
+	for i := range samples {
+		s := &samples[i]
+		t.nrSamples++
+		n := len(t.stts.SampleCount)
+		if n > 0 && t.stts.SampleTimeDelta[n-1] == s.Dur {
+			t.stts.SampleCount[n-1]++
+		} else {
+			t.stts.SampleCount = append(t.stts.SampleCount, 1)
+			t.stts.SampleTimeDelta = append(t.stts.SampleTimeDelta, s.Dur)
+		}
+		t.duration += uint64(s.Dur)
+		t.sizes = append(t.sizes, s.Size)
+		t.cttsOffsets = append(t.cttsOffsets, s.CompositionTimeOffset)
+		if s.IsSync() {
+			t.syncSampleNrs = append(t.syncSampleNrs, t.nrSamples)
+		}
+	}
+	return nil
+}
+
+// setSampleTable replaces the sample table boxes except stsd and sgpd in the trak.
+// The chunk offsets are relative to a start of mdat at position 0.
+func (t *unfragTrack) setSampleTable(mdatHeaderSize uint64) {
+	oldStbl := t.trak.Mdia.Minf.Stbl
+	stbl := NewStblBox()
+	stbl.AddChild(oldStbl.Stsd)
+	stbl.AddChild(t.stts)
+	if !allZero(t.cttsOffsets) {
+		ctts := &CttsBox{}
+		for _, offset := range t.cttsOffsets {
+			if offset < 0 {
+				ctts.Version = 1
+			}
+			n := len(ctts.SampleOffset)
+			if n > 0 && ctts.SampleOffset[n-1] == offset {
+				ctts.EndSampleNr[n]++
+				continue
+			}
+			_ = ctts.AddSampleCountsAndOffset([]uint32{1}, []int32{offset})
+		}
+		stbl.AddChild(ctts)
+	}
+	if len(t.syncSampleNrs) != int(t.nrSamples) {
+		stbl.AddChild(&StssBox{SampleNumber: t.syncSampleNrs})
+	}
+	stbl.AddChild(t.stsc)
+	stsz := &StszBox{SampleNumber: t.nrSamples}
+	if allEqual(t.sizes) && t.sizes[0] > 0 {
+		stsz.SampleUniformSize = t.sizes[0]
+	} else {
+		stsz.SampleSize = t.sizes
+	}
+	stbl.AddChild(stsz)
+	lastOffset := t.chunkOffsets[len(t.chunkOffsets)-1] + mdatHeaderSize
+	if lastOffset > math.MaxUint32 {
+		co64 := &Co64Box{ChunkOffset: make([]uint64, len(t.chunkOffsets))}
+		for i, offset := range t.chunkOffsets {
+			co64.ChunkOffset[i] = offset + mdatHeaderSize
+		}
+		stbl.AddChild(co64)
+	} else {
+		stco := &StcoBox{ChunkOffset: make([]uint32, len(t.chunkOffsets))}
+		for i, offset := range t.chunkOffsets {
+			stco.ChunkOffset[i] = uint32(offset + mdatHeaderSize)
+		}
+		stbl.AddChild(stco)
+	}
+	for _, sgpd := range oldStbl.Sgpds {
+		stbl.AddChild(sgpd)
+	}
+	minf := t.trak.Mdia.Minf
+	for i, c := range minf.Children {
+		if c == oldStbl {
+			minf.Children[i] = stbl
+			break
+		}
+	}
+	minf.Stbl = stbl
+}
+
+// setDuration sets durations in tkhd, mdhd, and zero-duration edit list entries.
+func setDuration(trak *TrakBox, trackDuration, mediaDuration uint64, mediaTimescale, movieTimescale uint32) {
+	trak.Tkhd.SetDuration(trackDuration)
+	trak.Mdia.Mdhd.SetDuration(mediaDuration)
+	entries := trak.GetEditList()
+	changed := false
+	for i := range entries {
+		if entries[i].SegmentDuration == 0 && entries[i].MediaTime >= 0 &&
+			uint64(entries[i].MediaTime) < mediaDuration {
+			remaining := mediaDuration - uint64(entries[i].MediaTime)
+			entries[i].SegmentDuration = remaining * uint64(movieTimescale) / uint64(mediaTimescale)
+			changed = true
+		}
+	}
+	if changed {
+		trak.SetEditList(entries)
+	}
+}
+
+// addEmptyEdit inserts an empty edit of duration delay in movie timescale first in the edit list
+// and adds delay to the tkhd duration. Without edit list, an edit for the whole media is added
+// after the empty edit.
+func addEmptyEdit(trak *TrakBox, delay, trackDuration uint64) {
+	entries := trak.GetEditList()
+	if len(entries) == 0 {
+		entries = []ElstEntry{{SegmentDuration: trackDuration, MediaTime: 0, MediaRateInteger: 1}}
+	}
+	empty := ElstEntry{SegmentDuration: delay, MediaTime: -1, MediaRateInteger: 1}
+	trak.SetEditList(append([]ElstEntry{empty}, entries...))
+	trak.Tkhd.SetDuration(trak.Tkhd.Duration + delay)
+}
+
+func allZero(values []int32) bool {
+	for _, v := range values {
+		if v != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func allEqual(values []uint32) bool {
+	for _, v := range values {
+		if v != values[0] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,128 @@
+package mp4
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestUnfragment(t *testing.T) {
+	for _, fileName := range []string{"testdata/prog_8s_dec_dashinit.mp4", "testdata/bbb5s_aac_sidx.mp4"} {
+		t.Run(fileName, func(t *testing.T) {
+			fragFile, err := ReadMP4File(fileName)
+			if err != nil {
+				t.Fatal(err)
+			}
+			progFile, err := Unfragment(fragFile.Init, fragFile.Segments)
+			if err != nil {
+				t.Fatal(err)
+			}
+			buf := bytes.Buffer{}
+			err = progFile.Encode(&buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			decFile, err := DecodeFile(&buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if decFile.IsFragmented() {
+				t.Fatal("output file is fragmented")
+			}
+			for _, trak := range decFile.Moov.Traks {
+				trackID := trak.Tkhd.TrackID
+				trex, _ := fragFile.Init.Moov.Mvex.GetTrex(trackID)
+				var fragSamples []FullSample
+				var dur uint64
+				for _, seg := range fragFile.Segments {
+					for _, frag := range seg.Fragments {
+						samples, err := frag.GetFullSamples(trex)
+						if err != nil {
+							t.Fatal(err)
+						}
+						fragSamples = append(fragSamples, samples...)
+						for _, s := range samples {
+							dur += uint64(s.Dur)
+						}
+					}
+				}
+				if trak.Mdia.Mdhd.Duration != dur {
+					t.Errorf("track %d: mdhd duration %d instead of %d", trackID, trak.Mdia.Mdhd.Duration, dur)
+				}
+				nrSamples := trak.Mdia.Minf.Stbl.Stsz.GetNrSamples()
+				if int(nrSamples) != len(fragSamples) {
+					t.Fatalf("track %d: %d samples instead of %d", trackID, nrSamples, len(fragSamples))
+				}
+				progSamples, err := decFile.ReadSamples(nil, trak, 1, nrSamples)
+				if err != nil {
+					t.Fatal(err)
+				}
+				for i := range progSamples {
+					p, f := progSamples[i], fragSamples[i]
+					if p.Dur != f.Dur || p.CompositionTimeOffset != f.CompositionTimeOffset || p.IsSync() != f.IsSync() {
+						t.Errorf("track %d sample %d: got %+v instead of %+v", trackID, i+1, p.Sample, f.Sample)
+					}
+					if !bytes.Equal(p.Data, f.Data) {
+						t.Errorf("track %d sample %d: data differs", trackID, i+1)
+					}
+				}
+			}
+			if decFile.Moov.Mvhd.Duration == 0 {
+				t.Error("mvhd duration not set")
+			}
+		})
+	}
+}
+
+func TestUnfragmentTrackStartOffset(t *testing.T) {
+	fragFile, err := ReadMP4File("testdata/prog_8s_dec_dashinit.mp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Delay audio track 1 by one second relative to video track 2
+	const delayedTrackID = 1
+	var mediaTimescale uint64
+	for _, trak := range fragFile.Init.Moov.Traks {
+		if trak.Tkhd.TrackID == delayedTrackID {
+			mediaTimescale = uint64(trak.Mdia.Mdhd.Timescale)
+		}
+	}
+	for _, seg := range fragFile.Segments {
+		for _, frag := range seg.Fragments {
+			for _, traf := range frag.Moof.Trafs {
+				if traf.Tfhd.TrackID == delayedTrackID {
+					traf.Tfdt.SetBaseMediaDecodeTime(traf.Tfdt.BaseMediaDecodeTime() + mediaTimescale)
+				}
+			}
+		}
+	}
+	progFile, err := Unfragment(fragFile.Init, fragFile.Segments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	movieTimescale := uint64(progFile.Moov.Mvhd.Timescale)
+	for _, trak := range progFile.Moov.Traks {
+		entries := trak.GetEditList()
+		if trak.Tkhd.TrackID != delayedTrackID {
+			for _, e := range entries {
+				if e.MediaTime == -1 {
+					t.Errorf("track %d: unexpected empty edit", trak.Tkhd.TrackID)
+				}
+			}
+			continue
+		}
+		if len(entries) != 2 {
+			t.Fatalf("track %d: %d edit list entries instead of 2", trak.Tkhd.TrackID, len(entries))
+		}
+		if entries[0].MediaTime != -1 || entries[0].SegmentDuration != movieTimescale {
+			t.Errorf("track %d: first edit %+v is not an empty edit of 1s", trak.Tkhd.TrackID, entries[0])
+		}
+		mediaDuration := trak.Mdia.Mdhd.Duration * movieTimescale / mediaTimescale
+		if entries[1].MediaTime != 0 || entries[1].SegmentDuration != mediaDuration {
+			t.Errorf("track %d: second edit %+v does not cover the media", trak.Tkhd.TrackID, entries[1])
+		}
+		if trak.Tkhd.Duration != mediaDuration+movieTimescale {
+			t.Errorf("track %d: tkhd duration %d instead of %d", trak.Tkhd.TrackID, trak.Tkhd.Duration,
+				mediaDuration+movieTimescale)
+		}
+	}
+}
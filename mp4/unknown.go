@@ -2,16 +2,29 @@ package mp4
 
 import (
 	"encoding/hex"
+	"fmt"
 	"io"
 
 	"github.com/Eyevinn/mp4ff/bits"
 )
 
 // UnknownBox - box that we don't know how to parse
+// It is also used as a stub with only type and size for boxes skipped by a box filter.
 type UnknownBox struct {
 	name       string
 	size       uint64
 	notDecoded []byte
+	skipped    bool
+}
+
+// newSkippedBox - stub for a box that was skipped without reading its payload
+func newSkippedBox(hdr BoxHeader) *UnknownBox {
+	return &UnknownBox{name: hdr.Name, size: hdr.Size, skipped: true}
+}
+
+// IsSkipped - true if the box was skipped during decoding, so that there is no payload
+func (b *UnknownBox) IsSkipped() bool {
+	return b.skipped
 }
 
 // DecodeUnknown - decode an unknown box
@@ -26,7 +39,7 @@ func DecodeUnknown(hdr BoxHeader, startPos uint64, r io.Reader) (Box, error) {
 
 // DecodeUnknownSR - decode an unknown box
 func DecodeUnknownSR(hdr BoxHeader, startPos uint64, sr bits.SliceReader) (Box, error) {
-	return &UnknownBox{hdr.Name, hdr.Size, sr.ReadBytes(hdr.payloadLen())}, sr.AccError()
+	return &UnknownBox{name: hdr.Name, size: hdr.Size, notDecoded: sr.ReadBytes(hdr.payloadLen())}, sr.AccError()
 }
 
 // Type - return box type
@@ -41,6 +54,9 @@ func (b *UnknownBox) Size() uint64 {
 
 // Encode - write box to w
 func (b *UnknownBox) Encode(w io.Writer) error {
+	if b.skipped {
+		return fmt.Errorf("cannot encode %s box skipped during decoding", b.name)
+	}
 	sw := bits.NewFixedSliceWriter(int(b.Size()))
 	err := b.EncodeSW(sw)
 	if err != nil {
@@ -52,6 +68,9 @@ func (b *UnknownBox) Encode(w io.Writer) error {
 
 // EncodeSW - box-specific encode to slicewriter
 func (b *UnknownBox) EncodeSW(sw bits.SliceWriter) error {
+	if b.skipped {
+		return fmt.Errorf("cannot encode %s box skipped during decoding", b.name)
+	}
 	err := EncodeHeaderSW(b, sw)
 	if err != nil {
 		return err
@@ -63,6 +82,10 @@ func (b *UnknownBox) EncodeSW(sw bits.SliceWriter) error {
 // Info - write box-specific information
 func (b *UnknownBox) Info(w io.Writer, specificBoxLevels, indent, indentStep string) error {
 	bd := newInfoDumper(w, indent, b, -1, 0)
+	if b.skipped {
+		bd.write(" - skipped during decoding")
+		return bd.err
+	}
 	bd.write(" - not implemented or unknown box")
 	level := getInfoLevel(b, specificBoxLevels)
 	if level > 0 {
@@ -75,6 +75,29 @@ var (
 	uuidPiffSenc UUID = mustCreateUUID(UUIDPiffSenc)
 )
 
+// uuidDecodersSR - registered decoders for uuid boxes with specific extended type
+var uuidDecodersSR = map[string]BoxDecoderSR{}
+
+// RegisterUUIDBox registers a decoder for uuid boxes with extended type uuid.
+// The decoder is called with sr positioned after the 16-byte UUID, so hdr.payloadLen()-16
+// bytes remain to be read, and the returned box replaces the UUIDBox in the box tree.
+// It must therefore encode the uuid header and UUID itself.
+// Registered decoders take precedence over the built-in tfxd, tfrf, and PIFF senc decoding.
+//
+// This is a global change, so use with care.
+func RegisterUUIDBox(uuid UUID, decSR BoxDecoderSR) error {
+	if len(uuid) != 16 {
+		return fmt.Errorf("uuid must be 16 bytes, not %d", len(uuid))
+	}
+	uuidDecodersSR[uuid.String()] = decSR
+	return nil
+}
+
+// RemoveUUIDBox removes a registered decoder for uuid boxes with extended type uuid.
+func RemoveUUIDBox(uuid UUID) {
+	delete(uuidDecodersSR, uuid.String())
+}
+
 // UUIDBox - Used as container for MSS boxes tfxd and tfrf
 // For unknown UUID, the data after the UUID is stored as UnknownPayload
 type UUIDBox struct {
@@ -133,6 +156,12 @@ func DecodeUUIDBoxSR(hdr BoxHeader, startPos uint64, sr bits.SliceReader) (Box,
 		StartPos: startPos,
 		uuid:     sr.ReadBytes(16),
 	}
+	if err := sr.AccError(); err != nil {
+		return nil, err
+	}
+	if decSR, ok := uuidDecodersSR[b.UUID()]; ok {
+		return decSR(hdr, startPos, sr)
+	}
 	switch b.UUID() {
 	case UUIDTfxd:
 		tfxd, err := decodeTfxd(sr)
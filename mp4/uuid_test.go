@@ -3,7 +3,10 @@ package mp4
 import (
 	"bytes"
 	"encoding/hex"
+	"io"
 	"testing"
+
+	"github.com/Eyevinn/mp4ff/bits"
 )
 
 func TestUUIDVariants(t *testing.T) {
@@ -181,3 +184,95 @@ func TestUnpackKey(t *testing.T) {
 	}
 
 }
+
+// testUUIDBox - uuid box with a text payload for testing registered decoders
+type testUUIDBox struct {
+	uuid UUID
+	Text string
+}
+
+func (b *testUUIDBox) Type() string { return "uuid" }
+
+func (b *testUUIDBox) Size() uint64 { return uint64(8 + 16 + len(b.Text)) }
+
+func (b *testUUIDBox) Encode(w io.Writer) error {
+	sw := bits.NewFixedSliceWriter(int(b.Size()))
+	if err := b.EncodeSW(sw); err != nil {
+		return err
+	}
+	_, err := w.Write(sw.Bytes())
+	return err
+}
+
+func (b *testUUIDBox) EncodeSW(sw bits.SliceWriter) error {
+	if err := EncodeHeaderSW(b, sw); err != nil {
+		return err
+	}
+	sw.WriteBytes(b.uuid)
+	sw.WriteString(b.Text, false)
+	return sw.AccError()
+}
+
+func (b *testUUIDBox) Info(w io.Writer, specificBoxLevels, indent, indentStep string) error {
+	bd := newInfoDumper(w, indent, b, -1, 0)
+	bd.write(" - text: %q", b.Text)
+	return bd.err
+}
+
+func TestRegisterUUIDBox(t *testing.T) {
+	u := mustCreateUUID("ffcc8263-f855-4a93-8814-587a02521fdd")
+	box := &testUUIDBox{uuid: u, Text: "spherical"}
+	buf := bytes.Buffer{}
+	if err := box.Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	err := RegisterUUIDBox(u, func(hdr BoxHeader, startPos uint64, sr bits.SliceReader) (Box, error) {
+		return &testUUIDBox{uuid: u, Text: sr.ReadFixedLengthString(hdr.payloadLen() - 16)}, sr.AccError()
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, useSR := range []bool{false, true} {
+		var decBox Box
+		if useSR {
+			decBox, err = DecodeBoxSR(0, bits.NewFixedSliceReader(data))
+		} else {
+			decBox, err = DecodeBox(0, bytes.NewReader(data))
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		tb, ok := decBox.(*testUUIDBox)
+		if !ok || tb.Text != "spherical" {
+			t.Fatalf("registered decoder not used: %T", decBox)
+		}
+	}
+	RemoveUUIDBox(u)
+	decBox, err := DecodeBox(0, bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ub, ok := decBox.(*UUIDBox); !ok || ub.SubType() != "unknown" {
+		t.Errorf("got %T instead of unknown UUIDBox after removal", decBox)
+	}
+	if err := RegisterUUIDBox(UUID{1, 2}, nil); err == nil {
+		t.Error("expected error for short uuid")
+	}
+}
+
+func TestTrafTfxdTfrf(t *testing.T) {
+	traf := &TrafBox{}
+	if traf.GetTfxd() != nil || traf.GetTfrf() != nil {
+		t.Error("expected no tfxd or tfrf")
+	}
+	tfxd := &TfxdData{FragmentAbsoluteTime: 20000000, FragmentAbsoluteDuration: 20000000}
+	tfrf := &TfrfData{FragmentCount: 1, FragmentAbsoluteTimes: []uint64{40000000},
+		FragmentAbsoluteDurations: []uint64{20000000}}
+	_ = traf.AddChild(&UUIDBox{uuid: mustCreateUUID(UUIDTfxd), Tfxd: tfxd})
+	_ = traf.AddChild(&UUIDBox{uuid: mustCreateUUID(UUIDTfrf), Tfrf: tfrf})
+	if traf.GetTfxd() != tfxd || traf.GetTfrf() != tfrf {
+		t.Error("tfxd or tfrf not found in traf")
+	}
+}
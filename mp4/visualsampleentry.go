@@ -8,7 +8,7 @@ import (
 	"github.com/Eyevinn/mp4ff/hevc"
 )
 
-// VisualSampleEntryBox Video Sample Description box (avc1/avc3/hvc1/hev1...)
+// VisualSampleEntryBox Video Sample Description box (avc1/avc3/hvc1/hev1/vvc1/vvi1/dvh1/dvhe...)
 type VisualSampleEntryBox struct {
 	name               string
 	DataReferenceIndex uint16
@@ -20,14 +20,17 @@ type VisualSampleEntryBox struct {
 	CompressorName     string
 	AvcC               *AvcCBox
 	HvcC               *HvcCBox
+	VvcC               *VvcCBox
 	Av1C               *Av1CBox
 	VppC               *VppCBox
 	Btrt               *BtrtBox
 	Clap               *ClapBox
 	Pasp               *PaspBox
+	Colr               *ColrBox
 	Sinf               *SinfBox
 	SmDm               *SmDmBox
 	CoLL               *CoLLBox
+	DoVi               *DoViConfigurationBox
 	Children           []Box
 }
 
@@ -64,6 +67,8 @@ func (b *VisualSampleEntryBox) AddChild(child Box) {
 		b.AvcC = box
 	case *HvcCBox:
 		b.HvcC = box
+	case *VvcCBox:
+		b.VvcC = box
 	case *Av1CBox:
 		b.Av1C = box
 	case *VppCBox:
@@ -74,12 +79,16 @@ func (b *VisualSampleEntryBox) AddChild(child Box) {
 		b.Clap = box
 	case *PaspBox:
 		b.Pasp = box
+	case *ColrBox:
+		b.Colr = box
 	case *SinfBox:
 		b.Sinf = box
 	case *SmDmBox:
 		b.SmDm = box
 	case *CoLLBox:
 		b.CoLL = box
+	case *DoViConfigurationBox:
+		b.DoVi = box
 	}
 	b.Children = append(b.Children, child)
 }
@@ -278,6 +287,53 @@ func (b *VisualSampleEntryBox) RemoveEncryption() (*SinfBox, error) {
 	return sinf, nil
 }
 
+// SetColr sets nclx colour information, e.g. primaries=9, transfer=16, matrix=9 for BT.2020 PQ.
+// An existing colr box is replaced in place, otherwise a new one is added as last child.
+func (b *VisualSampleEntryBox) SetColr(primaries, transfer, matrix uint16, fullRange bool) {
+	colr := &ColrBox{
+		ColorType:               onScreenColors,
+		ColorPrimaries:          primaries,
+		TransferCharacteristics: transfer,
+		MatrixCoefficients:      matrix,
+		FullRangeFlag:           fullRange,
+	}
+	b.replaceOrAddChild(colr)
+}
+
+// SetPasp sets the pixel aspect ratio hSpacing:vSpacing.
+// An existing pasp box is replaced in place, otherwise a new one is added as last child.
+func (b *VisualSampleEntryBox) SetPasp(hSpacing, vSpacing uint32) {
+	b.replaceOrAddChild(&PaspBox{HSpacing: hSpacing, VSpacing: vSpacing})
+}
+
+// SetBitrate sets the btrt values, with bufferSizeDB in bytes and bitrates in bits per second.
+// An existing btrt box is replaced in place, otherwise a new one is added as last child.
+func (b *VisualSampleEntryBox) SetBitrate(bufferSizeDB, maxBitrate, avgBitrate uint32) {
+	b.replaceOrAddChild(&BtrtBox{BufferSizeDB: bufferSizeDB, MaxBitrate: maxBitrate, AvgBitrate: avgBitrate})
+}
+
+// PixelAspectRatio returns hSpacing and vSpacing from the pasp box, or 1:1 if there is no pasp box.
+func (b *VisualSampleEntryBox) PixelAspectRatio() (hSpacing, vSpacing uint32) {
+	if b.Pasp == nil {
+		return 1, 1
+	}
+	return b.Pasp.HSpacing, b.Pasp.VSpacing
+}
+
+// replaceOrAddChild replaces the first child of the same type as box, or adds box as last child.
+func (b *VisualSampleEntryBox) replaceOrAddChild(box Box) {
+	for i := range b.Children {
+		if b.Children[i].Type() == box.Type() {
+			children := b.Children
+			b.AddChild(box) // sets the pointer to the box
+			b.Children = children
+			b.Children[i] = box
+			return
+		}
+	}
+	b.AddChild(box)
+}
+
 // ConvertHev1ToHvc1 converts visual sample entry box type and insert VPS, SPS, and PPS parameter sets
 func (b *VisualSampleEntryBox) ConvertHev1ToHvc1(vpss [][]byte, spss [][]byte, ppss [][]byte) error {
 	if b.Type() != "hev1" {
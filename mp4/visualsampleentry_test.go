@@ -3,6 +3,8 @@ package mp4
 import (
 	"encoding/hex"
 	"testing"
+
+	"github.com/go-test/deep"
 )
 
 func TestVisualSampleEntryBoxVP9(t *testing.T) {
@@ -56,3 +58,39 @@ func TestVisualSampleEntryBoxVP9(t *testing.T) {
 		t.Errorf("")
 	}
 }
+
+func TestVisualSampleEntrySetColr(t *testing.T) {
+	hvc1 := CreateVisualSampleEntryBox("hvc1", 3840, 2160, nil)
+	hvc1.SetColr(1, 1, 1, false)
+	hvc1.SetColr(9, 16, 9, true)
+	if len(hvc1.Children) != 1 {
+		t.Fatalf("%d children instead of 1", len(hvc1.Children))
+	}
+	if hvc1.Colr != hvc1.Children[0] {
+		t.Error("Colr link is broken")
+	}
+	wanted := ColrBox{ColorType: "nclx", ColorPrimaries: 9, TransferCharacteristics: 16,
+		MatrixCoefficients: 9, FullRangeFlag: true}
+	if diff := deep.Equal(*hvc1.Colr, wanted); diff != nil {
+		t.Error(diff)
+	}
+	boxDiffAfterEncodeAndDecode(t, hvc1)
+}
+
+func TestVisualSampleEntrySetPasp(t *testing.T) {
+	avc1 := CreateVisualSampleEntryBox("avc1", 720, 576, nil)
+	if h, v := avc1.PixelAspectRatio(); h != 1 || v != 1 {
+		t.Errorf("got pixel aspect ratio %d:%d instead of 1:1", h, v)
+	}
+	avc1.AddChild(&BtrtBox{})
+	avc1.SetPasp(12, 11)
+	avc1.SetColr(1, 1, 1, false)
+	avc1.SetPasp(16, 11)
+	if len(avc1.Children) != 3 || avc1.Children[1] != avc1.Pasp {
+		t.Fatalf("pasp not replaced in place: %v", avc1.Children)
+	}
+	if h, v := avc1.PixelAspectRatio(); h != 16 || v != 11 {
+		t.Errorf("got pixel aspect ratio %d:%d instead of 16:11", h, v)
+	}
+	boxDiffAfterEncodeAndDecode(t, avc1)
+}
@@ -0,0 +1,123 @@
+package mp4
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+
+	"github.com/Eyevinn/mp4ff/bits"
+	"github.com/Eyevinn/mp4ff/vvc"
+)
+
+// VvcCBox - VVCConfigurationBox (ISO/IEC 14496-15 11.2.4.2)
+// Full box containing one VVCDecoderConfigurationRecord
+type VvcCBox struct {
+	Version byte
+	Flags   uint32
+	vvc.DecConfRec
+}
+
+// DecodeVvcC - box-specific decode
+func DecodeVvcC(hdr BoxHeader, startPos uint64, r io.Reader) (Box, error) {
+	data, err := readBoxBody(r, hdr)
+	if err != nil {
+		return nil, err
+	}
+	sr := bits.NewFixedSliceReader(data)
+	return DecodeVvcCSR(hdr, startPos, sr)
+}
+
+// DecodeVvcCSR - box-specific decode
+func DecodeVvcCSR(hdr BoxHeader, startPos uint64, sr bits.SliceReader) (Box, error) {
+	versionAndFlags := sr.ReadUint32()
+	if sr.AccError() != nil {
+		return nil, sr.AccError()
+	}
+	version := byte(versionAndFlags >> 24)
+	if version != 0 {
+		return nil, fmt.Errorf("vvcC: version %d not supported", version)
+	}
+	data := sr.ReadBytes(hdr.payloadLen() - 4)
+	if sr.AccError() != nil {
+		return nil, sr.AccError()
+	}
+	vvcDecConfRec, err := vvc.DecodeVVCDecConfRec(data)
+	if err != nil {
+		return nil, err
+	}
+	return &VvcCBox{
+		Version:    version,
+		Flags:      versionAndFlags & flagsMask,
+		DecConfRec: vvcDecConfRec,
+	}, sr.AccError()
+}
+
+// Type - return box type
+func (b *VvcCBox) Type() string {
+	return "vvcC"
+}
+
+// Size - return calculated size
+func (b *VvcCBox) Size() uint64 {
+	return uint64(boxHeaderSize + 4 + b.DecConfRec.Size())
+}
+
+// Encode - write box to w
+func (b *VvcCBox) Encode(w io.Writer) error {
+	sw := bits.NewFixedSliceWriter(int(b.Size()))
+	err := b.EncodeSW(sw)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(sw.Bytes())
+	return err
+}
+
+// EncodeSW - write box to sw
+func (b *VvcCBox) EncodeSW(sw bits.SliceWriter) error {
+	err := EncodeHeaderSW(b, sw)
+	if err != nil {
+		return err
+	}
+	versionAndFlags := (uint32(b.Version) << 24) + b.Flags
+	sw.WriteUint32(versionAndFlags)
+	return b.DecConfRec.EncodeSW(sw)
+}
+
+// Info - box-specific Info
+func (b *VvcCBox) Info(w io.Writer, specificBoxLevels, indent, indentStep string) error {
+	bd := newInfoDumper(w, indent, b, int(b.Version), b.Flags)
+	vdcr := b.DecConfRec
+	bd.write(" - LengthSizeMinusOne: %d", vdcr.LengthSizeMinusOne)
+	bd.write(" - PtlPresentFlag: %t", vdcr.PtlPresentFlag)
+	if vdcr.PtlPresentFlag {
+		ptl := vdcr.NativePTL
+		bd.write(" - OlsIdx: %d", vdcr.OlsIdx)
+		bd.write(" - NumSublayers: %d", vdcr.NumSublayers)
+		bd.write(" - ConstantFrameRate: %d", vdcr.ConstantFrameRate)
+		bd.write(" - ChromaFormatIDC: %d", vdcr.ChromaFormatIDC)
+		bd.write(" - BitDepth: %d", vdcr.BitDepthMinus8+8)
+		bd.write(" - GeneralProfileIDC: %d", ptl.GeneralProfileIDC)
+		bd.write(" - GeneralTierFlag: %t", ptl.GeneralTierFlag)
+		bd.write(" - GeneralLevelIDC: %d", ptl.GeneralLevelIDC)
+		bd.write(" - PtlFrameOnlyConstraintFlag: %t", ptl.PtlFrameOnlyConstraintFlag)
+		bd.write(" - PtlMultiLayerEnabledFlag: %t", ptl.PtlMultiLayerEnabledFlag)
+		bd.write(" - GeneralConstraintInfo: %s", hex.EncodeToString(ptl.GeneralConstraintInfo))
+		for _, sl := range ptl.SublayerLevelIDCs {
+			bd.write(" - SublayerLevelIDC[%d]: %d", sl.SublayerIdx, sl.LevelIDC)
+		}
+		for i, sp := range ptl.GeneralSubProfileIDCs {
+			bd.write(" - GeneralSubProfileIDC[%d]: %08x", i, sp)
+		}
+		bd.write(" - MaxPictureWidth: %d", vdcr.MaxPictureWidth)
+		bd.write(" - MaxPictureHeight: %d", vdcr.MaxPictureHeight)
+		bd.write(" - AvgFrameRate/256: %d", vdcr.AvgFrameRate)
+	}
+	for _, array := range vdcr.NaluArrays {
+		bd.write("   - %s complete: %d", array.NaluType(), array.Complete())
+		for _, nalu := range array.Nalus {
+			bd.write("    %s", hex.EncodeToString(nalu))
+		}
+	}
+	return bd.err
+}
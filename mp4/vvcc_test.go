@@ -0,0 +1,49 @@
+package mp4
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/Eyevinn/mp4ff/vvc"
+)
+
+func TestVvcC(t *testing.T) {
+	dcr := vvc.DecConfRec{
+		LengthSizeMinusOne: 3,
+		PtlPresentFlag:     true,
+		NumSublayers:       1,
+		ChromaFormatIDC:    1,
+		BitDepthMinus8:     2,
+		NativePTL: vvc.PTLRecord{
+			GeneralProfileIDC:          1,
+			GeneralLevelIDC:            51,
+			PtlFrameOnlyConstraintFlag: true,
+			GeneralConstraintInfo:      []byte{0},
+		},
+		MaxPictureWidth:  1920,
+		MaxPictureHeight: 1080,
+		NaluArrays: []vvc.NaluArray{
+			vvc.NewNaluArray(true, vvc.NALU_SPS, [][]byte{{0x00, 0x79, 0x01}}),
+			vvc.NewNaluArray(true, vvc.NALU_PPS, [][]byte{{0x00, 0x81, 0x02}}),
+		},
+	}
+	vvcC := &VvcCBox{DecConfRec: dcr}
+	boxDiffAfterEncodeAndDecode(t, vvcC)
+
+	vse := CreateVisualSampleEntryBox("vvc1", 1920, 1080, vvcC)
+	boxDiffAfterEncodeAndDecode(t, vse)
+	stsd := NewStsdBox()
+	stsd.AddChild(vse)
+	if stsd.VvcX == nil || stsd.VvcX.VvcC == nil {
+		t.Error("vvc1 and vvcC not found in stsd")
+	}
+}
+
+func TestVvcCDecodeEncode(t *testing.T) {
+	data, err := hex.DecodeString("000000287676634300000000" +
+		"ff00155f0102338000078004380000028f0001000200790d00020069")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmpAfterDecodeEncodeBox(t, data)
+}
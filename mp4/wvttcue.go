@@ -0,0 +1,168 @@
+package mp4
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/Eyevinn/mp4ff/bits"
+)
+
+// WebVTTCue - WebVTT cue with identifier, timing in milliseconds, settings and payload text.
+// In a wvtt sample, the timing is given by the sample decode time and duration and not by the cue.
+type WebVTTCue struct {
+	ID         string
+	StartMS    uint64
+	EndMS      uint64
+	Settings   string
+	Payload    string
+	SourceID   *uint32 // Optional vsid value
+	CurrentMS  *uint64 // Optional ctim value
+	Additional []string
+}
+
+// ParseVttSample parses a wvtt sample into its cues as specified in ISO/IEC 14496-30 Section 7.
+// A sample with only a vtte box results in zero cues. Timing is not set in the returned cues.
+// The text of vtta boxes is added to the cue of the preceding vttc box, and vtta boxes before
+// the first vttc box are ignored.
+func ParseVttSample(sample []byte) ([]WebVTTCue, error) {
+	sr := bits.NewFixedSliceReader(sample)
+	var cues []WebVTTCue
+	var pos uint64
+	for pos < uint64(len(sample)) {
+		box, err := DecodeBoxSR(pos, sr)
+		if err != nil {
+			return nil, fmt.Errorf("decode vtt sample box: %w", err)
+		}
+		pos += box.Size()
+		switch b := box.(type) {
+		case *VttcBox:
+			cue := WebVTTCue{}
+			if b.Iden != nil {
+				cue.ID = b.Iden.CueID
+			}
+			if b.Sttg != nil {
+				cue.Settings = b.Sttg.Settings
+			}
+			if b.Payl != nil {
+				cue.Payload = b.Payl.CueText
+			}
+			if b.Vsid != nil {
+				sourceID := b.Vsid.SourceID
+				cue.SourceID = &sourceID
+			}
+			if b.Ctim != nil {
+				ms, err := parseVttTimestamp(b.Ctim.CueCurrentTime)
+				if err != nil {
+					return nil, err
+				}
+				cue.CurrentMS = &ms
+			}
+			cues = append(cues, cue)
+		case *VtteBox:
+		case *VttaBox:
+			if len(cues) > 0 {
+				last := &cues[len(cues)-1]
+				last.Additional = append(last.Additional, b.CueAdditionalText)
+			}
+		default:
+			return nil, fmt.Errorf("unexpected box %s in vtt sample", box.Type())
+		}
+	}
+	return cues, nil
+}
+
+// VttSampleToText renders the cues of a wvtt sample as WebVTT cue blocks.
+// All cues get the timing startMS --> endMS which normally is the sample decode time and
+// decode time plus duration converted to milliseconds. An empty sample (vtte) results in an empty string.
+func VttSampleToText(sample []byte, startMS, endMS uint64) (string, error) {
+	cues, err := ParseVttSample(sample)
+	if err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	for _, cue := range cues {
+		cue.StartMS, cue.EndMS = startMS, endMS
+		sb.WriteString(cue.String())
+		sb.WriteString("\n")
+	}
+	return sb.String(), nil
+}
+
+// String - WebVTT text cue block for the cue
+func (c WebVTTCue) String() string {
+	var sb strings.Builder
+	if c.ID != "" {
+		sb.WriteString(c.ID + "\n")
+	}
+	sb.WriteString(formatVttTimestamp(c.StartMS) + " --> " + formatVttTimestamp(c.EndMS))
+	if c.Settings != "" {
+		sb.WriteString(" " + c.Settings)
+	}
+	sb.WriteString("\n" + c.Payload + "\n")
+	return sb.String()
+}
+
+// TextToVttSample creates a wvtt sample with one vttc box per cue.
+// The cue timing is not part of the sample, but should be used to set sample time and duration.
+// Without cues, a sample with a vtte box is created.
+func TextToVttSample(cues ...WebVTTCue) ([]byte, error) {
+	var boxes []Box
+	for _, cue := range cues {
+		vttc := &VttcBox{}
+		if cue.SourceID != nil {
+			vttc.AddChild(&VsidBox{SourceID: *cue.SourceID})
+		}
+		if cue.CurrentMS != nil {
+			vttc.AddChild(&CtimBox{CueCurrentTime: formatVttTimestamp(*cue.CurrentMS)})
+		}
+		if cue.ID != "" {
+			vttc.AddChild(&IdenBox{CueID: cue.ID})
+		}
+		if cue.Settings != "" {
+			vttc.AddChild(&SttgBox{Settings: cue.Settings})
+		}
+		vttc.AddChild(&PaylBox{CueText: cue.Payload})
+		boxes = append(boxes, vttc)
+		for _, a := range cue.Additional {
+			boxes = append(boxes, &VttaBox{CueAdditionalText: a})
+		}
+	}
+	if len(boxes) == 0 {
+		boxes = append(boxes, &VtteBox{})
+	}
+	buf := bytes.Buffer{}
+	for _, b := range boxes {
+		if err := b.Encode(&buf); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// formatVttTimestamp formats milliseconds as a WebVTT timestamp hh:mm:ss.ttt
+func formatVttTimestamp(ms uint64) string {
+	hours := ms / 3600_000
+	minutes := (ms / 60_000) % 60
+	seconds := (ms / 1000) % 60
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", hours, minutes, seconds, ms%1000)
+}
+
+// parseVttTimestamp parses a WebVTT timestamp [hh:]mm:ss.ttt into milliseconds
+func parseVttTimestamp(ts string) (uint64, error) {
+	var hours, minutes, seconds, millis uint64
+	parts := strings.Split(ts, ":")
+	var err error
+	switch len(parts) {
+	case 2:
+		_, err = fmt.Sscanf(ts, "%d:%d.%d", &minutes, &seconds, &millis)
+	case 3:
+		_, err = fmt.Sscanf(ts, "%d:%d:%d.%d", &hours, &minutes, &seconds, &millis)
+	default:
+		err = fmt.Errorf("wrong number of parts")
+	}
+	if err != nil {
+		return 0, fmt.Errorf("bad WebVTT timestamp %q: %w", ts, err)
+	}
+	return ((hours*60+minutes)*60+seconds)*1000 + millis, nil
+}
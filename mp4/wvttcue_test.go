@@ -0,0 +1,86 @@
+package mp4
+
+import (
+	"testing"
+
+	"github.com/go-test/deep"
+)
+
+func TestVttCueRoundTrip(t *testing.T) {
+	sourceID := uint32(42)
+	cues := []WebVTTCue{
+		{ID: "1", Settings: "line:20%", Payload: "First line", SourceID: &sourceID},
+		{Payload: "<v Bob>Second</v>"},
+	}
+	sample, err := TextToVttSample(cues...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decCues, err := ParseVttSample(sample)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := deep.Equal(decCues, cues); diff != nil {
+		t.Error(diff)
+	}
+	text, err := VttSampleToText(sample, 3_723_004, 3_725_500)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "1\n01:02:03.004 --> 01:02:05.500 line:20%\nFirst line\n\n" +
+		"01:02:03.004 --> 01:02:05.500\n<v Bob>Second</v>\n\n"
+	if text != expected {
+		t.Errorf("got %q instead of %q", text, expected)
+	}
+
+	empty, err := TextToVttSample()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(empty) != 8 || string(empty[4:8]) != "vtte" {
+		t.Errorf("expected vtte box, got %x", empty)
+	}
+	text, err = VttSampleToText(empty, 0, 1000)
+	if err != nil || text != "" {
+		t.Errorf("got %q, %v for empty sample", text, err)
+	}
+}
+
+func TestVttAdditionalTextRoundTrip(t *testing.T) {
+	cues := []WebVTTCue{
+		{Payload: "First", Additional: []string{"NOTE first a", "NOTE first b"}},
+		{Payload: "Second"},
+		{Payload: "Third", Additional: []string{"NOTE third"}},
+	}
+	sample, err := TextToVttSample(cues...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decCues, err := ParseVttSample(sample)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := deep.Equal(decCues, cues); diff != nil {
+		t.Error(diff)
+	}
+}
+
+func TestVttTimestamps(t *testing.T) {
+	for _, ts := range []string{"00:00:00.120", "01:02:03.004"} {
+		ms, err := parseVttTimestamp(ts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if formatVttTimestamp(ms) != ts {
+			t.Errorf("got %s instead of %s", formatVttTimestamp(ms), ts)
+		}
+	}
+	ms, err := parseVttTimestamp("02:03.004")
+	if err != nil || ms != 123004 {
+		t.Errorf("got %d, %v", ms, err)
+	}
+	_, err = parseVttTimestamp("bad")
+	if err == nil {
+		t.Error("expected error")
+	}
+}
@@ -0,0 +1,94 @@
+package sei
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/Eyevinn/mp4ff/bits"
+)
+
+// BufferingPeriodAvcSEI carries the data of an SEI 0 BufferingPeriod message for AVC.
+// The corresponding SEI 0 for HEVC is different.
+// Defined in ISO/IEC 14496-10 Section D.1.2 and D.2.2.
+type BufferingPeriodAvcSEI struct {
+	ExternalParams        AVCBufferingPeriodParams `json:"-"`
+	SeqParameterSetID     uint32                   `json:"SeqParameterSetID"`
+	NalInitialCpbRemovals []InitialCpbRemoval      `json:"NalInitialCpbRemovals,omitempty"`
+	VclInitialCpbRemovals []InitialCpbRemoval      `json:"VclInitialCpbRemovals,omitempty"`
+	payload               []byte                   `json:"-"`
+}
+
+// AVCBufferingPeriodParams are the parameters from the SPS VUI HRD needed to parse
+// an AVC BufferingPeriod SEI message. The CpbCntMinus1 and InitialCpbRemovalDelayLengthMinus1
+// values are only used if the corresponding HRD parameters are present.
+type AVCBufferingPeriodParams struct {
+	NalHrdBpPresentFlag                   bool
+	VclHrdBpPresentFlag                   bool
+	NalCpbCntMinus1                       uint8
+	VclCpbCntMinus1                       uint8
+	NalInitialCpbRemovalDelayLengthMinus1 uint8
+	VclInitialCpbRemovalDelayLengthMinus1 uint8
+}
+
+// InitialCpbRemoval is the initial CPB removal delay and offset for one CPB specification.
+type InitialCpbRemoval struct {
+	InitialCpbRemovalDelay       uint32
+	InitialCpbRemovalDelayOffset uint32
+}
+
+// DecodeBufferingPeriodAvcSEI decodes AVC SEI message 0 BufferingPeriod given SPS VUI HRD parameters.
+func DecodeBufferingPeriodAvcSEI(sd *SEIData, exPar AVCBufferingPeriodParams) (SEIMessage, error) {
+	buf := bytes.NewBuffer(sd.Payload())
+	br := bits.NewReader(buf)
+	bp := BufferingPeriodAvcSEI{
+		ExternalParams: exPar,
+		payload:        sd.Payload(),
+	}
+	bp.SeqParameterSetID = uint32(br.ReadExpGolomb())
+	if exPar.NalHrdBpPresentFlag {
+		bp.NalInitialCpbRemovals = readInitialCpbRemovals(br, exPar.NalCpbCntMinus1,
+			exPar.NalInitialCpbRemovalDelayLengthMinus1)
+	}
+	if exPar.VclHrdBpPresentFlag {
+		bp.VclInitialCpbRemovals = readInitialCpbRemovals(br, exPar.VclCpbCntMinus1,
+			exPar.VclInitialCpbRemovalDelayLengthMinus1)
+	}
+	return &bp, br.AccError()
+}
+
+func readInitialCpbRemovals(br *bits.Reader, cpbCntMinus1, lengthMinus1 uint8) []InitialCpbRemoval {
+	removals := make([]InitialCpbRemoval, int(cpbCntMinus1)+1)
+	for i := range removals {
+		removals[i].InitialCpbRemovalDelay = uint32(br.Read(int(lengthMinus1) + 1))
+		removals[i].InitialCpbRemovalDelayOffset = uint32(br.Read(int(lengthMinus1) + 1))
+	}
+	return removals
+}
+
+// Type returns the SEI payload type.
+func (s *BufferingPeriodAvcSEI) Type() uint {
+	return SEIBufferingPeriodType
+}
+
+// Payload returns the SEI raw rbsp payload.
+func (s *BufferingPeriodAvcSEI) Payload() []byte {
+	return s.payload
+}
+
+// String returns string representation of BufferingPeriod SEI0.
+func (s *BufferingPeriodAvcSEI) String() string {
+	msgType := SEIType(s.Type())
+	msg := fmt.Sprintf("%s, size=%d, spsID=%d", msgType, s.Size(), s.SeqParameterSetID)
+	for i, r := range s.NalInitialCpbRemovals {
+		msg += fmt.Sprintf(", nal[%d]: delay=%d offset=%d", i, r.InitialCpbRemovalDelay, r.InitialCpbRemovalDelayOffset)
+	}
+	for i, r := range s.VclInitialCpbRemovals {
+		msg += fmt.Sprintf(", vcl[%d]: delay=%d offset=%d", i, r.InitialCpbRemovalDelay, r.InitialCpbRemovalDelayOffset)
+	}
+	return msg
+}
+
+// Size is size in bytes of raw SEI message rbsp payload.
+func (s *BufferingPeriodAvcSEI) Size() uint {
+	return uint(len(s.payload))
+}
@@ -0,0 +1,51 @@
+package sei
+
+import (
+	"testing"
+
+	"github.com/go-test/deep"
+)
+
+func TestBufferingPeriodAvc(t *testing.T) {
+	// seq_parameter_set_id 1, one NAL and one VCL entry with 8-bit delay and offset
+	sd := NewSEIData(SEIBufferingPeriodType, []byte{0x40, 0x40, 0x20, 0x30, 0x20})
+	exPar := AVCBufferingPeriodParams{
+		NalHrdBpPresentFlag:                   true,
+		VclHrdBpPresentFlag:                   true,
+		NalInitialCpbRemovalDelayLengthMinus1: 7,
+		VclInitialCpbRemovalDelayLengthMinus1: 7,
+	}
+	msg, err := DecodeBufferingPeriodAvcSEI(sd, exPar)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wanted := &BufferingPeriodAvcSEI{
+		ExternalParams:        exPar,
+		SeqParameterSetID:     1,
+		NalInitialCpbRemovals: []InitialCpbRemoval{{0x02, 0x01}},
+		VclInitialCpbRemovals: []InitialCpbRemoval{{0x01, 0x81}},
+		payload:               sd.Payload(),
+	}
+	if diff := deep.Equal(msg, wanted); diff != nil {
+		t.Error(diff)
+	}
+	wantedString := "SEIBufferingPeriodType (0), size=5, spsID=1, nal[0]: delay=2 offset=1, vcl[0]: delay=1 offset=129"
+	if msg.String() != wantedString {
+		t.Errorf("got %q instead of %q", msg.String(), wantedString)
+	}
+
+	// The payload is RBSP, so 0x000003 must not be treated as emulation prevention
+	sd = NewSEIData(SEIBufferingPeriodType, []byte{0x80, 0x00, 0x00, 0x03, 0x00})
+	exPar = AVCBufferingPeriodParams{
+		NalHrdBpPresentFlag:                   true,
+		NalInitialCpbRemovalDelayLengthMinus1: 15,
+	}
+	msg, err = DecodeBufferingPeriodAvcSEI(sd, exPar)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bp := msg.(*BufferingPeriodAvcSEI)
+	if diff := deep.Equal(bp.NalInitialCpbRemovals, []InitialCpbRemoval{{0, 6}}); diff != nil {
+		t.Error(diff)
+	}
+}
@@ -16,6 +16,20 @@ type MasteringDisplayColourVolumeSEI struct {
 	MinDisplayMasteringLuminance uint32
 }
 
+// NewMasteringDisplayColourVolume creates an SEI message 137 with HDR10 static metadata.
+// Primaries and white point are in units of 0.00002, and luminances in units of 0.0001 cd/m2.
+func NewMasteringDisplayColourVolume(primariesX, primariesY [3]uint16, whitePointX, whitePointY uint16,
+	maxLuminance, minLuminance uint32) *MasteringDisplayColourVolumeSEI {
+	return &MasteringDisplayColourVolumeSEI{
+		DisplayPrimariesX:            primariesX,
+		DisplayPrimariesY:            primariesY,
+		WhitePointX:                  whitePointX,
+		WhitePointY:                  whitePointY,
+		MaxDisplayMasteringLuminance: maxLuminance,
+		MinDisplayMasteringLuminance: minLuminance,
+	}
+}
+
 func (m MasteringDisplayColourVolumeSEI) Type() uint {
 	return SEIMasteringDisplayColourVolumeType
 }
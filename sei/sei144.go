@@ -12,6 +12,14 @@ type ContentLightLevelInformationSEI struct {
 	MaxPicAverageLightLevel uint16
 }
 
+// NewContentLightLevelInfo creates an SEI message 144 with maxCLL and maxFALL in cd/m2.
+func NewContentLightLevelInfo(maxCLL, maxFALL uint16) *ContentLightLevelInformationSEI {
+	return &ContentLightLevelInformationSEI{
+		MaxContentLightLevel:    maxCLL,
+		MaxPicAverageLightLevel: maxFALL,
+	}
+}
+
 func (c ContentLightLevelInformationSEI) Type() uint {
 	return SEIContentLightLevelInformationType
 }
@@ -14,9 +14,12 @@ type PicTimingAvcSEI struct {
 	// CbpDbpDelay is optional and triggered by VUI HRD data
 	CbpDbpDelay *CbpDbpDelay `json:"-"`
 	// TimeOffsetLength is 5 bits and comes from SPS HRD if present
-	TimeOffsetLength uint8        `json:"-"`
-	PictStruct       uint8        `json:"pict_struct"`
-	Clocks           []ClockTSAvc `json:"clocks"`
+	TimeOffsetLength uint8 `json:"-"`
+	// NoPictStruct is set if pic_struct_present_flag in SPS VUI is false, so that there is
+	// neither pict_struct nor clock timestamps
+	NoPictStruct bool         `json:"-"`
+	PictStruct   uint8        `json:"pict_struct"`
+	Clocks       []ClockTSAvc `json:"clocks"`
 }
 
 // CbpDbpDelay carries the optional data on CpbDpbDelay.
@@ -44,6 +47,14 @@ func DecodePicTimingAvcSEI(sd *SEIData) (SEIMessage, error) {
 // The delay values in cbpDbpDelay will then be set by the decoder by reading the bits.
 // It is assumed that pict_struct_present_flag is true, so that a 4-bit pict_struct value is present.
 func DecodePicTimingAvcSEIHRD(sd *SEIData, cbpDbpDelay *CbpDbpDelay, timeOffsetLen byte) (SEIMessage, error) {
+	return DecodePicTimingAvcSEIPictStruct(sd, cbpDbpDelay, timeOffsetLen, true)
+}
+
+// DecodePicTimingAvcSEIPictStruct decodes AVC SEI message 1 PicTiming like DecodePicTimingAvcSEIHRD,
+// but with pictStructPresent given by pic_struct_present_flag in SPS VUI.
+// If it is false, only the optional CPB removal and DPB output delays are present.
+func DecodePicTimingAvcSEIPictStruct(sd *SEIData, cbpDbpDelay *CbpDbpDelay, timeOffsetLen byte,
+	pictStructPresent bool) (SEIMessage, error) {
 	buf := bytes.NewBuffer(sd.Payload())
 	br := bits.NewReader(buf)
 	var outCbDbpDelay CbpDbpDelay
@@ -53,6 +64,16 @@ func DecodePicTimingAvcSEIHRD(sd *SEIData, cbpDbpDelay *CbpDbpDelay, timeOffsetL
 		outCbDbpDelay.DpbOutputDelay = uint(br.Read(int(cbpDbpDelay.DpbOutputDelayLengthMinus1) + 1))
 	}
 
+	if !pictStructPresent {
+		tc := PicTimingAvcSEI{
+			TimeOffsetLength: timeOffsetLen,
+			NoPictStruct:     true,
+		}
+		if cbpDbpDelay != nil {
+			tc.CbpDbpDelay = &outCbDbpDelay
+		}
+		return &tc, br.AccError()
+	}
 	pictStruct := uint8(br.Read(4))
 	var numClockTS int
 	switch {
@@ -92,9 +113,11 @@ func (s *PicTimingAvcSEI) Payload() []byte {
 		sw.WriteBits(uint(s.CbpDbpDelay.CpbRemovalDelay), int(s.CbpDbpDelay.CpbRemovalDelayLengthMinus1)+1)
 		sw.WriteBits(uint(s.CbpDbpDelay.DpbOutputDelay), int(s.CbpDbpDelay.DpbOutputDelayLengthMinus1)+1)
 	}
-	sw.WriteBits(uint(s.PictStruct), 4)
-	for _, c := range s.Clocks {
-		c.WriteToSliceWriter(sw)
+	if !s.NoPictStruct {
+		sw.WriteBits(uint(s.PictStruct), 4)
+		for _, c := range s.Clocks {
+			c.WriteToSliceWriter(sw)
+		}
 	}
 	sw.FlushBits()
 	return sw.Bytes()
@@ -103,6 +126,13 @@ func (s *PicTimingAvcSEI) Payload() []byte {
 // String returns string representation of PicTiming SEI1.
 func (s *PicTimingAvcSEI) String() string {
 	msgType := SEIType(s.Type())
+	if len(s.Clocks) == 0 {
+		msg := fmt.Sprintf("%s, size=%d", msgType, s.Size())
+		if s.CbpDbpDelay != nil {
+			msg += fmt.Sprintf(", cpbRemovalDelay=%d, dpbOutputDelay=%d", s.CbpDbpDelay.CpbRemovalDelay, s.CbpDbpDelay.DpbOutputDelay)
+		}
+		return msg
+	}
 	msg := fmt.Sprintf("%s, size=%d, time=%s", msgType, s.Size(), s.Clocks[0].String())
 	if len(s.Clocks) > 1 {
 		for i := 1; i < len(s.Clocks); i++ {
@@ -119,9 +149,11 @@ func (s *PicTimingAvcSEI) Size() uint {
 		nrBits += int(s.CbpDbpDelay.CpbRemovalDelayLengthMinus1) + 1
 		nrBits += int(s.CbpDbpDelay.DpbOutputDelayLengthMinus1) + 1
 	}
-	nrBits += 4 // pict_struct
-	for _, c := range s.Clocks {
-		nrBits += c.NrBits()
+	if !s.NoPictStruct {
+		nrBits += 4 // pict_struct
+		for _, c := range s.Clocks {
+			nrBits += c.NrBits()
+		}
 	}
 	return uint((nrBits + 7) / 8)
 }
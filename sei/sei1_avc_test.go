@@ -50,3 +50,26 @@ func TestSE1AVCCLock(t *testing.T) {
 		t.Error(diff)
 	}
 }
+
+func TestPicTimingAvcWithoutPictStruct(t *testing.T) {
+	cbpDbpDelay := &CbpDbpDelay{
+		CpbRemovalDelayLengthMinus1: 7,
+		DpbOutputDelayLengthMinus1:  7,
+	}
+	sd := NewSEIData(SEIPicTimingType, []byte{0x04, 0x02})
+	msg, err := DecodePicTimingAvcSEIPictStruct(sd, cbpDbpDelay, 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pt := msg.(*PicTimingAvcSEI)
+	if pt.CbpDbpDelay.CpbRemovalDelay != 4 || pt.CbpDbpDelay.DpbOutputDelay != 2 || len(pt.Clocks) != 0 {
+		t.Errorf("got %s", pt)
+	}
+	wantedString := "SEIPicTimingType (1), size=2, cpbRemovalDelay=4, dpbOutputDelay=2"
+	if pt.String() != wantedString {
+		t.Errorf("got %q instead of %q", pt.String(), wantedString)
+	}
+	if !bytes.Equal(pt.Payload(), sd.Payload()) {
+		t.Errorf("got payload %x instead of %x", pt.Payload(), sd.Payload())
+	}
+}
@@ -74,22 +74,27 @@ func (s *RegisteredSEI) Payload() []byte {
 // CEA-608 encapsulation in SEI nal unit is defined in ATSC-120 and further
 // in CTA-708 specification (previously CEA-708).
 func ExtractCEA608sei(sd *SEIData) (*CEA608sei, error) {
-	field1, field2, err := ParseCEA608(sd.payload[8:])
+	ccData, processCCDataFlag, err := parseCCData(sd.payload[8:])
 	if err != nil {
 		return nil, err
 	}
+	field1, field2 := cea608Fields(ccData)
 	return &CEA608sei{
-		payload: sd.payload,
-		Field1:  field1,
-		Field2:  field2,
+		payload:       sd.payload,
+		Field1:        field1,
+		Field2:        field2,
+		CCData:        ccData,
+		ProcessCCData: processCCDataFlag,
 	}, nil
 }
 
 // CEA608sei data structure.
 type CEA608sei struct {
-	payload []byte // full raw payload
-	Field1  []byte
-	Field2  []byte
+	payload       []byte // full raw payload
+	Field1        []byte
+	Field2        []byte
+	CCData        []CCData // All cc_data triples including CEA-708 DTVCC data
+	ProcessCCData bool     // process_cc_data_flag. If not set, the data shall be discarded
 }
 
 // Type returns the SEI payload type.
@@ -113,37 +118,96 @@ func (s *CEA608sei) Payload() []byte {
 	return s.payload
 }
 
+// cc_type values for cc_data triples as defined in Section 4.3 of ANSI/CTA-708-E R-2018.
+const (
+	CCTypeNTSCField1 = 0 // CEA-608 data for field 1
+	CCTypeNTSCField2 = 1 // CEA-608 data for field 2
+	CCTypeDTVCCData  = 2 // CEA-708 DTVCC Channel Packet Data
+	CCTypeDTVCCStart = 3 // CEA-708 DTVCC Channel Packet Start
+)
+
+// CCData is one cc_data triple (cc_valid, cc_type, cc_data_1, cc_data_2).
+type CCData struct {
+	Valid bool
+	Type  byte
+	Data1 byte // Including parity bit for CEA-608 data
+	Data2 byte // Including parity bit for CEA-608 data
+}
+
+// IsCEA608 is true if the triple carries CEA-608 data wrapped in CEA-708 (cc_type 0 or 1).
+func (c CCData) IsCEA608() bool {
+	return c.Type == CCTypeNTSCField1 || c.Type == CCTypeNTSCField2
+}
+
+// IsEmpty is true if both data bytes are zero not counting parity bits.
+func (c CCData) IsEmpty() bool {
+	if c.IsCEA608() {
+		return (c.Data1&0x7f)+(c.Data2&0x7f) == 0
+	}
+	return c.Data1 == 0 && c.Data2 == 0
+}
+
+// ParseCCData parses the cc_data structure in CEA-708 encapsulation and returns all cc_data triples.
+// If the process_cc_data_flag is not set, the data shall be discarded and nil is returned.
+// This is specified in Section 4.3 of ANSI/CTA-708-E R-2018.
+func ParseCCData(payload []byte) ([]CCData, error) {
+	ccData, processCCDataFlag, err := parseCCData(payload)
+	if err != nil || !processCCDataFlag {
+		return nil, err
+	}
+	return ccData, nil
+}
+
+// parseCCData parses all cc_data triples and returns them together with the process_cc_data_flag.
+func parseCCData(payload []byte) (ccData []CCData, processCCDataFlag bool, err error) {
+	if len(payload) < 2 {
+		return nil, false, fmt.Errorf("not enough data for CEA-708 parsing")
+	}
+	processCCDataFlag = payload[0]&0x40 != 0
+	ccCount := int(payload[0] & 0x1f)
+	pos := 2 // Advance 1 and skip em_data byte
+	if len(payload) < pos+3*ccCount {
+		return nil, false, fmt.Errorf("not enough data for CEA-708 parsing")
+	}
+	ccData = make([]CCData, 0, ccCount)
+	for i := 0; i < ccCount; i++ {
+		b := payload[pos]
+		ccData = append(ccData, CCData{
+			Valid: b&0x4 != 0,
+			Type:  b & 0x3,
+			Data1: payload[pos+1],
+			Data2: payload[pos+2],
+		})
+		pos += 3
+	}
+	// There should also be a 0xff marker bits byte before the end of the NALU
+	return ccData, processCCDataFlag, nil
+}
+
 // ParseCEA608 parsers the the fields of data from CEA-708 encapsulation.
+// Only valid and non-empty CEA-608 byte pairs are returned. The process_cc_data_flag is not checked.
 // This is specified in Section 4.3 of ANSI/CTA-708-E R-2018.
 func ParseCEA608(payload []byte) ([]byte, []byte, error) {
-	pos := 0
-	ccCount := payload[pos] & 0x1f
-	pos += 2 // Advance 1 and skip reserved byte
-	var field1 []byte
-	var field2 []byte
-
-	for i := byte(0); i < ccCount; i++ {
-		if len(payload) < pos+3 {
-			return nil, nil, fmt.Errorf("not enough data for CEA-708 parsing")
+	ccData, _, err := parseCCData(payload)
+	if err != nil {
+		return nil, nil, err
+	}
+	field1, field2 := cea608Fields(ccData)
+	return field1, field2, nil
+}
+
+// cea608Fields returns the valid non-empty CEA-608 byte pairs for field 1 and field 2.
+func cea608Fields(ccData []CCData) (field1, field2 []byte) {
+	for _, cc := range ccData {
+		if !cc.Valid || cc.IsEmpty() {
+			continue
 		}
-		b := payload[pos]
-		ccValid := b & 0x4
-		ccType := b & 0x3
-		pos++
-		ccData1 := payload[pos] // Keep parity bit
-		pos++
-		ccData2 := payload[pos] // Keep parity bit
-		pos++
-		if ccValid != 0 && ((ccData1&0x7f)+(ccData2&0x7f) != 0) { //Check validity and non-empty data
-			if ccType == 0 {
-				field1 = append(field1, ccData1)
-				field1 = append(field1, ccData2)
-			} else if ccType == 1 {
-				field2 = append(field2, ccData1)
-				field2 = append(field2, ccData2)
-			}
+		switch cc.Type {
+		case CCTypeNTSCField1:
+			field1 = append(field1, cc.Data1, cc.Data2)
+		case CCTypeNTSCField2:
+			field2 = append(field2, cc.Data1, cc.Data2)
 		}
 	}
-	// There should also be a 0xff marker bits byte before the end of the NALU
-	return field1, field2, nil
+	return field1, field2
 }
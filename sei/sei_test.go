@@ -520,3 +520,52 @@ func TestAvailableSEITypes(t *testing.T) {
 	}
 
 }
+
+func TestParseCCData(t *testing.T) {
+	testCases := []struct {
+		name       string
+		payloadHex string
+		wanted     []sei.CCData
+		expErr     bool
+	}{
+		{"608 and 708 triples", "c3ff" + "fc9420" + "fd8080" + "ff0203",
+			[]sei.CCData{
+				{Valid: true, Type: sei.CCTypeNTSCField1, Data1: 0x94, Data2: 0x20},
+				{Valid: true, Type: sei.CCTypeNTSCField2, Data1: 0x80, Data2: 0x80},
+				{Valid: true, Type: sei.CCTypeDTVCCStart, Data1: 0x02, Data2: 0x03},
+			}, false},
+		{"process_cc_data_flag not set", "81fffc9420", nil, false},
+		{"too short", "c3fffc9420", nil, true},
+	}
+	for _, tc := range testCases {
+		payload, _ := hex.DecodeString(tc.payloadHex)
+		ccData, err := sei.ParseCCData(payload)
+		if tc.expErr {
+			if err == nil {
+				t.Errorf("%s: expected error", tc.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Error(err)
+		}
+		if diff := deep.Equal(ccData, tc.wanted); diff != nil {
+			t.Errorf("%s: %v", tc.name, diff)
+		}
+	}
+	field1, field2, err := sei.ParseCEA608([]byte{0xc3, 0xff, 0xfc, 0x94, 0x20, 0xfd, 0x80, 0x80, 0xff, 0x02, 0x03})
+	if err != nil {
+		t.Error(err)
+	}
+	if !bytes.Equal(field1, []byte{0x94, 0x20}) || len(field2) != 0 {
+		t.Errorf("got field1 %x field2 %x", field1, field2)
+	}
+	// ParseCEA608 does not check process_cc_data_flag
+	field1, _, err = sei.ParseCEA608([]byte{0x81, 0xff, 0xfc, 0x94, 0x20})
+	if err != nil {
+		t.Error(err)
+	}
+	if !bytes.Equal(field1, []byte{0x94, 0x20}) {
+		t.Errorf("got field1 %x without process_cc_data_flag", field1)
+	}
+}
@@ -0,0 +1,4 @@
+/*
+Package vvc - parsing of VVC(H.266) NAL unit headers, parameter sets, and the VVC decoder configuration record.
+*/
+package vvc
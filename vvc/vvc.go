@@ -0,0 +1,217 @@
+package vvc
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// NaluType - VVC nal type according to ISO/IEC 23090-3 Table 5
+type NaluType uint16
+
+// VVC NALU types
+const (
+	NALU_TRAIL = NaluType(0)
+	NALU_STSA  = NaluType(1)
+	NALU_RADL  = NaluType(2)
+	NALU_RASL  = NaluType(3)
+	// IDR_W_RADL and the following types up to 11 are Random Access (IRAP or GDR)
+	NALU_IDR_W_RADL = NaluType(7)
+	NALU_IDR_N_LP   = NaluType(8)
+	NALU_CRA        = NaluType(9)
+	NALU_GDR        = NaluType(10)
+	// NALU_OPI - Operating Point Information NAL Unit
+	NALU_OPI = NaluType(12)
+	// NALU_DCI - Decoding Capability Information NAL Unit
+	NALU_DCI = NaluType(13)
+	// NALU_VPS - VideoParameterSet NAL Unit
+	NALU_VPS = NaluType(14)
+	// NALU_SPS - SequenceParameterSet NAL Unit
+	NALU_SPS = NaluType(15)
+	// NALU_PPS - PictureParameterSet NAL Unit
+	NALU_PPS = NaluType(16)
+	// NALU_APS_PREFIX - Prefix Adaptation Parameter Set NAL Unit
+	NALU_APS_PREFIX = NaluType(17)
+	// NALU_APS_SUFFIX - Suffix Adaptation Parameter Set NAL Unit
+	NALU_APS_SUFFIX = NaluType(18)
+	// NALU_PH - Picture Header NAL Unit
+	NALU_PH = NaluType(19)
+	// NALU_AUD - AccessUnitDelimiter NAL Unit
+	NALU_AUD = NaluType(20)
+	// NALU_EOS - End of Sequence NAL Unit
+	NALU_EOS = NaluType(21)
+	// NALU_EOB - End of Bitstream NAL Unit
+	NALU_EOB = NaluType(22)
+	// NALU_SEI_PREFIX - Prefix SEI NAL Unit
+	NALU_SEI_PREFIX = NaluType(23)
+	// NALU_SEI_SUFFIX - Suffix SEI NAL Unit
+	NALU_SEI_SUFFIX = NaluType(24)
+	// NALU_FD - Filler data NAL Unit
+	NALU_FD = NaluType(25)
+
+	highestVideoNaluType = 11
+)
+
+func (n NaluType) String() string {
+	switch n {
+	case NALU_TRAIL:
+		return fmt.Sprintf("NonRAP_Trail_%d", n)
+	case NALU_STSA:
+		return fmt.Sprintf("NonRAP_STSA_%d", n)
+	case NALU_RADL:
+		return fmt.Sprintf("NonRAP_RADL_%d", n)
+	case NALU_RASL:
+		return fmt.Sprintf("NonRAP_RASL_%d", n)
+	case NALU_IDR_W_RADL, NALU_IDR_N_LP:
+		return fmt.Sprintf("RAP_IDR_%d", n)
+	case NALU_CRA:
+		return fmt.Sprintf("RAP_CRA_%d", n)
+	case NALU_GDR:
+		return fmt.Sprintf("RAP_GDR_%d", n)
+	case NALU_OPI:
+		return fmt.Sprintf("OPI_%d", n)
+	case NALU_DCI:
+		return fmt.Sprintf("DCI_%d", n)
+	case NALU_VPS:
+		return fmt.Sprintf("VPS_%d", n)
+	case NALU_SPS:
+		return fmt.Sprintf("SPS_%d", n)
+	case NALU_PPS:
+		return fmt.Sprintf("PPS_%d", n)
+	case NALU_APS_PREFIX, NALU_APS_SUFFIX:
+		return fmt.Sprintf("APS_%d", n)
+	case NALU_PH:
+		return fmt.Sprintf("PH_%d", n)
+	case NALU_AUD:
+		return fmt.Sprintf("AUD_%d", n)
+	case NALU_SEI_PREFIX, NALU_SEI_SUFFIX:
+		return fmt.Sprintf("SEI_%d", n)
+	default:
+		return fmt.Sprintf("Other_%d", n)
+	}
+}
+
+// GetNaluType - extract NALU type from the second byte of the two-byte NALU Header
+func GetNaluType(naluHeaderSecondByte byte) NaluType {
+	return NaluType((naluHeaderSecondByte >> 3) & 0x1f)
+}
+
+// FindNaluTypes - find list of nalu types in sample
+func FindNaluTypes(sample []byte) []NaluType {
+	naluList := make([]NaluType, 0)
+	length := len(sample)
+	if length < 6 {
+		return naluList
+	}
+	var pos uint32 = 0
+	for pos < uint32(length-5) {
+		naluLength := binary.BigEndian.Uint32(sample[pos : pos+4])
+		pos += 4
+		naluType := GetNaluType(sample[pos+1])
+		naluList = append(naluList, naluType)
+		pos += naluLength
+	}
+	return naluList
+}
+
+// FindNaluTypesUpToFirstVideoNalu - all nalu types up to first video nalu
+func FindNaluTypesUpToFirstVideoNalu(sample []byte) []NaluType {
+	naluList := make([]NaluType, 0)
+	length := len(sample)
+	if length < 6 {
+		return naluList
+	}
+	var pos uint32 = 0
+	for pos < uint32(length-5) {
+		naluLength := binary.BigEndian.Uint32(sample[pos : pos+4])
+		pos += 4
+		naluType := GetNaluType(sample[pos+1])
+		naluList = append(naluList, naluType)
+		pos += naluLength
+		if IsVideoNaluType(naluType) {
+			break // Video has started
+		}
+	}
+	return naluList
+}
+
+// IsVideoNaluType returns true if NaluType is a VCL type (<= 11)
+func IsVideoNaluType(naluType NaluType) bool {
+	return naluType <= highestVideoNaluType
+}
+
+// ContainsNaluType - is specific NaluType present in sample
+func ContainsNaluType(sample []byte, specificNaluType NaluType) bool {
+	for _, naluType := range FindNaluTypes(sample) {
+		if naluType == specificNaluType {
+			return true
+		}
+	}
+	return false
+}
+
+// IsRAPSample - is Random Access picture (IRAP or GDR, NALU 7-10)
+func IsRAPSample(sample []byte) bool {
+	for _, naluType := range FindNaluTypes(sample) {
+		if NALU_IDR_W_RADL <= naluType && naluType <= NALU_GDR {
+			return true
+		}
+	}
+	return false
+}
+
+// IsIDRSample - is IDR picture (NALU 7-8)
+func IsIDRSample(sample []byte) bool {
+	for _, naluType := range FindNaluTypes(sample) {
+		if naluType == NALU_IDR_W_RADL || naluType == NALU_IDR_N_LP {
+			return true
+		}
+	}
+	return false
+}
+
+// HasParameterSets - Check if VVC SPS and PPS are present. VPS is optional in VVC.
+func HasParameterSets(b []byte) bool {
+	naluTypeList := FindNaluTypesUpToFirstVideoNalu(b)
+	var hasSPS, hasPPS bool
+	for _, naluType := range naluTypeList {
+		switch naluType {
+		case NALU_SPS:
+			hasSPS = true
+		case NALU_PPS:
+			hasPPS = true
+		}
+		if hasSPS && hasPPS {
+			return true
+		}
+	}
+	return false
+}
+
+// GetParameterSets - get (multiple) VPS, SPS, and PPS from a sample
+func GetParameterSets(sample []byte) (vps, sps, pps [][]byte) {
+	sampleLength := uint32(len(sample))
+	var pos uint32 = 0
+naluLoop:
+	for {
+		if pos+6 > sampleLength {
+			break
+		}
+		naluLength := binary.BigEndian.Uint32(sample[pos : pos+4])
+		pos += 4
+		if pos+naluLength > sampleLength {
+			break
+		}
+		switch naluType := GetNaluType(sample[pos+1]); {
+		case naluType == NALU_VPS:
+			vps = append(vps, sample[pos:pos+naluLength])
+		case naluType == NALU_SPS:
+			sps = append(sps, sample[pos:pos+naluLength])
+		case naluType == NALU_PPS:
+			pps = append(pps, sample[pos:pos+naluLength])
+		case naluType <= highestVideoNaluType:
+			break naluLoop
+		}
+		pos += naluLength
+	}
+	return vps, sps, pps
+}
@@ -0,0 +1,108 @@
+package vvc
+
+import (
+	"testing"
+
+	"github.com/go-test/deep"
+)
+
+func TestGetNaluTypes(t *testing.T) {
+	testCases := []struct {
+		name                string
+		input               []byte
+		wanted              []NaluType
+		nalusUpToFirstVideo []NaluType
+		hasParameterSets    bool
+		isRapSample         bool
+		isIDRSample         bool
+	}{
+		{
+			"AUD",
+			[]byte{0, 0, 0, 3, 0, 0xa1, 0x10},
+			[]NaluType{NALU_AUD},
+			[]NaluType{NALU_AUD},
+			false,
+			false,
+			false,
+		},
+		{
+			"AUD, SPS, PPS, and IDR",
+			[]byte{
+				0, 0, 0, 3, 0, 0xa1, 0x10,
+				0, 0, 0, 3, 0, 0x79, 1,
+				0, 0, 0, 3, 0, 0x81, 2,
+				0, 0, 0, 3, 0, 0x41, 3,
+				0, 0, 0, 3, 0, 0x01, 4},
+			[]NaluType{NALU_AUD, NALU_SPS, NALU_PPS, NALU_IDR_N_LP, NALU_TRAIL},
+			[]NaluType{NALU_AUD, NALU_SPS, NALU_PPS, NALU_IDR_N_LP},
+			true,
+			true,
+			true,
+		},
+		{
+			"CRA",
+			[]byte{0, 0, 0, 3, 0, 0x49, 0x10},
+			[]NaluType{NALU_CRA},
+			[]NaluType{NALU_CRA},
+			false,
+			true,
+			false,
+		},
+		{
+			"too short",
+			[]byte{0, 0, 0, 1, 0},
+			[]NaluType{},
+			[]NaluType{},
+			false,
+			false,
+			false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := FindNaluTypes(tc.input)
+			if diff := deep.Equal(got, tc.wanted); diff != nil {
+				t.Errorf("nalulist diff: %v", diff)
+			}
+			got = FindNaluTypesUpToFirstVideoNalu(tc.input)
+			if diff := deep.Equal(got, tc.nalusUpToFirstVideo); diff != nil {
+				t.Errorf("nalus before first video diff: %v", diff)
+			}
+			hasPS := HasParameterSets(tc.input)
+			if hasPS != tc.hasParameterSets {
+				t.Errorf("got %t instead of %t", hasPS, tc.hasParameterSets)
+			}
+			isRAP := IsRAPSample(tc.input)
+			if isRAP != tc.isRapSample {
+				t.Errorf("got %t instead of %t", isRAP, tc.isRapSample)
+			}
+			isIDR := IsIDRSample(tc.input)
+			if isIDR != tc.isIDRSample {
+				t.Errorf("got %t instead of %t", isIDR, tc.isIDRSample)
+			}
+		})
+	}
+}
+
+func TestGetParameterSets(t *testing.T) {
+	sample := []byte{
+		0, 0, 0, 3, 0, 0x71, 1,
+		0, 0, 0, 3, 0, 0x79, 2,
+		0, 0, 0, 3, 0, 0x81, 3,
+		0, 0, 0, 3, 0, 0x41, 4,
+		0, 0, 0, 3, 0, 0x79, 5}
+	vps, sps, pps := GetParameterSets(sample)
+	if diff := deep.Equal(vps, [][]byte{{0, 0x71, 1}}); diff != nil {
+		t.Errorf("vps: %v", diff)
+	}
+	if diff := deep.Equal(sps, [][]byte{{0, 0x79, 2}}); diff != nil {
+		t.Errorf("sps: %v", diff)
+	}
+	if diff := deep.Equal(pps, [][]byte{{0, 0x81, 3}}); diff != nil {
+		t.Errorf("pps: %v", diff)
+	}
+	if !ContainsNaluType(sample, NALU_VPS) {
+		t.Error("VPS not found")
+	}
+}
@@ -0,0 +1,300 @@
+package vvc
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/Eyevinn/mp4ff/bits"
+)
+
+// VVC errors
+var (
+	ErrLengthSize = errors.New("NALU length size 3 is not allowed")
+)
+
+// DecConfRec - VVCDecoderConfigurationRecord
+// Specified in ISO/IEC 14496-15 6'th ed 2022 Sec. 11.2.4.2
+type DecConfRec struct {
+	LengthSizeMinusOne byte
+	PtlPresentFlag     bool
+	OlsIdx             uint16
+	NumSublayers       byte
+	ConstantFrameRate  byte
+	ChromaFormatIDC    byte
+	BitDepthMinus8     byte
+	NativePTL          PTLRecord
+	MaxPictureWidth    uint16
+	MaxPictureHeight   uint16
+	AvgFrameRate       uint16
+	NaluArrays         []NaluArray
+}
+
+// PTLRecord - VvcPTLRecord with profile, tier, and level information
+// Specified in ISO/IEC 14496-15 6'th ed 2022 Sec. 11.2.4.1.3
+type PTLRecord struct {
+	GeneralProfileIDC          byte
+	GeneralTierFlag            bool
+	GeneralLevelIDC            byte
+	PtlFrameOnlyConstraintFlag bool
+	PtlMultiLayerEnabledFlag   bool
+	GeneralConstraintInfo      []byte // num_bytes_constraint_info bytes. Two highest bits are always zero
+	// SublayerLevelIDCs are the present sublayer levels in decreasing sublayer order
+	SublayerLevelIDCs     []SublayerLevel
+	GeneralSubProfileIDCs []uint32
+}
+
+// SublayerLevel - sublayer_level_idc for a specific sublayer
+type SublayerLevel struct {
+	SublayerIdx byte
+	LevelIDC    byte
+}
+
+// NaluArray - VVC NALU array including complete bit and type
+type NaluArray struct {
+	completeAndType byte
+	Nalus           [][]byte
+}
+
+// NewNaluArray - create a VVC NaluArray
+func NewNaluArray(complete bool, naluType NaluType, nalus [][]byte) NaluArray {
+	var completeBit byte
+	if complete {
+		completeBit = 0x80
+	}
+	na := NaluArray{
+		completeAndType: completeBit | byte(naluType),
+		Nalus:           nalus,
+	}
+	return na
+}
+
+// NaluType - return NaluType for NaluArray
+func (n *NaluArray) NaluType() NaluType {
+	return NaluType(n.completeAndType & 0x1f)
+}
+
+// Complete - return 0x1 if complete
+func (n *NaluArray) Complete() byte {
+	return n.completeAndType >> 7
+}
+
+// hasNumNalus - DCI and OPI arrays have exactly one NALU and no num_nalus field
+func (n *NaluArray) hasNumNalus() bool {
+	naluType := n.NaluType()
+	return naluType != NALU_DCI && naluType != NALU_OPI
+}
+
+// DecodeVVCDecConfRec - decode a VVCDecConfRec
+func DecodeVVCDecConfRec(data []byte) (DecConfRec, error) {
+	vdcr := DecConfRec{}
+	sr := bits.NewFixedSliceReader(data)
+	aByte := sr.ReadUint8()
+	vdcr.LengthSizeMinusOne = (aByte >> 1) & 0x3
+	// NALU lengths of 1, 2, or 4 bytes are allowed
+	if vdcr.LengthSizeMinusOne == 2 {
+		return vdcr, ErrLengthSize
+	}
+	vdcr.PtlPresentFlag = aByte&0x1 == 0x1
+	if vdcr.PtlPresentFlag {
+		u16 := sr.ReadUint16()
+		vdcr.OlsIdx = u16 >> 7
+		vdcr.NumSublayers = byte((u16 >> 4) & 0x7)
+		vdcr.ConstantFrameRate = byte((u16 >> 2) & 0x3)
+		vdcr.ChromaFormatIDC = byte(u16 & 0x3)
+		vdcr.BitDepthMinus8 = sr.ReadUint8() >> 5
+		ptl, err := decodePTLRecord(sr, vdcr.NumSublayers)
+		if err != nil {
+			return vdcr, err
+		}
+		vdcr.NativePTL = ptl
+		vdcr.MaxPictureWidth = sr.ReadUint16()
+		vdcr.MaxPictureHeight = sr.ReadUint16()
+		vdcr.AvgFrameRate = sr.ReadUint16()
+	}
+	numArrays := sr.ReadUint8()
+	for j := 0; j < int(numArrays); j++ {
+		array := NaluArray{
+			completeAndType: sr.ReadUint8(),
+			Nalus:           nil,
+		}
+		numNalus := 1
+		if array.hasNumNalus() {
+			numNalus = int(sr.ReadUint16())
+		}
+		for i := 0; i < numNalus; i++ {
+			naluLength := int(sr.ReadUint16())
+			array.Nalus = append(array.Nalus, sr.ReadBytes(naluLength))
+		}
+		if sr.AccError() != nil {
+			break
+		}
+		vdcr.NaluArrays = append(vdcr.NaluArrays, array)
+	}
+	return vdcr, sr.AccError()
+}
+
+func decodePTLRecord(sr bits.SliceReader, numSublayers byte) (PTLRecord, error) {
+	ptl := PTLRecord{}
+	numBytesConstraintInfo := int(sr.ReadUint8() & 0x3f)
+	if numBytesConstraintInfo == 0 {
+		return ptl, fmt.Errorf("VVC PTL record num_bytes_constraint_info is 0")
+	}
+	aByte := sr.ReadUint8()
+	ptl.GeneralProfileIDC = aByte >> 1
+	ptl.GeneralTierFlag = aByte&0x1 == 0x1
+	ptl.GeneralLevelIDC = sr.ReadUint8()
+	gci := sr.ReadBytes(numBytesConstraintInfo)
+	if sr.AccError() != nil {
+		return ptl, sr.AccError()
+	}
+	ptl.PtlFrameOnlyConstraintFlag = gci[0]&0x80 != 0
+	ptl.PtlMultiLayerEnabledFlag = gci[0]&0x40 != 0
+	ptl.GeneralConstraintInfo = make([]byte, numBytesConstraintInfo)
+	copy(ptl.GeneralConstraintInfo, gci)
+	ptl.GeneralConstraintInfo[0] &= 0x3f
+	if numSublayers > 1 {
+		flags := sr.ReadUint8()
+		for i := int(numSublayers) - 2; i >= 0; i-- {
+			bitNr := 7 - (int(numSublayers) - 2 - i)
+			if (flags>>bitNr)&1 == 1 {
+				ptl.SublayerLevelIDCs = append(ptl.SublayerLevelIDCs, SublayerLevel{SublayerIdx: byte(i)})
+			}
+		}
+		for i := range ptl.SublayerLevelIDCs {
+			ptl.SublayerLevelIDCs[i].LevelIDC = sr.ReadUint8()
+		}
+	}
+	numSubProfiles := int(sr.ReadUint8())
+	for j := 0; j < numSubProfiles; j++ {
+		ptl.GeneralSubProfileIDCs = append(ptl.GeneralSubProfileIDCs, sr.ReadUint32())
+	}
+	return ptl, sr.AccError()
+}
+
+// Size - total size in bytes
+func (v *DecConfRec) Size() uint64 {
+	totalSize := 2 // First byte + numArrays
+	if v.PtlPresentFlag {
+		totalSize += 3 + v.NativePTL.size(v.NumSublayers) + 6
+	}
+	for _, array := range v.NaluArrays {
+		totalSize++ // complete + nalu type
+		if array.hasNumNalus() {
+			totalSize += 2
+		}
+		for _, nalu := range array.Nalus {
+			totalSize += 2 // nal unit length
+			totalSize += len(nalu)
+		}
+	}
+	return uint64(totalSize)
+}
+
+func (p *PTLRecord) size(numSublayers byte) int {
+	size := 3 + len(p.GeneralConstraintInfo)
+	if numSublayers > 1 {
+		size += 1 + len(p.SublayerLevelIDCs)
+	}
+	size += 1 + 4*len(p.GeneralSubProfileIDCs)
+	return size
+}
+
+// Encode - write a VVCDecConfRec to w
+func (v *DecConfRec) Encode(w io.Writer) error {
+	sw := bits.NewFixedSliceWriter(int(v.Size()))
+	err := v.EncodeSW(sw)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(sw.Bytes())
+	return err
+}
+
+// EncodeSW - write a VVCDecConfRec to sw
+func (v *DecConfRec) EncodeSW(sw bits.SliceWriter) error {
+	var ptlPresentBit byte
+	if v.PtlPresentFlag {
+		ptlPresentBit = 1
+	}
+	sw.WriteUint8(0xf8 | v.LengthSizeMinusOne<<1 | ptlPresentBit)
+	if v.PtlPresentFlag {
+		sw.WriteUint16(v.OlsIdx<<7 | uint16(v.NumSublayers)<<4 | uint16(v.ConstantFrameRate)<<2 |
+			uint16(v.ChromaFormatIDC))
+		sw.WriteUint8(v.BitDepthMinus8<<5 | 0x1f)
+		err := v.NativePTL.encodeSW(sw, v.NumSublayers)
+		if err != nil {
+			return err
+		}
+		sw.WriteUint16(v.MaxPictureWidth)
+		sw.WriteUint16(v.MaxPictureHeight)
+		sw.WriteUint16(v.AvgFrameRate)
+	}
+	sw.WriteUint8(byte(len(v.NaluArrays)))
+	for _, array := range v.NaluArrays {
+		sw.WriteUint8(array.completeAndType)
+		if array.hasNumNalus() {
+			sw.WriteUint16(uint16(len(array.Nalus)))
+		} else if len(array.Nalus) != 1 {
+			return fmt.Errorf("VVC %s array must have exactly one NALU", array.NaluType())
+		}
+		for _, nalu := range array.Nalus {
+			sw.WriteUint16(uint16(len(nalu)))
+			sw.WriteBytes(nalu)
+		}
+	}
+	return sw.AccError()
+}
+
+func (p *PTLRecord) encodeSW(sw bits.SliceWriter, numSublayers byte) error {
+	if len(p.GeneralConstraintInfo) == 0 {
+		return fmt.Errorf("VVC PTL record without general constraint info")
+	}
+	sw.WriteUint8(byte(len(p.GeneralConstraintInfo)) & 0x3f)
+	var tierBit byte
+	if p.GeneralTierFlag {
+		tierBit = 1
+	}
+	sw.WriteUint8(p.GeneralProfileIDC<<1 | tierBit)
+	sw.WriteUint8(p.GeneralLevelIDC)
+	firstByte := p.GeneralConstraintInfo[0] & 0x3f
+	if p.PtlFrameOnlyConstraintFlag {
+		firstByte |= 0x80
+	}
+	if p.PtlMultiLayerEnabledFlag {
+		firstByte |= 0x40
+	}
+	sw.WriteUint8(firstByte)
+	sw.WriteBytes(p.GeneralConstraintInfo[1:])
+	if numSublayers > 1 {
+		var flags byte
+		for _, sl := range p.SublayerLevelIDCs {
+			bitNr := 7 - (int(numSublayers) - 2 - int(sl.SublayerIdx))
+			flags |= 1 << bitNr
+		}
+		sw.WriteUint8(flags)
+		for _, sl := range p.SublayerLevelIDCs {
+			sw.WriteUint8(sl.LevelIDC)
+		}
+	}
+	sw.WriteUint8(byte(len(p.GeneralSubProfileIDCs)))
+	for _, sp := range p.GeneralSubProfileIDCs {
+		sw.WriteUint32(sp)
+	}
+	return sw.AccError()
+}
+
+// GetNalusForType - get all nalus for a specific naluType
+func (v *DecConfRec) GetNalusForType(naluType NaluType) [][]byte {
+	for _, naluArray := range v.NaluArrays {
+		if naluArray.NaluType() == naluType {
+			return naluArray.Nalus
+		}
+	}
+	return nil
+}
+
+// AddNaluArrays appends new nalus to VVCDecConfRec.
+func (v *DecConfRec) AddNaluArrays(na []NaluArray) {
+	v.NaluArrays = append(v.NaluArrays, na...)
+}
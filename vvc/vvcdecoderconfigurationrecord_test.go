@@ -0,0 +1,156 @@
+package vvc
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/go-test/deep"
+)
+
+const vvcDecConfRecHex = "ff00155f0102338000078004380000028f0001000200790d00020069"
+
+func TestDecodeVVCDecConfRec(t *testing.T) {
+	data, err := hex.DecodeString(vvcDecConfRecHex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dcr, err := DecodeVVCDecConfRec(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := DecConfRec{
+		LengthSizeMinusOne: 3,
+		PtlPresentFlag:     true,
+		OlsIdx:             0,
+		NumSublayers:       1,
+		ConstantFrameRate:  1,
+		ChromaFormatIDC:    1,
+		BitDepthMinus8:     2,
+		NativePTL: PTLRecord{
+			GeneralProfileIDC:          1,
+			GeneralTierFlag:            false,
+			GeneralLevelIDC:            51,
+			PtlFrameOnlyConstraintFlag: true,
+			PtlMultiLayerEnabledFlag:   false,
+			GeneralConstraintInfo:      []byte{0},
+		},
+		MaxPictureWidth:  1920,
+		MaxPictureHeight: 1080,
+		AvgFrameRate:     0,
+		NaluArrays: []NaluArray{
+			NewNaluArray(true, NALU_SPS, [][]byte{{0x00, 0x79}}),
+			NewNaluArray(false, NALU_DCI, [][]byte{{0x00, 0x69}}),
+		},
+	}
+	if diff := deep.Equal(dcr, expected); diff != nil {
+		t.Error(diff)
+	}
+	if int(dcr.Size()) != len(data) {
+		t.Errorf("got size %d instead of %d", dcr.Size(), len(data))
+	}
+	buf := bytes.Buffer{}
+	err = dcr.Encode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), data) {
+		t.Errorf("got %s instead of %s", hex.EncodeToString(buf.Bytes()), vvcDecConfRecHex)
+	}
+	sps := dcr.GetNalusForType(NALU_SPS)
+	if len(sps) != 1 {
+		t.Errorf("got %d SPS NALUs instead of 1", len(sps))
+	}
+}
+
+func TestVVCDecConfRecRoundTrip(t *testing.T) {
+	testCases := []struct {
+		desc string
+		dcr  DecConfRec
+	}{
+		{
+			desc: "no ptl",
+			dcr: DecConfRec{
+				LengthSizeMinusOne: 3,
+				NaluArrays: []NaluArray{
+					NewNaluArray(true, NALU_VPS, [][]byte{{0x00, 0x71, 0x01}}),
+					NewNaluArray(true, NALU_SPS, [][]byte{{0x00, 0x79, 0x02}, {0x00, 0x79, 0x03}}),
+					NewNaluArray(true, NALU_PPS, [][]byte{{0x00, 0x81, 0x04}}),
+				},
+			},
+		},
+		{
+			desc: "2-byte NALU lengths",
+			dcr: DecConfRec{
+				LengthSizeMinusOne: 1,
+				NaluArrays: []NaluArray{
+					NewNaluArray(true, NALU_SPS, [][]byte{{0x00, 0x79, 0x02}}),
+				},
+			},
+		},
+		{
+			desc: "sublayers and sub profiles",
+			dcr: DecConfRec{
+				LengthSizeMinusOne: 3,
+				PtlPresentFlag:     true,
+				OlsIdx:             3,
+				NumSublayers:       3,
+				ConstantFrameRate:  2,
+				ChromaFormatIDC:    3,
+				BitDepthMinus8:     0,
+				NativePTL: PTLRecord{
+					GeneralProfileIDC:        17,
+					GeneralTierFlag:          true,
+					GeneralLevelIDC:          83,
+					PtlMultiLayerEnabledFlag: true,
+					GeneralConstraintInfo:    []byte{0x21, 0x43, 0x65},
+					SublayerLevelIDCs: []SublayerLevel{
+						{SublayerIdx: 1, LevelIDC: 80},
+						{SublayerIdx: 0, LevelIDC: 64},
+					},
+					GeneralSubProfileIDCs: []uint32{0x01020304, 0x05060708},
+				},
+				MaxPictureWidth:  3840,
+				MaxPictureHeight: 2160,
+				AvgFrameRate:     12800,
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			buf := bytes.Buffer{}
+			err := tc.dcr.Encode(&buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if buf.Len() != int(tc.dcr.Size()) {
+				t.Errorf("got %d bytes instead of %d", buf.Len(), tc.dcr.Size())
+			}
+			dec, err := DecodeVVCDecConfRec(buf.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := deep.Equal(dec, tc.dcr); diff != nil {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestBadVVCDecConfRec(t *testing.T) {
+	data, err := hex.DecodeString(vvcDecConfRecHex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < len(data); i++ {
+		_, err = DecodeVVCDecConfRec(data[:i])
+		if err == nil {
+			t.Errorf("expected error for truncated data of length %d", i)
+		}
+	}
+	badLengthSize := []byte{0xfd, 0x00}
+	_, err = DecodeVVCDecConfRec(badLengthSize)
+	if err != ErrLengthSize {
+		t.Errorf("expected ErrLengthSize, got %v", err)
+	}
+}